@@ -179,7 +179,7 @@ type (
 	// A BasicLit node represents a literal of basic type.
 	BasicLit struct {
 		ValuePos token.Pos   // literal position
-		Kind     token.Token // token.INT, token.FLOAT, token.IMAG, token.CHAR, token.STRING or token.CSTRING
+		Kind     token.Token // token.INT, token.FLOAT, token.IMAG, token.CHAR, token.STRING, token.CSTRING, token.RAT or token.DURATION
 		Value    string      // literal string; e.g. 42, 0x7f, 3.14, 1e-9, 2.4i, 'a', '\x7f', "foo" or `\m\n\o`
 	}
 
@@ -704,8 +704,9 @@ type (
 
 	// A CommClause node represents a case of a select statement.
 	CommClause struct {
-		Case  token.Pos // position of "case" or "default" keyword
-		Comm  Stmt      // send or receive statement; nil means default case
+		Case  token.Pos // position of "case", "default" or "after" keyword
+		Comm  Stmt      // send or receive statement; nil means default or after case
+		After Expr      // timeout of after case, eg. `after 5s:`; or nil
 		Colon token.Pos // position of ":"
 		Body  []Stmt    // statement list; or nil
 	}
@@ -1044,6 +1045,7 @@ type (
 	//
 	GenDecl struct {
 		Doc    *CommentGroup // associated documentation; or nil
+		Annots []*Annotation // annotations; or nil
 		TokPos token.Pos     // position of Tok
 		Tok    token.Token   // IMPORT, CONST, TYPE, VAR
 		Lparen token.Pos     // position of '(', if any
@@ -1054,6 +1056,7 @@ type (
 	// A FuncDecl node represents a function declaration.
 	FuncDecl struct {
 		Doc      *CommentGroup // associated documentation; or nil
+		Annots   []*Annotation // annotations; or nil
 		Recv     *FieldList    // receiver (methods); or nil (functions)
 		Name     *Ident        // function/method name
 		Type     *FuncType     // function signature: parameters, results, and position of "func" keyword
@@ -265,3 +265,32 @@ func (p *RangeExpr) End() token.Pos {
 func (*RangeExpr) exprNode() {}
 
 // -----------------------------------------------------------------------------
+
+// An Annotation node represents an annotation of a declaration:
+//
+// `@name`
+// `@name(args)`
+//
+// A named argument `name: value` is represented by a *KeyValueExpr.
+type Annotation struct {
+	At     token.Pos // position of "@"
+	Name   *Ident    // annotation name
+	Lparen token.Pos // position of "(", if any
+	Args   []Expr    // annotation arguments; or nil
+	Rparen token.Pos // position of ")", if any
+}
+
+// Pos - position of first character belonging to the node.
+func (p *Annotation) Pos() token.Pos {
+	return p.At
+}
+
+// End - position of first character immediately after the node.
+func (p *Annotation) End() token.Pos {
+	if p.Rparen != token.NoPos {
+		return p.Rparen + 1
+	}
+	return p.Name.End()
+}
+
+// -----------------------------------------------------------------------------
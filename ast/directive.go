@@ -0,0 +1,53 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ast
+
+import "strings"
+
+// -----------------------------------------------------------------------------
+
+// Nolint reports whether text is a nolint directive comment, that is
+// `//nolint` or `//nolint:name1,name2` optionally followed by an
+// explanation. It returns names of checks that the directive suppresses,
+// and nil names means to suppress all checks.
+func Nolint(text string) (names []string, ok bool) {
+	text = strings.TrimPrefix(text, "//")
+	if !strings.HasPrefix(text, "nolint") {
+		return
+	}
+	text = text[6:]
+	if text == "" {
+		return nil, true
+	}
+	switch text[0] {
+	case ' ', '\t':
+		return nil, true
+	case ':':
+		if pos := strings.IndexAny(text, " \t"); pos >= 0 {
+			text = text[:pos]
+		}
+		for _, name := range strings.Split(text[1:], ",") {
+			if name != "" {
+				names = append(names, name)
+			}
+		}
+		return names, names != nil
+	}
+	return
+}
+
+// -----------------------------------------------------------------------------
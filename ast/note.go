@@ -0,0 +1,215 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ast
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/goplus/gop/token"
+)
+
+// -----------------------------------------------------------------------------
+
+const notePrefix = "//gop:note"
+
+// ParseNote parses a note comment, that is `//gop:note key1=value1 key2=value2`,
+// which carries annotations of a tool (eg. a migration assistant or a coverage
+// overlay). A value is a word without spaces or quotes, or a Go string
+// literal. It returns the annotations if text is a valid note comment.
+func ParseNote(text string) (notes map[string]string, ok bool) {
+	if !strings.HasPrefix(text, notePrefix) {
+		return
+	}
+	text = text[len(notePrefix):]
+	if text != "" && text[0] != ' ' && text[0] != '\t' {
+		return
+	}
+	notes = make(map[string]string)
+	for {
+		text = strings.TrimLeft(text, " \t")
+		if text == "" {
+			return notes, true
+		}
+		pos := strings.IndexByte(text, '=')
+		if pos <= 0 || strings.ContainsAny(text[:pos], " \t\"`") {
+			return nil, false
+		}
+		key, val := text[:pos], text[pos+1:]
+		if val != "" && (val[0] == '"' || val[0] == '`') {
+			quoted, err := strconv.QuotedPrefix(val)
+			if err != nil {
+				return nil, false
+			}
+			text = val[len(quoted):]
+			val, _ = strconv.Unquote(quoted)
+		} else {
+			if pos = strings.IndexAny(val, " \t"); pos >= 0 {
+				val, text = val[:pos], val[pos:]
+			} else {
+				text = ""
+			}
+			if strings.ContainsAny(val, "\"`") {
+				return nil, false
+			}
+		}
+		if text != "" && text[0] != ' ' && text[0] != '\t' {
+			return nil, false
+		}
+		notes[key] = val
+	}
+}
+
+// FormatNote returns the note comment of annotations notes, in order of
+// keys. Keys must be words without spaces, quotes or '='.
+func FormatNote(notes map[string]string) string {
+	keys := make([]string, 0, len(notes))
+	for key := range notes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	b.WriteString(notePrefix)
+	for _, key := range keys {
+		val := notes[key]
+		if val == "" || strings.ContainsAny(val, " \t\"`\\") || !strconv.CanBackquote(val) {
+			val = strconv.Quote(val)
+		}
+		b.WriteByte(' ')
+		b.WriteString(key)
+		b.WriteByte('=')
+		b.WriteString(val)
+	}
+	return b.String()
+}
+
+// -----------------------------------------------------------------------------
+
+// A NoteMap maps AST nodes to their annotations, which tools attach to nodes
+// and which are kept in note comments (see ParseNote), so that they survive
+// formatting of the source:
+//
+//	notes := ast.NewNoteMap(fset, f)
+//	notes.Set(stmt, "covered", "3")
+//	notes.Apply(f)
+//	format.Node(w, fset, f)
+type NoteMap map[Node]map[string]string
+
+// NewNoteMap returns annotations of nodes of f in its note comments. A note
+// comment belongs to the node that NewCommentMap associates its comment group
+// with, that is usually the node on the next line (or the line itself for a
+// trailing comment).
+func NewNoteMap(fset *token.FileSet, f *File) NoteMap {
+	m := make(NoteMap)
+	for n, list := range NewCommentMap(fset, f, f.Comments) {
+		for _, g := range list {
+			for _, c := range g.List {
+				if notes, ok := ParseNote(c.Text); ok {
+					for key, val := range notes {
+						m.Set(n, key, val)
+					}
+				}
+			}
+		}
+	}
+	return m
+}
+
+// Get returns the annotation key of node n.
+func (m NoteMap) Get(n Node, key string) (val string, ok bool) {
+	val, ok = m[n][key]
+	return
+}
+
+// Set sets the annotation key of node n to val.
+func (m NoteMap) Set(n Node, key, val string) {
+	notes, ok := m[n]
+	if !ok {
+		notes = make(map[string]string)
+		m[n] = notes
+	}
+	notes[key] = val
+}
+
+// Delete deletes the annotation key of node n.
+func (m NoteMap) Delete(n Node, key string) {
+	if notes, ok := m[n]; ok {
+		delete(notes, key)
+		if len(notes) == 0 {
+			delete(m, n)
+		}
+	}
+}
+
+// Apply replaces note comments of f by annotations in m: each node with
+// annotations gets a note comment on the line before it. Nodes must be nodes
+// of f with valid positions which start their lines (eg. declarations,
+// statements or fields); annotations of nodes not in f are ignored.
+func (m NoteMap) Apply(f *File) {
+	comments := f.Comments[:0]
+	for _, g := range f.Comments {
+		list := g.List[:0]
+		for _, c := range g.List {
+			if _, ok := ParseNote(c.Text); !ok {
+				list = append(list, c)
+			}
+		}
+		if g.List = list; len(list) > 0 {
+			comments = append(comments, g)
+		}
+	}
+	Inspect(f, func(n Node) bool {
+		if n == nil {
+			return false
+		}
+		for _, doc := range docsOf(n) {
+			if *doc != nil && len((*doc).List) == 0 { // it only had note comments
+				*doc = nil
+			}
+		}
+		if notes := m[n]; len(notes) > 0 && n.Pos() > f.Package {
+			comments = append(comments, &CommentGroup{
+				List: []*Comment{{Slash: n.Pos() - 1, Text: FormatNote(notes)}},
+			})
+		}
+		return true
+	})
+	sortComments(comments)
+	f.Comments = comments
+}
+
+// docsOf returns addresses of the doc comment and the trailing comment of n.
+func docsOf(n Node) []**CommentGroup {
+	switch v := n.(type) {
+	case *FuncDecl:
+		return []**CommentGroup{&v.Doc}
+	case *GenDecl:
+		return []**CommentGroup{&v.Doc}
+	case *Field:
+		return []**CommentGroup{&v.Doc, &v.Comment}
+	case *ImportSpec:
+		return []**CommentGroup{&v.Doc, &v.Comment}
+	case *ValueSpec:
+		return []**CommentGroup{&v.Doc, &v.Comment}
+	case *TypeSpec:
+		return []**CommentGroup{&v.Doc, &v.Comment}
+	}
+	return nil
+}
+
+// -----------------------------------------------------------------------------
@@ -41,6 +41,12 @@ func walkExprList(v Visitor, list []Expr) {
 	}
 }
 
+func walkAnnotations(v Visitor, list []*Annotation) {
+	for _, x := range list {
+		Walk(v, x)
+	}
+}
+
 func walkStmtList(v Visitor, list []Stmt) {
 	for _, x := range list {
 		Walk(v, x)
@@ -270,6 +276,9 @@ func Walk(v Visitor, node Node) {
 		if n.Comm != nil {
 			Walk(v, n.Comm)
 		}
+		if n.After != nil {
+			Walk(v, n.After)
+		}
 		walkStmtList(v, n.Body)
 
 	case *SelectStmt:
@@ -340,6 +349,7 @@ func Walk(v Visitor, node Node) {
 		if n.Doc != nil {
 			Walk(v, n.Doc)
 		}
+		walkAnnotations(v, n.Annots)
 		for _, s := range n.Specs {
 			Walk(v, s)
 		}
@@ -349,6 +359,7 @@ func Walk(v Visitor, node Node) {
 			if n.Doc != nil {
 				Walk(v, n.Doc)
 			}
+			walkAnnotations(v, n.Annots)
 			if n.Recv != nil {
 				Walk(v, n.Recv)
 			}
@@ -381,6 +392,10 @@ func Walk(v Visitor, node Node) {
 	case *SliceLit:
 		walkExprList(v, n.Elts)
 
+	case *Annotation:
+		Walk(v, n.Name)
+		walkExprList(v, n.Args)
+
 	case *LambdaExpr:
 		walkIdentList(v, n.Lhs)
 		walkExprList(v, n.Rhs)
@@ -17,10 +17,13 @@
 package gop
 
 import (
+	"io"
 	"log"
 	"os"
 
+	"github.com/goplus/gop/cl"
 	"github.com/goplus/gop/x/gocmd"
+	"github.com/goplus/gop/x/pathutil"
 	"github.com/qiniu/x/errors"
 )
 
@@ -117,6 +120,13 @@ func restoreDirAndMod(old string, mod os.FileMode) {
 // -----------------------------------------------------------------------------
 
 func RunDir(dir string, args []string, conf *Config, run *gocmd.RunConfig) (err error) {
+	if conf != nil && conf.GenDir != "" {
+		overlay, err := genGoDirTo(dir, conf)
+		if err != nil {
+			return err
+		}
+		return gocmd.RunDir(dir, args, withOverlay(run, overlay))
+	}
 	_, _, err = GenGo(dir, conf, false)
 	if err != nil {
 		return errors.NewWith(err, `GenGo(dir, conf, false)`, -2, "gop.GenGo", dir, conf, false)
@@ -141,6 +151,13 @@ func RunPkgPath(pkgPath string, args []string, chDir bool, conf *Config, run *go
 }
 
 func RunFiles(autogen string, files []string, args []string, conf *Config, run *gocmd.RunConfig) (err error) {
+	if conf != nil && conf.GenDir != "" {
+		file, overlay, err := genGoFilesTo(autogen, files, conf)
+		if err != nil {
+			return err
+		}
+		return gocmd.RunFiles([]string{file}, args, withOverlay(run, overlay))
+	}
 	files, err = GenGoFiles(autogen, files, conf)
 	if err != nil {
 		return errors.NewWith(err, `GenGoFiles(autogen, files, conf)`, -2, "gop.GenGoFiles", autogen, files, conf)
@@ -148,6 +165,50 @@ func RunFiles(autogen string, files []string, args []string, conf *Config, run *
 	return gocmd.RunFiles(files, args, run)
 }
 
+// RunSource compiles Go+ source read from src as the file filename in the
+// current directory, and runs it. The filename needn't exist (eg. stdin.gop
+// for source read from stdin).
+func RunSource(autogen, filename string, src io.Reader, args []string, conf *Config, run *gocmd.RunConfig) (err error) {
+	files := []string{filename}
+	out, err := loadFiles(".", files, src, conf)
+	if err != nil {
+		return errors.NewWith(err, `loadFiles(".", files, src, conf)`, -2, "gop.loadFiles", ".", files, src, conf)
+	}
+	autogen = autogenOf(autogen, files)
+	if conf != nil && conf.GenDir != "" {
+		file, overlay, err := genGoOverlay(out, autogen, conf)
+		if err != nil {
+			return err
+		}
+		return gocmd.RunFiles([]string{file}, args, withOverlay(run, overlay))
+	}
+	autogen = pathutil.Long(autogen)
+	if err = cl.WriteFile(backendOf(conf), out, autogen); err != nil {
+		return errors.NewWith(err, `cl.WriteFile(backendOf(conf), out, autogen)`, -2, "cl.WriteFile", backendOf(conf), out, autogen)
+	}
+	return gocmd.RunFiles([]string{autogen}, args, run)
+}
+
+// withOverlay returns a copy of run with files of overlay added.
+func withOverlay(run *gocmd.RunConfig, overlay map[string]string) *gocmd.RunConfig {
+	ret := new(gocmd.RunConfig)
+	if run != nil {
+		*ret = *run
+	}
+	if len(ret.Overlay) > 0 {
+		all := make(map[string]string, len(ret.Overlay)+len(overlay))
+		for k, v := range ret.Overlay {
+			all[k] = v
+		}
+		for k, v := range overlay {
+			all[k] = v
+		}
+		overlay = all
+	}
+	ret.Overlay = overlay
+	return ret
+}
+
 // -----------------------------------------------------------------------------
 
 func TestDir(dir string, conf *Config, test *gocmd.TestConfig) (err error) {
@@ -0,0 +1,64 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package builtin
+
+import (
+	"fmt"
+)
+
+// -----------------------------------------------------------------------------
+
+// Assert panics if cond is false. The Go+ builtin assert, eg.
+// `assert n > 0, "n = %d", n`, is compiled into an if statement instead, which
+// is stripped in release builds (see Assertions).
+func Assert(cond bool, msg ...interface{}) {
+	if !cond {
+		panic(AssertFailed(msg...))
+	}
+}
+
+// AssertFailed returns the panic message of a failed assertion.
+func AssertFailed(msg ...interface{}) string {
+	return failure("assertion failed", msg)
+}
+
+// Todo panics to mark code which is not implemented yet. It is the Go+
+// builtin todo, eg. `todo "parse options"`.
+func Todo(msg ...interface{}) {
+	panic(failure("TODO: not implemented", msg))
+}
+
+// Unreachable panics to mark code which should never be executed. It is the
+// Go+ builtin unreachable.
+func Unreachable(msg ...interface{}) {
+	panic(failure("unreachable", msg))
+}
+
+// failure returns the panic message of what failed. If the first argument of
+// msg is a string and there are more arguments, it is used as a format of
+// them like fmt.Sprintf.
+func failure(what string, msg []interface{}) string {
+	if len(msg) == 0 {
+		return what
+	}
+	if format, ok := msg[0].(string); ok && len(msg) > 1 {
+		return what + ": " + fmt.Sprintf(format, msg[1:]...)
+	}
+	return what + ": " + fmt.Sprint(msg...)
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,43 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package builtin
+
+// -----------------------------------------------------------------------------
+
+// LogInfo logs msg at INFO level with key-value pairs of args, eg.
+// `logInfo "connected", "addr", addr`. It is the Go+ builtin logInfo.
+//
+// Logs are written by log/slog (since Go 1.21): to slog.Default() if the
+// program sets it by slog.SetDefault, or otherwise as text to stderr with
+// source positions in Go+ files.
+func LogInfo(msg string, args ...interface{}) {
+	logAt(levelInfo, msg, args)
+}
+
+// LogWarn logs msg at WARN level with key-value pairs of args. It is the Go+
+// builtin logWarn.
+func LogWarn(msg string, args ...interface{}) {
+	logAt(levelWarn, msg, args)
+}
+
+// LogError logs msg at ERROR level with key-value pairs of args. It is the
+// Go+ builtin logError.
+func LogError(msg string, args ...interface{}) {
+	logAt(levelError, msg, args)
+}
+
+// -----------------------------------------------------------------------------
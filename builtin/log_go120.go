@@ -0,0 +1,76 @@
+//go:build !go1.21
+// +build !go1.21
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package builtin
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// logLevel is a log level as log/slog prints, which isn't available before
+// Go 1.21.
+type logLevel string
+
+const (
+	levelInfo  logLevel = "INFO"
+	levelWarn  logLevel = "WARN"
+	levelError logLevel = "ERROR"
+)
+
+var logMutex sync.Mutex
+
+// logAt writes a log in the format of slog.TextHandler.
+func logAt(level logLevel, msg string, args []interface{}) {
+	var b strings.Builder
+	b.WriteString("time=" + time.Now().Format(time.RFC3339Nano))
+	b.WriteString(" level=" + string(level))
+	if _, file, line, ok := runtime.Caller(2); ok { // skip logAt and LogXXX
+		b.WriteString(" source=" + filepath.Base(file) + ":" + strconv.Itoa(line))
+	}
+	writeLogAttr(&b, "msg", msg)
+	for len(args) > 0 {
+		key, ok := args[0].(string)
+		if !ok || len(args) == 1 {
+			writeLogAttr(&b, "!BADKEY", args[0])
+			args = args[1:]
+			continue
+		}
+		writeLogAttr(&b, key, args[1])
+		args = args[2:]
+	}
+	b.WriteByte('\n')
+	logMutex.Lock()
+	defer logMutex.Unlock()
+	os.Stderr.WriteString(b.String())
+}
+
+func writeLogAttr(b *strings.Builder, key string, val interface{}) {
+	s := fmt.Sprint(val)
+	if s == "" || strings.ContainsAny(s, " =\"\t\n") {
+		s = strconv.Quote(s)
+	}
+	b.WriteString(" " + key + "=" + s)
+}
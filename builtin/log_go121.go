@@ -0,0 +1,77 @@
+//go:build go1.21
+// +build go1.21
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package builtin
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	levelInfo  = slog.LevelInfo
+	levelWarn  = slog.LevelWarn
+	levelError = slog.LevelError
+)
+
+var (
+	initLogger = slog.Default()
+
+	logOnce    sync.Once
+	logHandler slog.Handler
+)
+
+// handler returns the handler of slog.Default() if it is set by the program,
+// or a text handler writing to stderr otherwise.
+func handler() slog.Handler {
+	if l := slog.Default(); l != initLogger {
+		return l.Handler()
+	}
+	logOnce.Do(func() {
+		logHandler = slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+			AddSource: true,
+			ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+				if src, ok := a.Value.Any().(*slog.Source); ok && a.Key == slog.SourceKey && groups == nil {
+					a.Value = slog.StringValue(filepath.Base(src.File) + ":" + strconv.Itoa(src.Line))
+				}
+				return a
+			},
+		})
+	})
+	return logHandler
+}
+
+func logAt(level slog.Level, msg string, args []interface{}) {
+	ctx := context.Background()
+	h := handler()
+	if !h.Enabled(ctx, level) {
+		return
+	}
+	var pcs [1]uintptr
+	runtime.Callers(3, pcs[:]) // skip runtime.Callers, logAt and LogXXX
+	r := slog.NewRecord(time.Now(), level, msg, pcs[0])
+	r.Add(args...)
+	_ = h.Handle(ctx, r)
+}
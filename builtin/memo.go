@@ -0,0 +1,113 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package builtin
+
+import (
+	"container/list"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// -----------------------------------------------------------------------------
+
+// Memo is a thread-safe cache of results of a function, which is used by
+// functions annotated by `@memoize`. It evicts the least recently used
+// results if it has a limited size, and expires results if it has a TTL.
+type Memo struct {
+	mu    sync.Mutex
+	size  int
+	ttl   time.Duration
+	items map[interface{}]*list.Element
+	lru   list.List // front is the most recently used
+}
+
+type memoItem struct {
+	key    interface{}
+	rets   []interface{}
+	expire time.Time
+}
+
+// NewMemo creates a Memo which caches results of at most size calls (no
+// limit if size <= 0) for the duration ttl (no expiration if ttl <= 0).
+func NewMemo(size int, ttl time.Duration) *Memo {
+	return &Memo{size: size, ttl: ttl, items: make(map[interface{}]*list.Element)}
+}
+
+// Key returns the cache key of the call with arguments args.
+func (p *Memo) Key(args ...interface{}) interface{} {
+	switch len(args) {
+	case 0:
+		return nil
+	case 1:
+		return args[0]
+	case 2:
+		return [2]interface{}{args[0], args[1]}
+	case 3:
+		return [3]interface{}{args[0], args[1], args[2]}
+	}
+	key := reflect.New(reflect.ArrayOf(len(args), tyInterface)).Elem()
+	for i, arg := range args {
+		if arg != nil {
+			key.Index(i).Set(reflect.ValueOf(arg))
+		}
+	}
+	return key.Interface()
+}
+
+// Get returns the cached results of the call whose key is key.
+func (p *Memo) Get(key interface{}) (rets []interface{}, ok bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	e, ok := p.items[key]
+	if !ok {
+		return
+	}
+	item := e.Value.(*memoItem)
+	if p.ttl > 0 && time.Now().After(item.expire) {
+		p.lru.Remove(e)
+		delete(p.items, key)
+		return nil, false
+	}
+	p.lru.MoveToFront(e)
+	return item.rets, true
+}
+
+// Put caches results rets of the call whose key is key.
+func (p *Memo) Put(key interface{}, rets ...interface{}) {
+	item := &memoItem{key: key, rets: rets}
+	if p.ttl > 0 {
+		item.expire = time.Now().Add(p.ttl)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if e, ok := p.items[key]; ok {
+		e.Value = item
+		p.lru.MoveToFront(e)
+		return
+	}
+	p.items[key] = p.lru.PushFront(item)
+	if p.size > 0 && p.lru.Len() > p.size {
+		e := p.lru.Back()
+		p.lru.Remove(e)
+		delete(p.items, e.Value.(*memoItem).key)
+	}
+}
+
+var tyInterface = reflect.TypeOf((*interface{})(nil)).Elem()
+
+// -----------------------------------------------------------------------------
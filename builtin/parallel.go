@@ -0,0 +1,145 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package builtin
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// -----------------------------------------------------------------------------
+
+// Pmap__0 calls fn for each item of items concurrently, by at most n
+// goroutines (GOMAXPROCS if n <= 0), and returns the results in the order of
+// items. It is the Go+ builtin pmap, eg. `pmap(urls, 8, url => len(url))`.
+func Pmap__0[T, R any](items []T, n int, fn func(T) R) []R {
+	ret := make([]R, len(items))
+	parallel(len(items), n, func(i int) error {
+		ret[i] = fn(items[i])
+		return nil
+	})
+	return ret
+}
+
+// Pmap__1 is like Pmap__0, but fn may fail. Once it fails, items which are
+// not started yet are skipped, and the first error is returned.
+func Pmap__1[T, R any](items []T, n int, fn func(T) (R, error)) ([]R, error) {
+	ret := make([]R, len(items))
+	err := parallel(len(items), n, func(i int) (err error) {
+		ret[i], err = fn(items[i])
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// Pfor__0 calls fn for each item of items concurrently, by at most n
+// goroutines (GOMAXPROCS if n <= 0), and waits for all of them. It is the Go+
+// builtin pfor, eg.
+//
+//	pfor files, 8, file => {
+//		compress file
+//	}
+func Pfor__0[T any](items []T, n int, fn func(T)) {
+	parallel(len(items), n, func(i int) error {
+		fn(items[i])
+		return nil
+	})
+}
+
+// Pfor__1 is like Pfor__0, but fn may fail. Once it fails, items which are
+// not started yet are skipped, and the first error is returned.
+func Pfor__1[T any](items []T, n int, fn func(T) error) error {
+	return parallel(len(items), n, func(i int) error {
+		return fn(items[i])
+	})
+}
+
+// FanIn__0 returns a channel which receives values from all of chs, and is
+// closed after all of them are closed. It is the Go+ builtin fanIn, eg.
+//
+//	for v <- fanIn(ch1, ch2) {
+//		echo v
+//	}
+func FanIn__0[T any](chs ...chan T) <-chan T {
+	ins := make([]<-chan T, len(chs))
+	for i, ch := range chs {
+		ins[i] = ch
+	}
+	return FanIn__1(ins...)
+}
+
+// FanIn__1 is like FanIn__0, but for receive-only channels.
+func FanIn__1[T any](chs ...<-chan T) <-chan T {
+	out := make(chan T)
+	var wg sync.WaitGroup
+	wg.Add(len(chs))
+	for _, ch := range chs {
+		go func(ch <-chan T) {
+			defer wg.Done()
+			for v := range ch {
+				out <- v
+			}
+		}(ch)
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
+
+// parallel calls fn(i) for i in [0, count) by at most n goroutines, until fn
+// fails. It returns the first error.
+func parallel(count, n int, fn func(i int) error) error {
+	if n <= 0 {
+		n = runtime.GOMAXPROCS(0)
+	}
+	if n > count {
+		n = count
+	}
+	var (
+		wg     sync.WaitGroup
+		next   int64 = -1
+		failed int32
+		once   sync.Once
+		first  error
+	)
+	wg.Add(n)
+	for w := 0; w < n; w++ {
+		go func() {
+			defer wg.Done()
+			for atomic.LoadInt32(&failed) == 0 {
+				i := int(atomic.AddInt64(&next, 1))
+				if i >= count {
+					return
+				}
+				if err := fn(i); err != nil {
+					once.Do(func() { first = err })
+					atomic.StoreInt32(&failed, 1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return first
+}
+
+// -----------------------------------------------------------------------------
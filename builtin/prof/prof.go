@@ -0,0 +1,75 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package prof implements runtime support of profiling programs, which is
+// used by code generated by `gop run -prof-exec`.
+package prof
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+)
+
+// ----------------------------------------------------------------------------
+
+// Start starts CPU profiling, and returns a function to stop it, which is
+// deferred in func main. Profiles are written into dir as cpu.pprof and
+// mem.pprof (a heap profile written when stopping, after the CPU profile is
+// flushed). Errors are reported to stderr without affecting the program.
+//
+// As deferred functions don't run if the program exits by os.Exit (eg. in
+// log.Fatal) or a panic of another goroutine, profiles aren't written then:
+// cpu.pprof is left empty or truncated and mem.pprof is missing, which
+// `gop run -prof-exec` warns about.
+func Start(dir string) (stop func()) {
+	cpu, err := os.Create(filepath.Join(dir, "cpu.pprof"))
+	if err == nil {
+		if err = pprof.StartCPUProfile(cpu); err != nil {
+			cpu.Close()
+			cpu = nil
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "prof:", err)
+	}
+	return func() {
+		if cpu != nil {
+			pprof.StopCPUProfile()
+			cpu.Close()
+		}
+		if err := writeHeapProfile(filepath.Join(dir, "mem.pprof")); err != nil {
+			fmt.Fprintln(os.Stderr, "prof:", err)
+		}
+	}
+}
+
+func writeHeapProfile(file string) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	runtime.GC() // get up-to-date statistics
+	if err = pprof.WriteHeapProfile(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ----------------------------------------------------------------------------
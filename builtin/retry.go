@@ -0,0 +1,106 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package builtin
+
+import (
+	"context"
+	"time"
+)
+
+// -----------------------------------------------------------------------------
+
+// Backoff is a policy of delays between attempts of retry: the delay starts
+// at Delay, and is multiplied by Factor after each attempt (but never exceeds
+// MaxDelay if it isn't zero).
+type Backoff struct {
+	Delay    time.Duration
+	MaxDelay time.Duration
+	Factor   float64
+}
+
+// DefaultBackoff is the backoff policy of retry if it isn't specified.
+var DefaultBackoff = &Backoff{Delay: 100 * time.Millisecond, MaxDelay: 10 * time.Second, Factor: 2}
+
+// NewBackoff returns an exponential backoff policy which starts at delay and
+// doubles after each attempt. It is the Go+ builtin backoff, eg.
+// `retry 3, backoff(time.Second), => { ... }`.
+func NewBackoff(delay time.Duration) *Backoff {
+	return &Backoff{Delay: delay, Factor: 2}
+}
+
+// Max limits delays of the backoff policy to maxDelay.
+func (p *Backoff) Max(maxDelay time.Duration) *Backoff {
+	ret := *p
+	ret.MaxDelay = maxDelay
+	return &ret
+}
+
+// next returns the delay after delay.
+func (p *Backoff) next(delay time.Duration) time.Duration {
+	factor := p.Factor
+	if factor < 1 {
+		factor = 1
+	}
+	delay = time.Duration(float64(delay) * factor)
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay
+}
+
+// Retry__0 calls fn until it succeeds, at most attempts times, waiting
+// between attempts by DefaultBackoff. It returns the error of the last
+// attempt if all attempts fail. It is the Go+ builtin retry, eg.
+//
+//	retry 3, => {
+//		return upload(file)
+//	}
+func Retry__0(attempts int, fn func() error) error {
+	return Retry__2(context.Background(), attempts, DefaultBackoff, fn)
+}
+
+// Retry__1 is like Retry__0, but waits between attempts by backoff b.
+func Retry__1(attempts int, b *Backoff, fn func() error) error {
+	return Retry__2(context.Background(), attempts, b, fn)
+}
+
+// Retry__2 is like Retry__1, but stops waiting and returns ctx.Err() if ctx
+// is done before fn succeeds.
+func Retry__2(ctx context.Context, attempts int, b *Backoff, fn func() error) (err error) {
+	if b == nil {
+		b = DefaultBackoff
+	}
+	delay := b.Delay
+	for i := 0; ; i++ {
+		if err = ctx.Err(); err != nil {
+			return
+		}
+		if err = fn(); err == nil || i+1 >= attempts {
+			return
+		}
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+		delay = b.next(delay)
+	}
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,60 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package trace implements runtime support of tracing statements, which is
+// used by code generated by `gop run -trace-exec`.
+package trace
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// ----------------------------------------------------------------------------
+
+var (
+	// Output is where traces are written to.
+	Output io.Writer = os.Stderr
+
+	mutex sync.Mutex
+)
+
+// Line prints a trace of executing a source line, in form of:
+//
+//	file:line: name1=val1 name2=val2 ...
+//
+// where vars are pairs of names and values of variables.
+func Line(file string, line int, vars ...any) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s:%d:", file, line)
+	for i := 0; i+1 < len(vars); i += 2 {
+		if reflect.ValueOf(vars[i+1]).Kind() == reflect.String {
+			fmt.Fprintf(&b, " %v=%q", vars[i], vars[i+1])
+		} else {
+			fmt.Fprintf(&b, " %v=%v", vars[i], vars[i+1])
+		}
+	}
+	b.WriteByte('\n')
+	mutex.Lock()
+	io.WriteString(Output, b.String())
+	mutex.Unlock()
+}
+
+// ----------------------------------------------------------------------------
@@ -0,0 +1,32 @@
+//go:build !(linux || darwin || dragonfly || freebsd || netbsd || openbsd || windows)
+// +build !linux,!darwin,!dragonfly,!freebsd,!netbsd,!openbsd,!windows
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tty
+
+import (
+	"errors"
+)
+
+func isTerminal(fd int) bool {
+	return false
+}
+
+func disableEcho(fd int) (restore func(), err error) {
+	return nil, errors.New("tty: disabling echo is not supported")
+}
@@ -0,0 +1,42 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tty
+
+import (
+	"golang.org/x/sys/windows"
+)
+
+func isTerminal(fd int) bool {
+	var mode uint32
+	return windows.GetConsoleMode(windows.Handle(fd), &mode) == nil
+}
+
+func disableEcho(fd int) (restore func(), err error) {
+	var old uint32
+	h := windows.Handle(fd)
+	if err = windows.GetConsoleMode(h, &old); err != nil {
+		return
+	}
+	mode := old &^ windows.ENABLE_ECHO_INPUT
+	mode |= windows.ENABLE_PROCESSED_INPUT | windows.ENABLE_LINE_INPUT
+	if err = windows.SetConsoleMode(h, mode); err != nil {
+		return
+	}
+	return func() {
+		windows.SetConsoleMode(h, old)
+	}, nil
+}
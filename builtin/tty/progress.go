@@ -0,0 +1,125 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tty
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// -----------------------------------------------------------------------------
+
+// A Spinner shows an animation with a message while a long operation runs.
+type Spinner struct {
+	Msg    string
+	Frames []string
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewSpinner creates and starts a spinner.
+func NewSpinner(msg string) *Spinner {
+	p := &Spinner{Msg: msg, Frames: []string{"|", "/", "-", "\\"}}
+	p.Start()
+	return p
+}
+
+// Start starts the spinner animation.
+func (p *Spinner) Start() {
+	if p.done != nil {
+		return
+	}
+	p.done = make(chan struct{})
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
+		for i := 0; ; i++ {
+			fmt.Fprintf(Stdout, "\r%s %s", p.Frames[i%len(p.Frames)], p.Msg)
+			select {
+			case <-p.done:
+				fmt.Fprintf(Stdout, "\r%s\r", strings.Repeat(" ", len(p.Msg)+2))
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop stops the spinner and clears its line.
+func (p *Spinner) Stop() {
+	if p.done != nil {
+		close(p.done)
+		p.wg.Wait()
+		p.done = nil
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+// A Progress shows a progress bar of a long operation.
+type Progress struct {
+	Total int
+	Width int
+
+	mutex sync.Mutex
+	cur   int
+}
+
+// NewProgress creates a progress bar whose full length means total.
+func NewProgress(total int) *Progress {
+	p := &Progress{Total: total, Width: 40}
+	p.render()
+	return p
+}
+
+// Add advances the progress by n.
+func (p *Progress) Add(n int) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.cur += n
+	if p.cur > p.Total {
+		p.cur = p.Total
+	} else if p.cur < 0 {
+		p.cur = 0
+	}
+	p.render()
+}
+
+// Done fills the progress bar and ends its line.
+func (p *Progress) Done() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.cur = p.Total
+	p.render()
+	fmt.Fprintln(Stdout)
+}
+
+func (p *Progress) render() {
+	percent := 100
+	if p.Total > 0 {
+		percent = p.cur * 100 / p.Total
+	}
+	n := p.Width * percent / 100
+	fmt.Fprintf(Stdout, "\r[%s%s] %3d%%", strings.Repeat("=", n), strings.Repeat(" ", p.Width-n), percent)
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,98 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package tty implements interactive helpers (prompts, password input,
+// spinners and progress bars) for Go+ scripts.
+package tty
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// -----------------------------------------------------------------------------
+
+var (
+	// Stdin is where prompts read answers from.
+	Stdin io.Reader = os.Stdin
+
+	// Stdout is where prompts, spinners and progress bars are written to.
+	Stdout io.Writer = os.Stdout
+)
+
+var (
+	reader   *bufio.Reader
+	readerOf io.Reader
+)
+
+func readLine() string {
+	if reader == nil || readerOf != Stdin {
+		reader, readerOf = bufio.NewReader(Stdin), Stdin
+	}
+	line, err := reader.ReadString('\n')
+	if err != nil && err != io.EOF {
+		panic(err)
+	}
+	return strings.TrimRight(line, "\r\n")
+}
+
+// IsTerminal reports whether f is a terminal.
+func IsTerminal(f *os.File) bool {
+	return isTerminal(int(f.Fd()))
+}
+
+// Ask prints prompt and returns the answer read from Stdin (without the
+// trailing newline).
+func Ask(prompt string) string {
+	fmt.Fprint(Stdout, prompt)
+	return readLine()
+}
+
+// Confirm prints prompt and reads a yes/no answer from Stdin. An empty answer
+// means the default choice, which is yes if prompt contains "[Y/n]" and no
+// otherwise.
+func Confirm(prompt string) bool {
+	for {
+		switch strings.ToLower(strings.TrimSpace(Ask(prompt))) {
+		case "y", "yes":
+			return true
+		case "n", "no":
+			return false
+		case "":
+			return strings.Contains(prompt, "[Y/n]")
+		}
+	}
+}
+
+// Password prints prompt and reads a line from Stdin without echoing it when
+// Stdin is a terminal.
+func Password(prompt string) string {
+	fmt.Fprint(Stdout, prompt)
+	if f, ok := Stdin.(*os.File); ok && IsTerminal(f) {
+		if restore, err := disableEcho(int(f.Fd())); err == nil {
+			defer func() {
+				restore()
+				fmt.Fprintln(Stdout)
+			}()
+		}
+	}
+	return readLine()
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,14 @@
+package main
+
+import "fmt"
+
+func main() {
+	fmt.Println(-7/2, -7%2, 7%-2)
+	fmt.Println(7.0/2, 1<<3|1, 6&^3)
+	var n = -9
+	fmt.Println(n>>1, uint8(n)>>1)
+	for i, r := range "héllo" {
+		fmt.Print(i, " ", r, ";")
+	}
+	fmt.Println()
+}
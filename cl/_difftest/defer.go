@@ -0,0 +1,17 @@
+package main
+
+import "fmt"
+
+func count() (n int) {
+	defer func() {
+		n *= 10
+	}()
+	for i := 0; i < 3; i++ {
+		defer fmt.Print(i, " ")
+	}
+	return 4
+}
+
+func main() {
+	fmt.Println(count())
+}
@@ -0,0 +1,26 @@
+package main
+
+import "fmt"
+
+var trace []string
+
+func f(name string, v int) int {
+	trace = append(trace, name)
+	return v
+}
+
+func pair() (int, int) {
+	trace = append(trace, "pair")
+	return 1, 2
+}
+
+func main() {
+	fmt.Println(f("a", 1) + f("b", 2)*f("c", 3))
+	s := []int{f("d", 4), f("e", 5)}
+	m := map[int]int{f("k", 1): f("v", 2)}
+	s[f("i", 0)] = f("x", 7)
+	x, y := pair()
+	x, y = y, x
+	fmt.Println(s, m, x, y)
+	fmt.Println(trace)
+}
@@ -0,0 +1,25 @@
+package main
+
+import "fmt"
+
+func main() {
+	var a int8 = 127
+	a++
+	fmt.Println(a)
+
+	var b uint8 = 0
+	b--
+	fmt.Println(b)
+
+	var c int32 = -2147483648
+	fmt.Println(-c, c/-1)
+
+	var d uint = 1
+	fmt.Println(d<<63, d<<64)
+
+	x := int64(9223372036854775807)
+	x += 1
+	fmt.Println(x)
+	big, neg := 70000, 200
+	fmt.Println(uint16(big), int8(neg))
+}
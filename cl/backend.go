@@ -0,0 +1,163 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cl
+
+import (
+	"bytes"
+	goast "go/ast"
+	"go/format"
+	gotoken "go/token"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/goplus/gox"
+)
+
+// -----------------------------------------------------------------------------
+
+// Backend represents the output stage of the compile pipeline. The front-end
+// (parser and cl) produces a *gox.Package, and a Backend emits it in a
+// concrete form (Go source code, Go AST, an interpreter IR, etc).
+type Backend interface {
+	// Name returns name of the backend, eg. "go".
+	Name() string
+
+	// WriteTo writes the file named fname of pkg to dst.
+	// If fname is not provided, it writes the default (NOT current) file.
+	WriteTo(dst io.Writer, pkg *gox.Package, fname ...string) error
+}
+
+type goBackend struct{}
+
+func (goBackend) Name() string {
+	return "go"
+}
+
+func (goBackend) WriteTo(dst io.Writer, pkg *gox.Package, fname ...string) (err error) {
+	if !hasKeptComments(pkg, fname...) {
+		return pkg.WriteTo(dst, fname...)
+	}
+	// comments in source don't have positions, so they aren't indented
+	var b bytes.Buffer
+	if err = pkg.WriteTo(&b, fname...); err != nil {
+		return
+	}
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return
+	}
+	_, err = dst.Write(src)
+	return
+}
+
+// hasKeptComments checks if comments in Go+ source are generated into the
+// file (see Config.KeepComments).
+func hasKeptComments(pkg *gox.Package, fname ...string) bool {
+	f := pkg.CommentedASTFile(fname...)
+	if f == nil {
+		return false
+	}
+	for _, cg := range f.CommentedStmts {
+		for _, c := range cg.List {
+			if strings.HasPrefix(c.Text, "\n/") && !strings.HasPrefix(c.Text, "\n//line ") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type goastBackend struct{}
+
+func (goastBackend) Name() string {
+	return "goast"
+}
+
+func (goastBackend) WriteTo(dst io.Writer, pkg *gox.Package, fname ...string) error {
+	f := pkg.ASTFile(fname...)
+	if f == nil {
+		return syscall.ENOENT
+	}
+	return goast.Fprint(dst, gotoken.NewFileSet(), f, goast.NotNilFilter)
+}
+
+var (
+	// GoBackend is the default backend. It generates Go source code.
+	GoBackend Backend = goBackend{}
+
+	// GoASTBackend dumps the generated Go AST (for debugging).
+	GoASTBackend Backend = goastBackend{}
+
+	// SSABackend dumps the SSA form of the generated Go code, a readable IR
+	// of what the Go+ code is compiled to (for debugging).
+	SSABackend Backend = ssaBackend{}
+)
+
+var (
+	backendMutex sync.RWMutex
+	backends     = map[string]Backend{
+		"go":    GoBackend,
+		"goast": GoASTBackend,
+		"ssa":   SSABackend,
+	}
+)
+
+// RegisterBackend registers a backend by its name. It overrides the existing
+// backend which has the same name.
+func RegisterBackend(b Backend) {
+	backendMutex.Lock()
+	backends[b.Name()] = b
+	backendMutex.Unlock()
+}
+
+// LookupBackend lookups a registered backend by its name.
+func LookupBackend(name string) (b Backend, ok bool) {
+	backendMutex.RLock()
+	b, ok = backends[name]
+	backendMutex.RUnlock()
+	return
+}
+
+// WriteFile writes the file named fname of pkg into file by backend b.
+// If b is nil, GoBackend is used. If fname is not provided, it writes the
+// default (NOT current) file. It returns syscall.ENOENT if pkg doesn't have
+// such a file.
+func WriteFile(b Backend, pkg *gox.Package, file string, fname ...string) (err error) {
+	if b == nil {
+		b = GoBackend
+	}
+	if _, ok := pkg.File(fname...); !ok {
+		return syscall.ENOENT
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		return
+	}
+	err = syscall.EFAULT
+	defer func() {
+		f.Close()
+		if err != nil {
+			os.Remove(file)
+		}
+	}()
+	return b.WriteTo(f, pkg, fname...)
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,144 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cl_test
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/goplus/gop/cl"
+	"github.com/goplus/gop/parser"
+	"github.com/goplus/gop/parser/fsx/memfs"
+	"github.com/goplus/gox"
+)
+
+func newTestPackage(t *testing.T, gopcode string) *gox.Package {
+	fs := memfs.SingleFile("/foo", "bar.gop", gopcode)
+	pkgs, err := parser.ParseFSDir(gblFset, fs, "/foo", parser.Config{Mode: parser.ParseComments})
+	if err != nil {
+		t.Fatal("ParseFSDir:", err)
+	}
+	pkg, err := cl.NewPackage("", pkgs["main"], gblConf)
+	if err != nil {
+		t.Fatal("NewPackage:", err)
+	}
+	return pkg
+}
+
+type nameBackend struct{}
+
+func (nameBackend) Name() string {
+	return "name"
+}
+
+func (nameBackend) WriteTo(dst io.Writer, pkg *gox.Package, fname ...string) error {
+	_, err := io.WriteString(dst, pkg.Types.Name())
+	return err
+}
+
+func TestBackend(t *testing.T) {
+	pkg := newTestPackage(t, `println "Hi"`)
+
+	var b1, b2 bytes.Buffer
+	if err := cl.GoBackend.WriteTo(&b1, pkg); err != nil {
+		t.Fatal("GoBackend.WriteTo:", err)
+	}
+	pkg.WriteTo(&b2)
+	if b1.String() != b2.String() {
+		t.Fatalf("GoBackend.WriteTo:\n%s\nExpected:\n%s\n", b1.String(), b2.String())
+	}
+
+	b1.Reset()
+	if err := cl.GoASTBackend.WriteTo(&b1, pkg); err != nil || !strings.Contains(b1.String(), "*ast.File") {
+		t.Fatal("GoASTBackend.WriteTo:", err, b1.String())
+	}
+
+	if _, ok := cl.LookupBackend("name"); ok {
+		t.Fatal("LookupBackend: found unregistered backend")
+	}
+	cl.RegisterBackend(nameBackend{})
+	b, ok := cl.LookupBackend("name")
+	if !ok {
+		t.Fatal("LookupBackend: not found")
+	}
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "out.txt")
+	if err := cl.WriteFile(b, pkg, file); err != nil {
+		t.Fatal("WriteFile:", err)
+	}
+	if data, _ := os.ReadFile(file); string(data) != "main" {
+		t.Fatal("WriteFile:", string(data))
+	}
+	testFile := filepath.Join(dir, "out_test.txt")
+	if err := cl.WriteFile(nil, pkg, testFile, "_test"); err != syscall.ENOENT {
+		t.Fatal("WriteFile _test:", err)
+	}
+	if _, err := os.Stat(testFile); err == nil {
+		t.Fatal("WriteFile _test: file created")
+	}
+}
+
+func TestSSABackend(t *testing.T) {
+	pkg := newTestPackage(t, `
+type T struct {
+	n int
+}
+
+func (p *T) Inc() {
+	p.n++
+}
+
+func double(x int) int {
+	return x * 2
+}
+
+t := &T{}
+t.inc
+f := func(x int) int {
+	return double(x) + 1
+}
+println f(3)
+`)
+	if b, ok := cl.LookupBackend("ssa"); !ok || b != cl.SSABackend {
+		t.Fatal("LookupBackend ssa:", b, ok)
+	}
+	var b bytes.Buffer
+	if err := cl.SSABackend.WriteTo(&b, pkg); err != nil {
+		t.Fatal("SSABackend.WriteTo:", err)
+	}
+	out := b.String()
+	for _, s := range []string{
+		"func init():", "func (p *T) Inc():", "func double(x int) int:", "func main():", "func main$1(x int) int:",
+		"t0 = x * 2:int", "(*T).Inc(t0)", "fmt.Println(",
+	} {
+		if !strings.Contains(out, s) {
+			t.Fatalf("SSABackend.WriteTo: %q not found in\n%s", s, out)
+		}
+	}
+	if i, j := strings.Index(out, "func double"), strings.Index(out, "func main()"); i > j {
+		t.Fatal("SSABackend.WriteTo: functions not in order of source\n" + out)
+	}
+	if err := cl.SSABackend.WriteTo(&b, pkg, "_test"); err != syscall.ENOENT {
+		t.Fatal("SSABackend.WriteTo _test:", err)
+	}
+}
@@ -19,6 +19,7 @@ package cl
 import (
 	"go/token"
 	"go/types"
+	"sync"
 
 	"github.com/goplus/gox"
 )
@@ -70,6 +71,17 @@ func initBuiltin(pkg *gox.Package, builtin *types.Package, os, fmt, ng, iox, bui
 	}
 	if buil != nil {
 		scope.Insert(gox.NewOverloadFunc(token.NoPos, builtin, "newRange", buil.Ref("NewRange__0")))
+		scope.Insert(gox.NewOverloadFunc(token.NoPos, builtin, "newMemo", buil.Ref("NewMemo")))
+		scope.Insert(gox.NewOverloadFunc(token.NoPos, builtin, "backoff", buil.Ref("NewBackoff")))
+		scope.Insert(gox.NewOverloadFunc(token.NoPos, builtin, "retry",
+			buil.Ref("Retry__0"), buil.Ref("Retry__1"), buil.Ref("Retry__2")))
+		scope.Insert(gox.NewOverloadFunc(token.NoPos, builtin, "pmap", buil.Ref("Pmap__0"), buil.Ref("Pmap__1")))
+		scope.Insert(gox.NewOverloadFunc(token.NoPos, builtin, "pfor", buil.Ref("Pfor__0"), buil.Ref("Pfor__1")))
+		scope.Insert(gox.NewOverloadFunc(token.NoPos, builtin, "fanIn", buil.Ref("FanIn__0"), buil.Ref("FanIn__1")))
+		initBuiltinFns(builtin, scope, buil, []string{
+			"assert", "todo", "unreachable",
+			"logInfo", "logWarn", "logError",
+		})
 	}
 	scope.Insert(types.NewTypeName(token.NoPos, builtin, "any", gox.TyEmptyInterface))
 }
@@ -78,7 +90,7 @@ func newBuiltinDefault(pkg *gox.Package, conf *gox.Config) *types.Package {
 	builtin := types.NewPackage("", "")
 	fmt := pkg.TryImport("fmt")
 	os := pkg.TryImport("os")
-	buil := pkg.TryImport("github.com/goplus/gop/builtin")
+	buil := pkg.TryImport(builtinPkgPath)
 	ng := pkg.TryImport("github.com/goplus/gop/builtin/ng")
 	iox := pkg.TryImport("github.com/goplus/gop/builtin/iox")
 	pkg.TryImport("strconv")
@@ -87,8 +99,14 @@ func newBuiltinDefault(pkg *gox.Package, conf *gox.Config) *types.Package {
 		initMathBig(pkg, conf, ng)
 	}
 	initBuiltin(pkg, builtin, os, fmt, ng, iox, buil)
+	initGoxMutex.Lock()
 	gox.InitBuiltin(pkg, builtin, conf)
+	initGoxMutex.Unlock()
 	return builtin
 }
 
+// initGoxMutex serializes gox.InitBuiltin, which isn't safe for concurrent use
+// (it reuses global variables), while packages may be compiled concurrently.
+var initGoxMutex sync.Mutex
+
 // -----------------------------------------------------------------------------
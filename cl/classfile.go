@@ -21,10 +21,12 @@ import (
 	"go/constant"
 	"go/types"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/goplus/gop/ast"
 	"github.com/goplus/gop/token"
+	"github.com/goplus/gop/x/classfile"
 	"github.com/goplus/gox"
 	"github.com/goplus/mod/modfile"
 )
@@ -39,8 +41,11 @@ type gmxSettings struct {
 	schedStmts []goast.Stmt // nil or len(scheds) == 2 (delayload)
 	pkgImps    []*gox.PkgRef
 	pkgPaths   []string
+	workers    []string // worker classes of this package, see Gop_workfields
+	bind       string   // name of the event binding method, see Gop_bind
 	hasScheds  bool
 	gameIsPtr  bool
+	workFields bool
 }
 
 func (p *gmxSettings) getScheds(cb *gox.CodeBuilder) []goast.Stmt {
@@ -71,7 +76,14 @@ func ClassNameAndExt(file string) (name, ext string) {
 
 func newGmx(ctx *pkgCtx, pkg *gox.Package, file string, f *ast.File, conf *Config) *gmxSettings {
 	tname, ext := ClassNameAndExt(file)
-	gt, ok := conf.LookupClass(ext)
+	var gt *Project
+	var ok bool
+	if conf.LookupClass != nil {
+		gt, ok = conf.LookupClass(ext)
+	}
+	if !ok {
+		gt, ok = classfile.Lookup(ext)
+	}
 	if !ok {
 		panic("TODO: class not found")
 	}
@@ -100,9 +112,32 @@ func newGmx(ctx *pkgCtx, pkg *gox.Package, file string, f *ast.File, conf *Confi
 	if x := getStringConst(spx, "Gop_sched"); x != "" {
 		p.scheds, p.hasScheds = strings.SplitN(x, ",", 2), true
 	}
+	p.bind = getStringConst(spx, "Gop_bind")
+	p.workFields = getBoolConst(spx, "Gop_workfields")
 	return p
 }
 
+// initWorkers collects worker classes of a package (sorted by file path) if
+// the classfile framework wants them to be fields of the project class.
+func (p *gmxSettings) initWorkers(files map[string]*ast.File) {
+	if !p.workFields {
+		return
+	}
+	fpaths := make([]string, 0, len(files))
+	for fpath, f := range files {
+		if f.IsClass && !f.IsProj {
+			fpaths = append(fpaths, fpath)
+		}
+	}
+	sort.Strings(fpaths)
+	for _, fpath := range fpaths {
+		name, ext := ClassNameAndExt(fpath)
+		if _, ok := p.sprite[ext]; ok {
+			p.workers = append(p.workers, name)
+		}
+	}
+}
+
 func spxLookup(pkgImps []*gox.PkgRef, name string) gox.Ref {
 	for _, pkg := range pkgImps {
 		if o := pkg.TryRef(name); o != nil {
@@ -137,6 +172,15 @@ func getStringConst(spx *gox.PkgRef, name string) string {
 	return ""
 }
 
+func getBoolConst(spx *gox.PkgRef, name string) bool {
+	if o := spx.TryRef(name); o != nil {
+		if c, ok := o.(*types.Const); ok && c.Val().Kind() == constant.Bool {
+			return constant.BoolVal(c.Val())
+		}
+	}
+	return false
+}
+
 func getFields(f *ast.File) []ast.Spec {
 	for _, decl := range f.Decls {
 		if g, ok := decl.(*ast.GenDecl); ok {
@@ -164,11 +208,127 @@ func setBodyHandler(ctx *blockCtx) {
 	}
 }
 
+// isEventHandler checks if name is an event handler name like `onClick`.
+func isEventHandler(name string) bool {
+	return len(name) > 2 && strings.HasPrefix(name, "on") && name[2] >= 'A' && name[2] <= 'Z'
+}
+
+// hasEventMethod checks if a class based on baseType has the method (or a
+// template method `Gopt_<baseTypeName>_<name>`) to register event handlers.
+func hasEventMethod(lookups []*gox.PkgRef, baseTypeName string, baseType types.Type, name string) bool {
+	if _, ok := baseType.(*types.Pointer); !ok {
+		baseType = types.NewPointer(baseType)
+	}
+	if types.NewMethodSet(baseType).Lookup(nil, name) != nil {
+		return true
+	}
+	tname := "Gopt_" + baseTypeName + "_" + name
+	for _, pkg := range lookups {
+		if pkg.TryRef(tname) != nil || pkg.TryRef(tname+"__0") != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// genEventBinding generates the event binding method of a class file:
+//
+//	func (this *Class) <bind>() {
+//		this.OnClick(this.onClick)
+//		...
+//	}
+//
+// for all methods `onX` of the class having a corresponding `OnX` method in
+// its base class. The classfile framework calls it to bind event handlers.
+// It returns the event handlers bound.
+func genEventBinding(ctx *blockCtx, f *ast.File, bind, baseTypeName string, baseType types.Type) (handlers []*EventHandler) {
+	var stmts []ast.Stmt
+	for _, decl := range f.Decls {
+		d, ok := decl.(*ast.FuncDecl)
+		if !ok || d.Recv != nil {
+			continue
+		}
+		name := d.Name.Name
+		if name == bind {
+			return nil
+		}
+		if !isEventHandler(name) {
+			continue
+		}
+		method := "O" + name[1:]
+		if !hasEventMethod(ctx.lookups, baseTypeName, baseType, method) {
+			continue
+		}
+		pos := d.Name.Pos()
+		handlers = append(handlers, &EventHandler{Name: d.Name, Binder: method})
+		stmts = append(stmts, &ast.ExprStmt{X: &ast.CallExpr{
+			Fun: &ast.SelectorExpr{
+				X: &ast.Ident{NamePos: pos, Name: "this"}, Sel: &ast.Ident{NamePos: pos, Name: method},
+			},
+			Args: []ast.Expr{&ast.SelectorExpr{
+				X: &ast.Ident{NamePos: pos, Name: "this"}, Sel: &ast.Ident{NamePos: pos, Name: name},
+			}},
+		}})
+	}
+	if stmts != nil {
+		f.Decls = append(f.Decls, &ast.FuncDecl{
+			Name: ast.NewIdent(bind),
+			Type: &ast.FuncType{Params: &ast.FieldList{}},
+			Body: &ast.BlockStmt{List: stmts},
+		})
+	}
+	return
+}
+
+// A ClassInfo describes the class of a classfile, and the members injected
+// into the class by its classfile framework. It is reported to the Recorder
+// if it implements ClassRecorder, so that tools (eg. hover of a language
+// server) can explain where members of a class come from.
+type ClassInfo struct {
+	File string   // the classfile
+	Name string   // name of the class
+	Proj bool     // it's the project class (eg. the Game of spx)
+	Pkgs []string // package paths of the classfile framework
+	Bind string   // name of the generated event binding method, or ""
+
+	// Base is the base class embedded in the class (eg. spx.Game or
+	// spx.Sprite), whose fields and methods can be used without `this.`.
+	Base types.Object
+
+	// Game is the project class, which is embedded as a pointer in a work
+	// class if the work class is a worker of the project (eg. *Game embedded
+	// in a sprite), or "".
+	Game string
+
+	// Workers are names of work classes which are fields of the project
+	// class (see Gop_workfields). It's available for the project class.
+	Workers []string
+
+	// Handlers are event handlers of the class bound to the methods of the
+	// base class (see Gop_bind).
+	Handlers []*EventHandler
+}
+
+// An EventHandler is a method of a class handling an event (eg. onClick),
+// which is bound by calling the method Binder (eg. OnClick) of its base class.
+type EventHandler struct {
+	Name   *ast.Ident
+	Binder string
+}
+
+// ClassRecorder is implemented by a Recorder which wants to know classes of
+// classfiles.
+type ClassRecorder interface {
+	// Class is called for each classfile.
+	Class(info *ClassInfo)
+}
+
 func gmxMainFunc(p *gox.Package, ctx *pkgCtx) {
 	if o := p.Types.Scope().Lookup(ctx.gameClass); o != nil && hasMethod(o, "MainEntry") {
 		// new(Game).Main()
-		p.NewFunc(nil, "main", nil, nil, false).BodyStart(p).
-			Val(p.Builtin().Ref("new")).Val(o).Call(1).
+		cb := p.NewFunc(nil, "main", nil, nil, false).BodyStart(p)
+		profileMain(p, ctx.profile, cb)
+		cb.Val(p.Builtin().Ref("new")).Val(o).Call(1).
 			MemberVal("Main").Call(0).EndStmt().
 			End()
 	}
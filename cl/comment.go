@@ -0,0 +1,154 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cl
+
+import (
+	"sort"
+
+	"github.com/goplus/gop/ast"
+	"github.com/goplus/gop/token"
+)
+
+// -----------------------------------------------------------------------------
+
+// initStmtComments collects comments in function bodies (including the body
+// of the main func of a script) of files, so that they can be generated as
+// comments of statements (see Config.KeepComments):
+//   - comments between two statements are generated before the latter.
+//   - comments at the end of a statement's line are generated before it.
+//
+// Doc comments of declarations are generated along with the declarations, and
+// other comments (eg. ones in expressions) are dropped.
+func (p *pkgCtx) initStmtComments(files map[string]*ast.File) {
+	p.stmtComments = make(map[ast.Stmt]*ast.CommentGroup)
+	for _, f := range files {
+		c := &stmtCommenter{fset: p.fset, ret: p.stmtComments, used: make(map[*ast.CommentGroup]bool)}
+		c.init(f)
+		ast.Inspect(f, func(node ast.Node) bool {
+			switch v := node.(type) {
+			case *ast.BlockStmt:
+				c.stmts(v.Lbrace, v.List)
+			case *ast.CaseClause:
+				c.stmts(v.Colon, v.Body)
+			case *ast.CommClause:
+				c.stmts(v.Colon, v.Body)
+			}
+			return true
+		})
+	}
+}
+
+type stmtCommenter struct {
+	fset *token.FileSet
+	free []*ast.CommentGroup // comments not owned by declarations, sorted
+	used map[*ast.CommentGroup]bool
+	ret  map[ast.Stmt]*ast.CommentGroup
+}
+
+func (p *stmtCommenter) init(f *ast.File) {
+	owned := make(map[*ast.CommentGroup]bool)
+	own := func(cgs ...*ast.CommentGroup) {
+		for _, cg := range cgs {
+			if cg != nil {
+				owned[cg] = true
+			}
+		}
+	}
+	ast.Inspect(f, func(node ast.Node) bool {
+		switch v := node.(type) {
+		case *ast.FuncDecl:
+			own(v.Doc)
+		case *ast.GenDecl:
+			own(v.Doc)
+		case *ast.ValueSpec:
+			own(v.Doc, v.Comment)
+		case *ast.TypeSpec:
+			own(v.Doc, v.Comment)
+		case *ast.ImportSpec:
+			own(v.Doc, v.Comment)
+		case *ast.Field:
+			own(v.Doc, v.Comment)
+		}
+		return true
+	})
+	for _, cg := range f.Comments {
+		if !owned[cg] {
+			p.free = append(p.free, cg)
+		}
+	}
+}
+
+// after returns index of the first comment at or after pos.
+func (p *stmtCommenter) after(pos token.Pos) int {
+	return sort.Search(len(p.free), func(i int) bool {
+		return p.free[i].Pos() >= pos
+	})
+}
+
+// between returns comments in range [from, to).
+func (p *stmtCommenter) between(from, to token.Pos) []*ast.CommentGroup {
+	i := p.after(from)
+	j := i
+	for j < len(p.free) && p.free[j].End() <= to {
+		j++
+	}
+	return p.free[i:j]
+}
+
+func (p *stmtCommenter) line(pos token.Pos) int {
+	return p.fset.Position(pos).Line
+}
+
+func (p *stmtCommenter) stmts(start token.Pos, list []ast.Stmt) {
+	var prev ast.Stmt
+	for _, stmt := range list {
+		if !stmt.Pos().IsValid() {
+			continue
+		}
+		from := start
+		if prev != nil {
+			from = prev.End()
+		}
+		for _, cg := range p.between(from, stmt.Pos()) {
+			if prev != nil && p.line(cg.Pos()) == p.line(prev.End()) {
+				p.add(prev, cg)
+			} else {
+				p.add(stmt, cg)
+			}
+		}
+		prev = stmt
+	}
+	if prev != nil {
+		end := prev.End()
+		for i := p.after(end); i < len(p.free) && p.line(p.free[i].Pos()) == p.line(end); i++ {
+			p.add(prev, p.free[i])
+		}
+	}
+}
+
+func (p *stmtCommenter) add(stmt ast.Stmt, cg *ast.CommentGroup) {
+	if p.used[cg] { // eg. `if x { f() } // comment` is added to the if statement
+		return
+	}
+	p.used[cg] = true
+	if old, ok := p.ret[stmt]; ok {
+		cg = &ast.CommentGroup{List: append(append([]*ast.Comment(nil), old.List...), cg.List...)}
+	}
+	p.ret[stmt] = cg
+}
+
+// -----------------------------------------------------------------------------
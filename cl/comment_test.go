@@ -0,0 +1,130 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cl_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/goplus/gop/cl"
+	"github.com/goplus/gop/parser"
+	"github.com/goplus/gop/parser/fsx/memfs"
+)
+
+func TestKeepComments(t *testing.T) {
+	fs := memfs.SingleFile("/foo", "bar.gop", `
+// Double returns 2*x.
+func Double(x int) int {
+	// double it
+	y := x * 2 // twice
+
+	/* return
+	   the result */
+	return y
+}
+
+x := 1
+if x > 0 {
+	println x // positive
+} else { // never
+	println -x
+}
+switch x {
+case 1:
+	// one
+	println "one"
+}
+`)
+	pkgs, err := parser.ParseFSDir(gblFset, fs, "/foo", parser.Config{Mode: parser.ParseComments})
+	if err != nil {
+		t.Fatal("ParseFSDir:", err)
+	}
+	conf := *gblConf
+	conf.KeepComments = true
+	pkg, err := cl.NewPackage("", pkgs["main"], &conf)
+	if err != nil {
+		t.Fatal("NewPackage:", err)
+	}
+	var b bytes.Buffer
+	if err = cl.GoBackend.WriteTo(&b, pkg); err != nil {
+		t.Fatal("WriteTo:", err)
+	}
+	if ret := b.String(); ret != `package main
+
+import "fmt"
+
+// Double returns 2*x.
+func Double(x int) int {
+	// double it
+	// twice
+	y := x * 2
+	/* return
+	   the result */
+	return y
+}
+func main() {
+	x := 1
+	if x > 0 {
+		// positive
+		fmt.Println(x)
+	} else {
+		// never
+		fmt.Println(-x)
+	}
+	switch x {
+	case 1:
+		// one
+		fmt.Println("one")
+	}
+}
+` {
+		t.Fatal("TestKeepComments:", ret)
+	}
+}
+
+func TestTypeDoc(t *testing.T) {
+	pkg := newTestPackage(t, `
+// Point is a point.
+type Point struct {
+	X, Y int
+}
+
+type (
+	// Size is a size.
+	Size struct {
+		W, H int
+	}
+	Rect struct {
+		Point
+		Size
+	}
+)
+`)
+	scope := pkg.Types.Scope()
+	for name, doc := range map[string]string{"Point": "Point is a point.\n", "Size": "Size is a size.\n", "Rect": ""} {
+		if ret := pkg.Docs[scope.Lookup(name)].Text(); ret != doc {
+			t.Fatalf("doc of %s: %q", name, ret)
+		}
+	}
+	var b bytes.Buffer
+	if err := pkg.WriteTo(&b); err != nil {
+		t.Fatal("WriteTo:", err)
+	}
+	if s := b.String(); bytes.Count(b.Bytes(), []byte("// Point is a point.")) != 1 {
+		t.Fatal("WriteTo:", s)
+	}
+}
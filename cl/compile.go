@@ -18,11 +18,14 @@
 package cl
 
 import (
+	"context"
 	"fmt"
 	"go/constant"
 	"go/types"
 	"log"
 	"reflect"
+	"runtime"
+	"runtime/debug"
 	"sort"
 	"strings"
 	_ "unsafe"
@@ -183,11 +186,15 @@ type Config struct {
 	// See gop/x/c2go.LookupPub.
 	LookupPub func(pkgPath string) (pubfile string, err error)
 
-	// LookupClass lookups a class by specified file extension (required).
+	// LookupClass lookups a class by specified file extension (required if
+	// there are classfiles of frameworks not registered by
+	// gop/x/classfile.Register).
 	// See (*github.com/goplus/mod/gopmod.Module).LookupClass.
 	LookupClass func(ext string) (c *Project, ok bool)
 
-	// An Importer resolves import paths to Packages (optional).
+	// An Importer resolves import paths to Packages (optional). If nil,
+	// packages are loaded from export data found by `go list -export`.
+	// Embedders can supply their own one, see NewExportImporter.
 	Importer types.Importer
 
 	// A Recorder records existing objects including constants, variables and
@@ -197,6 +204,17 @@ type Config struct {
 	// NoFileLine = true means not to generate file line comments.
 	NoFileLine bool
 
+	// KeepComments = true means to generate comments in function bodies as
+	// comments of statements, so that the generated Go code is reviewable.
+	// Doc comments of declarations are always generated.
+	KeepComments bool
+
+	// AbsFileLine = true means to use absolute file names in file line
+	// comments instead of ones relative to RelativeBase, so that stack
+	// traces, debuggers and coverage reports can locate Go+ sources wherever
+	// the generated Go code is.
+	AbsFileLine bool
+
 	// NoAutoGenMain = true means not to auto generate main func is no entry.
 	NoAutoGenMain bool
 
@@ -205,6 +223,42 @@ type Config struct {
 
 	// Outline = true means to skip compiling function bodies.
 	Outline bool
+
+	// Strict = true means to report warnings (eg. missing cases of enum-style
+	// switches) as errors.
+	Strict bool
+
+	// OnWarning is called for each warning if Strict is false (optional).
+	OnWarning func(err error)
+
+	// OnError is called for each error when it's reported (optional), eg. to
+	// show errors before compiling is done. NewPackage still returns all of
+	// them.
+	OnError func(err error)
+
+	// MaxErrors, if positive, stops compiling after so many errors, and a
+	// "too many errors" error is reported (optional). Default is to compile
+	// the whole package and report all errors.
+	MaxErrors int
+
+	// Lang is the language version in form of gopX.Y (optional), eg. gop1.0
+	// keeps syntax deprecated since Go+ 1.1 compiling without warnings.
+	// Default is the latest version.
+	Lang string
+
+	// Trace = true means to generate code to print each executed statement
+	// and values of local variables of basic types. See gop/builtin/trace.
+	Trace bool
+
+	// Profile, if not empty, means to generate code in func main to profile
+	// the program, which writes cpu.pprof and mem.pprof into directory
+	// Profile. See gop/builtin/prof.
+	Profile string
+
+	// Context, if not nil, aborts compiling when it is done (optional).
+	// Compiling is stopped gracefully with an error reported at the position
+	// being compiled.
+	Context context.Context
 }
 
 type nodeInterp struct {
@@ -338,9 +392,23 @@ type pkgCtx struct {
 	tylds []*typeLoader
 	errs  errors.List
 
-	generics map[string]bool // generic type record
-	idents   []*ast.Ident    // toType ident recored
-	inInst   int             // toType in generic instance
+	strict    bool                   // report warnings as errors
+	onWarning func(error)            // warning handler
+	onError   func(error)            // error handler
+	maxErrors int                    // stop compiling after maxErrors errors
+	nolints   map[nolintKey][]string // nolint directives
+	abort     context.Context        // abort compiling when done
+	aborted   error                  // why compiling is aborted
+	trace     bool                   // generate code to trace statements
+	profile   string                 // generate code to profile the program
+	lang      int                    // language version, see parseLang
+
+	stmtComments map[ast.Stmt]*ast.CommentGroup // see initStmtComments
+
+	generics map[string]bool                   // generic type record
+	memoized map[*ast.FuncDecl]*ast.Annotation // functions wrapped by @memoize
+	idents   []*ast.Ident                      // toType ident recored
+	inInst   int                               // toType in generic instance
 }
 
 type pkgImp struct {
@@ -364,6 +432,8 @@ type blockCtx struct {
 	fileLine   bool
 	isClass    bool
 	isGopFile  bool // is Go+ file or not
+
+	absFileLine bool
 }
 
 func (bc *blockCtx) recorder() *typesRecorder {
@@ -392,8 +462,17 @@ func (p *pkgCtx) handleErrorf(pos token.Pos, format string, args ...interface{})
 
 func (p *pkgCtx) handleErr(err error) {
 	p.errs = append(p.errs, err)
+	if p.onError != nil {
+		p.onError(err)
+	}
+	if p.maxErrors > 0 && len(p.errs) >= p.maxErrors && p.aborted == nil {
+		p.aborted = errTooManyErrors
+		panic(p.aborted)
+	}
 }
 
+var errTooManyErrors = errors.New("too many errors")
+
 func (p *pkgCtx) loadNamed(at *gox.Package, t *types.Named) {
 	o := t.Obj()
 	if o.Pkg() == at.Types {
@@ -402,9 +481,38 @@ func (p *pkgCtx) loadNamed(at *gox.Package, t *types.Named) {
 }
 
 func (p *pkgCtx) complete() error {
+	if p.aborted != nil { // report the aborted error only once
+		errs := make(errors.List, 0, len(p.errs)+1)
+		for _, e := range p.errs {
+			if e != p.aborted {
+				errs = append(errs, e)
+			}
+		}
+		p.errs = append(errs, p.aborted)
+	}
 	return p.errs.ToError()
 }
 
+// checkAbort stops compiling if the context of compiling is done, or there
+// are too many errors.
+func (p *pkgCtx) checkAbort(pos token.Pos) {
+	if p == nil {
+		return
+	}
+	if p.aborted == nil {
+		if p.abort == nil {
+			return
+		}
+		select {
+		case <-p.abort.Done():
+		default:
+			return
+		}
+		p.aborted = p.newCodeErrorf(pos, "compiling aborted: %v", p.abort.Err())
+	}
+	panic(p.aborted)
+}
+
 func (p *pkgCtx) loadType(name string) {
 	if sym, ok := p.syms[name]; ok {
 		if ld, ok := sym.(*typeLoader); ok {
@@ -434,15 +542,70 @@ func (p *pkgCtx) loadSymbol(name string) bool {
 }
 
 func (p *pkgCtx) handleRecover(e interface{}) {
+	if p.aborted != nil && e == p.aborted { // stop compiling
+		panic(e)
+	}
+	if isInternalPanic(e) { // a bug of the compiler: stop compiling
+		p.aborted = &InternalError{Value: e, Stack: debug.Stack()}
+		panic(p.aborted)
+	}
 	err, ok := e.(error)
 	if !ok {
-		if msg, ok := e.(string); ok {
-			err = errors.New(msg)
-		} else {
+		err = errors.New(e.(string))
+	}
+	p.handleErr(err)
+}
+
+// recoverTop handles the panic e recovered at the top level of compiling a
+// package, where compiling stops anyway.
+func (p *pkgCtx) recoverTop(e interface{}) {
+	defer func() {
+		if e := recover(); e != nil && e != p.aborted {
 			panic(e)
 		}
+	}()
+	p.handleRecover(e)
+}
+
+// isInternalPanic reports whether the panic value e is caused by a bug of the
+// compiler rather than an error of the code compiled.
+func isInternalPanic(e interface{}) bool {
+	switch e.(type) {
+	case runtime.Error:
+		return true
+	case error, string:
+		return false
 	}
-	p.handleErr(err)
+	return true
+}
+
+// An InternalError is a panic of the compiler itself, eg. a nil pointer
+// dereference, which is a bug of Go+ rather than an error of the code
+// compiled. Compiling stops once it happens.
+type InternalError struct {
+	Value interface{} // the value passed to panic
+	Stack []byte      // stack trace of the goroutine when it panicked
+}
+
+func (p *InternalError) Error() string {
+	return fmt.Sprintf("internal compiler error: %v", p.Value)
+}
+
+// InternalErrorOf returns the InternalError in err returned by NewPackage.
+func InternalErrorOf(err error) (ie *InternalError, ok bool) {
+	switch e := err.(type) {
+	case *InternalError:
+		return e, true
+	case errors.List:
+		for _, v := range e {
+			if ie, ok = InternalErrorOf(v); ok {
+				return
+			}
+		}
+	case *errors.Frame:
+		return InternalErrorOf(e.Err)
+	}
+	return
 }
 
 const (
@@ -466,6 +629,11 @@ func NewPackage(pkgPath string, pkg *ast.Package, conf *Config) (p *gox.Package,
 	ctx := &pkgCtx{
 		fset: fset,
 		syms: make(map[string]loader), nodeInterp: interp, generics: make(map[string]bool),
+		strict: conf.Strict, onWarning: conf.OnWarning, abort: conf.Context, trace: conf.Trace,
+		profile: conf.Profile, onError: conf.OnError, maxErrors: conf.MaxErrors,
+	}
+	if ctx.lang, err = parseLang(conf.Lang); err != nil {
+		return
 	}
 	confGox := &gox.Config{
 		Types:           conf.Types,
@@ -488,11 +656,18 @@ func NewPackage(pkgPath string, pkg *ast.Package, conf *Config) (p *gox.Package,
 	if enableRecover {
 		defer func() {
 			if e := recover(); e != nil {
-				ctx.handleRecover(e)
-				err = ctx.errs.ToError()
+				if ctx.aborted == nil || e != ctx.aborted {
+					ctx.recoverTop(e)
+				}
+				err = ctx.complete()
 			}
 		}()
 	}
+	ctx.initNolints(files)
+	if conf.KeepComments {
+		ctx.initStmtComments(files)
+	}
+	files = expandFiles(ctx, files)
 	p = gox.NewPackage(pkgPath, pkg.Name, confGox)
 	ctx.cpkgs = cpackages.NewImporter(&cpackages.Config{
 		Pkg: p, LookupPub: conf.LookupPub,
@@ -511,17 +686,20 @@ func NewPackage(pkgPath string, pkg *ast.Package, conf *Config) (p *gox.Package,
 			}
 		}
 	}
+	if ctx.gmxSettings != nil {
+		ctx.initWorkers(files)
+	}
 
 	for fpath, f := range files {
 		fileLine := !conf.NoFileLine
 		fileScope := types.NewScope(p.Types.Scope(), f.Pos(), f.End(), fpath)
 		ctx := &blockCtx{
 			pkg: p, pkgCtx: ctx, cb: p.CB(), relBaseDir: relBaseDir, fileScope: fileScope,
-			fileLine: fileLine, isClass: f.IsClass, rec: rec,
+			fileLine: fileLine, absFileLine: conf.AbsFileLine, isClass: f.IsClass, rec: rec,
 			c2goBase: c2goBase(conf.C2goBase), imports: make(map[string]pkgImp), isGopFile: true,
 		}
 		if rec := ctx.rec; rec != nil {
-			rec.Scope(f, fileScope)
+			rec.Scope(pkg.Files[fpath], fileScope)
 		}
 		preloadGopFile(p, ctx, fpath, f, conf)
 	}
@@ -585,7 +763,9 @@ func NewPackage(pkgPath string, pkg *ast.Package, conf *Config) (p *gox.Package,
 	if !conf.NoAutoGenMain && pkg.Name == "main" {
 		if obj := p.Types.Scope().Lookup("main"); obj == nil {
 			old, _ := p.SetCurFile(defaultGoFile, false)
-			p.NewFunc(nil, "main", nil, nil, false).BodyStart(p).End()
+			cb := p.NewFunc(nil, "main", nil, nil, false).BodyStart(p)
+			profileMain(p, ctx.profile, cb)
+			cb.End()
 			p.RestoreCurFile(old)
 		}
 	}
@@ -693,12 +873,13 @@ func preloadGopFile(p *gox.Package, ctx *blockCtx, file string, f *ast.File, con
 	var classType string
 	var baseTypeName string
 	var baseType types.Type
+	var baseObj types.Object
 	var spxClass bool
 	switch {
 	case f.IsProj:
 		classType = parent.gameClass
 		o := parent.game
-		baseTypeName, baseType = o.Name(), o.Type()
+		baseTypeName, baseType, baseObj = o.Name(), o.Type(), o
 		if parent.gameIsPtr {
 			baseType = types.NewPointer(baseType)
 		}
@@ -708,7 +889,7 @@ func preloadGopFile(p *gox.Package, ctx *blockCtx, file string, f *ast.File, con
 		if parent.gmxSettings != nil {
 			o, ok := parent.sprite[classExt]
 			if ok {
-				baseTypeName, baseType, spxClass = o.Name(), o.Type(), true
+				baseTypeName, baseType, baseObj, spxClass = o.Name(), o.Type(), o, true
 			}
 		}
 	}
@@ -757,7 +938,6 @@ func preloadGopFile(p *gox.Package, ctx *blockCtx, file string, f *ast.File, con
 				for _, v := range specs {
 					spec := v.(*ast.ValueSpec)
 					typ := toType(ctx, spec.Type)
-					tag := toFieldTag(spec.Tag)
 					if len(spec.Names) == 0 {
 						name := parseTypeEmbedName(spec.Type)
 						if chk.chkRedecl(ctx, name.Name, spec.Type.Pos()) {
@@ -768,7 +948,7 @@ func preloadGopFile(p *gox.Package, ctx *blockCtx, file string, f *ast.File, con
 							rec.Def(name, fld)
 						}
 						flds = append(flds, fld)
-						tags = append(tags, tag)
+						tags = append(tags, toFieldTag(ctx, spec.Tag, ""))
 					} else {
 						for _, name := range spec.Names {
 							if chk.chkRedecl(ctx, name.Name, name.Pos()) {
@@ -779,8 +959,18 @@ func preloadGopFile(p *gox.Package, ctx *blockCtx, file string, f *ast.File, con
 								rec.Def(name, fld)
 							}
 							flds = append(flds, fld)
-							tags = append(tags, tag)
+							tags = append(tags, toFieldTag(ctx, spec.Tag, name.Name))
+						}
+					}
+				}
+				if f.IsProj { // add fields of worker classes not declared by user
+					for _, name := range parent.workers {
+						if _, ok := chk.names[name]; ok {
+							continue
 						}
+						typ := toType(ctx, &ast.Ident{NamePos: pos, Name: name})
+						flds = append(flds, types.NewField(pos, pkg, name, typ, false))
+						tags = append(tags, "")
 					}
 				}
 				decl.InitType(p, types.NewStruct(flds, tags))
@@ -819,6 +1009,24 @@ func preloadGopFile(p *gox.Package, ctx *blockCtx, file string, f *ast.File, con
 	if d := f.ShadowEntry; d != nil {
 		d.Name.Name = getEntrypoint(f)
 	}
+	var handlers []*EventHandler
+	if baseType != nil && parent.bind != "" {
+		handlers = genEventBinding(ctx, f, parent.bind, baseTypeName, baseType)
+	}
+	if rec := ctx.recorder(); rec != nil && classType != "" {
+		if cr, ok := rec.Recorder.(ClassRecorder); ok {
+			info := &ClassInfo{File: file, Name: classType, Proj: f.IsProj, Base: baseObj, Handlers: handlers}
+			if parent.gmxSettings != nil {
+				info.Pkgs, info.Bind = parent.pkgPaths, parent.bind
+				if f.IsProj {
+					info.Workers = parent.workers
+				} else if spxClass {
+					info.Game = parent.gameClass
+				}
+			}
+			cr.Class(info)
+		}
+	}
 	preloadFile(p, ctx, file, f, true, !conf.Outline)
 }
 
@@ -923,10 +1131,13 @@ func preloadFile(p *gox.Package, ctx *blockCtx, file string, f *ast.File, gopFil
 								log.Println("==> Load > NewType", name)
 							}
 							decl := defs.NewType(name, tName)
-							if t.Doc != nil {
-								defs.SetComments(t.Doc)
-							} else if d.Doc != nil {
-								defs.SetComments(d.Doc)
+							doc := t.Doc
+							if doc == nil {
+								doc = d.Doc
+							}
+							if doc != nil {
+								defs.SetComments(doc)
+								setTypeDoc(ctx.pkg, decl.Type().Obj(), doc)
 							}
 							ld.typInit = func() { // decycle
 								if debugLoad {
@@ -963,6 +1174,9 @@ func preloadFile(p *gox.Package, ctx *blockCtx, file string, f *ast.File, gopFil
 						}
 					}
 				}
+				if d.Annots != nil {
+					checkImplements(ctx, d)
+				}
 			case token.CONST:
 				pkg := ctx.pkg
 				cdecl := pkg.NewConstDefs(pkg.Types.Scope())
@@ -1024,6 +1238,15 @@ func aliasType(pkg *types.Package, pos token.Pos, name string, typ types.Type) {
 	pkg.Scope().Insert(o)
 }
 
+// setTypeDoc records doc of a type in pkg.Docs, like gox does for functions
+// (see cl/outline).
+func setTypeDoc(pkg *gox.Package, o types.Object, doc *ast.CommentGroup) {
+	if pkg.Docs == nil {
+		pkg.Docs = make(gox.ObjectDocs)
+	}
+	pkg.Docs[o] = doc
+}
+
 func loadFunc(ctx *blockCtx, recv *types.Var, d *ast.FuncDecl, genBody bool) {
 	name := d.Name.Name
 	if debugLoad {
@@ -1051,6 +1274,9 @@ func loadFunc(ctx *blockCtx, recv *types.Var, d *ast.FuncDecl, genBody bool) {
 		}
 	}
 	sig := toFuncType(ctx, d.Type, recv, d)
+	if annot, ok := ctx.memoized[d]; ok {
+		checkMemoized(ctx, annot, sig)
+	}
 	fn, err := pkg.NewFuncWith(d.Name.Pos(), name, sig, func() token.Pos {
 		return d.Recv.List[0].Type.Pos()
 	})
@@ -1130,7 +1356,11 @@ var unaryGopNames = map[string]string{
 
 func loadFuncBody(ctx *blockCtx, fn *gox.Func, body *ast.BlockStmt, src ast.Node) {
 	cb := fn.BodyStart(ctx.pkg, body)
+	if fn.Name() == "main" && ctx.pkg.Types.Name() == "main" && fn.Type().(*types.Signature).Recv() == nil {
+		profileMain(ctx.pkg, ctx.profile, cb)
+	}
 	compileStmts(ctx, body.List)
+	checkMissingReturn(ctx, fn.Type().(*types.Signature), body)
 	cb.End(src)
 }
 
@@ -1242,6 +1472,9 @@ func loadVars(ctx *blockCtx, v *ast.ValueSpec, doc *ast.CommentGroup, global boo
 	} else {
 		scope = ctx.cb.Scope()
 	}
+	if global && ctx.fileLine && len(v.Values) > 0 && v.Pos() != token.NoPos { // initialization may panic
+		doc = fileLineDoc(ctx, doc, v.Pos(), false)
+	}
 	varDefs := ctx.pkg.NewVarDefs(scope).SetComments(doc)
 	varDecl := varDefs.New(v.Names[0].Pos(), typ, names...)
 	if nv := len(v.Values); nv > 0 {
@@ -42,6 +42,11 @@ func lookupClass(ext string) (c *modfile.Project, ok bool) {
 			Works: []*modfile.Class{{Ext: ".t2spx", Class: "Sprite"},
 				{Ext: ".t2spx2", Class: "Sprite2"}},
 			PkgPaths: []string{"github.com/goplus/gop/cl/internal/spx2"}}, true
+	case ".t4gmx", ".t4spx":
+		return &modfile.Project{
+			Ext: ".t4gmx", Class: "Game",
+			Works:    []*modfile.Class{{Ext: ".t4spx", Class: "Sprite"}},
+			PkgPaths: []string{"github.com/goplus/gop/cl/internal/spx3"}}, true
 	case "_t3spx.gox", ".t3spx2":
 		return &modfile.Project{
 			Works: []*modfile.Class{{Ext: "_t3spx.gox", Class: "Sprite"},
@@ -892,3 +897,111 @@ func (this *Kai) Main() {
 }
 `, "Game.tgmx", "Kai.tspx")
 }
+
+func TestSpxEventBinding(t *testing.T) {
+	gopSpxTestEx(t, `
+var (
+	Score int
+)
+
+func onStart() {
+	println "start"
+}
+`, `
+func onClick() {
+	Score++
+}
+
+func onKey(key string) {
+	println key
+}
+
+func onMsg(msg string) {
+}
+`, `package main
+
+import (
+	"fmt"
+	"github.com/goplus/gop/cl/internal/spx3"
+)
+
+type Game struct {
+	spx3.Game
+	Score int
+	Kai   Kai
+}
+type Kai struct {
+	spx3.Sprite
+	*Game
+}
+
+func (this *Game) onStart() {
+	fmt.Println("start")
+}
+func (this *Game) Gop_Bind() {
+	this.OnStart(this.onStart)
+}
+func (this *Kai) onClick() {
+	this.Score++
+}
+func (this *Kai) onKey(key string) {
+	fmt.Println(key)
+}
+func (this *Kai) onMsg(msg string) {
+}
+func (this *Kai) Gop_Bind() {
+	this.OnClick(this.onClick)
+	spx3.Gopt_Sprite_OnKey__0(this, this.onKey)
+}
+`, "main.t4gmx", "Kai.t4spx")
+}
+
+type classRecorder struct {
+	gopRecorder
+	classes map[string]*cl.ClassInfo
+}
+
+func (p classRecorder) Class(info *cl.ClassInfo) {
+	p.classes[info.File] = info
+}
+
+func TestSpxClassRecorder(t *testing.T) {
+	fs := memfs.TwoFiles("/foo", "Kai.t4spx", `
+func onClick() {
+}
+
+func onMsg(msg string) {
+}
+`, "main.t4gmx", `
+var (
+	Kai Kai
+)
+
+func onStart() {
+}
+`)
+	pkgs, err := parser.ParseFSDir(gblFset, fs, "/foo", spxParserConf())
+	if err != nil {
+		t.Fatal("ParseFSDir:", err)
+	}
+	rec := classRecorder{classes: make(map[string]*cl.ClassInfo)}
+	conf := *gblConf
+	conf.Recorder = rec
+	if _, err = cl.NewPackage("", pkgs["main"], &conf); err != nil {
+		t.Fatal("NewPackage:", err)
+	}
+	game, kai := rec.classes["/foo/main.t4gmx"], rec.classes["/foo/Kai.t4spx"]
+	if game == nil || kai == nil {
+		t.Fatal("TestSpxClassRecorder:", rec.classes)
+	}
+	if game.Name != "Game" || !game.Proj || game.Base.Name() != "Game" || game.Bind != "Gop_Bind" ||
+		len(game.Workers) != 1 || game.Workers[0] != "Kai" || game.Game != "" ||
+		len(game.Handlers) != 1 || game.Handlers[0].Name.Name != "onStart" || game.Handlers[0].Binder != "OnStart" {
+		t.Fatalf("TestSpxClassRecorder: game = %+v\n", game)
+	}
+	if kai.Name != "Kai" || kai.Proj || kai.Base.Name() != "Sprite" || kai.Game != "Game" ||
+		len(kai.Pkgs) != 1 || kai.Pkgs[0] != "github.com/goplus/gop/cl/internal/spx3" ||
+		len(kai.Handlers) != 1 || kai.Handlers[0].Name.Name != "onClick" || kai.Handlers[0].Binder != "OnClick" {
+		t.Fatalf("TestSpxClassRecorder: kai = %+v\n", kai)
+	}
+}
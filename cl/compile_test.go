@@ -219,6 +219,222 @@ func main() {
 `)
 }
 
+func TestAssertBuiltins(t *testing.T) {
+	gopClTest(t, `
+func div(a, b int) int {
+	assert b != 0, "div by %d", b
+	return a / b
+}
+
+func parse(s string) int {
+	if s == "" {
+		unreachable()
+	}
+	todo "parse", s
+	return 0
+}
+
+assert div(4, 2) == 2
+`, `package main
+
+import "github.com/goplus/gop/builtin"
+
+func div(a int, b int) int {
+	if builtin.Assertions && !(b != 0) {
+		panic(builtin.AssertFailed("div by %d", b))
+	}
+	return a / b
+}
+func parse(s string) int {
+	if s == "" {
+		builtin.Unreachable()
+	}
+	builtin.Todo("parse", s)
+	return 0
+}
+func main() {
+	if builtin.Assertions && !(div(4, 2) == 2) {
+		panic(builtin.AssertFailed())
+	}
+}
+`)
+	gopClTest(t, `
+func assert(cond bool, msg string) {
+}
+
+assert true, "not the builtin"
+`, `package main
+
+func assert(cond bool, msg string) {
+}
+func main() {
+	assert(true, "not the builtin")
+}
+`)
+}
+
+func TestLogBuiltins(t *testing.T) {
+	gopClTest(t, `
+addr := "localhost"
+logInfo "connected", "addr", addr
+if addr == "" {
+	logWarn("no address")
+}
+logError "failed", "err", errorf("timeout")
+`, `package main
+
+import (
+	"fmt"
+	"github.com/goplus/gop/builtin"
+)
+
+func main() {
+	addr := "localhost"
+	builtin.LogInfo("connected", "addr", addr)
+	if addr == "" {
+		builtin.LogWarn("no address")
+	}
+	builtin.LogError("failed", "err", fmt.Errorf("timeout"))
+}
+`)
+}
+
+func TestRetryBuiltins(t *testing.T) {
+	gopClTest(t, `
+import (
+	"context"
+	"time"
+)
+
+func upload() error {
+	return nil
+}
+
+err := retry(3, => {
+	return upload()
+})
+retry 5, backoff(time.Second).max(time.Minute), => {
+	return upload()
+}
+err = retry(context.TODO(), 5, nil, upload)
+println err
+`, `package main
+
+import (
+	"fmt"
+	"github.com/goplus/gop/builtin"
+	"context"
+	"time"
+)
+
+func upload() error {
+	return nil
+}
+func main() {
+	err := builtin.Retry__0(3, func() error {
+		return upload()
+	})
+	builtin.Retry__1(5, builtin.NewBackoff(time.Second).Max(time.Minute), func() error {
+		return upload()
+	})
+	err = builtin.Retry__2(context.TODO(), 5, nil, upload)
+	fmt.Println(err)
+}
+`)
+}
+
+func TestParallelBuiltins(t *testing.T) {
+	gopClTest(t, `
+import "os"
+
+files := []string{"a.txt", "b.txt"}
+sizes := pmap(files, 8, file => len(file))
+names := pmap(files, 0, file => sprint(file, ":", len(file)))
+datas, err := pmap(files, 4, os.ReadFile)
+pfor files, 8, file => {
+	println file
+}
+err = pfor(files, 8, file => {
+	return os.Remove(file)
+})
+println sizes, names, datas, err
+`, `package main
+
+import (
+	"fmt"
+	"os"
+	"github.com/goplus/gop/builtin"
+)
+
+func main() {
+	files := []string{"a.txt", "b.txt"}
+	sizes := builtin.Pmap__0(files, 8, func(file string) int {
+		return len(file)
+	})
+	names := builtin.Pmap__0(files, 0, func(file string) string {
+		return fmt.Sprint(file, ":", len(file))
+	})
+	datas, err := builtin.Pmap__1(files, 4, os.ReadFile)
+	builtin.Pfor__0(files, 8, func(file string) {
+		fmt.Println(file)
+	})
+	err = builtin.Pfor__1(files, 8, func(file string) error {
+		return os.Remove(file)
+	})
+	fmt.Println(sizes, names, datas, err)
+}
+`)
+}
+
+func TestSelectAfter(t *testing.T) {
+	gopClTest(t, `
+ch1, ch2 := make(chan int), make(chan int)
+var in <-chan int = ch1
+timeouts := [1h30m, 1.5s, 100ms, 2us, 0s, 1_000ns]
+for {
+	select {
+	case v, ok := <-fanIn(ch1, ch2):
+		if !ok {
+			return
+		}
+		println v
+	case w := <-fanIn(in, in):
+		println w
+	after 5s:
+		println "timeout", timeouts
+		return
+	}
+}
+`, `package main
+
+import (
+	"fmt"
+	"github.com/goplus/gop/builtin"
+	"time"
+)
+
+func main() {
+	ch1, ch2 := make(chan int), make(chan int)
+	var in <-chan int = ch1
+	timeouts := []time.Duration{90 * time.Minute, 1500 * time.Millisecond, 100 * time.Millisecond, 2 * time.Microsecond, time.Duration(0), time.Microsecond}
+	for {
+		select {
+		case v, ok := <-builtin.FanIn__0(ch1, ch2):
+			if !ok {
+				return
+			}
+			fmt.Println(v)
+		case w := <-builtin.FanIn__1(in, in):
+			fmt.Println(w)
+		case <-time.After(5 * time.Second):
+			fmt.Println("timeout", timeouts)
+			return
+		}
+	}
+}
+`)
+}
+
 func TestIoxLines(t *testing.T) {
 	gopClTest(t, `
 import "io"
@@ -2171,12 +2387,12 @@ func TestStructLit(t *testing.T) {
 	gopClTest(t, `
 type foo struct {
 	A int
-	B string "tag1:123"
+	B string "tag1:\"123\""
 }
 
 a := struct {
 	A int
-	B string "tag1:123"
+	B string "tag1:\"123\""
 }{1, "Hello"}
 
 b := foo{1, "Hello"}
@@ -2185,13 +2401,13 @@ c := foo{B: "Hi"}
 
 type foo struct {
 	A int
-	B string `+"`tag1:123`"+`
+	B string `+"`tag1:\"123\"`"+`
 }
 
 func main() {
 	a := struct {
 		A int
-		B string `+"`tag1:123`"+`
+		B string `+"`tag1:\"123\"`"+`
 	}{1, "Hello"}
 	b := foo{1, "Hello"}
 	c := foo{B: "Hi"}
@@ -2206,7 +2422,7 @@ type bar = foo
 type foo struct {
 	p *bar
 	A int
-	B string "tag1:123"
+	B string "tag1:\"123\""
 }
 
 func main() {
@@ -2221,7 +2437,7 @@ type bar = foo
 type foo struct {
 	p *foo
 	A int
-	B string `+"`tag1:123`"+`
+	B string `+"`tag1:\"123\"`"+`
 }
 
 func main() {
@@ -2233,6 +2449,24 @@ func main() {
 `)
 }
 
+func TestStructTag(t *testing.T) {
+	gopClTest(t, `
+type User struct {
+	Name      string `+"`json`"+`
+	ID, Email string `+"`json  db:\"x\"`"+`
+	Age       int    `+"`json:\"age,omitempty\"`"+`
+}
+`, `package main
+
+type User struct {
+	Name  string `+"`json:\"name\"`"+`
+	ID    string `+"`json:\"iD\" db:\"x\"`"+`
+	Email string `+"`json:\"email\" db:\"x\"`"+`
+	Age   int    `+"`json:\"age,omitempty\"`"+`
+}
+`)
+}
+
 func TestDeferGo(t *testing.T) {
 	gopClTest(t, `
 go println("Hi")
@@ -4712,6 +4946,37 @@ func main() {
 	gopClTestEx(t, &conf, "main", src, expected)
 }
 
+func TestCommentLineVar(t *testing.T) {
+	conf := *gblConf
+	conf.NoFileLine = false
+	conf.RelativeBase = "/foo/root"
+	conf.AbsFileLine = true
+	gopClTestEx(t, &conf, "main", `
+var a = []int{1}
+var n int
+
+// b may panic
+var b = a[n]
+
+println b
+`, `package main
+
+import "fmt"
+//line /foo/bar.gop:2:1
+var a = []int{1}
+var n int
+// b may panic
+//
+//line /foo/bar.gop:6:1
+var b = a[n]
+//line /foo/bar.gop:8
+func main() {
+//line /foo/bar.gop:8:1
+	fmt.Println(b)
+}
+`)
+}
+
 func TestMixedOverload(t *testing.T) {
 	gopMixedClTest(t, "main", `
 package main
@@ -5112,3 +5377,30 @@ func main() {
 }
 `)
 }
+
+func TestPipeline(t *testing.T) {
+	gopClTest(t, `//gop:enable pipeline
+import "strings"
+
+func double(x int) int {
+	return x * 2
+}
+
+"a,b" |> strings.Split(",") |> println
+x := 3 |> double |> double
+`, `package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+func double(x int) int {
+	return x * 2
+}
+func main() {
+	fmt.Println(strings.Split("a,b", ","))
+	x := double(double(3))
+}
+`)
+}
@@ -0,0 +1,121 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cl_test
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/goplus/gop/cl"
+	"github.com/goplus/gop/parser"
+	"github.com/goplus/gop/scanner"
+	"github.com/goplus/gop/x/diff"
+)
+
+var update = flag.Bool("update", false, "update expected results of the corpus in _testdata")
+
+const (
+	corpusDir  = "_testdata"
+	outExpect  = "out.expect" // expected Go code
+	diagExpect = "err.expect" // expected errors and warnings
+)
+
+// TestCorpus compiles each package in _testdata, and checks the generated Go
+// code by out.expect and diagnostics (errors and warnings) by err.expect. A
+// missing expect file means no output is expected. Run with -update to
+// write results to expect files, and review them by `git diff`.
+func TestCorpus(t *testing.T) {
+	fis, err := os.ReadDir(corpusDir)
+	if err != nil {
+		t.Fatal("ReadDir failed:", err)
+	}
+	for _, fi := range fis {
+		name := fi.Name()
+		if !fi.IsDir() || strings.HasPrefix(name, "_") {
+			continue
+		}
+		t.Run(name, func(t *testing.T) {
+			dir := filepath.Join(corpusDir, name)
+			out, diag := compileCorpus(dir)
+			checkCorpus(t, filepath.Join(dir, outExpect), out)
+			checkCorpus(t, filepath.Join(dir, diagExpect), diag)
+		})
+	}
+}
+
+// compileCorpus compiles the package in dir, and returns the generated Go
+// code and diagnostics with positions relative to dir.
+func compileCorpus(dir string) (out, diag []byte) {
+	var diags bytes.Buffer
+	pkgs, err := parser.ParseDirEx(gblFset, dir, spxParserConf())
+	if err == nil {
+		for _, pkg := range pkgs {
+			conf := *gblConf
+			conf.RelativeBase = dir
+			conf.OnWarning = func(err error) {
+				diags.WriteString("warning: " + err.Error() + "\n")
+			}
+			ret, e := cl.NewPackage("", pkg, &conf)
+			if err = e; err == nil {
+				var b bytes.Buffer
+				if err = ret.WriteTo(&b); err == nil {
+					out = b.Bytes()
+				}
+			}
+			break
+		}
+	}
+	if errs, ok := err.(scanner.ErrorList); ok { // all syntax errors
+		for _, e := range errs {
+			diags.WriteString(e.Error() + "\n")
+		}
+	} else if err != nil {
+		diags.WriteString(err.Error() + "\n")
+	}
+	diag = bytes.ReplaceAll(diags.Bytes(), []byte(dir+string(filepath.Separator)), nil)
+	return
+}
+
+func checkCorpus(t *testing.T, file string, ret []byte) {
+	t.Helper()
+	if *update {
+		var err error
+		if ret == nil {
+			err = os.Remove(file)
+			if os.IsNotExist(err) {
+				err = nil
+			}
+		} else {
+			err = os.WriteFile(file, ret, 0644)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	expected, err := os.ReadFile(file)
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(ret, expected) {
+		t.Fatalf("%s mismatched (run with -update to accept):\n%s", file, diff.Unified(file, "result", expected, ret))
+	}
+}
@@ -0,0 +1,215 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cl
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/goplus/gop/ast"
+	"github.com/goplus/gop/token"
+	"github.com/goplus/gox"
+	xerrors "github.com/qiniu/x/errors"
+)
+
+// -----------------------------------------------------------------------------
+
+// Severity represents the severity of a diagnostic.
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+)
+
+// TextEdit is a machine-applicable edit which replaces source text in
+// [Pos, End) by NewText. Pos == End means an insertion.
+type TextEdit struct {
+	Pos     token.Pos
+	End     token.Pos
+	NewText string
+}
+
+// Insert returns an edit that inserts text at pos.
+func Insert(pos token.Pos, text string) TextEdit {
+	return TextEdit{Pos: pos, End: pos, NewText: text}
+}
+
+// Replace returns an edit that replaces source text in [pos, end) by text.
+func Replace(pos, end token.Pos, text string) TextEdit {
+	return TextEdit{Pos: pos, End: end, NewText: text}
+}
+
+// Delete returns an edit that deletes source text in [pos, end).
+func Delete(pos, end token.Pos) TextEdit {
+	return TextEdit{Pos: pos, End: end}
+}
+
+// SuggestedFix is a compiler-suggested fix, which consists of edits that
+// should be applied together.
+type SuggestedFix struct {
+	Message string
+	Edits   []TextEdit
+}
+
+// Diagnostic is an error or a warning reported by the compiler, optionally
+// with suggested fixes. Tools like `gop vet -fix`, gopfmt or a language
+// server can apply these fixes by ApplyEdits.
+type Diagnostic struct {
+	gox.CodeError
+	End      token.Pos // end of the diagnostic range (optional)
+	Severity Severity
+	Code     string // name of the check, eg. "exhaustive" (optional)
+	Fixes    []SuggestedFix
+}
+
+// Fix adds a suggested fix to the diagnostic.
+func (p *Diagnostic) Fix(msg string, edits ...TextEdit) *Diagnostic {
+	p.Fixes = append(p.Fixes, SuggestedFix{Message: msg, Edits: edits})
+	return p
+}
+
+// Unwrap returns the underlying code error.
+func (p *Diagnostic) Unwrap() error {
+	return &p.CodeError
+}
+
+// Diagnostics returns all diagnostics in err, which is usually returned by
+// NewPackage or passed to Config.OnWarning.
+func Diagnostics(err error) (ret []*Diagnostic) {
+	var list xerrors.List
+	if errors.As(err, &list) {
+		for _, e := range list {
+			ret = append(ret, Diagnostics(e)...)
+		}
+		return
+	}
+	var d *Diagnostic
+	if errors.As(err, &d) {
+		ret = append(ret, d)
+	}
+	return
+}
+
+// ApplyEdits applies edits to src which is the content of file f. Edits must
+// be in f and must not overlap.
+func ApplyEdits(f *token.File, src []byte, edits []TextEdit) ([]byte, error) {
+	type edit struct {
+		start, end int
+		text       string
+	}
+	list := make([]edit, len(edits))
+	base, size := f.Base(), f.Size()
+	for i, e := range edits {
+		start, end := int(e.Pos)-base, int(e.End)-base
+		if start < 0 || start > end || end > size {
+			return nil, fmt.Errorf("edit %d out of file %s", i, f.Name())
+		}
+		list[i] = edit{start, end, e.NewText}
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].start < list[j].start
+	})
+	var b bytes.Buffer
+	last := 0
+	for _, e := range list {
+		if e.start < last {
+			return nil, fmt.Errorf("overlapped edits at %v", f.Position(f.Pos(e.start)))
+		}
+		b.Write(src[last:e.start])
+		b.WriteString(e.text)
+		last = e.end
+	}
+	b.Write(src[last:])
+	return b.Bytes(), nil
+}
+
+// -----------------------------------------------------------------------------
+
+func (p *pkgCtx) newDiagf(sev Severity, pos, end token.Pos, format string, args ...interface{}) *Diagnostic {
+	return &Diagnostic{
+		CodeError: gox.CodeError{Fset: p.nodeInterp, Pos: pos, Msg: fmt.Sprintf(format, args...)},
+		End:       end,
+		Severity:  sev,
+	}
+}
+
+func (p *pkgCtx) handleDiag(d *Diagnostic) {
+	if d.Severity == SeverityWarning && p.nolint(d) {
+		return
+	}
+	if d.Severity == SeverityWarning && !p.strict {
+		if p.onWarning != nil {
+			p.onWarning(d)
+		}
+		return
+	}
+	p.handleErr(d)
+}
+
+type nolintKey struct {
+	file string
+	line int
+}
+
+// initNolints collects nolint directives of files. A directive suppresses
+// warnings reported at its own line and the next line.
+func (p *pkgCtx) initNolints(files map[string]*ast.File) {
+	for _, f := range files {
+		for _, cg := range f.Comments {
+			for _, c := range cg.List {
+				names, ok := ast.Nolint(c.Text)
+				if !ok {
+					continue
+				}
+				if p.nolints == nil {
+					p.nolints = make(map[nolintKey][]string)
+				}
+				pos := p.fset.Position(c.Pos())
+				for _, line := range [2]int{pos.Line, pos.Line + 1} {
+					key := nolintKey{pos.Filename, line}
+					if old, ok := p.nolints[key]; !ok || (old != nil && names != nil) {
+						p.nolints[key] = append(old, names...)
+					} else {
+						p.nolints[key] = nil
+					}
+				}
+			}
+		}
+	}
+}
+
+func (p *pkgCtx) nolint(d *Diagnostic) bool {
+	pos := p.fset.Position(d.Pos)
+	names, ok := p.nolints[nolintKey{pos.Filename, pos.Line}]
+	if !ok {
+		return false
+	}
+	if names == nil {
+		return true
+	}
+	for _, name := range names {
+		if name == d.Code {
+			return true
+		}
+	}
+	return false
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,227 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cl_test
+
+import (
+	"testing"
+
+	"github.com/goplus/gop/ast"
+	"github.com/goplus/gop/cl"
+	"github.com/goplus/gop/parser"
+	"github.com/goplus/gop/token"
+)
+
+func fixTest(t *testing.T, src, expected string) {
+	fixTestEx(t, false, src, expected)
+}
+
+func fixTestEx(t *testing.T, strict bool, src, expected string) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "bar.gop", src, 0)
+	if err != nil {
+		t.Fatal("parser.ParseFile failed:", err)
+	}
+	pkg := &ast.Package{
+		Name:  "main",
+		Files: map[string]*ast.File{"bar.gop": f},
+	}
+	var diags []*cl.Diagnostic
+	conf := *gblConf
+	conf.Fset = fset
+	conf.Strict = strict
+	conf.OnWarning = func(err error) {
+		diags = append(diags, cl.Diagnostics(err)...)
+	}
+	_, err = cl.NewPackage("", pkg, &conf)
+	diags = append(diags, cl.Diagnostics(err)...)
+	if len(diags) != 1 || len(diags[0].Fixes) != 1 {
+		t.Fatal("unexpected diagnostics:", diags, err)
+	}
+	ret, err := cl.ApplyEdits(fset.File(f.Pos()), []byte(src), diags[0].Fixes[0].Edits)
+	if err != nil {
+		t.Fatal("cl.ApplyEdits failed:", err)
+	}
+	if string(ret) != expected {
+		t.Fatalf("\nResult:\n%s\nExpected:\n%s\n", ret, expected)
+	}
+}
+
+func TestFixExhaustive(t *testing.T) {
+	fixTest(t, `import "time"
+
+func f(m time.Month) {
+	switch m {
+	case time.January, time.February, time.March, time.April, time.May, time.June:
+	case time.July, time.August, time.September, time.October:
+	}
+}
+`, `import "time"
+
+func f(m time.Month) {
+	switch m {
+	case time.January, time.February, time.March, time.April, time.May, time.June:
+	case time.July, time.August, time.September, time.October:
+	case time.November, time.December:
+}
+}
+`)
+}
+
+func TestFixMissingReturn(t *testing.T) {
+	fixTest(t, `import "strconv"
+
+func f(s string) (int, error) {
+	if s != "" {
+		return strconv.Atoi(s)
+	}
+}
+`, `import "strconv"
+
+func f(s string) (int, error) {
+	if s != "" {
+		return strconv.Atoi(s)
+	}
+	return 0, nil
+}
+`)
+	fixTest(t, `type Point struct {
+	X, Y int
+}
+
+func f(s string) (p Point, ok bool) {
+	for s != "" {
+		return
+	}
+}
+`, `type Point struct {
+	X, Y int
+}
+
+func f(s string) (p Point, ok bool) {
+	for s != "" {
+		return
+	}
+	return
+}
+`)
+	fixTest(t, `type Point struct {
+	X, Y int
+}
+
+func f() (Point, string) { println "f" }
+`, `type Point struct {
+	X, Y int
+}
+
+func f() (Point, string) { println "f" ; return Point{}, "" }
+`)
+	fixTest(t, `func f(ch chan int) (bool, []int) {
+	for {
+		select {
+		case <-ch:
+			break
+		}
+	}
+	panic("unreachable")
+	for {
+		break
+	}
+}
+`, `func f(ch chan int) (bool, []int) {
+	for {
+		select {
+		case <-ch:
+			break
+		}
+	}
+	panic("unreachable")
+	for {
+		break
+	}
+	return false, nil
+}
+`)
+}
+
+func TestFixErrIgnored(t *testing.T) {
+	fixTestEx(t, true, `import "strconv"
+
+func f(s string) error {
+	println "parse", s
+	strconv.Atoi(s)
+	return nil
+}
+`, `import "strconv"
+
+func f(s string) error {
+	println "parse", s
+	_ = strconv.Atoi(s)?
+	return nil
+}
+`)
+	fixTestEx(t, true, `import "strconv"
+
+strconv.ParseBool("true")
+`, `import "strconv"
+
+strconv.ParseBool("true")!
+`)
+}
+
+func TestFixImplements(t *testing.T) {
+	fixTest(t, `import "io"
+
+@implements(io.ReadCloser)
+type T struct{}
+
+func (T) Close() error {
+	return nil
+}
+`, `import "io"
+
+@implements(io.ReadCloser)
+type T struct{}
+
+func (*T) Read(p []byte) (n int, err error) {
+	panic("TODO: implement")
+}
+
+func (T) Close() error {
+	return nil
+}
+`)
+}
+
+func TestApplyEdits(t *testing.T) {
+	fset := token.NewFileSet()
+	f := fset.AddFile("foo.gop", -1, 10)
+	src := []byte("0123456789")
+	ret, err := cl.ApplyEdits(f, src, []cl.TextEdit{
+		cl.Replace(f.Pos(8), f.Pos(10), "ab"), cl.Delete(f.Pos(1), f.Pos(3)), cl.Insert(f.Pos(0), "x"),
+	})
+	if err != nil || string(ret) != "x034567ab" {
+		t.Fatal("ApplyEdits:", string(ret), err)
+	}
+	if _, err = cl.ApplyEdits(f, src, []cl.TextEdit{
+		cl.Delete(f.Pos(1), f.Pos(3)), cl.Delete(f.Pos(2), f.Pos(4)),
+	}); err == nil {
+		t.Fatal("ApplyEdits: no overlapped error?")
+	}
+	if _, err = cl.ApplyEdits(f, src, []cl.TextEdit{cl.Insert(f.Pos(5)+10, "x")}); err == nil {
+		t.Fatal("ApplyEdits: no out of file error?")
+	}
+}
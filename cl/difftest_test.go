@@ -0,0 +1,60 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cl_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/goplus/gop/x/diff"
+)
+
+const diffTestDir = "_difftest"
+
+// TestDifferential runs each Go+ program x.gop in _difftest and its
+// semantically-equivalent Go program x.go, and checks that they print the
+// same output. It catches regressions of semantics in generated code (eg.
+// integer overflow and evaluation order) without writing expected results.
+func TestDifferential(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping differential testing in short mode")
+	}
+	files, err := filepath.Glob(filepath.Join(diffTestDir, "*.gop"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".gop")
+		t.Run(name, func(t *testing.T) {
+			gopcode, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			gocode, err := os.ReadFile(strings.TrimSuffix(file, ".gop") + ".go")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := goRun(t, genGo(t, gblConf, string(gopcode)))
+			expected := goRun(t, gocode)
+			if got != expected {
+				t.Fatalf("output of %s differs from Go:\n%s", file, diff.Unified(name+".go", name+".gop", []byte(expected), []byte(got)))
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cl
+
+import (
+	"go/types"
+	"sort"
+	"strings"
+
+	"github.com/goplus/gop/ast"
+)
+
+// -----------------------------------------------------------------------------
+
+// EnumConsts returns constants of an enum-style type, that is a named type
+// whose underlying type is an integer or a string, and constants of exactly
+// this type declared in its package. Unexported constants are included only
+// if the type is declared in the package `local`.
+// The result is sorted in declaration order.
+func EnumConsts(typ types.Type, local *types.Package) []*types.Const {
+	t, ok := typ.(*types.Named)
+	if !ok {
+		return nil
+	}
+	if b, ok := t.Underlying().(*types.Basic); !ok || b.Info()&(types.IsInteger|types.IsString) == 0 {
+		return nil
+	}
+	o := t.Obj()
+	pkg := o.Pkg()
+	if pkg == nil {
+		return nil
+	}
+	var ret []*types.Const
+	scope := pkg.Scope()
+	for _, name := range scope.Names() {
+		c, ok := scope.Lookup(name).(*types.Const)
+		if !ok || c.Name() == "_" || !types.Identical(c.Type(), typ) {
+			continue
+		}
+		if pkg != local && !c.Exported() {
+			continue
+		}
+		ret = append(ret, c)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if pi, pj := ret[i].Pos(), ret[j].Pos(); pi != pj {
+			return pi < pj
+		}
+		return ret[i].Name() < ret[j].Name()
+	})
+	return ret
+}
+
+// checkExhaustive warns if an enum-style switch without default case doesn't
+// cover all constants of the enum type.
+func checkExhaustive(ctx *blockCtx, v *ast.SwitchStmt, tag types.Type, seen valueMap) {
+	var missing, cases []string
+	qf := types.RelativeTo(ctx.pkg.Types)
+	done := make(map[interface{}]bool)
+	for _, c := range EnumConsts(tag, ctx.pkg.Types) {
+		val := goVal(c.Val())
+		if val == nil || done[val] {
+			continue
+		}
+		done[val] = true
+		if !seen.has(val, tag) {
+			name := c.Name()
+			missing = append(missing, name)
+			if pkg := qf(c.Pkg()); pkg != "" {
+				name = pkg + "." + name
+			}
+			cases = append(cases, name)
+		}
+	}
+	if missing != nil {
+		d := ctx.newDiagf(SeverityWarning, v.Pos(), v.Body.Lbrace, "missing cases in switch of type %s: %s",
+			types.TypeString(tag, qf), strings.Join(missing, ", "))
+		d.Code = "exhaustive"
+		d.Fix("add missing cases", Insert(v.Body.Rbrace, "case "+strings.Join(cases, ", ")+":\n"))
+		ctx.handleDiag(d)
+	}
+}
+
+func (p valueMap) has(val interface{}, typ types.Type) bool {
+	for _, vt := range p[val] {
+		if types.Identical(typ, vt.typ) {
+			return true
+		}
+	}
+	return false
+}
+
+// -----------------------------------------------------------------------------
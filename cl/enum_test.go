@@ -0,0 +1,138 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cl_test
+
+import (
+	"go/types"
+	"reflect"
+	"testing"
+
+	"github.com/goplus/gop/ast"
+	"github.com/goplus/gop/cl"
+	"github.com/goplus/gop/parser"
+)
+
+func warnTest(t *testing.T, src string, expected ...string) {
+	f, err := parser.ParseFile(gblFset, "bar.gop", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal("parser.ParseFile failed:", err)
+	}
+	pkg := &ast.Package{
+		Name:  "main",
+		Files: map[string]*ast.File{"bar.gop": f},
+	}
+	var warns []string
+	conf := *gblConf
+	conf.RelativeBase = "/foo"
+	conf.OnWarning = func(err error) {
+		warns = append(warns, err.Error())
+	}
+	if _, err = cl.NewPackage("", pkg, &conf); err != nil {
+		t.Fatal("cl.NewPackage failed:", err)
+	}
+	if !reflect.DeepEqual(warns, expected) {
+		t.Fatalf("\nWarnings: %q\nExpected: %q\n", warns, expected)
+	}
+}
+
+func TestEnumSwitch(t *testing.T) {
+	const enum = `
+type Color int
+
+const (
+	Red Color = iota
+	Green
+	Blue
+	Crimson = Red
+)
+`
+	warnTest(t, enum+`
+func f(c Color) {
+	switch c {
+	case Red:
+	case Blue:
+	}
+}
+`, "bar.gop:12:2: missing cases in switch of type Color: Green")
+	warnTest(t, enum+`
+func f(c Color) {
+	switch c {
+	case Crimson, Green, Blue:
+	}
+	switch c {
+	case Red:
+	default:
+	}
+	switch {
+	case c == Red:
+	}
+}
+`)
+	warnTest(t, enum+`
+func f(c Color) {
+	switch c { //nolint:exhaustive
+	case Red:
+	}
+	//nolint
+	switch c {
+	case Red:
+	}
+	switch c { //nolint:other,implements
+	case Red, Green:
+	}
+}
+`, "bar.gop:19:2: missing cases in switch of type Color: Blue")
+	warnTest(t, `
+import "time"
+
+func f(m time.Month) {
+	switch m {
+	case time.January, time.February, time.March, time.April, time.May, time.June:
+	case time.July, time.August, time.September, time.October, time.November:
+	}
+}
+`, "bar.gop:5:2: missing cases in switch of type time.Month: December")
+}
+
+func TestErrEnumSwitch(t *testing.T) {
+	conf := *gblConf
+	gblConf.Strict = true
+	defer func() {
+		*gblConf = conf
+	}()
+	codeErrorTest(t, `bar.gop:11:2: missing cases in switch of type Color: Green, Blue`, `
+type Color string
+
+const (
+	Red   Color = "red"
+	Green Color = "green"
+	Blue  Color = "blue"
+)
+
+func f(c Color) {
+	switch c {
+	case Red:
+	}
+}
+`)
+}
+
+func TestEnumConsts(t *testing.T) {
+	if ret := cl.EnumConsts(types.Typ[types.Int], nil); ret != nil {
+		t.Fatal("EnumConsts(int):", ret)
+	}
+}
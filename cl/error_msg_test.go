@@ -17,10 +17,13 @@
 package cl_test
 
 import (
+	"context"
 	"fmt"
+	"go/types"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
 
 	"github.com/goplus/gop/ast"
@@ -995,3 +998,113 @@ type
 a := 1
 `)
 }
+
+func TestErrStructTag(t *testing.T) {
+	codeErrorTest(t, `bar.gop:3:11: malformed struct tag "tag1:123": bad syntax for struct tag value of key tag1`, `
+type T struct {
+	A string "tag1:123"
+}
+`)
+	codeErrorTest(t, "bar.gop:3:11: malformed struct tag `json json:\"a\"`: duplicate struct tag key json", `
+type T struct {
+	A string `+"`json json:\"a\"`"+`
+}
+`)
+	codeErrorTest(t, "bar.gop:4:16: malformed struct tag `json`: struct tag key json without value requires a field name", `
+import "bytes"
+type T struct {
+	*bytes.Buffer `+"`json`"+`
+}
+`)
+	codeErrorTest(t, "bar.gop:3:8: malformed struct tag `a:\"1\"b:\"2\"`: struct tag pairs must be separated by spaces", `
+type T struct {
+	A int `+"`a:\"1\"b:\"2\"`"+`
+}
+`)
+}
+
+func TestErrCompileAborted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	conf := *gblConf
+	gblConf.Context = ctx
+	defer func() {
+		*gblConf = conf
+	}()
+	codeErrorTest(t, `bar.gop:2:9: compiling aborted: context canceled`, `
+var a = 1
+
+func main() {
+	println a
+	println a
+}
+`)
+}
+
+func TestErrMaxErrors(t *testing.T) {
+	var reported []string
+	conf := *gblConf
+	gblConf.MaxErrors = 2
+	gblConf.OnError = func(err error) {
+		reported = append(reported, err.Error())
+	}
+	defer func() {
+		*gblConf = conf
+	}()
+	src := `
+func f() {
+	a := undefined1
+	b := 1 + "x"
+	println a, b
+}
+
+var c int = "x"
+`
+	codeErrorTest(t, `bar.gop:3:7: undefined: undefined1
+bar.gop:4:7: invalid operation: 1 + "x" (mismatched types untyped int and untyped string)
+too many errors`, src)
+	if len(reported) != 2 {
+		t.Fatal("OnError:", reported)
+	}
+	gblConf.MaxErrors = 0
+	reported = nil
+	codeErrorTest(t, `bar.gop:3:7: undefined: undefined1
+bar.gop:4:7: invalid operation: 1 + "x" (mismatched types untyped int and untyped string)
+bar.gop:5:10: undefined: a
+bar.gop:8:13: cannot use "x" (type untyped string) as type int in assignment`, src)
+	if len(reported) != 4 {
+		t.Fatal("OnError:", reported)
+	}
+}
+
+type crashImporter struct {
+	types.Importer
+}
+
+func (p crashImporter) Import(path string) (*types.Package, error) {
+	if path == "crash" { // a bug of the compiler
+		var m map[string]int
+		m[path] = 1
+	}
+	return p.Importer.Import(path)
+}
+
+func TestErrInternal(t *testing.T) {
+	conf := *gblConf
+	gblConf.Importer = crashImporter{conf.Importer}
+	defer func() {
+		*gblConf = conf
+	}()
+	codeErrorTest(t, `internal compiler error: assignment to entry in nil map`, `
+import "crash"
+
+crash.foo
+`)
+	fs := memfs.SingleFile("/foo", "bar.gop", `import "crash"; crash.foo`)
+	pkgs, _ := parser.ParseFSDir(gblFset, fs, "/foo", parser.Config{})
+	_, err := cl.NewPackage("", pkgs["main"], gblConf)
+	ie, ok := cl.InternalErrorOf(err)
+	if !ok || !strings.Contains(string(ie.Stack), "crashImporter") {
+		t.Fatal("InternalErrorOf:", err, ok)
+	}
+}
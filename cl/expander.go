@@ -0,0 +1,569 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package cl
+
+import (
+	"errors"
+	"fmt"
+	"go/types"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/goplus/gop/ast"
+	"github.com/goplus/gop/token"
+)
+
+// -----------------------------------------------------------------------------
+
+// Expander expands annotated declarations before they are compiled. It is
+// the sanctioned extension point for frameworks which need to generate code
+// from declarations annotated like `@memoize` or `@route("/users")`.
+type Expander interface {
+	// Name returns name of the annotation which the expander handles,
+	// eg. "memoize" for `@memoize`.
+	Name() string
+
+	// Expand expands decl annotated by annot. The returned declarations
+	// replace decl, so they usually include decl itself (maybe modified).
+	// Annotations of the returned declarations are expanded too.
+	//
+	// decl is a shallow copy of the declaration of the source file, which
+	// must not be changed as it may be used again (eg. compiled twice): Expand
+	// replaces the nodes of decl to modify instead of changing them in place.
+	Expand(annot *ast.Annotation, decl ast.Decl) ([]ast.Decl, error)
+}
+
+var (
+	expanderMutex sync.RWMutex
+	expanders     = map[string]Expander{
+		"constructor": constructorExpander{},
+		"builder":     builderExpander{},
+		"memoize":     memoizeExpander{},
+	}
+)
+
+// RegisterExpander registers an expander by its name. It overrides the
+// existing expander which has the same name.
+func RegisterExpander(e Expander) {
+	expanderMutex.Lock()
+	expanders[e.Name()] = e
+	expanderMutex.Unlock()
+}
+
+// LookupExpander lookups a registered expander by its name.
+func LookupExpander(name string) (e Expander, ok bool) {
+	expanderMutex.RLock()
+	e, ok = expanders[name]
+	expanderMutex.RUnlock()
+	return
+}
+
+// compilerAnnots are annotations handled by the compiler itself:
+//
+//	@implements(I1, I2, ...)  // asserts a type implements interfaces I1, I2, ...
+var compilerAnnots = map[string]bool{
+	"implements": true,
+}
+
+// expandFiles returns files with annotated declarations expanded. Files with
+// such declarations are copied, so the caller's AST isn't changed.
+func expandFiles(ctx *pkgCtx, files map[string]*ast.File) map[string]*ast.File {
+	ret := make(map[string]*ast.File, len(files))
+	for fpath, f := range files {
+		ret[fpath] = expandFile(ctx, f)
+	}
+	return ret
+}
+
+func expandFile(ctx *pkgCtx, f *ast.File) *ast.File {
+	for _, decl := range f.Decls {
+		if len(annotsOf(decl)) != 0 {
+			ret := *f
+			ret.Decls = hoistImports(expandDecls(ctx, f.Decls))
+			return &ret
+		}
+	}
+	return f
+}
+
+// hoistImports moves import declarations generated by expanders before
+// other declarations.
+func hoistImports(decls []ast.Decl) []ast.Decl {
+	ret := make([]ast.Decl, 0, len(decls))
+	for _, decl := range decls {
+		if d, ok := decl.(*ast.GenDecl); ok && d.Tok == token.IMPORT {
+			ret = append(ret, decl)
+		}
+	}
+	for _, decl := range decls {
+		if d, ok := decl.(*ast.GenDecl); !ok || d.Tok != token.IMPORT {
+			ret = append(ret, decl)
+		}
+	}
+	return ret
+}
+
+func expandDecls(ctx *pkgCtx, decls []ast.Decl) []ast.Decl {
+	ret := make([]ast.Decl, 0, len(decls))
+	for _, decl := range decls {
+		ret = append(ret, expandDecl(ctx, decl)...)
+	}
+	return ret
+}
+
+func expandDecl(ctx *pkgCtx, decl ast.Decl) []ast.Decl {
+	annots := annotsOf(decl)
+	for i, annot := range annots {
+		name := annot.Name.Name
+		if compilerAnnots[name] { // handled by the compiler itself
+			continue
+		}
+		decl := withAnnots(decl, append(annots[:i:i], annots[i+1:]...))
+		e, ok := LookupExpander(name)
+		if !ok {
+			ctx.handleErrorf(annot.Pos(), "unknown annotation @%s", name)
+			return expandDecl(ctx, decl)
+		}
+		decls, err := e.Expand(annot, decl)
+		if err != nil {
+			ctx.handleErrorf(annot.Pos(), "@%s: %v", name, err)
+			return expandDecl(ctx, decl)
+		}
+		if _, ok := e.(memoizeExpander); ok {
+			for _, d := range decls { // the wrapper keeps name of the function
+				if fn, ok := d.(*ast.FuncDecl); ok && fn.Name == decl.(*ast.FuncDecl).Name {
+					if ctx.memoized == nil {
+						ctx.memoized = make(map[*ast.FuncDecl]*ast.Annotation)
+					}
+					ctx.memoized[fn] = annot
+				}
+			}
+		}
+		return expandDecls(ctx, decls)
+	}
+	return []ast.Decl{decl}
+}
+
+func annotsOf(decl ast.Decl) []*ast.Annotation {
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		return d.Annots
+	case *ast.GenDecl:
+		return d.Annots
+	}
+	return nil
+}
+
+// withAnnots returns a copy of decl whose annotations are annots.
+func withAnnots(decl ast.Decl, annots []*ast.Annotation) ast.Decl {
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		ret := *d
+		ret.Annots = annots
+		return &ret
+	case *ast.GenDecl:
+		ret := *d
+		ret.Annots = annots
+		return &ret
+	}
+	return decl
+}
+
+// -----------------------------------------------------------------------------
+
+// constructorExpander expands `@constructor` of a struct type T by generating
+// a constructor function which initializes all fields:
+//
+//	func NewT(field1 T1, field2 T2, ...) *T {
+//		return &T{Field1: field1, Field2: field2, ...}
+//	}
+//
+// The constructor is named newT if T is unexported.
+type constructorExpander struct{}
+
+func (constructorExpander) Name() string {
+	return "constructor"
+}
+
+func (constructorExpander) Expand(annot *ast.Annotation, decl ast.Decl) ([]ast.Decl, error) {
+	spec, flds, err := structFields(decl)
+	if err != nil {
+		return nil, err
+	}
+	tname := spec.Name.Name
+	params := make([]*ast.Field, len(flds))
+	elts := make([]ast.Expr, len(flds))
+	for i, fld := range flds {
+		params[i] = &ast.Field{Names: []*ast.Ident{ast.NewIdent(fld.param)}, Type: fld.typ}
+		elts[i] = &ast.KeyValueExpr{Key: ast.NewIdent(fld.name), Value: ast.NewIdent(fld.param)}
+	}
+	fname := "New" + tname
+	if !token.IsExported(tname) {
+		fname = "new" + strings.ToUpper(tname[:1]) + tname[1:]
+	}
+	fn := &ast.FuncDecl{
+		Name: ast.NewIdent(fname),
+		Type: &ast.FuncType{
+			Params:  &ast.FieldList{List: params},
+			Results: &ast.FieldList{List: []*ast.Field{{Type: &ast.StarExpr{X: ast.NewIdent(tname)}}}},
+		},
+		Body: &ast.BlockStmt{List: []ast.Stmt{
+			&ast.ReturnStmt{Results: []ast.Expr{&ast.UnaryExpr{
+				Op: token.AND, X: &ast.CompositeLit{Type: ast.NewIdent(tname), Elts: elts},
+			}}},
+		}},
+	}
+	return []ast.Decl{decl, fn}, nil
+}
+
+// builderExpander expands `@builder` of a struct type T by generating a
+// fluent setter for each field:
+//
+//	func (p *T) WithField(field T1) *T {
+//		p.Field = field
+//		return p
+//	}
+type builderExpander struct{}
+
+func (builderExpander) Name() string {
+	return "builder"
+}
+
+func (builderExpander) Expand(annot *ast.Annotation, decl ast.Decl) ([]ast.Decl, error) {
+	spec, flds, err := structFields(decl)
+	if err != nil {
+		return nil, err
+	}
+	tname := spec.Name.Name
+	ret := make([]ast.Decl, 1, len(flds)+1)
+	ret[0] = decl
+	for _, fld := range flds {
+		recvType := &ast.StarExpr{X: ast.NewIdent(tname)}
+		ret = append(ret, &ast.FuncDecl{
+			Recv: &ast.FieldList{List: []*ast.Field{{Names: []*ast.Ident{ast.NewIdent("p")}, Type: recvType}}},
+			Name: ast.NewIdent("With" + strings.ToUpper(fld.name[:1]) + fld.name[1:]),
+			Type: &ast.FuncType{
+				Params:  &ast.FieldList{List: []*ast.Field{{Names: []*ast.Ident{ast.NewIdent(fld.param)}, Type: fld.typ}}},
+				Results: &ast.FieldList{List: []*ast.Field{{Type: &ast.StarExpr{X: ast.NewIdent(tname)}}}},
+			},
+			Body: &ast.BlockStmt{List: []ast.Stmt{
+				&ast.AssignStmt{
+					Lhs: []ast.Expr{&ast.SelectorExpr{X: ast.NewIdent("p"), Sel: ast.NewIdent(fld.name)}},
+					Tok: token.ASSIGN,
+					Rhs: []ast.Expr{ast.NewIdent(fld.param)},
+				},
+				&ast.ReturnStmt{Results: []ast.Expr{ast.NewIdent("p")}},
+			}},
+		})
+	}
+	return ret, nil
+}
+
+// memoizeExpander expands `@memoize` of a function f by caching its results
+// in a thread-safe Memo (see github.com/goplus/gop/builtin) keyed by its
+// arguments:
+//
+//	@memoize                           // no limit, never expires
+//	@memoize(1000)                     // caches at most 1000 calls
+//	@memoize(size: 1000, ttl: 5*time.Minute)
+//
+// f is renamed to _gop_f and f becomes a wrapper of it (so recursive calls
+// are cached too). Results are not cached if the last one is a non-nil error.
+// Parameters of f must be comparable, which is checked by checkMemoized when
+// the wrapper is loaded.
+type memoizeExpander struct{}
+
+func (memoizeExpander) Name() string {
+	return "memoize"
+}
+
+func (memoizeExpander) Expand(annot *ast.Annotation, decl ast.Decl) ([]ast.Decl, error) {
+	d, ok := decl.(*ast.FuncDecl)
+	if !ok || d.Recv != nil || d.Type.TypeParams != nil || d.Body == nil {
+		return nil, errors.New("requires a non-generic function declaration")
+	}
+	opts, err := memoizeOpts(annot)
+	if err != nil {
+		return nil, err
+	}
+	name := d.Name.Name
+	memo := "_gop_memo_" + name
+	impl := &ast.FuncDecl{Name: ast.NewIdent("_gop_" + name), Type: d.Type, Body: d.Body}
+
+	var params []*ast.Field
+	var args []ast.Expr
+	for _, fld := range d.Type.Params.List {
+		if _, ok := fld.Type.(*ast.Ellipsis); ok {
+			return nil, errors.New("variadic parameters are not supported")
+		}
+		names := fld.Names
+		if names == nil {
+			names = []*ast.Ident{nil}
+		}
+		for _, id := range names {
+			if id == nil || id.Name == "_" {
+				id = ast.NewIdent("_gop_arg" + strconv.Itoa(len(args)))
+			}
+			params = append(params, &ast.Field{Names: []*ast.Ident{id}, Type: fld.Type})
+			args = append(args, ast.NewIdent(id.Name))
+		}
+	}
+	var rets []ast.Expr
+	var getRets []ast.Stmt
+	var lastType ast.Expr
+	if d.Type.Results != nil {
+		for _, fld := range d.Type.Results.List {
+			n := len(fld.Names)
+			if n == 0 {
+				n = 1
+			}
+			for i := 0; i < n; i++ {
+				ret := "_gop_ret" + strconv.Itoa(len(rets))
+				idx := &ast.BasicLit{Kind: token.INT, Value: strconv.Itoa(len(rets))}
+				getRets = append(getRets, &ast.AssignStmt{ // retN, _ := rets[N].(TN)
+					Lhs: []ast.Expr{ast.NewIdent(ret), ast.NewIdent("_")},
+					Tok: token.DEFINE,
+					Rhs: []ast.Expr{&ast.TypeAssertExpr{
+						X: &ast.IndexExpr{X: ast.NewIdent("_gop_rets"), Index: idx}, Type: fld.Type,
+					}},
+				})
+				rets = append(rets, ast.NewIdent(ret))
+				lastType = fld.Type
+			}
+		}
+	}
+	if rets == nil {
+		return nil, errors.New("requires a function with results")
+	}
+	getRets = append(getRets, &ast.ReturnStmt{Results: rets})
+
+	memoCall := func(method string, args []ast.Expr) *ast.CallExpr {
+		return &ast.CallExpr{
+			Fun: &ast.SelectorExpr{X: ast.NewIdent(memo), Sel: ast.NewIdent(method)}, Args: args,
+		}
+	}
+	key := ast.NewIdent("_gop_key")
+	var put ast.Stmt = &ast.ExprStmt{X: memoCall("Put", append([]ast.Expr{key}, rets...))}
+	if id, ok := lastType.(*ast.Ident); ok && id.Name == "error" { // don't cache errors
+		put = &ast.IfStmt{
+			Cond: &ast.BinaryExpr{X: rets[len(rets)-1], Op: token.EQL, Y: ast.NewIdent("nil")},
+			Body: &ast.BlockStmt{List: []ast.Stmt{put}},
+		}
+	}
+	wrapper := &ast.FuncDecl{
+		Doc:  d.Doc,
+		Name: d.Name,
+		Type: &ast.FuncType{Params: &ast.FieldList{List: params}, Results: d.Type.Results},
+		Body: &ast.BlockStmt{List: []ast.Stmt{
+			&ast.AssignStmt{
+				Lhs: []ast.Expr{key},
+				Tok: token.DEFINE,
+				Rhs: []ast.Expr{memoCall("Key", args)},
+			},
+			&ast.IfStmt{ // if rets, ok := memo.Get(key); ok { ... }
+				Init: &ast.AssignStmt{
+					Lhs: []ast.Expr{ast.NewIdent("_gop_rets"), ast.NewIdent("_gop_ok")},
+					Tok: token.DEFINE,
+					Rhs: []ast.Expr{memoCall("Get", []ast.Expr{key})},
+				},
+				Cond: ast.NewIdent("_gop_ok"),
+				Body: &ast.BlockStmt{List: getRets},
+			},
+			&ast.AssignStmt{
+				Lhs: rets,
+				Tok: token.DEFINE,
+				Rhs: []ast.Expr{&ast.CallExpr{Fun: ast.NewIdent(impl.Name.Name), Args: args}},
+			},
+			put,
+			&ast.ReturnStmt{Results: rets},
+		}},
+	}
+	imp := &ast.GenDecl{Tok: token.IMPORT, Specs: []ast.Spec{&ast.ImportSpec{
+		Name: ast.NewIdent(memoPkg),
+		Path: &ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(builtinPkgPath)},
+	}}}
+	newMemo := &ast.SelectorExpr{X: ast.NewIdent(memoPkg), Sel: ast.NewIdent("NewMemo")}
+	vars := &ast.GenDecl{Tok: token.VAR, Specs: []ast.Spec{&ast.ValueSpec{
+		Names:  []*ast.Ident{ast.NewIdent(memo)},
+		Values: []ast.Expr{&ast.CallExpr{Fun: newMemo, Args: opts}},
+	}}}
+	return []ast.Decl{imp, vars, wrapper, impl}, nil
+}
+
+const (
+	builtinPkgPath = "github.com/goplus/gop/builtin"
+	memoPkg        = "_gop_builtin" // name of builtinPkgPath imported by @memoize
+)
+
+// checkMemoized checks that parameters of sig, the signature of a function
+// wrapped by `@memoize`, are comparable: they are the key of the cache.
+func checkMemoized(ctx *blockCtx, annot *ast.Annotation, sig *types.Signature) {
+	params := sig.Params()
+	for i, n := 0, params.Len(); i < n; i++ {
+		param := params.At(i)
+		if typ := param.Type(); !types.Comparable(typ) {
+			ctx.handleErrorf(annot.Pos(), "@memoize: parameter %s of type %s is not comparable",
+				param.Name(), types.TypeString(typ, ctx.qualifier))
+			return
+		}
+	}
+}
+
+// memoizeOpts returns the arguments (size, ttl) of `@memoize`, which are
+// positional or named.
+func memoizeOpts(annot *ast.Annotation) ([]ast.Expr, error) {
+	if len(annot.Args) > 2 {
+		return nil, errors.New("too many arguments")
+	}
+	opts := []ast.Expr{
+		&ast.BasicLit{Kind: token.INT, Value: "0"},
+		&ast.BasicLit{Kind: token.INT, Value: "0"},
+	}
+	for i, arg := range annot.Args {
+		if kv, ok := arg.(*ast.KeyValueExpr); ok {
+			key, _ := kv.Key.(*ast.Ident)
+			switch {
+			case key == nil:
+				return nil, errors.New("invalid argument name")
+			case key.Name == "size":
+				i = 0
+			case key.Name == "ttl":
+				i = 1
+			default:
+				return nil, fmt.Errorf("unknown argument %s", key.Name)
+			}
+			arg = kv.Value
+		}
+		opts[i] = arg
+	}
+	return opts, nil
+}
+
+type structField struct {
+	name  string
+	param string
+	typ   ast.Expr
+}
+
+func structFields(decl ast.Decl) (spec *ast.TypeSpec, flds []structField, err error) {
+	if d, ok := decl.(*ast.GenDecl); ok && d.Tok == token.TYPE && len(d.Specs) == 1 {
+		spec = d.Specs[0].(*ast.TypeSpec)
+		if t, ok := spec.Type.(*ast.StructType); ok && spec.TypeParams == nil && !spec.Assign.IsValid() {
+			for _, field := range t.Fields.List {
+				if len(field.Names) == 0 {
+					name := parseTypeEmbedName(field.Type).Name
+					flds = append(flds, structField{name, paramName(name), field.Type})
+					continue
+				}
+				for _, name := range field.Names {
+					if name.Name != "_" {
+						flds = append(flds, structField{name.Name, paramName(name.Name), field.Type})
+					}
+				}
+			}
+			return
+		}
+	}
+	return nil, nil, errors.New("requires a non-generic struct type declaration")
+}
+
+// paramName converts a field name into a parameter name, eg. URLPath => urlPath.
+func paramName(name string) string {
+	n := 0
+	for n < len(name) && name[n] >= 'A' && name[n] <= 'Z' {
+		n++
+	}
+	if n > 1 && n < len(name) {
+		n--
+	}
+	if n == 0 {
+		n = 1
+	}
+	name = strings.ToLower(name[:n]) + name[n:]
+	if token.Lookup(name).IsKeyword() {
+		name += "_"
+	}
+	return name
+}
+
+// checkImplements checks `@implements(I1, I2, ...)` of type declarations
+// after all types and methods are loaded, and generates compile-time
+// assertions `var _ I = (*T)(nil)` for them.
+func checkImplements(ctx *blockCtx, d *ast.GenDecl) {
+	for _, annot := range d.Annots {
+		if annot.Name.Name != "implements" {
+			continue
+		}
+		if len(annot.Args) == 0 {
+			ctx.handleErrorf(annot.Pos(), "@implements: missing interfaces")
+			continue
+		}
+		annot := annot
+		ctx.inits = append(ctx.inits, func() {
+			for _, spec := range d.Specs {
+				name := spec.(*ast.TypeSpec).Name.Name
+				o := ctx.pkg.Types.Scope().Lookup(name)
+				if o == nil {
+					continue
+				}
+				for _, arg := range annot.Args {
+					checkImplement(ctx, d, annot, o.Type(), arg)
+				}
+			}
+		})
+	}
+}
+
+func checkImplement(ctx *blockCtx, d *ast.GenDecl, annot *ast.Annotation, typ types.Type, arg ast.Expr) {
+	iface := toType(ctx, arg)
+	t, ok := iface.Underlying().(*types.Interface)
+	if !ok {
+		ctx.handleErrorf(arg.Pos(), "@implements: %v is not an interface", ctx.LoadExpr(arg))
+		return
+	}
+	ptr := types.NewPointer(typ)
+	if types.Implements(ptr, t) { // var _ I = (*T)(nil)
+		pkg := ctx.pkg
+		cb := pkg.NewVarDefs(pkg.Types.Scope()).New(arg.Pos(), iface, "_").InitStart(pkg)
+		cb.Typ(ptr).Val(nil).Call(1).EndInit(1)
+		return
+	}
+	qf := types.RelativeTo(ctx.pkg.Types)
+	tname := types.TypeString(typ, qf)
+	var b, stubs strings.Builder
+	for i, n := 0, t.NumMethods(); i < n; i++ {
+		m := t.Method(i)
+		obj, _, _ := types.LookupFieldOrMethod(ptr, false, m.Pkg(), m.Name())
+		want := m.Name() + strings.TrimPrefix(types.TypeString(m.Type(), qf), "func")
+		if fn, ok := obj.(*types.Func); !ok {
+			fmt.Fprintf(&b, "\n\tmissing method %s", want)
+			fmt.Fprintf(&stubs, "\n\nfunc (*%s) %s {\n\tpanic(\"TODO: implement\")\n}", tname, want)
+		} else if !types.Identical(fn.Type(), m.Type()) {
+			have := fn.Name() + strings.TrimPrefix(types.TypeString(fn.Type(), qf), "func")
+			fmt.Fprintf(&b, "\n\twrong type for method %s\n\t\thave %s\n\t\twant %s", m.Name(), have, want)
+		}
+	}
+	diag := ctx.newDiagf(SeverityError, annot.Pos(), annot.End(), "%s does not implement %s:%s",
+		tname, types.TypeString(iface, qf), b.String())
+	diag.Code = "implements"
+	if stubs.Len() > 0 {
+		diag.Fix("add missing methods", Insert(d.End(), stubs.String()))
+	}
+	ctx.handleDiag(diag)
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,411 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package cl_test
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/goplus/gop/ast"
+	"github.com/goplus/gop/cl"
+	"github.com/goplus/gop/parser"
+	"github.com/goplus/gop/parser/fsx/memfs"
+	"github.com/goplus/gop/token"
+)
+
+// traceExpander expands `@trace` by printing the function name on entry.
+type traceExpander struct{}
+
+func (traceExpander) Name() string {
+	return "trace"
+}
+
+func (traceExpander) Expand(annot *ast.Annotation, decl ast.Decl) ([]ast.Decl, error) {
+	fn, ok := decl.(*ast.FuncDecl)
+	if !ok {
+		return nil, errors.New("only functions can be traced")
+	}
+	msg := "enter " + fn.Name.Name
+	if len(annot.Args) == 1 {
+		if lit, ok := annot.Args[0].(*ast.BasicLit); ok {
+			msg, _ = strconv.Unquote(lit.Value)
+		}
+	}
+	call := &ast.ExprStmt{X: &ast.CallExpr{
+		Fun:  ast.NewIdent("println"),
+		Args: []ast.Expr{&ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(msg)}},
+	}}
+	body := *fn.Body
+	body.List = append([]ast.Stmt{call}, body.List...)
+	fn.Body = &body
+	return []ast.Decl{fn}, nil
+}
+
+func init() {
+	cl.RegisterExpander(traceExpander{})
+}
+
+func TestExpander(t *testing.T) {
+	if _, ok := cl.LookupExpander("trace"); !ok {
+		t.Fatal("LookupExpander: not found")
+	}
+	gopClTest(t, `
+@trace
+func foo() {
+}
+
+@trace("in bar")
+@trace
+func bar() {
+}
+`, `package main
+
+import "fmt"
+
+func foo() {
+	fmt.Println("enter foo")
+}
+func bar() {
+	fmt.Println("enter bar")
+	fmt.Println("in bar")
+}
+`)
+}
+
+func TestExpanderCompileTwice(t *testing.T) {
+	fs := memfs.SingleFile("/foo", "bar.gop", `
+@trace
+func foo() {
+}
+
+@memoize
+func double(n int) int {
+	return n * 2
+}
+`)
+	pkgs, err := parser.ParseFSDir(gblFset, fs, "/foo", parser.Config{})
+	if err != nil {
+		t.Fatal("ParseFSDir:", err)
+	}
+	pkg := pkgs["main"]
+	f := pkg.Files["/foo/bar.gop"]
+	var rets [2]string
+	for i := range rets {
+		p, err := cl.NewPackage("", pkg, gblConf)
+		if err != nil {
+			t.Fatal("NewPackage:", err)
+		}
+		var b bytes.Buffer
+		if err = p.WriteTo(&b); err != nil {
+			t.Fatal("WriteTo:", err)
+		}
+		rets[i] = b.String()
+		if n := len(f.Decls); n != 2 {
+			t.Fatal("NewPackage changed decls of the file:", n)
+		}
+		foo, double := f.Decls[0].(*ast.FuncDecl), f.Decls[1].(*ast.FuncDecl)
+		if len(foo.Annots) != 1 || len(double.Annots) != 1 || len(foo.Body.List) != 0 {
+			t.Fatal("NewPackage changed annotated functions of the file")
+		}
+	}
+	if rets[0] != rets[1] {
+		t.Fatalf("compiled twice:\n%s\nfirst:\n%s\n", rets[1], rets[0])
+	}
+}
+
+func TestExpanderError(t *testing.T) {
+	codeErrorTest(t, `bar.gop:2:1: unknown annotation @unknown`, `
+@unknown
+func foo() {
+}
+`)
+	codeErrorTest(t, `bar.gop:2:1: @trace: only functions can be traced`, `
+@trace
+type T int
+`)
+}
+
+func TestConstructorAndBuilder(t *testing.T) {
+	gopClTest(t, `
+import "bytes"
+
+@constructor
+@builder
+type Request struct {
+	*bytes.Buffer
+	URL, Type string
+	_         int
+}
+
+@constructor
+type point struct {
+	X, Y int
+}
+
+req := NewRequest(nil, "http://goplus.org", "json").WithType("xml")
+println req.Type, newPoint(1, 2)
+`, `package main
+
+import (
+	"fmt"
+	"bytes"
+)
+
+type Request struct {
+	*bytes.Buffer
+	URL  string
+	Type string
+	_    int
+}
+type point struct {
+	X int
+	Y int
+}
+
+func (p *Request) WithBuffer(buffer *bytes.Buffer) *Request {
+	p.Buffer = buffer
+	return p
+}
+func (p *Request) WithURL(url string) *Request {
+	p.URL = url
+	return p
+}
+func (p *Request) WithType(type_ string) *Request {
+	p.Type = type_
+	return p
+}
+func NewRequest(buffer *bytes.Buffer, url string, type_ string) *Request {
+	return &Request{Buffer: buffer, URL: url, Type: type_}
+}
+func newPoint(x int, y int) *point {
+	return &point{X: x, Y: y}
+}
+func main() {
+	req := NewRequest(nil, "http://goplus.org", "json").WithType("xml")
+	fmt.Println(req.Type, newPoint(1, 2))
+}
+`)
+	codeErrorTest(t, `bar.gop:2:1: @builder: requires a non-generic struct type declaration`, `
+@builder
+type T int
+`)
+}
+
+func TestImplements(t *testing.T) {
+	gopClTest(t, `
+import "io"
+
+@implements(io.Reader, io.Closer)
+type T struct{}
+
+func (T) Read(b []byte) (n int, err error) {
+	return
+}
+
+func (p *T) Close() error {
+	return nil
+}
+`, `package main
+
+import "io"
+
+type T struct {
+}
+
+func (T) Read(b []byte) (n int, err error) {
+	return
+}
+func (p *T) Close() error {
+	return nil
+}
+
+var _ io.Reader = (*T)(nil)
+var _ io.Closer = (*T)(nil)
+`)
+}
+
+func TestErrImplements(t *testing.T) {
+	codeErrorTest(t, `bar.gop:4:1: T does not implement io.ReadCloser:
+	missing method Close() error
+	wrong type for method Read
+		have Read(b []byte) int
+		want Read(p []byte) (n int, err error)`, `
+import "io"
+
+@implements(io.ReadCloser)
+type T struct{}
+
+func (T) Read(b []byte) int {
+	return 0
+}
+`)
+	codeErrorTest(t, `bar.gop:4:13: @implements: int is not an interface`, `
+import "io"
+
+@implements(int)
+type T struct{}
+`)
+	codeErrorTest(t, `bar.gop:2:1: @implements: missing interfaces`, `
+@implements
+type T struct{}
+`)
+}
+
+func TestMemoize(t *testing.T) {
+	gopClTest(t, `
+import "time"
+
+// fib returns the n-th Fibonacci number.
+@memoize
+func fib(n int) int {
+	if n < 2 {
+		return n
+	}
+	return fib(n-1) + fib(n-2)
+}
+
+@memoize(size: 100, ttl: time.Minute)
+func load(name string, _ bool) (data []byte, err error) {
+	return
+}
+
+println fib(50)
+`, `package main
+
+import (
+	"fmt"
+	"github.com/goplus/gop/builtin"
+	"time"
+)
+
+var _gop_memo_fib = builtin.NewMemo(0, 0)
+// fib returns the n-th Fibonacci number.
+func fib(n int) int {
+	_gop_key := _gop_memo_fib.Key(n)
+	if _gop_rets, _gop_ok := _gop_memo_fib.Get(_gop_key); _gop_ok {
+		_gop_ret0, _ := _gop_rets[0].(int)
+		return _gop_ret0
+	}
+	_gop_ret0 := _gop_fib(n)
+	_gop_memo_fib.Put(_gop_key, _gop_ret0)
+	return _gop_ret0
+}
+func _gop_fib(n int) int {
+	if n < 2 {
+		return n
+	}
+	return fib(n-1) + fib(n-2)
+}
+
+var _gop_memo_load = builtin.NewMemo(100, time.Minute)
+
+func load(name string, _gop_arg1 bool) (data []byte, err error) {
+	_gop_key := _gop_memo_load.Key(name, _gop_arg1)
+	if _gop_rets, _gop_ok := _gop_memo_load.Get(_gop_key); _gop_ok {
+		_gop_ret0, _ := _gop_rets[0].([]byte)
+		_gop_ret1, _ := _gop_rets[1].(error)
+		return _gop_ret0, _gop_ret1
+	}
+	_gop_ret0, _gop_ret1 := _gop_load(name, _gop_arg1)
+	if _gop_ret1 == nil {
+		_gop_memo_load.Put(_gop_key, _gop_ret0, _gop_ret1)
+	}
+	return _gop_ret0, _gop_ret1
+}
+func _gop_load(name string, _ bool) (data []byte, err error) {
+	return
+}
+func main() {
+	fmt.Println(fib(50))
+}
+`)
+	gopClTest(t, `
+func newMemo(n int) int {
+	return n
+}
+
+@memoize
+func double(n int) int {
+	return newMemo(n) * 2
+}
+`, `package main
+
+import "github.com/goplus/gop/builtin"
+
+func newMemo(n int) int {
+	return n
+}
+
+var _gop_memo_double = builtin.NewMemo(0, 0)
+
+func double(n int) int {
+	_gop_key := _gop_memo_double.Key(n)
+	if _gop_rets, _gop_ok := _gop_memo_double.Get(_gop_key); _gop_ok {
+		_gop_ret0, _ := _gop_rets[0].(int)
+		return _gop_ret0
+	}
+	_gop_ret0 := _gop_double(n)
+	_gop_memo_double.Put(_gop_key, _gop_ret0)
+	return _gop_ret0
+}
+func _gop_double(n int) int {
+	return newMemo(n) * 2
+}
+`)
+	codeErrorTest(t, `bar.gop:2:1: @memoize: variadic parameters are not supported`, `
+@memoize
+func sum(a ...int) int {
+	return 0
+}
+`)
+	codeErrorTest(t, `bar.gop:2:1: @memoize: parameter a of type []int is not comparable`, `
+@memoize
+func sum(a []int) int {
+	return 0
+}
+`)
+	codeErrorTest(t, `bar.gop:4:1: @memoize: parameter s of type S is not comparable`, `
+type S []int
+
+@memoize
+func sum(s S) int {
+	return 0
+}
+`)
+	codeErrorTest(t, `bar.gop:6:1: @memoize: parameter p of type Point is not comparable`, `
+type Point struct {
+	tags []string
+}
+
+@memoize
+func norm(n int, p Point) int {
+	return n
+}
+`)
+	codeErrorTest(t, `bar.gop:2:1: @memoize: requires a function with results`, `
+@memoize
+func f(a int) {
+}
+`)
+	codeErrorTest(t, `bar.gop:2:1: @memoize: unknown argument max`, `
+@memoize(max: 10)
+func f(a int) int {
+	return a
+}
+`)
+}
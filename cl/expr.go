@@ -24,8 +24,10 @@ import (
 	"log"
 	"math/big"
 	"reflect"
+	"runtime"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/goplus/gop/ast"
 	"github.com/goplus/gop/printer"
@@ -214,6 +216,7 @@ func twoValue(inFlags []int) bool {
 }
 
 func compileExpr(ctx *blockCtx, expr ast.Expr, inFlags ...int) {
+	ctx.checkAbort(expr.Pos())
 	switch v := expr.(type) {
 	case *ast.Ident:
 		flags := clIdentCanAutoCall
@@ -301,11 +304,28 @@ func compileUnaryExpr(ctx *blockCtx, v *ast.UnaryExpr, twoValue bool) {
 }
 
 func compileBinaryExpr(ctx *blockCtx, v *ast.BinaryExpr) {
+	if v.Op == token.PIPE {
+		compileCallExpr(ctx, pipeCall(v), 0)
+		return
+	}
 	compileExpr(ctx, v.X)
 	compileExpr(ctx, v.Y)
 	ctx.cb.BinaryOp(gotoken.Token(v.Op), v)
 }
 
+// pipeCall converts a pipeline x |> f(args) into the call f(x, args), and
+// x |> f into f(x).
+func pipeCall(v *ast.BinaryExpr) *ast.CallExpr {
+	if call, ok := v.Y.(*ast.CallExpr); ok {
+		args := make([]ast.Expr, 0, len(call.Args)+1)
+		return &ast.CallExpr{
+			Fun: call.Fun, Lparen: call.Lparen, Args: append(append(args, v.X), call.Args...),
+			Ellipsis: call.Ellipsis, Rparen: call.Rparen, NoParenEnd: call.NoParenEnd,
+		}
+	}
+	return &ast.CallExpr{Fun: v.Y, Args: []ast.Expr{v.X}, Lparen: v.OpPos, Rparen: v.End()}
+}
+
 func compileIndexExprLHS(ctx *blockCtx, v *ast.IndexExpr) {
 	compileExpr(ctx, v.X)
 	compileExpr(ctx, v.Index)
@@ -459,6 +479,7 @@ func compilePkgRef(ctx *blockCtx, at *gox.PkgRef, x *ast.Ident, flags, pkgKind i
 
 type fnType struct {
 	next     *fnType
+	generic  types.Object // a generic candidate of overload funcs, see initFuncs
 	params   *types.Tuple
 	base     int
 	size     int
@@ -528,14 +549,16 @@ func (p *fnType) load(fnt types.Type) {
 func (p *fnType) initFuncs(base int, funcs ...types.Object) {
 	for i, obj := range funcs {
 		if sig, ok := obj.Type().(*types.Signature); ok {
-			if i == 0 {
-				p.init(base, sig)
-			} else {
-				fn := &fnType{}
-				fn.init(base, sig)
-				p.next = fn
+			if i != 0 {
+				p.next = &fnType{}
 				p = p.next
 			}
+			p.init(base, sig)
+			if base == 0 && sig.TypeParams() != nil {
+				// gox fails instead of trying the next candidate if it can't
+				// infer type arguments of a generic one, so call it directly
+				p.generic = obj
+			}
 		}
 	}
 }
@@ -589,6 +612,9 @@ func compileCallArgs(fn *fnType, ctx *blockCtx, v *ast.CallExpr, ellipsis bool,
 	n := ctx.cb.InternalStack().Len()
 	defer func() {
 		if r := recover(); r != nil {
+			if _, ok := r.(runtime.Error); ok { // a bug of the compiler
+				panic(r)
+			}
 			if e, ok := r.(error); ok {
 				err = e
 			} else {
@@ -598,13 +624,17 @@ func compileCallArgs(fn *fnType, ctx *blockCtx, v *ast.CallExpr, ellipsis bool,
 			ctx.cb.InternalStack().SetLen(n)
 		}
 	}()
+	if fn.generic != nil {
+		fun := ctx.cb.InternalStack().Pop()
+		ctx.cb.Val(fn.generic, fun.Src)
+	}
 	for i, arg := range v.Args {
 		switch expr := arg.(type) {
 		case *ast.LambdaExpr:
-			sig := checkLambdaFuncType(ctx, expr, fn.arg(i, ellipsis), clLambaArgument, v.Fun)
+			sig := checkLambdaFuncType(ctx, expr, inferLambdaType(ctx, fn, expr, i, ellipsis), clLambaArgument, v.Fun)
 			compileLambdaExpr(ctx, expr, sig)
 		case *ast.LambdaExpr2:
-			sig := checkLambdaFuncType(ctx, expr, fn.arg(i, ellipsis), clLambaArgument, v.Fun)
+			sig := checkLambdaFuncType(ctx, expr, inferLambdaType(ctx, fn, expr, i, ellipsis), clLambaArgument, v.Fun)
 			compileLambdaExpr2(ctx, expr, sig)
 		case *ast.CompositeLit:
 			compileCompositeLit(ctx, expr, fn.arg(i, ellipsis), true)
@@ -631,6 +661,7 @@ func compileCallArgs(fn *fnType, ctx *blockCtx, v *ast.CallExpr, ellipsis bool,
 			compileExpr(ctx, arg)
 		}
 	}
+	checkPrintfArgs(ctx, v, ellipsis)
 	ctx.cb.CallWith(len(v.Args), flags, v)
 	return
 }
@@ -649,9 +680,14 @@ func checkLambdaFuncType(ctx *blockCtx, lambda ast.Expr, ftyp types.Type, flag c
 retry:
 	switch t := typ.(type) {
 	case *types.Signature:
-		if l, ok := lambda.(*ast.LambdaExpr); ok {
+		switch l := lambda.(type) {
+		case *ast.LambdaExpr:
 			if len(l.Rhs) != t.Results().Len() {
-				break
+				break retry
+			}
+		case *ast.LambdaExpr2:
+			if !lambdaReturnsMatch(l.Body, t.Results().Len()) {
+				break retry
 			}
 		}
 		return t
@@ -664,6 +700,24 @@ retry:
 	panic(err)
 }
 
+// lambdaReturnsMatch reports whether return statements of a lambda body can
+// return n results.
+func lambdaReturnsMatch(body *ast.BlockStmt, n int) bool {
+	ok := true
+	ast.Inspect(body, func(node ast.Node) bool {
+		switch v := node.(type) {
+		case *ast.ReturnStmt:
+			if k := len(v.Results); k != 0 && k != n && (k != 1 || n == 0) {
+				ok = false
+			}
+		case *ast.FuncLit, *ast.LambdaExpr, *ast.LambdaExpr2:
+			return false
+		}
+		return ok
+	})
+	return ok
+}
+
 func compileLambda(ctx *blockCtx, lambda ast.Expr, sig *types.Signature) {
 	switch expr := lambda.(type) {
 	case *ast.LambdaExpr:
@@ -777,11 +831,50 @@ func compileBasicLit(ctx *blockCtx, v *ast.BasicLit) {
 			cb.Val(rune(s[i]))
 		}
 		cb.Val(rune(0)).ArrayLit(typ, n+1).UnaryOp(gotoken.AND).Call(1).Call(1)
+	case token.DURATION:
+		compileDurationLit(ctx, v)
 	default:
 		cb.Val(&goast.BasicLit{Kind: gotoken.Token(v.Kind), Value: v.Value}, v)
 	}
 }
 
+var durationUnits = [...]struct {
+	name string
+	unit time.Duration
+}{
+	{"Hour", time.Hour},
+	{"Minute", time.Minute},
+	{"Second", time.Second},
+	{"Millisecond", time.Millisecond},
+	{"Microsecond", time.Microsecond},
+	{"Nanosecond", time.Nanosecond},
+}
+
+// compileDurationLit compiles a duration literal, eg. 1h30m, to the largest
+// unit of package time which it's a multiple of, eg. 90 * time.Minute.
+func compileDurationLit(ctx *blockCtx, v *ast.BasicLit) {
+	d, err := time.ParseDuration(strings.ReplaceAll(v.Value, "_", ""))
+	if err != nil {
+		panic(ctx.newCodeErrorf(v.Pos(), "invalid duration literal %s", v.Value))
+	}
+	cb, pkg := ctx.cb, ctx.pkg.Import("time")
+	if d == 0 {
+		cb.Typ(pkg.Ref("Duration").Type()).Val(0).CallWith(1, 0, v)
+		return
+	}
+	for _, u := range durationUnits {
+		if d%u.unit == 0 {
+			if n := d / u.unit; n == 1 {
+				cb.Val(pkg.Ref(u.name), v)
+			} else {
+				val := &goast.BasicLit{Kind: gotoken.INT, Value: strconv.FormatInt(int64(n), 10)}
+				cb.Val(val).Val(pkg.Ref(u.name)).BinaryOp(gotoken.MUL, v)
+			}
+			return
+		}
+	}
+}
+
 const (
 	compositeLitVal    = 0
 	compositeLitKeyVal = 1
@@ -1040,6 +1133,7 @@ func compileComprehensionExpr(ctx *blockCtx, v *ast.ComprehensionExpr, twoValue
 		names := make([]string, 0, 2)
 		defineNames := make([]*ast.Ident, 0, 2)
 		forStmt := v.Fors[i]
+		checkForPhraseCond(ctx, forStmt)
 		if forStmt.Key != nil {
 			names = append(names, forStmt.Key.Name)
 			defineNames = append(defineNames, forStmt.Key)
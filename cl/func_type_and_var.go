@@ -17,11 +17,14 @@
 package cl
 
 import (
+	"errors"
+	"fmt"
 	"go/constant"
 	"go/types"
 	"log"
 	"math/big"
 	"strconv"
+	"strings"
 
 	"github.com/goplus/gop/ast"
 	"github.com/goplus/gop/token"
@@ -328,7 +331,7 @@ func toStructType(ctx *blockCtx, v *ast.StructType) *types.Struct {
 			ident := parseTypeEmbedName(field.Type)
 			fld := types.NewField(ident.NamePos, pkg, name, typ, true)
 			fields = append(fields, fld)
-			tags = append(tags, toFieldTag(field.Tag))
+			tags = append(tags, toFieldTag(ctx, field.Tag, ""))
 			if rec != nil {
 				rec.Def(ident, fld)
 			}
@@ -340,7 +343,7 @@ func toStructType(ctx *blockCtx, v *ast.StructType) *types.Struct {
 			}
 			fld := types.NewField(name.NamePos, pkg, name.Name, typ, false)
 			fields = append(fields, fld)
-			tags = append(tags, toFieldTag(field.Tag))
+			tags = append(tags, toFieldTag(ctx, field.Tag, name.Name))
 			if rec != nil {
 				rec.Def(name, fld)
 			}
@@ -349,15 +352,86 @@ func toStructType(ctx *blockCtx, v *ast.StructType) *types.Struct {
 	return types.NewStruct(fields, tags)
 }
 
-func toFieldTag(v *ast.BasicLit) string {
-	if v != nil {
-		tag, err := strconv.Unquote(v.Value)
-		if err != nil {
-			log.Panicln("TODO: toFieldTag -", err)
-		}
+// toFieldTag converts a field tag and checks if it is well-formed, ie. a list
+// of `key:"value"` pairs separated by spaces. As a shorthand, a key without
+// value means its value is the field name with the first letter lowercased:
+//
+//	Name string `json yaml:"n"`  // means `json:"name" yaml:"n"`
+func toFieldTag(ctx *blockCtx, v *ast.BasicLit, name string) string {
+	if v == nil {
+		return ""
+	}
+	tag, err := strconv.Unquote(v.Value)
+	if err != nil {
+		log.Panicln("TODO: toFieldTag -", err)
+	}
+	ret, err := expandFieldTag(tag, name)
+	if err != nil {
+		ctx.handleErrorf(v.Pos(), "malformed struct tag %s: %v", v.Value, err)
 		return tag
 	}
-	return ""
+	return ret
+}
+
+func expandFieldTag(tag, name string) (string, error) {
+	var b strings.Builder
+	keys := make(map[string]bool)
+	for tag != "" {
+		i := 0
+		for i < len(tag) && tag[i] == ' ' {
+			i++
+		}
+		if tag = tag[i:]; tag == "" {
+			break
+		}
+		i = 0
+		for i < len(tag) && tag[i] > ' ' && tag[i] != ':' && tag[i] != '"' && tag[i] != 0x7f {
+			i++
+		}
+		key := tag[:i]
+		if key == "" {
+			return "", errors.New("bad syntax for struct tag key")
+		}
+		if keys[key] {
+			return "", fmt.Errorf("duplicate struct tag key %s", key)
+		}
+		keys[key] = true
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		if i == len(tag) || tag[i] == ' ' { // shorthand: key without value
+			if name == "" {
+				return "", fmt.Errorf("struct tag key %s without value requires a field name", key)
+			}
+			b.WriteString(key + ":" + strconv.Quote(strings.ToLower(name[:1])+name[1:]))
+			tag = tag[i:]
+			continue
+		}
+		if tag[i] != ':' || i+1 == len(tag) || tag[i+1] != '"' {
+			return "", fmt.Errorf("bad syntax for struct tag value of key %s", key)
+		}
+		tag = tag[i+1:]
+		i = 1
+		for i < len(tag) && tag[i] != '"' {
+			if tag[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i >= len(tag) {
+			return "", fmt.Errorf("bad syntax for struct tag value of key %s", key)
+		}
+		qvalue := tag[:i+1]
+		if _, err := strconv.Unquote(qvalue); err != nil {
+			return "", fmt.Errorf("bad syntax for struct tag value of key %s", key)
+		}
+		b.WriteString(key + ":" + qvalue)
+		tag = tag[i+1:]
+		if tag != "" && tag[0] != ' ' {
+			return "", errors.New("struct tag pairs must be separated by spaces")
+		}
+	}
+	return b.String(), nil
 }
 
 func getTypeName(typ types.Type) string {
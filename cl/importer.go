@@ -0,0 +1,102 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cl
+
+import (
+	"bufio"
+	"bytes"
+	"go/token"
+	"go/types"
+	"io"
+	"sync"
+
+	"golang.org/x/tools/go/gcexportdata"
+)
+
+// -----------------------------------------------------------------------------
+
+type exportImporter struct {
+	lookup func(pkgPath string) (io.ReadCloser, error)
+	fset   *token.FileSet
+	loaded map[string]*types.Package
+	mutex  sync.Mutex
+}
+
+// NewExportImporter returns an Importer which loads packages from gc export
+// data opened by lookup, so that embedders can resolve import paths from a
+// database, a zip file, another process, etc. without the go command and
+// GOPATH. The export data can be an object file, an archive file, or the raw
+// data written by gcexportdata.Write.
+func NewExportImporter(fset *token.FileSet, lookup func(pkgPath string) (io.ReadCloser, error)) types.ImporterFrom {
+	if fset == nil {
+		fset = token.NewFileSet()
+	}
+	loaded := map[string]*types.Package{"unsafe": types.Unsafe}
+	return &exportImporter{lookup: lookup, fset: fset, loaded: loaded}
+}
+
+func (p *exportImporter) Import(pkgPath string) (*types.Package, error) {
+	return p.ImportFrom(pkgPath, "", 0)
+}
+
+func (p *exportImporter) ImportFrom(pkgPath, dir string, mode types.ImportMode) (pkg *types.Package, err error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if ret, ok := p.loaded[pkgPath]; ok && ret.Complete() {
+		return ret, nil
+	}
+	f, err := p.lookup(pkgPath)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	var r io.Reader = bufio.NewReader(f)
+	if hdr, _ := r.(*bufio.Reader).Peek(10); bytes.HasPrefix(hdr, []byte("!<arch>")) ||
+		bytes.HasPrefix(hdr, []byte("go object")) {
+		if r, err = gcexportdata.NewReader(r); err != nil {
+			return
+		}
+	}
+	return gcexportdata.Read(r, p.fset, p.loaded, pkgPath)
+}
+
+// -----------------------------------------------------------------------------
+
+type chainImporter []types.Importer
+
+// ChainImporters returns an Importer which tries imps in order, and returns
+// the first package found. If all of them fail, it returns the error of the
+// first one.
+func ChainImporters(imps ...types.Importer) types.Importer {
+	return chainImporter(imps)
+}
+
+func (p chainImporter) Import(pkgPath string) (pkg *types.Package, err error) {
+	for i, imp := range p {
+		ret, e := imp.Import(pkgPath)
+		if e == nil {
+			return ret, nil
+		}
+		if i == 0 {
+			err = e
+		}
+	}
+	return
+}
+
+// -----------------------------------------------------------------------------
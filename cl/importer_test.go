@@ -0,0 +1,101 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cl_test
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/types"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/goplus/gop/cl"
+	"golang.org/x/tools/go/gcexportdata"
+)
+
+func exportData(t *testing.T, pkgPath, src string) []byte {
+	f, err := parser.ParseFile(gblFset, "foo.go", src, 0)
+	if err != nil {
+		t.Fatal("parser.ParseFile:", err)
+	}
+	pkg, err := new(types.Config).Check(pkgPath, gblFset, []*ast.File{f}, nil)
+	if err != nil {
+		t.Fatal("types.Check:", err)
+	}
+	var buf bytes.Buffer
+	if err = gcexportdata.Write(&buf, gblFset, pkg); err != nil {
+		t.Fatal("gcexportdata.Write:", err)
+	}
+	return buf.Bytes()
+}
+
+func TestExportImporter(t *testing.T) {
+	data := map[string][]byte{
+		"example.com/foo": exportData(t, "example.com/foo", `package foo
+
+func Add(a, b int) int {
+	return a + b
+}
+`),
+	}
+	n := 0
+	imp := cl.NewExportImporter(gblFset, func(pkgPath string) (io.ReadCloser, error) {
+		n++
+		if b, ok := data[pkgPath]; ok {
+			return io.NopCloser(bytes.NewReader(b)), nil
+		}
+		return nil, os.ErrNotExist
+	})
+	if _, err := imp.Import("example.com/bar"); err != os.ErrNotExist {
+		t.Fatal("Import example.com/bar:", err)
+	}
+	conf := *gblConf
+	conf.Importer = cl.ChainImporters(imp, gblConf.Importer)
+	gopClTestEx(t, &conf, "main", `import "example.com/foo"
+
+println foo.Add(1, 2)
+`, `package main
+
+import (
+	"fmt"
+	"example.com/foo"
+)
+
+func main() {
+	fmt.Println(foo.Add(1, 2))
+}
+`)
+	loaded := n
+	if pkg, err := imp.Import("example.com/foo"); err != nil || pkg.Path() != "example.com/foo" || n != loaded {
+		t.Fatal("Import example.com/foo:", pkg, err, n, loaded)
+	}
+}
+
+func TestChainImporters(t *testing.T) {
+	imp := cl.ChainImporters(
+		cl.NewExportImporter(nil, func(pkgPath string) (io.ReadCloser, error) {
+			return nil, os.ErrNotExist
+		}),
+		cl.NewExportImporter(nil, func(pkgPath string) (io.ReadCloser, error) {
+			return nil, os.ErrPermission
+		}))
+	if _, err := imp.Import("fmt"); err != os.ErrNotExist {
+		t.Fatal("ChainImporters:", err)
+	}
+}
@@ -0,0 +1,176 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cl
+
+import (
+	"go/types"
+
+	"github.com/goplus/gop/ast"
+	"github.com/goplus/gop/token"
+	"github.com/goplus/gox"
+)
+
+// -----------------------------------------------------------------------------
+
+// TypesInfo holds type information of Go+ expressions, keyed by the Go+ ast
+// nodes they are recorded for. It is akin to go/types.Info: only maps that
+// are non-nil are filled.
+type TypesInfo struct {
+	// Types maps expressions to their types, and for constant
+	// expressions, also their values.
+	Types map[ast.Expr]types.TypeAndValue
+
+	// Defs maps identifiers to the objects they define.
+	Defs map[*ast.Ident]types.Object
+
+	// Uses maps identifiers to the objects they denote.
+	Uses map[*ast.Ident]types.Object
+
+	// Selections maps selector expressions (excluding qualified
+	// identifiers) to their corresponding selections.
+	Selections map[*ast.SelectorExpr]*types.Selection
+}
+
+// NewTypesInfo creates a TypesInfo with all of its maps allocated.
+func NewTypesInfo() *TypesInfo {
+	return &TypesInfo{
+		Types:      make(map[ast.Expr]types.TypeAndValue),
+		Defs:       make(map[*ast.Ident]types.Object),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+	}
+}
+
+// TypeOf returns the type of expression e, or nil if not found.
+func (info *TypesInfo) TypeOf(e ast.Expr) types.Type {
+	if t, ok := info.Types[e]; ok {
+		return t.Type
+	}
+	if id, _ := e.(*ast.Ident); id != nil {
+		if obj := info.ObjectOf(id); obj != nil {
+			return obj.Type()
+		}
+	}
+	return nil
+}
+
+// ObjectOf returns the object denoted by the specified id, or nil if not
+// found.
+func (info *TypesInfo) ObjectOf(id *ast.Ident) types.Object {
+	if obj := info.Defs[id]; obj != nil {
+		return obj
+	}
+	return info.Uses[id]
+}
+
+// ExprAt returns the innermost expression covering pos that has a recorded
+// type, and its type and value.
+func (info *TypesInfo) ExprAt(pos token.Pos) (expr ast.Expr, tv types.TypeAndValue, ok bool) {
+	for e, t := range info.Types {
+		if e.Pos() <= pos && pos < e.End() {
+			if !ok || narrower(e, expr) {
+				expr, tv, ok = e, t, true
+			}
+		}
+	}
+	return
+}
+
+func narrower(e, than ast.Expr) bool {
+	if e.Pos() != than.Pos() {
+		return e.Pos() > than.Pos()
+	}
+	return e.End() < than.End()
+}
+
+type infoRecorder struct {
+	info *TypesInfo
+	next Recorder
+}
+
+func (p *infoRecorder) Type(e ast.Expr, tv types.TypeAndValue) {
+	if p.info.Types != nil {
+		p.info.Types[e] = tv
+	}
+	if p.next != nil {
+		p.next.Type(e, tv)
+	}
+}
+
+func (p *infoRecorder) Instantiate(id *ast.Ident, inst types.Instance) {
+	if p.next != nil {
+		p.next.Instantiate(id, inst)
+	}
+}
+
+func (p *infoRecorder) Def(id *ast.Ident, obj types.Object) {
+	if p.info.Defs != nil {
+		p.info.Defs[id] = obj
+	}
+	if p.next != nil {
+		p.next.Def(id, obj)
+	}
+}
+
+func (p *infoRecorder) Use(id *ast.Ident, obj types.Object) {
+	if p.info.Uses != nil {
+		p.info.Uses[id] = obj
+	}
+	if p.next != nil {
+		p.next.Use(id, obj)
+	}
+}
+
+func (p *infoRecorder) Implicit(node ast.Node, obj types.Object) {
+	if p.next != nil {
+		p.next.Implicit(node, obj)
+	}
+}
+
+func (p *infoRecorder) Select(e *ast.SelectorExpr, sel *types.Selection) {
+	if p.info.Selections != nil {
+		p.info.Selections[e] = sel
+	}
+	if p.next != nil {
+		p.next.Select(e, sel)
+	}
+}
+
+func (p *infoRecorder) Scope(n ast.Node, scope *types.Scope) {
+	if p.next != nil {
+		p.next.Scope(n, scope)
+	}
+}
+
+func (p *infoRecorder) Class(info *ClassInfo) {
+	if cr, ok := p.next.(ClassRecorder); ok {
+		cr.Class(info)
+	}
+}
+
+// NewPackageEx is like NewPackage, but it also returns type information of
+// the Go+ expressions of the package. conf.Recorder, if any, still receives
+// all recording events.
+func NewPackageEx(pkgPath string, pkg *ast.Package, conf *Config) (p *gox.Package, info *TypesInfo, err error) {
+	info = NewTypesInfo()
+	confEx := *conf
+	confEx.Recorder = &infoRecorder{info: info, next: conf.Recorder}
+	p, err = NewPackage(pkgPath, pkg, &confEx)
+	return
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,81 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cl_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/goplus/gop/ast"
+	"github.com/goplus/gop/cl"
+	"github.com/goplus/gop/parser"
+	"github.com/goplus/gop/parser/fsx/memfs"
+	"github.com/goplus/gop/token"
+)
+
+func TestTypesInfo(t *testing.T) {
+	const src = `type T struct {
+	name string
+}
+
+x := 1.0
+y := x + 1.5
+t := &T{name: "hi"}
+println y, t.name
+`
+	fs := memfs.SingleFile("/foo", "bar.gop", src)
+	pkgs, err := parser.ParseFSDir(gblFset, fs, "/foo", parser.Config{})
+	if err != nil {
+		t.Fatal("ParseFSDir:", err)
+	}
+	_, info, err := cl.NewPackageEx("", pkgs["main"], gblConf)
+	if err != nil {
+		t.Fatal("NewPackageEx:", err)
+	}
+	f := pkgs["main"].Files["/foo/bar.gop"]
+	posOf := func(s string) token.Pos {
+		return f.Pos() + token.Pos(strings.Index(src, s)-strings.Index(src, "type"))
+	}
+	if e, tv, ok := info.ExprAt(posOf("1.5")); !ok || tv.Type.String() != "untyped float" {
+		t.Fatal("ExprAt 1.5:", e, tv, ok)
+	}
+	var y *ast.Ident
+	var sel *ast.SelectorExpr
+	ast.Inspect(f, func(n ast.Node) bool {
+		switch v := n.(type) {
+		case *ast.Ident:
+			if v.Name == "y" && y == nil {
+				y = v
+			}
+		case *ast.SelectorExpr:
+			sel = v
+		}
+		return true
+	})
+	if typ := info.TypeOf(y); typ == nil || typ.String() != "float64" {
+		t.Fatal("TypeOf y:", typ)
+	}
+	if typ := info.TypeOf(sel); typ == nil || typ.String() != "string" {
+		t.Fatal("TypeOf t.name:", typ)
+	}
+	if obj := info.ObjectOf(sel.Sel); obj == nil || obj.Name() != "name" {
+		t.Fatal("ObjectOf name:", obj)
+	}
+	if _, ok := info.Defs[y]; !ok {
+		t.Fatal("Defs: y not found")
+	}
+}
@@ -0,0 +1,43 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package spx3
+
+const (
+	GopPackage     = true
+	Gop_bind       = "Gop_Bind"
+	Gop_workfields = true
+)
+
+type Game struct {
+}
+
+func (p *Game) OnStart(onStart func()) {
+}
+
+func Gopt_Game_Main(game interface{}) {
+}
+
+type Sprite struct {
+}
+
+func (p *Sprite) OnClick(onClick func()) {
+}
+
+func Gopt_Sprite_OnKey__0(sprite interface{}, onKey func(key string)) {
+}
+
+func Gopt_Sprite_OnKey__1(sprite interface{}, key string, onKey func()) {
+}
@@ -0,0 +1,85 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cl
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/goplus/gop/ast"
+	"github.com/goplus/gop/token"
+)
+
+// A language version of Go+ is represented as major*1000 + minor.
+const (
+	lang1_1    = 1001
+	langLatest = 1<<31 - 1
+)
+
+// parseLang parses a language version in form of gopX.Y (the "gop" prefix is
+// optional). An empty lang means the latest version.
+func parseLang(lang string) (ver int, err error) {
+	if lang == "" {
+		return langLatest, nil
+	}
+	s := strings.TrimPrefix(lang, "gop")
+	if pos := strings.IndexByte(s, '.'); pos > 0 {
+		major, e1 := strconv.ParseUint(s[:pos], 10, 16)
+		minor, e2 := strconv.ParseUint(s[pos+1:], 10, 16)
+		if e1 == nil && e2 == nil && minor < 1000 {
+			return int(major)*1000 + int(minor), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid language version: %s", lang)
+}
+
+// deprecated reports a warning of syntax deprecated since language version
+// since, unless the package is compiled with an older language version (see
+// Config.Lang). edits are fixes which `gop fix` applies.
+func (p *pkgCtx) deprecated(since int, pos, end token.Pos, msg string, edits ...TextEdit) {
+	if p.lang < since {
+		return
+	}
+	d := p.newDiagf(SeverityWarning, pos, end, "%s (deprecated since gop%d.%d, run gop fix)", msg, since/1000, since%1000)
+	d.Code = "deprecated"
+	if edits != nil {
+		d.Fix(msg, edits...)
+	}
+	p.handleDiag(d)
+}
+
+// checkForPhraseCond checks the deprecated syntax `for x <- container, cond`
+// (Go+ 1.0), which should be `for x <- container if cond`.
+func checkForPhraseCond(ctx *blockCtx, fp *ast.ForPhrase) {
+	if fp.Cond != nil && ctx.srcByte(fp.IfPos) == ',' {
+		ctx.deprecated(lang1_1, fp.IfPos, fp.IfPos+1,
+			`use "if" instead of "," before condition of for phrase`,
+			Replace(fp.IfPos, fp.IfPos+1, " if"))
+	}
+}
+
+// srcByte returns the source byte at pos, or 0 if unavailable.
+func (p *nodeInterp) srcByte(pos token.Pos) byte {
+	position := p.fset.Position(pos)
+	if f := p.files[position.Filename]; f != nil && position.Offset < len(f.Code) {
+		return f.Code[position.Offset]
+	}
+	return 0
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,72 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cl_test
+
+import (
+	"testing"
+
+	"github.com/goplus/gop/ast"
+	"github.com/goplus/gop/cl"
+)
+
+const forPhraseComma = `
+arr := [1, 3, 5]
+for x <- arr, x > 1 {
+	println x
+}
+println [x for x <- arr, x > 3], [x for x <- arr if x > 3]
+`
+
+func TestLangDeprecated(t *testing.T) {
+	warnTest(t, forPhraseComma,
+		`bar.gop:3:13: use "if" instead of "," before condition of for phrase (deprecated since gop1.1, run gop fix)`,
+		`bar.gop:6:24: use "if" instead of "," before condition of for phrase (deprecated since gop1.1, run gop fix)`)
+	warnTest(t, `
+for x <- [1, 3], x > 1 { //nolint:deprecated
+	println x
+}
+`)
+
+	lang := gblConf.Lang
+	defer func() { gblConf.Lang = lang }()
+	gblConf.Lang = "gop1.0"
+	warnTest(t, forPhraseComma)
+	gblConf.Lang = "1.1"
+	warnTest(t, `
+for x <- [1, 3], x > 1 {
+}
+`, `bar.gop:2:16: use "if" instead of "," before condition of for phrase (deprecated since gop1.1, run gop fix)`)
+}
+
+func TestFixDeprecated(t *testing.T) {
+	fixTest(t, `
+println [x for x <- [1, 3, 5], x > 1]
+`, `
+println [x for x <- [1, 3, 5] if x > 1]
+`)
+}
+
+func TestErrLang(t *testing.T) {
+	for _, lang := range []string{"gop", "gop1", "1.x", "gop1.1000", "gop.1"} {
+		conf := *gblConf
+		conf.Lang = lang
+		pkg := &ast.Package{Name: "main", Files: map[string]*ast.File{}}
+		if _, err := cl.NewPackage("", pkg, &conf); err == nil || err.Error() != "invalid language version: "+lang {
+			t.Fatal("NewPackage:", lang, err)
+		}
+	}
+}
@@ -197,7 +197,7 @@ func (p *All) getNamed(t *types.TypeName) *TypeName {
 func (p *All) initNamed(aliasr *typeutil.Map, objs []types.Object) {
 	for _, o := range objs {
 		if t, ok := o.(*types.TypeName); ok {
-			named := &TypeName{TypeName: t}
+			named := &TypeName{TypeName: t, docs: p.docs}
 			p.named[t] = named
 			p.Types = append(p.Types, named)
 			if t.IsAlias() {
@@ -418,6 +418,7 @@ type TypeName struct {
 	Creators  []Func
 	GoptFuncs []Func
 	Helpers   []Func
+	docs      gox.ObjectDocs
 	isUsed    bool
 }
 
@@ -438,7 +439,7 @@ func (p *TypeName) Obj() types.Object {
 }
 
 func (p *TypeName) Doc() string {
-	return ""
+	return p.docs[p.TypeName].Text()
 }
 
 func (p *TypeName) Type() Type {
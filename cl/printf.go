@@ -0,0 +1,205 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cl
+
+import (
+	"go/constant"
+	"go/types"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/goplus/gop/ast"
+	"github.com/goplus/gop/internal/printf"
+	"github.com/goplus/gox"
+)
+
+// -----------------------------------------------------------------------------
+
+// bigVerbs lists the verbs accepted by Go+ big number types.
+var bigVerbs = map[string]string{
+	"Bigint":          "bdoOsvxX",
+	"UntypedBigint":   "bdoOsvxX",
+	"Int128":          "bdoOsvxX",
+	"Uint128":         "bdoOsvxX",
+	"Bigrat":          "qsvxX",
+	"UntypedBigrat":   "qsvxX",
+	"Bigfloat":        "beEfFgGpvxX",
+	"UntypedBigfloat": "beEfFgGpvxX",
+}
+
+// checkPrintfArgs reports a warning for each argument of a printf-style call
+// with a constant format that doesn't match its verb, for each verb without
+// argument, and for arguments left over by the format. It runs on the stack of
+// the call, so types of Go+ expressions (eg. big number literals) are known.
+// Checking stops at a directive without verb or with an unknown verb, which
+// are reported by “gop vet”.
+func checkPrintfArgs(ctx *blockCtx, v *ast.CallExpr, ellipsis bool) {
+	n := len(v.Args)
+	if ellipsis {
+		return
+	}
+	stk := ctx.cb.InternalStack()
+	idx, ok := printfFormatIndex(ctx, v.Fun, stk.Get(-n-1).Type)
+	if !ok || idx >= n {
+		return
+	}
+	args := stk.GetArgs(n)
+	format := args[idx].CVal
+	if format == nil || format.Kind() != constant.String {
+		return
+	}
+	for _, arg := range args {
+		if _, ok := arg.Type.(*types.Tuple); ok {
+			return
+		}
+	}
+	name := ctx.LoadExpr(v.Fun)
+	s, argi := constant.StringVal(format), idx+1
+	for {
+		rest, verb, nstar, ok := printf.NextDirective(s)
+		if !ok {
+			break
+		}
+		s = rest
+		if verb == "%%" {
+			continue
+		}
+		if strings.IndexByte(verb, '[') >= 0 { // explicit argument indexes
+			return
+		}
+		r, _ := utf8.DecodeLastRuneInString(verb)
+		if !printf.Complete(verb) || !strings.ContainsRune(printf.Verbs, r) {
+			return
+		}
+		argi += nstar
+		if argi >= n {
+			ctx.handleDiag(printfDiag(ctx, v, "%s format %s reads arg #%d, but call has %s",
+				name, verb, argi-idx, printf.CountArgs(n-idx-1)))
+			return
+		}
+		if !printfArgMatch(args[argi].Type, r) {
+			arg := v.Args[argi]
+			d := ctx.newDiagf(SeverityWarning, arg.Pos(), arg.End(),
+				"%s format %s has arg %s of wrong type %s",
+				name, verb, ctx.LoadExpr(arg), types.TypeString(args[argi].Type, ctx.qualifier))
+			d.Code = "printf"
+			ctx.handleDiag(d)
+		}
+		argi++
+	}
+	if argi < n {
+		ctx.handleDiag(printfDiag(ctx, v, "%s call needs %s but has %s",
+			name, printf.CountArgs(argi-idx-1), printf.CountArgs(n-idx-1)))
+	}
+}
+
+func printfDiag(ctx *blockCtx, v *ast.CallExpr, format string, args ...interface{}) *Diagnostic {
+	d := ctx.newDiagf(SeverityWarning, v.Pos(), v.End(), format, args...)
+	d.Code = "printf"
+	return d
+}
+
+// printfFormatIndex returns the index of the format argument if fn, of type
+// ft, is a printf-style function.
+func printfFormatIndex(ctx *blockCtx, fn ast.Expr, ft types.Type) (int, bool) {
+	sig, ok := ft.(*types.Signature)
+	if !ok {
+		return 0, false
+	}
+	var o types.Object
+	switch fn := fn.(type) {
+	case *ast.Ident:
+		if funcs, ok := gox.CheckOverloadFunc(sig); ok && len(funcs) == 1 { // builtin
+			o = funcs[0]
+		}
+	case *ast.SelectorExpr:
+		if x, ok := fn.X.(*ast.Ident); ok {
+			if pi, ok := ctx.findImport(x.Name); ok {
+				if ref, _ := pkgRef(pi.PkgRef, fn.Sel.Name); ref != nil && ref.Type() == ft {
+					o = ref
+				}
+			}
+		}
+	}
+	if o == nil || o.Pkg() == nil {
+		return 0, false
+	}
+	idx, ok := printf.Funcs[o.Pkg().Path()+"."+o.Name()]
+	return idx, ok
+}
+
+// printfArgMatch checks if an argument of type t can be formatted by verb.
+// Composite types are formatted element by element, so only their elements
+// are checked, and types with a Format method accept any verb.
+func printfArgMatch(t types.Type, verb rune) bool {
+	switch verb {
+	case 'v', 'T':
+		return true
+	}
+	if named, ok := t.(*types.Named); ok {
+		if obj := named.Obj(); obj.Pkg() != nil && obj.Pkg().Path() == "github.com/goplus/gop/builtin/ng" {
+			if verbs, ok := bigVerbs[obj.Name()]; ok {
+				return strings.ContainsRune(verbs, verb)
+			}
+		}
+	}
+	if typeHasMethod(t, "Format") {
+		return true
+	}
+	if verb == 'w' {
+		return typeHasMethod(t, "Error") || types.Identical(t, types.Typ[types.UntypedNil])
+	}
+	if strings.ContainsRune("sqxX", verb) && (typeHasMethod(t, "Error") || typeHasMethod(t, "String")) {
+		return true
+	}
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		info := u.Info()
+		switch {
+		case info&types.IsInteger != 0:
+			return strings.ContainsRune("bcdoOqxXU", verb)
+		case info&(types.IsFloat|types.IsComplex) != 0:
+			return strings.ContainsRune("beEfFgGxX", verb)
+		case info&types.IsString != 0:
+			return strings.ContainsRune("sqxX", verb)
+		case info&types.IsBoolean != 0:
+			return verb == 't'
+		}
+		return true
+	case *types.Slice:
+		if verb == 'p' {
+			return true
+		}
+		if b, ok := u.Elem().Underlying().(*types.Basic); ok && b.Kind() == types.Byte {
+			if strings.ContainsRune("sqxX", verb) {
+				return true
+			}
+		}
+		return printfArgMatch(u.Elem(), verb)
+	case *types.Array:
+		return printfArgMatch(u.Elem(), verb)
+	}
+	return true
+}
+
+func typeHasMethod(t types.Type, name string) bool {
+	obj, _, _ := types.LookupFieldOrMethod(t, false, nil, name)
+	_, ok := obj.(*types.Func)
+	return ok
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,97 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cl_test
+
+import (
+	"testing"
+)
+
+func TestPrintfCheck(t *testing.T) {
+	warnTest(t, `import "fmt"
+
+var n = 1
+var s = "hi"
+printf "%d %s\n", n, s
+printf "%d\n", s
+printf "%s %5.2f\n", n, 1
+x := sprintf("%t", 1.5)
+fmt.Printf "%x %q %c\n", s, 'a', n
+fmt.printf "%d\n", "foo"
+println x
+`,
+		"bar.gop:6:16: printf format %d has arg s of wrong type string",
+		"bar.gop:7:22: printf format %s has arg n of wrong type int",
+		"bar.gop:7:25: printf format %5.2f has arg 1 of wrong type untyped int",
+		"bar.gop:8:20: sprintf format %t has arg 1.5 of wrong type untyped float",
+		"bar.gop:10:20: fmt.printf format %d has arg \"foo\" of wrong type untyped string",
+	)
+}
+
+func TestPrintfCheckArgs(t *testing.T) {
+	warnTest(t, `import "os"
+
+fprintf os.Stderr, "%d %d\n", 1
+printf "%d\n", 1, 2
+printf "%*d %[1]d %%\n", 3, 4
+printf "%v %T\n", nil
+printf "%z %d\n", 1
+printf "100%"
+`,
+		"bar.gop:3:1: fprintf format %d reads arg #2, but call has 1 arg",
+		"bar.gop:4:1: printf call needs 1 arg but has 2 args",
+		"bar.gop:6:1: printf format %T reads arg #2, but call has 1 arg",
+	)
+}
+
+func TestPrintfCheckBig(t *testing.T) {
+	warnTest(t, `
+a := 1r << 65
+b := 3/4r
+var c bigfloat
+printf "%d %x %v\n", a, a, a
+printf "%s %v\n", b, b
+printf "%.2f %e\n", c, c
+printf "%f %d %s\n", a, b, c
+printf "%d\n", 1r << 65
+`,
+		"bar.gop:8:22: printf format %f has arg a of wrong type ng.Bigint",
+		"bar.gop:8:25: printf format %d has arg b of wrong type ng.Bigrat",
+		"bar.gop:8:28: printf format %s has arg c of wrong type ng.Bigfloat",
+	)
+}
+
+func TestPrintfCheckMethods(t *testing.T) {
+	warnTest(t, `import "errors"
+
+type T string
+
+func (T) String() string { return "T" }
+
+type Ints []int
+
+err := errors.New("x")
+printf "%s %q %d\n", T("a"), err, Ints{1, 2}
+printf "%d %x\n", []byte("ab"), []byte("ab")
+printf "%d\n", T("b")
+errorf "%w", err
+errorf "%w", 1
+errorf "%w", nil
+`,
+		"bar.gop:12:16: printf format %d has arg T(\"b\") of wrong type T",
+		"bar.gop:14:14: errorf format %w has arg 1 of wrong type untyped int",
+	)
+}
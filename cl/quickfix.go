@@ -0,0 +1,272 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cl
+
+import (
+	"go/types"
+	"strings"
+
+	"github.com/goplus/gop/ast"
+	"github.com/goplus/gop/token"
+)
+
+// -----------------------------------------------------------------------------
+
+// checkMissingReturn reports a warning if the function body doesn't end in a
+// terminating statement but the function has results. It is a warning rather
+// than an error as the Go compiler rejects the generated code anyway. The
+// suggested fix returns zero values (or named results) at the end of the body.
+func checkMissingReturn(ctx *blockCtx, sig *types.Signature, body *ast.BlockStmt) {
+	results := sig.Results()
+	if results.Len() == 0 || ctx.isTerminating(body, "") {
+		return
+	}
+	ret := "return"
+	if results.At(0).Name() == "" {
+		vals := make([]string, results.Len())
+		for i := range vals {
+			vals[i] = zeroValueOf(ctx, results.At(i).Type())
+		}
+		ret += " " + strings.Join(vals, ", ")
+	}
+	rbrace := body.Rbrace
+	if indent, ok := ctx.lineIndent(rbrace); ok {
+		ret = "\t" + ret + "\n" + indent
+	} else if ret += " "; len(body.List) > 0 {
+		ret = "; " + ret
+	}
+	d := ctx.newDiagf(SeverityWarning, rbrace, rbrace+1, "missing return")
+	d.Code = "missingreturn"
+	d.Fix("add return statement", Insert(rbrace, ret))
+	ctx.handleDiag(d)
+}
+
+// zeroValueOf returns the zero value of typ in Go+ syntax.
+func zeroValueOf(ctx *blockCtx, typ types.Type) string {
+	switch t := typ.Underlying().(type) {
+	case *types.Basic:
+		switch info := t.Info(); {
+		case info&types.IsBoolean != 0:
+			return "false"
+		case info&types.IsNumeric != 0:
+			return "0"
+		case info&types.IsString != 0:
+			return `""`
+		}
+	case *types.Struct, *types.Array:
+		return types.TypeString(typ, ctx.qualifier) + "{}"
+	case *types.Interface:
+		if _, ok := typ.(*types.TypeParam); ok {
+			return "*new(" + types.TypeString(typ, ctx.qualifier) + ")"
+		}
+	}
+	return "nil"
+}
+
+// qualifier qualifies objects of imported packages by their package names,
+// as they are referred to in Go+ source code.
+func (p *blockCtx) qualifier(pkg *types.Package) string {
+	if pkg == p.pkg.Types {
+		return ""
+	}
+	return pkg.Name()
+}
+
+// lineIndent returns the indentation of the line of pos, if only whitespaces
+// precede pos in the line.
+func (p *nodeInterp) lineIndent(pos token.Pos) (string, bool) {
+	position := p.fset.Position(pos)
+	f := p.files[position.Filename]
+	if f == nil || position.Offset > len(f.Code) {
+		return "", false
+	}
+	indent := f.Code[position.Offset-position.Column+1 : position.Offset]
+	if strings.TrimLeft(string(indent), " \t") != "" {
+		return "", false
+	}
+	return string(indent), true
+}
+
+// isTerminating reports whether s is a terminating statement (see "Terminating
+// statements" of the Go spec). If s is labeled, label is its label.
+func (p *blockCtx) isTerminating(s ast.Stmt, label string) bool {
+	switch s := s.(type) {
+	case *ast.ReturnStmt:
+		return true
+	case *ast.BranchStmt:
+		return s.Tok == token.GOTO || s.Tok == token.FALLTHROUGH
+	case *ast.ExprStmt:
+		return p.isPanic(s.X)
+	case *ast.BlockStmt:
+		return p.isTerminatingList(s.List, "")
+	case *ast.LabeledStmt:
+		return p.isTerminating(s.Stmt, s.Label.Name)
+	case *ast.IfStmt:
+		return s.Else != nil && p.isTerminating(s.Body, "") && p.isTerminating(s.Else, "")
+	case *ast.SwitchStmt:
+		return p.isTerminatingSwitch(s.Body, label)
+	case *ast.TypeSwitchStmt:
+		return p.isTerminatingSwitch(s.Body, label)
+	case *ast.SelectStmt:
+		for _, stmt := range s.Body.List {
+			cc, ok := stmt.(*ast.CommClause)
+			if !ok || !p.isTerminatingList(cc.Body, "") || hasBreakList(cc.Body, label, true) {
+				return false
+			}
+		}
+		return true
+	case *ast.ForStmt:
+		return s.Cond == nil && !hasBreak(s.Body, label, true)
+	}
+	return false
+}
+
+func (p *blockCtx) isTerminatingList(list []ast.Stmt, label string) bool {
+	for i := len(list) - 1; i >= 0; i-- { // trailing empty statements are permitted
+		if _, ok := list[i].(*ast.EmptyStmt); !ok {
+			return p.isTerminating(list[i], label)
+		}
+	}
+	return false
+}
+
+func (p *blockCtx) isTerminatingSwitch(body *ast.BlockStmt, label string) bool {
+	hasDefault := false
+	for _, stmt := range body.List {
+		cc, ok := stmt.(*ast.CaseClause)
+		if !ok {
+			return false
+		}
+		if cc.List == nil {
+			hasDefault = true
+		}
+		if !p.isTerminatingList(cc.Body, "") || hasBreakList(cc.Body, label, true) {
+			return false
+		}
+	}
+	return hasDefault
+}
+
+// isPanic reports whether x is a call of the builtin panic.
+func (p *blockCtx) isPanic(x ast.Expr) bool {
+	if call, ok := x.(*ast.CallExpr); ok {
+		if id, ok := call.Fun.(*ast.Ident); ok && id.Name == "panic" {
+			_, o := p.cb.Scope().LookupParent("panic", token.NoPos)
+			_, ok = o.(*types.Builtin)
+			return o == nil || ok
+		}
+	}
+	return false
+}
+
+// hasBreak reports whether s is or contains a break statement referring to
+// the statement labeled label, or to the enclosing statement if implicit.
+func hasBreak(s ast.Stmt, label string, implicit bool) bool {
+	switch s := s.(type) {
+	case *ast.BranchStmt:
+		if s.Tok == token.BREAK {
+			if s.Label == nil {
+				return implicit
+			}
+			return s.Label.Name == label
+		}
+	case *ast.BlockStmt:
+		return hasBreakList(s.List, label, implicit)
+	case *ast.LabeledStmt:
+		return hasBreak(s.Stmt, label, implicit)
+	case *ast.IfStmt:
+		return hasBreak(s.Body, label, implicit) || s.Else != nil && hasBreak(s.Else, label, implicit)
+	case *ast.CaseClause:
+		return hasBreakList(s.Body, label, implicit)
+	case *ast.CommClause:
+		return hasBreakList(s.Body, label, implicit)
+	case *ast.SwitchStmt:
+		return label != "" && hasBreak(s.Body, label, false)
+	case *ast.TypeSwitchStmt:
+		return label != "" && hasBreak(s.Body, label, false)
+	case *ast.SelectStmt:
+		return label != "" && hasBreak(s.Body, label, false)
+	case *ast.ForStmt:
+		return label != "" && hasBreak(s.Body, label, false)
+	case *ast.RangeStmt:
+		return label != "" && hasBreak(s.Body, label, false)
+	case *ast.ForPhraseStmt:
+		return label != "" && hasBreak(s.Body, label, false)
+	}
+	return false
+}
+
+func hasBreakList(list []ast.Stmt, label string, implicit bool) bool {
+	for _, s := range list {
+		if hasBreak(s, label, implicit) {
+			return true
+		}
+	}
+	return false
+}
+
+// -----------------------------------------------------------------------------
+
+// checkErrIgnored reports a warning if call is used as a statement and its
+// results (T, error) are ignored, which is checked only in strict mode. Like
+// errcheck, results of printing functions are not checked. The suggested fix
+// propagates the error by `_ = call?` if the enclosing function returns an
+// error, or panics on the error by `call!` otherwise.
+func checkErrIgnored(ctx *blockCtx, x ast.Expr, results types.Type) {
+	call, ok := x.(*ast.CallExpr)
+	if !ok || isPrintCall(call) {
+		return
+	}
+	t, ok := results.(*types.Tuple)
+	if !ok || t.Len() != 2 || !isErrorType(t.At(1).Type()) {
+		return
+	}
+	d := ctx.newDiagf(SeverityWarning, call.Pos(), call.End(), "result of type (%s, error) is not checked",
+		types.TypeString(t.At(0).Type(), ctx.qualifier))
+	d.Code = "errcheck"
+	if call.NoParenEnd == token.NoPos {
+		sig := ctx.cb.Func().Type().(*types.Signature)
+		if n := sig.Results().Len(); n > 0 && isErrorType(sig.Results().At(n-1).Type()) {
+			d.Fix("return the error", Insert(call.Pos(), "_ = "), Insert(call.End(), "?"))
+		} else {
+			d.Fix("panic on the error", Insert(call.End(), "!"))
+		}
+	}
+	ctx.handleDiag(d)
+}
+
+func isErrorType(t types.Type) bool {
+	return types.Identical(t, types.Universe.Lookup("error").Type())
+}
+
+func isPrintCall(call *ast.CallExpr) bool {
+	switch fn := call.Fun.(type) {
+	case *ast.Ident:
+		switch fn.Name {
+		case "print", "println", "printf", "echo":
+			return true
+		}
+	case *ast.SelectorExpr:
+		if x, ok := fn.X.(*ast.Ident); ok && x.Name == "fmt" {
+			name := fn.Sel.Name
+			return strings.HasPrefix(name, "Print") || strings.HasPrefix(name, "Fprint")
+		}
+	}
+	return false
+}
+
+// -----------------------------------------------------------------------------
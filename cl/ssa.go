@@ -0,0 +1,131 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cl
+
+import (
+	"bytes"
+	"fmt"
+	goast "go/ast"
+	goparser "go/parser"
+	gotoken "go/token"
+	"go/types"
+	"io"
+	"sort"
+	"syscall"
+
+	"github.com/goplus/gox"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/ssautil"
+)
+
+// -----------------------------------------------------------------------------
+
+type ssaBackend struct{}
+
+func (ssaBackend) Name() string {
+	return "ssa"
+}
+
+// WriteTo writes the SSA form of Go code generated for the file named fname
+// of pkg. Locations of functions are in Go+ source if the Go code has //line
+// directives.
+func (ssaBackend) WriteTo(dst io.Writer, pkg *gox.Package, fname ...string) (err error) {
+	var b bytes.Buffer
+	if err = GoBackend.WriteTo(&b, pkg, fname...); err != nil {
+		return
+	}
+	fset := gotoken.NewFileSet()
+	f, err := goparser.ParseFile(fset, "gop_autogen.go", b.Bytes(), goparser.ParseComments)
+	if err != nil {
+		return
+	}
+	conf := &types.Config{Importer: ssaImporter{pkg}}
+	pkgPath := pkg.Path()
+	if pkgPath == "" { // eg. package main
+		pkgPath = pkg.Types.Name()
+	}
+	typs := types.NewPackage(pkgPath, pkg.Types.Name())
+	ssaPkg, _, err := ssautil.BuildPackage(conf, fset, typs, []*goast.File{f}, ssa.InstantiateGenerics)
+	if err != nil {
+		return
+	}
+	var fns []*ssa.Function
+	for _, m := range ssaPkg.Members {
+		switch v := m.(type) {
+		case *ssa.Function:
+			fns = append(fns, v)
+		case *ssa.Type:
+			fns = append(fns, methodsOf(ssaPkg.Prog, v.Type())...)
+		}
+	}
+	sort.Slice(fns, func(i, j int) bool { // in order of source, init first
+		return fns[i].Pos() < fns[j].Pos()
+	})
+	_, err = ssaPkg.WriteTo(dst)
+	for _, fn := range fns {
+		if err != nil {
+			return
+		}
+		err = writeSSAFunc(dst, fn)
+	}
+	return
+}
+
+func methodsOf(prog *ssa.Program, t types.Type) (fns []*ssa.Function) {
+	for _, typ := range [...]types.Type{t, types.NewPointer(t)} {
+		mset := prog.MethodSets.MethodSet(typ)
+		for i, n := 0, mset.Len(); i < n; i++ {
+			sel := mset.At(i)
+			if sel.Obj().Pkg() != t.(*types.Named).Obj().Pkg() || len(sel.Index()) > 1 { // promoted
+				continue
+			}
+			if fn := prog.MethodValue(sel); fn != nil && fn.Synthetic == "" {
+				fns = append(fns, fn)
+			}
+		}
+	}
+	return
+}
+
+func writeSSAFunc(dst io.Writer, fn *ssa.Function) (err error) {
+	if _, err = fmt.Fprintln(dst); err != nil {
+		return
+	}
+	if _, err = fn.WriteTo(dst); err != nil {
+		return
+	}
+	for _, anon := range fn.AnonFuncs {
+		if err = writeSSAFunc(dst, anon); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// ssaImporter imports packages which pkg imported when it was compiled.
+type ssaImporter struct {
+	pkg *gox.Package
+}
+
+func (p ssaImporter) Import(pkgPath string) (*types.Package, error) {
+	if ref := p.pkg.TryImport(pkgPath); ref != nil {
+		return ref.Types, nil
+	}
+	return nil, syscall.ENOENT
+}
+
+// -----------------------------------------------------------------------------
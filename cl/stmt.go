@@ -46,41 +46,62 @@ func relFile(dir string, absFile string) string {
 	return absFile
 }
 
-func commentStmt(ctx *blockCtx, stmt ast.Stmt) {
-	if ctx.fileLine {
-		start := stmt.Pos()
-		pos := ctx.fset.Position(start)
-		if ctx.relBaseDir != "" {
-			pos.Filename = fileLineFile(ctx.relBaseDir, pos.Filename)
+// fileLinePos returns position of start used by //line directives.
+func fileLinePos(ctx *blockCtx, start token.Pos) token.Position {
+	pos := ctx.fset.Position(start)
+	if ctx.absFileLine {
+		if abs, err := filepath.Abs(pos.Filename); err == nil {
+			pos.Filename = filepath.ToSlash(abs)
 		}
+	} else if ctx.relBaseDir != "" {
+		pos.Filename = fileLineFile(ctx.relBaseDir, pos.Filename)
+	}
+	return pos
+}
+
+// fileLineDoc returns doc followed by a //line directive of start, to map the
+// declaration to its Go+ source.
+func fileLineDoc(ctx *blockCtx, doc *ast.CommentGroup, start token.Pos, noCol bool) *goast.CommentGroup {
+	pos := fileLinePos(ctx, start)
+	var line string
+	if noCol {
+		line = fmt.Sprintf("//line %s:%d", pos.Filename, pos.Line)
+	} else {
+		line = fmt.Sprintf("//line %s:%d:1", pos.Filename, pos.Line)
+	}
+	ret := &goast.CommentGroup{}
+	if doc != nil {
+		ret.List = append(ret.List, doc.List...)
+		ret.List = append(ret.List, &goast.Comment{Text: "//"})
+	}
+	ret.List = append(ret.List, &goast.Comment{Text: line})
+	return ret
+}
+
+func commentStmt(ctx *blockCtx, stmt ast.Stmt) {
+	var list []*goast.Comment
+	doc, ok := ctx.stmtComments[stmt]
+	if ok {
+		// start at a new line, see hasKeptComments
+		list = append(list, &goast.Comment{Text: "\n" + doc.List[0].Text})
+		list = append(list, doc.List[1:]...)
+	}
+	if ctx.fileLine && stmt.Pos() != token.NoPos { // no position if generated, eg. by expanders
+		pos := fileLinePos(ctx, stmt.Pos())
 		line := fmt.Sprintf("\n//line %s:%d:1", pos.Filename, pos.Line)
-		comments := &goast.CommentGroup{
-			List: []*goast.Comment{{Text: line}},
-		}
-		ctx.cb.SetComments(comments, false)
+		list = append(list, &goast.Comment{Text: line})
+	}
+	if list != nil {
+		// comments in source are generated only once even if the statement
+		// is compiled into multiple Go statements
+		ctx.cb.SetComments(&goast.CommentGroup{List: list}, ok)
 	}
 }
 
 func commentFunc(ctx *blockCtx, fn *gox.Func, decl *ast.FuncDecl) {
 	start := decl.Name.Pos()
 	if ctx.fileLine && start != token.NoPos {
-		pos := ctx.fset.Position(start)
-		if ctx.relBaseDir != "" {
-			pos.Filename = fileLineFile(ctx.relBaseDir, pos.Filename)
-		}
-		var line string
-		if decl.Shadow {
-			line = fmt.Sprintf("//line %s:%d", pos.Filename, pos.Line)
-		} else {
-			line = fmt.Sprintf("//line %s:%d:1", pos.Filename, pos.Line)
-		}
-		doc := &goast.CommentGroup{}
-		if decl.Doc != nil {
-			doc.List = append(doc.List, decl.Doc.List...)
-			doc.List = append(doc.List, &goast.Comment{Text: "//"})
-		}
-		doc.List = append(doc.List, &goast.Comment{Text: line})
-		fn.SetComments(ctx.pkg, doc)
+		fn.SetComments(ctx.pkg, fileLineDoc(ctx, decl.Doc, start, decl.Shadow))
 	} else if decl.Doc != nil {
 		fn.SetComments(ctx.pkg, decl.Doc)
 	}
@@ -94,6 +115,9 @@ func compileStmts(ctx *blockCtx, body []ast.Stmt) {
 		}
 	}
 	for _, stmt := range body {
+		if ctx.trace {
+			traceStmt(ctx, stmt)
+		}
 		compileStmt(ctx, stmt)
 	}
 }
@@ -107,9 +131,13 @@ func compileStmt(ctx *blockCtx, stmt ast.Stmt) {
 			}
 		}()
 	}
+	ctx.checkAbort(stmt.Pos())
 	commentStmt(ctx, stmt)
 	switch v := stmt.(type) {
 	case *ast.ExprStmt:
+		if compileAssertStmt(ctx, v) {
+			break
+		}
 		inFlags := 0
 		if isCommandWithoutArgs(v.X) {
 			inFlags = clCommandWithoutArgs
@@ -118,6 +146,9 @@ func compileStmt(ctx *blockCtx, stmt ast.Stmt) {
 		if inFlags != 0 && gox.IsFunc(ctx.cb.InternalStack().Get(-1).Type) {
 			ctx.cb.CallWith(0, 0, v.X)
 		}
+		if ctx.strict {
+			checkErrIgnored(ctx, v.X, ctx.cb.InternalStack().Get(-1).Type)
+		}
 	case *ast.AssignStmt:
 		compileAssignStmt(ctx, v)
 	case *ast.ReturnStmt:
@@ -367,6 +398,7 @@ func compileRangeStmt(ctx *blockCtx, v *ast.RangeStmt) {
 }
 
 func compileForPhraseStmt(ctx *blockCtx, v *ast.ForPhraseStmt) {
+	checkForPhraseCond(ctx, v.ForPhrase)
 	if re, ok := v.X.(*ast.RangeExpr); ok {
 		compileForStmt(ctx, toForStmt(v.For, v.Value, v.Body, re, token.DEFINE, v.ForPhrase))
 		return
@@ -528,6 +560,51 @@ func compileForStmt(ctx *blockCtx, v *ast.ForStmt) {
 //	body
 //
 // end
+// compileAssertStmt compiles `assert cond, msg...` calling the builtin assert
+// into:
+//
+//	if builtin.Assertions && !cond {
+//		panic(builtin.AssertFailed(msg...))
+//	}
+//
+// so that the Go compiler strips it in release builds (see builtin.Assertions),
+// without evaluating cond and msg. It returns false if v doesn't call the
+// builtin assert.
+func compileAssertStmt(ctx *blockCtx, v *ast.ExprStmt) bool {
+	call, ok := v.X.(*ast.CallExpr)
+	if !ok || len(call.Args) == 0 || call.Ellipsis != token.NoPos {
+		return false
+	}
+	if id, ok := call.Fun.(*ast.Ident); !ok || id.Name != "assert" {
+		return false
+	} else {
+		compileIdent(ctx, id, clIdentAllowBuiltin)
+	}
+	stk := ctx.cb.InternalStack()
+	fn := stk.Get(-1)
+	stk.PopN(1)
+	if sig, ok := fn.Type.(*types.Signature); ok {
+		if funcs, ok := gox.CheckOverloadFunc(sig); !ok || len(funcs) != 1 ||
+			funcs[0].Pkg() == nil || funcs[0].Pkg().Path() != builtinPkgPath || funcs[0].Name() != "Assert" {
+			return false
+		}
+	} else {
+		return false
+	}
+	pkg, cb := ctx.pkg, ctx.cb
+	buil := pkg.Import(builtinPkgPath)
+	cb.If(v).Val(buil.Ref("Assertions"))
+	compileExpr(ctx, call.Args[0])
+	cb.UnaryOp(gotoken.NOT).BinaryOp(gotoken.LAND, call.Args[0]).Then()
+	cb.Val(pkg.Builtin().Ref("panic")).Val(buil.Ref("AssertFailed"))
+	for _, arg := range call.Args[1:] {
+		compileExpr(ctx, arg)
+	}
+	cb.CallWith(len(call.Args)-1, 0, call).CallWith(1, 0, call).EndStmt()
+	cb.End(v)
+	return true
+}
+
 func compileIfStmt(ctx *blockCtx, v *ast.IfStmt) {
 	cb := ctx.cb
 	comments, once := cb.BackupComments()
@@ -655,8 +732,10 @@ func compileSwitchStmt(ctx *blockCtx, v *ast.SwitchStmt) {
 	if v.Init != nil {
 		compileStmt(ctx, v.Init)
 	}
+	var tag types.Type
 	if v.Tag != nil { // switch tag {....}
 		compileExpr(ctx, v.Tag)
+		tag = cb.Get(-1).Type
 	} else {
 		cb.None() // switch {...}
 	}
@@ -710,6 +789,9 @@ func compileSwitchStmt(ctx *blockCtx, v *ast.SwitchStmt) {
 		commentStmt(ctx, stmt)
 		cb.End(c)
 	}
+	if firstDefault == nil && tag != nil {
+		checkExhaustive(ctx, v, tag, seen)
+	}
 	cb.SetComments(comments, once)
 	cb.End(v)
 }
@@ -754,6 +836,11 @@ func compileSelectStmt(ctx *blockCtx, v *ast.SelectStmt) {
 		if c.Comm != nil {
 			compileStmt(ctx, c.Comm)
 			n = 1
+		} else if c.After != nil { // after timeout: => case <-time.After(timeout):
+			cb.Val(ctx.pkg.Import("time").Ref("After"), c.After)
+			compileExpr(ctx, c.After)
+			cb.CallWith(1, 0, c.After).UnaryOp(gotoken.ARROW).EndStmt()
+			n = 1
 		}
 		cb.CommCase(n, c) // CommCase(0) means default case
 		compileStmts(ctx, c.Body)
@@ -0,0 +1,92 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cl
+
+import (
+	"go/types"
+	"path/filepath"
+	"sort"
+
+	"github.com/goplus/gop/ast"
+	"github.com/goplus/gox"
+)
+
+const (
+	tracePkgPath = "github.com/goplus/gop/builtin/trace"
+	profPkgPath  = "github.com/goplus/gop/builtin/prof"
+)
+
+// traceStmt generates a trace.Line call (see gop/builtin/trace) before stmt,
+// which prints the source line of stmt and values of local variables of basic
+// types that are visible.
+func traceStmt(ctx *blockCtx, stmt ast.Stmt) {
+	switch stmt.(type) {
+	case *ast.EmptyStmt, *ast.BlockStmt:
+		return
+	}
+	commentStmt(ctx, stmt)
+	pos := ctx.fset.Position(stmt.Pos())
+	vars := traceVars(ctx)
+	cb := ctx.cb
+	cb.Val(ctx.pkg.Import(tracePkgPath).Ref("Line")).
+		Val(filepath.Base(pos.Filename)).Val(pos.Line)
+	for _, v := range vars {
+		cb.Val(v.Name()).Val(v)
+	}
+	cb.Call(2 + 2*len(vars)).EndStmt()
+}
+
+// traceVars returns local variables of basic types in current scope, sorted
+// by their positions (and names).
+func traceVars(ctx *blockCtx) (vars []*types.Var) {
+	pkgScope := ctx.pkg.Types.Scope()
+	seen := make(map[string]bool)
+	for scope := ctx.cb.Scope(); scope != nil && scope != pkgScope && scope != types.Universe; scope = scope.Parent() {
+		for _, name := range scope.Names() {
+			if name == "_" || seen[name] {
+				continue
+			}
+			seen[name] = true
+			if v, ok := scope.Lookup(name).(*types.Var); ok && isTraceable(v.Type()) {
+				vars = append(vars, v)
+			}
+		}
+	}
+	sort.Slice(vars, func(i, j int) bool {
+		if pi, pj := vars[i].Pos(), vars[j].Pos(); pi != pj {
+			return pi < pj
+		}
+		return vars[i].Name() < vars[j].Name()
+	})
+	return
+}
+
+// profileMain generates `defer prof.Start(dir)()` (see gop/builtin/prof) at
+// the beginning of func main, if profiling is required.
+func profileMain(pkg *gox.Package, dir string, cb *gox.CodeBuilder) {
+	if dir == "" {
+		return
+	}
+	cb.Val(pkg.Import(profPkgPath).Ref("Start")).Val(dir).Call(1).Call(0).Defer()
+}
+
+func isTraceable(typ types.Type) bool {
+	t, ok := typ.Underlying().(*types.Basic)
+	return ok && t.Info()&types.IsUntyped == 0 && t.Kind() != types.UnsafePointer && t.Kind() != types.Invalid
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,136 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cl_test
+
+import (
+	"testing"
+)
+
+func TestTrace(t *testing.T) {
+	conf := *gblConf
+	conf.Trace = true
+	gopClTestEx(t, &conf, "main", `
+type Msg string
+
+func f(n int, p *int) (ret int) {
+	if n < 2 {
+		return n
+	}
+	;
+	ret = n
+	return
+}
+
+var g = 1
+x, msg := 0, Msg("hi")
+{
+	x := 1.5
+	x++
+}
+L:
+for i := 0; i < 2; i++ {
+	_ = func() {
+		msg = "hello"
+	}
+	break L
+}
+println x, msg, f(g, &x)
+`, `package main
+
+import (
+	"fmt"
+	"github.com/goplus/gop/builtin/trace"
+)
+
+type Msg string
+
+func f(n int, p *int) (ret int) {
+	trace.Line("bar.gop", 5, "n", n, "ret", ret)
+	if n < 2 {
+		trace.Line("bar.gop", 6, "n", n, "ret", ret)
+		return n
+	}
+	trace.Line("bar.gop", 9, "n", n, "ret", ret)
+	ret = n
+	trace.Line("bar.gop", 10, "n", n, "ret", ret)
+	return
+}
+
+var g = 1
+
+func main() {
+	trace.Line("bar.gop", 14)
+	x, msg := 0, Msg("hi")
+	{
+		trace.Line("bar.gop", 16, "msg", msg, "x", x)
+		x := 1.5
+		trace.Line("bar.gop", 17, "msg", msg, "x", x)
+		x++
+	}
+	trace.Line("bar.gop", 19, "msg", msg, "x", x)
+L:
+	for i := 0; i < 2; i++ {
+		trace.Line("bar.gop", 21, "msg", msg, "x", x, "i", i)
+		_ = func() {
+			trace.Line("bar.gop", 22, "msg", msg, "x", x, "i", i)
+			msg = "hello"
+		}
+		trace.Line("bar.gop", 24, "msg", msg, "x", x, "i", i)
+		break L
+	}
+	trace.Line("bar.gop", 26, "msg", msg, "x", x)
+	fmt.Println(x, msg, f(g, &x))
+}
+`)
+}
+
+func TestProfile(t *testing.T) {
+	conf := *gblConf
+	conf.Profile = "/tmp/prof"
+	gopClTestEx(t, &conf, "main", `
+func f() {
+}
+
+f
+`, `package main
+
+import "github.com/goplus/gop/builtin/prof"
+
+func f() {
+}
+func main() {
+	defer prof.Start("/tmp/prof")()
+	f()
+}
+`)
+	gopClTestEx(t, &conf, "main", `
+func main() {
+	println "hi"
+}
+`, `package main
+
+import (
+	"fmt"
+	"github.com/goplus/gop/builtin/prof"
+)
+
+func main() {
+	defer prof.Start("/tmp/prof")()
+	fmt.Println("hi")
+}
+`)
+}
@@ -301,3 +301,167 @@ func namedIsTypeParams(ctx *blockCtx, t *types.Named) bool {
 	}
 	return t.Obj() != nil && t.TypeArgs() == nil && t.TypeParams() != nil
 }
+
+// -----------------------------------------------------------------------------
+
+// inferLambdaType returns type of the i-th argument of a call, which is a
+// lambda. If it refers to type parameters of a generic function, they are
+// inferred from the preceding arguments (which are on the stack), and from
+// results of the lambda if needed, eg. `pmap(items, 8, x => x * 2)`.
+func inferLambdaType(ctx *blockCtx, fn *fnType, lambda ast.Expr, i int, ellipsis bool) types.Type {
+	t := fn.arg(i, ellipsis)
+	sig, ok := t.(*types.Signature)
+	if !ok || !hasTypeParam(sig) {
+		return t
+	}
+	binds := make(map[*types.TypeParam]types.Type)
+	for j := 0; j < i; j++ {
+		if pt := fn.arg(j, ellipsis); pt != nil {
+			unifyTypeParams(pt, ctx.cb.Get(j-i).Type, binds)
+		}
+	}
+	params := substTuple(sig.Params(), binds)
+	if hasTypeParam(params) {
+		return t
+	}
+	results := substTuple(sig.Results(), binds)
+	if l, ok := lambda.(*ast.LambdaExpr); ok && hasTypeParam(results) && len(l.Rhs) == results.Len() {
+		for k, ret := range probeLambdaResults(ctx, l, params) {
+			unifyTypeParams(sig.Results().At(k).Type(), ret, binds)
+		}
+		results = substTuple(sig.Results(), binds)
+	}
+	return types.NewSignatureType(nil, nil, nil, params, results, sig.Variadic())
+}
+
+// probeLambdaResults compiles a lambda with parameters params to get types of
+// its results. The compiled lambda is discarded.
+func probeLambdaResults(ctx *blockCtx, v *ast.LambdaExpr, params *types.Tuple) []types.Type {
+	pkg, cb := ctx.pkg, ctx.cb
+	cb.NewClosure(makeLambdaParams(ctx, v.Pos(), v.Lhs, params), nil, false).BodyStart(pkg)
+	if len(v.Lhs) > 0 {
+		defNames(ctx, v.Lhs, cb.Scope())
+	}
+	rets := make([]types.Type, len(v.Rhs))
+	for i, rhs := range v.Rhs {
+		compileExpr(ctx, rhs)
+		rets[i] = types.Default(cb.InternalStack().Pop().Type)
+	}
+	cb.End()
+	cb.InternalStack().Pop()
+	return rets
+}
+
+// unifyTypeParams binds type parameters in param to the corresponding types
+// in arg.
+func unifyTypeParams(param, arg types.Type, binds map[*types.TypeParam]types.Type) {
+	if arg == nil {
+		return
+	}
+	switch p := param.(type) {
+	case *types.TypeParam:
+		if _, ok := binds[p]; !ok {
+			binds[p] = types.Default(arg)
+		}
+	case *types.Pointer:
+		if a, ok := arg.Underlying().(*types.Pointer); ok {
+			unifyTypeParams(p.Elem(), a.Elem(), binds)
+		}
+	case *types.Slice:
+		if a, ok := arg.Underlying().(*types.Slice); ok {
+			unifyTypeParams(p.Elem(), a.Elem(), binds)
+		}
+	case *types.Array:
+		if a, ok := arg.Underlying().(*types.Array); ok {
+			unifyTypeParams(p.Elem(), a.Elem(), binds)
+		}
+	case *types.Chan:
+		if a, ok := arg.Underlying().(*types.Chan); ok {
+			unifyTypeParams(p.Elem(), a.Elem(), binds)
+		}
+	case *types.Map:
+		if a, ok := arg.Underlying().(*types.Map); ok {
+			unifyTypeParams(p.Key(), a.Key(), binds)
+			unifyTypeParams(p.Elem(), a.Elem(), binds)
+		}
+	case *types.Signature:
+		if a, ok := arg.Underlying().(*types.Signature); ok {
+			unifyTuples(p.Params(), a.Params(), binds)
+			unifyTuples(p.Results(), a.Results(), binds)
+		}
+	}
+}
+
+func unifyTuples(param, arg *types.Tuple, binds map[*types.TypeParam]types.Type) {
+	if param.Len() == arg.Len() {
+		for i, n := 0, param.Len(); i < n; i++ {
+			unifyTypeParams(param.At(i).Type(), arg.At(i).Type(), binds)
+		}
+	}
+}
+
+// substTypeParams substitutes type parameters in t by types bound to them.
+func substTypeParams(t types.Type, binds map[*types.TypeParam]types.Type) types.Type {
+	switch v := t.(type) {
+	case *types.TypeParam:
+		if b, ok := binds[v]; ok {
+			return b
+		}
+	case *types.Pointer:
+		return types.NewPointer(substTypeParams(v.Elem(), binds))
+	case *types.Slice:
+		return types.NewSlice(substTypeParams(v.Elem(), binds))
+	case *types.Array:
+		return types.NewArray(substTypeParams(v.Elem(), binds), v.Len())
+	case *types.Chan:
+		return types.NewChan(v.Dir(), substTypeParams(v.Elem(), binds))
+	case *types.Map:
+		return types.NewMap(substTypeParams(v.Key(), binds), substTypeParams(v.Elem(), binds))
+	case *types.Signature:
+		params, results := substTuple(v.Params(), binds), substTuple(v.Results(), binds)
+		return types.NewSignatureType(nil, nil, nil, params, results, v.Variadic())
+	}
+	return t
+}
+
+func substTuple(t *types.Tuple, binds map[*types.TypeParam]types.Type) *types.Tuple {
+	n := t.Len()
+	if n == 0 {
+		return t
+	}
+	vars := make([]*types.Var, n)
+	for i := 0; i < n; i++ {
+		v := t.At(i)
+		vars[i] = types.NewParam(v.Pos(), v.Pkg(), v.Name(), substTypeParams(v.Type(), binds))
+	}
+	return types.NewTuple(vars...)
+}
+
+// hasTypeParam reports whether t refers to any type parameter.
+func hasTypeParam(t types.Type) bool {
+	switch v := t.(type) {
+	case *types.TypeParam:
+		return true
+	case *types.Pointer:
+		return hasTypeParam(v.Elem())
+	case *types.Slice:
+		return hasTypeParam(v.Elem())
+	case *types.Array:
+		return hasTypeParam(v.Elem())
+	case *types.Chan:
+		return hasTypeParam(v.Elem())
+	case *types.Map:
+		return hasTypeParam(v.Key()) || hasTypeParam(v.Elem())
+	case *types.Signature:
+		return hasTypeParam(v.Params()) || hasTypeParam(v.Results())
+	case *types.Tuple:
+		for i, n := 0, v.Len(); i < n; i++ {
+			if hasTypeParam(v.At(i).Type()) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// -----------------------------------------------------------------------------
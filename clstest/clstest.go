@@ -0,0 +1,214 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package clstest provides helpers for authors of classfiles to test them:
+// compiling Go+ code of a classfile, checking the generated Go code, and
+// running it with output captured.
+package clstest
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/goplus/gop"
+	"github.com/goplus/gop/cl"
+	"github.com/goplus/gop/env"
+	"github.com/goplus/gop/parser"
+	"github.com/goplus/gop/parser/fsx/memfs"
+	"github.com/goplus/gop/token"
+	"github.com/goplus/gop/x/gocmd"
+	modenv "github.com/goplus/mod/env"
+	"github.com/goplus/mod/gopmod"
+	"github.com/goplus/mod/modfile"
+)
+
+// Files represents source files of a package, which maps file names (eg.
+// main.spx and Kai.spx) to their code.
+type Files map[string]string
+
+// srcDir is the directory where files are compiled in. Positions of errors
+// are relative to it.
+const srcDir = "/clstest"
+
+var (
+	// ErrMultiPackages is returned by Compile if files are of more than one
+	// package.
+	ErrMultiPackages = errors.New("clstest: multiple packages")
+
+	// ErrNoPackage is returned by Compile if there is no Go+ file in files.
+	ErrNoPackage = errors.New("clstest: no Go+ files")
+)
+
+// Compile compiles files with the classfile proj, and returns the generated
+// Go code. If proj is nil, classfiles registered in gop.mod of the module in
+// the current directory are used. Packages of the classfile are imported in
+// the module, too.
+func Compile(proj *modfile.Project, files Files) (code string, err error) {
+	mod, err := gop.LoadMod(".")
+	if err != nil {
+		return
+	}
+	names := make([]string, 0, len(files))
+	srcs := make(map[string]string, len(files))
+	for name, src := range files {
+		names = append(names, name)
+		srcs[path.Join(srcDir, name)] = src
+	}
+	sort.Strings(names)
+	fs := memfs.New(map[string][]string{srcDir: names}, srcs)
+
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseFSDir(fset, fs, srcDir, parser.Config{
+		ClassKind: func(fname string) (isProj, ok bool) {
+			return classKind(mod, proj, fname)
+		},
+		Mode: parser.ParseComments,
+	})
+	if err != nil {
+		return
+	}
+	if len(pkgs) > 1 {
+		return "", ErrMultiPackages
+	}
+	for _, pkg := range pkgs {
+		conf := &cl.Config{
+			Fset:         fset,
+			Importer:     gop.NewImporter(mod, gopEnv(), fset),
+			RelativeBase: srcDir,
+			NoFileLine:   true,
+			LookupClass: func(ext string) (*modfile.Project, bool) {
+				return lookupClass(mod, proj, ext)
+			},
+		}
+		out, err := cl.NewPackage("", pkg, conf)
+		if err != nil {
+			return "", err
+		}
+		var b bytes.Buffer
+		if err = out.WriteTo(&b); err != nil {
+			return "", err
+		}
+		return b.String(), nil
+	}
+	return "", ErrNoPackage
+}
+
+// gopEnv returns the Go+ environment without GOPROOT, so that packages of
+// Go+ (eg. builtin) are imported in the module in the current directory, as
+// the generated code is built.
+func gopEnv() *modenv.Gop {
+	return &modenv.Gop{Version: env.Version(), BuildDate: env.BuildDate()}
+}
+
+func lookupClass(mod *gopmod.Module, proj *modfile.Project, ext string) (*modfile.Project, bool) {
+	if proj == nil {
+		return mod.LookupClass(ext)
+	}
+	if ext == proj.Ext {
+		return proj, true
+	}
+	for _, w := range proj.Works {
+		if ext == w.Ext {
+			return proj, true
+		}
+	}
+	return nil, false
+}
+
+// classKind is like gopmod.Module.ClassKind, but for proj if it isn't nil.
+func classKind(mod *gopmod.Module, proj *modfile.Project, fname string) (isProj, ok bool) {
+	if proj == nil {
+		return mod.ClassKind(fname)
+	}
+	ext := modfile.ClassExt(fname)
+	c, ok := lookupClass(mod, proj, ext)
+	if !ok {
+		return
+	}
+	for _, w := range c.Works {
+		if w.Ext == ext {
+			return ext == c.Ext && fname == "main"+ext, true
+		}
+	}
+	return true, true
+}
+
+// -----------------------------------------------------------------------------
+
+// Expect compiles files with the classfile proj (see Compile), and checks
+// the generated Go code is expected.
+func Expect(t testing.TB, proj *modfile.Project, files Files, expected string) {
+	t.Helper()
+	code, err := Compile(proj, files)
+	if err != nil {
+		t.Fatal("Compile failed:", err)
+	}
+	if code != expected {
+		t.Fatalf("\nResult:\n%s\nExpected:\n%s\n", code, expected)
+	}
+}
+
+// ExpectError compiles files with the classfile proj (see Compile), and
+// checks it fails with the error msg. Positions in msg are relative to the
+// directory of files, eg. "Kai.spx:3:2: undefined: foo".
+func ExpectError(t testing.TB, proj *modfile.Project, files Files, msg string) {
+	t.Helper()
+	_, err := Compile(proj, files)
+	if err == nil {
+		t.Fatal("Compile: no error?")
+	}
+	if ret := err.Error(); ret != msg {
+		t.Fatalf("\nError: \"%s\"\nExpected: \"%s\"\n", ret, msg)
+	}
+}
+
+// Run compiles files with the classfile proj (see Compile), runs the
+// program with args in the current directory, and returns its output.
+func Run(t testing.TB, proj *modfile.Project, files Files, args ...string) string {
+	t.Helper()
+	code, err := Compile(proj, files)
+	if err != nil {
+		t.Fatal("Compile failed:", err)
+	}
+	gen := filepath.Join(t.TempDir(), "gop_autogen.go")
+	if err = os.WriteFile(gen, []byte(code), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// the Go file is in the current directory, so the program is built in
+	// the module of it
+	file, err := filepath.Abs("clstest_autogen.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stdout, stderr bytes.Buffer
+	conf := &gocmd.RunConfig{
+		Gop:     gopEnv(),
+		Stdout:  &stdout,
+		Stderr:  &stderr,
+		Overlay: map[string]string{file: gen},
+	}
+	if err = gocmd.RunFiles([]string{file}, args, conf); err != nil {
+		t.Fatalf("Run failed: %v\n%s", err, stderr.Bytes())
+	}
+	return stdout.String()
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,81 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package clstest_test
+
+import (
+	"testing"
+
+	"github.com/goplus/gop/clstest"
+	"github.com/goplus/mod/modfile"
+)
+
+var appProj = &modfile.Project{
+	Ext: ".tapp", Class: "App",
+	PkgPaths: []string{"github.com/goplus/gop/clstest/internal/app"},
+}
+
+func TestExpect(t *testing.T) {
+	clstest.Expect(t, appProj, clstest.Files{"main.tapp": `
+greet "Go+"
+`}, `package main
+
+import "github.com/goplus/gop/clstest/internal/app"
+
+type App struct {
+	app.App
+}
+
+func (this *App) MainEntry() {
+	this.Greet("Go+")
+}
+func main() {
+	app.Gopt_App_Main(new(App))
+}
+`)
+}
+
+func TestExpectError(t *testing.T) {
+	clstest.ExpectError(t, appProj, clstest.Files{"main.tapp": `
+greet 100
+`}, `main.tapp:2:7: cannot use 100 (type untyped int) as type string in argument to greet 100`)
+}
+
+func TestRun(t *testing.T) {
+	out := clstest.Run(t, appProj, clstest.Files{"main.tapp": `
+import "os"
+
+for name <- os.Args[1:] {
+	greet name
+}
+println greeted
+`}, "Go+", "classfile")
+	if out != "Hello, Go+\nHello, classfile\n2\n" {
+		t.Fatal("Run:", out)
+	}
+}
+
+func TestCompile(t *testing.T) {
+	if _, err := clstest.Compile(appProj, clstest.Files{"a.gop": "package a", "b.gop": "package b"}); err != clstest.ErrMultiPackages {
+		t.Fatal("Compile:", err)
+	}
+	if _, err := clstest.Compile(appProj, clstest.Files{"a.txt": "hello"}); err != clstest.ErrNoPackage {
+		t.Fatal("Compile:", err)
+	}
+	if _, err := clstest.Compile(nil, clstest.Files{"a.gop": "println 1 +"}); err == nil {
+		t.Fatal("Compile: no error?")
+	}
+}
@@ -0,0 +1,41 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package app is a classfile framework to test clstest.
+package app
+
+import "fmt"
+
+const (
+	GopPackage = true
+)
+
+type App struct {
+	greeted int
+}
+
+func (p *App) Greet(name string) {
+	p.greeted++
+	fmt.Println("Hello,", name)
+}
+
+func (p *App) Greeted() int {
+	return p.greeted
+}
+
+func Gopt_App_Main(app interface{}) {
+	app.(interface{ MainEntry() }).MainEntry()
+}
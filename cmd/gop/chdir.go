@@ -0,0 +1,79 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"os"
+	"strings"
+
+	"github.com/goplus/gop/cmd/internal/base"
+	"github.com/qiniu/x/log"
+)
+
+// handleChdirFlag handles the -C flag before doing anything else, as what
+// `go -C dir` does. The flag must be the first one, either before or right
+// after the command name (eg. `gop -C dir build` or `gop build -C dir`), so
+// that it's easy to find even with commands having custom flag parsing. It
+// changes to the directory, and removes the flag from os.Args.
+func handleChdirFlag() {
+	used := 1
+	if used < len(os.Args) && !strings.HasPrefix(os.Args[used], "-") {
+		used += cmdNames(os.Args[used:])
+	}
+	if used >= len(os.Args) {
+		return
+	}
+	var dir string
+	switch a := os.Args[used]; {
+	case a == "-C" || a == "--C":
+		if used+1 >= len(os.Args) {
+			return
+		}
+		dir = os.Args[used+1]
+		os.Args = append(os.Args[:used:used], os.Args[used+2:]...)
+	case strings.HasPrefix(a, "-C=") || strings.HasPrefix(a, "--C="):
+		dir = a[strings.IndexByte(a, '=')+1:]
+		os.Args = append(os.Args[:used:used], os.Args[used+1:]...)
+	default:
+		return
+	}
+	if err := os.Chdir(dir); err != nil {
+		log.Fatalln("gop:", err)
+	}
+}
+
+// cmdNames returns how many leading arguments of args are names of (sub)commands.
+func cmdNames(args []string) (n int) {
+	cmds := base.Gop.Commands
+	for n < len(args) {
+		var found *base.Command
+		for _, cmd := range cmds {
+			if cmd.Name() == args[n] {
+				found = cmd
+				break
+			}
+		}
+		if found == nil {
+			break
+		}
+		n++
+		if cmds = found.Commands; len(cmds) == 0 {
+			break
+		}
+	}
+	return
+}
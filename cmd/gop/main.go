@@ -20,28 +20,45 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/qiniu/x/log"
 
+	"github.com/goplus/gop/cmd/internal/apidiff"
 	"github.com/goplus/gop/cmd/internal/base"
 	"github.com/goplus/gop/cmd/internal/bug"
 	"github.com/goplus/gop/cmd/internal/build"
+	"github.com/goplus/gop/cmd/internal/bundle"
 	"github.com/goplus/gop/cmd/internal/c2go"
 	"github.com/goplus/gop/cmd/internal/clean"
 	"github.com/goplus/gop/cmd/internal/doc"
 	"github.com/goplus/gop/cmd/internal/env"
+	"github.com/goplus/gop/cmd/internal/export"
+	"github.com/goplus/gop/cmd/internal/fix"
 	"github.com/goplus/gop/cmd/internal/gengo"
 	"github.com/goplus/gop/cmd/internal/gopfmt"
 	"github.com/goplus/gop/cmd/internal/gopget"
 	"github.com/goplus/gop/cmd/internal/help"
 	"github.com/goplus/gop/cmd/internal/install"
+	"github.com/goplus/gop/cmd/internal/list"
+	"github.com/goplus/gop/cmd/internal/lsp"
 	"github.com/goplus/gop/cmd/internal/mod"
+	"github.com/goplus/gop/cmd/internal/publish"
+	"github.com/goplus/gop/cmd/internal/repl"
 	"github.com/goplus/gop/cmd/internal/run"
 	"github.com/goplus/gop/cmd/internal/serve"
+	"github.com/goplus/gop/cmd/internal/stats"
 	"github.com/goplus/gop/cmd/internal/test"
+	"github.com/goplus/gop/cmd/internal/tool"
+	"github.com/goplus/gop/cmd/internal/verifygen"
 	"github.com/goplus/gop/cmd/internal/version"
+	"github.com/goplus/gop/cmd/internal/vet"
 	"github.com/goplus/gop/cmd/internal/watch"
+	gopenv "github.com/goplus/gop/env"
+	"github.com/goplus/gop/x/gocmd"
+	"github.com/goplus/gop/x/progress"
+	"github.com/goplus/gop/x/sandbox"
 )
 
 func mainUsage() {
@@ -55,28 +72,72 @@ func init() {
 		run.Cmd,
 		install.Cmd,
 		build.Cmd,
+		bundle.Cmd,
 		test.Cmd,
 		gopfmt.Cmd,
+		fix.Cmd,
+		vet.Cmd,
 		gopget.Cmd,
 		gengo.Cmd,
+		export.Cmd,
+		verifygen.Cmd,
 		mod.Cmd,
 		doc.Cmd,
+		apidiff.Cmd,
+		publish.Cmd,
 		clean.Cmd,
-		// list.Cmd,
+		list.Cmd,
 		// deps.Cmd,
 		serve.Cmd,
+		lsp.Cmd,
+		stats.Cmd,
 		watch.Cmd,
+		repl.Cmd,
 		env.Cmd,
 		c2go.Cmd,
+		tool.Cmd,
 		bug.Cmd,
 		version.Cmd,
 	}
 }
 
+var (
+	flagGo       = flag.String("go", "", "the go command to use: a `path` to go, or a Go version like go1.21.3")
+	flagGoFlags  = flag.String("goflags", "", "additional `flags` for all go commands invoked, appended to GOFLAGS")
+	flagProgress = flag.String("json-progress", "", "write progress events of long operations in NDJSON format to the file descriptor `fd`")
+)
+
 func main() {
+	sandbox.Main()
+	handleChdirFlag()
+	switchToolchain(os.Args[1:])
 	flag.Parse()
+	if *flagGo != "" {
+		if _, err := gocmd.SelectGo(*flagGo); err != nil {
+			log.Fatalln("gop -go:", err)
+		}
+	}
+	if *flagGoFlags != "" {
+		if err := gocmd.AppendGoFlags(*flagGoFlags); err != nil {
+			log.Fatalln("gop -goflags:", err)
+		}
+	}
+	if *flagProgress != "" {
+		if err := progress.Open(*flagProgress); err != nil {
+			log.Fatalln("gop -json-progress:", err)
+		}
+	}
+	if _, err := gopenv.ParseExperiment(os.Getenv(gopenv.EnvExperiment)); err != nil {
+		log.Fatalln("gop:", err)
+	}
 	args := flag.Args()
 	if len(args) < 1 {
+		if isTerminal(os.Stdin) { // bare gop: start an interactive shell
+			if err := repl.Main(""); err != nil {
+				log.Fatalln(err)
+			}
+			return
+		}
 		flag.Usage()
 	}
 	log.SetFlags(log.Ldefault &^ log.LstdFlags)
@@ -86,6 +147,11 @@ func main() {
 		help.Help(os.Stderr, args[1:])
 		return
 	}
+	if isScript(args[0]) { // eg. ./hello.gop invoked by `#!/usr/bin/env gop`
+		base.CmdName = "run"
+		run.Cmd.Run(run.Cmd, append([]string{args[0], "--"}, args[1:]...))
+		return
+	}
 
 BigCmdLoop:
 	for bigCmd := base.Gop; ; {
@@ -121,3 +187,17 @@ BigCmdLoop:
 		os.Exit(2)
 	}
 }
+
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// isScript reports whether arg is a Go+ script file, rather than a command.
+func isScript(arg string) bool {
+	if filepath.Ext(arg) != ".gop" {
+		return false
+	}
+	fi, err := os.Stat(arg)
+	return err == nil && fi.Mode().IsRegular()
+}
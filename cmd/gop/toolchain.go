@@ -0,0 +1,91 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/goplus/gop"
+)
+
+// switchToolchain runs gop<version> (eg. gop1.3) found in PATH instead if
+// GOPTOOLCHAIN=auto and the running gop doesn't satisfy the gop directive in
+// gop.mod of current module. See gop.EnvToolchain.
+func switchToolchain(args []string) {
+	switched, err := runToolchainOf(".", args)
+	if err != nil {
+		if ee, ok := err.(*exec.ExitError); ok {
+			os.Exit(ee.ExitCode())
+		}
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if switched {
+		os.Exit(0)
+	}
+}
+
+var (
+	lookPath = exec.LookPath
+	runGop   = func(path string, args, env []string) error {
+		cmd := exec.Command(path, args...)
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+		cmd.Env = env
+		return cmd.Run()
+	}
+)
+
+// runToolchainOf runs gop<version> with args if GOPTOOLCHAIN=auto and the
+// running gop doesn't satisfy the gop directive in gop.mod of the module in
+// dir. It reports whether it is run.
+func runToolchainOf(dir string, args []string) (bool, error) {
+	switch v := os.Getenv(gop.EnvToolchain); v {
+	case "", "local":
+		return false, nil
+	case "auto":
+	default:
+		return false, fmt.Errorf("invalid %s=%s: must be auto or local", gop.EnvToolchain, v)
+	}
+	var e *gop.ToolchainError
+	if _, err := gop.LoadMod(dir); !errors.As(err, &e) {
+		return false, nil
+	}
+	name := "gop" + e.Required
+	path, err := lookPath(name)
+	if err != nil {
+		return false, fmt.Errorf("%v\n%s not found in PATH", e, name)
+	}
+	if isRunning(path) { // eg. a symlink to the running gop
+		return false, fmt.Errorf("%v\n%s found in PATH is the running gop", e, name)
+	}
+	env := append(os.Environ(), gop.EnvToolchain+"=") // don't switch again
+	return true, runGop(path, args, env)
+}
+
+// isRunning reports whether path is the executable of the running process.
+func isRunning(path string) bool {
+	self, err := os.Executable()
+	if err != nil {
+		return false
+	}
+	fi1, err1 := os.Stat(path)
+	fi2, err2 := os.Stat(self)
+	return err1 == nil && err2 == nil && os.SameFile(fi1, fi2)
+}
@@ -0,0 +1,113 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/goplus/gop"
+)
+
+// stubToolchain stubs lookPath and runGop, and returns the record of runs.
+func stubToolchain(t *testing.T, found map[string]string) *[]string {
+	var runs []string
+	oldLookPath, oldRunGop := lookPath, runGop
+	t.Cleanup(func() { lookPath, runGop = oldLookPath, oldRunGop })
+	lookPath = func(name string) (string, error) {
+		if path, ok := found[name]; ok {
+			return path, nil
+		}
+		return "", exec.ErrNotFound
+	}
+	runGop = func(path string, args, env []string) error {
+		runs = append(runs, path+" "+strings.Join(args, " ")+" "+env[len(env)-1])
+		return nil
+	}
+	return &runs
+}
+
+func modDir(t *testing.T, gopVer string) string {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/foo\n\ngo 1.18\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "gop.mod"), []byte("gop "+gopVer+"\n"), 0644)
+	return dir
+}
+
+func TestSwitchToolchain(t *testing.T) {
+	runs := stubToolchain(t, map[string]string{"gop99.0": "/opt/gop99.0"})
+	t.Setenv(gop.EnvToolchain, "auto")
+
+	if switched, err := runToolchainOf(modDir(t, "1.0"), []string{"run", "."}); switched || err != nil {
+		t.Fatal("local toolchain:", switched, err)
+	}
+	switched, err := runToolchainOf(modDir(t, "99.0"), []string{"run", "."})
+	if !switched || err != nil {
+		t.Fatal("newer toolchain:", switched, err)
+	}
+	if len(*runs) != 1 || (*runs)[0] != "/opt/gop99.0 run . "+gop.EnvToolchain+"=" {
+		t.Fatal("runs:", *runs)
+	}
+}
+
+func TestSwitchToolchainNotFound(t *testing.T) {
+	runs := stubToolchain(t, nil)
+	t.Setenv(gop.EnvToolchain, "auto")
+	_, err := runToolchainOf(modDir(t, "99.0"), nil)
+	var e *gop.ToolchainError
+	if err == nil || !strings.HasSuffix(err.Error(), "gop99.0 not found in PATH") || errors.As(err, &e) || len(*runs) != 0 {
+		t.Fatal("runToolchainOf:", err, *runs)
+	}
+}
+
+func TestSwitchToolchainRecursion(t *testing.T) {
+	self, err := os.Executable()
+	if err != nil {
+		t.Skip(err)
+	}
+	runs := stubToolchain(t, map[string]string{"gop99.0": self})
+	t.Setenv(gop.EnvToolchain, "auto")
+	_, err = runToolchainOf(modDir(t, "99.0"), nil)
+	if err == nil || !strings.HasSuffix(err.Error(), "gop99.0 found in PATH is the running gop") || len(*runs) != 0 {
+		t.Fatal("runToolchainOf:", err, *runs)
+	}
+
+	// the switched gop runs with GOPTOOLCHAIN= and doesn't switch again
+	t.Setenv(gop.EnvToolchain, "")
+	if switched, err := runToolchainOf(modDir(t, "99.0"), nil); switched || err != nil || len(*runs) != 0 {
+		t.Fatal("runToolchainOf:", switched, err, *runs)
+	}
+}
+
+func TestSwitchToolchainInvalid(t *testing.T) {
+	runs := stubToolchain(t, nil)
+	for _, v := range []string{"local", ""} {
+		t.Setenv(gop.EnvToolchain, v)
+		if switched, err := runToolchainOf(modDir(t, "99.0"), nil); switched || err != nil {
+			t.Fatal("runToolchainOf:", v, switched, err)
+		}
+	}
+	t.Setenv(gop.EnvToolchain, "gop1.3")
+	_, err := runToolchainOf(modDir(t, "1.0"), nil)
+	if err == nil || err.Error() != "invalid GOPTOOLCHAIN=gop1.3: must be auto or local" || len(*runs) != 0 {
+		t.Fatal("runToolchainOf:", err)
+	}
+}
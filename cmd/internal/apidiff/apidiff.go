@@ -0,0 +1,121 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package apidiff implements the “gop apidiff” command.
+package apidiff
+
+import (
+	"fmt"
+	"go/types"
+	"log"
+	"os"
+
+	"github.com/goplus/gop"
+	"github.com/goplus/gop/cmd/internal/base"
+	"github.com/goplus/gop/token"
+	"github.com/goplus/gop/x/apidiff"
+)
+
+// gop apidiff
+var Cmd = &base.Command{
+	UsageLine: "gop apidiff [-incompatible -w file] old [new]",
+	Short:     "Report changes of the exported API of a Go+ package between two versions",
+}
+
+var (
+	flag             = &Cmd.Flag
+	flagWrite        = flag.String("w", "", "write export data of the package to `file`, to compare with later versions")
+	flagIncompatible = flag.Bool("incompatible", false, "report only incompatible changes")
+)
+
+func init() {
+	Cmd.Run = runCmd
+}
+
+// old and new are directories of Go+ packages, or export data files written
+// by `gop apidiff -w`. Default of new is current directory.
+func runCmd(cmd *base.Command, args []string) {
+	err := flag.Parse(args)
+	if err != nil {
+		log.Fatalln("parse input arguments failed:", err)
+	}
+	args = flag.Args()
+	fset := token.NewFileSet()
+	if *flagWrite != "" {
+		if len(args) > 1 {
+			cmd.Usage(os.Stderr)
+		}
+		pkg := load(fset, argOr(args, 0))
+		f, err := os.Create(*flagWrite)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		defer f.Close()
+		if err = apidiff.WriteExport(f, fset, pkg); err != nil {
+			log.Fatalln("write export data failed:", err)
+		}
+		return
+	}
+	if len(args) < 1 || len(args) > 2 {
+		cmd.Usage(os.Stderr)
+	}
+	old, new := load(fset, args[0]), load(fset, argOr(args, 1))
+	r := apidiff.Changes(old, new)
+	if *flagIncompatible {
+		r.Changes = r.Incompatible()
+	}
+	r.Fprint(os.Stdout)
+	if len(r.Incompatible()) > 0 {
+		os.Exit(1)
+	}
+	if !*flagIncompatible {
+		fmt.Println("Suggested version bump:", r.Bump())
+	}
+}
+
+func argOr(args []string, i int) string {
+	if i < len(args) {
+		return args[i]
+	}
+	return "."
+}
+
+// load loads a Go+ package in a directory, or from an export data file.
+func load(fset *token.FileSet, path string) *types.Package {
+	fi, err := os.Stat(path)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if !fi.IsDir() {
+		f, err := os.Open(path)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		defer f.Close()
+		pkg, err := apidiff.ReadExport(f, fset)
+		if err != nil {
+			log.Fatalf("%s: %v\n", path, err)
+		}
+		return pkg
+	}
+	out, _, err := gop.LoadDir(path, &gop.Config{Fset: fset}, false)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	return out.Types
+}
+
+// -----------------------------------------------------------------------------
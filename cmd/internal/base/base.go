@@ -24,6 +24,8 @@ import (
 	"io"
 	"os"
 	"strings"
+
+	"github.com/goplus/gop/x/diag"
 )
 
 // A Command is an implementation of a gop command
@@ -51,7 +53,7 @@ type Command struct {
 
 // Gop command
 var Gop = &Command{
-	UsageLine: "gop",
+	UsageLine: "gop [-C dir] [-go go] [-goflags flags] [-json-progress fd]",
 	Short:     `Gop is a tool for managing Go+ source code.`,
 	// Commands initialized in package main
 }
@@ -112,3 +114,16 @@ func Main(c *Command, app string, args []string) {
 	}
 	c.Run(c, args)
 }
+
+// Diag prints errors and warnings of commands to stderr, with source snippets.
+var Diag = diag.NewPrinter(os.Stderr)
+
+// PrintError prints errors in err to stderr.
+func PrintError(err error) {
+	Diag.PrintError(os.Stderr, err)
+}
+
+// PrintWarning prints a compiler warning to stderr.
+func PrintWarning(err error) {
+	Diag.PrintWarning(os.Stderr, err)
+}
@@ -0,0 +1,51 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package base
+
+import (
+	"io"
+	"os"
+
+	"github.com/goplus/gop/x/explain"
+	"github.com/goplus/gop/x/gocmd"
+)
+
+// Explainer explains errors of a gop command (the -explain flag), including
+// errors reported by the go command, eg. unused variables.
+type Explainer struct {
+	output *gocmd.Capture
+}
+
+// NewExplainer creates an Explainer, and tees *stderr (os.Stderr if nil) to it.
+func NewExplainer(stderr *io.Writer) *Explainer {
+	output := gocmd.NewCapture(64 << 10)
+	w := *stderr
+	if w == nil {
+		w = os.Stderr
+	}
+	*stderr = io.MultiWriter(w, output)
+	return &Explainer{output: output}
+}
+
+// Explain prints explanations of err. It does nothing if p is nil.
+func (p *Explainer) Explain(err error) {
+	if p != nil {
+		explain.Fprint(os.Stderr, err, p.output.String())
+	}
+}
+
+// -----------------------------------------------------------------------------
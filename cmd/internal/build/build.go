@@ -18,33 +18,54 @@
 package build
 
 import (
+	"bytes"
 	"fmt"
 	"log"
 	"os"
+	"path"
 	"path/filepath"
 	"reflect"
+	"strings"
 
 	"github.com/goplus/gop"
 	"github.com/goplus/gop/cl"
 	"github.com/goplus/gop/cmd/internal/base"
+	"github.com/goplus/gop/x/crash"
+	"github.com/goplus/gop/x/diag"
 	"github.com/goplus/gop/x/gocmd"
 	"github.com/goplus/gop/x/gopenv"
 	"github.com/goplus/gop/x/gopprojs"
+	"github.com/goplus/gop/x/perfhints"
+	"github.com/goplus/gop/x/progress"
+	"github.com/goplus/gop/x/stats"
 	"github.com/goplus/gox"
 )
 
 // gop build
 var Cmd = &base.Command{
-	UsageLine: "gop build [-debug -o output] [packages]",
+	UsageLine: "gop build [-debug -strict -explain -lang version -compile-timeout d -compile-memlimit MB -o output -targets list -perf-hints] [packages]",
 	Short:     "Build Go+ files",
 }
 
 var (
-	flagDebug  = flag.Bool("debug", false, "print debug information")
-	flagOutput = flag.String("o", "", "gop build output file")
-	flag       = &Cmd.Flag
+	flagDebug   = flag.Bool("debug", false, "print debug information")
+	flagOutput  = flag.String("o", "", "gop build output file")
+	flagStrict  = flag.Bool("strict", false, "report compiler warnings as errors")
+	flagExplain = flag.Bool("explain", false, "print explanations of common errors with examples of how to fix them")
+	flagLang    = flag.String("lang", "", "language `version` of Go+, eg. gop1.0")
+	flagTargets = flag.String("targets", "", "comma-separated `list` of GOOS/GOARCH to build for in parallel, using -o as output name template")
+	flagPerf    = flag.Bool("perf-hints", false, "print escape analysis and inlining decisions of the Go compiler on Go+ source code")
+	flag        = &Cmd.Flag
+
+	flagCompileTimeout  = flag.Duration("compile-timeout", 0, "limit of time to compile a package")
+	flagCompileMemLimit = flag.Uint64("compile-memlimit", 0, "limit of memory in `MB` to compile a package")
 )
 
+var explainer *base.Explainer
+
+// perfOutput is output of the go command if -perf-hints is set.
+var perfOutput *bytes.Buffer
+
 func init() {
 	Cmd.Run = runCmd
 }
@@ -76,19 +97,80 @@ func runCmd(cmd *base.Command, args []string) {
 	}
 
 	gopEnv := gopenv.Get()
-	conf := &gop.Config{Gop: gopEnv}
+	conf := &gop.Config{Gop: gopEnv, Strict: *flagStrict, OnWarning: base.PrintWarning}
+	conf.OnCompiled = stats.Hook("build")
+	conf.OnProgress = progress.Hook()
+	conf.OnCrash = crash.Hook(conf)
+	conf.CompileTimeout, conf.CompileMemLimit = *flagCompileTimeout, *flagCompileMemLimit<<20
+	conf.Lang = *flagLang
 	confCmd := &gocmd.BuildConfig{Gop: gopEnv}
-	if *flagOutput != "" {
-		output, err := filepath.Abs(*flagOutput)
+	if *flagTargets != "" {
+		targets, err := gocmd.ParseTargets(*flagTargets)
+		if err != nil {
+			log.Panicln(err)
+		}
+		output, err := gocmd.TargetOutput(*flagOutput, len(targets))
+		if err != nil {
+			log.Panicln(err)
+		}
+		if output, err = filepath.Abs(output); err != nil {
+			log.Panicln(err)
+		}
+		confCmd.Run = gocmd.RunTargets(targets, output, projName(proj), 0)
+	} else if *flagOutput != "" {
+		output, err := absOutput(*flagOutput)
+		if err != nil {
+			log.Panicln(err)
+		}
+		confCmd.Flags = []string{"-o", output}
+	} else if _, ok := proj.(*gopprojs.PkgPathProj); ok {
+		// `go build` runs in the directory of the package, so write the
+		// executable into the current directory as `go build pkgPath` does.
+		output, err := absOutput("." + string(filepath.Separator))
 		if err != nil {
 			log.Panicln(err)
 		}
 		confCmd.Flags = []string{"-o", output}
 	}
 	confCmd.Flags = append(confCmd.Flags, pass.Args...)
+	if *flagPerf {
+		confCmd.Flags = perfhints.BuildFlags(confCmd.Flags)
+		perfOutput = new(bytes.Buffer)
+		confCmd.Stderr = perfOutput
+	}
+	confCmd.Run = progress.WrapRun("build", confCmd.Run)
+	if *flagExplain {
+		explainer = base.NewExplainer(&confCmd.Stderr)
+	}
 	build(proj, conf, confCmd)
 }
 
+// absOutput returns the absolute path of the output of `go build -o`. A
+// trailing path separator is kept, which means output is a directory.
+func absOutput(output string) (string, error) {
+	ret, err := filepath.Abs(output)
+	if err == nil && os.IsPathSeparator(output[len(output)-1]) && !os.IsPathSeparator(ret[len(ret)-1]) {
+		ret += string(filepath.Separator)
+	}
+	return ret, err
+}
+
+// projName returns the default name of the executable built from proj.
+func projName(proj gopprojs.Proj) string {
+	switch v := proj.(type) {
+	case *gopprojs.DirProj:
+		if dir, err := filepath.Abs(v.Dir); err == nil {
+			return filepath.Base(dir)
+		}
+	case *gopprojs.PkgPathProj:
+		return path.Base(strings.TrimSuffix(v.Path, "/..."))
+	case *gopprojs.FilesProj:
+		name := filepath.Base(v.Files[0])
+		return strings.TrimSuffix(name, filepath.Ext(name))
+	}
+	return "a.out"
+}
+
 func build(proj gopprojs.Proj, conf *gop.Config, build *gocmd.BuildConfig) {
 	var obj string
 	var err error
@@ -104,14 +186,40 @@ func build(proj gopprojs.Proj, conf *gop.Config, build *gocmd.BuildConfig) {
 	default:
 		log.Panicln("`gop build` doesn't support", reflect.TypeOf(v))
 	}
+	progress.Done(err)
+	if perfOutput != nil {
+		printPerfHints(proj, perfOutput.Bytes())
+	}
 	if gop.NotFound(err) {
 		fmt.Fprintf(os.Stderr, "gop build %v: not found\n", obj)
 	} else if err != nil {
-		fmt.Fprintln(os.Stderr, err)
+		base.PrintError(err)
+		explainer.Explain(err)
 	} else {
 		return
 	}
 	os.Exit(1)
 }
 
+// printPerfHints prints performance hints in output of `go build -gcflags=-m`,
+// and the rest of output (eg. compile errors) as is.
+func printPerfHints(proj gopprojs.Proj, output []byte) {
+	var dirs []string // relative file names in output are relative to the module root
+	dir := "."
+	switch v := proj.(type) {
+	case *gopprojs.DirProj:
+		dir = v.Dir
+	case *gopprojs.FilesProj:
+		dir = filepath.Dir(v.Files[0])
+	}
+	if mod, err := gop.LoadMod(dir); err == nil && mod.HasModfile() {
+		dirs = append(dirs, mod.Root())
+	}
+	hints, rest := perfhints.Parse(output, dirs...)
+	os.Stderr.Write(rest)
+	for _, h := range hints {
+		base.Diag.Print(os.Stderr, &diag.Diag{Pos: h.Pos, Msg: h.Msg, Hint: true})
+	}
+}
+
 // -----------------------------------------------------------------------------
@@ -0,0 +1,298 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package bundle implements the “gop bundle” command.
+package bundle
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/goplus/gop"
+	"github.com/goplus/gop/cmd/internal/base"
+	"github.com/goplus/gop/x/gocmd"
+	"github.com/goplus/gop/x/gopenv"
+	"github.com/goplus/gop/x/gopprojs"
+	"github.com/goplus/mod/gopmod"
+	"github.com/goplus/mod/modfile"
+)
+
+// gop bundle
+var Cmd = &base.Command{
+	UsageLine: "gop bundle [-zip -assets patterns -o output] [dir]",
+	Short:     "Pack a Go+ program and its assets into a single executable or zip file",
+}
+
+var (
+	flag       = &Cmd.Flag
+	flagZip    = flag.Bool("zip", false, "create a zip file containing the executable and assets")
+	flagAssets = flag.String("assets", "", "comma-separated glob `patterns` of assets (default is all non-code files)")
+	flagOutput = flag.String("o", "", "output file")
+)
+
+func init() {
+	Cmd.Run = runCmd
+}
+
+// runCmd builds the program in dir, and then:
+//   - with -zip, packs the executable and assets into a zip file.
+//   - otherwise, appends assets (in zip format) to the executable. A runner
+//     compiled into the program extracts them into a cache directory and
+//     changes the working directory there when the program starts.
+func runCmd(cmd *base.Command, args []string) {
+	pass := base.PassBuildFlags(cmd)
+	err := flag.Parse(args)
+	if err != nil {
+		log.Fatalln("parse input arguments failed:", err)
+	}
+	pattern := flag.Args()
+	if len(pattern) == 0 {
+		pattern = []string{"."}
+	}
+	proj, pattern, err := gopprojs.ParseOne(pattern...)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if len(pattern) != 0 {
+		log.Fatalln("too many arguments:", pattern)
+	}
+	v, ok := proj.(*gopprojs.DirProj)
+	if !ok {
+		log.Fatalln("`gop bundle` only supports a directory")
+	}
+	dir, err := filepath.Abs(v.Dir)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	assets, err := collectAssets(dir, *flagAssets)
+	if err != nil {
+		log.Fatalln("collect assets:", err)
+	}
+	name, exe := filepath.Base(dir), ""
+	if goos() == "windows" {
+		exe = ".exe"
+	}
+	output := *flagOutput
+	if output == "" {
+		if *flagZip {
+			output = name + ".zip"
+		} else {
+			output = name + exe
+		}
+	}
+
+	tmp, err := os.MkdirTemp("", "gop-bundle-")
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer os.RemoveAll(tmp)
+	bin := filepath.Join(tmp, name+exe)
+
+	if !*flagZip && len(assets) > 0 {
+		runner := filepath.Join(dir, runnerFile)
+		if err = os.WriteFile(runner, []byte(runnerSrc(assetsID(dir, assets))), 0644); err != nil {
+			log.Fatalln(err)
+		}
+		defer os.Remove(runner)
+	}
+	gopEnv := gopenv.Get()
+	conf := &gop.Config{Gop: gopEnv, OnWarning: base.PrintWarning}
+	confCmd := &gocmd.BuildConfig{Gop: gopEnv}
+	confCmd.Flags = append([]string{"-o", bin}, pass.Args...)
+	if err = gop.BuildDir(dir, conf, confCmd); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if *flagZip {
+		err = writeZip(output, dir, assets, name+exe, bin)
+	} else {
+		err = writeExe(output, dir, assets, bin)
+	}
+	if err != nil {
+		log.Fatalln(err)
+	}
+	fmt.Fprintf(os.Stderr, "bundled %s with %d assets\n", output, len(assets))
+}
+
+func goos() string {
+	if v := os.Getenv("GOOS"); v != "" {
+		return v
+	}
+	return runtime.GOOS
+}
+
+// -----------------------------------------------------------------------------
+
+// collectAssets returns assets in dir (as slash-separated relative paths). If
+// patterns is empty, all files except Go/Go+ source files, module files and
+// files in hidden, `_` prefixed or testdata directories are assets.
+func collectAssets(dir, patterns string) (assets []string, err error) {
+	if patterns != "" {
+		for _, pat := range strings.Split(patterns, ",") {
+			files, err := filepath.Glob(filepath.Join(dir, strings.TrimSpace(pat)))
+			if err != nil {
+				return nil, err
+			}
+			for _, file := range files {
+				if fi, e := os.Stat(file); e == nil && fi.Mode().IsRegular() {
+					rel, _ := filepath.Rel(dir, file)
+					assets = append(assets, filepath.ToSlash(rel))
+				}
+			}
+		}
+		sort.Strings(assets)
+		return dedup(assets), nil
+	}
+	mod, err := gop.LoadMod(dir)
+	if err != nil && !gop.NotFound(err) {
+		return
+	}
+	err = filepath.WalkDir(dir, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if file != dir && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "testdata") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || strings.HasPrefix(name, ".") || isCode(mod, name) {
+			return nil
+		}
+		rel, _ := filepath.Rel(dir, file)
+		assets = append(assets, filepath.ToSlash(rel))
+		return nil
+	})
+	return
+}
+
+func isCode(mod *gopmod.Module, fname string) bool {
+	switch ext := path.Ext(fname); ext {
+	case ".go", ".gop", ".gox", ".s", ".c", ".h":
+		return true
+	default:
+		switch fname {
+		case "go.mod", "go.sum", "gop.mod", "gop.sum", "go.work", "go.work.sum":
+			return true
+		}
+		return mod != nil && mod.IsClass(modfile.ClassExt(fname))
+	}
+}
+
+func dedup(a []string) []string {
+	ret := a[:0]
+	for i, v := range a {
+		if i == 0 || v != a[i-1] {
+			ret = append(ret, v)
+		}
+	}
+	return ret
+}
+
+// assetsID returns an ID of the assets, which identifies the directory they
+// are extracted into.
+func assetsID(dir string, assets []string) string {
+	h := sha256.New()
+	for _, asset := range assets {
+		fmt.Fprintf(h, "%s\x00", asset)
+		if f, err := os.Open(filepath.Join(dir, asset)); err == nil {
+			io.Copy(h, f)
+			f.Close()
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
+
+// -----------------------------------------------------------------------------
+
+func addFile(zw *zip.Writer, name, file string, mode fs.FileMode) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	hdr := &zip.FileHeader{Name: name, Method: zip.Deflate, Modified: fi.ModTime()}
+	hdr.SetMode(mode)
+	w, err := zw.CreateHeader(hdr)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, f)
+	return err
+}
+
+func writeZip(output, dir string, assets []string, exe, bin string) error {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	if err := addFile(zw, exe, bin, 0755); err != nil {
+		return err
+	}
+	for _, asset := range assets {
+		if err := addFile(zw, asset, filepath.Join(dir, asset), 0644); err != nil {
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return os.WriteFile(output, buf.Bytes(), 0644)
+}
+
+// writeExe writes the executable with assets appended in zip format. The
+// zip offsets are relative to the beginning of the file, so that the whole
+// executable can be opened as a zip file.
+func writeExe(output, dir string, assets []string, bin string) error {
+	b, err := os.ReadFile(bin)
+	if err != nil {
+		return err
+	}
+	buf := bytes.NewBuffer(b)
+	if len(assets) > 0 {
+		zw := zip.NewWriter(buf)
+		zw.SetOffset(int64(len(b)))
+		for _, asset := range assets {
+			if err = addFile(zw, asset, filepath.Join(dir, asset), 0644); err != nil {
+				return err
+			}
+		}
+		if err = zw.Close(); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(output, buf.Bytes(), 0755)
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,112 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bundle
+
+import (
+	"strings"
+)
+
+// runnerFile is the runner compiled into the program. It is skipped by the
+// Go+ compiler because of its gop_autogen prefix.
+const runnerFile = "gop_autogen_bundle.go"
+
+const runnerTempl = `// Code generated by gop bundle. DO NOT EDIT.
+
+package main
+
+import (
+	"archive/zip"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+func init() {
+	if err := _gopBundleExtract(); err != nil {
+		os.Stderr.WriteString("gop bundle: extract assets failed: " + err.Error() + "\n")
+		os.Exit(1)
+	}
+}
+
+// _gopBundleExtract extracts assets appended to the executable into a cache
+// directory (only once), and changes the working directory there. The
+// original working directory is saved in $GOP_BUNDLE_CWD.
+func _gopBundleExtract() error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(exe)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	zr, err := zip.NewReader(f, fi.Size())
+	if err != nil {
+		return err
+	}
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		cache = os.TempDir()
+	}
+	dir := filepath.Join(cache, "gop-bundle", "$ID")
+	done := filepath.Join(dir, ".done")
+	if _, err = os.Stat(done); err != nil {
+		for _, zf := range zr.File {
+			if err = _gopBundleExtractFile(dir, zf); err != nil {
+				return err
+			}
+		}
+		if err = os.WriteFile(done, nil, 0644); err != nil {
+			return err
+		}
+	}
+	if cwd, err := os.Getwd(); err == nil {
+		os.Setenv("GOP_BUNDLE_CWD", cwd)
+	}
+	return os.Chdir(dir)
+}
+
+func _gopBundleExtractFile(dir string, zf *zip.File) error {
+	file := filepath.Join(dir, filepath.FromSlash(zf.Name))
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	r, err := zf.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	w, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, zf.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(w, r); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+`
+
+func runnerSrc(id string) string {
+	return strings.Replace(runnerTempl, "$ID", id, 1)
+}
@@ -24,6 +24,7 @@ import (
 	"strings"
 
 	"github.com/goplus/gop/cmd/internal/base"
+	"github.com/goplus/gop/x/gocmd"
 )
 
 const (
@@ -97,7 +98,7 @@ func removeGopDir(dir string, execAct bool) {
 
 // Cmd - gop clean
 var Cmd = &base.Command{
-	UsageLine: "gop clean [flags] <gopSrcDir>",
+	UsageLine: "gop clean [-cache] [flags] [gopSrcDir]",
 	Short:     "Clean all Go+ auto generated files",
 }
 
@@ -106,6 +107,7 @@ var (
 
 	_        = flag.Bool("v", false, "print verbose information.")
 	testMode = flag.Bool("t", false, "test mode: display files to clean but don't clean them.")
+	cache    = flag.Bool("cache", false, "remove the entire $GOPCACHE, instead of generated files in gopSrcDir if it isn't specified.")
 )
 
 func init() {
@@ -117,6 +119,18 @@ func runCmd(_ *base.Command, args []string) {
 	if err != nil {
 		log.Fatalln("parse input arguments failed:", err)
 	}
+	if *cache {
+		dir := gocmd.CacheDir()
+		fmt.Printf("Cleaning %s ...\n", dir)
+		if !*testMode {
+			if err = os.RemoveAll(dir); err != nil {
+				log.Fatalln(err)
+			}
+		}
+		if flag.NArg() == 0 {
+			return
+		}
+	}
 	var dir string
 	if flag.NArg() == 0 {
 		dir = "."
@@ -38,7 +38,7 @@ import (
 
 // gop doc
 var Cmd = &base.Command{
-	UsageLine: "gop doc [-u -all -debug] [pkgPath]",
+	UsageLine: "gop doc [-u -all -debug] [pkgPath] [sym[.methodOrField]]",
 	Short:     "Show documentation for package or symbol",
 }
 
@@ -59,15 +59,25 @@ func runCmd(cmd *base.Command, args []string) {
 		log.Fatalln("parse input arguments failed:", err)
 	}
 
-	pattern := flag.Args()
-	if len(pattern) == 0 {
-		pattern = []string{"."}
+	pkgArg, sym := ".", ""
+	switch args := flag.Args(); len(args) {
+	case 0:
+	case 1: // eg. fmt.Println, or Println of current package
+		pkgArg, sym = splitSymbol(args[0])
+	case 2: // eg. ./mypkg MyFunc
+		pkgArg, sym = args[0], args[1]
+	default:
+		cmd.Usage(os.Stderr)
 	}
 
-	proj, _, err := gopprojs.ParseOne(pattern...)
+	proj, _, err := gopprojs.ParseOne(pkgArg)
 	if err != nil {
 		log.Panicln("gopprojs.ParseOne:", err)
 	}
+	if isGoPkg(proj) {
+		goDoc(pkgArg, sym)
+		return
+	}
 
 	if *debug {
 		gox.SetDebug(gox.DbgFlagAll &^ gox.DbgFlagComments)
@@ -77,10 +87,10 @@ func runCmd(cmd *base.Command, args []string) {
 
 	gopEnv := gopenv.Get()
 	conf := &gop.Config{Gop: gopEnv}
-	outlinePkg(proj, conf)
+	outlinePkg(proj, sym, conf)
 }
 
-func outlinePkg(proj gopprojs.Proj, conf *gop.Config) {
+func outlinePkg(proj gopprojs.Proj, sym string, conf *gop.Config) {
 	var obj string
 	var out outline.Package
 	var err error
@@ -98,8 +108,11 @@ func outlinePkg(proj gopprojs.Proj, conf *gop.Config) {
 		fmt.Fprintf(os.Stderr, "gop doc %v: not Go/Go+ files found\n", obj)
 	} else if err != nil {
 		fmt.Fprintln(os.Stderr, err)
-	} else {
+	} else if sym == "" {
 		outlineDoc(out.Outline(*unexp), *unexp, *withDoc)
+	} else if !symbolDoc(out.Outline(*unexp), sym, *unexp) {
+		fmt.Fprintf(os.Stderr, "gop doc: no symbol %s in package %s\n", sym, out.Pkg().Path())
+		os.Exit(1)
 	}
 }
 
@@ -136,28 +149,34 @@ func outlineDoc(out *outline.All, all, withDoc bool) {
 		if !(all || t.IsUsed()) {
 			continue
 		}
-		typName := t.ObjWith(all)
-		fmt.Print(objectString(pkg, typName), ln)
-		for _, o := range t.Consts {
-			fmt.Print(indent, constShortString(o.Const), ln)
-		}
-		if withDoc {
-			printDoc(t)
-		}
-		printFuncsForType(pkg, t.Creators, withDoc)
-		printFuncsForType(pkg, t.GoptFuncs, withDoc)
-		printFuncsForType(pkg, t.Helpers, withDoc)
-		if !typName.IsAlias() {
-			typ := t.Type()
-			if named, ok := typ.CheckNamed(out.Package); ok {
-				for _, fn := range named.Methods() {
-					if o := fn.Obj(); all || o.Exported() {
-						if withDoc {
-							fmt.Print(objectString(pkg, o), ln)
-							printDoc(fn)
-						} else {
-							fmt.Print(indent, objectString(pkg, o), ln)
-						}
+		printType(pkg, out, t, all, withDoc, withDoc)
+	}
+}
+
+// printType prints the type t with its constants, functions and methods,
+// followed by the doc of t if typeDoc, and with their docs if withDoc.
+func printType(pkg *types.Package, out *outline.All, t *outline.TypeName, all, typeDoc, withDoc bool) {
+	typName := t.ObjWith(all)
+	fmt.Print(objectString(pkg, typName), ln)
+	for _, o := range t.Consts {
+		fmt.Print(indent, constShortString(o.Const), ln)
+	}
+	if typeDoc {
+		printDoc(t)
+	}
+	printFuncsForType(pkg, t.Creators, withDoc)
+	printFuncsForType(pkg, t.GoptFuncs, withDoc)
+	printFuncsForType(pkg, t.Helpers, withDoc)
+	if !typName.IsAlias() {
+		typ := t.Type()
+		if named, ok := typ.CheckNamed(out.Package); ok {
+			for _, fn := range named.Methods() {
+				if o := fn.Obj(); all || o.Exported() {
+					if withDoc {
+						fmt.Print(objectString(pkg, o), ln)
+						printDoc(fn)
+					} else {
+						fmt.Print(indent, objectString(pkg, o), ln)
 					}
 				}
 			}
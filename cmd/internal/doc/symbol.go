@@ -0,0 +1,218 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package doc
+
+import (
+	"fmt"
+	"go/types"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/goplus/gop"
+	"github.com/goplus/gop/cl/outline"
+	"github.com/goplus/gop/x/classfile"
+	"github.com/goplus/gop/x/gocmd"
+	"github.com/goplus/gop/x/gopprojs"
+)
+
+// -----------------------------------------------------------------------------
+
+// splitSymbol splits arg like pkg.Sym or pkg.Sym.Method into the package and
+// the symbol. A single Sym (which starts with an upper-case letter) is a
+// symbol of the package in current directory.
+func splitSymbol(arg string) (pkg, sym string) {
+	elem := arg[strings.LastIndexAny(arg, `/\`)+1:]
+	if i := strings.IndexByte(elem, '.'); i > 0 && i+1 < len(elem) {
+		// eg. fmt.println, but not gopkg.in/yaml.v3
+		if rest := elem[i+1:]; isUpper(rest) || len(elem) == len(arg) {
+			return arg[:len(arg)-len(elem)+i], rest
+		}
+	}
+	if isUpper(arg) && !strings.ContainsAny(arg, `./\`) {
+		return ".", arg
+	}
+	return arg, ""
+}
+
+func isUpper(s string) bool {
+	c, _ := utf8.DecodeRuneInString(s)
+	return unicode.IsUpper(c)
+}
+
+// isGoPkg checks if proj is a Go package without Go+ files, whose
+// documentation is shown by `go doc`.
+func isGoPkg(proj gopprojs.Proj) bool {
+	var dir string
+	switch v := proj.(type) {
+	case *gopprojs.DirProj:
+		dir = v.Dir
+	case *gopprojs.PkgPathProj:
+		out, err := gocmd.Command("list", "-f", "{{.Dir}}", v.Path).Output()
+		if err != nil { // eg. a Go+ package without Go files, or not downloaded yet
+			return false
+		}
+		dir = strings.TrimSpace(string(out))
+	default:
+		return false
+	}
+	mod, err := gop.LoadMod(dir)
+	if err != nil {
+		return false
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false
+	}
+	kind := classfile.KindOf(mod.ClassKind)
+	hasGo := false
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		switch fname := e.Name(); filepath.Ext(fname) {
+		case ".gop", ".gox":
+			return false
+		case ".go":
+			hasGo = true
+		default:
+			if _, ok := kind(fname); ok {
+				return false
+			}
+		}
+	}
+	return hasGo
+}
+
+// goDoc shows documentation of a Go package by `go doc`.
+func goDoc(pkg, sym string) {
+	args := []string{"doc"}
+	if *withDoc {
+		args = append(args, "-all")
+	}
+	if *unexp {
+		args = append(args, "-u")
+	}
+	args = append(args, pkg)
+	if sym != "" {
+		args = append(args, sym)
+	}
+	cmd := gocmd.Command(args...)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		if _, ok := err.(*exec.ExitError); !ok {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		os.Exit(1)
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+// symbolDoc prints documentation of the symbol sym (Sym or Sym.Member) of
+// out. A symbol name in lower case matches the exported name too, like
+// println matches Println in Go+ code. Overloaded functions and methods are
+// shown together by their Go+ name. It returns false if sym isn't found.
+func symbolDoc(out *outline.All, sym string, all bool) (found bool) {
+	pkg := out.Pkg()
+	name, member := sym, ""
+	if pos := strings.IndexByte(sym, '.'); pos >= 0 {
+		name, member = sym[:pos], sym[pos+1:]
+	}
+	for _, t := range out.Types {
+		if !matchName(t.Obj(), name, all) {
+			continue
+		}
+		if member == "" {
+			printType(pkg, out, t, all, true, false)
+			return true
+		}
+		if named, ok := t.Type().CheckNamed(out.Package); ok && !t.IsAlias() {
+			for _, fn := range named.Methods() {
+				if matchName(fn.Obj(), member, all) {
+					printObject(pkg, fn, true)
+					found = true
+				}
+			}
+		}
+		if st, ok := t.Type().Underlying().(*types.Struct); ok && !found {
+			for i, n := 0, st.NumFields(); i < n; i++ { // eg. members of a classfile
+				if fld := st.Field(i); matchName(fld, member, all) {
+					fmt.Print(types.ObjectString(fld, qualifier(pkg)), ln)
+					found = true
+				}
+			}
+		}
+		return
+	}
+	if member != "" {
+		return false
+	}
+	for _, o := range out.Consts {
+		if matchName(o.Obj(), name, all) {
+			printObject(pkg, o, true)
+			found = true
+		}
+	}
+	for _, o := range out.Vars {
+		if matchName(o.Obj(), name, all) {
+			printObject(pkg, o, true)
+			found = true
+		}
+	}
+	fns := out.Funcs
+	for _, t := range out.Types {
+		for _, o := range t.Consts {
+			if matchName(o.Obj(), name, all) {
+				printObject(pkg, o, true)
+				found = true
+			}
+		}
+		fns = append(fns, t.Creators...)
+		fns = append(fns, t.GoptFuncs...)
+		fns = append(fns, t.Helpers...)
+	}
+	for _, fn := range fns {
+		if matchName(fn.Obj(), name, all) {
+			printObject(pkg, fn, true)
+			found = true
+		}
+	}
+	return
+}
+
+// matchName checks if name refers to o, which is an overloaded function (or
+// method) named name in Go+ code, or whose name is name or name in title case.
+func matchName(o types.Object, name string, all bool) bool {
+	if !(all || o.Exported()) {
+		return false
+	}
+	objName := o.Name()
+	if oname, _, ok := outline.CheckOverload(o); ok {
+		objName = oname
+	}
+	if objName == name {
+		return true
+	}
+	c, n := utf8.DecodeRuneInString(name)
+	return unicode.IsLower(c) && objName == string(unicode.ToUpper(c))+name[n:]
+}
+
+// -----------------------------------------------------------------------------
@@ -22,9 +22,10 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"os/exec"
 	"sort"
+	"text/tabwriter"
 
+	"github.com/goplus/gop"
 	"github.com/goplus/gop/cmd/internal/base"
 	"github.com/goplus/gop/env"
 	"github.com/goplus/gop/x/gocmd"
@@ -34,13 +35,14 @@ import (
 
 // Cmd - gop env
 var Cmd = &base.Command{
-	UsageLine: "gop env [-json] [var ...]",
+	UsageLine: "gop env [-json] [-experiments] [var ...]",
 	Short:     "Prints Go+ environment information",
 }
 
 var (
 	flag    = &Cmd.Flag
 	envJson = flag.Bool("json", false, "prints Go environment information.")
+	envExps = flag.Bool("experiments", false, "prints experiments which can be enabled by GOPEXPERIMENT.")
 )
 
 func init() {
@@ -52,11 +54,14 @@ func runCmd(_ *base.Command, args []string) {
 	if err != nil {
 		log.Fatalln("parse input arguments failed:", err)
 	}
+	if *envExps {
+		outputExperiments()
+		return
+	}
 
 	var stdout bytes.Buffer
 
-	cmd := exec.Command("go", "env", "-json")
-	cmd.Env = os.Environ()
+	cmd := gocmd.Command("env", "-json")
 	cmd.Stdout = &stdout
 
 	err = cmd.Run()
@@ -75,7 +80,10 @@ func runCmd(_ *base.Command, args []string) {
 	gopEnv["GOP_GOCMD"] = gocmd.Name()
 	gopEnv["GOMODCACHE"] = modcache.GOMODCACHE
 	gopEnv["GOPMOD"], _ = mod.GOPMOD("")
+	gopEnv["GOPCACHE"] = gocmd.CacheDir()
 	gopEnv["HOME"] = env.HOME()
+	gopEnv[gop.EnvToolchain] = os.Getenv(gop.EnvToolchain)
+	gopEnv[env.EnvExperiment] = os.Getenv(env.EnvExperiment)
 
 	vars := flag.Args()
 
@@ -125,3 +133,20 @@ func outputEnvVars(gopEnv map[string]interface{}, vars []string, outputJson bool
 		}
 	}
 }
+
+func outputExperiments() {
+	enabled, _ := env.ParseExperiment(os.Getenv(env.EnvExperiment))
+	on := make(map[string]bool, len(enabled))
+	for _, name := range enabled {
+		on[name] = true
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, exp := range env.Experiments() {
+		state := "off"
+		if on[exp.Name] {
+			state = "on"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", exp.Name, state, exp.Doc)
+	}
+	w.Flush()
+}
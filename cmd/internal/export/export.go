@@ -0,0 +1,385 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package export implements the “gop export” command.
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/goplus/gop"
+	"github.com/goplus/gop/cl"
+	"github.com/goplus/gop/cmd/internal/base"
+	"github.com/goplus/gop/x/gocmd"
+	"github.com/goplus/gop/x/gopenv"
+	"github.com/goplus/gox"
+	"github.com/goplus/mod/gopmod"
+	"golang.org/x/mod/modfile"
+)
+
+// gop export
+var Cmd = &base.Command{
+	UsageLine: "gop export [-v -verify=false] [-o dir] [dir]",
+	Short:     "Export a Go+ module as a pure Go module",
+}
+
+var (
+	flag        = &Cmd.Flag
+	flagOutput  = flag.String("o", "", "output `directory` (default is <module>-go next to the module)")
+	flagVerify  = flag.Bool("verify", true, "verify that the exported module builds")
+	flagVerbose = flag.Bool("v", false, "print verbose information")
+)
+
+func init() {
+	Cmd.Run = runCmd
+}
+
+// runCmd exports the module containing dir into the output directory:
+//   - Go+ source files of each package are compiled into a gofmt-ed Go file
+//     without //line directives.
+//   - other files are copied as they are.
+//   - go.mod is rewritten: local replacements are made relative to the new
+//     location, and github.com/goplus/gop is required only if the exported
+//     code still imports it (eg. for builtin/ng).
+//
+// Finally it runs `go build ./...` in the output directory.
+func runCmd(cmd *base.Command, args []string) {
+	err := flag.Parse(args)
+	if err != nil {
+		log.Fatalln("parse input arguments failed:", err)
+	}
+	if flag.NArg() > 1 {
+		cmd.Usage(os.Stderr)
+	}
+	dir := "."
+	if flag.NArg() == 1 {
+		dir = flag.Arg(0)
+	}
+	mod, err := gop.LoadMod(dir)
+	if err != nil {
+		fatal(err)
+	}
+	if !mod.HasModfile() {
+		fatal("go.mod not found")
+	}
+	root := mod.Root()
+	out := *flagOutput
+	if out == "" {
+		out = root + "-go"
+	}
+	if out, err = filepath.Abs(out); err != nil {
+		fatal(err)
+	}
+	entries, err := os.ReadDir(out)
+	if len(entries) > 0 {
+		fatal(out, "already exists and is not empty")
+	}
+	created := err != nil
+
+	p := &exporter{
+		mod:  mod,
+		out:  out,
+		conf: &gop.Config{Gop: gopenv.Get(), NoFileLine: true, KeepComments: true, OnWarning: base.PrintWarning},
+	}
+	if err = p.export(root); err != nil {
+		if created {
+			os.RemoveAll(out)
+		}
+		fatal(err)
+	}
+	if *flagVerify {
+		build := gocmd.Command("build", "./...")
+		build.Dir = out
+		build.Stdout, build.Stderr = os.Stdout, os.Stderr
+		if err = build.Run(); err != nil {
+			fatal("exported module in", out, "doesn't build:", err)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "exported %d Go+ packages of %s to %s\n", p.npkg, mod.Path(), out)
+}
+
+func fatal(args ...interface{}) {
+	fmt.Fprintln(os.Stderr, append([]interface{}{"gop export:"}, args...)...)
+	os.Exit(1)
+}
+
+// -----------------------------------------------------------------------------
+
+type exporter struct {
+	mod  *gopmod.Module
+	out  string
+	conf *gop.Config
+	npkg int
+}
+
+func (p *exporter) export(root string) (err error) {
+	if err = p.exportDir(root, p.out); err != nil {
+		return
+	}
+	return p.rewriteGoMod(root)
+}
+
+func (p *exporter) isGopFile(fname string) bool {
+	switch filepath.Ext(fname) {
+	case ".gop", ".gox":
+		return true
+	case ".go", ".mod", ".sum":
+		return false
+	}
+	_, ok := p.mod.ClassKind(fname)
+	return ok
+}
+
+func skipFile(fname string) bool {
+	return strings.HasPrefix(fname, "gop_autogen") || fname == "gop.mod"
+}
+
+func (p *exporter) exportDir(src, dst string) (err error) {
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return
+	}
+	if err = os.MkdirAll(dst, 0755); err != nil {
+		return
+	}
+	var gopFiles []string
+	for _, e := range entries {
+		fname := e.Name()
+		srcFile := filepath.Join(src, fname)
+		if e.IsDir() {
+			// skip .git, .gop (cache of Go+ tools), and the output itself
+			if strings.HasPrefix(fname, ".") || srcFile == p.out {
+				continue
+			}
+			if err = p.exportDir(srcFile, filepath.Join(dst, fname)); err != nil {
+				return
+			}
+			continue
+		}
+		if p.isGopFile(fname) {
+			if !strings.HasPrefix(fname, "_") {
+				gopFiles = append(gopFiles, fname)
+			}
+			continue
+		}
+		if skipFile(fname) {
+			continue
+		}
+		if err = copyFile(filepath.Join(dst, fname), srcFile); err != nil {
+			return
+		}
+	}
+	if len(gopFiles) > 0 {
+		err = p.exportPkg(src, dst, gopFiles)
+	}
+	return
+}
+
+func (p *exporter) exportPkg(src, dst string, gopFiles []string) (err error) {
+	if *flagVerbose {
+		fmt.Fprintln(os.Stderr, "export", src)
+	}
+	out, test, err := gop.LoadDir(src, p.conf, true)
+	if err != nil {
+		if gop.NotFound(err) {
+			return nil
+		}
+		return
+	}
+	name := goFileName(dst, out.Types.Name(), gopFiles)
+	if err = writeFile(out, filepath.Join(dst, name+".go")); err != nil {
+		return
+	}
+	err = writeFile(out, filepath.Join(dst, name+"_test.go"), "_test")
+	if err != nil && err != syscall.ENOENT {
+		return
+	}
+	err = nil
+	if test != nil {
+		err = writeFile(test, filepath.Join(dst, name+"_x_test.go"), "_test")
+	}
+	p.npkg++
+	return
+}
+
+// writeFile writes a Go file of pkg, and then makes it read like handwritten
+// Go code: top-level declarations are separated by blank lines and the file
+// is gofmt-ed.
+func writeFile(pkg *gox.Package, file string, fname ...string) (err error) {
+	if err = cl.WriteFile(nil, pkg, file, fname...); err != nil {
+		return
+	}
+	src, err := os.ReadFile(file)
+	if err != nil {
+		return
+	}
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, file, src, parser.ParseComments)
+	if err != nil {
+		return
+	}
+	var b bytes.Buffer
+	last := 0
+	for _, decl := range f.Decls {
+		pos := decl.Pos()
+		if doc := declDoc(decl); doc != nil {
+			pos = doc.Pos()
+		}
+		off := fset.Position(pos).Offset
+		off -= len(src[:off]) - len(bytes.TrimRight(src[:off], " \t"))
+		if off >= 2 && src[off-1] == '\n' && src[off-2] != '\n' {
+			b.Write(src[last:off])
+			b.WriteByte('\n')
+			last = off
+		}
+	}
+	b.Write(src[last:])
+	if src, err = format.Source(b.Bytes()); err != nil {
+		return
+	}
+	return os.WriteFile(file, src, 0644)
+}
+
+func declDoc(decl ast.Decl) *ast.CommentGroup {
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		return d.Doc
+	case *ast.GenDecl:
+		return d.Doc
+	}
+	return nil
+}
+
+// goFileName returns the name (without .go) of the Go file to export a Go+
+// package into: foo.gop is exported into foo.go if it's the only Go+ source
+// file of the package, otherwise the package name is used. A gop_ prefix is
+// added to avoid conflicts with existing Go files.
+func goFileName(dst, pkgName string, gopFiles []string) string {
+	name := pkgName
+	var srcs []string
+	for _, f := range gopFiles {
+		if !strings.HasSuffix(strings.TrimSuffix(f, filepath.Ext(f)), "_test") {
+			srcs = append(srcs, f)
+		}
+	}
+	if len(srcs) == 1 {
+		name = strings.TrimSuffix(srcs[0], filepath.Ext(srcs[0]))
+	}
+	for _, suffix := range []string{".go", "_test.go", "_x_test.go"} {
+		if _, err := os.Stat(filepath.Join(dst, name+suffix)); err == nil {
+			return "gop_" + name
+		}
+	}
+	return name
+}
+
+func copyFile(dst, src string) (err error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return
+	}
+	defer in.Close()
+	fi, err := in.Stat()
+	if err != nil {
+		return
+	}
+	f, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, fi.Mode().Perm())
+	if err != nil {
+		return
+	}
+	_, err = io.Copy(f, in)
+	if e := f.Close(); err == nil {
+		err = e
+	}
+	return
+}
+
+// -----------------------------------------------------------------------------
+
+const gopMod = "github.com/goplus/gop"
+
+// rewriteGoMod writes go.mod of the exported module.
+func (p *exporter) rewriteGoMod(root string) (err error) {
+	gomod := filepath.Join(root, "go.mod")
+	data, err := os.ReadFile(gomod)
+	if err != nil {
+		return
+	}
+	f, err := modfile.Parse(gomod, data, nil)
+	if err != nil {
+		return
+	}
+	for _, r := range f.Replace {
+		if r.New.Version == "" && !filepath.IsAbs(r.New.Path) {
+			abs := filepath.Join(root, filepath.FromSlash(r.New.Path))
+			rel, e := filepath.Rel(p.out, abs)
+			if e != nil {
+				rel = abs
+			} else if !strings.HasPrefix(rel, "..") {
+				rel = "./" + rel
+			}
+			if err = f.AddReplace(r.Old.Path, r.Old.Version, filepath.ToSlash(rel), ""); err != nil {
+				return
+			}
+		}
+	}
+	usesGop, err := importsGop(p.out)
+	if err != nil {
+		return
+	}
+	if !usesGop && p.mod.Path() != gopMod {
+		f.DropRequire(gopMod)
+		for _, r := range f.Replace {
+			if r.Old.Path == gopMod {
+				f.DropReplace(r.Old.Path, r.Old.Version)
+			}
+		}
+		f.Cleanup()
+	}
+	if data, err = f.Format(); err != nil {
+		return
+	}
+	return os.WriteFile(filepath.Join(p.out, "go.mod"), data, 0644)
+}
+
+// importsGop checks if any Go file in dir imports a package of Go+.
+func importsGop(dir string) (found bool, err error) {
+	err = filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil || found || fi.IsDir() || !strings.HasSuffix(path, ".go") {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		found = strings.Contains(string(data), `"`+gopMod+`/`)
+		return nil
+	})
+	return
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,140 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package fix implements the “gop fix” command.
+package fix
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+	"sort"
+
+	"github.com/goplus/gop"
+	"github.com/goplus/gop/cl"
+	"github.com/goplus/gop/cmd/internal/base"
+	"github.com/goplus/gop/token"
+	"github.com/goplus/gop/x/gopprojs"
+)
+
+// gop fix
+var Cmd = &base.Command{
+	UsageLine: "gop fix [-n -lang version] [packages]",
+	Short:     "Update Go+ packages to use new syntax instead of deprecated ones",
+}
+
+var (
+	flag       = &Cmd.Flag
+	flagDryRun = flag.Bool("n", false, "print names of files to fix, but don't fix them")
+	flagLang   = flag.String("lang", "", "language `version` to fix to, eg. gop1.1 (default is the latest)")
+)
+
+func init() {
+	Cmd.Run = runCmd
+}
+
+func runCmd(cmd *base.Command, args []string) {
+	err := flag.Parse(args)
+	if err != nil {
+		log.Panicln("parse input arguments failed:", err)
+	}
+	pattern := flag.Args()
+	if len(pattern) == 0 {
+		pattern = []string{"."}
+	}
+
+	projs, err := gopprojs.ParseAll(pattern...)
+	if err != nil {
+		log.Panicln("gopprojs.ParseAll:", err)
+	}
+
+	fset := token.NewFileSet()
+	edits := make(map[*token.File][]cl.TextEdit)
+	conf := &gop.Config{Fset: fset, Lang: *flagLang}
+	conf.OnWarning = func(err error) {
+		for _, d := range cl.Diagnostics(err) {
+			if d.Code == "deprecated" && len(d.Fixes) > 0 {
+				f := fset.File(d.Pos)
+				edits[f] = append(edits[f], d.Fixes[0].Edits...)
+			}
+		}
+	}
+	exitCode := 0
+	for _, proj := range projs {
+		switch v := proj.(type) {
+		case *gopprojs.DirProj:
+			_, _, err = gop.GenGoEx(v.Dir, conf, true, gop.GenFlagCheckOnly)
+		case *gopprojs.PkgPathProj:
+			_, _, err = gop.GenGoPkgPathEx("", v.Path, conf, false, gop.GenFlagCheckOnly)
+		default:
+			log.Panicln("`gop fix` doesn't support", reflect.TypeOf(v))
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			exitCode = 1
+		}
+	}
+	for _, f := range sortedFiles(edits) {
+		if *flagDryRun {
+			fmt.Println(f.Name())
+		} else if err = fixFile(f, edits[f]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			exitCode = 1
+		}
+	}
+	os.Exit(exitCode)
+}
+
+func fixFile(f *token.File, edits []cl.TextEdit) error {
+	file := f.Name()
+	src, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	ret, err := cl.ApplyEdits(f, src, uniqueEdits(edits))
+	if err != nil {
+		return fmt.Errorf("%s: %v", file, err)
+	}
+	fmt.Println("fix", file)
+	return os.WriteFile(file, ret, 0666)
+}
+
+// uniqueEdits removes duplicated edits, as some code may be compiled twice.
+func uniqueEdits(edits []cl.TextEdit) []cl.TextEdit {
+	seen := make(map[cl.TextEdit]bool, len(edits))
+	ret := edits[:0]
+	for _, e := range edits {
+		if !seen[e] {
+			seen[e] = true
+			ret = append(ret, e)
+		}
+	}
+	return ret
+}
+
+func sortedFiles(edits map[*token.File][]cl.TextEdit) []*token.File {
+	files := make([]*token.File, 0, len(edits))
+	for f := range edits {
+		files = append(files, f)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name() < files[j].Name()
+	})
+	return files
+}
+
+// -----------------------------------------------------------------------------
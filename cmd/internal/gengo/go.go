@@ -26,14 +26,16 @@ import (
 	"github.com/goplus/gop"
 	"github.com/goplus/gop/cl"
 	"github.com/goplus/gop/cmd/internal/base"
+	"github.com/goplus/gop/x/crash"
 	"github.com/goplus/gop/x/gopprojs"
+	"github.com/goplus/gop/x/progress"
 	"github.com/goplus/gox"
 	"github.com/qiniu/x/errors"
 )
 
 // gop go
 var Cmd = &base.Command{
-	UsageLine: "gop go [-v] [packages]",
+	UsageLine: "gop go [-v] [-p n] [packages]",
 	Short:     "Convert Go+ packages into Go packages",
 }
 
@@ -42,6 +44,7 @@ var (
 	flagVerbose          = flag.Bool("v", false, "print verbose information")
 	flagCheckMode        = flag.Bool("t", false, "do check syntax only, no generate gop_autogen.go")
 	flagSingleMode       = flag.Bool("s", false, "run in single file mode")
+	flagParallel         = flag.Int("p", 0, "the number of packages to compile in parallel, default is the number of CPUs")
 	flagIgnoreNotatedErr = flag.Bool(
 		"ignore-notated-error", false, "ignore notated errors, only available together with -t (check mode)")
 )
@@ -69,15 +72,15 @@ func runCmd(cmd *base.Command, args []string) {
 		gox.SetDebug(gox.DbgFlagAll &^ gox.DbgFlagComments)
 		cl.SetDebug(cl.DbgFlagAll)
 		cl.SetDisableRecover(true)
+		*flagParallel = 1 // not to interleave debug output of packages
 	}
 
-	var conf *gop.Config
+	conf := &gop.Config{OnProgress: progress.Hook(), Parallel: *flagParallel}
+	conf.OnCrash = crash.Hook(conf)
 	flags := gop.GenFlagPrintError | gop.GenFlagPrompt
 	if *flagCheckMode {
 		flags |= gop.GenFlagCheckOnly
-		if *flagIgnoreNotatedErr {
-			conf = &gop.Config{IgnoreNotatedError: true}
-		}
+		conf.IgnoreNotatedError = *flagIgnoreNotatedErr
 	}
 	if *flagSingleMode {
 		flags |= gop.GenFlagSingleFile
@@ -92,10 +95,12 @@ func runCmd(cmd *base.Command, args []string) {
 			log.Panicln("`gop go` doesn't support", reflect.TypeOf(v))
 		}
 		if err != nil {
+			progress.Done(err)
 			fmt.Fprintf(os.Stderr, "GenGo failed: %d errors.\n", errorNum(err))
 			os.Exit(1)
 		}
 	}
+	progress.Done(nil)
 }
 
 func errorNum(err error) int {
@@ -20,11 +20,15 @@ package gopfmt
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"io/fs"
 	"log"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/goplus/gop"
 
@@ -35,21 +39,28 @@ import (
 	"go/parser"
 	"go/token"
 
+	"github.com/goplus/gop/x/diff"
 	xformat "github.com/goplus/gop/x/format"
 )
 
 // Cmd - gop fmt
 var Cmd = &base.Command{
-	UsageLine: "gop fmt [flags] path ...",
+	UsageLine: "gop fmt [flags] [path ...]",
 	Short:     "Format Go+ packages",
 }
 
 var (
 	flag        = &Cmd.Flag
 	flagTest    = flag.Bool("t", false, "test if Go+ files are formatted or not.")
+	flagList    = flag.Bool("l", false, "list files whose formatting differs from gop fmt's.")
+	flagWrite   = flag.Bool("w", false, "write result to the source file. It is the default if none of -l, -d and -t is specified.")
+	flagDiff    = flag.Bool("d", false, "display diffs instead of rewriting files.")
 	flagNotExec = flag.Bool("n", false, "prints commands that would be executed.")
 	flagMoveGo  = flag.Bool("mvgo", false, "move .go files to .gop files (only available in `--smart` mode).")
 	flagSmart   = flag.Bool("smart", false, "convert Go code style into Go+ style.")
+	flagSimple  = flag.Bool("s", false, "simplify Go+ code into Go+ idioms.")
+	flagRewrite = flag.String("r", "", "apply a `rewrite` to Go+ code: comp2loop (comprehensions => for loops) or loop2comp (for loops => comprehensions).")
+	flagSrcPath = flag.String("srcpath", "", "`path` of the source read from stdin, to detect its kind (eg. classfile).")
 )
 
 func init() {
@@ -57,56 +68,111 @@ func init() {
 }
 
 var (
-	testErrCnt = 0
-	procCnt    = 0
 	walkSubDir = false
 	rootDir    = ""
 )
 
-func gopfmt(path string, class, smart, mvgo bool) (err error) {
+// gopfmt formats the file path, and reports whether it is changed (or needs
+// to be changed with -t).
+func gopfmt(path string, class, smart, mvgo bool) (changed bool, err error) {
 	src, err := os.ReadFile(path)
-	if err != nil {
+	if err != nil || isGenerated(src) {
 		return
 	}
-	var target []byte
-	if smart {
-		target, err = xformat.GopstyleSource(src, path)
-	} else {
-		if !mvgo && filepath.Ext(path) == ".go" {
-			fset := token.NewFileSet()
-			f, err := parser.ParseFile(fset, path, src, parser.ParseComments)
-			if err != nil {
-				return err
-			}
-			var buf bytes.Buffer
-			err = goformat.Node(&buf, fset, f)
-			if err != nil {
-				return err
-			}
-			target = buf.Bytes()
-		} else {
-			target, err = format.Source(src, class, path)
-		}
-	}
+	target, err := formatSource(src, path, class, smart, mvgo)
 	if err != nil {
 		return
 	}
 	if bytes.Equal(src, target) {
 		return
 	}
-	fmt.Println(path)
-	if *flagTest {
-		testErrCnt++
-		return nil
+	list, write := outputMode()
+	printMutex.Lock()
+	if list {
+		fmt.Println(path)
+	}
+	if *flagDiff {
+		os.Stdout.Write(diff.Unified(path+".orig", path, src, target))
+	}
+	printMutex.Unlock()
+	if !write {
+		return true, nil
 	}
 	if mvgo {
 		newPath := strings.TrimSuffix(path, ".go") + ".gop"
 		if err = os.WriteFile(newPath, target, 0666); err != nil {
 			return
 		}
-		return os.Remove(path)
+		return true, os.Remove(path)
+	}
+	return true, writeFileWithBackup(path, target)
+}
+
+// outputMode reports whether names of changed files are listed and whether
+// they are rewritten. Without -l, -d, -w and -t, files are rewritten and
+// listed.
+func outputMode() (list, write bool) {
+	if !*flagList && !*flagDiff && !*flagWrite {
+		return true, !*flagTest
+	}
+	return *flagList, *flagWrite
+}
+
+// maxFormatPasses limits passes of formatting to reach a stable result.
+const maxFormatPasses = 3
+
+// formatSource formats src of the file path. It formats the result again
+// until it doesn't change any more, so that the output is byte-stable: that
+// is, formatting the output gets the output itself.
+func formatSource(src []byte, path string, class, smart, mvgo bool) (target []byte, err error) {
+	if *flagRewrite != "" && !smart && filepath.Ext(path) != ".go" {
+		if src, err = rewriteSource(src, path); err != nil {
+			return
+		}
+	}
+	for i := 0; i < maxFormatPasses; i++ {
+		if target, err = formatOnce(src, path, class, smart, mvgo); err != nil {
+			return
+		}
+		if bytes.Equal(src, target) {
+			break
+		}
+		if (smart || mvgo) && filepath.Ext(path) == ".go" { // Go code is converted into Go+ code
+			path = strings.TrimSuffix(path, ".go") + ".gop"
+		}
+		src, smart, mvgo = target, false, false
+	}
+	return
+}
+
+func formatOnce(src []byte, path string, class, smart, mvgo bool) (target []byte, err error) {
+	if smart {
+		return xformat.GopstyleSource(src, path)
+	}
+	if !mvgo && filepath.Ext(path) == ".go" {
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, path, src, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		err = goformat.Node(&buf, fset, f)
+		if err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	style, err := styleOf(path)
+	if err != nil {
+		return
+	}
+	if *flagSimple {
+		if target, err = xformat.SimplifySource(src, class, path); err != nil || style == nil {
+			return
+		}
+		src = target
 	}
-	return writeFileWithBackup(path, target)
+	return format.SourceWithStyle(src, class, style, path)
 }
 
 func writeFileWithBackup(path string, target []byte) (err error) {
@@ -130,70 +196,162 @@ func writeFileWithBackup(path string, target []byte) (err error) {
 
 type walker struct {
 	dirMap map[string]func(ext string) (ok, class bool)
+	ignore *ignorer
+	jobs   []*job
+}
+
+type job struct {
+	path         string
+	class, smart bool
+	mvgo         bool
 }
 
-func newWalker() *walker {
-	return &walker{dirMap: make(map[string]func(ext string) (ok, class bool))}
+func newWalker(root string) *walker {
+	return &walker{
+		dirMap: make(map[string]func(ext string) (ok, class bool)),
+		ignore: loadIgnore(root),
+	}
+}
+
+// kindOf reports whether the file path is a Go/Go+ source file, and whether
+// it is a classfile.
+func (w *walker) kindOf(path string) (ok, class bool) {
+	dir, _ := filepath.Split(path)
+	fn, ok := w.dirMap[dir]
+	if !ok {
+		if mod, err := gop.LoadMod(path); err == nil {
+			fn = func(ext string) (ok bool, class bool) {
+				switch ext {
+				case ".go", ".gop":
+					ok = true
+				case ".gox", ".spx", ".gmx":
+					ok, class = true, true
+				default:
+					class = mod.IsClass(ext)
+					ok = class
+				}
+				return
+			}
+		} else {
+			fn = func(ext string) (ok bool, class bool) {
+				switch ext {
+				case ".go", ".gop":
+					ok = true
+				case ".gox", ".spx", ".gmx":
+					ok, class = true, true
+				}
+				return
+			}
+		}
+		w.dirMap[dir] = fn
+	}
+	return fn(filepath.Ext(path))
 }
 
 func (w *walker) walk(path string, d fs.DirEntry, err error) error {
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 	} else if d.IsDir() {
-		if !walkSubDir && path != rootDir {
+		if path != rootDir && (!walkSubDir || w.ignore.match(path, true)) {
 			return filepath.SkipDir
 		}
-	} else {
-		dir, _ := filepath.Split(path)
-		fn, ok := w.dirMap[dir]
-		if !ok {
-			if mod, err := gop.LoadMod(path); err == nil {
-				fn = func(ext string) (ok bool, class bool) {
-					switch ext {
-					case ".go", ".gop":
-						ok = true
-					case ".gox", ".spx", ".gmx":
-						ok, class = true, true
-					default:
-						class = mod.IsClass(ext)
-						ok = class
-					}
-					return
-				}
-			} else {
-				fn = func(ext string) (ok bool, class bool) {
-					switch ext {
-					case ".go", ".gop":
-						ok = true
-					case ".gox", ".spx", ".gmx":
-						ok, class = true, true
-					}
-					return
-				}
-			}
-			w.dirMap[dir] = fn
-		}
+	} else if !w.ignore.match(path, false) {
 		ext := filepath.Ext(path)
 		smart := *flagSmart
 		mvgo := smart && *flagMoveGo
-		if ok, class := fn(ext); ok && (!mvgo || ext == ".go") {
-			procCnt++
+		if ok, class := w.kindOf(path); ok && (!mvgo || ext == ".go") {
 			if *flagNotExec {
 				fmt.Println("gop fmt", path)
-			} else {
-				err = gopfmt(path, class, smart && (mvgo || ext != ".go"), mvgo)
+			}
+			w.jobs = append(w.jobs, &job{path, class, smart && (mvgo || ext != ".go"), mvgo})
+		}
+	}
+	return err
+}
+
+// run formats files of jobs in parallel, and returns number of changed files.
+func run(jobs []*job) (changed int) {
+	var (
+		wg    sync.WaitGroup
+		mutex sync.Mutex
+		errs  []error
+		ch    = make(chan *job)
+	)
+	for i, n := 0, runtime.NumCPU(); i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range ch {
+				ok, err := gopfmt(j.path, j.class, j.smart, j.mvgo)
+				mutex.Lock()
+				if ok {
+					changed++
+				}
 				if err != nil {
-					report(err)
+					errs = append(errs, err)
 				}
+				mutex.Unlock()
 			}
+		}()
+	}
+	for _, j := range jobs {
+		ch <- j
+	}
+	close(ch)
+	wg.Wait()
+	if errs != nil {
+		for _, err := range errs {
+			fmt.Println(err)
 		}
+		os.Exit(2)
 	}
-	return err
+	return
 }
 
-func report(err error) {
-	fmt.Println(err)
-	os.Exit(2)
+var printMutex sync.Mutex
+
+// fmtStdin formats source read from stdin, and writes the result to stdout.
+// Errors are written to stderr with exit code 2. With -t, it writes nothing
+// but exits with code 1 if the source isn't formatted. With -l or -d, it
+// writes the name or the diff instead if the source isn't formatted. It is
+// designed for editors to format unsaved buffers without temporary files.
+func fmtStdin() {
+	if *flagWrite {
+		fmt.Fprintln(os.Stderr, "gop fmt: cannot use -w with standard input")
+		os.Exit(2)
+	}
+	src, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	path := *flagSrcPath
+	if path == "" {
+		path = "stdin.gop"
+	}
+	_, class := newWalker("").kindOf(path)
+	smart := *flagSmart && filepath.Ext(path) != ".go"
+	target, err := formatSource(src, path, class, smart, false)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if *flagTest {
+		if !bytes.Equal(src, target) {
+			os.Exit(1)
+		}
+		return
+	}
+	if *flagList || *flagDiff {
+		if *flagList && !bytes.Equal(src, target) {
+			fmt.Println("<standard input>")
+		}
+		if *flagDiff {
+			os.Stdout.Write(diff.Unified("<standard input>.orig", "<standard input>", src, target))
+		}
+		return
+	}
+	os.Stdout.Write(target)
 }
 
 func runCmd(cmd *base.Command, args []string) {
@@ -201,31 +359,46 @@ func runCmd(cmd *base.Command, args []string) {
 	if err != nil {
 		log.Fatalln("parse input arguments failed:", err)
 	}
-	narg := flag.NArg()
-	if narg < 1 {
-		cmd.Usage(os.Stderr)
+	if *flagRewrite != "" && rewrites[*flagRewrite] == nil {
+		log.Fatalln("unknown rewrite:", *flagRewrite)
 	}
-	if *flagTest {
-		defer func() {
-			if testErrCnt > 0 {
-				fmt.Printf("total %d files are not formatted.\n", testErrCnt)
-				os.Exit(1)
-			}
-		}()
+	narg := flag.NArg()
+	if narg == 0 || (narg == 1 && flag.Arg(0) == "-") {
+		fmtStdin()
+		return
 	}
-	walker := newWalker()
+	var total, changed int
+	start := time.Now()
 	for i := 0; i < narg; i++ {
 		path := flag.Arg(i)
 		walkSubDir = strings.HasSuffix(path, "/...")
 		if walkSubDir {
 			path = path[:len(path)-4]
 		}
-		procCnt = 0
 		rootDir = path
+		walker := newWalker(path)
 		filepath.WalkDir(path, walker.walk)
-		if procCnt == 0 {
+		if len(walker.jobs) == 0 {
 			fmt.Println("no Go+ files in", path)
+			continue
 		}
+		total += len(walker.jobs)
+		if !*flagNotExec {
+			changed += run(walker.jobs)
+		}
+	}
+	if *flagNotExec {
+		return
+	}
+	if *flagTest {
+		if changed > 0 {
+			fmt.Printf("total %d files are not formatted.\n", changed)
+			os.Exit(1)
+		}
+		return
+	}
+	if _, write := outputMode(); write && (walkSubDir || total > 1) {
+		fmt.Fprintf(os.Stderr, "gop fmt: %d of %d files changed in %v\n", changed, total, time.Since(start).Round(time.Millisecond))
 	}
 }
 
@@ -0,0 +1,131 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gopfmt
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/goplus/gop/x/pathutil"
+)
+
+// -----------------------------------------------------------------------------
+
+// IgnoreFile is the name of the file which lists patterns of files and
+// directories not to format, one per line, in the root directory to format.
+// A pattern is matched against each name in the path relative to the root
+// directory, or against the whole relative path if it contains a slash. A
+// pattern ending with a slash matches only directories, and `#` starts a
+// comment line. vendor, testdata and hidden directories, and generated files
+// are always ignored.
+const IgnoreFile = ".gopfmtignore"
+
+type ignorePattern struct {
+	pattern  string
+	dirOnly  bool
+	anchored bool
+}
+
+type ignorer struct {
+	root     string
+	patterns []ignorePattern
+}
+
+func loadIgnore(root string) *ignorer {
+	p := &ignorer{root: root}
+	f, err := os.Open(filepath.Join(root, IgnoreFile))
+	if err != nil {
+		return p
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		var pat ignorePattern
+		if strings.HasSuffix(line, "/") {
+			line, pat.dirOnly = strings.TrimRight(line, "/"), true
+		}
+		if strings.HasPrefix(line, "/") {
+			line, pat.anchored = strings.TrimLeft(line, "/"), true
+		}
+		pat.pattern, pat.anchored = line, pat.anchored || strings.Contains(line, "/")
+		p.patterns = append(p.patterns, pat)
+	}
+	return p
+}
+
+// match reports whether the file (or directory if isDir) should be ignored.
+func (p *ignorer) match(file string, isDir bool) bool {
+	name := filepath.Base(file)
+	if isDir && (name == "vendor" || name == "testdata" || (strings.HasPrefix(name, ".") && len(name) > 1 && name != "..")) {
+		return true
+	}
+	if !isDir && strings.HasPrefix(name, "gop_autogen") {
+		return true
+	}
+	if len(p.patterns) == 0 {
+		return false
+	}
+	rel, ok := pathutil.Rel(p.root, file)
+	if !ok {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+	for _, pat := range p.patterns {
+		if pat.dirOnly && !isDir {
+			continue
+		}
+		if pat.anchored {
+			if ok, _ := path.Match(pat.pattern, rel); ok {
+				return true
+			}
+		} else if ok, _ := path.Match(pat.pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// isGenerated reports whether src is generated code, which has a comment
+// like `// Code generated ... DO NOT EDIT.` before the package clause.
+func isGenerated(src []byte) bool {
+	for len(src) > 0 {
+		line := src
+		if i := bytes.IndexByte(src, '\n'); i >= 0 {
+			line, src = src[:i], src[i+1:]
+		} else {
+			src = nil
+		}
+		line = bytes.TrimSpace(line)
+		if bytes.HasPrefix(line, []byte("package ")) {
+			break
+		}
+		if bytes.HasPrefix(line, []byte("// Code generated ")) && bytes.HasSuffix(line, []byte(" DO NOT EDIT.")) {
+			return true
+		}
+	}
+	return false
+}
+
+// -----------------------------------------------------------------------------
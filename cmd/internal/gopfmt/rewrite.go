@@ -0,0 +1,54 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gopfmt
+
+import (
+	"bytes"
+
+	"github.com/goplus/gop/ast"
+	"github.com/goplus/gop/format"
+	"github.com/goplus/gop/token"
+	xformat "github.com/goplus/gop/x/format"
+	"github.com/goplus/gop/x/langserver"
+)
+
+// -----------------------------------------------------------------------------
+
+// rewrites are rewrites which can be applied by `gop fmt -r`.
+var rewrites = map[string]func(file *ast.File, pos token.Pos, typeOf xformat.TypeOf) bool{
+	"comp2loop": xformat.CompToLoop,
+	"loop2comp": xformat.LoopToComp,
+}
+
+// rewriteSource applies the rewrite specified by -r to src of the file path.
+// The file is type-checked with its package, because rewrites need types.
+func rewriteSource(src []byte, path string) ([]byte, error) {
+	f, err := langserver.CheckFile(path, src)
+	if err != nil {
+		return nil, err
+	}
+	if !rewrites[*flagRewrite](f.AST, token.NoPos, f.TypeOf) {
+		return src, nil
+	}
+	var buf bytes.Buffer
+	if err = format.Node(&buf, f.Fset, f.AST); err != nil {
+		return nil, err
+	}
+	return format.KeepFmtOff(src, buf.Bytes()), nil
+}
+
+// -----------------------------------------------------------------------------
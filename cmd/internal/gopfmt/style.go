@@ -0,0 +1,133 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gopfmt
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/goplus/gop/format"
+)
+
+// -----------------------------------------------------------------------------
+
+// StyleFile is the name of the file which configures the formatting style of
+// Go+ files in its directory and subdirectories, so that a team gets the
+// consistent output. It is looked up from the directory of a file to format
+// up to the module root. Each line is a `key = value` pair, and `#` starts a
+// comment line. Supported keys are:
+//
+//	max-width = 100        # wrap long call chains and comprehensions (0 means no limit)
+//	align-comments = false # don't align trailing comments of consecutive lines
+const StyleFile = ".gopfmt"
+
+type styleResult struct {
+	style *format.Style
+	err   error
+}
+
+var (
+	styleMutex sync.Mutex
+	styleCache = make(map[string]styleResult)
+)
+
+// styleOf returns the formatting style of the file path, or nil if no style
+// file is found.
+func styleOf(path string) (*format.Style, error) {
+	dir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+	styleMutex.Lock()
+	defer styleMutex.Unlock()
+	ret := lookupStyle(dir)
+	return ret.style, ret.err
+}
+
+func lookupStyle(dir string) (ret styleResult) {
+	if ret, ok := styleCache[dir]; ok {
+		return ret
+	}
+	defer func() {
+		styleCache[dir] = ret
+	}()
+	file := filepath.Join(dir, StyleFile)
+	if b, err := os.ReadFile(file); err == nil {
+		ret.style, ret.err = parseStyle(file, b)
+		return
+	}
+	if isModRoot(dir) {
+		return
+	}
+	if parent := filepath.Dir(dir); parent != dir {
+		return lookupStyle(parent)
+	}
+	return
+}
+
+func isModRoot(dir string) bool {
+	for _, name := range []string{"gop.mod", "go.mod"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func parseStyle(file string, b []byte) (*format.Style, error) {
+	style := new(format.Style)
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for lno := 1; scanner.Scan(); lno++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		if pos := strings.Index(line, " #"); pos >= 0 {
+			line = strings.TrimSpace(line[:pos])
+		}
+		key, val, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: expected `key = value`", file, lno)
+		}
+		key, val = strings.TrimSpace(key), strings.TrimSpace(val)
+		switch key {
+		case "max-width":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("%s:%d: invalid max-width: %s", file, lno, val)
+			}
+			style.MaxWidth = n
+		case "align-comments":
+			v, err := strconv.ParseBool(val)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: invalid align-comments: %s", file, lno, val)
+			}
+			style.NoAlignComments = !v
+		default:
+			return nil, fmt.Errorf("%s:%d: unknown style: %s", file, lno, key)
+		}
+	}
+	return style, nil
+}
+
+// -----------------------------------------------------------------------------
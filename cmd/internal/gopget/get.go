@@ -17,15 +17,22 @@
 package gopget
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/goplus/gop"
 	"github.com/goplus/gop/cmd/internal/base"
-	"github.com/goplus/mod/modcache"
-	"github.com/goplus/mod/modfetch"
+	"github.com/goplus/gop/x/gocmd"
+	"github.com/goplus/gop/x/gopenv"
 	"github.com/goplus/mod/modload"
+	"golang.org/x/mod/module"
 )
 
 // -----------------------------------------------------------------------------
@@ -33,7 +40,7 @@ import (
 // Cmd - gop get
 var Cmd = &base.Command{
 	UsageLine: "gop get [-v] [packages]",
-	Short:     `Add dependencies to current module and install them`,
+	Short:     `Add dependencies to current module and generate Go code of their Go+ packages`,
 }
 
 var (
@@ -60,23 +67,23 @@ func runCmd(cmd *base.Command, args []string) {
 }
 
 func get(pkgPath string) {
-	modBase := ""
 	mod, err := modload.Load(".")
 	noMod := gop.NotFound(err)
 	if !noMod {
 		check(err)
-		modBase = mod.Path()
 	}
 
-	pkgModVer, _, err := modfetch.GetPkg(pkgPath, modBase)
+	pkgModVer, pkgModRoot, err := download(pkgPath)
 	check(err)
+
+	if hasGopFiles(pkgModRoot) {
+		check(genGo(pkgModRoot))
+		fmt.Fprintf(os.Stderr, "gop get: generated Go code of %s %s\n", pkgModVer.Path, pkgModVer.Version)
+	}
 	if noMod {
 		return
 	}
 
-	pkgModRoot, err := modcache.Path(pkgModVer)
-	check(err)
-
 	pkgMod, err := modload.Load(pkgModRoot)
 	check(err)
 	if pkgMod.HasProject() {
@@ -90,6 +97,96 @@ func get(pkgPath string) {
 	check(mod.Save())
 }
 
+// download downloads the module which contains pkgPath (in the form of
+// pkgPath[@version]) to GOMODCACHE, and returns the module and its directory.
+// It runs “go mod download” rather than “go get”, which loads packages of
+// the module: the go command caches the files of packages in GOMODCACHE when
+// loading them, so it wouldn't see Go code generated later (see genGo).
+func download(pkgPath string) (modVer module.Version, modRoot string, err error) {
+	ver := "latest"
+	if pos := strings.IndexByte(pkgPath, '@'); pos > 0 {
+		pkgPath, ver = pkgPath[:pos], pkgPath[pos+1:]
+	}
+	for modPath := pkgPath; ; {
+		var ret struct {
+			Path, Version, Dir, Error string
+		}
+		var out bytes.Buffer
+		cmd := gocmd.Command("mod", "download", "-json", modPath+"@"+ver)
+		cmd.Stdout = &out
+		e := cmd.Run()
+		if json.Unmarshal(out.Bytes(), &ret) == nil && ret.Error != "" {
+			e = errors.New(ret.Error)
+		}
+		if e == nil {
+			relPath := strings.TrimPrefix(pkgPath[len(modPath):], "/")
+			if _, e = os.Stat(filepath.Join(ret.Dir, relPath)); e != nil {
+				err = fmt.Errorf("gop get: module %s found, but does not contain package %s", ret.Path, pkgPath)
+				return
+			}
+			return module.Version{Path: ret.Path, Version: ret.Version}, ret.Dir, nil
+		}
+		if err == nil {
+			err = e
+		}
+		pos := strings.LastIndexByte(modPath, '/')
+		if pos < 0 {
+			return
+		}
+		modPath = modPath[:pos]
+	}
+}
+
+// hasGopFiles checks if the module at modRoot contains Go+ source files,
+// including classfiles of the classfile frameworks it uses or defines.
+func hasGopFiles(modRoot string) bool {
+	mod, err := gop.LoadMod(modRoot)
+	if err != nil {
+		return false
+	}
+	errFound := errors.New("found")
+	err = filepath.WalkDir(modRoot, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if path != modRoot && (strings.HasPrefix(name, "_") || name == "testdata") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if ext := filepath.Ext(name); ext == ".gop" || ext == ".gox" || mod.IsClass(ext) {
+			return errFound
+		}
+		return nil
+	})
+	return err == errFound
+}
+
+// genGo generates Go code of all Go+ packages of the module at modRoot in the
+// module cache, whose directories are read-only, so that importing them later
+// doesn't need to compile them.
+func genGo(modRoot string) error {
+	var dirs []string
+	filepath.WalkDir(modRoot, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && d.IsDir() {
+			dirs = append(dirs, path)
+		}
+		return err
+	})
+	for _, dir := range dirs {
+		os.Chmod(dir, 0755)
+	}
+	defer func() {
+		for _, dir := range dirs {
+			os.Chmod(dir, 0555)
+		}
+	}()
+	_, _, err := gop.GenGo(modRoot+"/...", &gop.Config{Gop: gopenv.Get()}, false)
+	return err
+}
+
 func check(err error) {
 	if err != nil {
 		log.Fatalln(err)
@@ -26,16 +26,19 @@ import (
 	"github.com/goplus/gop"
 	"github.com/goplus/gop/cl"
 	"github.com/goplus/gop/cmd/internal/base"
+	"github.com/goplus/gop/x/crash"
 	"github.com/goplus/gop/x/gocmd"
 	"github.com/goplus/gop/x/gopenv"
 	"github.com/goplus/gop/x/gopprojs"
+	"github.com/goplus/gop/x/progress"
+	"github.com/goplus/gop/x/stats"
 	"github.com/goplus/gox"
 	"github.com/goplus/mod/modfetch"
 )
 
 // gop install
 var Cmd = &base.Command{
-	UsageLine: "gop install [-debug] [packages]",
+	UsageLine: "gop install [-debug] [build flags] [packages]",
 	Short:     "Build Go+ files and install target to GOBIN",
 }
 
@@ -74,11 +77,16 @@ func runCmd(cmd *base.Command, args []string) {
 
 	gopEnv := gopenv.Get()
 	conf := &gop.Config{Gop: gopEnv}
+	conf.OnCompiled = stats.Hook("install")
+	conf.OnProgress = progress.Hook()
+	conf.OnCrash = crash.Hook(conf)
 	confCmd := &gocmd.Config{Gop: gopEnv}
 	confCmd.Flags = pass.Args
+	confCmd.Run = progress.WrapRun("install", nil)
 	for _, proj := range projs {
 		install(proj, conf, confCmd)
 	}
+	progress.Done(nil)
 }
 
 func install(proj gopprojs.Proj, conf *gop.Config, install *gocmd.InstallConfig) {
@@ -103,6 +111,7 @@ func install(proj gopprojs.Proj, conf *gop.Config, install *gocmd.InstallConfig)
 	} else {
 		return
 	}
+	progress.Done(err)
 	os.Exit(1)
 }
 
@@ -14,28 +14,39 @@
  * limitations under the License.
  */
 
+// Package list implements the “gop list” command.
 package list
 
-/*
 import (
+	"encoding/json"
 	"fmt"
-	"log"
+	"go/types"
+	"os"
+	"reflect"
 
+	"github.com/goplus/gop"
+	"github.com/goplus/gop/ast"
 	"github.com/goplus/gop/cmd/internal/base"
-	"github.com/goplus/gop/x/gopmod"
+	"github.com/goplus/gop/cmd/internal/load"
+	"github.com/goplus/gop/token"
+	"github.com/goplus/gop/x/gopenv"
+	"github.com/goplus/gop/x/gopprojs"
+	"github.com/goplus/gop/x/typesutil"
+	"github.com/goplus/mod/gopmod"
+	"github.com/qiniu/x/log"
 )
 
 // -----------------------------------------------------------------------------
 
 // gop list
 var Cmd = &base.Command{
-	UsageLine: "gop list [-json] [packages]",
-	Short:     "List packages or modules",
+	UsageLine: "gop list [-typeinfo] [packages]",
+	Short:     "List packages or their type information",
 }
 
 var (
-	flag = &Cmd.Flag
-	_    = flag.Bool("json", false, "printing in JSON format.")
+	flag         = &Cmd.Flag
+	flagTypeInfo = flag.Bool("typeinfo", false, "print type information of packages in JSON format")
 )
 
 func init() {
@@ -53,11 +64,52 @@ func runCmd(cmd *base.Command, args []string) {
 		pattern = []string{"."}
 	}
 
-	pkgPaths, err := gopmod.List(pattern...)
+	projs, err := gopprojs.ParseAll(pattern...)
+	check(err)
+	for _, proj := range projs {
+		switch v := proj.(type) {
+		case *gopprojs.DirProj:
+			list(v.Dir)
+		default:
+			log.Fatalln("`gop list` doesn't support", reflect.TypeOf(v))
+		}
+	}
+}
+
+func list(dir string) {
+	fset := token.NewFileSet()
+	mod, pkgs, err := load.Dir(fset, dir)
 	check(err)
-	for _, pkgPath := range pkgPaths {
-		fmt.Println(pkgPath)
+	for _, pkg := range pkgs {
+		if !*flagTypeInfo {
+			fmt.Println(pkg.Path)
+			continue
+		}
+		info, pkgTypes, err := checkPkg(mod, fset, pkg)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		b, err := json.MarshalIndent(info.Export(fset, pkgTypes), "", "  ")
+		check(err)
+		fmt.Printf("%s\n", b)
+	}
+}
+
+func checkPkg(mod *gopmod.Module, fset *token.FileSet, pkg *load.Package) (*typesutil.Info, *types.Package, error) {
+	conf := &types.Config{
+		Importer: gop.NewImporter(mod, gopenv.Get(), fset),
+		Error:    func(err error) {},
+	}
+	pkgTypes := types.NewPackage(pkg.Path, pkg.Name)
+	opts := &typesutil.Config{Types: pkgTypes, Fset: fset, Mod: mod}
+	info := &typesutil.Info{
+		Types:      make(map[ast.Expr]types.TypeAndValue),
+		Defs:       make(map[*ast.Ident]types.Object),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
 	}
+	err := typesutil.NewChecker(conf, opts, nil, info).Files(pkg.GoFiles, pkg.Files)
+	return info, pkgTypes, err
 }
 
 func check(err error) {
@@ -65,6 +117,5 @@ func check(err error) {
 		log.Fatalln(err)
 	}
 }
-*/
 
 // -----------------------------------------------------------------------------
@@ -0,0 +1,94 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package load parses Go+ packages of a directory for commands which type
+// check them, like “gop list -typeinfo” and “gop vet”.
+package load
+
+import (
+	goast "go/ast"
+	"path"
+	"path/filepath"
+	"sort"
+
+	"github.com/goplus/gop"
+	"github.com/goplus/gop/ast"
+	"github.com/goplus/gop/parser"
+	"github.com/goplus/gop/token"
+	"github.com/goplus/gop/x/pathutil"
+	"github.com/goplus/mod/gopmod"
+)
+
+// -----------------------------------------------------------------------------
+
+// A Package is a package parsed from a directory.
+type Package struct {
+	Path    string        // import path of the package
+	Name    string        // package name
+	Files   []*ast.File   // Go+ files, sorted by file name
+	GoFiles []*goast.File // Go files, sorted by file name
+}
+
+// Dir loads the module of dir, and parses packages in dir (with comments)
+// into fset. Packages are sorted by name.
+func Dir(fset *token.FileSet, dir string) (mod *gopmod.Module, pkgs []*Package, err error) {
+	if mod, err = gop.LoadMod(dir); err != nil {
+		return
+	}
+	ret, err := parser.ParseDirEx(fset, dir, parser.Config{
+		ClassKind: mod.ClassKind,
+		Mode:      parser.ParseComments,
+	})
+	if err != nil {
+		return
+	}
+	for _, name := range sortedKeys(ret) {
+		pkg := ret[name]
+		p := &Package{Path: PkgPath(mod, dir, name), Name: name}
+		for _, fname := range sortedKeys(pkg.Files) {
+			p.Files = append(p.Files, pkg.Files[fname])
+		}
+		for _, fname := range sortedKeys(pkg.GoFiles) {
+			p.GoFiles = append(p.GoFiles, pkg.GoFiles[fname])
+		}
+		pkgs = append(pkgs, p)
+	}
+	return
+}
+
+// PkgPath returns the import path of package name in dir of module mod. It is
+// name itself if mod has no go.mod/gop.mod, or dir is out of mod.
+func PkgPath(mod *gopmod.Module, dir, name string) string {
+	if mod.HasModfile() {
+		if absDir, err := filepath.Abs(dir); err == nil {
+			if rel, ok := pathutil.Rel(mod.Root(), absDir); ok {
+				return path.Join(mod.Path(), filepath.ToSlash(rel))
+			}
+		}
+	}
+	return name
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// -----------------------------------------------------------------------------
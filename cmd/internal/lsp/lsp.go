@@ -0,0 +1,62 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package lsp implements the “gop lsp” command.
+package lsp
+
+import (
+	"context"
+
+	"github.com/goplus/gop/cmd/internal/base"
+	"github.com/goplus/gop/x/jsonrpc2"
+	"github.com/goplus/gop/x/jsonrpc2/stdio"
+	"github.com/goplus/gop/x/lsp"
+	"github.com/qiniu/x/log"
+)
+
+// gop lsp
+var Cmd = &base.Command{
+	UsageLine: "gop lsp [flags]",
+	Short:     "Serve the Language Server Protocol over stdio for editors",
+}
+
+var (
+	flag        = &Cmd.Flag
+	flagVerbose = flag.Bool("v", false, "print verbose information")
+)
+
+func init() {
+	Cmd.Run = runCmd
+}
+
+func runCmd(cmd *base.Command, args []string) {
+	err := flag.Parse(args)
+	if err != nil {
+		log.Fatalln("parse input arguments failed:", err)
+	}
+
+	if *flagVerbose {
+		jsonrpc2.SetDebug(jsonrpc2.DbgFlagCall)
+	}
+
+	listener := stdio.Listener(false)
+	defer listener.Close()
+
+	server := lsp.NewServer(context.Background(), listener, nil)
+	server.Wait()
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,277 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package publish implements the “gop publish” command.
+package publish
+
+import (
+	"bytes"
+	"fmt"
+	"go/types"
+	"io/fs"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/goplus/gop"
+	"github.com/goplus/gop/cmd/internal/base"
+	"github.com/goplus/gop/env"
+	"github.com/goplus/gop/token"
+	"github.com/goplus/gop/x/apidiff"
+	"github.com/goplus/gop/x/gocmd"
+	"github.com/goplus/gop/x/gopenv"
+	"github.com/goplus/mod/gopmod"
+)
+
+// gop publish
+var Cmd = &base.Command{
+	UsageLine: "gop publish [-skip-tests -version v] [dir]",
+	Short:     "Verify a Go+ module before tagging a new version",
+}
+
+var (
+	flag          = &Cmd.Flag
+	flagSkipTests = flag.Bool("skip-tests", false, "don't run tests")
+	flagVersion   = flag.String("version", "", "`version` to publish (default is suggested by API changes)")
+)
+
+func init() {
+	Cmd.Run = runCmd
+}
+
+// runCmd verifies a module by steps:
+//  1. the git working tree is clean.
+//  2. regenerated Go code is the same as the committed one.
+//  3. tests pass.
+//  4. API changes since the previous tag are compatible with the version.
+//
+// and then prints the `git tag` command to publish the version.
+func runCmd(cmd *base.Command, args []string) {
+	err := flag.Parse(args)
+	if err != nil {
+		log.Fatalln("parse input arguments failed:", err)
+	}
+	if flag.NArg() > 1 {
+		cmd.Usage(os.Stderr)
+	}
+	dir := "."
+	if flag.NArg() == 1 {
+		dir = flag.Arg(0)
+	}
+	mod, err := gopmod.Load(dir)
+	if err != nil {
+		fatal("load module:", err)
+	}
+	root := mod.Root()
+	if err = os.Chdir(root); err != nil {
+		fatal(err)
+	}
+	fmt.Println("publishing module", mod.Path(), "in", root)
+
+	step("checking git working tree")
+	if changed := gitChanges(); changed != "" {
+		fatal("working tree is not clean, commit or stash changes first:\n" + changed)
+	}
+
+	step("regenerating Go code")
+	if _, _, err = gop.GenGoEx("./...", nil, true, gop.GenFlagPrintError); err != nil {
+		fatal("gop go failed:", err)
+	}
+	if changed := gitChanges(); changed != "" {
+		fatal("generated Go code is not committed or out of date:\n" + changed)
+	}
+
+	if !*flagSkipTests {
+		step("running tests")
+		if err = gocmd.Test("./...", &gocmd.TestConfig{Gop: gopenv.Get()}); err != nil {
+			fatal("tests failed:", err)
+		}
+	}
+
+	prev, _ := git("describe", "--tags", "--abbrev=0")
+	var bump string
+	if prev == "" {
+		step("no previous tag, skip checking API changes")
+	} else {
+		step("checking API changes since " + prev)
+		r, err := changesSince(prev)
+		if err != nil {
+			fatal(err)
+		}
+		r.Fprint(os.Stdout)
+		bump = r.Bump()
+	}
+
+	ver, err := nextVersion(prev, bump, *flagVersion)
+	if err != nil {
+		fatal(err)
+	}
+	step("ready to publish " + ver + ", run:")
+	fmt.Printf("\tgit tag %s && git push origin %s\n", ver, ver)
+}
+
+func step(msg string) {
+	fmt.Println("==>", msg)
+}
+
+func fatal(args ...interface{}) {
+	fmt.Fprintln(os.Stderr, append([]interface{}{"gop publish:"}, args...)...)
+	os.Exit(1)
+}
+
+func git(args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s: %v\n%s", strings.Join(args, " "), err, stderr.String())
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// gitChanges returns uncommitted changes in the working tree.
+func gitChanges() string {
+	changed, err := git("status", "--porcelain")
+	if err != nil {
+		fatal(err)
+	}
+	return changed
+}
+
+// -----------------------------------------------------------------------------
+
+// changesSince reports API changes of all Go+ packages in the module since
+// the tag. The tagged version is checked out into a temporary worktree.
+func changesSince(tag string) (*apidiff.Report, error) {
+	tmp, err := os.MkdirTemp("", "gop-publish-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+	old := filepath.Join(tmp, "old")
+	if _, err = git("worktree", "add", "--detach", old, tag); err != nil {
+		return nil, err
+	}
+	defer git("worktree", "remove", "--force", old)
+
+	fset := token.NewFileSet()
+	olds, err := loadPkgs(fset, old)
+	if err != nil {
+		return nil, err
+	}
+	news, err := loadPkgs(fset, ".")
+	if err != nil {
+		return nil, err
+	}
+	ret := new(apidiff.Report)
+	for _, rel := range sortedKeys(olds) {
+		pkg := news[rel]
+		if pkg == nil {
+			ret.Changes = append(ret.Changes, apidiff.Change{Name: rel, Msg: "package removed"})
+			continue
+		}
+		for _, c := range apidiff.Changes(olds[rel], pkg).Changes {
+			c.Name = rel + ": " + c.Name
+			ret.Changes = append(ret.Changes, c)
+		}
+	}
+	for _, rel := range sortedKeys(news) {
+		if olds[rel] == nil {
+			ret.Changes = append(ret.Changes, apidiff.Change{Name: rel, Msg: "package added", Compatible: true})
+		}
+	}
+	return ret, nil
+}
+
+// loadPkgs loads all Go+ packages (except internal ones) in root, indexed by
+// their relative paths.
+func loadPkgs(fset *token.FileSet, root string) (map[string]*types.Package, error) {
+	pkgs := make(map[string]*types.Package)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return err
+		}
+		name := d.Name()
+		if path != root && (strings.HasPrefix(name, "_") || strings.HasPrefix(name, ".") ||
+			name == "testdata" || name == "internal") {
+			return filepath.SkipDir
+		}
+		out, _, err := gop.LoadDir(path, &gop.Config{Fset: fset}, false)
+		if err != nil {
+			if gop.NotFound(err) {
+				return nil
+			}
+			return err
+		}
+		rel, _ := filepath.Rel(root, path)
+		pkgs[filepath.ToSlash(rel)] = out.Types
+		return nil
+	})
+	return pkgs, err
+}
+
+func sortedKeys(pkgs map[string]*types.Package) []string {
+	keys := make([]string, 0, len(pkgs))
+	for k := range pkgs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// -----------------------------------------------------------------------------
+
+// nextVersion returns the version to publish: ver if specified (which is
+// checked against API changes), or the suggested one.
+func nextVersion(prev, bump, ver string) (string, error) {
+	if prev == "" {
+		if ver == "" {
+			ver = "v0.1.0"
+		}
+		_, err := env.ParseVersion(ver)
+		return ver, err
+	}
+	p, err := env.ParseVersion(prev)
+	if err != nil {
+		return "", fmt.Errorf("invalid previous tag %s: %v", prev, err)
+	}
+	if bump == "major" && p.Major > 0 {
+		return "", fmt.Errorf("incompatible API changes since %s require a new major version with module path suffix /v%d", prev, p.Major+1)
+	}
+	next := env.Ver{Major: p.Major, Minor: p.Minor, Patch: p.Patch + 1}
+	if bump != "patch" && bump != "" { // v0: incompatible changes bump minor version
+		next = env.Ver{Major: p.Major, Minor: p.Minor + 1}
+	}
+	if ver == "" {
+		return next.String(), nil
+	}
+	v, err := env.ParseVersion(ver)
+	if err != nil {
+		return "", fmt.Errorf("invalid version %s: %v", ver, err)
+	}
+	if v.Compare(p) <= 0 {
+		return "", fmt.Errorf("version %s isn't greater than %s", ver, prev)
+	}
+	if v.Major == p.Major && v.Compare(next) < 0 && v.Pre == "" {
+		return "", fmt.Errorf("API changes since %s require version >= %s", prev, next)
+	}
+	return ver, nil
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,193 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package repl implements the “gop repl” command.
+package repl
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/goplus/gop/cmd/internal/base"
+	"github.com/goplus/gop/parser"
+	"github.com/goplus/gop/scanner"
+	"github.com/goplus/gop/token"
+	"github.com/goplus/gop/x/notebook"
+	xerrors "github.com/qiniu/x/errors"
+)
+
+// gop repl
+var Cmd = &base.Command{
+	UsageLine: "gop repl [-dir dir]",
+	Short:     "Start an interactive Go+ shell",
+}
+
+var (
+	flag    = &Cmd.Flag
+	flagDir = flag.String("dir", "", "the working directory to compile and run inputs in, it must be in a Go module to import non-std packages.")
+)
+
+func init() {
+	Cmd.Run = runCmd
+}
+
+func runCmd(cmd *base.Command, args []string) {
+	err := flag.Parse(args)
+	if err != nil {
+		log.Fatalln("parse input arguments failed:", err)
+	}
+	if flag.NArg() != 0 {
+		cmd.Usage(os.Stderr)
+	}
+	if err = Main(*flagDir); err != nil {
+		log.Fatalln(err)
+	}
+}
+
+// Main starts an interactive shell on stdin/stdout.
+func Main(dir string) error {
+	sess, err := notebook.NewSession(&notebook.Config{Dir: dir})
+	if err != nil {
+		return err
+	}
+	defer sess.Close()
+	r := &REPL{sess: sess, out: os.Stdout, errOut: os.Stderr}
+	fmt.Fprintln(r.out, `Go+ repl, type ":help" for help.`)
+	return r.Run(os.Stdin)
+}
+
+// -----------------------------------------------------------------------------
+
+const (
+	prompt     = "gop> "
+	contPrompt = "...  "
+)
+
+const helpText = `Commands:
+  :help     show this help
+  :clear    clear all variables, functions and types defined so far
+  :quit     exit the repl (or press Ctrl-D)
+
+Statements and declarations are kept between inputs. If an input is an
+expression, its value is printed. An input continues over multiple lines
+until all brackets are closed.
+`
+
+// REPL represents an interactive Go+ shell.
+type REPL struct {
+	sess   *notebook.Session
+	out    io.Writer
+	errOut io.Writer
+}
+
+// Run reads inputs from in and evaluates them until EOF or ":quit".
+func (p *REPL) Run(in io.Reader) error {
+	var src strings.Builder
+	scan := bufio.NewScanner(in)
+	for {
+		if src.Len() == 0 {
+			fmt.Fprint(p.out, prompt)
+		} else {
+			fmt.Fprint(p.out, contPrompt)
+		}
+		if !scan.Scan() {
+			fmt.Fprintln(p.out)
+			return scan.Err()
+		}
+		line := scan.Text()
+		if src.Len() == 0 {
+			switch cmd := strings.TrimSpace(line); cmd {
+			case "":
+				continue
+			case ":help", ":h":
+				fmt.Fprint(p.out, helpText)
+				continue
+			case ":clear":
+				p.sess.Reset()
+				continue
+			case ":quit", ":q", ":exit":
+				return nil
+			default:
+				if strings.HasPrefix(cmd, ":") {
+					fmt.Fprintf(p.errOut, "unknown command %s, type \":help\" for help\n", cmd)
+					continue
+				}
+			}
+		}
+		src.WriteString(line)
+		src.WriteByte('\n')
+		if incomplete(src.String()) {
+			continue
+		}
+		p.eval(src.String())
+		src.Reset()
+	}
+}
+
+func (p *REPL) eval(src string) {
+	if _, err := parser.ParseExpr(src); err == nil { // echo value of an expression
+		cell, err := p.sess.Exec("println(" + strings.TrimSpace(src) + ")")
+		if err == nil || errors.Is(err, notebook.ErrRun) {
+			p.report(cell, err)
+			return
+		}
+		// maybe the expression has no value, e.g. a call of a function without results
+	}
+	cell, err := p.sess.Exec(src)
+	p.report(cell, err)
+}
+
+func (p *REPL) report(cell *notebook.Cell, err error) {
+	if cell != nil {
+		fmt.Fprint(p.out, cell.Output)
+	}
+	if err != nil {
+		fmt.Fprintln(p.errOut, xerrors.Summary(err))
+	}
+}
+
+// incomplete reports whether src needs more lines: it has unclosed brackets,
+// raw strings or comments.
+func incomplete(src string) bool {
+	var s scanner.Scanner
+	var unterminated bool
+	fset := token.NewFileSet()
+	file := fset.AddFile("", -1, len(src))
+	s.Init(file, []byte(src), func(pos token.Position, msg string) {
+		if msg == "raw string literal not terminated" || msg == "comment not terminated" {
+			unterminated = true
+		}
+	}, 0)
+	depth := 0
+	for {
+		_, tok, _ := s.Scan()
+		switch tok {
+		case token.LPAREN, token.LBRACE, token.LBRACK:
+			depth++
+		case token.RPAREN, token.RBRACE, token.RBRACK:
+			depth--
+		case token.EOF:
+			return depth > 0 || unterminated
+		}
+	}
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,111 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package run
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"strings"
+	"sync"
+
+	"github.com/goplus/gop/cmd/internal/base"
+	"github.com/goplus/gop/x/gopprojs"
+	"github.com/qiniu/x/log"
+)
+
+// stopProf stops profiling of the compile stage started by startProf. It's a
+// no-op if profiling isn't started or already stopped.
+var stopProf = func() {}
+
+// startProf starts CPU profiling of the compile stage (parser and cl), whose
+// profiles are written into dir as compile-cpu.pprof and compile-mem.pprof
+// when stopProf is called.
+func startProf(dir string) {
+	cpu, err := os.Create(filepath.Join(dir, "compile-cpu.pprof"))
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if err = pprof.StartCPUProfile(cpu); err != nil {
+		log.Fatalln(err)
+	}
+	var once sync.Once
+	stopProf = func() {
+		once.Do(func() {
+			pprof.StopCPUProfile()
+			cpu.Close()
+			mem, err := os.Create(filepath.Join(dir, "compile-mem.pprof"))
+			if err != nil {
+				log.Error(err)
+				return
+			}
+			defer mem.Close()
+			runtime.GC() // get up-to-date statistics
+			if err = pprof.WriteHeapProfile(mem); err != nil {
+				log.Error(err)
+			}
+		})
+	}
+}
+
+// checkProfExec wraps run (cmd.Run if nil) to warn if profiles of the
+// program, profiled into dir by -prof-exec, aren't written. They are written
+// when func main of the program returns or panics, but not if the program
+// exits by os.Exit or log.Fatal (see gop/builtin/prof).
+func checkProfExec(dir string, run func(cmd *exec.Cmd) error) func(cmd *exec.Cmd) error {
+	return func(cmd *exec.Cmd) error {
+		if run == nil {
+			run = (*exec.Cmd).Run
+		}
+		if isGoBuild(cmd) { // a cached program is built before running it
+			return run(cmd)
+		}
+		mem := filepath.Join(dir, "mem.pprof") // written last when profiling stops
+		os.Remove(mem)
+		err := run(cmd)
+		if _, e := os.Stat(mem); e != nil {
+			base.PrintWarning(errors.New("profiles of the program are not written (or truncated), " +
+				"as it exited without returning from main (eg. by os.Exit or log.Fatal)"))
+		}
+		return err
+	}
+}
+
+// isGoBuild reports whether cmd is `go build`.
+func isGoBuild(cmd *exec.Cmd) bool {
+	name := strings.TrimSuffix(filepath.Base(cmd.Args[0]), ".exe")
+	return len(cmd.Args) > 1 && cmd.Args[1] == "build" && strings.HasPrefix(name, "go")
+}
+
+// profDir returns the directory to write profiles into, that is next to the
+// source of proj.
+func profDir(proj gopprojs.Proj) string {
+	dir := "."
+	switch v := proj.(type) {
+	case *gopprojs.DirProj:
+		dir = v.Dir
+	case *gopprojs.FilesProj:
+		dir = filepath.Dir(v.Files[0])
+	}
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	return dir
+}
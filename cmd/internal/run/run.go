@@ -19,34 +19,64 @@ package run
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"os/exec"
+	"path/filepath"
 	"reflect"
+	"strings"
 
 	"github.com/goplus/gop"
 	"github.com/goplus/gop/cl"
 	"github.com/goplus/gop/cmd/internal/base"
+	"github.com/goplus/gop/x/crash"
 	"github.com/goplus/gop/x/gocmd"
 	"github.com/goplus/gop/x/gopenv"
 	"github.com/goplus/gop/x/gopprojs"
+	"github.com/goplus/gop/x/sandbox"
+	"github.com/goplus/gop/x/stats"
 	"github.com/goplus/gox"
 	"github.com/qiniu/x/log"
 )
 
 // gop run
 var Cmd = &base.Command{
-	UsageLine: "gop run [-nc -asm -quiet -debug -prof] package [arguments...]",
+	UsageLine: "gop run [-dir dir -nc -nocache -keep -asm -quiet -debug -strict -explain -lang version -trace-exec -prof -prof-exec -watch -watch-mod -log file -sandbox] package|files|- [--] [arguments...]",
 	Short:     "Run a Go+ program",
 }
 
 var (
-	flag        = &Cmd.Flag
-	flagAsm     = flag.Bool("asm", false, "generates `asm` code of Go+ bytecode backend")
-	flagDebug   = flag.Bool("debug", false, "print debug information")
-	flagQuiet   = flag.Bool("quiet", false, "don't generate any compiling stage log")
-	flagNoChdir = flag.Bool("nc", false, "don't change dir (only for `gop run pkgPath`)")
-	flagProf    = flag.Bool("prof", false, "do profile and generate profile report")
+	flag         = &Cmd.Flag
+	flagAsm      = flag.Bool("asm", false, "dump the SSA form of Go code compiled from Go+ code to stderr")
+	flagDebug    = flag.Bool("debug", false, "print debug information")
+	flagQuiet    = flag.Bool("quiet", false, "don't generate any compiling stage log")
+	flagNoChdir  = flag.Bool("nc", false, "don't change dir (only for `gop run pkgPath`)")
+	flagWorkDir  = flag.String("dir", "", "run the program in `dir` instead of the current directory")
+	flagNoCache  = flag.Bool("nocache", false, "don't reuse the cached executable (only for `gop run files`)")
+	flagKeep     = flag.Bool("keep", false, "generate Go code into the source directory instead of $GOPCACHE (not for `gop run pkgPath`)")
+	flagProf     = flag.Bool("prof", false, "profile the compile stage, writing compile-cpu.pprof and compile-mem.pprof next to the source")
+	flagProfExec = flag.Bool("prof-exec", false, "profile the executed program, writing cpu.pprof and mem.pprof next to the source")
+	flagStrict   = flag.Bool("strict", false, "report compiler warnings as errors")
+	flagLog      = flag.String("log", "", "tee output of the program to `file`")
+	flagExplain  = flag.Bool("explain", false, "print explanations of common errors with examples of how to fix them")
+	flagTrace    = flag.Bool("trace-exec", false, "print each executed source line with values of local variables of basic types")
+	flagLang     = flag.String("lang", "", "language `version` of Go+, eg. gop1.0")
+	flagWatch    = flag.Bool("watch", false, "recompile and restart the program when its source files are changed (not for `gop run pkgPath`)")
+	flagWatchMod = flag.Bool("watch-mod", false, "watch the whole module with -watch, including imported local packages")
+
+	flagCompileTimeout  = flag.Duration("compile-timeout", 0, "limit of time to compile a package")
+	flagCompileMemLimit = flag.Uint64("compile-memlimit", 0, "limit of memory in `MB` to compile a package")
+
+	flagSandbox        = flag.Bool("sandbox", false, "run the program in a sandbox (no network, read-only file system)")
+	flagSandboxRO      = flag.String("sandbox-ro", "/", "comma-separated read-only `paths` in the sandbox")
+	flagSandboxNet     = flag.Bool("sandbox-net", false, "allow network access in the sandbox")
+	flagSandboxCPU     = flag.Duration("sandbox-cpu", 0, "limit of CPU time in the sandbox")
+	flagSandboxMem     = flag.Uint64("sandbox-mem", 0, "limit of memory in `MB` in the sandbox")
+	flagSandboxTimeout = flag.Duration("sandbox-timeout", 0, "limit of wall clock time in the sandbox")
 )
 
+var explainer *base.Explainer
+
 func init() {
 	Cmd.Run = runCmd
 }
@@ -57,14 +87,18 @@ func runCmd(cmd *base.Command, args []string) {
 	if err != nil {
 		log.Fatalln("parse input arguments failed:", err)
 	}
-	if flag.NArg() < 1 {
+	srcArgs, progArgs := cutArgs(flag.Args())
+	if len(srcArgs) < 1 {
 		cmd.Usage(os.Stderr)
 	}
 
-	proj, args, err := gopprojs.ParseOne(flag.Args()...)
-	if err != nil {
+	var proj gopprojs.Proj
+	if srcArgs[0] == "-" { // source is read from stdin
+		proj, args = &gopprojs.FilesProj{Files: srcArgs[:1]}, srcArgs[1:]
+	} else if proj, args, err = gopprojs.ParseOne(srcArgs...); err != nil {
 		log.Fatalln(err)
 	}
+	args = append(args, progArgs...)
 
 	if *flagQuiet {
 		log.SetOutputLevel(0x7000)
@@ -72,25 +106,149 @@ func runCmd(cmd *base.Command, args []string) {
 		gox.SetDebug(gox.DbgFlagAll &^ gox.DbgFlagComments)
 		cl.SetDebug(cl.DbgFlagAll)
 		cl.SetDisableRecover(true)
-	} else if *flagAsm {
-		gox.SetDebug(gox.DbgFlagInstruction)
-	}
-
-	if *flagProf {
-		panic("TODO: profile not impl")
 	}
 
 	noChdir := *flagNoChdir
 	gopEnv := gopenv.Get()
-	conf := &gop.Config{Gop: gopEnv}
+	conf := &gop.Config{Gop: gopEnv, Strict: *flagStrict, OnWarning: base.PrintWarning}
+	conf.OnCompiled = stats.Hook("run")
+	conf.OnCrash = crash.Hook(conf)
+	conf.CompileTimeout, conf.CompileMemLimit = *flagCompileTimeout, *flagCompileMemLimit<<20
+	conf.Lang = *flagLang
+	conf.Trace = *flagTrace
+	if *flagAsm {
+		conf.Backend = asmBackend{}
+	}
+	if !*flagKeep {
+		conf.GenDir = filepath.Join(gocmd.CacheDir(), "gen")
+		conf.AbsFileLine = true // generated code is somewhere else
+	}
 	confCmd := &gocmd.Config{Gop: gopEnv}
 	confCmd.Flags = pass.Args
+	if *flagWorkDir != "" {
+		if confCmd.Dir, err = filepath.Abs(*flagWorkDir); err != nil {
+			log.Fatalln(err)
+		}
+	}
+	if *flagLog != "" {
+		f, err := os.Create(*flagLog)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		defer f.Close()
+		confCmd.Stdout = io.MultiWriter(os.Stdout, f)
+		confCmd.Stderr = io.MultiWriter(os.Stderr, f)
+	}
+	if *flagExplain {
+		explainer = base.NewExplainer(&confCmd.Stderr)
+	}
+	if *flagSandbox {
+		if err = useSandbox(confCmd); err != nil {
+			log.Fatalln(err)
+		}
+	}
+	if *flagProf || *flagProfExec {
+		dir := profDir(proj)
+		if *flagProf {
+			startProf(dir)
+			confCmd.Run = func(cmd *exec.Cmd) error { // compiling Go+ code is done
+				stopProf()
+				return cmd.Run()
+			}
+		}
+		if *flagProfExec {
+			conf.Profile = dir
+			confCmd.Run = checkProfExec(dir, confCmd.Run)
+		}
+	}
+	if *flagWatch {
+		watchAndRun(proj, args, !noChdir, conf, confCmd)
+		return
+	}
 	run(proj, args, !noChdir, conf, confCmd)
 }
 
+// cutArgs splits args at the first `--`: arguments after it are passed to
+// the program as they are, instead of being treated as source files.
+func cutArgs(args []string) (srcArgs, progArgs []string) {
+	for i, arg := range args {
+		if arg == "--" {
+			return args[:i], args[i+1:]
+		}
+	}
+	return args, nil
+}
+
+// useSandbox runs the program by `go run -exec gop`, where gop serves as the
+// sandbox launcher.
+func useSandbox(confCmd *gocmd.Config) error {
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	sb := &sandbox.Config{
+		NoNetwork: !*flagSandboxNet,
+		CPUTime:   *flagSandboxCPU,
+		Memory:    *flagSandboxMem << 20,
+		Timeout:   *flagSandboxTimeout,
+	}
+	if *flagSandboxRO != "" {
+		sb.ReadOnly = strings.Split(*flagSandboxRO, ",")
+	}
+	execCmd, err := quoteExec(self)
+	if err != nil {
+		return err
+	}
+	confCmd.Flags = append(confCmd.Flags, "-exec", execCmd)
+	confCmd.Env = append(confCmd.Env, sb.Environ())
+	return nil
+}
+
+// quoteExec quotes path as the command of `go run -exec`, which is split into
+// words by spaces and can be quoted by single or double quotes (without
+// escapes).
+func quoteExec(path string) (string, error) {
+	if !strings.ContainsAny(path, " \t\n\r'\"") {
+		return path, nil
+	}
+	if !strings.Contains(path, "'") {
+		return "'" + path + "'", nil
+	}
+	if !strings.Contains(path, `"`) {
+		return `"` + path + `"`, nil
+	}
+	return "", fmt.Errorf("can't use %s as the sandbox launcher: it contains both ' and \"", path)
+}
+
+// asmBackend generates Go code like cl.GoBackend, and dumps its SSA form to
+// stderr (see -asm). Note Go code isn't cached if conf.Backend is set, so the
+// SSA form is always dumped.
+type asmBackend struct{}
+
+func (asmBackend) Name() string {
+	return "asm"
+}
+
+func (asmBackend) WriteTo(dst io.Writer, pkg *gox.Package, fname ...string) error {
+	if err := cl.SSABackend.WriteTo(os.Stderr, pkg, fname...); err != nil {
+		return err
+	}
+	return cl.GoBackend.WriteTo(dst, pkg, fname...)
+}
+
+// stdinFile is the file name of source read from stdin, used in positions
+// of errors.
+const stdinFile = "stdin.gop"
+
 func run(proj gopprojs.Proj, args []string, chDir bool, conf *gop.Config, run *gocmd.RunConfig) {
-	var obj string
-	var err error
+	obj, err := runProj(proj, args, chDir, conf, run)
+	stopProf()
+	if reportErr(obj, err) {
+		os.Exit(1)
+	}
+}
+
+func runProj(proj gopprojs.Proj, args []string, chDir bool, conf *gop.Config, run *gocmd.RunConfig) (obj string, err error) {
 	switch v := proj.(type) {
 	case *gopprojs.DirProj:
 		obj = v.Dir
@@ -99,18 +257,32 @@ func run(proj gopprojs.Proj, args []string, chDir bool, conf *gop.Config, run *g
 		obj = v.Path
 		err = gop.RunPkgPath(v.Path, args, chDir, conf, run)
 	case *gopprojs.FilesProj:
+		if !*flagNoCache {
+			run.CacheDir = gocmd.RunCacheDir()
+		}
+		if v.Files[0] == "-" {
+			err = gop.RunSource("", stdinFile, os.Stdin, args, conf, run)
+			break
+		}
 		err = gop.RunFiles("", v.Files, args, conf, run)
 	default:
 		log.Panicln("`gop run` doesn't support", reflect.TypeOf(v))
 	}
+	return
+}
+
+// reportErr prints err of running obj if it isn't nil, and reports whether
+// it is printed.
+func reportErr(obj string, err error) bool {
 	if gop.NotFound(err) {
 		fmt.Fprintf(os.Stderr, "gop run %v: not found\n", obj)
 	} else if err != nil {
-		fmt.Fprintln(os.Stderr, err)
+		base.PrintError(err)
+		explainer.Explain(err)
 	} else {
-		return
+		return false
 	}
-	os.Exit(1)
+	return true
 }
 
 // -----------------------------------------------------------------------------
@@ -0,0 +1,240 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package run
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/goplus/gop"
+	"github.com/goplus/gop/x/fsnotify"
+	"github.com/goplus/gop/x/gocmd"
+	"github.com/goplus/gop/x/gopprojs"
+	"github.com/goplus/mod/gopmod"
+	"github.com/qiniu/x/log"
+)
+
+// debounceDelay is how long to wait for more changes before restarting, as
+// saving files by an editor often makes several changes in a short time.
+const debounceDelay = 300 * time.Millisecond
+
+var errRestart = errors.New("restarting")
+
+// watchAndRun runs the program of proj, and recompiles and restarts it
+// whenever its source files are changed, until gop is interrupted.
+func watchAndRun(proj gopprojs.Proj, args []string, chDir bool, conf *gop.Config, run *gocmd.RunConfig) {
+	root, err := watchRoot(proj)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	mod, err := gop.LoadMod(root)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	changed := make(chan string, 1)
+	w := fsnotify.New()
+	defer w.Close()
+	if err = w.Run(root, &sourceChanged{mod, changed}, ignoreWatch); err != nil {
+		log.Fatalln(err)
+	}
+
+	p := new(procState)
+	run.Run = p.run
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	for {
+		p.reset()
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			obj, err := runProj(proj, args, chDir, conf, run)
+			if !p.restarting() {
+				reportErr(obj, err)
+				log.Println("gop run: waiting for changes to", root)
+			}
+		}()
+	wait:
+		for {
+			select {
+			case name := <-changed:
+				debounce(changed)
+				log.Println("gop run:", name, "changed, restarting")
+				p.stop()
+				<-done
+				break wait
+			case <-sig:
+				p.stop()
+				<-done
+				os.Exit(1)
+			}
+		}
+	}
+}
+
+// watchRoot returns the directory to watch for running proj.
+func watchRoot(proj gopprojs.Proj) (root string, err error) {
+	switch v := proj.(type) {
+	case *gopprojs.DirProj:
+		root = v.Dir
+	case *gopprojs.FilesProj:
+		if v.Files[0] == "-" {
+			return "", errors.New("gop run: can't watch source read from stdin")
+		}
+		root = filepath.Dir(v.Files[0])
+	default:
+		return "", errors.New("gop run: -watch doesn't support `gop run pkgPath`")
+	}
+	if *flagWatchMod {
+		if mod, e := gop.LoadMod(root); e == nil && mod.Root() != "" {
+			root = mod.Root()
+		}
+	}
+	return filepath.Abs(root)
+}
+
+// debounce waits until there are no more changes for debounceDelay.
+func debounce(changed <-chan string) {
+	for {
+		select {
+		case <-changed:
+		case <-time.After(debounceDelay):
+			return
+		}
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+// sourceChanged notifies changes of source files of a module by ch.
+type sourceChanged struct {
+	mod *gopmod.Module
+	ch  chan<- string
+}
+
+func (p *sourceChanged) FileChanged(name string) {
+	p.notify(name)
+}
+
+func (p *sourceChanged) DirAdded(name string) {
+}
+
+func (p *sourceChanged) EntryDeleted(name string, isDir bool) {
+	if !isDir {
+		p.notify(name)
+	}
+}
+
+func (p *sourceChanged) notify(name string) {
+	if p.isSource(name) {
+		select {
+		case p.ch <- name:
+		default: // a change is already pending
+		}
+	}
+}
+
+func (p *sourceChanged) isSource(name string) bool {
+	fname := path.Base(name)
+	if strings.HasPrefix(fname, "gop_autogen") || strings.HasSuffix(fname, "_test.go") {
+		return false
+	}
+	switch ext := path.Ext(fname); ext {
+	case ".gop", ".go", ".gox":
+		return true
+	case ".mod":
+		return fname == "go.mod" || fname == "gop.mod"
+	default:
+		return ext != "" && p.mod.IsClass(ext)
+	}
+}
+
+// ignoreWatch ignores hidden entries and ones starting with `_`, as what the
+// go command does.
+func ignoreWatch(name string, isDir bool) bool {
+	for _, elem := range strings.Split(name, "/") {
+		if elem != "." && elem != "" && (elem[0] == '.' || elem[0] == '_') {
+			return true
+		}
+	}
+	return false
+}
+
+// -----------------------------------------------------------------------------
+
+// procState tracks the running program, so that it can be stopped before
+// restarting.
+type procState struct {
+	mu      sync.Mutex
+	cmd     *exec.Cmd
+	stopped bool
+}
+
+func (p *procState) reset() {
+	p.mu.Lock()
+	p.stopped = false
+	p.mu.Unlock()
+}
+
+func (p *procState) restarting() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.stopped
+}
+
+// run is used as gocmd.Config.Run to start cmd in a new process group, which
+// is killed as a whole by stop (`go run` doesn't pass signals to the program).
+func (p *procState) run(cmd *exec.Cmd) error {
+	stopProf() // compiling Go+ code is done
+	setProcGroup(cmd)
+	p.mu.Lock()
+	if p.stopped { // changed during compiling
+		p.mu.Unlock()
+		return errRestart
+	}
+	err := cmd.Start()
+	if err == nil {
+		p.cmd = cmd
+	}
+	p.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	err = cmd.Wait()
+	p.mu.Lock()
+	p.cmd = nil
+	p.mu.Unlock()
+	return err
+}
+
+func (p *procState) stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stopped = true
+	if p.cmd != nil {
+		killProcGroup(p.cmd)
+	}
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,103 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package stats implements the “gop stats” command.
+package stats
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+
+	"github.com/goplus/gop/cmd/internal/base"
+	"github.com/goplus/gop/x/stats"
+)
+
+// gop stats
+var Cmd = &base.Command{
+	UsageLine: "gop stats [-json -clear]",
+	Short:     "Show local usage statistics (opt-in by GOP_STATS=on)",
+}
+
+var (
+	flag      = &Cmd.Flag
+	flagJSON  = flag.Bool("json", false, "print all records in JSON format")
+	flagClear = flag.Bool("clear", false, "clear usage statistics")
+)
+
+func init() {
+	Cmd.Run = runCmd
+}
+
+func runCmd(cmd *base.Command, args []string) {
+	err := flag.Parse(args)
+	if err != nil {
+		log.Fatalln("parse input arguments failed:", err)
+	}
+	file, ok := stats.File()
+	if !ok {
+		fmt.Fprintf(os.Stderr, "usage statistics are disabled, set %s=on to enable.\n", stats.EnvStats)
+		os.Exit(1)
+	}
+	if *flagClear {
+		if err = os.Remove(file); err != nil && !os.IsNotExist(err) {
+			log.Fatalln(err)
+		}
+		return
+	}
+	recs, err := stats.Load(file)
+	if err != nil && !os.IsNotExist(err) {
+		log.Fatalln(err)
+	}
+	if *flagJSON {
+		enc := json.NewEncoder(os.Stdout)
+		for _, rec := range recs {
+			enc.Encode(rec)
+		}
+		return
+	}
+	sum := stats.Summarize(recs)
+	fmt.Println("file:", file)
+	fmt.Printf("compiles: %d (failed: %d)\n", sum.Count, sum.Failed)
+	if sum.Count == 0 {
+		return
+	}
+	fmt.Printf("time: total %v, p50 %v, p95 %v, max %v\n", sum.Total, sum.P50, sum.P95, sum.Max)
+	printCounts("commands:", sum.Cmds)
+	printCounts("errors:", sum.Errors)
+}
+
+func printCounts(title string, counts map[string]int) {
+	if len(counts) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if ci, cj := counts[keys[i]], counts[keys[j]]; ci != cj {
+			return ci > cj
+		}
+		return keys[i] < keys[j]
+	})
+	fmt.Println(title)
+	for _, key := range keys {
+		fmt.Printf("\t%-16s %d\n", key, counts[key])
+	}
+}
@@ -22,39 +22,61 @@ import (
 	"log"
 	"os"
 	"reflect"
+	"strings"
 
 	"github.com/goplus/gop"
 	"github.com/goplus/gop/cl"
 	"github.com/goplus/gop/cmd/internal/base"
+	"github.com/goplus/gop/x/crash"
+	"github.com/goplus/gop/x/examples"
 	"github.com/goplus/gop/x/gocmd"
 	"github.com/goplus/gop/x/gopenv"
 	"github.com/goplus/gop/x/gopprojs"
+	"github.com/goplus/gop/x/progress"
+	"github.com/goplus/gop/x/stats"
 	"github.com/goplus/gox"
 )
 
 // gop test
 var Cmd = &base.Command{
-	UsageLine: "gop test [-debug] [packages]",
+	UsageLine: "gop test [-debug] [-examples=false] [packages]",
 	Short:     "Test Go+ packages",
 }
 
 var (
-	flag      = &Cmd.Flag
-	flagDebug = flag.Bool("debug", false, "print debug information")
+	flag         = &Cmd.Flag
+	flagDebug    = flag.Bool("debug", false, "print debug information")
+	flagExamples = flag.Bool("examples", true, "verify outputs of examples in doc comments (see `gop tool examples`)")
 )
 
 func init() {
 	Cmd.Run = runCmd
 }
 
+// parseArgs parses flags that may be mixed with packages, as `go test` does,
+// eg. `gop test ./foo -v -run TestBar`.
+func parseArgs(args []string) (pattern []string, err error) {
+	for {
+		if err = flag.Parse(args); err != nil {
+			return
+		}
+		args = flag.Args()
+		for len(args) > 0 && (args[0] == "-" || !strings.HasPrefix(args[0], "-")) { // a lone "-" isn't a flag
+			pattern = append(pattern, args[0])
+			args = args[1:]
+		}
+		if len(args) == 0 {
+			return
+		}
+	}
+}
+
 func runCmd(cmd *base.Command, args []string) {
 	pass := PassTestFlags(cmd)
-	err := flag.Parse(args)
+	pattern, err := parseArgs(args)
 	if err != nil {
 		log.Fatalln("parse input arguments failed:", err)
 	}
-
-	pattern := flag.Args()
 	if len(pattern) == 0 {
 		pattern = []string{"."}
 	}
@@ -72,11 +94,16 @@ func runCmd(cmd *base.Command, args []string) {
 
 	gopEnv := gopenv.Get()
 	conf := &gop.Config{Gop: gopEnv}
+	conf.OnCompiled = stats.Hook("test")
+	conf.OnProgress = progress.Hook()
+	conf.OnCrash = crash.Hook(conf)
 	confCmd := &gocmd.Config{Gop: gopEnv}
 	confCmd.Flags = pass.Args
+	confCmd.Run = progress.WrapRun("test", nil)
 	for _, proj := range projs {
 		test(proj, conf, confCmd)
 	}
+	progress.Done(nil)
 }
 
 func test(proj gopprojs.Proj, conf *gop.Config, test *gocmd.TestConfig) {
@@ -85,7 +112,9 @@ func test(proj gopprojs.Proj, conf *gop.Config, test *gocmd.TestConfig) {
 	switch v := proj.(type) {
 	case *gopprojs.DirProj:
 		obj = v.Dir
-		err = gop.TestDir(obj, conf, test)
+		if err = gop.TestDir(obj, conf, test); err == nil && *flagExamples {
+			err = verifyExamples(obj)
+		}
 	case *gopprojs.PkgPathProj:
 		obj = v.Path
 		err = gop.TestPkgPath("", v.Path, conf, test)
@@ -101,7 +130,32 @@ func test(proj gopprojs.Proj, conf *gop.Config, test *gocmd.TestConfig) {
 	} else {
 		return
 	}
+	progress.Done(err)
 	os.Exit(1)
 }
 
+// verifyExamples runs examples in doc comments of the package in dir, and
+// checks their outputs.
+func verifyExamples(dir string) error {
+	if strings.HasSuffix(dir, "/...") { // TODO: examples of packages in subdirectories
+		return nil
+	}
+	pkg, err := examples.LoadDir(dir)
+	if err != nil {
+		return err
+	}
+	gopCmd, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	run, failed := pkg.Verify(gopCmd, os.Stdout)
+	if failed > 0 {
+		return fmt.Errorf("FAIL\t%s: %d of %d doc examples failed", pkg.Path, failed, run)
+	}
+	if run > 0 {
+		fmt.Printf("ok  \t%s\t%d doc examples\n", pkg.Path, run)
+	}
+	return nil
+}
+
 // -----------------------------------------------------------------------------
@@ -0,0 +1,41 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package test
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseArgs(t *testing.T) {
+	defer func() { *flagDebug = false }()
+	for _, c := range []struct {
+		args, pattern []string
+	}{
+		{[]string{"./foo", "-debug", "./bar"}, []string{"./foo", "./bar"}},
+		{[]string{".", "-"}, []string{".", "-"}},
+		{[]string{"-", "-debug"}, []string{"-"}},
+	} {
+		pattern, err := parseArgs(c.args)
+		if err != nil || !reflect.DeepEqual(pattern, c.pattern) {
+			t.Fatal("parseArgs:", c.args, pattern, err)
+		}
+	}
+	if !*flagDebug {
+		t.Fatal("parseArgs: -debug not parsed")
+	}
+}
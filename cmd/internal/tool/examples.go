@@ -0,0 +1,96 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tool
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/goplus/gop/cmd/internal/base"
+	"github.com/goplus/gop/x/examples"
+	"github.com/goplus/gop/x/gopprojs"
+	"github.com/qiniu/x/log"
+)
+
+// gop tool examples
+var cmdExamples = &base.Command{
+	UsageLine: "gop tool examples [-o dir] [packages]",
+	Short:     "Extract examples in doc comments into standalone .gop files",
+}
+
+var (
+	flagExamples    = &cmdExamples.Flag
+	flagExamplesOut = flagExamples.String("o", "examples", "write examples to `dir`/<package path>/<name>.gop")
+)
+
+func init() {
+	cmdExamples.Run = runExamples
+}
+
+func runExamples(cmd *base.Command, args []string) {
+	err := flagExamples.Parse(args)
+	if err != nil {
+		log.Fatalln("parse input arguments failed:", err)
+	}
+	pattern := flagExamples.Args()
+	if len(pattern) == 0 {
+		pattern = []string{"."}
+	}
+	projs, err := gopprojs.ParseAll(pattern...)
+	check(err)
+	for _, proj := range projs {
+		v, ok := proj.(*gopprojs.DirProj)
+		if !ok {
+			fatal(fmt.Sprint("`gop tool examples` doesn't support ", reflect.TypeOf(proj)))
+		}
+		dir := v.Dir
+		if !strings.HasSuffix(dir, "/...") {
+			extractExamples(dir)
+			continue
+		}
+		dir = dir[:len(dir)-4]
+		err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err == nil && d.IsDir() {
+				if name := d.Name(); path != dir && (strings.HasPrefix(name, "_") || strings.HasPrefix(name, ".") || name == "testdata") {
+					return filepath.SkipDir
+				}
+				extractExamples(path)
+			}
+			return err
+		})
+		check(err)
+	}
+}
+
+// extractExamples writes examples of the package in dir as .gop files.
+func extractExamples(dir string) {
+	pkg, err := examples.LoadDir(dir)
+	check(err)
+	if len(pkg.Examples) == 0 {
+		return
+	}
+	out := filepath.Join(*flagExamplesOut, filepath.FromSlash(pkg.Path))
+	check(os.MkdirAll(out, 0777))
+	for _, ex := range pkg.Examples {
+		check(os.WriteFile(filepath.Join(out, ex.FileName()), ex.Script(pkg.Name, pkg.Path), 0666))
+	}
+	fmt.Fprintf(os.Stderr, "%s: %d examples\n", pkg.Path, len(pkg.Examples))
+}
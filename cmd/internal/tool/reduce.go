@@ -0,0 +1,216 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tool
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/goplus/gop"
+	"github.com/goplus/gop/cl"
+	"github.com/goplus/gop/cmd/internal/base"
+	"github.com/goplus/gop/token"
+	"github.com/goplus/gop/x/gopenv"
+	"github.com/goplus/gop/x/reduce"
+	"github.com/qiniu/x/log"
+)
+
+// gop tool reduce
+var cmdReduce = &base.Command{
+	UsageLine: "gop tool reduce [-panic | -diag regexp | -output regexp | -cmd command] [-o file] [-timeout d] file.gop",
+	Short:     "Shrink a Go+ source file while preserving a failure",
+}
+
+var (
+	flagReduce  = &cmdReduce.Flag
+	flagPanic   = flagReduce.Bool("panic", false, "the compiler panics, with the same message as the original file")
+	flagDiag    = flagReduce.String("diag", "", "compiling the file reports an error matching the regexp")
+	flagOutput  = flagReduce.String("output", "", "output of `gop run` (stdout and stderr) matches the regexp")
+	flagCmd     = flagReduce.String("cmd", "", "the command exits with status 0; the candidate file is appended to its arguments")
+	flagOut     = flagReduce.String("o", "", "write the reduced file to `file` instead of stdout")
+	flagTimeout = flagReduce.Duration("timeout", 0, "stop reducing after the duration, keeping the smallest file found (0 means no limit)")
+	flagVerbose = flagReduce.Bool("v", false, "print progress of reducing")
+)
+
+func init() {
+	cmdReduce.Run = runReduce
+}
+
+func runReduce(cmd *base.Command, args []string) {
+	err := flagReduce.Parse(args)
+	if err != nil {
+		log.Fatalln("parse input arguments failed:", err)
+	}
+	if flagReduce.NArg() != 1 {
+		cmd.Usage(os.Stderr)
+	}
+	n := 0
+	for _, set := range []bool{*flagPanic, *flagDiag != "", *flagOutput != "", *flagCmd != ""} {
+		if set {
+			n++
+		}
+	}
+	if n != 1 {
+		fatal("exactly one of -panic, -diag, -output and -cmd must be specified")
+	}
+	file, err := filepath.Abs(flagReduce.Arg(0))
+	check(err)
+	src, err := os.ReadFile(file)
+	check(err)
+
+	// candidates are written to a hidden directory beside the file, so that
+	// they are in the same module and ignored by package patterns like ./...
+	dir, err := os.MkdirTemp(filepath.Dir(file), ".reduce-")
+	check(err)
+	err = reduceFile(flagReduce.Arg(0), src, filepath.Join(dir, filepath.Base(file)))
+	os.RemoveAll(dir)
+	check(err)
+}
+
+// reduceFile reduces src of file, writing candidates to the path candidate.
+func reduceFile(file string, src []byte, candidate string) error {
+	ctx := context.Background()
+	if *flagTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *flagTimeout)
+		defer cancel()
+	}
+	fails, err := newPredicate(ctx, src, candidate)
+	if err != nil {
+		return err
+	}
+	tests := 0
+	interesting := func(code []byte) bool {
+		if ctx.Err() != nil {
+			return false
+		}
+		tests++
+		if os.WriteFile(candidate, code, 0666) != nil {
+			return false
+		}
+		ok := fails()
+		if ok && *flagVerbose {
+			fmt.Fprintf(os.Stderr, "test %d: %d lines\n", tests, bytes.Count(code, []byte("\n")))
+		}
+		return ok
+	}
+	if !interesting(src) {
+		return fmt.Errorf("%s doesn't fail as specified", file)
+	}
+	ret := reduce.Lines(src, interesting)
+	fmt.Fprintf(os.Stderr, "reduced from %d to %d lines in %d tests\n",
+		bytes.Count(src, []byte("\n")), bytes.Count(ret, []byte("\n")), tests)
+	if *flagOut != "" {
+		return os.WriteFile(*flagOut, ret, 0666)
+	}
+	_, err = os.Stdout.Write(ret)
+	return err
+}
+
+// newPredicate returns a function reporting whether the candidate file fails
+// as specified by flags. src is the original file, which some predicates
+// compare the candidate with.
+func newPredicate(ctx context.Context, src []byte, candidate string) (func() bool, error) {
+	dir := filepath.Dir(candidate)
+	switch {
+	case *flagPanic, *flagDiag != "":
+		compile, err := newCompiler(dir, candidate)
+		if err != nil {
+			return nil, err
+		}
+		if *flagDiag != "" {
+			re, err := regexp.Compile(*flagDiag)
+			if err != nil {
+				return nil, err
+			}
+			return func() bool {
+				err := compile()
+				_, crash := cl.InternalErrorOf(err)
+				return err != nil && !crash && re.MatchString(err.Error())
+			}, nil
+		}
+		if err = os.WriteFile(candidate, src, 0666); err != nil {
+			return nil, err
+		}
+		ie, ok := cl.InternalErrorOf(compile())
+		if !ok {
+			return nil, errors.New("the compiler doesn't panic on the file")
+		}
+		want := fmt.Sprint(ie.Value)
+		return func() bool {
+			ie, ok := cl.InternalErrorOf(compile())
+			return ok && fmt.Sprint(ie.Value) == want
+		}, nil
+	case *flagOutput != "":
+		re, err := regexp.Compile(*flagOutput)
+		if err != nil {
+			return nil, err
+		}
+		gopCmd, err := os.Executable()
+		if err != nil {
+			return nil, err
+		}
+		return func() bool {
+			out, _ := command(ctx, dir, gopCmd, "run", candidate).CombinedOutput()
+			return re.Match(out)
+		}, nil
+	default:
+		args := strings.Fields(*flagCmd)
+		return func() bool {
+			return command(ctx, dir, args[0], append(args[1:], candidate)...).Run() == nil
+		}, nil
+	}
+}
+
+// newCompiler returns a function compiling the file in dir, which shares an
+// importer between calls to save time of loading imported packages.
+func newCompiler(dir, file string) (func() error, error) {
+	mod, err := gop.LoadMod(dir)
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	conf := &gop.Config{Fset: fset, Importer: gop.NewImporter(mod, gopenv.Get(), fset)}
+	return func() error {
+		_, err := gop.LoadFiles(dir, []string{file}, conf)
+		return err
+	}, nil
+}
+
+func command(ctx context.Context, dir, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	return cmd
+}
+
+func check(err error) {
+	if err != nil {
+		fatal(err)
+	}
+}
+
+func fatal(msg interface{}) {
+	fmt.Fprintln(os.Stderr, msg)
+	os.Exit(1)
+}
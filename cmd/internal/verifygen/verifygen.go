@@ -0,0 +1,111 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package verifygen implements the “gop verify-gen” command.
+package verifygen
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/goplus/gop"
+	"github.com/goplus/gop/cmd/internal/base"
+	"github.com/goplus/gop/x/diff"
+	"github.com/goplus/gop/x/gopprojs"
+	"github.com/goplus/mod/gopmod"
+)
+
+// gop verify-gen
+var Cmd = &base.Command{
+	UsageLine: "gop verify-gen [-l] [packages]",
+	Short:     "Verify that generated Go code (gop_autogen.go) is up to date",
+}
+
+var (
+	flag         = &Cmd.Flag
+	flagListOnly = flag.Bool("l", false, "list stale files only, don't print diffs")
+)
+
+func init() {
+	Cmd.Run = runCmd
+}
+
+func runCmd(cmd *base.Command, args []string) {
+	err := flag.Parse(args)
+	if err != nil {
+		log.Panicln("parse input arguments failed:", err)
+	}
+	pattern := flag.Args()
+	if len(pattern) == 0 {
+		pattern = []string{"."}
+	}
+
+	projs, err := gopprojs.ParseAll(pattern...)
+	if err != nil {
+		log.Panicln("gopprojs.ParseAll:", err)
+	}
+
+	var stales []*gop.StaleFile
+	for _, proj := range projs {
+		var ret []*gop.StaleFile
+		switch v := proj.(type) {
+		case *gopprojs.DirProj:
+			ret, err = gop.VerifyGen(v.Dir, nil, true)
+		case *gopprojs.PkgPathProj:
+			ret, err = verifyPkgPath(v.Path)
+		default:
+			log.Panicln("`gop verify-gen` doesn't support", reflect.TypeOf(v))
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			fmt.Fprintln(os.Stderr, "gop verify-gen: failed to generate Go code.")
+			os.Exit(1)
+		}
+		stales = append(stales, ret...)
+	}
+	for _, f := range stales {
+		if *flagListOnly {
+			fmt.Println(f.File)
+			continue
+		}
+		os.Stdout.Write(diff.Unified(f.File+" (committed)", f.File+" (generated)", f.Old, f.New))
+	}
+	if n := len(stales); n > 0 {
+		fmt.Fprintf(os.Stderr, "gop verify-gen: %d generated files are stale, run `gop go` to update them.\n", n)
+		os.Exit(1)
+	}
+}
+
+func verifyPkgPath(pkgPath string) ([]*gop.StaleFile, error) {
+	dir := strings.TrimSuffix(pkgPath, "/...")
+	recursively := dir != pkgPath
+	mod, err := gopmod.Load("")
+	if err != nil {
+		return nil, err
+	}
+	pkg, err := mod.Lookup(dir)
+	if err != nil {
+		return nil, err
+	}
+	dir = pkg.Dir
+	if recursively {
+		dir += "/..."
+	}
+	return gop.VerifyGen(dir, nil, true)
+}
@@ -0,0 +1,315 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package vet implements the “gop vet” command.
+package vet
+
+import (
+	"fmt"
+	goast "go/ast"
+	goparser "go/parser"
+	"go/types"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/goplus/gop"
+	"github.com/goplus/gop/ast"
+	"github.com/goplus/gop/cl"
+	"github.com/goplus/gop/cmd/internal/base"
+	"github.com/goplus/gop/cmd/internal/load"
+	"github.com/goplus/gop/parser"
+	"github.com/goplus/gop/token"
+	"github.com/goplus/gop/x/diag"
+	"github.com/goplus/gop/x/gopenv"
+	"github.com/goplus/gop/x/gopprojs"
+	"github.com/goplus/gop/x/typesutil"
+	"github.com/goplus/gop/x/vet"
+	"github.com/goplus/mod/gopmod"
+	"github.com/qiniu/x/log"
+)
+
+// -----------------------------------------------------------------------------
+
+// gop vet
+var Cmd = &base.Command{
+	UsageLine: "gop vet [-fix] [-<check>[=false]] [packages]",
+	Short:     "Report likely mistakes in Go+ packages",
+}
+
+var (
+	flag    = &Cmd.Flag
+	flagFix = flag.Bool("fix", false, "apply fixes suggested by the compiler (eg. missing return statements) instead of reporting them")
+)
+
+// checkFlag is the value of a -<check> flag. It is unset, true or false.
+type checkFlag struct {
+	set, on bool
+}
+
+func (p *checkFlag) IsBoolFlag() bool { return true }
+
+func (p *checkFlag) String() string {
+	if p == nil || !p.set {
+		return ""
+	}
+	return strconv.FormatBool(p.on)
+}
+
+func (p *checkFlag) Set(s string) error {
+	on, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+	p.set, p.on = true, on
+	return nil
+}
+
+var checkFlags = make(map[*vet.Analyzer]*checkFlag)
+
+func init() {
+	Cmd.Run = runCmd
+	for _, a := range vet.Analyzers {
+		v := new(checkFlag)
+		flag.Var(v, a.Name, "enable the "+a.Name+" check: "+a.Doc)
+		checkFlags[a] = v
+	}
+}
+
+// enabledAnalyzers returns the checks to run. As `go vet`, if any check is
+// explicitly enabled, only the enabled ones run; otherwise all checks run
+// except the explicitly disabled ones.
+func enabledAnalyzers() []*vet.Analyzer {
+	only := false
+	for _, v := range checkFlags {
+		if v.set && v.on {
+			only = true
+		}
+	}
+	var ret []*vet.Analyzer
+	for _, a := range vet.Analyzers {
+		if v := checkFlags[a]; only && v.on || !only && !(v.set && !v.on) {
+			ret = append(ret, a)
+		}
+	}
+	return ret
+}
+
+func runCmd(cmd *base.Command, args []string) {
+	err := flag.Parse(args)
+	if err != nil {
+		log.Fatalln("parse input arguments failed:", err)
+	}
+	pattern := flag.Args()
+	if len(pattern) == 0 {
+		pattern = []string{"."}
+	}
+	projs, err := gopprojs.ParseAll(pattern...)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	analyzers := enabledAnalyzers()
+	failed := false
+	for _, proj := range projs {
+		switch v := proj.(type) {
+		case *gopprojs.DirProj:
+			dir, recursively := v.Dir, false
+			if strings.HasSuffix(dir, "/...") {
+				dir, recursively = dir[:len(dir)-4], true
+			}
+			if recursively {
+				err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+					if err == nil && d.IsDir() {
+						if name := d.Name(); path != dir && (strings.HasPrefix(name, "_") || strings.HasPrefix(name, ".") || name == "testdata") {
+							return filepath.SkipDir
+						}
+						if !vetDir(path, analyzers) {
+							failed = true
+						}
+					}
+					return err
+				})
+				if err != nil {
+					log.Fatalln(err)
+				}
+			} else if !vetDir(dir, analyzers) {
+				failed = true
+			}
+		case *gopprojs.FilesProj:
+			if !vetFiles(v.Files, analyzers) {
+				failed = true
+			}
+		default:
+			log.Fatalln("`gop vet` doesn't support", reflect.TypeOf(v))
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// vetDir vets packages in dir. It reports whether no problem is found.
+func vetDir(dir string, analyzers []*vet.Analyzer) bool {
+	fset := token.NewFileSet()
+	mod, pkgs, err := load.Dir(fset, dir)
+	if err != nil {
+		base.PrintError(err)
+		return false
+	}
+	ok := true
+	for _, pkg := range pkgs {
+		if len(pkg.Files) == 0 { // Go package: nothing to vet
+			continue
+		}
+		if !vetPkg(mod, fset, pkg.Path, pkg.Name, pkg.Files, pkg.GoFiles, analyzers) {
+			ok = false
+		}
+	}
+	return ok
+}
+
+// vetFiles vets the package made of files. It reports whether no problem is
+// found.
+func vetFiles(fnames []string, analyzers []*vet.Analyzer) bool {
+	mod, err := gop.LoadMod(filepath.Dir(fnames[0]))
+	if err != nil {
+		base.PrintError(err)
+		return false
+	}
+	fset := token.NewFileSet()
+	conf := parser.Config{ClassKind: mod.ClassKind, Mode: parser.ParseComments}
+	var files []*ast.File
+	var goFiles []*goast.File
+	for _, fname := range fnames {
+		if filepath.Ext(fname) == ".go" {
+			f, err := goparser.ParseFile(fset, fname, nil, goparser.ParseComments)
+			if err != nil {
+				base.PrintError(err)
+				return false
+			}
+			goFiles = append(goFiles, f)
+			continue
+		}
+		f, err := parser.ParseEntry(fset, fname, nil, conf)
+		if err != nil {
+			base.PrintError(err)
+			return false
+		}
+		files = append(files, f)
+	}
+	if len(files) == 0 {
+		return true
+	}
+	name := files[0].Name.Name
+	return vetPkg(mod, fset, name, name, files, goFiles, analyzers)
+}
+
+func vetPkg(mod *gopmod.Module, fset *token.FileSet, pkgPath, name string, files []*ast.File, goFiles []*goast.File, analyzers []*vet.Analyzer) bool {
+	ok, typeErr := true, false
+	edits := make(map[*token.File][]cl.TextEdit)
+	handled := make(map[diagKey]bool) // fixed, or reported by the printf check
+	var diags []*cl.Diagnostic
+	conf := &types.Config{
+		Importer: gop.NewImporter(mod, gopenv.Get(), fset),
+		Error: func(err error) {
+			if e, isTypeErr := err.(types.Error); isTypeErr {
+				if handled[diagKey{e.Pos, e.Msg}] {
+					return
+				}
+				typeErr = typeErr || !e.Soft // warnings of the compiler are soft errors
+			} else {
+				typeErr = true
+			}
+			base.PrintError(err)
+			ok = false
+		},
+	}
+	opts := &typesutil.Config{Types: types.NewPackage(pkgPath, name), Fset: fset, Mod: mod, Strict: true}
+	opts.OnDiagnostic = func(d *cl.Diagnostic) {
+		diags = append(diags, d)
+		if d.Code == "printf" {
+			handled[diagKey{d.Pos, d.Msg}] = true
+		} else if *flagFix && len(d.Fixes) > 0 {
+			f := fset.File(d.Pos)
+			edits[f] = append(edits[f], d.Fixes[0].Edits...)
+			handled[diagKey{d.Pos, d.Msg}] = true
+		}
+	}
+	info := vet.NewInfo()
+	typesutil.NewChecker(conf, opts, nil, info).Files(goFiles, files)
+	for _, f := range sortedFiles(edits) {
+		if err := fixFile(f, edits[f]); err != nil {
+			base.PrintError(err)
+			ok = false
+		}
+	}
+	if typeErr { // don't vet packages with type errors
+		return false
+	}
+	for _, d := range vet.Run(fset, files, opts.Types, info, diags, analyzers) {
+		base.Diag.Print(os.Stderr, &diag.Diag{Pos: relPos(d.Pos), End: relPos(d.End), Msg: d.Message})
+		ok = false
+	}
+	return ok
+}
+
+type diagKey struct {
+	pos token.Pos
+	msg string
+}
+
+// fixFile applies edits suggested by the compiler to the file f.
+func fixFile(f *token.File, edits []cl.TextEdit) error {
+	file := f.Name()
+	src, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	ret, err := cl.ApplyEdits(f, src, edits)
+	if err != nil {
+		return fmt.Errorf("%s: %v", file, err)
+	}
+	fmt.Println("fix", file)
+	return os.WriteFile(file, ret, 0666)
+}
+
+func sortedFiles(edits map[*token.File][]cl.TextEdit) []*token.File {
+	files := make([]*token.File, 0, len(edits))
+	for f := range edits {
+		files = append(files, f)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name() < files[j].Name()
+	})
+	return files
+}
+
+// relPos returns pos with its file name relative to the working directory if
+// possible.
+func relPos(pos token.Position) token.Position {
+	if wd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(wd, pos.Filename); err == nil && !strings.HasPrefix(rel, "..") {
+			pos.Filename = rel
+		}
+	}
+	return pos
+}
+
+// -----------------------------------------------------------------------------
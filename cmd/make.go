@@ -168,17 +168,14 @@ func execCommand(command string, arg ...string) (string, error) {
 	return stdout.String(), err
 }
 
+// getTagRev returns the commit a tag (annotated or lightweight) points to,
+// or "" if the tag doesn't exist.
 func getTagRev(tag string) string {
-	const commit = "commit "
-	stdout, err := execCommand("git", "show", tag)
-	if err != nil || !strings.HasPrefix(stdout, commit) {
+	stdout, err := execCommand("git", "rev-parse", "--verify", "--quiet", "refs/tags/"+tag+"^{commit}")
+	if err != nil {
 		return ""
 	}
-	data := stdout[len(commit):]
-	if pos := strings.IndexByte(data, '\n'); pos > 0 {
-		return data[:pos]
-	}
-	return ""
+	return trimRight(stdout)
 }
 
 func getGitRemoteUrl(name string) string {
@@ -245,18 +242,35 @@ func getBuildDateTime() string {
 	return now.Format("2006-01-02_15-04-05")
 }
 
+// getBuildVer describes HEAD by the nearest tag reachable from it, eg.
+// v1.2.0-5-g1a2b3c4. Only tags of MainVersion are considered, or gop panics
+// when starting. git finds the tag itself, so it's fast even if there are
+// thousands of tags.
 func getBuildVer() string {
-	latestTagCommit, err := execCommand("git", "rev-list", "--tags", "--max-count=1")
-	if err != nil {
-		return ""
+	mainVer := getMainVersion()
+	stdout, err := execCommand("git", "describe", "--tags", "--match", "v"+mainVer+".*", "HEAD")
+	if err != nil { // no tags of MainVersion, eg. a shallow clone
+		rev, err := execCommand("git", "rev-parse", "--short=12", "HEAD")
+		if err != nil {
+			return ""
+		}
+		println("Warning: no tag of MainVersion", mainVer, "found")
+		return fmt.Sprintf("v%s.x devel %s", mainVer, trimRight(rev))
 	}
+	return fmt.Sprintf("%s devel", trimRight(stdout))
+}
 
-	stdout, err := execCommand("git", "describe", "--tags", trimRight(latestTagCommit))
+// getMainVersion returns MainVersion defined in env/version.go.
+func getMainVersion() string {
+	data, err := os.ReadFile(filepath.Join(gopRoot, "env", "version.go"))
 	if err != nil {
-		return ""
+		log.Fatalln("Error: read MainVersion failed:", err)
 	}
-
-	return fmt.Sprintf("%s devel", trimRight(stdout))
+	m := regexp.MustCompile(`MainVersion = "([^"]+)"`).FindSubmatch(data)
+	if m == nil {
+		log.Fatalln("Error: MainVersion not found in env/version.go")
+	}
+	return string(m[1])
 }
 
 func getGopBuildFlags() string {
@@ -267,8 +281,10 @@ func getGopBuildFlags() string {
 	buildFlags := fmt.Sprintf("-X \"github.com/goplus/gop/env.defaultGopRoot=%s\"", defaultGopRoot)
 	buildFlags += fmt.Sprintf(" -X \"github.com/goplus/gop/env.buildDate=%s\"", getBuildDateTime())
 
-	version := findGopVersion()
-	buildFlags += fmt.Sprintf(" -X \"github.com/goplus/gop/env.buildVersion=%s\"", version)
+	// If version is unknown, gop takes it from the module information.
+	if version := findGopVersion(); version != "" {
+		buildFlags += fmt.Sprintf(" -X \"github.com/goplus/gop/env.buildVersion=%s\"", version)
+	}
 
 	return buildFlags
 }
@@ -353,7 +369,11 @@ func buildGoplusTools(useGoProxy bool) {
 
 	println("Building Go+ tools...\n")
 	os.Chdir(commandsDir)
-	buildOutput, err := execCommand("go", "build", "-o", gopBinPath, "-v", "-ldflags", buildFlags, "./...")
+	args := []string{"build", "-o", gopBinPath, "-v", "-ldflags", buildFlags}
+	if !isGitRepo() { // go build fails to stamp VCS information if .git exists but git doesn't
+		args = append(args, "-buildvcs=false")
+	}
+	buildOutput, err := execCommand("go", append(args, "./...")...)
 	if err != nil {
 		log.Fatalln(err)
 	}
@@ -484,12 +504,16 @@ func findGopVersion() string {
 	data, err := os.ReadFile(versionFile)
 	if err == nil {
 		version := trimRight(string(data))
+		if mainVer := getMainVersion(); !strings.HasPrefix(version, "v"+mainVer+".") {
+			log.Fatalf("Error: version %s in VERSION file doesn't match MainVersion %s\n", version, mainVer)
+		}
 		return version
 	}
 
 	// Read version from git repo
 	if !isGitRepo() {
-		log.Fatal("Error: must be a git repo or a VERSION file existed.")
+		println("Warning: neither git metadata nor a VERSION file is available, version of gop is unknown.")
+		return ""
 	}
 	version := getBuildVer() // Closet tag on git log
 	return version
@@ -506,11 +530,15 @@ func releaseNewVersion(tag string) {
 	}
 
 	version := tag
-	re := regexp.MustCompile(`^v\d+?\.\d+?`)
-	releaseBranch := re.FindString(version)
-	if releaseBranch == "" {
+	re := regexp.MustCompile(`^(v\d+\.\d+)\.\d+(-[0-9A-Za-z.]+)?$`)
+	m := re.FindStringSubmatch(version)
+	if m == nil {
 		log.Fatal("Error: A valid version should be has form: vX.Y.Z")
 	}
+	releaseBranch := m[1]
+	if mainVer := getMainVersion(); releaseBranch != "v"+mainVer {
+		println("Warning: version", version, "doesn't match MainVersion", mainVer, "in env/version.go")
+	}
 
 	sourceBranch := getGitBranch()
 
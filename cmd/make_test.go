@@ -296,13 +296,17 @@ func TestInstallInNonGitRepo(t *testing.T) {
 		return exec.Command("go", "run", installer, "--install")
 	}
 
-	t.Run("failed build operation", func(t *testing.T) {
+	t.Run("install without VERSION file", func(t *testing.T) {
 		os.Remove(versionFile)
 		cmd := installCmd()
+		if output, err := cmd.CombinedOutput(); err != nil || !strings.Contains(string(output), "Warning") {
+			t.Fatalf("Failed: %v, output: %s\n", err, output)
+		}
+
+		cmd = exec.Command(filepath.Join(gopRoot, "bin", gopBinFiles[0]), "version")
 		output, err := cmd.CombinedOutput()
-		if err == nil || !strings.Contains(string(output), "Error") {
-			t.Log(string(output))
-			t.Fatal("Failed: build Go+ in a non-git repo and no VERSION file should be failed.")
+		if err != nil || !strings.Contains(string(output), "gop v1.") {
+			t.Fatalf("Failed: %v, output: %s\n", err, output)
 		}
 	})
 
@@ -22,7 +22,12 @@ var (
 	buildDate string
 )
 
-// BuildDate returns build date of the `gop` command.
+// BuildDate returns build date of the `gop` command. If it isn't set by
+// the linker, it is the commit time recorded by the go command, if any.
 func BuildDate() string {
+	if buildDate == "" {
+		_, time := buildInfo()
+		return time
+	}
 	return buildDate
 }
@@ -0,0 +1,123 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package env
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// -----------------------------------------------------------------------------
+
+// EnvExperiment is the environment variable to enable (or disable)
+// in-development behaviors of Go+ at build or run time, like GOEXPERIMENT of
+// Go. It is a comma-separated list of experiment names: a name enables the
+// experiment, a name with prefix "no" disables it, and "none" disables all
+// experiments enabled so far (including ones enabled by default):
+//
+//	GOPEXPERIMENT=pipeline gop run .
+const EnvExperiment = "GOPEXPERIMENT"
+
+// An Experiment is an in-development behavior of Go+ which can be enabled by
+// GOPEXPERIMENT.
+type Experiment struct {
+	Name    string
+	Doc     string
+	Default bool // enabled if GOPEXPERIMENT doesn't disable it
+}
+
+var (
+	expMutex    sync.RWMutex
+	experiments = make(map[string]*Experiment)
+)
+
+// RegisterExperiment registers an experiment. It is called by packages
+// implementing the experiment in their init functions.
+func RegisterExperiment(exp *Experiment) {
+	expMutex.Lock()
+	experiments[exp.Name] = exp
+	expMutex.Unlock()
+}
+
+// Experiments returns all registered experiments sorted by names.
+func Experiments() []*Experiment {
+	expMutex.RLock()
+	exps := make([]*Experiment, 0, len(experiments))
+	for _, exp := range experiments {
+		exps = append(exps, exp)
+	}
+	expMutex.RUnlock()
+	sort.Slice(exps, func(i, j int) bool {
+		return exps[i].Name < exps[j].Name
+	})
+	return exps
+}
+
+// ParseExperiment parses a GOPEXPERIMENT value and returns the enabled
+// experiments sorted by names. It returns an error if s refers to an unknown
+// experiment.
+func ParseExperiment(s string) (enabled []string, err error) {
+	on := make(map[string]bool)
+	exps := Experiments()
+	for _, exp := range exps {
+		on[exp.Name] = exp.Default
+	}
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		switch {
+		case name == "":
+			continue
+		case name == "none":
+			for k := range on {
+				on[k] = false
+			}
+			continue
+		}
+		val := true
+		if _, ok := on[name]; !ok && strings.HasPrefix(name, "no") {
+			name, val = name[2:], false
+		}
+		if _, ok := on[name]; !ok {
+			return nil, fmt.Errorf("unknown %s %s", EnvExperiment, name)
+		}
+		on[name] = val
+	}
+	for _, exp := range exps {
+		if on[exp.Name] {
+			enabled = append(enabled, exp.Name)
+		}
+	}
+	return
+}
+
+// ExperimentEnabled checks if the experiment name is enabled by
+// GOPEXPERIMENT (or by default). An invalid GOPEXPERIMENT enables nothing;
+// gop commands report it before running.
+func ExperimentEnabled(name string) bool {
+	enabled, _ := ParseExperiment(os.Getenv(EnvExperiment))
+	for _, v := range enabled {
+		if v == name {
+			return true
+		}
+	}
+	return false
+}
+
+// -----------------------------------------------------------------------------
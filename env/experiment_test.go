@@ -0,0 +1,70 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package env
+
+import (
+	"reflect"
+	"testing"
+)
+
+func init() {
+	RegisterExperiment(&Experiment{Name: "foo", Doc: "test experiment foo"})
+	RegisterExperiment(&Experiment{Name: "bar", Doc: "test experiment bar", Default: true})
+	RegisterExperiment(&Experiment{Name: "note", Doc: "test experiment note"})
+}
+
+func TestParseExperiment(t *testing.T) {
+	cases := []struct {
+		val     string
+		enabled []string
+		err     string
+	}{
+		{"", []string{"bar"}, ""},
+		{"foo", []string{"bar", "foo"}, ""},
+		{"foo,nobar", []string{"foo"}, ""},
+		{"none,foo", []string{"foo"}, ""},
+		{" note , foo ", []string{"bar", "foo", "note"}, ""},
+		{"baz", nil, "unknown GOPEXPERIMENT baz"},
+		{"nobaz", nil, "unknown GOPEXPERIMENT baz"},
+	}
+	for _, c := range cases {
+		enabled, err := ParseExperiment(c.val)
+		if err != nil {
+			if err.Error() != c.err {
+				t.Fatal("ParseExperiment:", c.val, err)
+			}
+			continue
+		}
+		if c.err != "" || !reflect.DeepEqual(enabled, c.enabled) {
+			t.Fatal("ParseExperiment:", c.val, enabled, err)
+		}
+	}
+}
+
+func TestExperimentEnabled(t *testing.T) {
+	t.Setenv(EnvExperiment, "foo")
+	if !ExperimentEnabled("foo") || !ExperimentEnabled("bar") || ExperimentEnabled("note") {
+		t.Fatal("ExperimentEnabled: foo")
+	}
+	t.Setenv(EnvExperiment, "foo,baz")
+	if ExperimentEnabled("foo") || ExperimentEnabled("bar") {
+		t.Fatal("ExperimentEnabled: invalid GOPEXPERIMENT")
+	}
+	if exps := Experiments(); len(exps) != 3 || exps[0].Name != "bar" || exps[2].Name != "note" {
+		t.Fatal("Experiments:", exps)
+	}
+}
@@ -21,6 +21,8 @@ import (
 	"os"
 	"path/filepath"
 	"syscall"
+
+	"github.com/goplus/gop/x/pathutil"
 )
 
 var (
@@ -89,10 +91,14 @@ var executable = func() (string, error) {
 func executableRealPath() (path string, err error) {
 	path, err = executable()
 	if err == nil {
-		path, err = filepath.EvalSymlinks(path)
-		if err == nil {
-			path, err = filepath.Abs(path)
+		real, e := filepath.EvalSymlinks(path)
+		if e != nil { // EvalSymlinks may fail on Windows junctions, eg. of network drives
+			if _, err = os.Stat(path); err != nil {
+				return
+			}
+			real = path
 		}
+		path, err = pathutil.Abs(real)
 	}
 	return
 }
@@ -0,0 +1,198 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package env
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Ver represents a semantic version of the GoPlus tree, eg. v1.2.0-rc1 or
+// v1.2.3-12-gabcdef0 (output of `git describe`).
+type Ver struct {
+	Major, Minor, Patch int
+
+	Pre     string // prerelease, eg. "rc1" of v1.2.0-rc1
+	Commits int    // number of commits since the tag, eg. 12 of v1.2.3-12-gabcdef0
+	Hash    string // abbreviated commit hash, eg. "abcdef0" of v1.2.3-12-gabcdef0
+	Meta    string // build metadata and suffix, eg. "devel" of "v1.2.3 devel" (ignored in comparing)
+}
+
+// ErrInvalidVersion is returned by ParseVersion if a version is invalid.
+var ErrInvalidVersion = errors.New("invalid version")
+
+var gitDescribe = regexp.MustCompile(`^(.+)-(\d+)-g([0-9a-f]+)$`)
+
+// ParseVersion parses a version in form of [v]X.Y[.Z][-pre][+meta][ suffix].
+// Z can be "x" (eg. v1.2.x, see Version), which is treated as 0.
+func ParseVersion(s string) (v Ver, err error) {
+	s = strings.TrimSpace(s)
+	if pos := strings.IndexAny(s, " \t"); pos >= 0 {
+		s, v.Meta = s[:pos], strings.TrimSpace(s[pos+1:])
+	}
+	if pos := strings.IndexByte(s, '+'); pos >= 0 {
+		meta := s[pos+1:]
+		if v.Meta != "" {
+			meta += " " + v.Meta
+		}
+		s, v.Meta = s[:pos], meta
+	}
+	if m := gitDescribe.FindStringSubmatch(s); m != nil {
+		s, v.Hash = m[1], m[3]
+		v.Commits, _ = strconv.Atoi(m[2])
+	}
+	core := strings.TrimPrefix(s, "v")
+	if pos := strings.IndexByte(core, '-'); pos >= 0 {
+		core, v.Pre = core[:pos], core[pos+1:]
+		if v.Pre == "" {
+			return Ver{}, ErrInvalidVersion
+		}
+	}
+	parts := strings.Split(core, ".")
+	if len(parts) < 2 || len(parts) > 3 {
+		return Ver{}, ErrInvalidVersion
+	}
+	nums := [3]*int{&v.Major, &v.Minor, &v.Patch}
+	for i, part := range parts {
+		if i == 2 && part == "x" {
+			continue
+		}
+		n, e := strconv.ParseUint(part, 10, 31)
+		if e != nil || (len(part) > 1 && part[0] == '0') {
+			return Ver{}, ErrInvalidVersion
+		}
+		*nums[i] = int(n)
+	}
+	return
+}
+
+// String returns the canonical form of the version (without Meta).
+func (v Ver) String() string {
+	var b strings.Builder
+	b.WriteByte('v')
+	b.WriteString(strconv.Itoa(v.Major))
+	b.WriteByte('.')
+	b.WriteString(strconv.Itoa(v.Minor))
+	b.WriteByte('.')
+	b.WriteString(strconv.Itoa(v.Patch))
+	if v.Pre != "" {
+		b.WriteByte('-')
+		b.WriteString(v.Pre)
+	}
+	if v.Hash != "" {
+		b.WriteByte('-')
+		b.WriteString(strconv.Itoa(v.Commits))
+		b.WriteString("-g")
+		b.WriteString(v.Hash)
+	}
+	return b.String()
+}
+
+// Compare returns -1, 0 or 1 if v is less than, equal to or greater than w.
+// Versions are compared by semantic versioning rules, and then by Commits.
+func (v Ver) Compare(w Ver) int {
+	if c := compareInt(v.Major, w.Major); c != 0 {
+		return c
+	}
+	if c := compareInt(v.Minor, w.Minor); c != 0 {
+		return c
+	}
+	if c := compareInt(v.Patch, w.Patch); c != 0 {
+		return c
+	}
+	if c := comparePre(v.Pre, w.Pre); c != 0 {
+		return c
+	}
+	return compareInt(v.Commits, w.Commits)
+}
+
+// AtLeast reports whether v is greater than or equal to ver.
+// It panics if ver is invalid.
+func (v Ver) AtLeast(ver string) bool {
+	w, err := ParseVersion(ver)
+	if err != nil {
+		panic("gop/env: " + err.Error() + ": " + ver)
+	}
+	return v.Compare(w) >= 0
+}
+
+// Satisfies reports whether v satisfies the gop directive ver (eg. `gop 1.2`)
+// in gop.mod. Like the go command does for go lines, a major.minor directive
+// is satisfied by any version of that line, including prereleases and dev
+// builds (eg. v1.2.0-pre.1), while other directives are compared by AtLeast.
+// It panics if ver is invalid.
+func (v Ver) Satisfies(ver string) bool {
+	w, err := ParseVersion(ver)
+	if err != nil {
+		panic("gop/env: " + err.Error() + ": " + ver)
+	}
+	if w.Pre == "" && w.Hash == "" && strings.Count(ver, ".") == 1 {
+		if v.Major != w.Major {
+			return v.Major > w.Major
+		}
+		return v.Minor >= w.Minor
+	}
+	return v.Compare(w) >= 0
+}
+
+func compareInt(a, b int) int {
+	if a < b {
+		return -1
+	} else if a > b {
+		return 1
+	}
+	return 0
+}
+
+// comparePre compares prereleases: a version without prerelease is greater,
+// and dot-separated identifiers are compared numerically if both numeric, or
+// lexically otherwise (numeric ones are lower).
+func comparePre(a, b string) int {
+	if a == b {
+		return 0
+	} else if a == "" {
+		return 1
+	} else if b == "" {
+		return -1
+	}
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		x, ex := strconv.ParseUint(as[i], 10, 64)
+		y, ey := strconv.ParseUint(bs[i], 10, 64)
+		switch {
+		case ex == nil && ey == nil:
+			if x != y {
+				if x < y {
+					return -1
+				}
+				return 1
+			}
+		case ex == nil:
+			return -1
+		case ey == nil:
+			return 1
+		case as[i] != bs[i]:
+			if as[i] < bs[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return compareInt(len(as), len(bs))
+}
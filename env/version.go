@@ -21,7 +21,9 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime/debug"
 	"strings"
+	"sync"
 )
 
 const (
@@ -43,11 +45,20 @@ func initEnv() {
 		initEnvByGop()
 		return
 	}
-	if !strings.HasPrefix(buildVersion, "v"+MainVersion+".") {
+	if err := checkVersion(buildVersion); err != nil {
 		panic("gop/env: [FATAL] Invalid buildVersion: " + buildVersion)
 	}
 }
 
+// checkVersion checks if a build version is a valid version of MainVersion.
+func checkVersion(ver string) error {
+	if !strings.HasPrefix(ver, "v"+MainVersion+".") {
+		return ErrInvalidVersion
+	}
+	_, err := ParseVersion(ver)
+	return err
+}
+
 func initEnvByGop() {
 	if fname := filepath.Base(os.Args[0]); !isGopCmd(fname) {
 		if ret, err := gopEnv(); err == nil {
@@ -76,9 +87,90 @@ func Installed() bool {
 // Version returns the GoPlus tree's version string.
 // It is either the commit hash and date at the time of the build or,
 // when possible, a release tag like "v1.0.0-rc1".
+//
+// If it isn't set by the linker (eg. gop is built from a tarball without
+// git, or by `go install`), it is the module version of gop, or the VCS
+// revision recorded by the go command.
 func Version() string {
 	if buildVersion == "" {
+		if ver, _ := buildInfo(); ver != "" {
+			return ver
+		}
 		return "v" + MainVersion + ".x"
 	}
 	return buildVersion
 }
+
+const gopModPath = "github.com/goplus/gop"
+
+var (
+	buildInfoOnce         sync.Once
+	buildInfoVer, vcsTime string
+	readBuildInfo         = debug.ReadBuildInfo
+)
+
+// buildInfo returns version and VCS time of gop recorded by the go command.
+func buildInfo() (ver, time string) {
+	buildInfoOnce.Do(func() {
+		if bi, ok := readBuildInfo(); ok {
+			buildInfoVer, vcsTime = versionOf(bi)
+		}
+	})
+	return buildInfoVer, vcsTime
+}
+
+func versionOf(bi *debug.BuildInfo) (ver, time string) {
+	mod := &bi.Main
+	if mod.Path != gopModPath { // gop is a dependency
+		mod = nil
+		for _, dep := range bi.Deps {
+			if dep.Path == gopModPath {
+				mod = dep
+				if dep.Replace != nil {
+					mod = dep.Replace
+				}
+				break
+			}
+		}
+		if mod == nil {
+			return
+		}
+	}
+	if v := mod.Version; v != "" && v != "(devel)" && checkVersion(v) == nil {
+		ver = v
+	}
+	if mod != &bi.Main { // VCS information is about the main module
+		return
+	}
+	var rev, modified string
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			rev = s.Value
+		case "vcs.time":
+			time = s.Value
+		case "vcs.modified":
+			modified = s.Value
+		}
+	}
+	if ver == "" && rev != "" {
+		if len(rev) > 12 {
+			rev = rev[:12]
+		}
+		ver = "v" + MainVersion + ".x devel " + rev
+		if modified == "true" {
+			ver += "-dirty"
+		}
+	}
+	return
+}
+
+// SemVersion returns the GoPlus tree's version as a semantic version, so it
+// can be compared, eg. env.SemVersion().AtLeast("1.2").
+func SemVersion() Ver {
+	v, err := ParseVersion(Version())
+	if err != nil { // buildVersion set by `gop env` is invalid
+		v, _ = ParseVersion("v" + MainVersion + ".x")
+	}
+	return v
+}
@@ -19,6 +19,7 @@ package env
 import (
 	"os"
 	"path/filepath"
+	"runtime/debug"
 	"testing"
 )
 
@@ -66,3 +67,142 @@ func TestEnv(t *testing.T) {
 		t.Fatal("BuildInfo failed:", BuildDate())
 	}
 }
+
+func TestParseVersion(t *testing.T) {
+	cases := []struct {
+		ver  string
+		want Ver
+		str  string
+	}{
+		{"v1.2.3", Ver{Major: 1, Minor: 2, Patch: 3}, "v1.2.3"},
+		{"1.2", Ver{Major: 1, Minor: 2}, "v1.2.0"},
+		{"v1.2.x", Ver{Major: 1, Minor: 2}, "v1.2.0"},
+		{"v1.0.0-beta1", Ver{Major: 1, Pre: "beta1"}, "v1.0.0-beta1"},
+		{"v1.2.0-rc.1+build.5", Ver{Major: 1, Minor: 2, Pre: "rc.1", Meta: "build.5"}, "v1.2.0-rc.1"},
+		{"v1.2.3-12-gabcdef0 devel", Ver{Major: 1, Minor: 2, Patch: 3, Commits: 12, Hash: "abcdef0", Meta: "devel"}, "v1.2.3-12-gabcdef0"},
+		{"v1.2.0-rc1-3-g1234567", Ver{Major: 1, Minor: 2, Pre: "rc1", Commits: 3, Hash: "1234567"}, "v1.2.0-rc1-3-g1234567"},
+	}
+	for _, c := range cases {
+		v, err := ParseVersion(c.ver)
+		if err != nil || v != c.want || v.String() != c.str {
+			t.Fatal("ParseVersion:", c.ver, v, err)
+		}
+	}
+	for _, ver := range []string{"", "v1", "v1.2.3.4", "va.b", "v1.02.3", "v1.2.3-", "v1.-2.3", "v1.x"} {
+		if _, err := ParseVersion(ver); err != ErrInvalidVersion {
+			t.Fatal("ParseVersion:", ver, err)
+		}
+	}
+}
+
+func TestCompareVersion(t *testing.T) {
+	// in ascending order
+	vers := []string{
+		"v1.0.0-alpha", "v1.0.0-alpha.1", "v1.0.0-alpha.beta", "v1.0.0-beta",
+		"v1.0.0-beta.2", "v1.0.0-beta.11", "v1.0.0-rc.1", "v1.0.0", "v1.0.0-3-gabcdef0",
+		"v1.2.0-rc1", "v1.2.0", "v1.2.1", "v1.10.0", "v2.0.0",
+	}
+	for i, a := range vers {
+		va, _ := ParseVersion(a)
+		for j, b := range vers {
+			vb, _ := ParseVersion(b)
+			want := compareInt(i, j)
+			if c := va.Compare(vb); c != want {
+				t.Fatal("Compare:", a, b, c)
+			}
+		}
+	}
+	v, _ := ParseVersion("v1.2.0 devel")
+	if !v.AtLeast("1.2") || !v.AtLeast("v1.2.0-rc1") || v.AtLeast("1.2.1") {
+		t.Fatal("AtLeast failed")
+	}
+	defer func() {
+		if e := recover(); e == nil {
+			t.Fatal("AtLeast: no panic?")
+		}
+	}()
+	v.AtLeast("1")
+}
+
+func TestSatisfies(t *testing.T) {
+	cases := []struct {
+		ver, directive string
+		ok             bool
+	}{
+		{"v1.2.0", "1.2", true},
+		{"v1.2.0-pre.1", "1.2", true},
+		{"v1.2.0-rc1-3-gabcdef0", "1.2", true},
+		{"v1.2.3 devel", "1.1", true},
+		{"v2.0.0-pre.1", "1.3", true},
+		{"v1.1.9", "1.2", false},
+		{"v1.2.0-pre.1", "1.3", false},
+		{"v1.2.0-pre.1", "1.2.0", false},
+		{"v1.2.0-pre.1", "1.2.0-pre.1", true},
+		{"v1.2.1-rc1", "1.2.1", false},
+		{"v1.2.1", "1.2.1", true},
+	}
+	for _, c := range cases {
+		v, _ := ParseVersion(c.ver)
+		if ok := v.Satisfies(c.directive); ok != c.ok {
+			t.Fatal("Satisfies:", c.ver, c.directive, ok)
+		}
+	}
+	defer func() {
+		if e := recover(); e == nil {
+			t.Fatal("Satisfies: no panic?")
+		}
+	}()
+	Ver{}.Satisfies("1")
+}
+
+func TestMainVersion(t *testing.T) {
+	v, err := ParseVersion(MainVersion)
+	if err != nil || v.String() != "v"+MainVersion+".0" {
+		t.Fatal("invalid MainVersion:", MainVersion, err)
+	}
+	if checkVersion("v"+MainVersion+".3 devel") != nil || checkVersion("v"+MainVersion+".0-rc1") != nil {
+		t.Fatal("checkVersion failed")
+	}
+	if checkVersion("v0.9.1") == nil || checkVersion("v"+MainVersion+".-1") == nil {
+		t.Fatal("checkVersion: no error?")
+	}
+	buildVersion = ""
+	if v := SemVersion(); v.String() != "v"+MainVersion+".0" || !v.AtLeast(MainVersion) {
+		t.Fatal("SemVersion:", v)
+	}
+	buildVersion = "v" + MainVersion + ".7"
+	defer func() { buildVersion = "" }()
+	if v := SemVersion(); v.Patch != 7 {
+		t.Fatal("SemVersion:", v)
+	}
+}
+
+func TestVersionOf(t *testing.T) {
+	mainVer := "v" + MainVersion + ".3"
+	pseudo := "v" + MainVersion + ".4-0.20240115161159-762f74054b17"
+	settings := []debug.BuildSetting{
+		{Key: "vcs.revision", Value: "e657d899cb2e3f4a5b6c"},
+		{Key: "vcs.time", Value: "2024-01-15T15:59:41Z"},
+		{Key: "vcs.modified", Value: "true"},
+	}
+	cases := []struct {
+		bi       debug.BuildInfo
+		ver, tim string
+	}{
+		{debug.BuildInfo{Main: debug.Module{Path: gopModPath, Version: mainVer}}, mainVer, ""},
+		{debug.BuildInfo{Main: debug.Module{Path: gopModPath, Version: "(devel)"}, Settings: settings},
+			"v" + MainVersion + ".x devel e657d899cb2e-dirty", "2024-01-15T15:59:41Z"},
+		{debug.BuildInfo{Main: debug.Module{Path: gopModPath, Version: "(devel)"}}, "", ""},
+		{debug.BuildInfo{Main: debug.Module{Path: gopModPath, Version: "v0.9.1"}}, "", ""},
+		{debug.BuildInfo{Main: debug.Module{Path: "example.com/foo"}, Settings: settings,
+			Deps: []*debug.Module{{Path: gopModPath, Version: pseudo}}}, pseudo, ""},
+		{debug.BuildInfo{Main: debug.Module{Path: "example.com/foo"},
+			Deps: []*debug.Module{{Path: gopModPath, Version: mainVer, Replace: &debug.Module{Path: "../gop"}}}}, "", ""},
+		{debug.BuildInfo{Main: debug.Module{Path: "example.com/foo"}}, "", ""},
+	}
+	for _, c := range cases {
+		if ver, tim := versionOf(&c.bi); ver != c.ver || tim != c.tim {
+			t.Fatal("versionOf:", ver, tim, "expected:", c.ver, c.tim)
+		}
+	}
+}
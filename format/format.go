@@ -105,6 +105,14 @@ func Source(src []byte, class bool, filename ...string) ([]byte, error) {
 	if filename != nil {
 		fname = filename[0]
 	}
+	res, err := source(src, class, config, fname)
+	if err != nil {
+		return nil, err
+	}
+	return KeepFmtOff(src, res), nil
+}
+
+func source(src []byte, class bool, cfg printer.Config, fname string) ([]byte, error) {
 	fset := token.NewFileSet()
 	file, sourceAdj, indentAdj, err := parse(fset, fname, src, class, true)
 	if err != nil {
@@ -117,7 +125,65 @@ func Source(src []byte, class bool, filename ...string) ([]byte, error) {
 		ast.SortImports(fset, file)
 	}
 
-	return format(fset, file, sourceAdj, indentAdj, src, config)
+	return format(fset, file, sourceAdj, indentAdj, src, cfg)
+}
+
+// Directives to protect regions from formatting: source lines between a
+// `//gop:fmt off` line and a `//gop:fmt on` line (or the end of the file) are
+// left as they are.
+const (
+	FmtOff = "//gop:fmt off"
+	FmtOn  = "//gop:fmt on"
+)
+
+type region struct {
+	start, end int
+}
+
+// fmtOffRegions returns byte ranges of lines between FmtOff and FmtOn lines.
+func fmtOffRegions(src []byte) (ret []region) {
+	off := -1
+	for pos := 0; pos < len(src); {
+		next := len(src)
+		if i := bytes.IndexByte(src[pos:], '\n'); i >= 0 {
+			next = pos + i + 1
+		}
+		line := string(bytes.TrimSpace(src[pos:next]))
+		if off < 0 {
+			if line == FmtOff {
+				off = next
+			}
+		} else if line == FmtOn {
+			ret = append(ret, region{off, pos})
+			off = -1
+		}
+		pos = next
+	}
+	if off >= 0 {
+		ret = append(ret, region{off, len(src)})
+	}
+	return
+}
+
+// KeepFmtOff restores protected regions of src in the formatted result res.
+func KeepFmtOff(src, res []byte) []byte {
+	olds := fmtOffRegions(src)
+	if olds == nil {
+		return res
+	}
+	news := fmtOffRegions(res)
+	if len(news) != len(olds) { // should not happen
+		return res
+	}
+	var b bytes.Buffer
+	last := 0
+	for i, r := range news {
+		b.Write(res[last:r.start])
+		b.Write(src[olds[i].start:olds[i].end])
+		last = r.end
+	}
+	b.Write(res[last:])
+	return b.Bytes()
 }
 
 func hasUnsortedImports(file *ast.File) bool {
@@ -0,0 +1,252 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package format_test
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/goplus/gop/ast"
+	"github.com/goplus/gop/format"
+	"github.com/goplus/gop/parser"
+	"github.com/goplus/gop/token"
+)
+
+func TestFmtOff(t *testing.T) {
+	src := `package main
+
+//gop:fmt off
+var matrix = [][]int{
+	{1,  0,  0},
+	{0,  1,  0},
+}
+//gop:fmt on
+
+func f( ) {
+	//gop:fmt off
+	x:=[]int{1,  2}
+	println(x )
+}
+`
+	expected := `package main
+
+//gop:fmt off
+var matrix = [][]int{
+	{1,  0,  0},
+	{0,  1,  0},
+}
+//gop:fmt on
+
+func f() {
+	//gop:fmt off
+	x:=[]int{1,  2}
+	println(x )
+}
+`
+	ret, err := format.Source([]byte(src), false, "foo.gop")
+	if err != nil {
+		t.Fatal("format.Source failed:", err)
+	}
+	if string(ret) != expected {
+		t.Fatalf("\nResult:\n%s\nExpected:\n%s\n", ret, expected)
+	}
+}
+
+func testStyle(t *testing.T, style *format.Style, src, expected string) {
+	t.Helper()
+	ret, err := format.SourceWithStyle([]byte(src), false, style, "foo.gop")
+	if err != nil {
+		t.Fatal("format.SourceWithStyle failed:", err)
+	}
+	if string(ret) != expected {
+		t.Fatalf("\nResult:\n%s\nExpected:\n%s\n", ret, expected)
+	}
+}
+
+func TestStyleMaxWidth(t *testing.T) {
+	testStyle(t, &format.Style{MaxWidth: 40}, `package main
+
+func f() {
+	x := strings.NewReplacer("a", "b").Replace(s).Len()
+	y := [x * x + offset for x <- numbers if x > threshold]
+	z := a.b().c()
+}
+`, `package main
+
+func f() {
+	x := strings.NewReplacer("a", "b").
+		Replace(s).
+		Len()
+	y := [
+		x*x+offset for x <- numbers if x > threshold]
+	z := a.b().c()
+}
+`)
+}
+
+func TestStyleNoAlignComments(t *testing.T) {
+	src := `package main
+
+type T struct {
+	A int // a
+	Long string // long
+}
+`
+	testStyle(t, nil, src, `package main
+
+type T struct {
+	A    int    // a
+	Long string // long
+}
+`)
+	testStyle(t, &format.Style{NoAlignComments: true}, src, `package main
+
+type T struct {
+	A    int // a
+	Long string // long
+}
+`)
+}
+
+func TestParseNote(t *testing.T) {
+	cases := []struct {
+		text  string
+		notes map[string]string
+	}{
+		{"//gop:note", map[string]string{}},
+		{"//gop:note a=1 b=x.y", map[string]string{"a": "1", "b": "x.y"}},
+		{`//gop:note msg="hello world" empty="" raw=` + "`a b`", map[string]string{"msg": "hello world", "empty": "", "raw": "a b"}},
+		{"//gop:notes a=1", nil},
+		{"// gop:note a=1", nil},
+		{"//gop:note a", nil},
+		{"//gop:note =1", nil},
+		{`//gop:note a="1"b=2`, nil},
+		{`//gop:note a="1`, nil},
+		{`//gop:note a=1"`, nil},
+	}
+	for _, c := range cases {
+		notes, ok := ast.ParseNote(c.text)
+		if ok != (c.notes != nil) || !reflect.DeepEqual(notes, c.notes) {
+			t.Fatal("ParseNote:", c.text, notes, ok)
+		}
+	}
+	notes := map[string]string{"b": "hello world", "a": "1", "c": "", "d": `"q"`}
+	text := ast.FormatNote(notes)
+	if text != `//gop:note a=1 b="hello world" c="" d="\"q\""` {
+		t.Fatal("FormatNote:", text)
+	}
+	if ret, ok := ast.ParseNote(text); !ok || !reflect.DeepEqual(ret, notes) {
+		t.Fatal("ParseNote of FormatNote:", ret, ok)
+	}
+}
+
+func TestNoteMap(t *testing.T) {
+	src := `package main
+
+// doc of f
+//gop:note old=1
+func f() {
+	x := 1
+	if x > 0 {
+		println x // trailing
+	}
+}
+
+type T struct {
+	A int //gop:note drop=1
+	B int
+}
+`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "foo.gop", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal("ParseFile:", err)
+	}
+	notes := ast.NewNoteMap(fset, f)
+	fn := f.Decls[0].(*ast.FuncDecl)
+	typ := f.Decls[1].(*ast.GenDecl)
+	fields := typ.Specs[0].(*ast.TypeSpec).Type.(*ast.StructType).Fields.List
+	if v, ok := notes.Get(fn, "old"); !ok || v != "1" {
+		t.Fatal("NoteMap.Get old:", v, ok)
+	}
+	if v, ok := notes.Get(fields[0], "drop"); !ok || v != "1" {
+		t.Fatal("NoteMap.Get drop:", v, ok)
+	}
+	notes.Delete(fn, "old")
+	notes.Delete(fields[0], "drop")
+	notes.Set(fn, "migrated", "v2")
+	notes.Set(fn.Body.List[0], "covered", "3 times")
+	notes.Set(fn.Body.List[1].(*ast.IfStmt).Body.List[0], "hits", "0")
+	notes.Set(typ, "table", "t")
+	notes.Set(fields[1], "col", "b")
+	notes.Set(&ast.Ident{Name: "x"}, "ignored", "1")
+	notes.Apply(f)
+	if fields[0].Comment != nil {
+		t.Fatal("NoteMap.Apply: trailing comment of A is kept")
+	}
+
+	var b bytes.Buffer
+	if err = format.Node(&b, fset, f); err != nil {
+		t.Fatal("format.Node:", err)
+	}
+	expected := `package main
+
+// doc of f
+//gop:note migrated=v2
+func f() {
+	//gop:note covered="3 times"
+	x := 1
+	if x > 0 {
+		//gop:note hits=0
+		println x // trailing
+	}
+}
+
+//gop:note table=t
+type T struct {
+	A int
+	//gop:note col=b
+	B int
+}
+`
+	if b.String() != expected {
+		t.Fatalf("NoteMap.Apply:\n%s\nExpected:\n%s", b.String(), expected)
+	}
+
+	fset = token.NewFileSet()
+	if f, err = parser.ParseFile(fset, "foo.gop", b.Bytes(), parser.ParseComments); err != nil {
+		t.Fatal("ParseFile:", err)
+	}
+	got := make(map[string]map[string]string)
+	for n, v := range ast.NewNoteMap(fset, f) {
+		got[reflect.TypeOf(n).String()] = v
+	}
+	want := map[string]map[string]string{
+		"*ast.FuncDecl":   {"migrated": "v2"},
+		"*ast.AssignStmt": {"covered": "3 times"},
+		"*ast.ExprStmt":   {"hits": "0"},
+		"*ast.GenDecl":    {"table": "t"},
+		"*ast.Field":      {"col": "b"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatal("NewNoteMap after round-trip:", got)
+	}
+	if doc := f.Decls[0].(*ast.FuncDecl).Doc.Text(); doc != "doc of f\n" {
+		t.Fatalf("doc of f: %q", doc)
+	}
+}
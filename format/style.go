@@ -0,0 +1,154 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package format
+
+import (
+	"bytes"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/goplus/gop/ast"
+	"github.com/goplus/gop/parser"
+	"github.com/goplus/gop/printer"
+	"github.com/goplus/gop/token"
+)
+
+// -----------------------------------------------------------------------------
+
+// Style represents style options of the formatter. The zero value means the
+// canonical style used by Source.
+type Style struct {
+	// MaxWidth is the max width of lines, where a tab counts as 8 columns
+	// (0 means no limit). Long method call chains are wrapped after ".",
+	// and long comprehensions are wrapped after "[" or "{".
+	MaxWidth int
+
+	// NoAlignComments = true means not to align trailing comments of
+	// consecutive lines, eg. comments of struct fields.
+	NoAlignComments bool
+}
+
+// SourceWithStyle formats src like Source, but in the specified style.
+func SourceWithStyle(src []byte, class bool, style *Style, filename ...string) ([]byte, error) {
+	var fname string
+	if filename != nil {
+		fname = filename[0]
+	}
+	cfg := config
+	if style != nil && style.NoAlignComments {
+		cfg.Mode |= printer.NoAlignComments
+	}
+	res, err := source(src, class, cfg, fname)
+	if err != nil {
+		return nil, err
+	}
+	if style != nil && style.MaxWidth > 0 {
+		res = wrapLines(res, class, cfg, fname, style.MaxWidth)
+	}
+	return KeepFmtOff(src, res), nil
+}
+
+// maxWrapPasses limits passes of wrapping long lines, because wrapping a line
+// may result in another long line which needs to be wrapped.
+const maxWrapPasses = 5
+
+func wrapLines(src []byte, class bool, cfg printer.Config, fname string, maxWidth int) []byte {
+	mode := parserMode
+	if class {
+		mode |= parser.ParseGoPlusClass
+	}
+	for pass := 0; pass < maxWrapPasses; pass++ {
+		long := longLines(src, maxWidth, cfg.Tabwidth)
+		if long == nil {
+			break
+		}
+		fset := token.NewFileSet()
+		file, err := parser.ParseFile(fset, fname, src, mode)
+		if err != nil {
+			break
+		}
+		var offs []int
+		ast.Inspect(file, func(n ast.Node) bool {
+			switch v := n.(type) {
+			case *ast.SelectorExpr: // x.f().g() => x.f().\n\tg()
+				if _, ok := v.X.(*ast.CallExpr); ok {
+					sel := fset.Position(v.Sel.Pos())
+					if long[sel.Line] && sel.Line == fset.Position(v.X.End()).Line {
+						offs = append(offs, sel.Offset)
+					}
+				}
+			case *ast.ComprehensionExpr: // [x for x <- a] => [\n\tx for x <- a]
+				first := v.Fors[0].Pos()
+				if v.Elt != nil {
+					first = v.Elt.Pos()
+				}
+				lpos := fset.Position(v.Lpos)
+				if long[lpos.Line] && lpos.Line == fset.Position(first).Line {
+					offs = append(offs, lpos.Offset+1)
+				}
+			}
+			return true
+		})
+		if offs == nil {
+			break
+		}
+		sort.Ints(offs)
+		var b bytes.Buffer
+		last := 0
+		for _, off := range offs {
+			b.Write(src[last:off])
+			b.WriteByte('\n')
+			last = off
+		}
+		b.Write(src[last:])
+		res, err := source(b.Bytes(), class, cfg, fname)
+		if err != nil || bytes.Equal(res, src) {
+			break
+		}
+		src = res
+	}
+	return src
+}
+
+// longLines returns line numbers of lines in src which are longer than
+// maxWidth, or nil if there is none.
+func longLines(src []byte, maxWidth, tabWidth int) (long map[int]bool) {
+	for line := 1; len(src) > 0; line++ {
+		width := 0
+		for len(src) > 0 && src[0] != '\n' {
+			r, n := utf8.DecodeRune(src)
+			if r == '\t' {
+				width += tabWidth - width%tabWidth
+			} else {
+				width++
+			}
+			src = src[n:]
+		}
+		if width > maxWidth {
+			if long == nil {
+				long = make(map[int]bool)
+			}
+			long[line] = true
+		}
+		if len(src) > 0 {
+			src = src[1:]
+		}
+	}
+	return
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,275 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gop
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	goparser "go/parser"
+	"go/token"
+	"hash"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/goplus/gop/env"
+	"github.com/goplus/gox"
+	"github.com/goplus/mod/gopmod"
+)
+
+// -----------------------------------------------------------------------------
+
+// genCacheEntry records Go code generated into Config.GenDir from Go+ source
+// files. It is stored in $GenDir/<key>.pkg, where key is a hash of the source
+// files, the compiler, options of compiling and experiments enabled by
+// GOPEXPERIMENT (see genCacheKey).
+type genCacheEntry struct {
+	Gen      string            `json:"gen"`                // generated file in GenDir
+	Deps     map[string]string `json:"deps,omitempty"`     // dir => hash of imported local packages
+	Warnings []string          `json:"warnings,omitempty"` // compiler warnings
+}
+
+// genGoCached is like genGoOverlay(load(conf), file, conf), but doesn't
+// compile at all if Go code of the same Go+ source files (files of dir, or
+// files if it isn't nil) has been generated, and local packages it imports
+// are not changed since then. Compiler warnings are reported again in this
+// case.
+func genGoCached(dir string, files []string, file string, conf *Config, load func(conf *Config) (*gox.Package, error)) (abs string, overlay map[string]string, err error) {
+	mod, err := LoadMod(dir)
+	if err != nil {
+		return
+	}
+	key, ok := genCacheKey(dir, files, mod, conf)
+	if !ok {
+		out, err := load(conf)
+		if err != nil {
+			return "", nil, err
+		}
+		return genGoOverlay(out, file, conf)
+	}
+	entryFile := filepath.Join(conf.GenDir, key+".pkg")
+	if e := readGenCache(entryFile, mod); e != nil {
+		gen := filepath.Join(conf.GenDir, e.Gen)
+		if _, err = os.Stat(gen); err == nil {
+			if abs, err = filepath.Abs(file); err == nil {
+				if conf.OnWarning != nil {
+					for _, w := range e.Warnings {
+						conf.OnWarning(errors.New(w))
+					}
+				}
+				return abs, map[string]string{abs: gen}, nil
+			}
+		}
+	}
+
+	var warnings []string
+	if onWarning := conf.OnWarning; onWarning != nil {
+		c := *conf
+		c.OnWarning = func(err error) {
+			warnings = append(warnings, err.Error())
+			onWarning(err)
+		}
+		conf = &c
+	}
+	out, err := load(conf)
+	if err != nil {
+		return
+	}
+	if abs, overlay, err = genGoOverlay(out, file, conf); err != nil {
+		return
+	}
+	gen := overlay[abs]
+	e := &genCacheEntry{Gen: filepath.Base(gen), Deps: genCacheDeps(gen, mod), Warnings: warnings}
+	writeGenCache(entryFile, e) // it's ok if failed
+	return
+}
+
+// genCacheKey returns the key of Go code generated from Go+ source files, or
+// false if it can't be cached.
+func genCacheKey(dir string, files []string, mod *gopmod.Module, conf *Config) (key string, ok bool) {
+	if conf.FS != nil || conf.Backend != nil || conf.Importer != nil {
+		return
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "gop %s %s\n", env.Version(), executableStamp())
+	fmt.Fprintf(h, "conf %q %v %v %v %v %v %q\n", conf.Lang, conf.Strict, conf.Trace,
+		conf.AbsFileLine, conf.NoFileLine, conf.KeepComments, conf.Profile)
+	exps, _ := env.ParseExperiment(os.Getenv(env.EnvExperiment)) // nothing is enabled if it's invalid
+	fmt.Fprintf(h, "experiment %s\n", strings.Join(exps, ","))
+	fmt.Fprintf(h, "dir %s\n", absDir)
+	if files == nil {
+		if files, err = genCacheSources(absDir, mod); err != nil {
+			return
+		}
+	}
+	for _, f := range files {
+		if f, err = filepath.Abs(f); err != nil || !hashFile(h, f) {
+			return
+		}
+	}
+	if hasModfile(mod) {
+		root := mod.Root()
+		for _, f := range []string{"go.mod", "go.sum", "gop.mod", "gop.sum"} {
+			hashFile(h, filepath.Join(root, f))
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16]), true
+}
+
+// genCacheSources returns source files in dir which the Go code generated
+// from the Go+ package depends on: Go+ files and Go files (except generated
+// ones).
+func genCacheSources(dir string, mod *gopmod.Module) (files []string, err error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || strings.HasPrefix(name, "_") || strings.HasPrefix(name, ".") {
+			continue
+		}
+		switch ext := filepath.Ext(name); ext {
+		case ".go":
+			if strings.HasPrefix(name, "gop_autogen") {
+				continue
+			}
+		case ".gop", ".gox":
+		default:
+			if !mod.IsClass(ext) {
+				continue
+			}
+		}
+		files = append(files, filepath.Join(dir, name))
+	}
+	return
+}
+
+// hashDir returns a hash of a local package in dir, which changes if any
+// source file of it is changed, or its generated Go code is removed.
+func hashDir(dir string, mod *gopmod.Module) string {
+	files, err := genCacheSources(dir, mod)
+	if err != nil {
+		return ""
+	}
+	h := sha256.New()
+	for _, f := range files {
+		hashFile(h, f)
+	}
+	_, err = os.Lstat(filepath.Join(dir, autoGenFile))
+	fmt.Fprintf(h, "autogen %v\n", err == nil)
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+func hashFile(h hash.Hash, file string) bool {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		fmt.Fprintf(h, "file %s -\n", file)
+		return false
+	}
+	fmt.Fprintf(h, "file %s %d\n", file, len(b))
+	h.Write(b)
+	return true
+}
+
+// executableStamp identifies the running executable, so that Go code
+// generated by different builds of the compiler is never mixed up.
+func executableStamp() string {
+	exe, err := os.Executable()
+	if err != nil {
+		return ""
+	}
+	fi, err := os.Stat(exe)
+	if err != nil {
+		return exe
+	}
+	return fmt.Sprintf("%s %d %d", exe, fi.Size(), fi.ModTime().UnixNano())
+}
+
+// genCacheDeps returns local packages (and their hashes) which are imported
+// by the generated Go file gen.
+func genCacheDeps(gen string, mod *gopmod.Module) map[string]string {
+	if !hasModfile(mod) {
+		return nil
+	}
+	f, err := goparser.ParseFile(token.NewFileSet(), gen, nil, goparser.ImportsOnly)
+	if err != nil {
+		return nil
+	}
+	deps := make(map[string]string)
+	for _, spec := range f.Imports {
+		pkgPath, _ := strconv.Unquote(spec.Path.Value)
+		ret, err := mod.Lookup(pkgPath)
+		if err != nil || (ret.Type != gopmod.PkgtModule && ret.Type != gopmod.PkgtLocal) {
+			continue
+		}
+		deps[ret.Dir] = hashDir(ret.Dir, mod)
+	}
+	return deps
+}
+
+// readGenCache reads the cache entry in file. It returns nil if there isn't
+// a valid one.
+func readGenCache(file string, mod *gopmod.Module) *genCacheEntry {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil
+	}
+	var e genCacheEntry
+	if json.Unmarshal(b, &e) != nil || e.Gen == "" {
+		return nil
+	}
+	for dir, hash := range e.Deps {
+		if hashDir(dir, mod) != hash {
+			return nil
+		}
+	}
+	return &e
+}
+
+func writeGenCache(file string, e *genCacheEntry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	// write to a temporary file first, so others never see a partial file
+	f, err := os.CreateTemp(filepath.Dir(file), "pkg-*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(b)
+	if e := f.Close(); err == nil {
+		err = e
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), file)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// -----------------------------------------------------------------------------
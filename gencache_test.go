@@ -0,0 +1,142 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gop
+
+import (
+	"go/importer"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/goplus/gop/cl"
+	"github.com/goplus/gop/env"
+	"github.com/goplus/gop/parser/fsx"
+)
+
+func writeFile(t *testing.T, file, src string) {
+	t.Helper()
+	if err := os.WriteFile(file, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// cacheKeyOf returns the cache key of Go code generated from Go+ files in dir.
+func cacheKeyOf(t *testing.T, dir string, conf *Config) string {
+	t.Helper()
+	mod, err := LoadMod(dir)
+	if err != nil {
+		t.Fatal("LoadMod:", err)
+	}
+	key, ok := genCacheKey(dir, nil, mod, conf)
+	if !ok {
+		t.Fatal("genCacheKey: not cached")
+	}
+	return key
+}
+
+func TestGenCacheKey(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "go.mod"), "module example.com/foo\n\ngo 1.18\n")
+	writeFile(t, filepath.Join(dir, "main.gop"), "println 1\n")
+	conf := &Config{}
+	key := cacheKeyOf(t, dir, conf)
+	if key2 := cacheKeyOf(t, dir, conf); key2 != key {
+		t.Fatal("genCacheKey isn't stable:", key, key2)
+	}
+
+	// files which the generated code doesn't depend on
+	writeFile(t, filepath.Join(dir, "README.md"), "# foo\n")
+	writeFile(t, filepath.Join(dir, "gop_autogen.go"), "package main\n")
+	writeFile(t, filepath.Join(dir, "_draft.gop"), "println 2\n")
+	if key2 := cacheKeyOf(t, dir, conf); key2 != key {
+		t.Fatal("genCacheKey is changed by irrelevant files")
+	}
+
+	// the key depends on contents, not modification times
+	writeFile(t, filepath.Join(dir, "main.gop"), "println 2\n")
+	if cacheKeyOf(t, dir, conf) == key {
+		t.Fatal("genCacheKey isn't changed by source files")
+	}
+	writeFile(t, filepath.Join(dir, "main.gop"), "println 1\n")
+	if key2 := cacheKeyOf(t, dir, conf); key2 != key {
+		t.Fatal("genCacheKey is changed by the same source files")
+	}
+
+	writeFile(t, filepath.Join(dir, "foo.go"), "package main\n")
+	if cacheKeyOf(t, dir, conf) == key {
+		t.Fatal("genCacheKey isn't changed by Go files")
+	}
+	os.Remove(filepath.Join(dir, "foo.go"))
+
+	writeFile(t, filepath.Join(dir, "go.mod"), "module example.com/foo\n\ngo 1.21\n")
+	if cacheKeyOf(t, dir, conf) == key {
+		t.Fatal("genCacheKey isn't changed by go.mod")
+	}
+	writeFile(t, filepath.Join(dir, "go.mod"), "module example.com/foo\n\ngo 1.18\n")
+
+	for name, c := range map[string]*Config{
+		"Lang":         {Lang: "gop1.0"},
+		"Strict":       {Strict: true},
+		"Trace":        {Trace: true},
+		"AbsFileLine":  {AbsFileLine: true},
+		"NoFileLine":   {NoFileLine: true},
+		"KeepComments": {KeepComments: true},
+		"Profile":      {Profile: dir},
+	} {
+		if cacheKeyOf(t, dir, c) == key {
+			t.Fatal("genCacheKey isn't changed by Config." + name)
+		}
+	}
+	if key2 := cacheKeyOf(t, dir, &Config{OnWarning: func(err error) {}}); key2 != key {
+		t.Fatal("genCacheKey is changed by Config.OnWarning")
+	}
+}
+
+func TestGenCacheKeyExperiment(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "main.gop"), "println 1\n")
+	t.Setenv(env.EnvExperiment, "")
+	key := cacheKeyOf(t, dir, &Config{})
+	t.Setenv(env.EnvExperiment, "pipeline")
+	if cacheKeyOf(t, dir, &Config{}) == key {
+		t.Fatal("genCacheKey isn't changed by GOPEXPERIMENT")
+	}
+
+	// experiments enabled are hashed, not the value of GOPEXPERIMENT
+	t.Setenv(env.EnvExperiment, "nopipeline")
+	if key2 := cacheKeyOf(t, dir, &Config{}); key2 != key {
+		t.Fatal("genCacheKey is changed by GOPEXPERIMENT enabling nothing")
+	}
+}
+
+func TestGenCacheBypass(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "main.gop"), "println 1\n")
+	mod, err := LoadMod(dir)
+	if err != nil {
+		t.Fatal("LoadMod:", err)
+	}
+	for name, conf := range map[string]*Config{
+		"FS":       {FS: fsx.Local},
+		"Backend":  {Backend: cl.GoBackend},
+		"Importer": {Importer: importer.Default()},
+	} {
+		if _, ok := genCacheKey(dir, nil, mod, conf); ok {
+			t.Fatal("genCacheKey: Go code is cached with Config." + name)
+		}
+	}
+}
@@ -17,6 +17,9 @@
 package gop
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io/fs"
 	"os"
@@ -24,6 +27,9 @@ import (
 	"strings"
 	"syscall"
 
+	"github.com/goplus/gop/cl"
+	"github.com/goplus/gop/x/pathutil"
+	"github.com/goplus/gox"
 	"github.com/goplus/mod/gopmod"
 	"github.com/goplus/mod/modcache"
 	"github.com/goplus/mod/modfetch"
@@ -64,6 +70,7 @@ func genGoDir(dir string, conf *Config, genTestPkg, recursively bool, flags GenF
 	if recursively {
 		var (
 			list errors.List
+			dirs []string // directories to compile, collected first to know the total
 			fn   func(path string, d fs.DirEntry, err error) error
 		)
 		if flags&GenFlagSingleFile != 0 {
@@ -79,12 +86,7 @@ func genGoDir(dir string, conf *Config, genTestPkg, recursively bool, flags GenF
 					if strings.HasPrefix(d.Name(), "_") { // skip _
 						return filepath.SkipDir
 					}
-					if e := genGoIn(path, conf, genTestPkg, flags); e != nil && notIgnNotated(e, conf) {
-						if flags&GenFlagPrintError != 0 {
-							fmt.Fprintln(os.Stderr, e)
-						}
-						list.Add(e)
-					}
+					dirs = append(dirs, path)
 				}
 				return err
 			}
@@ -93,6 +95,9 @@ func genGoDir(dir string, conf *Config, genTestPkg, recursively bool, flags GenF
 		if err != nil {
 			return errors.NewWith(err, `filepath.WalkDir(dir, fn)`, -2, "filepath.WalkDir", dir, fn)
 		}
+		if dirs != nil {
+			return genGoDirs(dirs, conf, genTestPkg, flags)
+		}
 		return list.ToError()
 	}
 	if flags&GenFlagSingleFile != 0 {
@@ -112,9 +117,23 @@ func genGoDir(dir string, conf *Config, genTestPkg, recursively bool, flags GenF
 		}
 		err = e
 	}
+	onProgress(conf, dir, 1, 1)
 	return
 }
 
+func onProgress(conf *Config, dir string, done, total int) {
+	if conf != nil && conf.OnProgress != nil {
+		conf.OnProgress(dir, done, total)
+	}
+}
+
+func backendOf(conf *Config) cl.Backend {
+	if conf != nil {
+		return conf.Backend
+	}
+	return nil
+}
+
 func notIgnNotated(e error, conf *Config) bool {
 	return !(conf != nil && conf.IgnoreNotatedError && IgnoreNotated(e))
 }
@@ -149,8 +168,8 @@ func genGoSingleFile(file string, conf *Config, flags GenFlags) (err error) {
 	if flags&GenFlagCheckOnly != 0 {
 		return nil
 	}
-	if err := out.WriteFile(autogen); err != nil {
-		return errors.NewWith(err, `out.WriteFile(autogen)`, -2, "(*gox.Package).WriteFile", out, autogen)
+	if err := cl.WriteFile(backendOf(conf), out, autogen); err != nil {
+		return errors.NewWith(err, `cl.WriteFile(backendOf(conf), out, autogen)`, -2, "cl.WriteFile", backendOf(conf), out, autogen)
 	}
 	return nil
 }
@@ -167,26 +186,27 @@ func genGoIn(dir string, conf *Config, genTestPkg bool, flags GenFlags, gen ...*
 		return nil
 	}
 	os.MkdirAll(dir, 0755)
-	file := filepath.Join(dir, autoGenFile)
-	err = out.WriteFile(file)
+	file := pathutil.Long(filepath.Join(dir, autoGenFile))
+	backend := backendOf(conf)
+	err = cl.WriteFile(backend, out, file)
 	if err != nil {
-		return errors.NewWith(err, `out.WriteFile(file)`, -2, "(*gox.Package).WriteFile", out, file)
+		return errors.NewWith(err, `cl.WriteFile(backend, out, file)`, -2, "cl.WriteFile", backend, out, file)
 	}
 	if gen != nil { // say `gop_autogen.go generated`
 		*gen[0] = true
 	}
 
-	testFile := filepath.Join(dir, autoGenTestFile)
-	err = out.WriteFile(testFile, testingGoFile)
+	testFile := pathutil.Long(filepath.Join(dir, autoGenTestFile))
+	err = cl.WriteFile(backend, out, testFile, testingGoFile)
 	if err != nil && err != syscall.ENOENT {
-		return errors.NewWith(err, `out.WriteFile(testFile, testingGoFile)`, -2, "(*gox.Package).WriteFile", out, testFile, testingGoFile)
+		return errors.NewWith(err, `cl.WriteFile(backend, out, testFile, testingGoFile)`, -2, "cl.WriteFile", backend, out, testFile, testingGoFile)
 	}
 
 	if test != nil {
-		testFile = filepath.Join(dir, autoGen2TestFile)
-		err = test.WriteFile(testFile, testingGoFile)
+		testFile = pathutil.Long(filepath.Join(dir, autoGen2TestFile))
+		err = cl.WriteFile(backend, test, testFile, testingGoFile)
 		if err != nil {
-			return errors.NewWith(err, `test.WriteFile(testFile, testingGoFile)`, -2, "(*gox.Package).WriteFile", test, testFile, testingGoFile)
+			return errors.NewWith(err, `cl.WriteFile(backend, test, testFile, testingGoFile)`, -2, "cl.WriteFile", backend, test, testFile, testingGoFile)
 		}
 	} else {
 		err = nil
@@ -260,6 +280,36 @@ func remotePkgPathDo(pkgPath string, doSth func(pkgDir, modDir string), onErr fu
 // -----------------------------------------------------------------------------
 
 func GenGoFiles(autogen string, files []string, conf *Config) (result []string, err error) {
+	autogen = pathutil.Long(autogenOf(autogen, files))
+	out, err := LoadFiles(".", files, conf)
+	onProgress(conf, filepath.Dir(autogen), 1, 1)
+	if err != nil {
+		err = errors.NewWith(err, `LoadFiles(files, conf)`, -2, "gop.LoadFiles", files, conf)
+		return
+	}
+	result = append(result, autogen)
+	err = cl.WriteFile(backendOf(conf), out, autogen)
+	if err != nil {
+		err = errors.NewWith(err, `cl.WriteFile(backendOf(conf), out, autogen)`, -2, "cl.WriteFile", backendOf(conf), out, autogen)
+		return
+	}
+
+	// code of *_test.gop files goes into a separate _test.go file
+	testFile := strings.TrimSuffix(autogen, ".go") + testingGoFile + ".go"
+	err = cl.WriteFile(backendOf(conf), out, testFile, testingGoFile)
+	if err == nil {
+		result = append(result, testFile)
+	} else if err == syscall.ENOENT {
+		err = nil
+	} else {
+		err = errors.NewWith(err, `cl.WriteFile(backendOf(conf), out, testFile, testingGoFile)`, -2, "cl.WriteFile", backendOf(conf), out, testFile, testingGoFile)
+	}
+	return
+}
+
+// autogenOf returns the Go file generated from Go+ files, if autogen isn't
+// specified.
+func autogenOf(autogen string, files []string) string {
 	if autogen == "" {
 		autogen = "gop_autogen.go"
 		if len(files) == 1 {
@@ -270,17 +320,81 @@ func GenGoFiles(autogen string, files []string, conf *Config) (result []string,
 			}
 		}
 	}
-	out, err := LoadFiles(".", files, conf)
-	if err != nil {
-		err = errors.NewWith(err, `LoadFiles(files, conf)`, -2, "gop.LoadFiles", files, conf)
+	return autogen
+}
+
+// -----------------------------------------------------------------------------
+
+// genGoDirTo is like GenGo (not recursively and no test files), but generates
+// Go code into conf.GenDir instead of dir. See genGoOverlay and genGoCached.
+func genGoDirTo(dir string, conf *Config) (overlay map[string]string, err error) {
+	_, overlay, err = genGoCached(dir, nil, filepath.Join(dir, autoGenFile), conf, func(conf *Config) (*gox.Package, error) {
+		out, _, err := LoadDir(dir, conf, false)
+		if err != nil && !NotFound(err) {
+			err = errors.NewWith(err, `LoadDir(dir, conf, false)`, -2, "gop.LoadDir", dir, conf, false)
+		}
+		return out, err
+	})
+	if NotFound(err) { // no Go+ source files
+		return nil, nil
+	}
+	return
+}
+
+// genGoFilesTo is like GenGoFiles (no test files), but generates Go code into
+// conf.GenDir instead of the source directory. See genGoOverlay and
+// genGoCached.
+func genGoFilesTo(autogen string, files []string, conf *Config) (file string, overlay map[string]string, err error) {
+	return genGoCached(".", files, autogenOf(autogen, files), conf, func(conf *Config) (*gox.Package, error) {
+		out, err := LoadFiles(".", files, conf)
+		if err != nil {
+			err = errors.NewWith(err, `LoadFiles(files, conf)`, -2, "gop.LoadFiles", files, conf)
+		}
+		return out, err
+	})
+}
+
+// genGoOverlay writes Go code of out into conf.GenDir, named by its content
+// hash so that it's shared by identical code and never changed once written.
+// The returned overlay maps file (the absolute path of it) to the written one,
+// for `go build -overlay`.
+func genGoOverlay(out *gox.Package, file string, conf *Config) (abs string, overlay map[string]string, err error) {
+	b := backendOf(conf)
+	if b == nil {
+		b = cl.GoBackend
+	}
+	var buf bytes.Buffer
+	if err = b.WriteTo(&buf, out); err != nil {
+		err = errors.NewWith(err, `b.WriteTo(&buf, out)`, -2, "cl.Backend.WriteTo", b, &buf, out)
 		return
 	}
-	result = append(result, autogen)
-	err = out.WriteFile(autogen)
-	if err != nil {
-		err = errors.NewWith(err, `out.WriteFile(autogen)`, -2, "(*gox.Package).WriteFile", out, autogen)
+	if abs, err = filepath.Abs(file); err != nil {
+		return
 	}
-	return
+	sum := sha256.Sum256(buf.Bytes())
+	gen := filepath.Join(conf.GenDir, hex.EncodeToString(sum[:16])+".go")
+	if _, e := os.Stat(gen); e != nil {
+		if err = os.MkdirAll(conf.GenDir, 0755); err != nil {
+			return
+		}
+		// write to a temporary file first, so others never see a partial file
+		f, e := os.CreateTemp(conf.GenDir, "gen-*.tmp")
+		if e != nil {
+			return "", nil, e
+		}
+		_, err = f.Write(buf.Bytes())
+		if e := f.Close(); err == nil {
+			err = e
+		}
+		if err == nil {
+			err = os.Rename(f.Name(), gen)
+		}
+		if err != nil {
+			os.Remove(f.Name())
+			return
+		}
+	}
+	return abs, map[string]string{abs: gen}, nil
 }
 
 func hasMultiFiles(srcDir string, ext string) bool {
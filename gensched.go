@@ -0,0 +1,204 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gop
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+
+	"github.com/goplus/gop/parser"
+	"github.com/goplus/gop/token"
+	"github.com/goplus/gop/x/classfile"
+	"github.com/goplus/mod/gopmod"
+	"github.com/qiniu/x/errors"
+)
+
+// -----------------------------------------------------------------------------
+
+// genGoDirs compiles Go+ packages in dirs by genGoIn, at most parallelOf(conf)
+// ones at a time. A package is compiled after local packages in dirs it
+// imports, so that their Go code is generated before it's imported. Packages
+// importing a package which fails to compile (directly or indirectly) are not
+// compiled. Errors are returned in the order of dirs.
+func genGoDirs(dirs []string, conf *Config, genTestPkg bool, flags GenFlags) error {
+	if conf == nil {
+		conf = new(Config)
+	}
+	deps := importGraph(dirs, conf)
+	users := make([][]int, len(dirs))
+	waits := make([]int, len(dirs))
+	for i, list := range deps {
+		waits[i] = len(list)
+		for _, dep := range list {
+			users[dep] = append(users[dep], i)
+		}
+	}
+	var ready []int
+	for i, n := range waits {
+		if n == 0 {
+			ready = append(ready, i)
+		}
+	}
+
+	type result struct {
+		idx int
+		err error
+	}
+	var (
+		max      = parallelOf(conf)
+		started  = make([]bool, len(dirs))
+		canceled = make([]bool, len(dirs)) // a package it imports failed
+		errs     = make([]error, len(dirs))
+		done     = make(chan result)
+		running  int
+		finished int
+	)
+	finish := func(i int, err error) {
+		failed := canceled[i]
+		if err != nil && notIgnNotated(err, conf) {
+			if flags&GenFlagPrintError != 0 {
+				fmt.Fprintln(os.Stderr, err)
+			}
+			errs[i], failed = err, true
+		}
+		finished++
+		onProgress(conf, dirs[i], finished, len(dirs))
+		for _, user := range users[i] {
+			if failed {
+				canceled[user] = true
+			}
+			if waits[user]--; waits[user] == 0 {
+				ready = append(ready, user)
+			}
+		}
+	}
+	for finished < len(dirs) {
+		if running == 0 && len(ready) == 0 { // an import cycle, start any of it
+			for i := range dirs {
+				if !started[i] {
+					ready = append(ready, i)
+					break
+				}
+			}
+		}
+		for len(ready) > 0 && running < max {
+			i := ready[0]
+			ready = ready[1:]
+			if started[i] {
+				continue
+			}
+			started[i] = true
+			if canceled[i] { // it would fail to import the failed package
+				finish(i, nil)
+				continue
+			}
+			running++
+			go func(i int) {
+				done <- result{i, genGoIn(dirs[i], conf, genTestPkg, flags)}
+			}(i)
+		}
+		if running > 0 {
+			ret := <-done
+			running--
+			finish(ret.idx, ret.err)
+		}
+	}
+
+	var list errors.List
+	for _, e := range errs {
+		if e != nil {
+			list.Add(e)
+		}
+	}
+	return list.ToError()
+}
+
+// parallelOf returns the maximum number of packages to compile concurrently.
+// A custom conf.Importer may not be safe for concurrent use, so packages are
+// compiled one by one if it's set.
+func parallelOf(conf *Config) int {
+	if conf.Importer != nil {
+		return 1
+	}
+	if conf.Parallel > 0 {
+		return conf.Parallel
+	}
+	return runtime.GOMAXPROCS(0)
+}
+
+// importGraph returns indexes of dirs which the package in dirs[i] imports,
+// for each i. Only local packages of the module are looked up.
+func importGraph(dirs []string, conf *Config) [][]int {
+	idx := make(map[string]int, len(dirs))
+	for i, dir := range dirs {
+		if abs, err := filepath.Abs(dir); err == nil {
+			idx[abs] = i
+		}
+	}
+	deps := make([][]int, len(dirs))
+	for i, dir := range dirs {
+		mod, err := LoadMod(dir)
+		if err != nil {
+			continue
+		}
+		for _, pkgPath := range importsOf(dir, mod, conf) {
+			ret, err := mod.Lookup(pkgPath)
+			if err != nil || (ret.Type != gopmod.PkgtModule && ret.Type != gopmod.PkgtLocal) {
+				continue
+			}
+			if j, ok := idx[filepath.Clean(ret.Dir)]; ok && j != i {
+				deps[i] = append(deps[i], j)
+			}
+		}
+	}
+	return deps
+}
+
+// importsOf returns paths of packages imported by Go and Go+ files in dir.
+func importsOf(dir string, mod *gopmod.Module, conf *Config) []string {
+	pkgs, _ := parser.ParseFSDir(token.NewFileSet(), fsOf(conf), dir, parser.Config{
+		ClassKind: classfile.KindOf(mod.ClassKind),
+		Filter:    conf.Filter,
+		Mode:      parser.ImportsOnly,
+	})
+	seen := make(map[string]bool)
+	var ret []string
+	add := func(path string) {
+		if pkgPath, err := strconv.Unquote(path); err == nil && !seen[pkgPath] {
+			seen[pkgPath] = true
+			ret = append(ret, pkgPath)
+		}
+	}
+	for _, pkg := range pkgs {
+		for _, f := range pkg.Files {
+			for _, spec := range f.Imports {
+				add(spec.Path.Value)
+			}
+		}
+		for _, f := range pkg.GoFiles {
+			for _, spec := range f.Imports {
+				add(spec.Path.Value)
+			}
+		}
+	}
+	return ret
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,160 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gop
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func init() {
+	if os.Getenv("GOPROOT") == "" {
+		dir, _ := os.Getwd()
+		os.Setenv("GOPROOT", dir)
+	}
+}
+
+// diamondFiles are packages of module example.com/m, where a imports b and c,
+// and both of them import d.
+var diamondFiles = map[string]string{
+	"go.mod": "module example.com/m\n\ngo 1.18\n",
+	"a/a.gop": `package a
+
+import (
+	"example.com/m/b"
+	"example.com/m/c"
+)
+
+func A() int {
+	return b.B() + c.C()
+}
+`,
+	"b/b.gop": `package b
+
+import "example.com/m/d"
+
+func B() int {
+	return d.D() + 1
+}
+`,
+	"c/c.gop": `package c
+
+import "example.com/m/d"
+
+func C() int {
+	return d.D() + 2
+}
+`,
+	"d/d.gop": `package d
+
+func D() int {
+	return 1
+}
+`,
+	"e/e.gop": `package e
+
+func E() int {
+	return 5
+}
+`,
+}
+
+// writeModule writes files into a new temporary directory, and returns it.
+func writeModule(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, src := range files {
+		file := filepath.Join(root, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(file), 0755)
+		writeFile(t, file, src)
+	}
+	return root
+}
+
+// genDiamond compiles packages of the diamond in the order a, b, c, d, e,
+// and returns names of them in the order they are finished.
+func genDiamond(t *testing.T, root string) (order []string, err error) {
+	var dirs []string
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		dirs = append(dirs, filepath.Join(root, name))
+	}
+	conf := &Config{
+		Parallel: 4,
+		OnProgress: func(dir string, done, total int) {
+			if done != len(order)+1 || total != len(dirs) {
+				t.Errorf("OnProgress: %s %d/%d", dir, done, total)
+			}
+			order = append(order, filepath.Base(dir))
+		},
+	}
+	err = genGoDirs(dirs, conf, false, 0)
+	return
+}
+
+func isGenerated(root, pkg string) bool {
+	_, err := os.Stat(filepath.Join(root, pkg, autoGenFile))
+	return err == nil
+}
+
+func TestGenGoDirsDiamond(t *testing.T) {
+	root := writeModule(t, diamondFiles)
+	order, err := genDiamond(t, root)
+	if err != nil {
+		t.Fatal("genGoDirs:", err)
+	}
+	pos := make(map[string]int)
+	for i, name := range order {
+		pos[name] = i
+	}
+	if len(pos) != 5 || pos["d"] > pos["b"] || pos["d"] > pos["c"] || pos["b"] > pos["a"] || pos["c"] > pos["a"] {
+		t.Fatal("genGoDirs: wrong order", order)
+	}
+	for _, name := range order {
+		if !isGenerated(root, name) {
+			t.Fatal("genGoDirs: not generated", name)
+		}
+	}
+}
+
+func TestGenGoDirsCancel(t *testing.T) {
+	files := make(map[string]string)
+	for name, src := range diamondFiles {
+		files[name] = src
+	}
+	files["d/d.gop"] = strings.Replace(files["d/d.gop"], "return 1", `return "1"`, 1)
+	root := writeModule(t, files)
+	order, err := genDiamond(t, root)
+	if err == nil || !strings.Contains(err.Error(), "d.gop") {
+		t.Fatal("genGoDirs:", err)
+	}
+	if strings.Contains(err.Error(), "b.gop") || strings.Contains(err.Error(), "a.gop") {
+		t.Fatal("genGoDirs: dependents are compiled:", err)
+	}
+	if len(order) != 5 {
+		t.Fatal("genGoDirs: not all finished", order)
+	}
+	for _, name := range []string{"a", "b", "c", "d"} {
+		if isGenerated(root, name) {
+			t.Fatal("genGoDirs: generated", name)
+		}
+	}
+	if !isGenerated(root, "e") {
+		t.Fatal("genGoDirs: independent package e isn't generated")
+	}
+}
@@ -8,6 +8,8 @@ require (
 	github.com/goplus/gox v1.13.1-0.20240115155941-e657d899cb2e
 	github.com/goplus/mod v0.12.2-0.20240107203906-5044606d0c51
 	github.com/qiniu/x v1.13.2
+	golang.org/x/mod v0.14.0
+	golang.org/x/sys v0.16.0
 	golang.org/x/tools v0.17.0
 )
 
@@ -15,8 +17,6 @@ require (
 	github.com/json-iterator/go v1.1.12 // indirect
 	github.com/modern-go/concurrent v0.0.0-20180228061459-e0a39a4cb421 // indirect
 	github.com/modern-go/reflect2 v1.0.2 // indirect
-	golang.org/x/mod v0.14.0 // indirect
-	golang.org/x/sys v0.16.0 // indirect
 )
 
 retract v1.1.12
@@ -17,13 +17,17 @@
 package gop
 
 import (
+	goparser "go/parser"
 	"go/token"
 	"go/types"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/goplus/gop/x/classfile"
+	"github.com/goplus/gop/x/gocmd"
 	"github.com/goplus/gox/packages"
 	"github.com/goplus/mod/env"
 	"github.com/goplus/mod/gopmod"
@@ -38,6 +42,7 @@ type Importer struct {
 	gop     *env.Gop
 	fset    *token.FileSet
 	flags   GenFlags
+	checked map[string]error // dir => error of generating Go code
 }
 
 func NewImporter(mod *gopmod.Module, gop *env.Gop, fset *token.FileSet) *Importer {
@@ -52,7 +57,10 @@ func NewImporter(mod *gopmod.Module, gop *env.Gop, fset *token.FileSet) *Importe
 		dir = mod.Root()
 	}
 	impFrom := packages.NewImporter(fset, dir)
-	return &Importer{mod: mod, gop: gop, impFrom: impFrom, fset: fset, flags: defaultFlags}
+	return &Importer{
+		mod: mod, gop: gop, impFrom: impFrom, fset: fset, flags: defaultFlags,
+		checked: make(map[string]error),
+	}
 }
 
 func (p *Importer) Import(pkgPath string) (pkg *types.Package, err error) {
@@ -102,9 +110,20 @@ func (p *Importer) Import(pkgPath string) (pkg *types.Package, err error) {
 	return p.impFrom.Import(pkgPath)
 }
 
+// genGoExtern generates Go code of the Go+ package in dir if it doesn't
+// exist or is stale (see isStale). Every dir is checked only once.
 func (p *Importer) genGoExtern(dir string, isExtern bool) (err error) {
+	if err, ok := p.checked[dir]; ok {
+		return err
+	}
+	p.checked[dir] = nil // break import cycles
+	defer func() {
+		p.checked[dir] = err
+	}()
 	genfile := filepath.Join(dir, autoGenFile)
-	if _, err = os.Lstat(genfile); err != nil { // no gop_autogen.go
+	fi, err := os.Lstat(genfile)
+	if err != nil || p.isStale(dir, fi.ModTime()) {
+		noGenfile := err != nil
 		if isExtern {
 			os.Chmod(dir, modWritable)
 			defer os.Chmod(dir, modReadonly)
@@ -114,8 +133,8 @@ func (p *Importer) genGoExtern(dir string, isExtern bool) (err error) {
 		if err != nil {
 			return
 		}
-		if gen {
-			cmd := exec.Command("go", "mod", "tidy")
+		if gen && noGenfile {
+			cmd := gocmd.Command("mod", "tidy")
 			cmd.Stdout = os.Stdout
 			cmd.Stderr = os.Stderr
 			cmd.Dir = dir
@@ -125,6 +144,51 @@ func (p *Importer) genGoExtern(dir string, isExtern bool) (err error) {
 	return
 }
 
+// isStale reports whether the generated Go code of the Go+ package in dir,
+// which was modified at genTime, is older than its Go+ source files, or than
+// generated Go code of local packages it imports. Stale imported packages are
+// regenerated first, so that changes propagate transitively.
+func (p *Importer) isStale(dir string, genTime time.Time) bool {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return true
+	}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		if ext := filepath.Ext(e.Name()); ext != ".gop" && ext != ".gox" && !p.mod.IsClass(ext) {
+			if _, ok := classfile.Lookup(ext); !ok {
+				continue
+			}
+		}
+		if fi, err := e.Info(); err != nil || fi.ModTime().After(genTime) {
+			return true
+		}
+	}
+	if !hasModfile(p.mod) {
+		return false
+	}
+	f, err := goparser.ParseFile(token.NewFileSet(), filepath.Join(dir, autoGenFile), nil, goparser.ImportsOnly)
+	if err != nil {
+		return true
+	}
+	for _, spec := range f.Imports {
+		pkgPath, _ := strconv.Unquote(spec.Path.Value)
+		ret, err := p.mod.Lookup(pkgPath)
+		if err != nil || (ret.Type != gopmod.PkgtModule && ret.Type != gopmod.PkgtLocal) {
+			continue
+		}
+		if p.genGoExtern(ret.Dir, false) != nil {
+			return true
+		}
+		if fi, err := os.Lstat(filepath.Join(ret.Dir, autoGenFile)); err == nil && fi.ModTime().After(genTime) {
+			return true
+		}
+	}
+	return false
+}
+
 func defaultGoMod(modPath string) []byte {
 	return []byte(`module ` + modPath + `
 
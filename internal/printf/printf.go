@@ -0,0 +1,85 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package printf provides the format string parser shared by the printf
+// check of the Go+ compiler and the printf analyzer of “gop vet”.
+package printf
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// -----------------------------------------------------------------------------
+
+// Funcs maps printf-style functions to the index of their format argument.
+// The printf, sprintf, errorf and fprintf builtins resolve to their fmt
+// counterparts.
+var Funcs = map[string]int{
+	"fmt.Printf":  0,
+	"fmt.Sprintf": 0,
+	"fmt.Errorf":  0,
+	"fmt.Fprintf": 1,
+	"log.Printf":  0,
+	"log.Fatalf":  0,
+	"log.Panicf":  0,
+}
+
+// Verbs lists the verbs known by package fmt.
+const Verbs = "bcdeEfFgGopqstTUvxXw"
+
+// NextDirective finds the next directive of format s. It returns the rest of
+// s after the directive, the directive itself (like "%-5.2f") and the number
+// of '*' in it.
+func NextDirective(s string) (rest, verb string, nstar int, ok bool) {
+	start := strings.IndexByte(s, '%')
+	if start < 0 {
+		return
+	}
+	i := start + 1
+	for i < len(s) && strings.IndexByte("+-# 0", s[i]) >= 0 {
+		i++
+	}
+	for i < len(s) && (s[i] >= '0' && s[i] <= '9' || s[i] == '.' || s[i] == '*' || s[i] == '[' || s[i] == ']') {
+		if s[i] == '*' {
+			nstar++
+		}
+		i++
+	}
+	if i >= len(s) {
+		return "", s[start:], nstar, true
+	}
+	_, size := utf8.DecodeRuneInString(s[i:])
+	i += size
+	return s[i:], s[start:i], nstar, true
+}
+
+// Complete reports whether the directive verb, as returned by NextDirective,
+// ends with a verb (a directive at the end of a format may not).
+func Complete(verb string) bool {
+	return len(verb) > 1 && strings.IndexByte("+-# 0123456789.*[]", verb[len(verb)-1]) < 0
+}
+
+// CountArgs returns n as a number of arguments (eg. "1 arg", "2 args").
+func CountArgs(n int) string {
+	if n == 1 {
+		return "1 arg"
+	}
+	return strconv.Itoa(n) + " args"
+}
+
+// -----------------------------------------------------------------------------
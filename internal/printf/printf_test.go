@@ -0,0 +1,51 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package printf
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNextDirective(t *testing.T) {
+	var verbs []string
+	var nstars int
+	for s := "%d, %-5.2f %% %*[2]x %s 100%"; ; {
+		rest, verb, nstar, ok := NextDirective(s)
+		if !ok {
+			break
+		}
+		s, verbs, nstars = rest, append(verbs, verb), nstars+nstar
+	}
+	if ret := strings.Join(verbs, " "); ret != "%d %-5.2f %% %*[2]x %s %" || nstars != 1 {
+		t.Fatal("NextDirective:", ret, nstars)
+	}
+}
+
+func TestComplete(t *testing.T) {
+	for verb, ok := range map[string]bool{"%d": true, "%-5.2f": true, "%%": true, "%": false, "%5.": false, "%*": false} {
+		if Complete(verb) != ok {
+			t.Fatal("Complete:", verb)
+		}
+	}
+}
+
+func TestCountArgs(t *testing.T) {
+	if CountArgs(1) != "1 arg" || CountArgs(0) != "0 args" || CountArgs(2) != "2 args" {
+		t.Fatal("CountArgs")
+	}
+}
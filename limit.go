@@ -0,0 +1,109 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gop
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"time"
+)
+
+// -----------------------------------------------------------------------------
+
+// memCheckInterval is the interval to check memory usage of compiling.
+const memCheckInterval = 50 * time.Millisecond
+
+// limiter is a context that is done when a resource limit of compiling is
+// exceeded.
+type limiter struct {
+	context.Context
+	done chan struct{}
+	stop chan struct{}
+	err  error
+}
+
+// newLimiter returns a context to bound compiling by conf.CompileTimeout and
+// conf.CompileMemLimit. It returns nil if there is no limit.
+func newLimiter(conf *Config) (ctx context.Context, stop func()) {
+	timeout, memLimit := conf.CompileTimeout, conf.CompileMemLimit
+	if timeout <= 0 && memLimit == 0 {
+		return nil, func() {}
+	}
+	p := &limiter{
+		Context: context.Background(),
+		done:    make(chan struct{}),
+		stop:    make(chan struct{}),
+	}
+	go p.watch(timeout, memLimit)
+	return p, func() { close(p.stop) }
+}
+
+func (p *limiter) watch(timeout time.Duration, memLimit uint64) {
+	var timer, ticker <-chan time.Time
+	if timeout > 0 {
+		t := time.NewTimer(timeout)
+		defer t.Stop()
+		timer = t.C
+	}
+	var base uint64
+	if memLimit > 0 {
+		t := time.NewTicker(memCheckInterval)
+		defer t.Stop()
+		ticker, base = t.C, heapAlloc()
+	}
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-timer:
+			p.cancel(fmt.Errorf("time limit %v exceeded", timeout))
+			return
+		case <-ticker:
+			if used := heapAlloc(); used > base && used-base > memLimit {
+				p.cancel(fmt.Errorf("memory limit %dMB exceeded", memLimit>>20))
+				return
+			}
+		}
+	}
+}
+
+func (p *limiter) cancel(err error) {
+	p.err = err
+	close(p.done)
+}
+
+func (p *limiter) Done() <-chan struct{} {
+	return p.done
+}
+
+func (p *limiter) Err() error {
+	select {
+	case <-p.done:
+		return p.err
+	default:
+		return nil
+	}
+}
+
+func heapAlloc() uint64 {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	return ms.HeapAlloc
+}
+
+// -----------------------------------------------------------------------------
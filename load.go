@@ -19,16 +19,22 @@ package gop
 import (
 	"fmt"
 	"go/types"
+	"io"
 	"io/fs"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/goplus/gop/ast"
 	"github.com/goplus/gop/cl"
 	"github.com/goplus/gop/parser"
+	"github.com/goplus/gop/parser/fsx"
 	"github.com/goplus/gop/token"
 	"github.com/goplus/gop/x/c2go"
+	"github.com/goplus/gop/x/classfile"
 	"github.com/goplus/gop/x/gopenv"
 	"github.com/goplus/gox"
 	"github.com/goplus/mod/env"
@@ -54,6 +60,8 @@ func IgnoreNotated(err error) bool {
 // ErrorPos returns where the error occurs.
 func ErrorPos(err error) token.Pos {
 	switch v := err.(type) {
+	case *cl.Diagnostic:
+		return v.Pos
 	case *gox.CodeError:
 		return v.Pos
 	case *gox.MatchError:
@@ -121,7 +129,77 @@ type Config struct {
 	Filter   func(fs.FileInfo) bool
 	Importer types.Importer
 
+	// Backend specifies the output stage of GenGo (optional).
+	// Default is cl.GoBackend.
+	Backend cl.Backend
+
 	IgnoreNotatedError bool
+
+	// GenDir, if not empty, is the directory to generate Go code into by
+	// RunDir and RunFiles, instead of the source directory. Generated files
+	// are passed to the go command by -overlay (see gocmd.Config.Overlay).
+	GenDir string
+
+	// Strict = true means to report compiler warnings as errors.
+	Strict bool
+
+	// OnWarning is called for each compiler warning if Strict is false (optional).
+	OnWarning func(err error)
+
+	// Lang is the language version, eg. gop1.0 (see cl.Config.Lang).
+	Lang string
+
+	// Trace = true means to generate code to print each executed statement
+	// (see cl.Config.Trace).
+	Trace bool
+
+	// AbsFileLine = true means to use absolute file names in //line
+	// directives of generated Go code (see cl.Config.AbsFileLine).
+	AbsFileLine bool
+
+	// NoFileLine = true means not to generate //line directives, so that the
+	// generated Go code reads as ordinary Go code (see cl.Config.NoFileLine).
+	NoFileLine bool
+
+	// KeepComments = true means to carry comments of function bodies through
+	// to the generated Go code (see cl.Config.KeepComments).
+	KeepComments bool
+
+	// Profile is the directory to write profiles of the program into, if it
+	// isn't empty (see cl.Config.Profile).
+	Profile string
+
+	// CompileTimeout limits time of compiling a package (optional).
+	CompileTimeout time.Duration
+
+	// CompileMemLimit limits heap growth (in bytes) of compiling a package
+	// (optional). Note it's measured in the whole process.
+	CompileMemLimit uint64
+
+	// OnCompiled is called after a package (a directory or files in it) is
+	// loaded and compiled (optional). See gop/x/stats.Hook.
+	OnCompiled func(dir string, dur time.Duration, err error)
+
+	// OnProgress is called after a package is compiled by GenGo and its
+	// variants, with the number of packages done and the total number of
+	// packages to compile (optional). See gop/x/progress.Hook.
+	OnProgress func(dir string, done, total int)
+
+	// Parallel is the maximum number of packages to compile concurrently when
+	// GenGo compiles directories recursively, eg. `gop go ./...`. Default is
+	// runtime.GOMAXPROCS(0). OnCompiled, OnWarning and OnCrash may be called
+	// concurrently unless it's 1. Packages are compiled one by one if Importer
+	// is set.
+	Parallel int
+
+	// OnCrash is called if the compiler panics when compiling the package in
+	// dir, or files in dir if files isn't nil (optional). See
+	// gop/x/crash.Hook.
+	OnCrash func(dir string, files []string, err *cl.InternalError)
+
+	// FS specifies the file system to read source files from (optional).
+	// Default is the local file system.
+	FS parser.FileSystem
 }
 
 func LoadMod(dir string) (mod *gopmod.Module, err error) {
@@ -131,6 +209,9 @@ func LoadMod(dir string) (mod *gopmod.Module, err error) {
 		return
 	}
 	if mod != nil {
+		if err = CheckToolchain(mod); err != nil {
+			return
+		}
 		err = mod.ImportClasses()
 		if err != nil {
 			err = errors.NewWith(err, `mod.RegisterClasses()`, -2, "(*gopmod.Module).RegisterClasses", mod)
@@ -156,12 +237,18 @@ func LoadDir(dir string, conf *Config, genTestPkg bool, promptGenGo ...bool) (ou
 	if conf == nil {
 		conf = new(Config)
 	}
+	if conf.OnCompiled != nil {
+		start := time.Now()
+		defer func() {
+			conf.OnCompiled(dir, time.Since(start), err)
+		}()
+	}
 	fset := conf.Fset
 	if fset == nil {
 		fset = token.NewFileSet()
 	}
-	pkgs, err := parser.ParseDirEx(fset, dir, parser.Config{
-		ClassKind: mod.ClassKind,
+	pkgs, err := parser.ParseFSDir(fset, fsOf(conf), dir, parser.Config{
+		ClassKind: classfile.KindOf(mod.ClassKind),
 		Filter:    conf.Filter,
 		Mode:      parser.ParseComments | parser.SaveAbsFile,
 	})
@@ -186,9 +273,20 @@ func LoadDir(dir string, conf *Config, genTestPkg bool, promptGenGo ...bool) (ou
 		Fset:         fset,
 		RelativeBase: relativeBaseOf(mod),
 		Importer:     imp,
-		LookupClass:  mod.LookupClass,
+		LookupClass:  classfile.LookupOf(mod.LookupClass),
 		LookupPub:    c2go.LookupPub(mod),
+		Strict:       conf.Strict,
+		OnWarning:    conf.OnWarning,
+		Trace:        conf.Trace,
+		Profile:      conf.Profile,
+		Lang:         conf.Lang,
+		AbsFileLine:  conf.AbsFileLine,
+		NoFileLine:   conf.NoFileLine,
+		KeepComments: conf.KeepComments,
 	}
+	limit, stop := newLimiter(conf)
+	defer stop()
+	clConf.Context = limit
 
 	for name, pkg := range pkgs {
 		if strings.HasSuffix(name, "_test") {
@@ -209,6 +307,7 @@ func LoadDir(dir string, conf *Config, genTestPkg bool, promptGenGo ...bool) (ou
 		}
 		out, err = cl.NewPackage("", pkg, clConf)
 		if err != nil {
+			onCrash(conf, dir, nil, err)
 			if conf.IgnoreNotatedError {
 				err = ignNotatedErrs(err, pkg, fset)
 			}
@@ -220,10 +319,27 @@ func LoadDir(dir string, conf *Config, genTestPkg bool, promptGenGo ...bool) (ou
 	}
 	if pkgTest != nil && genTestPkg {
 		test, err = cl.NewPackage("", pkgTest, clConf)
+		onCrash(conf, dir, nil, err)
 	}
 	return
 }
 
+func fsOf(conf *Config) parser.FileSystem {
+	if conf.FS != nil {
+		return conf.FS
+	}
+	return fsx.Local
+}
+
+// onCrash calls conf.OnCrash if err is an internal error of the compiler.
+func onCrash(conf *Config, dir string, files []string, err error) {
+	if conf.OnCrash != nil && err != nil {
+		if ie, ok := cl.InternalErrorOf(err); ok {
+			conf.OnCrash(dir, files, ie)
+		}
+	}
+}
+
 func relativeBaseOf(mod *gopmod.Module) string {
 	if hasModfile(mod) {
 		return mod.Root()
@@ -235,6 +351,12 @@ func relativeBaseOf(mod *gopmod.Module) string {
 // -----------------------------------------------------------------------------
 
 func LoadFiles(dir string, files []string, conf *Config) (out *gox.Package, err error) {
+	return loadFiles(dir, files, nil, conf)
+}
+
+// loadFiles is like LoadFiles, but reads the only file from src if it isn't
+// nil (see parser.ParseReader).
+func loadFiles(dir string, files []string, src io.Reader, conf *Config) (out *gox.Package, err error) {
 	mod, err := LoadMod(dir)
 	if err != nil {
 		err = errors.NewWith(err, `LoadMod(dir)`, -2, "gop.LoadMod", dir)
@@ -244,17 +366,28 @@ func LoadFiles(dir string, files []string, conf *Config) (out *gox.Package, err
 	if conf == nil {
 		conf = new(Config)
 	}
+	if conf.OnCompiled != nil {
+		start := time.Now()
+		defer func() {
+			conf.OnCompiled(dir, time.Since(start), err)
+		}()
+	}
 	fset := conf.Fset
 	if fset == nil {
 		fset = token.NewFileSet()
 	}
-	pkgs, err := parser.ParseFiles(fset, files, parser.ParseComments|parser.SaveAbsFile)
+	var pkgs map[string]*ast.Package
+	if src != nil {
+		pkgs, err = parser.ParseReader(fset, files[0], src, parser.ParseComments|parser.SaveAbsFile)
+	} else {
+		pkgs, err = parser.ParseFSFiles(fset, fsOf(conf), files, parser.ParseComments|parser.SaveAbsFile)
+	}
 	if err != nil {
 		err = errors.NewWith(err, `parser.ParseFiles(fset, files, parser.ParseComments)`, -2, "parser.ParseFiles", fset, files, parser.ParseComments)
 		return
 	}
 	if len(pkgs) != 1 {
-		err = errors.NewWith(ErrMultiPackges, `len(pkgs) != 1`, -1, "!=", len(pkgs), 1)
+		err = multiPkgsErr(pkgs)
 		return
 	}
 	gop := conf.Gop
@@ -270,11 +403,25 @@ func LoadFiles(dir string, files []string, conf *Config) (out *gox.Package, err
 			Fset:         fset,
 			RelativeBase: relativeBaseOf(mod),
 			Importer:     imp,
-			LookupClass:  mod.LookupClass,
+			LookupClass:  classfile.LookupOf(mod.LookupClass),
 			LookupPub:    c2go.LookupPub(mod),
+			Strict:       conf.Strict,
+			OnWarning:    conf.OnWarning,
+			Trace:        conf.Trace,
+			Profile:      conf.Profile,
+			Lang:         conf.Lang,
+			AbsFileLine:  conf.AbsFileLine,
+			NoFileLine:   conf.NoFileLine,
+			KeepComments: conf.KeepComments,
 		}
+		limit, stop := newLimiter(conf)
+		defer stop()
+		clConf.Context = limit
 		out, err = cl.NewPackage("", pkg, clConf)
 		if err != nil {
+			if src == nil {
+				onCrash(conf, dir, files, err)
+			}
 			if conf.IgnoreNotatedError {
 				err = ignNotatedErrs(err, pkg, fset)
 			}
@@ -286,6 +433,30 @@ func LoadFiles(dir string, files []string, conf *Config) (out *gox.Package, err
 
 // -----------------------------------------------------------------------------
 
+// multiPkgsErr returns ErrMultiPackges with packages and their files, like
+// `go run` does for files of different packages, eg. "multiple packages: foo
+// (a.gop), main (b.gop, c.go)".
+func multiPkgsErr(pkgs map[string]*ast.Package) error {
+	names := make([]string, 0, len(pkgs))
+	for name := range pkgs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		pkg := pkgs[name]
+		files := make([]string, 0, len(pkg.Files)+len(pkg.GoFiles))
+		for file := range pkg.Files {
+			files = append(files, filepath.Base(file))
+		}
+		for file := range pkg.GoFiles {
+			files = append(files, filepath.Base(file))
+		}
+		sort.Strings(files)
+		names[i] = name + " (" + strings.Join(files, ", ") + ")"
+	}
+	return fmt.Errorf("%w: %s", ErrMultiPackges, strings.Join(names, ", "))
+}
+
 var (
 	ErrMultiPackges     = errors.New("multiple packages")
 	ErrMultiTestPackges = errors.New("multiple test packages")
@@ -28,6 +28,7 @@ import (
 	"github.com/goplus/gop/cl/outline"
 	"github.com/goplus/gop/parser"
 	"github.com/goplus/gop/x/c2go"
+	"github.com/goplus/gop/x/classfile"
 	"github.com/goplus/gop/x/gopenv"
 	"github.com/goplus/mod/gopmod"
 )
@@ -62,7 +63,7 @@ func Outline(dir string, conf *Config) (out outline.Package, err error) {
 		fset = token.NewFileSet()
 	}
 	pkgs, err := parser.ParseDirEx(fset, dir, parser.Config{
-		ClassKind: mod.ClassKind,
+		ClassKind: classfile.KindOf(mod.ClassKind),
 		Filter:    filter,
 		Mode:      parser.ParseComments,
 	})
@@ -96,7 +97,7 @@ func Outline(dir string, conf *Config) (out outline.Package, err error) {
 		out, err = outline.NewPackage(pkgPath, pkg, &outline.Config{
 			Fset:        fset,
 			Importer:    imp,
-			LookupClass: mod.LookupClass,
+			LookupClass: classfile.LookupOf(mod.LookupClass),
 			LookupPub:   c2go.LookupPub(mod),
 		})
 		if err != nil {
@@ -0,0 +1,320 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package parser
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"sort"
+
+	"github.com/goplus/gop/ast"
+	"github.com/goplus/gop/token"
+)
+
+// -----------------------------------------------------------------------------
+
+// A TextEdit replaces bytes [Start, End) of a source file with NewText.
+type TextEdit struct {
+	Start, End int
+	NewText    []byte
+}
+
+var errInvalidEdits = errors.New("parser: edits are out of range or overlapped")
+
+// ParseFileIncremental re-parses the file old after changes are applied to
+// its source (old.Code), and returns the new AST, whose Code is the new
+// source. Offsets of changes are relative to the old source, and changes
+// must not overlap. mode must be the mode old was parsed with.
+//
+// Top-level declarations whose lines are not touched by changes are reused
+// instead of being scanned and parsed again: the new source is parsed with
+// them blanked out, and they are moved into the new AST with positions
+// shifted, so old must not be used after the call. A script (whose
+// statements are parsed as a whole) or a file with syntax errors after
+// changes is parsed fully.
+func ParseFileIncremental(fset *token.FileSet, old *ast.File, changes []TextEdit, mode Mode) (f *ast.File, err error) {
+	edits := append([]TextEdit(nil), changes...)
+	sort.SliceStable(edits, func(i, j int) bool {
+		return edits[i].Start < edits[j].Start
+	})
+	src, end := old.Code, 0
+	for _, e := range edits {
+		if e.Start < end || e.End < e.Start || e.End > len(src) {
+			return nil, errInvalidEdits
+		}
+		end = e.End
+	}
+	newSrc := make([]byte, 0, len(src))
+	end = 0
+	for _, e := range edits {
+		newSrc = append(append(newSrc, src[end:e.Start]...), e.NewText...)
+		end = e.End
+	}
+	newSrc = append(newSrc, src[end:]...)
+
+	tf := fset.File(old.Name.Pos())
+	if tf == nil || !isIncremental(old) {
+		filename := ""
+		if tf != nil {
+			filename = tf.Name()
+		}
+		return parseFile(fset, filename, newSrc, mode)
+	}
+	filename := tf.Name()
+	regions := reusableRegions(tf, old, edits)
+	if len(regions) == 0 {
+		return parseFile(fset, filename, newSrc, mode)
+	}
+	blanked := append([]byte(nil), newSrc...)
+	for _, r := range regions {
+		for i := r.start + r.delta; i < r.end+r.delta; i++ {
+			if blanked[i] != '\n' {
+				blanked[i] = ' '
+			}
+		}
+	}
+	f, err = parseFile(fset, filename, blanked, mode)
+	if err != nil || !isIncremental(f) {
+		return parseFile(fset, filename, newSrc, mode)
+	}
+	f.Code = newSrc
+	mergeRegions(fset.File(f.Name.Pos()), f, tf, old, regions)
+	return
+}
+
+// isIncremental reports whether f can be re-parsed incrementally, ie. its
+// top-level declarations can be parsed independently.
+func isIncremental(f *ast.File) bool {
+	if f.ShadowEntry != nil || f.IsClass {
+		return false
+	}
+	for _, decl := range f.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Shadow {
+			return false
+		}
+	}
+	return true
+}
+
+// A region is lines of the old source occupied by a reusable declaration
+// (with its doc and line comments), which are moved by delta bytes in the
+// new source.
+type region struct {
+	start, end int
+	delta      int
+	decl       ast.Decl
+	comments   []*ast.CommentGroup
+}
+
+// reusableRegions returns regions of declarations of old which don't share
+// lines with other declarations, and are not touched by edits.
+func reusableRegions(tf *token.File, old *ast.File, edits []TextEdit) (ret []*region) {
+	src := old.Code
+	offset := func(pos token.Pos) int {
+		return tf.Offset(pos)
+	}
+	startOf := func(decl ast.Decl) int {
+		pos := decl.Pos()
+		if doc := declDoc(decl); doc != nil {
+			pos = doc.Pos()
+		}
+		return offset(pos)
+	}
+	prevEnd := 0
+	if old.Name.End().IsValid() && old.Package.IsValid() {
+		prevEnd = offset(old.Name.End())
+	}
+	for i, decl := range old.Decls {
+		start, end := startOf(decl), offset(decl.End())
+		lineStart := bytes.LastIndexByte(src[:start], '\n') + 1
+		lineEnd := len(src)
+		if n := bytes.IndexByte(src[end:], '\n'); n >= 0 {
+			lineEnd = end + n
+		}
+		shared := prevEnd > lineStart || i+1 < len(old.Decls) && startOf(old.Decls[i+1]) < lineEnd
+		prevEnd = end
+		if shared {
+			continue
+		}
+		delta, touched := 0, false
+		for _, e := range edits {
+			if e.Start <= lineEnd && e.End >= lineStart {
+				touched = true
+				break
+			}
+			if e.End < lineStart {
+				delta += len(e.NewText) - (e.End - e.Start)
+			}
+		}
+		if !touched {
+			ret = append(ret, &region{start: lineStart, end: lineEnd, delta: delta, decl: decl})
+		}
+	}
+	if len(ret) > 0 {
+		for _, cg := range old.Comments {
+			pos := offset(cg.Pos())
+			i := sort.Search(len(ret), func(i int) bool { return ret[i].end > pos })
+			if i < len(ret) && ret[i].start <= pos {
+				ret[i].comments = append(ret[i].comments, cg)
+			}
+		}
+	}
+	return
+}
+
+func declDoc(decl ast.Decl) *ast.CommentGroup {
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		return d.Doc
+	case *ast.GenDecl:
+		return d.Doc
+	case *ast.OverloadFuncDecl:
+		return d.Doc
+	}
+	return nil
+}
+
+// mergeRegions moves declarations and comments of regions of old into f,
+// which is parsed from the new source with the regions blanked out. tf and
+// oldTF are token files of f and old.
+func mergeRegions(tf *token.File, f *ast.File, oldTF *token.File, old *ast.File, regions []*region) {
+	// objects declared by reused declarations are moved into the new scope
+	inRegions := func(pos token.Pos) bool {
+		offset := oldTF.Offset(pos)
+		i := sort.Search(len(regions), func(i int) bool { return regions[i].end > offset })
+		return i < len(regions) && regions[i].start <= offset
+	}
+	var objs []*ast.Object
+	for _, obj := range old.Scope.Objects {
+		if n, ok := obj.Decl.(ast.Node); ok && n.Pos().IsValid() && inRegions(n.Pos()) {
+			objs = append(objs, obj)
+		}
+	}
+	sort.Slice(objs, func(i, j int) bool { // makes redeclarations deterministic
+		return objs[i].Pos() < objs[j].Pos()
+	})
+	for _, obj := range objs {
+		if f.Scope.Lookup(obj.Name) == nil {
+			f.Scope.Insert(obj)
+		}
+	}
+
+	s := &shifter{seen: make(map[uintptr]bool)}
+	for _, r := range regions {
+		s.delta = token.Pos(tf.Base() - oldTF.Base() + r.delta)
+		s.walk(reflect.ValueOf(r.decl))
+		for _, cg := range r.comments {
+			for _, c := range cg.List {
+				c.Slash += s.delta
+			}
+		}
+		f.Decls = append(f.Decls, r.decl)
+		f.Comments = append(f.Comments, r.comments...)
+	}
+	sort.SliceStable(f.Decls, func(i, j int) bool {
+		return f.Decls[i].Pos() < f.Decls[j].Pos()
+	})
+	sort.SliceStable(f.Comments, func(i, j int) bool {
+		return f.Comments[i].Pos() < f.Comments[j].Pos()
+	})
+	f.Imports = nil
+	for _, decl := range f.Decls {
+		if d, ok := decl.(*ast.GenDecl); ok && d.Tok == token.IMPORT {
+			for _, spec := range d.Specs {
+				f.Imports = append(f.Imports, spec.(*ast.ImportSpec))
+			}
+		}
+	}
+
+	// identifiers referring to package-level objects are resolved again
+	unresolved := make([]*ast.Ident, 0, len(f.Unresolved))
+	for _, id := range f.Unresolved {
+		if id.Obj = f.Scope.Lookup(id.Name); id.Obj == nil {
+			unresolved = append(unresolved, id)
+		}
+	}
+	oldUnresolved := make(map[*ast.Ident]bool, len(old.Unresolved))
+	for _, id := range old.Unresolved {
+		oldUnresolved[id] = true
+	}
+	for _, id := range s.idents {
+		if id.Obj != nil && old.Scope.Objects[id.Name] == id.Obj || id.Obj == nil && oldUnresolved[id] {
+			if id.Obj = f.Scope.Lookup(id.Name); id.Obj == nil {
+				unresolved = append(unresolved, id)
+			}
+		}
+	}
+	sort.SliceStable(unresolved, func(i, j int) bool {
+		return unresolved[i].Pos() < unresolved[j].Pos()
+	})
+	f.Unresolved = unresolved
+}
+
+var (
+	typePos          = reflect.TypeOf(token.NoPos)
+	typeObject       = reflect.TypeOf((*ast.Object)(nil))
+	typeScope        = reflect.TypeOf((*ast.Scope)(nil))
+	typeCommentGroup = reflect.TypeOf((*ast.CommentGroup)(nil))
+)
+
+// A shifter shifts positions of nodes by delta. Comments are shifted
+// separately, as they are shared by nodes and the list of comments of a file.
+type shifter struct {
+	delta  token.Pos
+	seen   map[uintptr]bool
+	idents []*ast.Ident
+}
+
+func (p *shifter) walk(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() || p.seen[v.Pointer()] {
+			return
+		}
+		switch v.Type() {
+		case typeObject, typeScope, typeCommentGroup:
+			return
+		}
+		p.seen[v.Pointer()] = true
+		if id, ok := v.Interface().(*ast.Ident); ok {
+			p.idents = append(p.idents, id)
+		}
+		p.walk(v.Elem())
+	case reflect.Interface:
+		if !v.IsNil() {
+			p.walk(v.Elem())
+		}
+	case reflect.Slice:
+		for i, n := 0, v.Len(); i < n; i++ {
+			p.walk(v.Index(i))
+		}
+	case reflect.Struct:
+		for i, n := 0, v.NumField(); i < n; i++ {
+			field := v.Field(i)
+			if field.Type() == typePos {
+				if pos := token.Pos(field.Int()); pos.IsValid() && field.CanSet() {
+					field.SetInt(int64(pos + p.delta))
+				}
+				continue
+			}
+			p.walk(field)
+		}
+	}
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,176 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package parser
+
+import (
+	"bytes"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/goplus/gop/ast"
+	"github.com/goplus/gop/token"
+)
+
+// -----------------------------------------------------------------------------
+
+const incrementalSrc = `// Package foo is a test.
+package foo
+
+import "fmt"
+
+// T is a type.
+type T struct {
+	Name string // name of T
+}
+
+// Hello says hello.
+func (t *T) Hello() {
+	fmt.Println("hello", t.Name)
+}
+
+var x, y = 1, 2 // line comment
+
+func add(a, b int) int {
+	return a + b
+}
+
+func sub(a, b int) int { return a - b }; const c = 1
+
+func use() int {
+	return add(x, y) + c
+}
+`
+
+// dumpFile prints f for comparison. Scopes are omitted as their maps are
+// printed in random order.
+func dumpFile(fset *token.FileSet, f *ast.File) string {
+	var b bytes.Buffer
+	ast.Fprint(&b, fset, f, func(name string, v reflect.Value) bool {
+		return name != "Scope" && name != "Code" && ast.NotNilFilter(name, v)
+	})
+	var scope []string
+	for name, obj := range f.Scope.Objects {
+		scope = append(scope, name+":"+fset.Position(obj.Pos()).String())
+	}
+	var unresolved []string
+	for _, id := range f.Unresolved {
+		unresolved = append(unresolved, id.Name+":"+fset.Position(id.Pos()).String())
+	}
+	sort.Strings(scope)
+	sort.Strings(unresolved)
+	return b.String() + strings.Join(scope, "\n") + "\n" + strings.Join(unresolved, "\n")
+}
+
+func testIncremental(t *testing.T, src string, edits []TextEdit, reused ...string) {
+	t.Helper()
+	fset := token.NewFileSet()
+	old, err := ParseFile(fset, "foo.gop", src, ParseComments)
+	if err != nil {
+		t.Fatal("ParseFile:", err)
+	}
+	oldDecls := make(map[ast.Decl]bool)
+	for _, decl := range old.Decls {
+		oldDecls[decl] = true
+	}
+	f, err := ParseFileIncremental(fset, old, edits, ParseComments)
+	if err != nil {
+		t.Fatal("ParseFileIncremental:", err)
+	}
+	fset2 := token.NewFileSet()
+	expected, err := ParseFile(fset2, "foo.gop", f.Code, ParseComments)
+	if err != nil {
+		t.Fatal("ParseFile:", err)
+	}
+	if ret, want := dumpFile(fset, f), dumpFile(fset2, expected); ret != want {
+		t.Fatalf("ParseFileIncremental:\n%s\nexpected:\n%s", ret, want)
+	}
+	var names []string
+	for _, decl := range f.Decls {
+		if oldDecls[decl] {
+			names = append(names, declName(decl))
+		}
+	}
+	if ret := strings.Join(names, " "); ret != strings.Join(reused, " ") {
+		t.Fatal("reused:", ret)
+	}
+}
+
+func declName(decl ast.Decl) string {
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		return d.Name.Name
+	case *ast.GenDecl:
+		switch s := d.Specs[0].(type) {
+		case *ast.TypeSpec:
+			return s.Name.Name
+		case *ast.ValueSpec:
+			return s.Names[0].Name
+		case *ast.ImportSpec:
+			return s.Path.Value
+		}
+	}
+	return "?"
+}
+
+func editOf(src, old, new string) TextEdit {
+	start := strings.Index(src, old)
+	return TextEdit{Start: start, End: start + len(old), NewText: []byte(new)}
+}
+
+func TestIncrementalBody(t *testing.T) {
+	testIncremental(t, incrementalSrc, []TextEdit{
+		editOf(incrementalSrc, "a + b", "a + b + 1"),
+	}, `"fmt"`, "T", "Hello", "x", "use")
+}
+
+func TestIncrementalDecls(t *testing.T) {
+	src := incrementalSrc
+	testIncremental(t, src, []TextEdit{
+		editOf(src, "// Hello says hello.\n", ""),
+		editOf(src, "func add(a, b int) int {\n\treturn a + b\n}\n", "func add(a, b int) int {\n\treturn a * b\n}\n\nvar z = add\n"),
+		editOf(src, "\treturn add(x, y) + c\n", "\treturn add(x, y)\n"),
+	}, `"fmt"`, "T", "x")
+}
+
+func TestIncrementalRename(t *testing.T) {
+	// identifiers in reused declarations refer to the renamed one
+	src := incrementalSrc
+	testIncremental(t, src, []TextEdit{
+		editOf(src, "var x, y = 1, 2", "var x, z = 1, 2"),
+	}, `"fmt"`, "T", "Hello", "add", "use")
+}
+
+func TestIncrementalFallback(t *testing.T) {
+	script := "import \"fmt\"\n\nfunc f() {\n}\n\nfmt.Println(1)\n"
+	testIncremental(t, script, []TextEdit{editOf(script, "1", "2")})
+	testIncremental(t, incrementalSrc, []TextEdit{editOf(incrementalSrc, "package foo", "package bar")},
+		`"fmt"`, "T", "Hello", "x", "add", "use")
+
+	fset := token.NewFileSet()
+	old, _ := ParseFile(fset, "foo.gop", incrementalSrc, ParseComments)
+	_, err := ParseFileIncremental(fset, old, []TextEdit{editOf(incrementalSrc, "a + b\n", "a +\n")}, ParseComments)
+	if err == nil {
+		t.Fatal("ParseFileIncremental: no syntax error")
+	}
+	if _, err = ParseFileIncremental(fset, old, []TextEdit{{Start: 3, End: 2}}, ParseComments); err != errInvalidEdits {
+		t.Fatal("ParseFileIncremental:", err)
+	}
+}
+
+// -----------------------------------------------------------------------------
@@ -93,6 +93,8 @@ type parser struct {
 	// (maintained by open/close LabelScope)
 	labelScope  *ast.Scope     // label scope for current function
 	targetStack [][]*ast.Ident // stack of unresolved labels
+
+	features map[string]bool // enabled features of experimental syntax
 }
 
 func (p *parser) init(fset *token.FileSet, filename string, src []byte, mode Mode) {
@@ -103,6 +105,7 @@ func (p *parser) init(fset *token.FileSet, filename string, src []byte, mode Mod
 	}
 	eh := func(pos token.Position, msg string) { p.errors.Add(pos, msg) }
 	p.scanner.Init(p.file, src, eh, m)
+	p.initPragmas(src)
 
 	p.mode = mode
 	p.trace = mode&Trace != 0 // for convenience (p.trace is used frequently)
@@ -368,6 +371,10 @@ func (p *parser) next() {
 
 		// consume successor comments, if any
 		endline = -1
+		if prev == token.NoPos && p.file.Offset(p.pos) == 0 && strings.HasPrefix(p.lit, "#!") {
+			// the shebang line is a comment group of its own, never a doc comment
+			p.consumeCommentGroup(0)
+		}
 		for p.tok == token.COMMENT {
 			comment, endline = p.consumeCommentGroup(1)
 		}
@@ -428,11 +435,20 @@ func (p *parser) expect(tok token.Token) token.Pos {
 	pos := p.pos
 	if p.tok != tok {
 		p.errorExpected(pos, "'"+tok.String()+"'", 3)
+		if p.tok == token.SEMICOLON && p.lit == "\n" && closing[tok] {
+			return pos // the statement ends here, don't lose the next line
+		}
 	}
 	p.next() // make progress
 	return pos
 }
 
+var closing = map[token.Token]bool{
+	token.RPAREN: true,
+	token.RBRACK: true,
+	token.RBRACE: true,
+}
+
 // expect2 is like expect, but it returns an invalid position
 // if the expected token is not found.
 func (p *parser) expect2(tok token.Token) (pos token.Pos) {
@@ -467,7 +483,7 @@ func (p *parser) expectSemi() {
 			p.next()
 		default:
 			p.errorExpected(p.pos, "';'", 3)
-			p.advance(stmtStart)
+			p.advance(stmtEnd)
 		}
 	}
 }
@@ -527,6 +543,25 @@ func (p *parser) advance(to map[token.Token]bool) {
 	}
 }
 
+// stmtEnd is used to synchronize at the end of a statement, that is usually
+// the end of a line in Go+, or the start of the next one, so that statements
+// in following lines are parsed after an error.
+var stmtEnd = withStmtStart(token.SEMICOLON, token.RPAREN, token.RBRACE)
+
+// exprStop is like stmtEnd, but also stops at tokens closing an operand.
+var exprStop = withStmtStart(token.SEMICOLON, token.COMMA, token.RPAREN, token.RBRACK, token.RBRACE)
+
+func withStmtStart(toks ...token.Token) map[token.Token]bool {
+	ret := make(map[token.Token]bool, len(stmtStart)+len(toks))
+	for tok := range stmtStart {
+		ret[tok] = true
+	}
+	for _, tok := range toks {
+		ret[tok] = true
+	}
+	return ret
+}
+
 var stmtStart = map[token.Token]bool{
 	token.BREAK:       true,
 	token.CONST:       true,
@@ -1533,6 +1568,9 @@ func (p *parser) parseStmtList() (list []ast.Stmt) {
 	}
 
 	for p.tok != token.CASE && p.tok != token.DEFAULT && p.tok != token.RBRACE && p.tok != token.EOF {
+		if p.tok == token.IDENT && p.lit == "after" && p.isAfterClause() { // Go+: after clause of select
+			break
+		}
 		list = append(list, p.parseStmt(true))
 	}
 
@@ -1606,7 +1644,7 @@ func (p *parser) parseOperand(lhs, allowTuple, allowCmd bool) (x ast.Expr, isTup
 		}
 		return
 
-	case token.STRING, token.CSTRING, token.INT, token.FLOAT, token.IMAG, token.CHAR, token.RAT:
+	case token.STRING, token.CSTRING, token.INT, token.FLOAT, token.IMAG, token.CHAR, token.RAT, token.DURATION:
 		x = &ast.BasicLit{ValuePos: p.pos, Kind: p.tok, Value: p.lit}
 		if debugParseOutput {
 			log.Printf("ast.BasicLit{Kind: %v, Value: %v}\n", p.tok, p.lit)
@@ -1689,7 +1727,7 @@ func (p *parser) parseOperand(lhs, allowTuple, allowCmd bool) (x ast.Expr, isTup
 	// we have an error
 	pos := p.pos
 	p.errorExpected(pos, "operand", 2)
-	p.advance(stmtStart)
+	p.advance(exprStop)
 	return &ast.BadExpr{From: pos, To: p.pos}, false
 }
 
@@ -2212,7 +2250,7 @@ func (p *parser) isCmd(x ast.Expr) bool {
 func (p *parser) checkCmd(x ast.Expr) bool {
 	switch p.tok {
 	case token.IDENT, token.RARROW,
-		token.STRING, token.CSTRING, token.INT, token.FLOAT, token.IMAG, token.CHAR, token.RAT,
+		token.STRING, token.CSTRING, token.INT, token.FLOAT, token.IMAG, token.CHAR, token.RAT, token.DURATION,
 		token.FUNC, token.GOTO, token.MAP, token.INTERFACE, token.CHAN, token.STRUCT:
 		return true
 	case token.SUB, token.AND, token.MUL, token.ARROW, token.XOR, token.ADD:
@@ -2332,6 +2370,9 @@ func (p *parser) parseBinaryExpr(lhs bool, prec1 int, allowTuple, allowCmd bool)
 			return
 		}
 		pos := p.expect(op)
+		if op == token.PIPE {
+			p.checkFeature(pos, "pipeline")
+		}
 		if lhs {
 			p.resolve(x)
 			lhs = false
@@ -2972,6 +3013,39 @@ func (p *parser) parseSwitchStmt() ast.Stmt {
 	return &ast.SwitchStmt{Switch: pos, Init: s1, Tag: p.makeExpr(s2, "switch expression"), Body: body}
 }
 
+// isAfterClause reports whether the current token "after" starts an after
+// clause of a select statement, ie. it's followed by an expression and a
+// colon, which is never a valid statement.
+func (p *parser) isAfterClause() bool {
+	if p.old.pos != 0 {
+		return false
+	}
+	s := p.scanner // scan ahead by a copy of the scanner
+	depth, n, prev := 0, 0, token.ILLEGAL
+	for {
+		_, tok, _ := s.Scan()
+		switch tok {
+		case token.COMMENT:
+			continue
+		case token.LPAREN, token.LBRACK, token.LBRACE:
+			depth++
+		case token.RPAREN, token.RBRACK, token.RBRACE:
+			if depth--; depth < 0 {
+				return false
+			}
+		case token.COLON:
+			if depth == 0 {
+				return n > 0 && prev != token.QUESTION // not `after x?:y`
+			}
+		case token.SEMICOLON, token.EOF:
+			if depth == 0 {
+				return false
+			}
+		}
+		n, prev = n+1, tok
+	}
+}
+
 func (p *parser) parseCommClause() *ast.CommClause {
 	if p.trace {
 		defer un(trace(p, "CommClause"))
@@ -2980,7 +3054,12 @@ func (p *parser) parseCommClause() *ast.CommClause {
 	p.openScope()
 	pos := p.pos
 	var comm ast.Stmt
-	if p.tok == token.CASE {
+	var after ast.Expr
+	if p.tok == token.IDENT && p.lit == "after" {
+		// after timeout:
+		p.next()
+		after = p.parseRHS()
+	} else if p.tok == token.CASE {
 		p.next()
 		lhs := p.parseLHSList(false)
 		if p.tok == token.ARROW {
@@ -3027,7 +3106,7 @@ func (p *parser) parseCommClause() *ast.CommClause {
 	body := p.parseStmtList()
 	p.closeScope()
 
-	return &ast.CommClause{Case: pos, Comm: comm, Colon: colon, Body: body}
+	return &ast.CommClause{Case: pos, Comm: comm, After: after, Colon: colon, Body: body}
 }
 
 func (p *parser) parseSelectStmt() *ast.SelectStmt {
@@ -3038,7 +3117,7 @@ func (p *parser) parseSelectStmt() *ast.SelectStmt {
 	pos := p.expect(token.SELECT)
 	lbrace := p.expect(token.LBRACE)
 	var list []ast.Stmt
-	for p.tok == token.CASE || p.tok == token.DEFAULT {
+	for p.tok == token.CASE || p.tok == token.DEFAULT || p.tok == token.IDENT && p.lit == "after" {
 		list = append(list, p.parseCommClause())
 	}
 	rbrace := p.expect(token.RBRACE)
@@ -3222,7 +3301,7 @@ func (p *parser) parseStmt(allowCmd bool) (s ast.Stmt) {
 		s = &ast.DeclStmt{Decl: p.parseGenDecl(p.tok, p.parseValueSpec)}
 	case
 		// tokens that may start an expression
-		token.INT, token.FLOAT, token.IMAG, token.RAT, token.CHAR, token.STRING, token.CSTRING, token.FUNC, token.LPAREN, // operands
+		token.INT, token.FLOAT, token.IMAG, token.RAT, token.DURATION, token.CHAR, token.STRING, token.CSTRING, token.FUNC, token.LPAREN, // operands
 		token.ADD, token.SUB, token.MUL, token.AND, token.XOR, token.ARROW, token.NOT, // unary operators
 		token.LBRACK, token.STRUCT, token.CHAN, token.INTERFACE: // composite types
 		allowCmd = false
@@ -3688,6 +3767,8 @@ func (p *parser) parseDecl(sync map[token.Token]bool) ast.Decl {
 	var f parseSpecFunction
 	pos := p.pos
 	switch p.tok {
+	case token.AT:
+		return p.parseAnnotatedDecl(sync)
 	case token.CONST, token.VAR:
 		f = p.parseValueSpec
 	case token.TYPE:
@@ -3695,9 +3776,6 @@ func (p *parser) parseDecl(sync map[token.Token]bool) ast.Decl {
 	case token.FUNC:
 		decl, call := p.parseFuncDeclOrCall()
 		if decl != nil {
-			if p.errors.Len() != 0 {
-				p.advance(sync)
-			}
 			return decl
 		}
 		return p.parseGlobalStmts(sync, pos, &ast.ExprStmt{X: call})
@@ -3707,6 +3785,77 @@ func (p *parser) parseDecl(sync map[token.Token]bool) ast.Decl {
 	return p.parseGenDecl(p.tok, f)
 }
 
+// parseAnnotatedDecl parses a declaration with annotations:
+//
+//	@name
+//	@name(args)
+//	decl
+func (p *parser) parseAnnotatedDecl(sync map[token.Token]bool) ast.Decl {
+	if p.trace {
+		defer un(trace(p, "AnnotatedDecl"))
+	}
+	doc := p.leadComment
+	var annots []*ast.Annotation
+	for p.tok == token.AT {
+		annots = append(annots, p.parseAnnotation())
+	}
+	pos := p.pos
+	switch p.tok {
+	case token.CONST, token.VAR, token.TYPE, token.FUNC:
+		switch d := p.parseDecl(sync).(type) {
+		case *ast.GenDecl:
+			if d.Doc == nil {
+				d.Doc = doc
+			}
+			d.Annots = annots
+			return d
+		case *ast.FuncDecl:
+			if !d.Shadow {
+				if d.Doc == nil {
+					d.Doc = doc
+				}
+				d.Annots = annots
+				return d
+			}
+		}
+	}
+	p.errorExpected(pos, "declaration after annotations", 2)
+	p.advance(sync)
+	return &ast.BadDecl{From: annots[0].Pos(), To: p.pos}
+}
+
+func (p *parser) parseAnnotation() *ast.Annotation {
+	if p.trace {
+		defer un(trace(p, "Annotation"))
+	}
+	at := p.expect(token.AT)
+	annot := &ast.Annotation{At: at, Name: p.parseIdent()}
+	if p.tok == token.LPAREN {
+		annot.Lparen = p.pos
+		p.next()
+		p.exprLev++
+		for p.tok != token.RPAREN && p.tok != token.EOF {
+			arg := p.parseRHS()
+			if p.tok == token.COLON { // named argument: `name: value`
+				colon := p.pos
+				p.next()
+				arg = &ast.KeyValueExpr{Key: arg, Colon: colon, Value: p.parseRHS()}
+			}
+			annot.Args = append(annot.Args, arg)
+			if !p.atComma("annotation arguments", token.RPAREN) {
+				break
+			}
+			p.next()
+		}
+		p.exprLev--
+		annot.Rparen = p.expectClosing(token.RPAREN, "annotation arguments")
+	}
+	if p.tok == token.SEMICOLON && p.lit == "\n" {
+		p.next()
+	}
+	return annot
+}
+
 func (p *parser) parseGlobalStmts(sync map[token.Token]bool, pos token.Pos, stmts ...ast.Stmt) *ast.FuncDecl {
 	p.topScope = ast.NewScope(p.topScope)
 	doc := p.leadComment
@@ -31,6 +31,7 @@ import (
 	"github.com/goplus/gop/ast"
 	"github.com/goplus/gop/parser/fsx"
 	"github.com/goplus/gop/token"
+	"github.com/goplus/gop/x/classfile"
 )
 
 const (
@@ -106,6 +107,11 @@ func ParseEntry(fset *token.FileSet, filename string, src interface{}, conf Conf
 // directory specified by path and returns a map of package name -> package
 // AST with all the packages found.
 //
+// Go files in the directory are parsed by go/parser into GoFiles of their
+// packages, unless conf.Mode has ParseGoAsGoPlus. Go+ files without package
+// clauses, which are in package main by default, are in the package of the Go
+// files instead, so that a Go package can be migrated to Go+ file by file.
+//
 // If filter != nil, only the files with fs.FileInfo entries passing through
 // the filter (and ending in ".gop") are considered. The mode bits are passed
 // to ParseFile unchanged. Position information is recorded in fset, which
@@ -189,9 +195,44 @@ func ParseFSDir(fset *token.FileSet, fs FileSystem, dir string, conf Config) (pk
 			}
 		}
 	}
+	adoptGoPkg(pkgs)
 	return
 }
 
+// adoptGoPkg moves Go+ files without package clauses from package main to the
+// package of Go files, if Go files (excluding external tests) are all in one
+// package other than main.
+func adoptGoPkg(pkgs map[string]*ast.Package) {
+	main, ok := pkgs["main"]
+	if !ok {
+		return
+	}
+	name := ""
+	for n, pkg := range pkgs {
+		if len(pkg.GoFiles) == 0 {
+			continue
+		}
+		if n = strings.TrimSuffix(n, "_test"); name != "" && name != n {
+			return
+		}
+		name = n
+	}
+	if name == "" || name == "main" {
+		return
+	}
+	pkg := reqPkg(pkgs, name)
+	for file, f := range main.Files {
+		if f.NoPkgDecl {
+			f.Name.Name = name
+			pkg.Files[file] = f
+			delete(main.Files, file)
+		}
+	}
+	if len(main.Files) == 0 && len(main.GoFiles) == 0 {
+		delete(pkgs, "main")
+	}
+}
+
 // ParseFSEntry parses the source code of a single Go+ source file and returns the corresponding ast.File node.
 // Compared to ParseFSFile, ParseFSEntry detects fileKind by its filename.
 func ParseFSEntry(fset *token.FileSet, fs FileSystem, filename string, src interface{}, conf Config) (f *ast.File, err error) {
@@ -252,7 +293,7 @@ func defaultClassKind(fname string) (isProj bool, ok bool) {
 	case ".spx":
 		return fname == "main.spx", true
 	}
-	return
+	return classfile.Kind(fname)
 }
 
 // -----------------------------------------------------------------------------
@@ -264,6 +305,9 @@ func ParseFiles(fset *token.FileSet, files []string, mode Mode) (map[string]*ast
 func ParseFSFiles(fset *token.FileSet, fs FileSystem, files []string, mode Mode) (map[string]*ast.Package, error) {
 	ret := map[string]*ast.Package{}
 	for _, file := range files {
+		if mode&SaveAbsFile != 0 {
+			file, _ = fs.Abs(file)
+		}
 		f, err := ParseFSFile(fset, fs, file, nil, mode)
 		if err != nil {
 			return nil, err
@@ -279,6 +323,22 @@ func ParseFSFiles(fset *token.FileSet, fs FileSystem, files []string, mode Mode)
 	return ret, nil
 }
 
+// ParseReader parses Go+ source read from r as a package of a single file.
+// The filename needn't exist: it's only used when recording position
+// information. It is designed for sources not in the file system, eg. stdin.
+func ParseReader(fset *token.FileSet, filename string, r io.Reader, mode Mode) (map[string]*ast.Package, error) {
+	if mode&SaveAbsFile != 0 {
+		filename, _ = fsx.Local.Abs(filename)
+	}
+	f, err := ParseFile(fset, filename, r, mode)
+	if err != nil {
+		return nil, err
+	}
+	pkgName := f.Name.Name
+	pkg := &ast.Package{Name: pkgName, Files: map[string]*ast.File{filename: f}}
+	return map[string]*ast.Package{pkgName: pkg}, nil
+}
+
 // -----------------------------------------------------------------------------
 
 // ParseFile parses the source code of a single Go+ source file and returns the corresponding ast.File node.
@@ -18,9 +18,12 @@ package parser
 
 import (
 	"io/fs"
+	"strings"
 	"testing"
 
+	"github.com/goplus/gop/env"
 	"github.com/goplus/gop/parser/parsertest"
+	"github.com/goplus/gop/scanner"
 	"github.com/goplus/gop/token"
 	fsx "github.com/qiniu/x/http/fs"
 )
@@ -108,6 +111,30 @@ func TestErrTuple(t *testing.T) {
 	testErrCode(t, `func test() (int,int) { return (100,100)`, `/foo/bar.gop:1:32: tuple is not supported`, ``)
 }
 
+func TestErrAnnotation(t *testing.T) {
+	testErrCode(t, `@memoize
+x := 1
+`, `/foo/bar.gop:2:1: expected declaration after annotations, found x`, ``)
+	testErrCode(t, `@route("/users"
+func f() {}
+`, `/foo/bar.gop:1:16: missing ',' before newline in annotation arguments (and 1 more errors)`, ``)
+}
+
+func TestErrPragma(t *testing.T) {
+	testErrCode(t, `x := 1 |> println`, `/foo/bar.gop:1:8: pipeline operator |> is experimental, enable it by //gop:enable pipeline`, ``)
+	testErrCode(t, `//gop:enable pipeline match
+x := 1
+`, `/foo/bar.gop:1:1: unknown feature in //gop:enable: match`, ``)
+}
+
+func TestExperimentPipeline(t *testing.T) {
+	t.Setenv(env.EnvExperiment, "pipeline")
+	fset := token.NewFileSet()
+	if _, err := Parse(fset, "/foo/bar.gop", `x := 1 |> println`, 0); err != nil {
+		t.Fatal("Parse:", err)
+	}
+}
+
 func TestErrOperand(t *testing.T) {
 	testErrCode(t, `a :=`, `/foo/bar.gop:1:5: expected operand, found 'EOF'`, ``)
 }
@@ -181,17 +208,52 @@ func h() { var }
 func TestErrInFunc(t *testing.T) {
 	testErrCode(t, `func test() {
 	a,
-}`, `/foo/bar.gop:2:2: expected 1 expression (and 2 more errors)`, ``)
+}`, `/foo/bar.gop:2:2: expected 1 expression (and 1 more errors)`, ``)
 	testErrCode(t, `func test() {
 	a.test, => {
 	}
-}`, `/foo/bar.gop:2:10: expected operand, found '=>' (and 1 more errors)`, ``)
+}`, `/foo/bar.gop:2:10: expected operand, found '=>'`, ``)
 	testErrCode(t, `func test() {
 		,
 	}
 }`, `/foo/bar.gop:2:3: expected statement, found ',' (and 1 more errors)`, ``)
 }
 
+func TestErrRecovery(t *testing.T) {
+	fset := token.NewFileSet()
+	_, err := Parse(fset, "/foo/bar.gop", `func f() {
+	if x {
+		y := ]
+	}
+	z := (2
+	println z
+}
+
+a := [1, 2
+println a
+var w = ]
+f(1,
+	2
+)
+`, 0)
+	errs, ok := err.(scanner.ErrorList)
+	if !ok {
+		t.Fatal("Parse:", err)
+	}
+	var b strings.Builder
+	for _, e := range errs {
+		b.WriteString(e.Error() + "\n")
+	}
+	if ret := b.String(); ret != `/foo/bar.gop:3:8: expected operand, found ']'
+/foo/bar.gop:5:9: expected ')', found newline
+/foo/bar.gop:9:11: expected ']', found newline
+/foo/bar.gop:11:9: expected operand, found ']'
+/foo/bar.gop:13:3: missing ',' before newline in argument list
+` {
+		t.Fatal("Parse:", ret)
+	}
+}
+
 // -----------------------------------------------------------------------------
 
 var testStdCode = `package bar; import "io"
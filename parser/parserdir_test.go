@@ -20,11 +20,13 @@ import (
 	"bytes"
 	"os"
 	"path"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"syscall"
 	"testing"
 
+	"github.com/goplus/gop/ast"
 	"github.com/goplus/gop/parser/fsx/memfs"
 	"github.com/goplus/gop/parser/parsertest"
 	"github.com/goplus/gop/scanner"
@@ -73,6 +75,39 @@ func TestParseFiles(t *testing.T) {
 	}
 }
 
+func TestParseReader(t *testing.T) {
+	fset := token.NewFileSet()
+	pkgs, err := ParseReader(fset, "stdin.gop", strings.NewReader(`println "hi"`), SaveAbsFile)
+	if err != nil {
+		t.Fatal("ParseReader failed:", err)
+	}
+	abs, _ := filepath.Abs("stdin.gop")
+	if pkg, ok := pkgs["main"]; !ok || len(pkg.Files) != 1 || pkg.Files[abs] == nil {
+		t.Fatal("ParseReader:", pkgs)
+	}
+	if _, err = ParseReader(fset, "stdin.gop", strings.NewReader("func {"), 0); err == nil {
+		t.Fatal("ParseReader: no error?")
+	}
+}
+
+func TestShebang(t *testing.T) {
+	fset := token.NewFileSet()
+	f, err := ParseFile(fset, "hello.gop", "#!/usr/bin/env gop\n// Hello is a doc\nfunc Hello() {}\n", ParseComments)
+	if err != nil {
+		t.Fatal("ParseFile failed:", err)
+	}
+	if len(f.Comments) != 2 || len(f.Comments[0].List) != 1 || f.Comments[0].List[0].Text != "#!/usr/bin/env gop" {
+		t.Fatal("shebang:", f.Comments)
+	}
+	if doc := f.Decls[0].(*ast.FuncDecl).Doc; doc == nil || doc.Text() != "Hello is a doc\n" {
+		t.Fatal("doc:", doc)
+	}
+	f, err = ParseFile(fset, "hello.gop", "#!/usr/bin/env gop\nfunc Hello() {}\n", ParseComments)
+	if err != nil || f.Decls[0].(*ast.FuncDecl).Doc != nil {
+		t.Fatal("shebang is a doc:", err)
+	}
+}
+
 func TestIparseFileInvalidSrc(t *testing.T) {
 	fset := token.NewFileSet()
 	if _, err := parseFile(fset, "/foo/bar/not-exists", 1, PackageClauseOnly); err != errInvalidSource {
@@ -245,6 +280,22 @@ func TestSaveAbsFile(t *testing.T) {
 			t.Fatal("ParseEntry functype.gop:", f.IsClass, f.IsProj, f.IsNormalGox)
 		}
 	})
+	t.Run("files", func(t *testing.T) {
+		pkgs, err := ParseFiles(fset, []string{"./_testdata/functype/functype.go"}, conf.Mode|ParseGoAsGoPlus)
+		if err != nil {
+			t.Fatal("ParseFiles failed:", err)
+		}
+		pkg := pkgs["main"]
+		if pkg == nil || len(pkg.Files) != 1 {
+			t.Fatal("ParseFiles:", pkgs)
+		}
+		for _, f := range pkg.Files {
+			file := fset.Position(f.Pos()).Filename
+			if pkg.Files[file] != f || !filepath.IsAbs(file) {
+				t.Fatal("ParseFiles: file not saved by absolute path -", file)
+			}
+		}
+	})
 	t.Run("dir", func(t *testing.T) {
 		_, err := ParseDirEx(fset, "./_nofmt/cmdlinestyle1", conf)
 		if err != nil {
@@ -277,6 +328,44 @@ func TestGoFile(t *testing.T) {
 	}
 }
 
+func TestMixedGoFiles(t *testing.T) {
+	fset := token.NewFileSet()
+	fs := memfs.New(map[string][]string{
+		"/foo": {"a.go", "b.gop", "c.gop", "foo_test.go"},
+	}, map[string]string{
+		"/foo/a.go":        "package foo\n\nfunc A() {}",
+		"/foo/b.gop":       "func B() { A() }",
+		"/foo/c.gop":       "package foo\n\nfunc C() { B() }",
+		"/foo/foo_test.go": "package foo_test",
+	})
+	pkgs, err := ParseFSDir(fset, fs, "/foo", Config{})
+	if err != nil {
+		t.Fatal("ParseFSDir:", err)
+	}
+	foo := pkgs["foo"]
+	if len(pkgs) != 2 || foo == nil || len(foo.Files) != 2 || len(foo.GoFiles) != 1 {
+		t.Fatal("TestMixedGoFiles:", pkgs)
+	}
+	if f := foo.Files["/foo/b.gop"]; f == nil || f.Name.Name != "foo" {
+		t.Fatal("TestMixedGoFiles: b.gop isn't in package foo")
+	}
+
+	fs = memfs.New(map[string][]string{
+		"/foo": {"a.go", "b.gop", "c.go"},
+	}, map[string]string{
+		"/foo/a.go":  "package foo",
+		"/foo/b.gop": "func B() {}",
+		"/foo/c.go":  "package bar",
+	})
+	pkgs, err = ParseFSDir(fset, fs, "/foo", Config{})
+	if err != nil {
+		t.Fatal("ParseFSDir:", err)
+	}
+	if len(pkgs) != 3 || pkgs["main"] == nil {
+		t.Fatal("TestMixedGoFiles: Go files of multiple packages:", pkgs)
+	}
+}
+
 func TestErrParse(t *testing.T) {
 	fset := token.NewFileSet()
 	fs := memfs.SingleFile("/foo", "test.go", `package foo bar`)
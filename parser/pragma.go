@@ -0,0 +1,96 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package parser
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+
+	"github.com/goplus/gop/env"
+	"github.com/goplus/gop/token"
+)
+
+// -----------------------------------------------------------------------------
+
+// experiments are features of experimental syntax. A file enables them by
+// pragmas at the beginning of a line:
+//
+//	//gop:enable pipeline
+//
+// They are also experiments of GOPEXPERIMENT, which enables them for all
+// files.
+var experiments = map[string]string{
+	"pipeline": "pipeline operator |>", // x |> f(args) means f(x, args)
+}
+
+func init() {
+	for name, what := range experiments {
+		env.RegisterExperiment(&env.Experiment{Name: name, Doc: "enable the " + what + " in all files"})
+	}
+}
+
+// Experiments returns names of features of experimental syntax, which a file
+// can enable by //gop:enable pragmas.
+func Experiments() []string {
+	names := make([]string, 0, len(experiments))
+	for name := range experiments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+const pragmaEnable = "//gop:enable "
+
+// initPragmas enables features of experimental syntax by //gop:enable
+// pragmas in src.
+func (p *parser) initPragmas(src []byte) {
+	for offs := 0; offs < len(src); {
+		line := src[offs:]
+		end := bytes.IndexByte(line, '\n')
+		if end >= 0 {
+			line = line[:end]
+		}
+		if bytes.HasPrefix(line, []byte(pragmaEnable)) {
+			for _, name := range strings.Fields(string(line[len(pragmaEnable):])) {
+				if _, ok := experiments[name]; !ok {
+					p.error(p.file.Pos(offs), "unknown feature in //gop:enable: "+name)
+					continue
+				}
+				if p.features == nil {
+					p.features = make(map[string]bool)
+				}
+				p.features[name] = true
+			}
+		}
+		if end < 0 {
+			break
+		}
+		offs += end + 1
+	}
+}
+
+// checkFeature reports an error at pos if the feature of experimental syntax
+// isn't enabled by a pragma or GOPEXPERIMENT.
+func (p *parser) checkFeature(pos token.Pos, name string) {
+	if !p.features[name] && !env.ExperimentEnabled(name) {
+		p.error(pos, experiments[name]+" is experimental, enable it by //gop:enable "+name)
+	}
+}
+
+// -----------------------------------------------------------------------------
@@ -1029,15 +1029,29 @@ func (p *printer) expr1(expr ast.Expr, prec1, depth int) {
 		p.print(mode, x.Rbrack, token.RBRACK, mode)
 
 	case *ast.ComprehensionExpr:
+		// a long comprehension can be wrapped after "[" or "{"
+		wrapped := x.Lpos.IsValid() && p.lineFor(x.Fors[0].For) > p.lineFor(x.Lpos)
+		if x.Elt != nil {
+			wrapped = x.Lpos.IsValid() && p.lineFor(x.Elt.Pos()) > p.lineFor(x.Lpos)
+		}
 		switch x.Tok {
 		case token.LBRACK: // [...]
 			p.print(token.LBRACK)
+			if wrapped {
+				p.print(indent, newline)
+			}
 			p.expr0(x.Elt, depth+1)
 			p.print(blank)
 			p.listForPhrase(x.Lpos, x.Fors, depth, x.Rpos)
-			p.print(token.RBRACK)
+			if wrapped {
+				p.print(unindent)
+			}
+			p.print(x.Rpos, token.RBRACK)
 		default: // {...}
 			p.print(token.LBRACE)
+			if wrapped {
+				p.print(indent, newline)
+			}
 			if x.Elt != nil {
 				if elt, ok := x.Elt.(*ast.KeyValueExpr); ok {
 					p.expr0(elt.Key, depth+1)
@@ -1049,7 +1063,10 @@ func (p *printer) expr1(expr ast.Expr, prec1, depth int) {
 				p.print(blank)
 			}
 			p.listForPhrase(x.Lpos, x.Fors, depth, x.Rpos)
-			p.print(token.RBRACE)
+			if wrapped {
+				p.print(unindent)
+			}
+			p.print(x.Rpos, token.RBRACE)
 		}
 	case *ast.ErrWrapExpr:
 		p.expr(x.X)
@@ -1475,6 +1492,9 @@ func (p *printer) stmt(stmt ast.Stmt, nextIsRBrace bool) {
 		if s.Comm != nil {
 			p.print(token.CASE, blank)
 			p.stmt(s.Comm, false)
+		} else if s.After != nil {
+			p.print(&ast.Ident{Name: "after"}, blank)
+			p.expr(s.After)
 		} else {
 			p.print(token.DEFAULT)
 		}
@@ -1733,8 +1753,22 @@ func (p *printer) spec(spec ast.Spec, n int, doIndent bool) {
 	}
 }
 
+func (p *printer) annotations(list []*ast.Annotation) {
+	for _, a := range list {
+		p.print(a.At, token.AT)
+		p.expr(a.Name)
+		if a.Lparen.IsValid() {
+			p.print(a.Lparen, token.LPAREN)
+			p.exprList(a.Lparen, a.Args, 1, commaTerm, a.Rparen, false)
+			p.print(a.Rparen, token.RPAREN)
+		}
+		p.print(newline)
+	}
+}
+
 func (p *printer) genDecl(d *ast.GenDecl) {
 	p.setComment(d.Doc)
+	p.annotations(d.Annots)
 	p.setPos(d.Pos())
 	p.print(d.Tok, blank)
 
@@ -1968,6 +2002,7 @@ func (p *printer) funcDecl(d *ast.FuncDecl) {
 		log.Println("==> Format Func", d.Name.Name)
 	}
 	p.setComment(d.Doc)
+	p.annotations(d.Annots)
 
 	if p.shadowEntry == d {
 		p.funcBodyUnnamed(0, vtab, d.Body)
@@ -388,6 +388,10 @@ func (p *printer) writeCommentPrefix(pos, next token.Position, prev *ast.Comment
 					p.wsbuf[i] = ignore
 					continue
 				case vtab:
+					if p.Config.Mode&NoAlignComments != 0 {
+						p.wsbuf[i] = ignore
+						continue
+					}
 					// respect existing tabs - important
 					// for proper formatting of commented structs
 					hasSep = true
@@ -404,7 +408,7 @@ func (p *printer) writeCommentPrefix(pos, next token.Position, prev *ast.Comment
 		// make sure there is at least one separator
 		if !hasSep {
 			sep := byte('\t')
-			if pos.Line == next.Line {
+			if pos.Line == next.Line || p.Config.Mode&NoAlignComments != 0 {
 				// next item is on the same line as the comment
 				// (which must be a /*-style comment): separate
 				// with a blank instead of a tab
@@ -1283,10 +1287,11 @@ func (p *trimmer) Write(data []byte) (n int, err error) {
 type Mode uint
 
 const (
-	RawFormat Mode = 1 << iota // do not use a tabwriter; if set, UseSpaces is ignored
-	TabIndent                  // use tabs for indentation independent of UseSpaces
-	UseSpaces                  // use spaces instead of tabs for alignment
-	SourcePos                  // emit //line directives to preserve original source positions
+	RawFormat       Mode = 1 << iota // do not use a tabwriter; if set, UseSpaces is ignored
+	TabIndent                        // use tabs for indentation independent of UseSpaces
+	UseSpaces                        // use spaces instead of tabs for alignment
+	SourcePos                        // emit //line directives to preserve original source positions
+	NoAlignComments                  // don't align trailing comments of consecutive lines
 )
 
 // A Config node controls the output of Fprint.
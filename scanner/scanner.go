@@ -26,6 +26,8 @@ import (
 	"go/scanner"
 	"path/filepath"
 	"strconv"
+	"strings"
+	"time"
 	"unicode"
 	"unicode/utf8"
 
@@ -505,6 +507,8 @@ func (s *Scanner) scanNumber() (token.Token, string) {
 	} else if s.ch == 'r' {
 		tok = token.RAT
 		s.next()
+	} else if isDurationUnit(s.ch) && (prefix == 0 || prefix == '0') {
+		tok = token.DURATION
 	}
 
 	lit := string(s.src[offs:s.offset])
@@ -516,10 +520,34 @@ func (s *Scanner) scanNumber() (token.Token, string) {
 			s.error(offs+i, "'_' must separate successive digits")
 		}
 	}
+	if tok == token.DURATION {
+		lit = s.scanDuration(offs)
+	}
 
 	return tok, lit
 }
 
+func isDurationUnit(ch rune) bool {
+	switch ch {
+	case 'n', 'u', 'µ', 'm', 's', 'h':
+		return true
+	}
+	return false
+}
+
+// scanDuration scans the rest of a duration literal starting at offs, eg.
+// 1h30m, 1.5s, 100ms. Units are those of time.ParseDuration.
+func (s *Scanner) scanDuration(offs int) string {
+	for isLetter(s.ch) || isDecimal(s.ch) || s.ch == '.' && isDecimal(rune(s.peek())) {
+		s.next()
+	}
+	lit := string(s.src[offs:s.offset])
+	if _, err := time.ParseDuration(strings.ReplaceAll(lit, "_", "")); err != nil {
+		s.errorf(offs, "invalid duration literal %s", lit)
+	}
+	return lit
+}
+
 func litname(prefix rune) string {
 	switch prefix {
 	case 'x':
@@ -977,10 +1005,17 @@ scanAgain:
 				tok = s.switch3(token.AND, token.AND_ASSIGN, '&', token.LAND)
 			}
 		case '|':
-			tok = s.switch3(token.OR, token.OR_ASSIGN, '|', token.LOR)
+			if s.ch == '>' {
+				s.next()
+				tok = token.PIPE
+			} else {
+				tok = s.switch3(token.OR, token.OR_ASSIGN, '|', token.LOR)
+			}
 		case '?':
 			tok = token.QUESTION
 			insertSemi = true
+		case '@':
+			tok = token.AT
 		default:
 			// next reports unexpected BOMs - don't repeat
 			if ch != bom {
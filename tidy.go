@@ -17,9 +17,15 @@
 package gop
 
 import (
+	"bytes"
+	"fmt"
+	"io/fs"
 	"os"
-	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
 
+	"github.com/goplus/gop/x/gocmd"
 	"github.com/goplus/mod/env"
 	"github.com/goplus/mod/gopmod"
 	"github.com/qiniu/x/errors"
@@ -60,13 +66,29 @@ func Tidy(dir string, gop *env.Gop) (err error) {
 			return errors.NewWith(err, `modObj.Save()`, -2, "(*gopmod.Module).Save")
 		}
 	*/
+	// Go+ files can't be compiled before modules they import are required, so
+	// let `go mod tidy` see their imports first.
+	if imports := externImports(modObj); len(imports) > 0 {
+		pkgDir, e := writeTidyImports(modRoot, imports)
+		if e != nil {
+			return errors.NewWith(e, `writeTidyImports(modRoot, imports)`, -2, "gop.writeTidyImports", modRoot, imports)
+		}
+		defer os.RemoveAll(pkgDir)
+		if err = goModTidy(modRoot); err != nil {
+			return
+		}
+	}
+
 	conf := &Config{Gop: gop}
 	err = genGoDir(modRoot, conf, true, true, 0)
 	if err != nil {
 		return errors.NewWith(err, `genGoDir(modRoot, conf, true, true)`, -2, "gop.genGoDir", modRoot, conf, true, true)
 	}
+	return goModTidy(modRoot)
+}
 
-	cmd := exec.Command("go", "mod", "tidy")
+func goModTidy(modRoot string) (err error) {
+	cmd := gocmd.Command("mod", "tidy")
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	cmd.Dir = modRoot
@@ -76,3 +98,65 @@ func Tidy(dir string, gop *env.Gop) (err error) {
 	}
 	return
 }
+
+// externImports returns paths of external packages imported by Go+ files of
+// the module mod, packages of classfiles it registers, and builtin packages of
+// Go+ which generated Go code may import.
+func externImports(mod *gopmod.Module) []string {
+	seen := make(map[string]bool)
+	var ret []string
+	add := func(pkgPath string) {
+		if !seen[pkgPath] && mod.PkgType(pkgPath) == gopmod.PkgtExtern {
+			seen[pkgPath] = true
+			ret = append(ret, pkgPath)
+		}
+	}
+	add("github.com/goplus/gop/builtin")
+	if opt := mod.Opt; opt != nil {
+		for _, proj := range opt.Projects {
+			for _, pkgPath := range proj.PkgPaths {
+				add(pkgPath)
+			}
+		}
+	}
+	modRoot := mod.Root()
+	filepath.WalkDir(modRoot, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if path != modRoot {
+			if name := d.Name(); strings.HasPrefix(name, "_") || strings.HasPrefix(name, ".") || name == "testdata" {
+				return filepath.SkipDir
+			}
+			if _, e := os.Lstat(filepath.Join(path, "go.mod")); e == nil { // another module
+				return filepath.SkipDir
+			}
+		}
+		for _, pkgPath := range importsOf(path, mod, &Config{}) {
+			add(pkgPath)
+		}
+		return nil
+	})
+	sort.Strings(ret)
+	return ret
+}
+
+// writeTidyImports writes a package importing imports into a temporary
+// directory in modRoot, which is only built with the tag gop_tidy, and returns
+// the directory. `go mod tidy` considers all build tags, so it sees them.
+func writeTidyImports(modRoot string, imports []string) (pkgDir string, err error) {
+	pkgDir, err = os.MkdirTemp(modRoot, "goptidy")
+	if err != nil {
+		return
+	}
+	var b bytes.Buffer
+	b.WriteString("//go:build gop_tidy\n\n// Code generated by gop mod tidy. DO NOT EDIT.\n\npackage goptidy\n\nimport (\n")
+	for _, pkgPath := range imports {
+		fmt.Fprintf(&b, "\t_ %q\n", pkgPath)
+	}
+	b.WriteString(")\n")
+	if err = os.WriteFile(filepath.Join(pkgDir, "imports.go"), b.Bytes(), 0644); err != nil {
+		os.RemoveAll(pkgDir)
+	}
+	return
+}
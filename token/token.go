@@ -139,12 +139,15 @@ const (
 
 	additional_beg
 	TILDE // additional tokens, handled in an ad-hoc manner
+	AT    // @
 	additional_end
 
-	CSTRING  = literal_beg  // C"Hello"
-	RAT      = literal_end  // 123.5r
-	RARROW   = operator_beg // =>
-	QUESTION = operator_end // ?
+	CSTRING  = literal_beg    // C"Hello"
+	RAT      = literal_end    // 123.5r
+	DURATION = additional_end // 5s, 1h30m
+	PIPE     = additional_beg // |> (experimental, see //gop:enable pipeline)
+	RARROW   = operator_beg   // =>
+	QUESTION = operator_end   // ?
 )
 
 var tokens = [...]string{
@@ -162,6 +165,9 @@ var tokens = [...]string{
 	CSTRING: "CSTRING",
 	RAT:     "RAT",
 
+	DURATION: "DURATION",
+	PIPE:     "|>",
+
 	ADD: "+",
 	SUB: "-",
 	MUL: "*",
@@ -220,6 +226,7 @@ var tokens = [...]string{
 	QUESTION:  "?",
 	RARROW:    "=>",
 	TILDE:     "~",
+	AT:        "@",
 
 	BREAK:    "break",
 	CASE:     "case",
@@ -287,7 +294,7 @@ const (
 //
 func (op Token) Precedence() int {
 	switch op {
-	case LOR:
+	case LOR, PIPE:
 		return 1
 	case LAND:
 		return 2
@@ -325,14 +332,14 @@ func Lookup(ident string) Token {
 // and basic type literals; it returns false otherwise.
 //
 func (tok Token) IsLiteral() bool {
-	return literal_beg <= tok && tok <= literal_end
+	return literal_beg <= tok && tok <= literal_end || tok == DURATION
 }
 
 // IsOperator returns true for tokens corresponding to operators and
 // delimiters; it returns false otherwise.
 //
 func (tok Token) IsOperator() bool {
-	return operator_beg <= tok && tok <= operator_end || tok == TILDE
+	return operator_beg <= tok && tok <= operator_end || tok == TILDE || tok == AT
 }
 
 // IsKeyword returns true for tokens corresponding to keywords;
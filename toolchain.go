@@ -0,0 +1,71 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gop
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/goplus/gop/env"
+	"github.com/goplus/mod/gopmod"
+)
+
+// EnvToolchain is the environment variable to control checking of the gop
+// directive (eg. `gop 1.2`) in gop.mod:
+//   - "" (default): fail with a ToolchainError if the running gop doesn't
+//     satisfy it.
+//   - "auto": switch to gop<version> (eg. gop1.3, installed by a version
+//     manager) found in PATH if the running gop doesn't satisfy it.
+//     See cmd/gop.
+//   - "local": don't check.
+const EnvToolchain = "GOPTOOLCHAIN"
+
+// ToolchainError is returned if the running gop doesn't satisfy the gop
+// directive in gop.mod.
+type ToolchainError struct {
+	Modfile  string // path of gop.mod
+	Required string // required gop version, eg. "1.3"
+	Running  string // version of the running gop, eg. "v1.2.0"
+}
+
+func (p *ToolchainError) Error() string {
+	return fmt.Sprintf(
+		"%s requires gop >= %s (running gop %s): please upgrade gop, or set %s=auto to switch to gop%s",
+		p.Modfile, p.Required, p.Running, EnvToolchain, p.Required)
+}
+
+// CheckToolchain checks if the running gop satisfies the gop directive of mod.
+func CheckToolchain(mod *gopmod.Module) error {
+	opt := mod.Opt
+	if opt == nil || opt.Gop == nil || os.Getenv(EnvToolchain) == "local" {
+		return nil
+	}
+	modfile := "gop.mod"
+	if opt.Syntax != nil {
+		modfile = opt.Syntax.Name
+	}
+	required := opt.Gop.Version
+	if _, err := env.ParseVersion(required); err != nil {
+		return fmt.Errorf("%s: invalid gop version %q", modfile, required)
+	}
+	if running := env.SemVersion(); !running.Satisfies(required) {
+		return &ToolchainError{Modfile: modfile, Required: required, Running: env.Version()}
+	}
+	return nil
+}
+
+// -----------------------------------------------------------------------------
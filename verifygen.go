@@ -0,0 +1,109 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gop
+
+import (
+	"bytes"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/goplus/gop/cl"
+	"github.com/goplus/gox"
+	"github.com/qiniu/x/errors"
+)
+
+// -----------------------------------------------------------------------------
+
+// StaleFile represents a generated Go file which is out of date.
+type StaleFile struct {
+	File string // path of the generated file
+	Old  []byte // content on disk, nil if the file doesn't exist
+	New  []byte // regenerated content
+}
+
+// VerifyGen regenerates Go code of the Go+ package in dir (or all packages
+// under dir if it ends with "/...") into memory, and returns generated files
+// which are different from the ones on disk. It never writes any file.
+func VerifyGen(dir string, conf *Config, genTestPkg bool) (stales []*StaleFile, err error) {
+	if !strings.HasSuffix(dir, "/...") {
+		return verifyGenIn(dir, conf, genTestPkg)
+	}
+	var list errors.List
+	err = filepath.WalkDir(dir[:len(dir)-4], func(path string, d fs.DirEntry, err error) error {
+		if err == nil && d.IsDir() {
+			if strings.HasPrefix(d.Name(), "_") { // skip _
+				return filepath.SkipDir
+			}
+			ret, e := verifyGenIn(path, conf, genTestPkg)
+			if e != nil && notIgnNotated(e, conf) {
+				list.Add(e)
+			}
+			stales = append(stales, ret...)
+		}
+		return err
+	})
+	if err != nil {
+		return
+	}
+	return stales, list.ToError()
+}
+
+func verifyGenIn(dir string, conf *Config, genTestPkg bool) (stales []*StaleFile, err error) {
+	out, test, err := LoadDir(dir, conf, genTestPkg)
+	if err != nil {
+		if NotFound(err) { // no Go+ source files
+			return nil, nil
+		}
+		return nil, errors.NewWith(err, `LoadDir(dir, conf, genTestPkg)`, -5, "gop.LoadDir", dir, conf, genTestPkg)
+	}
+	backend := backendOf(conf)
+	check := func(pkg *gox.Package, fname string, fnames ...string) error {
+		if _, ok := pkg.File(fnames...); !ok {
+			return nil
+		}
+		if backend == nil {
+			backend = cl.GoBackend
+		}
+		var buf bytes.Buffer
+		if err := backend.WriteTo(&buf, pkg, fnames...); err != nil {
+			return err
+		}
+		file := filepath.Join(dir, fname)
+		old, err := os.ReadFile(file)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if !bytes.Equal(old, buf.Bytes()) {
+			stales = append(stales, &StaleFile{File: file, Old: old, New: buf.Bytes()})
+		}
+		return nil
+	}
+	if err = check(out, autoGenFile); err != nil {
+		return
+	}
+	if err = check(out, autoGenTestFile, testingGoFile); err != nil {
+		return
+	}
+	if test != nil {
+		err = check(test, autoGen2TestFile, testingGoFile)
+	}
+	return
+}
+
+// -----------------------------------------------------------------------------
@@ -24,6 +24,7 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/goplus/gop/x/pathutil"
 	"github.com/goplus/mod/gopmod"
 )
 
@@ -59,8 +60,8 @@ type Changes struct {
 func NewChanges(root string) *Changes {
 	changed := make(map[string]none)
 	mods := make(map[string]*module)
-	root, _ = filepath.Abs(root)
-	c := &Changes{changed: changed, mods: mods, root: root + "/"}
+	root, _ = pathutil.Abs(root)
+	c := &Changes{changed: changed, mods: mods, root: root}
 	c.cond.L = &c.mutex
 	return c
 }
@@ -70,7 +71,7 @@ func (p *Changes) doLookupMod(name string) *module {
 	if !ok {
 		mod = new(module)
 		mod.exts = make([]string, 0, 8)
-		m, e := gopmod.Load(p.root + name)
+		m, e := gopmod.Load(pathutil.Join(p.root, name))
 		if e == nil {
 			m.ImportClasses(func(c *gopmod.Project) {
 				mod.exts = append(mod.exts, c.Ext)
@@ -115,7 +116,7 @@ func (p *Changes) Fetch(fullPath bool) (dir string) {
 	}
 	p.mutex.Unlock()
 	if fullPath {
-		dir = p.root + dir
+		dir = pathutil.Join(p.root, dir)
 	}
 	return
 }
@@ -148,12 +149,12 @@ func (p *Changes) EntryDeleted(name string, isDir bool) {
 }
 
 func (p *Changes) DirAdded(name string) {
-	dir := p.root + name
+	dir := pathutil.Join(p.root, name)
 	filepath.WalkDir(dir, func(entry string, d fs.DirEntry, err error) error {
 		if err != nil || d.IsDir() {
 			return err
 		}
-		entry, _ = filepath.Rel(dir, entry)
+		entry, _ = pathutil.Rel(p.root, entry)
 		entry = filepath.ToSlash(entry)
 		if !p.Ignore(entry, false) {
 			p.FileChanged(entry)
@@ -52,9 +52,7 @@ func (p Runner) Fetch(fullPath bool) (dir string) {
 }
 
 func (p Runner) Run() error {
-	root := p.c.root
-	root = root[:len(root)-1]
-	return p.w.Run(root, p.c, p.c.Ignore)
+	return p.w.Run(p.c.root, p.c, p.c.Ignore)
 }
 
 func (p *Runner) Close() error {
@@ -0,0 +1,126 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package exhaustive defines an Analyzer that reports enum-style switches
+// which don't cover all constants of the enum type.
+//
+// It applies to Go code the same check that the Go+ compiler does for Go+
+// code: a switch on a value of a named integer or string type, without a
+// default case, must list all constants of that type declared in its package.
+// A `//nolint` or `//nolint:exhaustive` comment at the line of the switch or
+// the line before suppresses the report.
+package exhaustive
+
+import (
+	"go/ast"
+	"go/types"
+	"strings"
+
+	gopast "github.com/goplus/gop/ast"
+	"github.com/goplus/gop/cl"
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+const doc = `check exhaustiveness of enum-style switch statements
+
+A switch statement on a value of a named integer or string type, whose package
+declares constants of this type, must either have a default case or list all
+of these constants.`
+
+// Analyzer reports enum-style switches with missing cases.
+var Analyzer = &analysis.Analyzer{
+	Name:     "exhaustive",
+	Doc:      doc,
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	nodeFilter := []ast.Node{
+		(*ast.SwitchStmt)(nil),
+	}
+	nolints := make(map[nolintKey]bool)
+	for _, f := range pass.Files {
+		for _, cg := range f.Comments {
+			for _, c := range cg.List {
+				if names, ok := gopast.Nolint(c.Text); ok && suppressed(names) {
+					pos := pass.Fset.Position(c.Pos())
+					nolints[nolintKey{pos.Filename, pos.Line}] = true
+					nolints[nolintKey{pos.Filename, pos.Line + 1}] = true
+				}
+			}
+		}
+	}
+	inspect.Preorder(nodeFilter, func(n ast.Node) {
+		v := n.(*ast.SwitchStmt)
+		if v.Tag == nil {
+			return
+		}
+		if pos := pass.Fset.Position(v.Pos()); nolints[nolintKey{pos.Filename, pos.Line}] {
+			return
+		}
+		tag := pass.TypesInfo.TypeOf(v.Tag)
+		consts := cl.EnumConsts(tag, pass.Pkg)
+		if consts == nil {
+			return
+		}
+		seen := make(map[string]bool)
+		for _, stmt := range v.Body.List {
+			c := stmt.(*ast.CaseClause)
+			if c.List == nil { // default
+				return
+			}
+			for _, e := range c.List {
+				if tv, ok := pass.TypesInfo.Types[e]; ok && tv.Value != nil {
+					seen[tv.Value.ExactString()] = true
+				}
+			}
+		}
+		var missing []string
+		for _, c := range consts {
+			key := c.Val().ExactString()
+			if !seen[key] {
+				seen[key] = true
+				missing = append(missing, c.Name())
+			}
+		}
+		if missing != nil {
+			pass.Reportf(v.Pos(), "missing cases in switch of type %s: %s",
+				types.TypeString(tag, types.RelativeTo(pass.Pkg)), strings.Join(missing, ", "))
+		}
+	})
+	return nil, nil
+}
+
+type nolintKey struct {
+	file string
+	line int
+}
+
+func suppressed(names []string) bool {
+	if names == nil {
+		return true
+	}
+	for _, name := range names {
+		if name == "exhaustive" {
+			return true
+		}
+	}
+	return false
+}
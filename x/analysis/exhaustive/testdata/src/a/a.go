@@ -0,0 +1,41 @@
+package a
+
+import "time"
+
+type Color int
+
+const (
+	Red Color = iota
+	Green
+	Blue
+	Crimson = Red
+)
+
+func f(c Color, m time.Month) {
+	switch c { // want "missing cases in switch of type Color: Green"
+	case Red, Blue:
+	}
+	switch c {
+	case Crimson, Green, Blue:
+	}
+	switch c {
+	case Red:
+	default:
+	}
+	switch m { // want "missing cases in switch of type time.Month: February, March, April, May, June, July, August, September, October, November, December"
+	case time.January:
+	}
+}
+
+func g(c Color) {
+	switch c { //nolint:exhaustive
+	case Red:
+	}
+	//nolint
+	switch c {
+	case Red:
+	}
+	switch c { //nolint:other // want "missing cases in switch of type Color: Green, Blue"
+	case Red:
+	}
+}
@@ -0,0 +1,323 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package apidiff reports changes of the exported API of a package between
+// two versions, and whether they are compatible.
+package apidiff
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"io"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/gcexportdata"
+)
+
+// -----------------------------------------------------------------------------
+
+// Change represents a change of an exported API.
+type Change struct {
+	Name       string // eg. "Foo", "T.Method", "T.Field"
+	Msg        string // eg. "removed", "added"
+	Compatible bool
+}
+
+func (c Change) String() string {
+	return c.Name + ": " + c.Msg
+}
+
+// Report is a list of changes sorted by names.
+type Report struct {
+	Changes []Change
+}
+
+// Incompatible returns incompatible (breaking) changes.
+func (r *Report) Incompatible() []Change {
+	return r.filter(false)
+}
+
+// Compatible returns compatible changes.
+func (r *Report) Compatible() []Change {
+	return r.filter(true)
+}
+
+func (r *Report) filter(compatible bool) (ret []Change) {
+	for _, c := range r.Changes {
+		if c.Compatible == compatible {
+			ret = append(ret, c)
+		}
+	}
+	return
+}
+
+// Bump returns which part of a semantic version should be bumped for the
+// changes: "major" if there are incompatible changes, "minor" if there are
+// compatible ones, or "patch" if the API isn't changed.
+func (r *Report) Bump() string {
+	if len(r.Incompatible()) > 0 {
+		return "major"
+	} else if len(r.Changes) > 0 {
+		return "minor"
+	}
+	return "patch"
+}
+
+// Fprint prints the report.
+func (r *Report) Fprint(w io.Writer) {
+	printChanges(w, "Incompatible changes:", r.Incompatible())
+	printChanges(w, "Compatible changes:", r.Compatible())
+}
+
+func printChanges(w io.Writer, title string, changes []Change) {
+	if len(changes) > 0 {
+		fmt.Fprintln(w, title)
+		for _, c := range changes {
+			fmt.Fprintln(w, "-", c)
+		}
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+// Changes reports changes of the exported API from package old to new.
+// Types are compared by their string forms, so old and new can be loaded
+// separately (eg. one from export data and the other from source code).
+func Changes(old, new *types.Package) *Report {
+	d := &differ{old: old, new: new}
+	oldScope, newScope := old.Scope(), new.Scope()
+	for _, name := range oldScope.Names() {
+		if !token.IsExported(name) {
+			continue
+		}
+		if o := newScope.Lookup(name); o != nil {
+			d.object(name, oldScope.Lookup(name), o)
+		} else {
+			d.incompatible(name, "removed")
+		}
+	}
+	for _, name := range newScope.Names() {
+		if token.IsExported(name) && oldScope.Lookup(name) == nil {
+			d.compatible(name, "added")
+		}
+	}
+	sort.SliceStable(d.changes, func(i, j int) bool {
+		return d.changes[i].Name < d.changes[j].Name
+	})
+	return &Report{Changes: d.changes}
+}
+
+type differ struct {
+	old, new *types.Package
+	changes  []Change
+}
+
+func (d *differ) incompatible(name, format string, args ...interface{}) {
+	d.changes = append(d.changes, Change{Name: name, Msg: fmt.Sprintf(format, args...)})
+}
+
+func (d *differ) compatible(name, format string, args ...interface{}) {
+	d.changes = append(d.changes, Change{Name: name, Msg: fmt.Sprintf(format, args...), Compatible: true})
+}
+
+// str returns string form of typ, qualified relative to its own package.
+func str(typ types.Type, pkg *types.Package) string {
+	return types.TypeString(typ, types.RelativeTo(pkg))
+}
+
+func (d *differ) types(t1, t2 types.Type) (s1, s2 string, same bool) {
+	s1, s2 = str(t1, d.old), str(t2, d.new)
+	return s1, s2, s1 == s2
+}
+
+func kindOf(o types.Object) string {
+	switch o.(type) {
+	case *types.Const:
+		return "const"
+	case *types.Var:
+		return "var"
+	case *types.Func:
+		return "func"
+	case *types.TypeName:
+		return "type"
+	}
+	return "object"
+}
+
+func (d *differ) object(name string, o1, o2 types.Object) {
+	if k1, k2 := kindOf(o1), kindOf(o2); k1 != k2 {
+		d.incompatible(name, "changed from %s to %s", k1, k2)
+		return
+	}
+	switch o1 := o1.(type) {
+	case *types.Const:
+		o2 := o2.(*types.Const)
+		if s1, s2, same := d.types(o1.Type(), o2.Type()); !same {
+			d.incompatible(name, "type changed from %s to %s", s1, s2)
+		} else if !constant.Compare(o1.Val(), token.EQL, o2.Val()) {
+			d.incompatible(name, "value changed from %v to %v", o1.Val(), o2.Val())
+		}
+	case *types.Var, *types.Func:
+		if s1, s2, same := d.types(o1.Type(), o2.Type()); !same {
+			d.incompatible(name, "changed from %s to %s", s1, s2)
+		}
+	case *types.TypeName:
+		d.typeName(name, o1, o2.(*types.TypeName))
+	}
+}
+
+func (d *differ) typeName(name string, o1, o2 *types.TypeName) {
+	if o1.IsAlias() || o2.IsAlias() {
+		if s1, s2, same := d.types(o1.Type(), o2.Type()); !same {
+			d.incompatible(name, "changed from %s to %s", s1, s2)
+		}
+		return
+	}
+	u1, u2 := o1.Type().Underlying(), o2.Type().Underlying()
+	switch u1 := u1.(type) {
+	case *types.Struct:
+		if u2, ok := u2.(*types.Struct); ok {
+			d.structFields(name, u1, u2)
+			break
+		}
+		d.underlying(name, u1, u2)
+	case *types.Interface:
+		if u2, ok := u2.(*types.Interface); ok {
+			d.ifaceMethods(name, u1, u2)
+			return // methods of interfaces are checked by ifaceMethods
+		}
+		d.underlying(name, u1, u2)
+	default:
+		if _, _, same := d.types(u1, u2); !same {
+			d.underlying(name, u1, u2)
+		}
+	}
+	d.methods(name, o1.Type(), o2.Type())
+}
+
+func (d *differ) underlying(name string, u1, u2 types.Type) {
+	s1, s2, _ := d.types(u1, u2)
+	d.incompatible(name, "underlying type changed from %s to %s", s1, s2)
+}
+
+func (d *differ) structFields(name string, s1, s2 *types.Struct) {
+	fields := make(map[string]*types.Var)
+	for i, n := 0, s2.NumFields(); i < n; i++ {
+		fields[s2.Field(i).Name()] = s2.Field(i)
+	}
+	old := make(map[string]bool)
+	for i, n := 0, s1.NumFields(); i < n; i++ {
+		f1 := s1.Field(i)
+		if !f1.Exported() {
+			continue
+		}
+		old[f1.Name()] = true
+		fname := name + "." + f1.Name()
+		if f2, ok := fields[f1.Name()]; !ok || !f2.Exported() {
+			d.incompatible(fname, "removed")
+		} else if t1, t2, same := d.types(f1.Type(), f2.Type()); !same {
+			d.incompatible(fname, "changed from %s to %s", t1, t2)
+		}
+	}
+	for i, n := 0, s2.NumFields(); i < n; i++ {
+		if f2 := s2.Field(i); f2.Exported() && !old[f2.Name()] {
+			d.compatible(name+"."+f2.Name(), "added")
+		}
+	}
+}
+
+func (d *differ) ifaceMethods(name string, i1, i2 *types.Interface) {
+	methods := make(map[string]*types.Func)
+	for i, n := 0, i1.NumMethods(); i < n; i++ {
+		methods[i1.Method(i).Name()] = i1.Method(i)
+	}
+	for i, n := 0, i2.NumMethods(); i < n; i++ {
+		m2 := i2.Method(i)
+		mname := name + "." + m2.Name()
+		if m1, ok := methods[m2.Name()]; !ok {
+			// adding a method breaks types implementing the interface
+			d.incompatible(mname, "added to interface")
+		} else if s1, s2, same := d.types(m1.Type(), m2.Type()); !same {
+			d.incompatible(mname, "changed from %s to %s", s1, s2)
+		}
+		delete(methods, m2.Name())
+	}
+	for mname := range methods {
+		if token.IsExported(mname) {
+			d.incompatible(name+"."+mname, "removed")
+		}
+	}
+}
+
+// methods checks exported methods of named types (including methods with
+// pointer receivers).
+func (d *differ) methods(name string, t1, t2 types.Type) {
+	ms1 := types.NewMethodSet(types.NewPointer(t1))
+	ms2 := types.NewMethodSet(types.NewPointer(t2))
+	for i, n := 0, ms1.Len(); i < n; i++ {
+		m1 := ms1.At(i).Obj()
+		if !m1.Exported() {
+			continue
+		}
+		mname := name + "." + m1.Name()
+		if sel := ms2.Lookup(nil, m1.Name()); sel == nil {
+			d.incompatible(mname, "removed")
+		} else if s1, s2, same := d.types(m1.Type(), sel.Obj().Type()); !same {
+			d.incompatible(mname, "changed from %s to %s", s1, s2)
+		}
+	}
+	for i, n := 0, ms2.Len(); i < n; i++ {
+		m2 := ms2.At(i).Obj()
+		if m2.Exported() && ms1.Lookup(nil, m2.Name()) == nil {
+			d.compatible(name+"."+m2.Name(), "added")
+		}
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+const exportMagic = "gop apidiff v1"
+
+// ErrInvalidExport is returned by ReadExport if the input isn't export data
+// written by WriteExport.
+var ErrInvalidExport = errors.New("invalid export data of apidiff")
+
+// WriteExport writes export data of pkg, which can be read by ReadExport to
+// compare with other versions later.
+func WriteExport(w io.Writer, fset *token.FileSet, pkg *types.Package) error {
+	if _, err := fmt.Fprintf(w, "%s %s\n", exportMagic, pkg.Path()); err != nil {
+		return err
+	}
+	return gcexportdata.Write(w, fset, pkg)
+}
+
+// ReadExport reads export data written by WriteExport.
+func ReadExport(r io.Reader, fset *token.FileSet) (*types.Package, error) {
+	br := bufio.NewReader(r)
+	line, err := br.ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, exportMagic+" ") {
+		return nil, ErrInvalidExport
+	}
+	pkgPath := strings.TrimSpace(line[len(exportMagic)+1:])
+	return gcexportdata.Read(br, fset, make(map[string]*types.Package), pkgPath)
+}
+
+// -----------------------------------------------------------------------------
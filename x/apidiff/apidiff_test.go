@@ -0,0 +1,157 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package apidiff_test
+
+import (
+	"bytes"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"testing"
+
+	"github.com/goplus/gop/x/apidiff"
+)
+
+func check(t *testing.T, fset *token.FileSet, src string) *types.Package {
+	f, err := parser.ParseFile(fset, "foo.go", src, 0)
+	if err != nil {
+		t.Fatal("parser.ParseFile:", err)
+	}
+	conf := &types.Config{Importer: importer.Default()}
+	pkg, err := conf.Check("example.com/foo", fset, []*ast.File{f}, nil)
+	if err != nil {
+		t.Fatal("types.Check:", err)
+	}
+	return pkg
+}
+
+const oldAPI = `package foo
+
+import "io"
+
+const Max = 10
+const Name = "foo"
+
+var Debug bool
+
+type Point struct {
+	X, Y int
+	Tag  string
+	priv int
+}
+
+func (p *Point) Move(dx, dy int) {}
+func (p Point) String() string   { return "" }
+
+type Reader interface {
+	Read(p []byte) (int, error)
+}
+
+type ID int
+
+func Open(name string) (io.Reader, error) { return nil, nil }
+func Close()                              {}
+`
+
+const newAPI = `package foo
+
+import "io"
+
+const Max = 20
+const Name = 1
+
+var Debug bool
+var Verbose bool
+
+type Point struct {
+	X, Y int
+	Tag  int
+	Z    int
+}
+
+func (p *Point) Move(dx, dy int) {}
+func (p *Point) Scale(k int)     {}
+
+type Reader interface {
+	Read(p []byte) (int, error)
+	Close() error
+}
+
+type ID string
+
+func Open(name string, flag int) (io.Reader, error) { return nil, nil }
+
+var Close = func() {}
+`
+
+func TestChanges(t *testing.T) {
+	fset := token.NewFileSet()
+	old, new := check(t, fset, oldAPI), check(t, fset, newAPI)
+	r := apidiff.Changes(old, new)
+	var b bytes.Buffer
+	r.Fprint(&b)
+	const expected = `Incompatible changes:
+- Close: changed from func to var
+- ID: underlying type changed from int to string
+- Max: value changed from 10 to 20
+- Name: type changed from untyped string to untyped int
+- Open: changed from func(name string) (io.Reader, error) to func(name string, flag int) (io.Reader, error)
+- Point.String: removed
+- Point.Tag: changed from string to int
+- Reader.Close: added to interface
+Compatible changes:
+- Point.Scale: added
+- Point.Z: added
+- Verbose: added
+`
+	if b.String() != expected {
+		t.Fatalf("\nResult:\n%s\nExpected:\n%s\n", b.String(), expected)
+	}
+	if r.Bump() != "major" || len(r.Compatible()) != 3 {
+		t.Fatal("Bump:", r.Bump())
+	}
+	if r := apidiff.Changes(old, old); r.Bump() != "patch" || len(r.Changes) != 0 {
+		t.Fatal("Changes(old, old):", r.Changes)
+	}
+	if r := apidiff.Changes(old, check(t, fset, oldAPI+"\nfunc New() {}\n")); r.Bump() != "minor" {
+		t.Fatal("Changes:", r.Changes)
+	}
+}
+
+func TestExport(t *testing.T) {
+	fset := token.NewFileSet()
+	pkg := check(t, fset, oldAPI)
+	var b bytes.Buffer
+	if err := apidiff.WriteExport(&b, fset, pkg); err != nil {
+		t.Fatal("WriteExport:", err)
+	}
+	ret, err := apidiff.ReadExport(&b, token.NewFileSet())
+	if err != nil {
+		t.Fatal("ReadExport:", err)
+	}
+	if ret.Path() != pkg.Path() {
+		t.Fatal("ReadExport:", ret.Path())
+	}
+	if r := apidiff.Changes(ret, pkg); len(r.Changes) != 0 {
+		t.Fatal("Changes:", r.Changes)
+	}
+	if _, err = apidiff.ReadExport(bytes.NewReader([]byte("foo\n")), fset); err != apidiff.ErrInvalidExport {
+		t.Fatal("ReadExport:", err)
+	}
+}
@@ -29,29 +29,22 @@ import (
 	"github.com/goplus/gop/parser"
 	"github.com/goplus/gop/parser/fsx/memfs"
 	"github.com/goplus/gop/token"
+	"github.com/goplus/gop/x/classfile"
 	"github.com/goplus/gox"
 	"github.com/goplus/gox/packages"
 )
 
 type Class = cl.Class
 
-var (
-	projects = make(map[string]*cl.Project)
-)
-
+// RegisterClassFileType registers a classfile framework (see
+// gop/x/classfile.Register).
 func RegisterClassFileType(ext string, class string, works []*Class, pkgPaths ...string) {
-	cls := &cl.Project{
+	classfile.Register(&cl.Project{
 		Ext:      ext,
 		Class:    class,
 		Works:    works,
 		PkgPaths: pkgPaths,
-	}
-	if ext != "" {
-		projects[ext] = cls
-	}
-	for _, w := range works {
-		projects[w.Ext] = cls
-	}
+	})
 }
 
 func init() {
@@ -82,20 +75,8 @@ func ClassKind(fname string) (isProj, ok bool) {
 		return true, true
 	case ".spx":
 		return fname == "main.spx", true
-	default:
-		if c, ok := projects[ext]; ok {
-			for _, w := range c.Works {
-				if w.Ext == ext {
-					if ext != c.Ext || fname != "main"+ext {
-						return false, true
-					}
-					break
-				}
-			}
-			return true, true
-		}
 	}
-	return
+	return classfile.Kind(fname)
 }
 
 type Context struct {
@@ -159,10 +140,7 @@ func (c *Context) loadPackage(srcDir string, pkgs map[string]*ast.Package) (*Pac
 	}
 	conf := &cl.Config{Fset: c.fset}
 	conf.Importer = c
-	conf.LookupClass = func(ext string) (c *cl.Project, ok bool) {
-		c, ok = projects[ext]
-		return
-	}
+	conf.LookupClass = classfile.Lookup
 	if c.LoadConfig != nil {
 		c.LoadConfig(conf)
 	}
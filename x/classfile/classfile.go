@@ -0,0 +1,117 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package classfile implements a registry of classfile frameworks, so that
+// engines embedding Go+ (eg. game or web frameworks) can define their own
+// kinds of Go+ files without a gop.mod:
+//
+//	classfile.Register(&classfile.Project{
+//		Ext:      ".gmx",
+//		Class:    "Game",
+//		Works:    []*classfile.Class{{Ext: ".spx", Class: "Sprite"}},
+//		PkgPaths: []string{"github.com/goplus/spx", "math"},
+//	})
+//
+// Registered frameworks are recognized by the parser (when Config.ClassKind
+// is nil) and the compiler (when Config.LookupClass is nil or doesn't find
+// the class), and by gop commands in addition to ones declared in gop.mod.
+package classfile
+
+import (
+	"sync"
+
+	"github.com/goplus/mod/modfile"
+)
+
+// A Project is a classfile framework: its project class and work classes.
+type Project = modfile.Project
+
+// A Class is a work class of a classfile framework.
+type Class = modfile.Class
+
+// -----------------------------------------------------------------------------
+
+var (
+	mutex    sync.RWMutex
+	projects = make(map[string]*Project) // ext => project
+)
+
+// Register registers the classfile framework proj by extensions of its
+// project class (if any) and its work classes. A registered extension is
+// overridden by a later registration.
+func Register(proj *Project) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	if proj.Ext != "" {
+		projects[proj.Ext] = proj
+	}
+	for _, w := range proj.Works {
+		projects[w.Ext] = proj
+	}
+}
+
+// Lookup lookups the registered classfile framework of a file extension
+// (eg. ".spx" or "_yap.gox").
+func Lookup(ext string) (proj *Project, ok bool) {
+	mutex.RLock()
+	proj, ok = projects[ext]
+	mutex.RUnlock()
+	return
+}
+
+// Kind checks if fname is a classfile of a registered framework, and if so,
+// whether it is the project class. It can be used as ClassKind of
+// parser.Config.
+func Kind(fname string) (isProj, ok bool) {
+	ext := modfile.ClassExt(fname)
+	proj, ok := Lookup(ext)
+	if !ok {
+		return
+	}
+	for _, w := range proj.Works {
+		if w.Ext == ext {
+			if ext != proj.Ext || fname != "main"+ext {
+				return false, true
+			}
+			break
+		}
+	}
+	return true, true
+}
+
+// KindOf returns a ClassKind of parser.Config which checks kind first (eg.
+// ClassKind of a gop.mod), and then registered frameworks.
+func KindOf(kind func(fname string) (isProj, ok bool)) func(fname string) (isProj, ok bool) {
+	return func(fname string) (isProj, ok bool) {
+		if isProj, ok = kind(fname); !ok {
+			isProj, ok = Kind(fname)
+		}
+		return
+	}
+}
+
+// LookupOf returns a LookupClass of cl.Config which calls lookup first (eg.
+// LookupClass of a gop.mod), and then Lookup.
+func LookupOf(lookup func(ext string) (*Project, bool)) func(ext string) (*Project, bool) {
+	return func(ext string) (proj *Project, ok bool) {
+		if proj, ok = lookup(ext); !ok {
+			proj, ok = Lookup(ext)
+		}
+		return
+	}
+}
+
+// -----------------------------------------------------------------------------
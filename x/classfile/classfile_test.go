@@ -0,0 +1,93 @@
+package classfile_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/goplus/gop/cl"
+	"github.com/goplus/gop/parser"
+	"github.com/goplus/gop/parser/fsx/memfs"
+	"github.com/goplus/gop/token"
+	"github.com/goplus/gop/x/classfile"
+	"github.com/goplus/gox/packages"
+)
+
+func init() {
+	classfile.Register(&classfile.Project{
+		Ext:      ".tgmx",
+		Class:    "MyGame",
+		Works:    []*classfile.Class{{Ext: ".tspx", Class: "Sprite"}},
+		PkgPaths: []string{"github.com/goplus/gop/cl/internal/spx"},
+	})
+	classfile.Register(&classfile.Project{
+		Ext:      "_tyap.gox",
+		Class:    "App",
+		Works:    []*classfile.Class{{Ext: "_tyap.gox", Class: "Handler"}},
+		PkgPaths: []string{"example.com/yap"},
+	})
+}
+
+func TestKind(t *testing.T) {
+	cases := []struct {
+		fname      string
+		isProj, ok bool
+	}{
+		{"main.tgmx", true, true},
+		{"Kai.tspx", false, true},
+		{"main_tyap.gox", true, true},
+		{"get_tyap.gox", false, true},
+		{"foo.gox", false, false},
+		{"foo.gop", false, false},
+	}
+	for _, c := range cases {
+		if isProj, ok := classfile.Kind(c.fname); isProj != c.isProj || ok != c.ok {
+			t.Fatal("Kind:", c.fname, isProj, ok)
+		}
+	}
+	if proj, ok := classfile.Lookup(".tspx"); !ok || proj.Class != "MyGame" {
+		t.Fatal("Lookup .tspx:", proj, ok)
+	}
+	kind := classfile.KindOf(func(fname string) (isProj, ok bool) {
+		return fname == "main.tspx", strings.HasSuffix(fname, ".tspx")
+	})
+	if isProj, ok := kind("main.tspx"); !isProj || !ok {
+		t.Fatal("KindOf main.tspx:", isProj, ok)
+	}
+	if isProj, ok := kind("main.tgmx"); !isProj || !ok {
+		t.Fatal("KindOf main.tgmx:", isProj, ok)
+	}
+}
+
+func TestParseAndCompile(t *testing.T) {
+	fs := memfs.New(map[string][]string{
+		"/foo": {"main.tgmx", "Kai.tspx"},
+	}, map[string]string{
+		"/foo/main.tgmx": `println "hi"`,
+		"/foo/Kai.tspx":  `say "Hello"`,
+	})
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseFSDir(fset, fs, "/foo", parser.Config{})
+	if err != nil {
+		t.Fatal("ParseFSDir:", err)
+	}
+	pkg := pkgs["main"]
+	if pkg == nil || !pkg.Files["/foo/main.tgmx"].IsProj || !pkg.Files["/foo/Kai.tspx"].IsClass {
+		t.Fatal("ParseFSDir: classfiles not recognized")
+	}
+	out, err := cl.NewPackage("", pkg, &cl.Config{
+		Fset:       fset,
+		Importer:   packages.NewImporter(fset),
+		NoFileLine: true,
+	})
+	if err != nil {
+		t.Fatal("cl.NewPackage:", err)
+	}
+	var b bytes.Buffer
+	if err = out.WriteTo(&b); err != nil {
+		t.Fatal("WriteTo:", err)
+	}
+	if s := b.String(); !strings.Contains(s, "type Kai struct") || !strings.Contains(s, "spx.MyGame") {
+		t.Fatal("cl.NewPackage:\n" + s)
+	}
+}
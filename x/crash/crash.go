@@ -0,0 +1,163 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package crash writes crash reports of the Go+ compiler. When the compiler
+// panics (see cl.InternalError), a report with the stack trace, metadata of
+// the toolchain and, with consent of the user (see EnvSource), the offending
+// source reduced to a minimal reproduction, is saved to a local file to be
+// attached to a bug report. Nothing is uploaded.
+package crash
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/goplus/gop"
+	"github.com/goplus/gop/cl"
+	"github.com/goplus/gop/env"
+)
+
+// -----------------------------------------------------------------------------
+
+// EnvSource is the environment variable to consent to include the offending
+// source in crash reports: "on" means to include it, reduced to a minimal
+// reproduction of the crash.
+const EnvSource = "GOP_CRASH_SOURCE"
+
+// EnvDir is the environment variable to specify the directory to save crash
+// reports in, eg. a directory of artifacts in CI. See Dir.
+const EnvDir = "GOP_CRASH_DIR"
+
+// ReduceTimeout limits time of reducing the offending source.
+var ReduceTimeout = 30 * time.Second
+
+// A Source is a source file in a crash report.
+type Source struct {
+	File string
+	Code []byte
+}
+
+// A Report is a crash report.
+type Report struct {
+	Time       time.Time
+	Args       []string // command line of gop
+	GopVersion string
+	GoVersion  string // Go version that gop is built with
+	Panic      string
+	Stack      []byte
+	Sources    []Source // offending source, nil without consent
+	Reduced    bool     // Sources are reduced
+}
+
+// NewReport creates a report of the crash err.
+func NewReport(err *cl.InternalError) *Report {
+	return &Report{
+		Time:       time.Now(),
+		Args:       os.Args,
+		GopVersion: env.Version(),
+		GoVersion:  runtime.Version() + " " + runtime.GOOS + "/" + runtime.GOARCH,
+		Panic:      fmt.Sprint(err.Value),
+		Stack:      err.Stack,
+	}
+}
+
+// WriteTo writes the report in markdown format, so that it can be pasted into
+// an issue.
+func (p *Report) WriteTo(w io.Writer) (int64, error) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "# Go+ compiler crash report\n\n")
+	fmt.Fprintf(&b, "- time: %s\n", p.Time.Format(time.RFC3339))
+	fmt.Fprintf(&b, "- command: %s\n", strings.Join(p.Args, " "))
+	fmt.Fprintf(&b, "- gop version: %s\n", p.GopVersion)
+	fmt.Fprintf(&b, "- go version: %s\n\n", p.GoVersion)
+	fmt.Fprintf(&b, "## Panic\n\n```\n%s\n\n%s```\n\n", p.Panic, p.Stack)
+	switch {
+	case p.Sources == nil:
+		fmt.Fprintf(&b, "## Source\n\nNot included. Set %s=on to include the offending source, reduced to a minimal reproduction.\n", EnvSource)
+	case p.Reduced:
+		fmt.Fprintf(&b, "## Source (reduced)\n")
+	default:
+		fmt.Fprintf(&b, "## Source\n")
+	}
+	for _, src := range p.Sources {
+		code := src.Code
+		if len(code) > 0 && code[len(code)-1] != '\n' {
+			code = append(code[:len(code):len(code)], '\n')
+		}
+		fmt.Fprintf(&b, "\n### %s\n\n```go\n%s```\n", src.File, code)
+	}
+	n, err := w.Write(b.Bytes())
+	return int64(n), err
+}
+
+// Dir returns the directory to save crash reports in: $GOP_CRASH_DIR if it
+// isn't empty, or gop/crash in the user cache directory.
+func Dir() string {
+	if dir := os.Getenv(EnvDir); dir != "" {
+		return dir
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "gop", "crash")
+}
+
+// Write saves the report into Dir and returns the file written.
+func Write(rep *Report) (file string, err error) {
+	dir := Dir()
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return
+	}
+	f, err := os.CreateTemp(dir, rep.Time.Format("crash-20060102-150405-*.md"))
+	if err != nil {
+		return
+	}
+	file = f.Name()
+	_, err = rep.WriteTo(f)
+	if e := f.Close(); err == nil {
+		err = e
+	}
+	return
+}
+
+// Hook returns a function to write crash reports, which can be used as
+// gop.Config.OnCrash. conf is the configuration of compiling, which is used
+// to reproduce the crash when reducing the offending source.
+func Hook(conf *gop.Config) func(dir string, files []string, err *cl.InternalError) {
+	return func(dir string, files []string, err *cl.InternalError) {
+		rep := NewReport(err)
+		if os.Getenv(EnvSource) == "on" {
+			fmt.Fprintln(os.Stderr, "gop: the compiler crashed, reducing the source to reproduce it ...")
+			rep.Sources, rep.Reduced = Reduce(dir, files, conf, err)
+		}
+		file, e := Write(rep)
+		if e != nil {
+			fmt.Fprintln(os.Stderr, "gop: the compiler crashed, failed to write the crash report:", e)
+			return
+		}
+		fmt.Fprintf(os.Stderr, "gop: the compiler crashed, the crash report is saved to %s\n", file)
+		fmt.Fprintln(os.Stderr, "Please file an issue with it at https://github.com/goplus/gop/issues/new")
+	}
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,131 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package crash_test
+
+import (
+	"bytes"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/goplus/gop"
+	"github.com/goplus/gop/cl"
+	"github.com/goplus/gop/token"
+	"github.com/goplus/gop/x/crash"
+	"github.com/goplus/gop/x/gopenv"
+)
+
+func init() {
+	if os.Getenv("GOPROOT") == "" {
+		dir, _ := os.Getwd()
+		os.Setenv("GOPROOT", filepath.Clean(filepath.Join(dir, "./../..")))
+	}
+}
+
+type crashImporter struct {
+	types.Importer
+}
+
+func (p crashImporter) Import(path string) (*types.Package, error) {
+	if path == "crash" { // a bug of the compiler
+		var m map[string]int
+		m[path] = 1
+	}
+	return p.Importer.Import(path)
+}
+
+const crashSrc = `import (
+	"crash"
+	"fmt"
+)
+
+a := 1
+fmt.println a
+crash.foo
+`
+
+func newConf() *gop.Config {
+	fset := token.NewFileSet()
+	imp := gop.NewImporter(nil, gopenv.Get(), fset)
+	return &gop.Config{Fset: fset, Importer: crashImporter{imp}}
+}
+
+func TestHook(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(crash.EnvDir, filepath.Join(dir, "crash"))
+	t.Setenv(crash.EnvSource, "on")
+	src := filepath.Join(dir, "src")
+	os.Mkdir(src, 0755)
+	os.WriteFile(filepath.Join(src, "main.gop"), []byte(crashSrc), 0644)
+
+	conf := newConf()
+	conf.OnCrash = crash.Hook(conf)
+	_, _, err := gop.LoadDir(src, conf, false)
+	if _, ok := cl.InternalErrorOf(err); !ok {
+		t.Fatal("LoadDir:", err)
+	}
+	entries, _ := os.ReadDir(crash.Dir())
+	if len(entries) != 1 {
+		t.Fatal("crash reports:", entries)
+	}
+	b, _ := os.ReadFile(filepath.Join(crash.Dir(), entries[0].Name()))
+	rep := string(b)
+	for _, s := range []string{
+		"# Go+ compiler crash report",
+		"assignment to entry in nil map",
+		"crashImporter",
+		"## Source (reduced)",
+		"```go\nimport (\n\t\"crash\"\n)\n```",
+	} {
+		if !strings.Contains(rep, s) {
+			t.Fatalf("crash report without %q:\n%s", s, rep)
+		}
+	}
+}
+
+func TestReduceFiles(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "main.gop")
+	os.WriteFile(file, []byte(crashSrc), 0644)
+	conf := newConf()
+	_, err := gop.LoadFiles(dir, []string{file}, conf)
+	ie, ok := cl.InternalErrorOf(err)
+	if !ok {
+		t.Fatal("LoadFiles:", err)
+	}
+	srcs, reduced := crash.Reduce(dir, []string{file}, conf, ie)
+	if !reduced || len(srcs) != 1 || srcs[0].File != file || string(srcs[0].Code) != "import (\n\t\"crash\"\n)\n" {
+		t.Fatal("Reduce:", srcs, reduced)
+	}
+
+	// not reproduced
+	ie = &cl.InternalError{Value: "other"}
+	if srcs, reduced = crash.Reduce(dir, []string{file}, conf, ie); reduced || len(srcs) != 1 || string(srcs[0].Code) != crashSrc {
+		t.Fatal("Reduce:", srcs, reduced)
+	}
+}
+
+func TestReportNoSource(t *testing.T) {
+	rep := crash.NewReport(&cl.InternalError{Value: "bug", Stack: []byte("stack\n")})
+	var b bytes.Buffer
+	rep.WriteTo(&b)
+	if s := b.String(); !strings.Contains(s, "```\nbug\n\nstack\n```") || !strings.Contains(s, "Not included. Set GOP_CRASH_SOURCE=on") {
+		t.Fatal("WriteTo:", s)
+	}
+}
@@ -0,0 +1,141 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package crash
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/goplus/gop"
+	"github.com/goplus/gop/cl"
+	"github.com/goplus/gop/parser"
+	"github.com/goplus/gop/parser/fsx"
+	"github.com/goplus/gop/token"
+	"github.com/goplus/gop/x/gopenv"
+	"github.com/goplus/gop/x/reduce"
+)
+
+// -----------------------------------------------------------------------------
+
+// overlayFS is a file system with contents of some files replaced.
+type overlayFS struct {
+	parser.FileSystem
+	files map[string][]byte // absolute path => content
+}
+
+func (p *overlayFS) ReadFile(filename string) ([]byte, error) {
+	if abs, err := p.Abs(filename); err == nil {
+		if code, ok := p.files[abs]; ok {
+			return code, nil
+		}
+	}
+	return p.FileSystem.ReadFile(filename)
+}
+
+// Reduce reads Go+ source files of the package in dir, or files in dir if
+// files isn't nil, which crash the compiler as err with conf, and reduces
+// them to a minimal reproduction of the crash within ReduceTimeout. It
+// reports whether they are reduced: they aren't if the crash doesn't
+// reproduce.
+func Reduce(dir string, files []string, conf *gop.Config, err *cl.InternalError) (srcs []Source, reduced bool) {
+	if conf == nil {
+		conf = new(gop.Config)
+	}
+	mod, e := gop.LoadMod(dir)
+	if e != nil {
+		return
+	}
+	base := conf.FS
+	if base == nil {
+		base = fsx.Local
+	}
+	names := files
+	if names == nil {
+		entries, e := base.ReadDir(dir)
+		if e != nil {
+			return
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			if entry.IsDir() || strings.HasPrefix(name, "_") {
+				continue
+			}
+			switch ext := filepath.Ext(name); {
+			case ext == ".gop", ext == ".gox", mod.IsClass(ext):
+				names = append(names, filepath.Join(dir, name))
+			}
+		}
+	}
+	fs := &overlayFS{FileSystem: base, files: make(map[string][]byte)}
+	abs := make([]string, 0, len(names))
+	for _, name := range names {
+		file, e := base.Abs(name)
+		if e != nil {
+			continue
+		}
+		code, e := base.ReadFile(file)
+		if e != nil {
+			continue
+		}
+		srcs = append(srcs, Source{File: name, Code: code})
+		abs = append(abs, file)
+		fs.files[file] = code
+	}
+
+	c := *conf
+	c.FS = fs
+	c.OnCrash, c.OnCompiled, c.OnProgress = nil, nil, nil
+	if c.Importer == nil { // share the importer to save time of reproducing
+		gopEnv := c.Gop
+		if gopEnv == nil {
+			gopEnv = gopenv.Get()
+		}
+		c.Fset = token.NewFileSet()
+		c.Importer = gop.NewImporter(mod, gopEnv, c.Fset)
+	}
+	want := fmt.Sprint(err.Value)
+	crashes := func() bool {
+		var e error
+		if files == nil {
+			_, _, e = gop.LoadDir(dir, &c, true)
+		} else {
+			_, e = gop.LoadFiles(dir, files, &c)
+		}
+		ie, ok := cl.InternalErrorOf(e)
+		return ok && fmt.Sprint(ie.Value) == want
+	}
+	if !crashes() {
+		return
+	}
+	deadline := time.Now().Add(ReduceTimeout)
+	for i := range srcs {
+		file := abs[i]
+		srcs[i].Code = reduce.Lines(srcs[i].Code, func(code []byte) bool {
+			if time.Now().After(deadline) {
+				return false
+			}
+			fs.files[file] = code
+			return crashes()
+		})
+		fs.files[file] = srcs[i].Code
+	}
+	return srcs, true
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,274 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package diag renders diagnostics (errors and warnings reported by the
+// parser, the compiler and gop vet) for humans. A diagnostic is printed in
+// the usual "file:line:col: msg" form, followed by the offending line of
+// source code with a caret under the column:
+//
+//	main.gop:3:6: undefined: foo
+//	 3 | x := foo + 1
+//	   |      ^~~
+//
+// Commands of gop share a Printer so that they produce consistent errors.
+package diag
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/types"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/goplus/gop/cl"
+	"github.com/goplus/gop/scanner"
+	"github.com/goplus/gop/token"
+	"github.com/goplus/gox"
+	xerrors "github.com/qiniu/x/errors"
+)
+
+// -----------------------------------------------------------------------------
+
+// Diag is a diagnostic to print.
+type Diag struct {
+	Pos     token.Position // position of the diagnostic (optional)
+	End     token.Position // end of the diagnostic range (optional)
+	Msg     string
+	Warning bool
+	Hint    bool // a hint (eg. of performance) rather than a problem
+}
+
+// String returns d in the form "file:line:col: msg".
+func (d *Diag) String() string {
+	if d.Pos.IsValid() || d.Pos.Filename != "" {
+		return d.Pos.String() + ": " + d.Msg
+	}
+	return d.Msg
+}
+
+// Diags returns diagnostics in err. Other errors (eg. I/O errors) are
+// returned as diagnostics with only a message.
+func Diags(err error) []*Diag {
+	return appendDiags(nil, err)
+}
+
+func appendDiags(ret []*Diag, err error) []*Diag {
+	if err == nil {
+		return ret
+	}
+	var list xerrors.List
+	if errors.As(err, &list) {
+		for _, e := range list {
+			ret = appendDiags(ret, e)
+		}
+		return ret
+	}
+	var serrs scanner.ErrorList
+	if errors.As(err, &serrs) {
+		for _, e := range serrs {
+			ret = append(ret, &Diag{Pos: e.Pos, Msg: e.Msg})
+		}
+		return ret
+	}
+	var serr *scanner.Error
+	if errors.As(err, &serr) {
+		return append(ret, &Diag{Pos: serr.Pos, Msg: serr.Msg})
+	}
+	var d *cl.Diagnostic
+	if errors.As(err, &d) {
+		ret = append(ret, &Diag{
+			Pos: d.Fset.Position(d.Pos), Msg: d.Msg, Warning: d.Severity == cl.SeverityWarning})
+		if d.End.IsValid() {
+			ret[len(ret)-1].End = d.Fset.Position(d.End)
+		}
+		return ret
+	}
+	var cerr *gox.CodeError
+	if errors.As(err, &cerr) {
+		return append(ret, &Diag{Pos: cerr.Fset.Position(cerr.Pos), Msg: cerr.Msg})
+	}
+	var terr types.Error
+	if errors.As(err, &terr) && terr.Fset != nil {
+		return append(ret, &Diag{Pos: terr.Fset.Position(terr.Pos), Msg: terr.Msg, Warning: terr.Soft})
+	}
+	return append(ret, &Diag{Msg: strings.TrimSuffix(err.Error(), "\n")})
+}
+
+// -----------------------------------------------------------------------------
+
+// ANSI escape sequences used when Color is on.
+const (
+	colorReset   = "\x1b[0m"
+	colorBold    = "\x1b[1m"
+	colorRed     = "\x1b[1;31m"
+	colorMagenta = "\x1b[1;35m"
+	colorGreen   = "\x1b[1;32m"
+	colorBlue    = "\x1b[1;34m"
+)
+
+// Printer prints diagnostics with source snippets.
+type Printer struct {
+	Color    bool                                  // use ANSI colors
+	ReadFile func(filename string) ([]byte, error) // defaults to os.ReadFile
+
+	mu    sync.Mutex
+	lines map[string][][]byte // source lines of files, nil if unreadable
+}
+
+// NewPrinter creates a Printer which prints to f. Colors are used if f is a
+// terminal, unless disabled by the NO_COLOR environment variable or
+// TERM=dumb.
+func NewPrinter(f *os.File) *Printer {
+	return &Printer{Color: useColor(f)}
+}
+
+func useColor(f *os.File) bool {
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// PrintError prints diagnostics in err to w.
+func (p *Printer) PrintError(w io.Writer, err error) {
+	for _, d := range Diags(err) {
+		p.Print(w, d)
+	}
+}
+
+// PrintWarning prints diagnostics in err to w, as warnings.
+func (p *Printer) PrintWarning(w io.Writer, err error) {
+	for _, d := range Diags(err) {
+		d.Warning = true
+		p.Print(w, d)
+	}
+}
+
+// Print prints the diagnostic d to w.
+func (p *Printer) Print(w io.Writer, d *Diag) {
+	var b bytes.Buffer
+	if d.Warning {
+		p.paint(&b, colorMagenta, "warning:")
+		b.WriteByte(' ')
+	}
+	if d.Pos.Filename != "" || d.Pos.IsValid() {
+		p.paint(&b, colorBold, d.Pos.String()+":")
+		b.WriteByte(' ')
+	}
+	switch {
+	case d.Hint:
+		b.WriteString(d.Msg)
+	case d.Warning:
+		p.paint(&b, colorBold, d.Msg)
+	default:
+		p.paint(&b, colorRed, d.Msg)
+	}
+	b.WriteByte('\n')
+	p.snippet(&b, d)
+
+	p.mu.Lock() // keep lines of concurrent diagnostics together
+	defer p.mu.Unlock()
+	w.Write(b.Bytes())
+}
+
+func (p *Printer) paint(b *bytes.Buffer, color, text string) {
+	if p.Color {
+		b.WriteString(color)
+		b.WriteString(text)
+		b.WriteString(colorReset)
+	} else {
+		b.WriteString(text)
+	}
+}
+
+// snippet prints the source line of d, with a caret under the column, or an
+// underline of the range if d.End is on the same line.
+func (p *Printer) snippet(b *bytes.Buffer, d *Diag) {
+	if d.Pos.Filename == "" || d.Pos.Line <= 0 {
+		return
+	}
+	line := p.line(d.Pos.Filename, d.Pos.Line)
+	if line == nil {
+		return
+	}
+	num := fmt.Sprint(d.Pos.Line)
+	gutter := strings.Repeat(" ", len(num)+2)
+	b.WriteByte(' ')
+	p.paint(b, colorBlue, num+" |")
+	b.WriteByte(' ')
+	b.Write(line)
+	b.WriteByte('\n')
+	if d.Pos.Column <= 0 {
+		return
+	}
+	b.WriteString(gutter)
+	p.paint(b, colorBlue, "|")
+	b.WriteByte(' ')
+	col := d.Pos.Column - 1
+	if col > len(line) {
+		col = len(line)
+	}
+	for _, c := range string(line[:col]) { // keep tabs to align with the line
+		if c == '\t' {
+			b.WriteByte('\t')
+		} else {
+			b.WriteByte(' ')
+		}
+	}
+	width := 1
+	if d.End.Line == d.Pos.Line && d.End.Column > d.Pos.Column {
+		end := d.End.Column - 1
+		if end > len(line) {
+			end = len(line)
+		}
+		if n := utf8.RuneCount(line[col:end]); n > 1 {
+			width = n
+		}
+	}
+	p.paint(b, colorGreen, "^"+strings.Repeat("~", width-1))
+	b.WriteByte('\n')
+}
+
+// line returns the n-th (1-based) line of file, or nil if not found.
+func (p *Printer) line(file string, n int) []byte {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	lines, ok := p.lines[file]
+	if !ok {
+		readFile := p.ReadFile
+		if readFile == nil {
+			readFile = os.ReadFile
+		}
+		if src, err := readFile(file); err == nil {
+			lines = bytes.Split(src, []byte{'\n'})
+		}
+		if p.lines == nil {
+			p.lines = make(map[string][][]byte)
+		}
+		p.lines[file] = lines
+	}
+	if n > len(lines) {
+		return nil
+	}
+	return bytes.TrimRight(lines[n-1], "\r")
+}
+
+// -----------------------------------------------------------------------------
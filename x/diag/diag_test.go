@@ -0,0 +1,112 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package diag_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/goplus/gop/cl"
+	"github.com/goplus/gop/parser"
+	"github.com/goplus/gop/token"
+	"github.com/goplus/gop/x/diag"
+	"github.com/goplus/gox"
+	xerrors "github.com/qiniu/x/errors"
+)
+
+func newPrinter(files map[string]string) *diag.Printer {
+	return &diag.Printer{
+		ReadFile: func(filename string) ([]byte, error) {
+			if src, ok := files[filename]; ok {
+				return []byte(src), nil
+			}
+			return nil, os.ErrNotExist
+		},
+	}
+}
+
+func TestParseError(t *testing.T) {
+	src := "package main\n\nfunc main() {\n\tx := (1 + \n}\n"
+	fset := token.NewFileSet()
+	_, err := parser.ParseFile(fset, "/foo/bar.gop", src, 0)
+	if err == nil {
+		t.Fatal("ParseFile: no error")
+	}
+	var b bytes.Buffer
+	newPrinter(map[string]string{"/foo/bar.gop": src}).PrintError(&b, err)
+	ds := diag.Diags(err)
+	if len(ds) == 0 {
+		t.Fatal("Diags:", err)
+	}
+	if got := b.String(); !bytes.HasPrefix(b.Bytes(), []byte(ds[0].String()+"\n 5 | }\n   | ^\n")) {
+		t.Fatal("PrintError:", got)
+	}
+}
+
+func TestCompileError(t *testing.T) {
+	src := "x := foo + 1\n\tprintln(\"héllo\", foo)\n"
+	fset := token.NewFileSet()
+	f := fset.AddFile("main.gop", -1, len(src))
+	f.SetLinesForContent([]byte(src))
+	base := token.Pos(f.Base())
+	var list xerrors.List
+	list.Add(&cl.Diagnostic{
+		CodeError: gox.CodeError{Fset: fset, Pos: base + 5, Msg: "undefined: foo"}, End: base + 8})
+	list.Add(&cl.Diagnostic{
+		CodeError: gox.CodeError{Fset: fset, Pos: base + 32, Msg: "undefined: foo"},
+		Severity:  cl.SeverityWarning})
+	list.Add(errors.New("no Go files"))
+	var b bytes.Buffer
+	newPrinter(map[string]string{"main.gop": src}).PrintError(&b, xerrors.NewWith(list.ToError(), "", 0, "cl.NewPackage"))
+	if got := b.String(); got != `main.gop:1:6: undefined: foo
+ 1 | x := foo + 1
+   |      ^~~
+warning: main.gop:2:20: undefined: foo
+ 2 | 	println("héllo", foo)
+   | 	                 ^
+no Go files
+` {
+		t.Fatal("PrintError:", got)
+	}
+}
+
+func TestColor(t *testing.T) {
+	p := newPrinter(map[string]string{"a.gop": "echo x\n"})
+	p.Color = true
+	var b bytes.Buffer
+	p.Print(&b, &diag.Diag{Pos: token.Position{Filename: "a.gop", Line: 1, Column: 6}, Msg: "undefined: x"})
+	if got := b.String(); got != "\x1b[1ma.gop:1:6:\x1b[0m \x1b[1;31mundefined: x\x1b[0m\n"+
+		" \x1b[1;34m1 |\x1b[0m echo x\n"+
+		"   \x1b[1;34m|\x1b[0m      \x1b[1;32m^\x1b[0m\n" {
+		t.Fatalf("Print: %q", got)
+	}
+	b.Reset()
+	p.PrintWarning(&b, errors.New("deprecated"))
+	if got := b.String(); got != "\x1b[1;35mwarning:\x1b[0m \x1b[1mdeprecated\x1b[0m\n" {
+		t.Fatalf("PrintWarning: %q", got)
+	}
+}
+
+func TestNoSource(t *testing.T) {
+	var b bytes.Buffer
+	newPrinter(nil).Print(&b, &diag.Diag{Pos: token.Position{Filename: "b.gop", Line: 3, Column: 1}, Msg: "syntax error"})
+	if got := b.String(); got != "b.gop:3:1: syntax error\n" {
+		t.Fatal("Print:", got)
+	}
+}
@@ -0,0 +1,190 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package diff computes line-oriented differences between two texts and
+// formats them in the unified diff format.
+package diff
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// -----------------------------------------------------------------------------
+
+const (
+	contextLines = 3
+
+	// maxCells limits memory used by the LCS table. If the differing part of
+	// two texts is larger, it is reported as replaced as a whole.
+	maxCells = 1 << 24
+)
+
+type op struct {
+	kind byte // ' ', '-' or '+'
+	text string
+}
+
+// Unified returns the unified diff of old and new texts, or nil if they are
+// the same. oldName and newName are used in the file header.
+func Unified(oldName, newName string, old, new []byte) []byte {
+	if bytes.Equal(old, new) {
+		return nil
+	}
+	ops := lineOps(splitLines(old), splitLines(new))
+
+	var hunks [][2]int
+	for k, o := range ops {
+		if o.kind == ' ' {
+			continue
+		}
+		if n := len(hunks); n > 0 && k <= hunks[n-1][1]+2*contextLines {
+			hunks[n-1][1] = k + 1
+		} else {
+			hunks = append(hunks, [2]int{k, k + 1})
+		}
+	}
+
+	// aline[k], bline[k]: number of old/new lines before ops[k]
+	aline := make([]int, len(ops)+1)
+	bline := make([]int, len(ops)+1)
+	for k, o := range ops {
+		aline[k+1], bline[k+1] = aline[k], bline[k]
+		if o.kind != '+' {
+			aline[k+1]++
+		}
+		if o.kind != '-' {
+			bline[k+1]++
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "--- %s\n+++ %s\n", oldName, newName)
+	for _, h := range hunks {
+		start, end := h[0]-contextLines, h[1]+contextLines
+		if start < 0 {
+			start = 0
+		}
+		if end > len(ops) {
+			end = len(ops)
+		}
+		fmt.Fprintf(&buf, "@@ -%s +%s @@\n",
+			hunkRange(aline[start], aline[end]), hunkRange(bline[start], bline[end]))
+		for _, o := range ops[start:end] {
+			buf.WriteByte(o.kind)
+			buf.WriteString(o.text)
+			if !strings.HasSuffix(o.text, "\n") {
+				buf.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+	}
+	return buf.Bytes()
+}
+
+func hunkRange(from, to int) string {
+	switch n := to - from; n {
+	case 0:
+		return fmt.Sprintf("%d,0", from)
+	case 1:
+		return fmt.Sprint(from + 1)
+	default:
+		return fmt.Sprintf("%d,%d", from+1, n)
+	}
+}
+
+// splitLines splits text into lines, each of which keeps its trailing "\n".
+func splitLines(text []byte) []string {
+	if len(text) == 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(string(text), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// lineOps returns an edit script which transforms lines a into lines b,
+// based on the longest common subsequence of them.
+func lineOps(a, b []string) []op {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix &&
+		a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	ops := make([]op, 0, len(a)+len(b))
+	for _, line := range a[:prefix] {
+		ops = append(ops, op{' ', line})
+	}
+	ma, mb := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	n, m := len(ma), len(mb)
+	if n*m > maxCells {
+		for _, line := range ma {
+			ops = append(ops, op{'-', line})
+		}
+		for _, line := range mb {
+			ops = append(ops, op{'+', line})
+		}
+	} else {
+		// lcs[i*(m+1)+j]: length of LCS of ma[i:] and mb[j:]
+		lcs := make([]int32, (n+1)*(m+1))
+		for i := n - 1; i >= 0; i-- {
+			for j := m - 1; j >= 0; j-- {
+				k := i*(m+1) + j
+				if ma[i] == mb[j] {
+					lcs[k] = lcs[k+m+2] + 1
+				} else if down, right := lcs[k+m+1], lcs[k+1]; down >= right {
+					lcs[k] = down
+				} else {
+					lcs[k] = right
+				}
+			}
+		}
+		i, j := 0, 0
+		for i < n && j < m {
+			k := i*(m+1) + j
+			switch {
+			case ma[i] == mb[j]:
+				ops = append(ops, op{' ', ma[i]})
+				i++
+				j++
+			case lcs[k+m+1] >= lcs[k+1]:
+				ops = append(ops, op{'-', ma[i]})
+				i++
+			default:
+				ops = append(ops, op{'+', mb[j]})
+				j++
+			}
+		}
+		for ; i < n; i++ {
+			ops = append(ops, op{'-', ma[i]})
+		}
+		for ; j < m; j++ {
+			ops = append(ops, op{'+', mb[j]})
+		}
+	}
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, op{' ', line})
+	}
+	return ops
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,87 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package diff_test
+
+import (
+	"testing"
+
+	"github.com/goplus/gop/x/diff"
+)
+
+func TestUnified(t *testing.T) {
+	cases := []struct {
+		name     string
+		old, new string
+		want     string
+	}{
+		{"same", "a\nb\n", "a\nb\n", ""},
+		{"change", "a\nb\nc\n", "a\nx\nc\n", `--- old
++++ new
+@@ -1,3 +1,3 @@
+ a
+-b
++x
+ c
+`},
+		{"add", "", "a\n", `--- old
++++ new
+@@ -0,0 +1 @@
++a
+`},
+		{"noeol", "a\n", "a", `--- old
++++ new
+@@ -1 +1 @@
+-a
++a
+\ No newline at end of file
+`},
+		{"hunks", "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n", "0\n1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n", `--- old
++++ new
+@@ -1,3 +1,4 @@
++0
+ 1
+ 2
+ 3
+@@ -9,4 +10,3 @@
+ 9
+ 10
+ 11
+-12
+`},
+		{"merge", "1\n2\n3\n4\n5\n6\n7\n", "1\nx\n3\n4\n5\n6\ny\n", `--- old
++++ new
+@@ -1,7 +1,7 @@
+ 1
+-2
++x
+ 3
+ 4
+ 5
+ 6
+-7
++y
+`},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := string(diff.Unified("old", "new", []byte(c.old), []byte(c.new)))
+			if got != c.want {
+				t.Fatalf("got:\n%s\nwant:\n%s", got, c.want)
+			}
+		})
+	}
+}
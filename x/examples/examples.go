@@ -0,0 +1,380 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package examples extracts runnable examples from doc comments of Go+
+// packages into standalone .gop scripts, and verifies their outputs.
+//
+// An example is an indented code block following a line `Example:` (or
+// `Example (suffix):` to name it) in the doc comment of a package or a
+// top-level declaration. As examples of Go tests, it may end with an output
+// comment, which is the expected output of running it:
+//
+//	// Add returns a + b.
+//	//
+//	// Example:
+//	//
+//	//	println foo.Add(1, 2)
+//	//	// Output: 3
+//	func Add(a, b int) int
+package examples
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/goplus/gop"
+	"github.com/goplus/gop/ast"
+	"github.com/goplus/gop/parser"
+	"github.com/goplus/gop/token"
+	"github.com/goplus/gop/x/pathutil"
+)
+
+// -----------------------------------------------------------------------------
+
+// An Example is a runnable example in a doc comment.
+type Example struct {
+	// Name is the name of the documented object, eg. `Add`, `T_Hello` for a
+	// method and `Package` for the package, followed by the suffix of the
+	// example (or its index if there are many), eg. `Add_negative`.
+	Name string
+	Code string    // the code block, including its output comment
+	Pos  token.Pos // position of the doc comment
+
+	// Output is the expected output if HasOutput is true.
+	Output    string
+	HasOutput bool
+}
+
+// FileName returns the name of the script file of the example.
+func (p *Example) FileName() string {
+	return p.Name + ".gop"
+}
+
+// Script returns the example as a standalone .gop script of the package
+// pkgName, whose path is pkgPath. The package is imported if it's used and
+// the example doesn't import packages itself.
+func (p *Example) Script(pkgName, pkgPath string) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Example %s of package %s.\n\n", p.Name, pkgPath)
+	if pkgPath != "" && pkgName != "main" && !hasImport(p.Code) && strings.Contains(p.Code, pkgName+".") {
+		fmt.Fprintf(&b, "import %q\n\n", pkgPath)
+	}
+	b.WriteString(p.Code)
+	b.WriteByte('\n')
+	return b.Bytes()
+}
+
+func hasImport(code string) bool {
+	for _, line := range strings.Split(code, "\n") {
+		if strings.HasPrefix(line, "import ") || line == "import (" {
+			return true
+		}
+	}
+	return false
+}
+
+// -----------------------------------------------------------------------------
+
+// FromDoc returns examples in the doc comment of the object name.
+func FromDoc(name string, doc *ast.CommentGroup) (ret []*Example) {
+	if doc == nil {
+		return
+	}
+	lines := strings.Split(doc.Text(), "\n")
+	for i := 0; i < len(lines); i++ {
+		suffix, ok := exampleHeading(lines[i])
+		if !ok {
+			continue
+		}
+		i++
+		for i < len(lines) && strings.TrimSpace(lines[i]) == "" {
+			i++
+		}
+		start := i
+		for i < len(lines) && (strings.TrimSpace(lines[i]) == "" || isIndented(lines[i])) {
+			i++
+		}
+		block := lines[start:i]
+		for len(block) > 0 && strings.TrimSpace(block[len(block)-1]) == "" {
+			block = block[:len(block)-1]
+		}
+		i--
+		if len(block) == 0 {
+			continue
+		}
+		ex := &Example{Name: name, Pos: doc.Pos(), Code: strings.Join(unindent(block), "\n")}
+		if suffix != "" {
+			ex.Name += "_" + suffix
+		}
+		ex.Output, ex.HasOutput = outputOf(ex.Code)
+		ret = append(ret, ex)
+	}
+	if len(ret) > 1 { // unnamed examples are numbered
+		for i, ex := range ret {
+			if ex.Name == name && i > 0 {
+				ex.Name = fmt.Sprintf("%s_%d", name, i+1)
+			}
+		}
+	}
+	return
+}
+
+// exampleHeading reports whether line is `Example:` or `Example (suffix):`.
+func exampleHeading(line string) (suffix string, ok bool) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "Example") || !strings.HasSuffix(line, ":") {
+		return
+	}
+	line = strings.TrimSpace(line[len("Example") : len(line)-1])
+	if line == "" {
+		return "", true
+	}
+	if strings.HasPrefix(line, "(") && strings.HasSuffix(line, ")") {
+		suffix = strings.TrimSpace(line[1 : len(line)-1])
+		return strings.Join(strings.Fields(suffix), "_"), suffix != ""
+	}
+	return
+}
+
+func isIndented(line string) bool {
+	return strings.HasPrefix(line, "\t") || strings.HasPrefix(line, " ")
+}
+
+// unindent removes the longest common indentation of non-blank lines.
+func unindent(lines []string) []string {
+	prefix, first := "", true
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if first {
+			prefix, first = indent, false
+		}
+		for !strings.HasPrefix(indent, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	ret := make([]string, len(lines))
+	for i, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			ret[i] = strings.TrimPrefix(line, prefix)
+		}
+	}
+	return ret
+}
+
+// outputOf returns the expected output in the trailing output comment of
+// code, ie. `// Output:` and comment lines following it.
+func outputOf(code string) (output string, ok bool) {
+	lines := strings.Split(code, "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := strings.TrimSpace(lines[i])
+		if !strings.HasPrefix(line, "//") {
+			return
+		}
+		text := strings.TrimSpace(strings.TrimPrefix(line, "//"))
+		if strings.HasPrefix(text, "Output:") {
+			out := []string{strings.TrimSpace(text[len("Output:"):])}
+			for _, l := range lines[i+1:] {
+				l = strings.TrimPrefix(strings.TrimSpace(l), "//")
+				out = append(out, strings.TrimPrefix(l, " "))
+			}
+			return strings.TrimSpace(strings.Join(out, "\n")), true
+		}
+	}
+	return
+}
+
+// Extract returns examples in doc comments of files of a package, sorted by
+// their positions.
+func Extract(fset *token.FileSet, files []*ast.File) (ret []*Example) {
+	for _, f := range files {
+		ret = append(ret, FromDoc("Package", f.Doc)...)
+		for _, decl := range f.Decls {
+			switch d := decl.(type) {
+			case *ast.FuncDecl:
+				if d.Shadow {
+					continue
+				}
+				name := d.Name.Name
+				if d.Recv != nil && len(d.Recv.List) > 0 {
+					name = recvName(d.Recv.List[0].Type) + "_" + name
+				}
+				ret = append(ret, FromDoc(name, d.Doc)...)
+			case *ast.GenDecl:
+				for _, spec := range d.Specs {
+					doc, name := d.Doc, ""
+					switch s := spec.(type) {
+					case *ast.TypeSpec:
+						name = s.Name.Name
+						if s.Doc != nil {
+							doc = s.Doc
+						}
+					case *ast.ValueSpec:
+						name = s.Names[0].Name
+						if s.Doc != nil {
+							doc = s.Doc
+						}
+					default:
+						continue
+					}
+					if len(d.Specs) > 1 && doc == d.Doc { // doc of a group
+						continue
+					}
+					ret = append(ret, FromDoc(name, doc)...)
+				}
+			}
+		}
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		a, b := fset.Position(ret[i].Pos), fset.Position(ret[j].Pos)
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+		return a.Offset < b.Offset
+	})
+	names := make(map[string]int) // eg. examples of package docs in files
+	for _, ex := range ret {
+		if n := names[ex.Name]; n > 0 {
+			names[ex.Name] = n + 1
+			ex.Name = fmt.Sprintf("%s_%d", ex.Name, n+1)
+		} else {
+			names[ex.Name] = 1
+		}
+	}
+	return
+}
+
+func recvName(t ast.Expr) string {
+	switch v := t.(type) {
+	case *ast.StarExpr:
+		return recvName(v.X)
+	case *ast.Ident:
+		return v.Name
+	case *ast.IndexExpr:
+		return recvName(v.X)
+	case *ast.IndexListExpr:
+		return recvName(v.X)
+	}
+	return ""
+}
+
+// -----------------------------------------------------------------------------
+
+// A Package is a Go+ package with examples in doc comments.
+type Package struct {
+	Dir      string
+	Name     string
+	Path     string // import path of the package, or Name if it isn't in a module
+	Examples []*Example
+}
+
+// LoadDir parses Go+ source files (except tests) of the package in dir, and
+// extracts examples of it.
+func LoadDir(dir string) (ret *Package, err error) {
+	if dir, err = pathutil.Abs(dir); err != nil {
+		return
+	}
+	mod, err := gop.LoadMod(dir)
+	if err != nil {
+		return
+	}
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDirEx(fset, dir, parser.Config{
+		ClassKind: mod.ClassKind,
+		Mode:      parser.ParseComments,
+		Filter: func(fi fs.FileInfo) bool {
+			name := fi.Name()
+			return !strings.HasSuffix(strings.TrimSuffix(name, filepath.Ext(name)), "_test")
+		},
+	})
+	if err != nil {
+		return
+	}
+	ret = &Package{Dir: dir}
+	for name, pkg := range pkgs {
+		if ret.Name != "" && name > ret.Name {
+			continue
+		}
+		files := make([]*ast.File, 0, len(pkg.Files))
+		for _, f := range pkg.Files {
+			files = append(files, f)
+		}
+		ret.Name, ret.Examples = name, Extract(fset, files)
+	}
+	ret.Path = ret.Name
+	if mod.HasModfile() {
+		if rel, ok := pathutil.Rel(mod.Root(), dir); ok {
+			ret.Path = path.Join(mod.Path(), filepath.ToSlash(rel))
+		}
+	}
+	return
+}
+
+// Run runs the example by `gopCmd run` and returns its standard output. The
+// script is written to a temporary directory in the package directory, so
+// that it imports the package from the same module.
+func (p *Package) Run(gopCmd string, ex *Example) (output string, err error) {
+	dir, err := os.MkdirTemp(p.Dir, ".examples-")
+	if err != nil {
+		return
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, ex.FileName())
+	if err = os.WriteFile(file, ex.Script(p.Name, p.Path), 0666); err != nil {
+		return
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(gopCmd, "run", file)
+	cmd.Dir = dir
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err = cmd.Run(); err != nil {
+		err = fmt.Errorf("%v\n%s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return stdout.String(), err
+}
+
+// Verify runs examples which have expected outputs, and reports failed ones
+// to w. It returns the number of examples run and failed.
+func (p *Package) Verify(gopCmd string, w io.Writer) (run, failed int) {
+	for _, ex := range p.Examples {
+		if !ex.HasOutput {
+			continue
+		}
+		run++
+		out, err := p.Run(gopCmd, ex)
+		if got := strings.TrimSpace(out); err != nil || got != ex.Output {
+			failed++
+			fmt.Fprintf(w, "--- FAIL: %s.%s (doc example)\n", p.Path, ex.Name)
+			if err != nil {
+				fmt.Fprintf(w, "%v\n", err)
+			}
+			fmt.Fprintf(w, "got:\n%s\nwant:\n%s\n", got, ex.Output)
+		}
+	}
+	return
+}
+
+// -----------------------------------------------------------------------------
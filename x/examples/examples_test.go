@@ -0,0 +1,133 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package examples_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/goplus/gop/ast"
+	"github.com/goplus/gop/parser"
+	"github.com/goplus/gop/token"
+	"github.com/goplus/gop/x/examples"
+)
+
+const testSrc = `// Package foo is for testing.
+//
+// Example:
+//
+//	import "example.com/foo"
+//
+//	println foo.Version
+package foo
+
+// Version is the version.
+const Version = "1.0"
+
+// Add returns a + b.
+//
+// Example:
+//
+//	println foo.Add(1, 2)
+//	// Output: 3
+//
+// Example (two lines):
+//
+//	println foo.Add(1, 2)
+//	println foo.Add(3, 4)
+//	// Output:
+//	// 3
+//	// 7
+func Add(a, b int) int {
+	return a + b
+}
+
+type T struct{}
+
+// Hello prints hello.
+//
+// Example:
+//
+//	t := &foo.T{}
+//	if true {
+//		t.Hello()
+//	}
+//
+// Example:
+//
+//	(&foo.T{}).Hello()
+//	// Output: hello
+func (t *T) Hello() {
+	println "hello"
+}
+`
+
+func TestExtract(t *testing.T) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "foo.gop", testSrc, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exs := examples.Extract(fset, []*ast.File{f})
+	var names []string
+	for _, ex := range exs {
+		names = append(names, ex.Name)
+	}
+	if ret := strings.Join(names, " "); ret != "Package Add Add_two_lines T_Hello T_Hello_2" {
+		t.Fatal("Extract:", ret)
+	}
+	if ex := exs[1]; !ex.HasOutput || ex.Output != "3" || ex.Code != "println foo.Add(1, 2)\n// Output: 3" {
+		t.Fatalf("Add: %#v", ex)
+	}
+	if ex := exs[2]; !ex.HasOutput || ex.Output != "3\n7" {
+		t.Fatalf("Add_two_lines: %#v", ex)
+	}
+	if ex := exs[3]; ex.HasOutput || ex.Code != "t := &foo.T{}\nif true {\n\tt.Hello()\n}" {
+		t.Fatalf("T_Hello: %#v", ex)
+	}
+
+	script := string(exs[1].Script("foo", "example.com/foo"))
+	if script != "// Example Add of package example.com/foo.\n\nimport \"example.com/foo\"\n\nprintln foo.Add(1, 2)\n// Output: 3\n" {
+		t.Fatal("Script:", script)
+	}
+	if script = string(exs[0].Script("foo", "example.com/foo")); strings.Count(script, "import") != 1 {
+		t.Fatal("Script: the package is imported twice\n", script)
+	}
+}
+
+func TestLoadDir(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":       "module example.com/foo\n\ngo 1.18\n",
+		"foo.gop":      testSrc,
+		"foo_test.gop": "package foo\n\n// Example:\n//\n//\tprintln 1\nfunc TestFoo() {}\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pkg, err := examples.LoadDir(dir)
+	if err != nil {
+		t.Fatal("LoadDir:", err)
+	}
+	if pkg.Name != "foo" || pkg.Path != "example.com/foo" || len(pkg.Examples) != 5 {
+		t.Fatalf("LoadDir: %#v", pkg)
+	}
+}
@@ -0,0 +1,343 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package explain implements an error tutor: a catalog of common errors with
+// extended explanations and minimal examples of how to fix them.
+package explain
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/goplus/gop/cl"
+	"github.com/goplus/gop/scanner"
+	xerrors "github.com/qiniu/x/errors"
+)
+
+// -----------------------------------------------------------------------------
+
+// Entry represents an error in the catalog.
+type Entry struct {
+	Code    string // error code, eg. "undefined"
+	Title   string
+	Explain string
+	Wrong   string // example with the error
+	Fixed   string // fixed example
+
+	pattern *regexp.Regexp // matches error messages
+}
+
+// Catalog lists all errors in the catalog.
+var Catalog = []*Entry{
+	{
+		Code:  "undefined",
+		Title: "undefined name",
+		Explain: `A name is used but not declared. Check the spelling, declare the name
+before using it, or import the package it comes from: unlike builtins
+such as println, package names like strings must be imported.`,
+		Wrong: `println strings.ToUpper("hi")`,
+		Fixed: `import "strings"
+
+println strings.ToUpper("hi")`,
+		pattern: regexp.MustCompile(`undefined: \S+`),
+	},
+	{
+		Code:  "no-field-or-method",
+		Title: "no field or method",
+		Explain: `A selector x.f is used, but the type of x has no field or method named f.
+Names are case-sensitive, and methods of a pointer type *T can't be
+called on a value of an interface type that doesn't have them.`,
+		Wrong: `s := "hello"
+println s.len`,
+		Fixed: `s := "hello"
+println s.len()`,
+		pattern: regexp.MustCompile(`undefined \(type .* has no field or method`),
+	},
+	{
+		Code:  "type-mismatch",
+		Title: "type mismatch",
+		Explain: `A value of one type is used where a value of another type is required.
+Go+ doesn't convert between types implicitly: convert the value
+explicitly, eg. int(x), string(r) or x.string, or change the type of the
+variable.`,
+		Wrong: `var n int
+n = "10"`,
+		Fixed: `import "strconv"
+
+var n int
+n, _ = strconv.Atoi("10")`,
+		pattern: regexp.MustCompile(`cannot use .* as (type )?\S|mismatched types`),
+	},
+	{
+		Code:  "assign-mismatch",
+		Title: "assignment mismatch",
+		Explain: `The number of variables on the left side of an assignment doesn't match
+the number of values on the right side. A function call returning
+multiple values must be assigned to the same number of variables (use _
+to ignore some of them).`,
+		Wrong: `import "strconv"
+
+n := strconv.Atoi("10")`,
+		Fixed: `import "strconv"
+
+n, _ := strconv.Atoi("10")`,
+		pattern: regexp.MustCompile(`assignment mismatch:`),
+	},
+	{
+		Code:  "unused-var",
+		Title: "unused variable",
+		Explain: `A local variable is declared but never used, which is usually a bug.
+Use the variable, remove it, or assign it to _ if the value is
+intentionally ignored.`,
+		Wrong: `func f() {
+	x := 1
+}`,
+		Fixed: `func f() {
+	x := 1
+	println x
+}`,
+		pattern: regexp.MustCompile(`declared (and|but) not used`),
+	},
+	{
+		Code:  "unused-import",
+		Title: "unused import",
+		Explain: `A package is imported but never used. Remove the import, or import it
+for its side effects only by the blank name _.`,
+		Wrong: `import "os"
+
+println "hi"`,
+		Fixed:   `println "hi"`,
+		pattern: regexp.MustCompile(`imported and not used`),
+	},
+	{
+		Code:  "missing-return",
+		Title: "missing return",
+		Explain: `A function with results must end in a return statement (or another
+terminating statement like panic) on every path.`,
+		Wrong: `func sign(x int) int {
+	if x < 0 {
+		return -1
+	}
+}`,
+		Fixed: `func sign(x int) int {
+	if x < 0 {
+		return -1
+	}
+	return 1
+}`,
+		pattern: regexp.MustCompile(`missing return`),
+	},
+	{
+		Code:  "return-count",
+		Title: "wrong number of return values",
+		Explain: `A return statement must return exactly the results declared by the
+function, in the same order.`,
+		Wrong: `func div(a, b int) (int, error) {
+	return a / b
+}`,
+		Fixed: `func div(a, b int) (int, error) {
+	return a / b, nil
+}`,
+		pattern: regexp.MustCompile(`(too many|too few|not enough) (arguments|values) to return`),
+	},
+	{
+		Code:  "redeclared",
+		Title: "name redeclared",
+		Explain: `A name is declared twice in the same scope. Rename one of them, or use
+= instead of := to assign to the existing variable.`,
+		Wrong: `a := 1
+a := 2`,
+		Fixed: `a := 1
+a = 2`,
+		pattern: regexp.MustCompile(`redeclared in this block|no new variables on left side of :=`),
+	},
+	{
+		Code:  "exhaustive",
+		Title: "missing cases in switch",
+		Explain: `A switch on a value of an enum-style type (a named type with constants)
+doesn't list all of its constants and has no default case. Add the
+missing cases or a default case.`,
+		Wrong: `type Color int
+
+const (
+	Red Color = iota
+	Green
+)
+
+func name(c Color) string {
+	switch c {
+	case Red:
+		return "red"
+	}
+	return ""
+}`,
+		Fixed: `type Color int
+
+const (
+	Red Color = iota
+	Green
+)
+
+func name(c Color) string {
+	switch c {
+	case Red:
+		return "red"
+	case Green:
+		return "green"
+	}
+	return ""
+}`,
+		pattern: regexp.MustCompile(`missing cases in switch`),
+	},
+	{
+		Code:  "implements",
+		Title: "interface not implemented",
+		Explain: `A type annotated by @implements doesn't have all methods of the
+interface, or has some of them with wrong signatures.`,
+		Wrong: `import "io"
+
+@implements(io.Reader)
+type Zero struct{}`,
+		Fixed: `import "io"
+
+@implements(io.Reader)
+type Zero struct{}
+
+func (Zero) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}`,
+		pattern: regexp.MustCompile(`does not implement`),
+	},
+	{
+		Code:  "syntax",
+		Title: "syntax error",
+		Explain: `The source code can't be parsed. Check the position reported: often a
+bracket, quote or comma is missing just before it.`,
+		Wrong:   `echo("hi"`,
+		Fixed:   `echo("hi")`,
+		pattern: regexp.MustCompile(`expected |unexpected |syntax error`),
+	},
+}
+
+// Lookup looks up an entry by its error code.
+func Lookup(code string) *Entry {
+	for _, e := range Catalog {
+		if e.Code == code {
+			return e
+		}
+	}
+	return nil
+}
+
+// Match returns the entry matching an error message, or nil if not found.
+func Match(msg string) *Entry {
+	for _, e := range Catalog {
+		if e.pattern.MatchString(msg) {
+			return e
+		}
+	}
+	return nil
+}
+
+// Code returns the error code of err in the catalog, or "" if not found.
+// err should be a single error (not an error list).
+func Code(err error) string {
+	if e := lookupErr(err); e != nil {
+		return e.Code
+	}
+	return ""
+}
+
+func lookupErr(err error) *Entry {
+	var diag *cl.Diagnostic
+	if errors.As(err, &diag) && diag.Code != "" {
+		if e := Lookup(diag.Code); e != nil {
+			return e
+		}
+	}
+	return Match(err.Error())
+}
+
+// -----------------------------------------------------------------------------
+
+// Fprint prints the explanation of the entry.
+func (p *Entry) Fprint(w io.Writer) {
+	fmt.Fprintf(w, "\n--- explain [%s]: %s ---\n%s\n", p.Code, p.Title, p.Explain)
+	if p.Wrong != "" {
+		fmt.Fprintf(w, "\nFor example:\n%s\nshould be:\n%s\n", indent(p.Wrong), indent(p.Fixed))
+	}
+}
+
+func indent(code string) string {
+	lines := strings.Split(code, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = "\t" + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Fprint prints explanations of errors in err, and error messages in output
+// (eg. output of the go command), once for each entry. It returns number of
+// entries printed.
+func Fprint(w io.Writer, err error, output string) int {
+	done := make(map[*Entry]bool)
+	add := func(e *Entry) {
+		if e != nil && !done[e] {
+			done[e] = true
+			e.Fprint(w)
+		}
+	}
+	for _, e := range flatten(err, nil) {
+		add(lookupErr(e))
+	}
+	for _, line := range strings.Split(output, "\n") {
+		if line != "" {
+			add(Match(line))
+		}
+	}
+	return len(done)
+}
+
+func flatten(err error, ret []error) []error {
+	if err == nil {
+		return ret
+	}
+	var list xerrors.List
+	if errors.As(err, &list) {
+		for _, e := range list {
+			ret = flatten(e, ret)
+		}
+		return ret
+	}
+	var serrs scanner.ErrorList
+	if errors.As(err, &serrs) {
+		for _, e := range serrs {
+			ret = append(ret, e)
+		}
+		return ret
+	}
+	return append(ret, err)
+}
+
+// -----------------------------------------------------------------------------
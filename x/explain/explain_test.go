@@ -0,0 +1,103 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package explain_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/goplus/gop/cl"
+	"github.com/goplus/gop/scanner"
+	"github.com/goplus/gop/token"
+	"github.com/goplus/gop/x/explain"
+	"github.com/goplus/gox"
+	xerrors "github.com/qiniu/x/errors"
+)
+
+func TestMatch(t *testing.T) {
+	cases := []struct {
+		msg, code string
+	}{
+		{"bar.gop:2:9: undefined: strings", "undefined"},
+		{"bar.gop:3:6: cannot use a (type string) as type int in slice literal", "type-mismatch"},
+		{"./a.gop:2:2: declared and not used: x", "unused-var"},
+		{"./a.gop:1:8: \"os\" imported and not used", "unused-import"},
+		{"bar.gop:2:1: assignment mismatch: 1 variables but 2 values", "assign-mismatch"},
+		{"bar.gop:4:1: missing return", "missing-return"},
+		{"bar.gop:4:1: no new variables on left side of :=", "redeclared"},
+		{"bar.gop:6:9: s.len undefined (type string has no field or method len)", "no-field-or-method"},
+		{"bar.gop:1:10: expected ')', found newline", "syntax"},
+		{"something else", ""},
+	}
+	for _, c := range cases {
+		code := ""
+		if e := explain.Match(c.msg); e != nil {
+			code = e.Code
+		}
+		if code != c.code {
+			t.Fatalf("Match(%q): got %q, want %q\n", c.msg, code, c.code)
+		}
+	}
+}
+
+func TestCatalog(t *testing.T) {
+	codes := make(map[string]bool)
+	for _, e := range explain.Catalog {
+		if codes[e.Code] {
+			t.Fatal("duplicated code:", e.Code)
+		}
+		codes[e.Code] = true
+		if explain.Lookup(e.Code) != e || e.Title == "" || e.Explain == "" || (e.Wrong == "") != (e.Fixed == "") {
+			t.Fatal("invalid entry:", e.Code)
+		}
+	}
+	if explain.Lookup("unknown") != nil {
+		t.Fatal("Lookup unknown")
+	}
+}
+
+func TestFprint(t *testing.T) {
+	fset := token.NewFileSet()
+	diag := &cl.Diagnostic{Code: "exhaustive"}
+	diag.Fset, diag.Msg = fset, "some message"
+	err := xerrors.List{
+		scanner.ErrorList{{Msg: "expected ')'"}, {Msg: "expected ';'"}},
+		&gox.CodeError{Fset: fset, Msg: "undefined: foo"},
+		&gox.CodeError{Fset: fset, Msg: "undefined: bar"},
+		diag,
+		errors.New("unknown"),
+	}
+	var b bytes.Buffer
+	n := explain.Fprint(&b, err, "# main\n./a.gop:2:2: declared and not used: x\nexit status 1\n")
+	if n != 4 {
+		t.Fatal("Fprint:", n, b.String())
+	}
+	out := b.String()
+	for _, code := range []string{"syntax", "undefined", "exhaustive", "unused-var"} {
+		if !strings.Contains(out, "--- explain ["+code+"]") {
+			t.Fatal("Fprint: no", code, "-", out)
+		}
+	}
+	if !strings.Contains(out, "should be:\n\tfunc f() {\n\t\tx := 1\n\t\tprintln x\n\t}\n") {
+		t.Fatal("Fprint:", out)
+	}
+	if explain.Code(diag) != "exhaustive" || explain.Code(errors.New("unknown")) != "" {
+		t.Fatal("Code failed")
+	}
+}
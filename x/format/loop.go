@@ -0,0 +1,394 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package format
+
+import (
+	"go/types"
+	"strconv"
+
+	"github.com/goplus/gop/ast"
+	"github.com/goplus/gop/token"
+)
+
+// -----------------------------------------------------------------------------
+
+// TypeOf returns the type of an expression, or nil if it is unknown.
+type TypeOf = func(e ast.Expr) types.Type
+
+// CompToLoop converts assignments of list and map comprehensions into
+// explicit for loops, and reports whether the file is changed:
+//
+//	a := [f(x) for x <- b if x > 0]   =>   var a []T
+//	                                       for x <- b if x > 0 {
+//	                                           a = append(a, f(x))
+//	                                       }
+//	m := {k: v for k, v <- b}         =>   m := make(map[K]V)
+//	                                       for k, v <- b {
+//	                                           m[k] = v
+//	                                       }
+//
+// If pos is valid, only the innermost assignment containing pos is converted.
+// typeOf is used to get types of variables assigned.
+func CompToLoop(file *ast.File, pos token.Pos, typeOf TypeOf) bool {
+	return rewriteStmts(file, pos, func(list []ast.Stmt, i int) ([]ast.Stmt, int) {
+		return compToLoop(file, list[i], typeOf), 1
+	})
+}
+
+// LoopToComp converts explicit for loops which build a slice with append or
+// build a map into list or map comprehensions, and reports whether the file
+// is changed. It is the reverse of CompToLoop. A loop is converted only if
+// the type of the comprehension is the same as the type of the variable.
+//
+// If pos is valid, only the innermost loop containing pos is converted.
+// typeOf is used to get types of variables and elements.
+func LoopToComp(file *ast.File, pos token.Pos, typeOf TypeOf) bool {
+	return rewriteStmts(file, pos, func(list []ast.Stmt, i int) ([]ast.Stmt, int) {
+		if i+1 < len(list) {
+			return loopToComp(file, list[i], list[i+1], typeOf), 2
+		}
+		return nil, 0
+	})
+}
+
+type stmtRewrite struct {
+	list  *[]ast.Stmt
+	i, n  int // replace (*list)[i:i+n] with stmts
+	stmts []ast.Stmt
+}
+
+// rewriteStmts calls fn for each statement in statement lists of file. fn
+// returns statements to replace n statements starting from list[i], or nil if
+// it doesn't apply.
+func rewriteStmts(file *ast.File, pos token.Pos, fn func(list []ast.Stmt, i int) ([]ast.Stmt, int)) bool {
+	var rewrites []*stmtRewrite
+	visit := func(list *[]ast.Stmt) {
+		for i := 0; i < len(*list); i++ {
+			stmts, n := fn(*list, i)
+			if stmts == nil {
+				continue
+			}
+			if pos.IsValid() && (pos < (*list)[i].Pos() || pos >= (*list)[i+n-1].End()) {
+				continue
+			}
+			rewrites = append(rewrites, &stmtRewrite{list, i, n, stmts})
+			i += n - 1
+		}
+	}
+	ast.Inspect(file, func(node ast.Node) bool {
+		switch v := node.(type) {
+		case *ast.BlockStmt:
+			visit(&v.List)
+		case *ast.CaseClause:
+			visit(&v.Body)
+		case *ast.CommClause:
+			visit(&v.Body)
+		}
+		return true
+	})
+	if rewrites == nil {
+		return false
+	}
+	if pos.IsValid() { // the innermost one
+		rewrites = rewrites[len(rewrites)-1:]
+	}
+	for i := len(rewrites) - 1; i >= 0; i-- {
+		r := rewrites[i]
+		list := *r.list
+		ret := make([]ast.Stmt, 0, len(list)-r.n+len(r.stmts))
+		ret = append(ret, list[:r.i]...)
+		ret = append(ret, r.stmts...)
+		*r.list = append(ret, list[r.i+r.n:]...)
+	}
+	return true
+}
+
+// -----------------------------------------------------------------------------
+
+func compToLoop(file *ast.File, stmt ast.Stmt, typeOf TypeOf) []ast.Stmt {
+	assign, ok := stmt.(*ast.AssignStmt)
+	if !ok || len(assign.Lhs) != 1 || len(assign.Rhs) != 1 || (assign.Tok != token.DEFINE && assign.Tok != token.ASSIGN) {
+		return nil
+	}
+	v, ok := assign.Lhs[0].(*ast.Ident)
+	if !ok || v.Name == "_" {
+		return nil
+	}
+	comp, ok := assign.Rhs[0].(*ast.ComprehensionExpr)
+	if !ok || comp.Elt == nil || refersTo(comp, v.Name) || isShadowed(file, "append", "make") {
+		return nil
+	}
+	for _, f := range comp.Fors {
+		if f.Init != nil {
+			return nil
+		}
+	}
+	kv, isMap := comp.Elt.(*ast.KeyValueExpr)
+	if isMap != (comp.Tok == token.LBRACE) {
+		return nil
+	}
+	var typ ast.Expr
+	if typeOf != nil {
+		typ = typeExpr(file, typeOf(v))
+	}
+	if typ == nil && (isMap || assign.Tok == token.DEFINE) {
+		return nil
+	}
+	var init, body ast.Stmt
+	lhs := &ast.Ident{Name: v.Name}
+	if isMap {
+		init = &ast.AssignStmt{
+			Lhs: []ast.Expr{v}, Tok: assign.Tok, TokPos: assign.TokPos,
+			Rhs: []ast.Expr{&ast.CallExpr{Fun: &ast.Ident{Name: "make"}, Args: []ast.Expr{typ}}},
+		}
+		body = &ast.AssignStmt{
+			Lhs: []ast.Expr{&ast.IndexExpr{X: lhs, Index: kv.Key}}, Tok: token.ASSIGN,
+			Rhs: []ast.Expr{kv.Value},
+		}
+	} else {
+		if assign.Tok == token.DEFINE {
+			init = &ast.DeclStmt{Decl: &ast.GenDecl{
+				TokPos: v.NamePos, Tok: token.VAR,
+				Specs: []ast.Spec{&ast.ValueSpec{Names: []*ast.Ident{v}, Type: typ}},
+			}}
+		} else {
+			init = &ast.AssignStmt{Lhs: []ast.Expr{v}, Tok: token.ASSIGN, TokPos: assign.TokPos, Rhs: []ast.Expr{&ast.Ident{Name: "nil"}}}
+		}
+		body = &ast.AssignStmt{
+			Lhs: []ast.Expr{lhs}, Tok: token.ASSIGN,
+			Rhs: []ast.Expr{&ast.CallExpr{
+				Fun: &ast.Ident{Name: "append"}, Args: []ast.Expr{&ast.Ident{Name: v.Name}, comp.Elt},
+			}},
+		}
+	}
+	for _, f := range comp.Fors { // the last for phrase is the outermost loop
+		body = &ast.ForPhraseStmt{ForPhrase: f, Body: &ast.BlockStmt{List: []ast.Stmt{body}}}
+	}
+	return []ast.Stmt{init, body}
+}
+
+func loopToComp(file *ast.File, stmt, next ast.Stmt, typeOf TypeOf) []ast.Stmt {
+	if typeOf == nil || isShadowed(file, "append", "make") {
+		return nil
+	}
+	v, tok, isMap := loopVar(stmt)
+	if v == nil {
+		return nil
+	}
+	outer, ok := next.(*ast.ForPhraseStmt)
+	if !ok {
+		return nil
+	}
+	var fors []*ast.ForPhrase
+	for {
+		loop, ok := next.(*ast.ForPhraseStmt)
+		if !ok || loop.Init != nil || len(loop.Body.List) != 1 || refersTo(loop.X, v.Name) || refersTo(loop.Cond, v.Name) {
+			return nil
+		}
+		fors = append([]*ast.ForPhrase{loop.ForPhrase}, fors...)
+		if next = loop.Body.List[0]; !isForPhrase(next) {
+			break
+		}
+	}
+	body, ok := next.(*ast.AssignStmt)
+	if !ok || body.Tok != token.ASSIGN || len(body.Lhs) != 1 || len(body.Rhs) != 1 {
+		return nil
+	}
+	typ := typeOf(v)
+	if typ == nil {
+		return nil
+	}
+	comp := &ast.ComprehensionExpr{Fors: fors, Rpos: outer.Body.Rbrace}
+	if isMap {
+		idx, ok := body.Lhs[0].(*ast.IndexExpr)
+		if !ok || !isIdent(idx.X, v.Name) || refersTo(idx.Index, v.Name) || refersTo(body.Rhs[0], v.Name) {
+			return nil
+		}
+		t, ok := typ.Underlying().(*types.Map)
+		if !ok || !isTypeOf(idx.Index, t.Key(), typeOf) || !isTypeOf(body.Rhs[0], t.Elem(), typeOf) {
+			return nil
+		}
+		comp.Tok, comp.Elt = token.LBRACE, &ast.KeyValueExpr{Key: idx.Index, Value: body.Rhs[0]}
+	} else {
+		call, ok := body.Rhs[0].(*ast.CallExpr)
+		if !ok || !isIdent(body.Lhs[0], v.Name) || !isIdent(call.Fun, "append") || len(call.Args) != 2 ||
+			call.Ellipsis.IsValid() || !isIdent(call.Args[0], v.Name) || refersTo(call.Args[1], v.Name) {
+			return nil
+		}
+		t, ok := typ.(*types.Slice)
+		if !ok || !isTypeOf(call.Args[1], t.Elem(), typeOf) {
+			return nil
+		}
+		comp.Tok, comp.Elt = token.LBRACK, call.Args[1]
+	}
+	return []ast.Stmt{&ast.AssignStmt{
+		Lhs: []ast.Expr{v}, Tok: tok, TokPos: v.End() + 1, Rhs: []ast.Expr{comp},
+	}}
+}
+
+// loopVar returns the variable initialized by stmt before a loop to build it:
+//   - `var v []T`, `v = nil` for slices
+//   - `v := make(map[K]V)`, `v = make(map[K]V)`, `var v = make(map[K]V)` for maps
+func loopVar(stmt ast.Stmt) (v *ast.Ident, tok token.Token, isMap bool) {
+	var rhs ast.Expr
+	switch s := stmt.(type) {
+	case *ast.DeclStmt:
+		decl, ok := s.Decl.(*ast.GenDecl)
+		if !ok || decl.Tok != token.VAR || len(decl.Specs) != 1 {
+			return
+		}
+		spec := decl.Specs[0].(*ast.ValueSpec)
+		if len(spec.Names) != 1 {
+			return
+		}
+		switch len(spec.Values) {
+		case 0:
+			if t, ok := spec.Type.(*ast.ArrayType); ok && t.Len == nil {
+				return spec.Names[0], token.DEFINE, false
+			}
+			return
+		case 1:
+			if spec.Type != nil {
+				return
+			}
+			v, tok, rhs = spec.Names[0], token.DEFINE, spec.Values[0]
+		default:
+			return
+		}
+	case *ast.AssignStmt:
+		if len(s.Lhs) != 1 || len(s.Rhs) != 1 || (s.Tok != token.DEFINE && s.Tok != token.ASSIGN) {
+			return
+		}
+		id, ok := s.Lhs[0].(*ast.Ident)
+		if !ok || id.Name == "_" {
+			return
+		}
+		if s.Tok == token.ASSIGN && isIdent(s.Rhs[0], "nil") {
+			return id, token.ASSIGN, false
+		}
+		v, tok, rhs = id, s.Tok, s.Rhs[0]
+	default:
+		return
+	}
+	if call, ok := rhs.(*ast.CallExpr); ok && isIdent(call.Fun, "make") && len(call.Args) == 1 {
+		if _, ok := call.Args[0].(*ast.MapType); ok {
+			return v, tok, true
+		}
+	}
+	return nil, 0, false
+}
+
+func isForPhrase(stmt ast.Stmt) bool {
+	_, ok := stmt.(*ast.ForPhraseStmt)
+	return ok
+}
+
+// isTypeOf reports whether type of the expression e inferred by Go+ is typ.
+func isTypeOf(e ast.Expr, typ types.Type, typeOf TypeOf) bool {
+	t := typeOf(e)
+	return t != nil && types.Identical(types.Default(t), typ)
+}
+
+func isIdent(x ast.Expr, name string) bool {
+	v, ok := x.(*ast.Ident)
+	return ok && v.Name == name
+}
+
+// refersTo reports whether node refers to an identifier named name.
+func refersTo(node ast.Node, name string) (found bool) {
+	if node == nil {
+		return
+	}
+	ast.Inspect(node, func(n ast.Node) bool {
+		if v, ok := n.(*ast.Ident); ok && v.Name == name {
+			found = true
+		}
+		return !found
+	})
+	return
+}
+
+// isShadowed reports whether any of the builtin names is redeclared in file.
+func isShadowed(file *ast.File, names ...string) bool {
+	for _, name := range names {
+		if file.Scope != nil && file.Scope.Lookup(name) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// typeExpr returns the type expression of t in file, or nil if it can't be
+// expressed (eg. a type of a package which isn't imported by file).
+func typeExpr(file *ast.File, t types.Type) ast.Expr {
+	switch t := t.(type) {
+	case *types.Basic:
+		if t.Info()&types.IsUntyped != 0 {
+			return nil
+		}
+		return &ast.Ident{Name: t.Name()}
+	case *types.Named:
+		obj := t.Obj()
+		if t.TypeArgs() != nil {
+			return nil
+		}
+		if obj.Pkg() == nil { // error
+			return &ast.Ident{Name: obj.Name()}
+		}
+		if name := importedAs(file, obj.Pkg()); name != "" {
+			return &ast.SelectorExpr{X: &ast.Ident{Name: name}, Sel: &ast.Ident{Name: obj.Name()}}
+		}
+		if file.Name != nil && file.Name.Name == obj.Pkg().Name() { // type of this package
+			return &ast.Ident{Name: obj.Name()}
+		}
+	case *types.Pointer:
+		if elem := typeExpr(file, t.Elem()); elem != nil {
+			return &ast.StarExpr{X: elem}
+		}
+	case *types.Slice:
+		if elem := typeExpr(file, t.Elem()); elem != nil {
+			return &ast.ArrayType{Elt: elem}
+		}
+	case *types.Map:
+		if key, val := typeExpr(file, t.Key()), typeExpr(file, t.Elem()); key != nil && val != nil {
+			return &ast.MapType{Key: key, Value: val}
+		}
+	case *types.Interface:
+		if t.Empty() {
+			return &ast.Ident{Name: "any"}
+		}
+	}
+	return nil
+}
+
+// importedAs returns the name of package pkg imported by file, or "" if it
+// isn't imported.
+func importedAs(file *ast.File, pkg *types.Package) string {
+	for _, spec := range file.Imports {
+		if path, err := strconv.Unquote(spec.Path.Value); err == nil && path == pkg.Path() {
+			if spec.Name == nil {
+				return pkg.Name()
+			}
+			if name := spec.Name.Name; name != "_" && name != "." {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,191 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package format
+
+import (
+	"bytes"
+	"go/importer"
+	"go/types"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/goplus/gop/ast"
+	"github.com/goplus/gop/format"
+	"github.com/goplus/gop/parser"
+	"github.com/goplus/gop/token"
+	"github.com/goplus/gop/x/typesutil"
+	"github.com/goplus/mod/gopmod"
+)
+
+func init() {
+	if os.Getenv("GOPROOT") == "" {
+		dir, _ := os.Getwd()
+		os.Setenv("GOPROOT", filepath.Clean(filepath.Join(dir, "./../..")))
+	}
+}
+
+func testLoop(t *testing.T, name string, rewrite func(*ast.File, token.Pos, TypeOf) bool, src, expect string) {
+	t.Run(name, func(t *testing.T) {
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, name+".gop", src, parser.ParseComments)
+		if err != nil {
+			t.Fatal("ParseFile failed:", err)
+		}
+		info := &typesutil.Info{
+			Types: make(map[ast.Expr]types.TypeAndValue),
+			Defs:  make(map[*ast.Ident]types.Object),
+			Uses:  make(map[*ast.Ident]types.Object),
+		}
+		conf := &types.Config{Importer: importer.Default(), Error: func(err error) {}}
+		opts := &typesutil.Config{Types: types.NewPackage("main", "main"), Fset: fset, Mod: gopmod.Default}
+		typesutil.NewChecker(conf, opts, nil, info).Files(nil, []*ast.File{f})
+		result := src
+		if rewrite(f, token.NoPos, info.TypeOf) {
+			var buf bytes.Buffer
+			if err = format.Node(&buf, fset, f); err != nil {
+				t.Fatal("format.Node failed:", err)
+			}
+			ret, err := format.Source(buf.Bytes(), false, name)
+			if err != nil {
+				t.Fatal("format.Source failed:", err)
+			}
+			result = string(ret)
+		}
+		if result != expect {
+			t.Fatalf("%s => Expect:\n%s\n=> Got:\n%s\n", name, expect, result)
+		}
+	})
+}
+
+func TestCompToLoop(t *testing.T) {
+	testLoop(t, "list", CompToLoop, `b := [1, 2, 3]
+a := [x * 2 for x <- b if x > 1]
+a = [x for x <- b]
+println a
+`, `b := [1, 2, 3]
+var a []int
+for x <- b if x > 1 {
+	a = append(a, x*2)
+}
+a = nil
+for x <- b {
+	a = append(a, x)
+}
+println a
+`)
+	testLoop(t, "map", CompToLoop, `import "strings"
+
+m := {strings.ToUpper(s): i for i, s <- ["a", "b"]}
+println m
+`, `import "strings"
+
+m := make(map[string]int)
+for i, s <- ["a", "b"] {
+	m[strings.ToUpper(s)] = i
+}
+println m
+`)
+	testLoop(t, "nested", CompToLoop, `println "nested"
+a := [x + y for x <- [1, 2] for y <- [3, 4]]
+println a
+`, `println "nested"
+var a []int
+for y <- [3, 4] {
+	for x <- [1, 2] {
+		a = append(a, x+y)
+	}
+}
+println a
+`)
+	testLoop(t, "not applicable", CompToLoop, `a := [1]
+a = [a[0] for x <- [1, 2]]
+b := {x for x <- [1, 2] if x > 1}
+println a, b
+`, `a := [1]
+a = [a[0] for x <- [1, 2]]
+b := {x for x <- [1, 2] if x > 1}
+println a, b
+`)
+}
+
+func TestLoopToComp(t *testing.T) {
+	testLoop(t, "list", LoopToComp, `b := [1, 2, 3]
+var a []int
+for x <- b if x > 1 {
+	a = append(a, x*2)
+}
+a = nil
+for x <- b {
+	a = append(a, x)
+}
+println a
+`, `b := [1, 2, 3]
+a := [x*2 for x <- b if x > 1]
+a = [x for x <- b]
+println a
+`)
+	testLoop(t, "map", LoopToComp, `m := make(map[string]int)
+for i, s <- ["a", "b"] {
+	m[s] = i
+}
+println m
+`, `m := {s: i for i, s <- ["a", "b"]}
+println m
+`)
+	testLoop(t, "nested", LoopToComp, `println "nested"
+var a []int
+for y <- [3, 4] {
+	for x <- [1, 2] {
+		a = append(a, x+y)
+	}
+}
+println a
+`, `println "nested"
+a := [x+y for x <- [1, 2] for y <- [3, 4]]
+println a
+`)
+	testLoop(t, "not applicable", LoopToComp, `var a []any
+for x <- [1, 2] {
+	a = append(a, x)
+}
+var b []int
+for x <- [1, 2] {
+	b = append(b, x)
+	println x
+}
+var c []int
+for x <- [1, 2] {
+	c = append(c, len(c))
+}
+println a, b, c
+`, `var a []any
+for x <- [1, 2] {
+	a = append(a, x)
+}
+var b []int
+for x <- [1, 2] {
+	b = append(b, x)
+	println x
+}
+var c []int
+for x <- [1, 2] {
+	c = append(c, len(c))
+}
+println a, b, c
+`)
+}
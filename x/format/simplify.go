@@ -0,0 +1,438 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package format
+
+import (
+	"bytes"
+	"reflect"
+	"strconv"
+
+	"github.com/goplus/gop/ast"
+	"github.com/goplus/gop/format"
+	"github.com/goplus/gop/parser"
+	"github.com/goplus/gop/token"
+)
+
+// -----------------------------------------------------------------------------
+
+// SimplifySource simplifies Go+ source code, and then formats it. It is used
+// by `gop fmt -s`. See Simplify.
+func SimplifySource(src []byte, class bool, filename ...string) (ret []byte, err error) {
+	var fname string
+	if filename != nil {
+		fname = filename[0]
+	}
+	mode := parser.ParseComments
+	if class {
+		mode |= parser.ParseGoPlusClass
+	}
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, fname, src, mode)
+	if err != nil {
+		return
+	}
+	if !Simplify(fset, f, src) {
+		return format.Source(src, class, fname)
+	}
+	var buf bytes.Buffer
+	if err = format.Node(&buf, fset, f); err != nil {
+		return
+	}
+	if ret, err = format.Source(buf.Bytes(), class, fname); err == nil {
+		ret = format.KeepFmtOff(src, ret)
+	}
+	return
+}
+
+// Simplify rewrites Go+ code into simpler Go+ idioms, and reports whether the
+// file is changed:
+//   - `for i := 0; i < n; i++ {...}` => `for i <- :n {...}`
+//   - `[]int{1, 2}` => `[1, 2]`, `map[string]int{"a": 1}` => `{"a": 1}`
+//   - `fmt.Println(...)` => `println(...)`
+//
+// Code in `//gop:fmt off` regions of src is kept as it is.
+func Simplify(fset *token.FileSet, file *ast.File, src []byte) bool {
+	s := &simplifier{file: file, fmtOff: fmtOffRanges(fset.File(file.Pos()), src)}
+	s.fmtName = s.importName("fmt")
+	for _, decl := range file.Decls {
+		applyNode(reflect.ValueOf(decl), s.simplify)
+	}
+	if s.changed && s.fmtName != "" && !s.isUsed(s.fmtName) {
+		s.deleteImport("fmt")
+	}
+	return s.changed
+}
+
+type simplifier struct {
+	file    *ast.File
+	fmtName string
+	fmtOff  [][2]token.Pos
+	changed bool
+}
+
+func (s *simplifier) simplify(node ast.Node) ast.Node {
+	if s.isFmtOff(node.Pos()) {
+		return node
+	}
+	var ret ast.Node
+	switch v := node.(type) {
+	case *ast.ForStmt:
+		ret = s.forStmt(v)
+	case *ast.CompositeLit:
+		ret = s.compositeLit(v)
+	case *ast.CallExpr:
+		ret = s.callExpr(v)
+	}
+	if ret == nil {
+		return node
+	}
+	s.changed = true
+	return ret
+}
+
+func (s *simplifier) isFmtOff(pos token.Pos) bool {
+	for _, r := range s.fmtOff {
+		if pos >= r[0] && pos < r[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// fmtOffRanges returns ranges of `//gop:fmt off` regions of src.
+func fmtOffRanges(f *token.File, src []byte) (ret [][2]token.Pos) {
+	if f == nil || !bytes.Contains(src, []byte(format.FmtOff)) {
+		return
+	}
+	off := -1
+	for pos := 0; pos < len(src); {
+		next := len(src)
+		if i := bytes.IndexByte(src[pos:], '\n'); i >= 0 {
+			next = pos + i + 1
+		}
+		line := string(bytes.TrimSpace(src[pos:next]))
+		if off < 0 {
+			if line == format.FmtOff {
+				off = pos
+			}
+		} else if line == format.FmtOn {
+			ret = append(ret, [2]token.Pos{f.Pos(off), f.Pos(pos)})
+			off = -1
+		}
+		pos = next
+	}
+	if off >= 0 {
+		ret = append(ret, [2]token.Pos{f.Pos(off), token.Pos(f.Base() + f.Size() + 1)})
+	}
+	return
+}
+
+// -----------------------------------------------------------------------------
+
+// forStmt simplifies `for i := 0; i < n; i++ {...}` into `for i <- :n {...}`,
+// if n is a literal, a constant, or a local variable (or len of it) which
+// isn't changed in the loop, and i isn't changed in the loop either.
+func (s *simplifier) forStmt(v *ast.ForStmt) ast.Node {
+	init, ok := v.Init.(*ast.AssignStmt)
+	if !ok || init.Tok != token.DEFINE || len(init.Lhs) != 1 || len(init.Rhs) != 1 || !isIntLit(init.Rhs[0], "0") {
+		return nil
+	}
+	i, ok := init.Lhs[0].(*ast.Ident)
+	if !ok || i.Obj == nil {
+		return nil
+	}
+	cond, ok := v.Cond.(*ast.BinaryExpr)
+	if !ok || cond.Op != token.LSS || !isObj(cond.X, i.Obj) {
+		return nil
+	}
+	switch post := v.Post.(type) {
+	case *ast.IncDecStmt:
+		if post.Tok != token.INC || !isObj(post.X, i.Obj) {
+			return nil
+		}
+	case *ast.AssignStmt:
+		if post.Tok != token.ADD_ASSIGN || len(post.Lhs) != 1 || !isObj(post.Lhs[0], i.Obj) || !isIntLit(post.Rhs[0], "1") {
+			return nil
+		}
+	default:
+		return nil
+	}
+	if !s.isLoopInvariant(cond.Y, v.Body) || isChanged(i.Obj, v.Body) {
+		return nil
+	}
+	return &ast.ForPhraseStmt{
+		ForPhrase: &ast.ForPhrase{
+			For:    v.For,
+			Value:  i,
+			TokPos: init.TokPos,
+			X:      &ast.RangeExpr{To: cond.OpPos, Last: cond.Y},
+		},
+		Body: v.Body,
+	}
+}
+
+func (s *simplifier) isLoopInvariant(n ast.Expr, body *ast.BlockStmt) bool {
+	switch v := n.(type) {
+	case *ast.BasicLit:
+		return v.Kind == token.INT
+	case *ast.Ident:
+		if v.Obj == nil {
+			return false
+		}
+		switch v.Obj.Kind {
+		case ast.Con:
+			spec, ok := v.Obj.Decl.(*ast.ValueSpec)
+			return ok && spec.Type == nil && len(spec.Values) == len(spec.Names) && isIntLitExpr(spec.Values[0])
+		case ast.Var:
+			return s.file.Scope.Lookup(v.Name) != v.Obj && !isChanged(v.Obj, body)
+		}
+	case *ast.CallExpr:
+		if fn, ok := v.Fun.(*ast.Ident); ok && fn.Name == "len" && fn.Obj == nil && len(v.Args) == 1 {
+			if x, ok := v.Args[0].(*ast.Ident); ok && x.Obj != nil && x.Obj.Kind == ast.Var {
+				return s.file.Scope.Lookup(x.Name) != x.Obj && !isChanged(x.Obj, body)
+			}
+		}
+	}
+	return false
+}
+
+// isChanged reports whether the variable obj may be changed in node.
+func isChanged(obj *ast.Object, node ast.Node) (changed bool) {
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch v := n.(type) {
+		case *ast.AssignStmt:
+			if v.Tok != token.DEFINE {
+				for _, lhs := range v.Lhs {
+					changed = changed || isObj(lhs, obj)
+				}
+			}
+		case *ast.IncDecStmt:
+			changed = changed || isObj(v.X, obj)
+		case *ast.UnaryExpr:
+			changed = changed || (v.Op == token.AND && isObj(v.X, obj))
+		case *ast.RangeStmt:
+			changed = changed || (v.Tok == token.ASSIGN && (isObj(v.Key, obj) || isObj(v.Value, obj)))
+		}
+		return !changed
+	})
+	return
+}
+
+func isObj(x ast.Expr, obj *ast.Object) bool {
+	v, ok := x.(*ast.Ident)
+	return ok && v.Obj == obj
+}
+
+func isIntLit(x ast.Expr, val string) bool {
+	v, ok := x.(*ast.BasicLit)
+	return ok && v.Kind == token.INT && v.Value == val
+}
+
+func isIntLitExpr(x ast.Expr) bool {
+	if v, ok := x.(*ast.UnaryExpr); ok && (v.Op == token.SUB || v.Op == token.ADD) {
+		x = v.X
+	}
+	v, ok := x.(*ast.BasicLit)
+	return ok && v.Kind == token.INT
+}
+
+// -----------------------------------------------------------------------------
+
+// compositeLit simplifies `[]T{...}` into `[...]`, and `map[K]V{...}` into
+// `{...}`, if their types are the same as the types inferred by Go+.
+func (s *simplifier) compositeLit(v *ast.CompositeLit) ast.Node {
+	if len(v.Elts) == 0 {
+		return nil
+	}
+	switch t := v.Type.(type) {
+	case *ast.ArrayType:
+		if t.Len != nil || !isInferredAs(v.Elts, t.Elt) {
+			return nil
+		}
+		return &ast.SliceLit{Lbrack: t.Lbrack, Elts: v.Elts, Rbrack: v.Rbrace}
+	case *ast.MapType:
+		keys := make([]ast.Expr, len(v.Elts))
+		vals := make([]ast.Expr, len(v.Elts))
+		for i, elt := range v.Elts {
+			kv, ok := elt.(*ast.KeyValueExpr)
+			if !ok {
+				return nil
+			}
+			keys[i], vals[i] = kv.Key, kv.Value
+		}
+		if !isInferredAs(keys, t.Key) || !isInferredAs(vals, t.Value) {
+			return nil
+		}
+		return &ast.CompositeLit{Lbrace: v.Lbrace, Elts: v.Elts, Rbrace: v.Rbrace}
+	}
+	return nil
+}
+
+// isInferredAs reports whether type of elts inferred by Go+ is typ.
+func isInferredAs(elts []ast.Expr, typ ast.Expr) bool {
+	t, ok := typ.(*ast.Ident)
+	if !ok || t.Obj != nil {
+		return false
+	}
+	hasFloat := false
+	for _, elt := range elts {
+		if u, ok := elt.(*ast.UnaryExpr); ok && (u.Op == token.SUB || u.Op == token.ADD) {
+			elt = u.X
+		}
+		var kind string
+		switch e := elt.(type) {
+		case *ast.BasicLit:
+			switch e.Kind {
+			case token.INT:
+				kind = "int"
+			case token.FLOAT:
+				kind, hasFloat = "float64", true
+			case token.STRING:
+				kind = "string"
+			}
+		case *ast.Ident:
+			if (e.Name == "true" || e.Name == "false") && e.Obj == nil {
+				kind = "bool"
+			}
+		}
+		if kind != t.Name && !(kind == "int" && t.Name == "float64") {
+			return false
+		}
+	}
+	return t.Name != "float64" || hasFloat
+}
+
+// -----------------------------------------------------------------------------
+
+// callExpr simplifies `fmt.Println(...)` into `println(...)`.
+func (s *simplifier) callExpr(v *ast.CallExpr) ast.Node {
+	if s.fmtName == "" {
+		return nil
+	}
+	sel, ok := v.Fun.(*ast.SelectorExpr)
+	if !ok || !s.isPkg(sel.X, s.fmtName) {
+		return nil
+	}
+	for _, fns := range printFuncs {
+		if fns[0] == sel.Sel.Name {
+			if s.file.Scope.Lookup(fns[1]) != nil {
+				return nil
+			}
+			ret := *v
+			ret.Fun = &ast.Ident{NamePos: sel.Pos(), Name: fns[1]}
+			return &ret
+		}
+	}
+	return nil
+}
+
+func (s *simplifier) isPkg(x ast.Expr, name string) bool {
+	v, ok := x.(*ast.Ident)
+	return ok && v.Name == name && v.Obj == nil
+}
+
+func (s *simplifier) importName(pkgPath string) string {
+	for _, spec := range s.file.Imports {
+		if path, err := strconv.Unquote(spec.Path.Value); err == nil && path == pkgPath {
+			if spec.Name == nil {
+				return pkgPath
+			}
+			if name := spec.Name.Name; name != "_" && name != "." {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+func (s *simplifier) isUsed(pkg string) (used bool) {
+	for _, decl := range s.file.Decls {
+		ast.Inspect(decl, func(n ast.Node) bool {
+			if sel, ok := n.(*ast.SelectorExpr); ok && s.isPkg(sel.X, pkg) {
+				used = true
+			}
+			return !used
+		})
+	}
+	return
+}
+
+func (s *simplifier) deleteImport(pkgPath string) {
+	for _, decl := range s.file.Decls {
+		if v, ok := decl.(*ast.GenDecl); ok && v.Tok == token.IMPORT {
+			for _, spec := range v.Specs {
+				if path, err := strconv.Unquote(spec.(*ast.ImportSpec).Path.Value); err == nil && path == pkgPath {
+					if len(v.Specs) == 1 {
+						s.file.Decls = deleteDecl(s.file.Decls, v)
+					} else {
+						v.Specs = deleteSpec(v.Specs, spec)
+					}
+					return
+				}
+			}
+		}
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+var (
+	tyNode    = reflect.TypeOf((*ast.Node)(nil)).Elem()
+	tyObject  = reflect.TypeOf((*ast.Object)(nil))
+	tyScope   = reflect.TypeOf((*ast.Scope)(nil))
+	tyComment = reflect.TypeOf((*ast.CommentGroup)(nil))
+)
+
+// applyNode calls fn for each node in the tree rooted at v (in post order),
+// and replaces the node with the result of fn if it is assignable.
+func applyNode(v reflect.Value, fn func(ast.Node) ast.Node) {
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return
+	}
+	switch v.Type() {
+	case tyObject, tyScope, tyComment:
+		return
+	}
+	e := v.Elem()
+	for i, n := 0, e.NumField(); i < n; i++ {
+		applyField(e.Field(i), fn)
+	}
+}
+
+func applyField(f reflect.Value, fn func(ast.Node) ast.Node) {
+	switch f.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if f.IsNil() || !f.CanSet() || !f.Type().Implements(tyNode) {
+			return
+		}
+		if f.Kind() == reflect.Ptr {
+			applyNode(f, fn)
+		} else {
+			applyNode(f.Elem(), fn)
+		}
+		node := f.Interface().(ast.Node)
+		if ret := fn(node); ret != node && reflect.TypeOf(ret).AssignableTo(f.Type()) {
+			f.Set(reflect.ValueOf(ret))
+		}
+	case reflect.Slice:
+		for i, n := 0, f.Len(); i < n; i++ {
+			applyField(f.Index(i), fn)
+		}
+	}
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,149 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package format
+
+import (
+	"testing"
+)
+
+func testSimplify(t *testing.T, name string, src, expect string) {
+	t.Run(name, func(t *testing.T) {
+		result, err := SimplifySource([]byte(src), false, name)
+		if err != nil {
+			t.Fatal("SimplifySource failed:", err)
+		}
+		if ret := string(result); ret != expect {
+			t.Fatalf("%s => Expect:\n%s\n=> Got:\n%s\n", name, expect, ret)
+		}
+	})
+}
+
+func TestSimplifyFor(t *testing.T) {
+	testSimplify(t, "for", `n := 10
+for i := 0; i < n; i++ {
+	println i
+}
+for i := 0; i < len(a); i += 1 {
+	println a[i]
+}
+for i := 0; i < 5; i++ {
+	println i
+}
+`, `n := 10
+for i <- :n {
+	println i
+}
+for i := 0; i < len(a); i += 1 {
+	println a[i]
+}
+for i <- :5 {
+	println i
+}
+`)
+	testSimplify(t, "for changed", `n := 10
+for i := 0; i < n; i++ {
+	n--
+}
+for i := 0; i < n; i++ {
+	i++
+}
+for i := 1; i < n; i++ {
+}
+for i := 0; i <= n; i++ {
+}
+`, `n := 10
+for i := 0; i < n; i++ {
+	n--
+}
+for i := 0; i < n; i++ {
+	i++
+}
+for i := 1; i < n; i++ {
+}
+for i := 0; i <= n; i++ {
+}
+`)
+	testSimplify(t, "for len", `func f(a []int) {
+	for i := 0; i < len(a); i++ {
+		println a[i]
+	}
+}
+`, `func f(a []int) {
+	for i <- :len(a) {
+		println a[i]
+	}
+}
+`)
+}
+
+func TestSimplifyLit(t *testing.T) {
+	testSimplify(t, "lit", `a := []int{1, -2, 3}
+b := []float64{1, 2.5}
+c := []float64{1, 2}
+d := map[string]int{"a": 1, "b": 2}
+e := []string{"a", x}
+f := []bool{true, false}
+g := []int{}
+h := map[string]any{"a": 1}
+`, `a := [1, -2, 3]
+b := [1, 2.5]
+c := []float64{1, 2}
+d := {"a": 1, "b": 2}
+e := []string{"a", x}
+f := [true, false]
+g := []int{}
+h := map[string]any{"a": 1}
+`)
+}
+
+func TestSimplifyFmt(t *testing.T) {
+	testSimplify(t, "fmt", `import "fmt"
+
+fmt.Println("Hello")
+s := fmt.Sprintf("%d", 1)
+`, `println("Hello")
+s := sprintf("%d", 1)
+`)
+	testSimplify(t, "fmt used", `import (
+	"fmt"
+	"os"
+)
+
+fmt.Fprintln(os.Stderr, "Hello")
+var _ fmt.Stringer
+`, `import (
+	"fmt"
+	"os"
+)
+
+fprintln(os.Stderr, "Hello")
+var _ fmt.Stringer
+`)
+	testSimplify(t, "fmt off", `import "fmt"
+
+//gop:fmt off
+fmt.Println("Hello")
+//gop:fmt on
+fmt.Println("World")
+`, `import "fmt"
+
+//gop:fmt off
+fmt.Println("Hello")
+//gop:fmt on
+println("World")
+`)
+}
@@ -228,6 +228,7 @@ func formatStmt(ctx *formatCtx, stmt ast.Stmt) {
 		formatTypeSwitchStmt(ctx, v)
 	case *ast.CommClause:
 		formatStmt(ctx, v.Comm)
+		formatExpr(ctx, v.After, &v.After)
 		formatStmts(ctx, v.Body)
 	case *ast.SelectStmt:
 		formatBlockStmt(ctx, v.Body)
@@ -24,6 +24,7 @@ import (
 	"path/filepath"
 
 	"github.com/fsnotify/fsnotify"
+	"github.com/goplus/gop/x/pathutil"
 )
 
 var (
@@ -83,9 +84,9 @@ func (p Watcher) watchLoop(root string, fc FSChanged, ignore Ignore) {
 			}
 			if (event.Op & fsnotify.Remove) != 0 {
 				e := p.w.Remove(event.Name)
-				name, err := filepath.Rel(root, event.Name)
-				if err != nil {
-					log.Println("[ERROR] fsnotify.EntryDeleted filepath.Rel:", err)
+				name, ok := pathutil.Rel(root, event.Name)
+				if !ok {
+					log.Println("[ERROR] fsnotify.EntryDeleted: not in", root, "-", event.Name)
 					continue
 				}
 				isDir := (e == nil || !errors.Is(e, fsnotify.ErrNonExistentWatch))
@@ -96,9 +97,9 @@ func (p Watcher) watchLoop(root string, fc FSChanged, ignore Ignore) {
 					fc.EntryDeleted(name, isDir)
 				}
 			} else if (event.Op & eventModify) != 0 {
-				name, err := filepath.Rel(root, event.Name)
-				if err != nil {
-					log.Println("[ERROR] fsnotify.FileChanged filepath.Rel:", err)
+				name, ok := pathutil.Rel(root, event.Name)
+				if !ok {
+					log.Println("[ERROR] fsnotify.FileChanged: not in", root, "-", event.Name)
 					continue
 				}
 				isDir := isDir(event.Name)
@@ -17,9 +17,15 @@
 package gocmd
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
 
 	"github.com/goplus/gop/x/gopenv"
 	"github.com/goplus/mod/env"
@@ -32,6 +38,34 @@ type Config struct {
 	GoCmd string
 	Flags []string
 	Run   func(cmd *exec.Cmd) error
+
+	// Stdin, Stdout and Stderr specify standard streams of the go command
+	// and the program it runs (optional). Default are os.Stdin, os.Stdout
+	// and os.Stderr. Use io.MultiWriter to tee output streams, and Capture
+	// to capture them with a size limit.
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// Env specifies additional environment variables in form of "key=value"
+	// (optional).
+	Env []string
+
+	// Dir specifies the working directory of the program run by RunFiles and
+	// RunDir (optional). Default is the current directory, whatever the
+	// directory of Go files is. The go command itself always runs in the
+	// current directory, so that relative paths of files keep their meaning.
+	Dir string
+
+	// CacheDir specifies the directory to cache executables of programs run
+	// by RunFiles (optional), eg. RunCacheDir(). No cache if it is empty.
+	CacheDir string
+
+	// Overlay maps paths of Go files to paths of files replacing them, which
+	// is passed to the go command by -overlay (optional). Files being replaced
+	// don't need to exist, eg. Go files generated into a cache directory
+	// instead of the source directory.
+	Overlay map[string]string
 }
 
 // -----------------------------------------------------------------------------
@@ -46,22 +80,107 @@ func doWithArgs(op string, conf *Config, args ...string) (err error) {
 	}
 	exargs := make([]string, 1, 16)
 	exargs[0] = op
-	exargs = appendLdflags(exargs, conf.Gop)
-	exargs = append(exargs, conf.Flags...)
+	exargs, flags := appendLdflags(exargs, conf.Gop, conf.Flags)
+	exargs = append(exargs, flags...)
+	if len(conf.Overlay) > 0 {
+		overlay, err := writeOverlay(conf.Overlay)
+		if err != nil {
+			return err
+		}
+		defer os.Remove(overlay)
+		exargs = append(exargs, "-overlay", overlay)
+	}
 	exargs = append(exargs, args...)
-	cmd := exec.Command(goCmd, exargs...)
+	return runWith(conf, exec.Command(goCmd, exargs...))
+}
+
+// writeOverlay writes overlay into a temporary file in the JSON format of
+// `go build -overlay`, and returns the file.
+func writeOverlay(overlay map[string]string) (file string, err error) {
+	data, err := json.Marshal(struct{ Replace map[string]string }{overlay})
+	if err != nil {
+		return
+	}
+	f, err := os.CreateTemp("", "gop-overlay-*.json")
+	if err != nil {
+		return
+	}
+	file = f.Name()
+	if _, err = f.Write(data); err == nil {
+		err = f.Close()
+	} else {
+		f.Close()
+	}
+	if err != nil {
+		os.Remove(file)
+	}
+	return
+}
+
+// runWith runs cmd with standard streams and environment variables of conf.
+func runWith(conf *Config, cmd *exec.Cmd) error {
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = conf.Stdin, conf.Stdout, conf.Stderr
+	if conf.Env != nil {
+		cmd.Env = append(os.Environ(), conf.Env...)
+	}
+	if cmd.Stdin == nil {
+		cmd.Stdin = os.Stdin
+	}
+	if cmd.Stdout == nil {
+		cmd.Stdout = os.Stdout
+	}
+	if cmd.Stderr == nil {
+		cmd.Stderr = os.Stderr
+	}
 	run := conf.Run
 	if run == nil {
-		run = runCmd
+		run = (*exec.Cmd).Run
 	}
 	return run(cmd)
 }
 
-func runCmd(cmd *exec.Cmd) (err error) {
-	cmd.Stdin = os.Stdin
-	cmd.Stderr = os.Stderr
-	cmd.Stdout = os.Stdout
-	return cmd.Run()
+// -----------------------------------------------------------------------------
+
+// Capture is an io.Writer that captures output into a buffer which holds at
+// most Limit bytes (no limit if Limit <= 0). Output beyond the limit is
+// discarded silently, so that the writing program isn't affected.
+type Capture struct {
+	Limit     int
+	Truncated bool // output is truncated because of the limit
+
+	mutex sync.Mutex
+	buf   bytes.Buffer
+}
+
+// NewCapture creates a Capture with the specified limit.
+func NewCapture(limit int) *Capture {
+	return &Capture{Limit: limit}
+}
+
+// Write implements io.Writer.
+func (p *Capture) Write(b []byte) (n int, err error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	n = len(b)
+	if p.Limit > 0 {
+		if left := p.Limit - p.buf.Len(); n > left {
+			b, p.Truncated = b[:left], true
+		}
+	}
+	p.buf.Write(b)
+	return
+}
+
+// Bytes returns the captured output.
+func (p *Capture) Bytes() []byte {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.buf.Bytes()
+}
+
+// String returns the captured output as a string.
+func (p *Capture) String() string {
+	return string(p.Bytes())
 }
 
 // -----------------------------------------------------------------------------
@@ -80,15 +199,125 @@ func loadFlags(env *GopEnv) string {
 	return fmt.Sprintf(ldFlagAll, env.Version, env.BuildDate, env.Root)
 }
 
-func appendLdflags(exargs []string, env *GopEnv) []string {
+// appendLdflags appends -ldflags to set variables of package gop/env. As only
+// the last -ldflags of a go command takes effect, -ldflags in flags, eg.
+// `-ldflags=-X main.version=v1.0`, are merged into it and removed from flags.
+func appendLdflags(exargs []string, env *GopEnv, flags []string) ([]string, []string) {
 	if env == nil {
 		env = gopenv.Get()
 	}
-	return append(exargs, "-ldflags", loadFlags(env))
+	ldflags := loadFlags(env)
+	rest := make([]string, 0, len(flags))
+	for _, flag := range flags {
+		if v, ok := cutLdflags(flag); ok {
+			if v != "" {
+				ldflags += " " + v
+			}
+			continue
+		}
+		rest = append(rest, flag)
+	}
+	return append(exargs, "-ldflags", ldflags), rest
+}
+
+func cutLdflags(flag string) (string, bool) {
+	const name = "-ldflags="
+	if strings.HasPrefix(flag, "--") {
+		flag = flag[1:]
+	}
+	if strings.HasPrefix(flag, name) {
+		return flag[len(name):], true
+	}
+	return "", false
 }
 
 // -----------------------------------------------------------------------------
 
+// Command returns a command to run the go command (see Name) with arguments
+// args. It runs in the environment of the current process, as all go commands
+// invoked by gop do, so that GOFLAGS, GOMODCACHE, GOPROXY, etc. are honored.
+func Command(args ...string) *exec.Cmd {
+	return exec.Command(Name(), args...)
+}
+
+// AppendGoFlags appends flags to GOFLAGS of the current process, so that they
+// apply to all go commands invoked by gop and its dependencies (eg. by
+// `gop -goflags=-mod=mod run .`). GOFLAGS set by `go env -w` is preserved.
+func AppendGoFlags(flags string) error {
+	old := os.Getenv("GOFLAGS")
+	if old == "" {
+		out, err := Command("env", "GOFLAGS").Output()
+		if err != nil {
+			return err
+		}
+		old = strings.TrimSpace(string(out))
+	}
+	if old != "" {
+		flags = old + " " + flags
+	}
+	return os.Setenv("GOFLAGS", flags)
+}
+
+// SelectGo selects the go command invoked by gop (see Name), which is a path
+// to a go binary, a command name in PATH, or a Go version like go1.21.3 or
+// 1.21.3. A version is selected by the command of that name (installed by
+// golang.org/dl) if it exists, or else by Go toolchain switching (GOTOOLCHAIN,
+// which requires Go 1.21+). The selected go command is validated by running
+// it, and its version is returned.
+//
+// If the selected command is named go, its directory is put at the beginning
+// of PATH, so that dependencies of gop running `go` directly use it too.
+func SelectGo(goCmd string) (ver string, err error) {
+	var want string
+	if isGoVersion(goCmd) {
+		want = "go" + strings.TrimPrefix(goCmd, "go")
+		if _, e := exec.LookPath(want); e == nil {
+			goCmd = want
+		} else {
+			goCmd = Name()
+			if err = os.Setenv("GOTOOLCHAIN", want); err != nil {
+				return
+			}
+		}
+	}
+	path, err := exec.LookPath(goCmd)
+	if err != nil {
+		return
+	}
+	if path, err = filepath.Abs(path); err != nil {
+		return
+	}
+	out, err := exec.Command(path, "env", "GOVERSION").Output()
+	if err != nil {
+		if e, ok := err.(*exec.ExitError); ok && len(e.Stderr) > 0 {
+			return "", fmt.Errorf("%s: %s", path, bytes.TrimSpace(e.Stderr))
+		}
+		return "", fmt.Errorf("%s: %v", path, err)
+	}
+	ver = strings.TrimSpace(string(out))
+	if want != "" && ver != want {
+		return "", fmt.Errorf("%s: version %s, not %s (Go 1.21+ is required to switch toolchains)", path, ver, want)
+	}
+	if name := filepath.Base(path); name == "go" || name == "go.exe" {
+		os.Setenv("PATH", filepath.Dir(path)+string(os.PathListSeparator)+os.Getenv("PATH"))
+	}
+	return ver, os.Setenv("GOP_GOCMD", path)
+}
+
+// isGoVersion reports whether s is a Go version like go1.21.3 or 1.21rc1.
+func isGoVersion(s string) bool {
+	s = strings.TrimPrefix(s, "go")
+	if !strings.HasPrefix(s, "1.") {
+		return false
+	}
+	for _, c := range s[2:] {
+		if !(c >= '0' && c <= '9' || c == '.' || c >= 'a' && c <= 'z') {
+			return false
+		}
+	}
+	return true
+}
+
 // Name returns name of the go command.
 // It returns value of environment variable `GOP_GOCMD` if not empty.
 // If not found, it returns `go`.
@@ -0,0 +1,384 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gocmd
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestCapture(t *testing.T) {
+	c := NewCapture(5)
+	if n, err := io.WriteString(c, "abc"); n != 3 || err != nil {
+		t.Fatal("Write:", n, err)
+	}
+	if n, err := io.WriteString(c, "defg"); n != 4 || err != nil {
+		t.Fatal("Write:", n, err)
+	}
+	if c.String() != "abcde" || !c.Truncated {
+		t.Fatal("Capture:", c.String(), c.Truncated)
+	}
+}
+
+func TestRunCapture(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "main.go")
+	err := os.WriteFile(file, []byte(`package main
+
+import "os"
+
+func main() {
+	os.Stdout.WriteString("hello")
+	os.Stderr.WriteString("world")
+}
+`), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout, stderr := NewCapture(0), NewCapture(0)
+	conf := &RunConfig{Gop: &GopEnv{}, Stdout: stdout, Stderr: stderr}
+	if err = RunFiles([]string{file}, nil, conf); err != nil {
+		t.Fatal("RunFiles:", err, stderr)
+	}
+	if stdout.String() != "hello" || stderr.String() != "world" {
+		t.Fatalf("RunFiles: stdout=%q stderr=%q", stdout, stderr)
+	}
+}
+
+func TestRunGoFileArgs(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "main.go")
+	err := os.WriteFile(file, []byte(`package main
+
+import (
+	"os"
+	"strings"
+)
+
+func main() {
+	os.Stdout.WriteString(strings.Join(os.Args[1:], ","))
+}
+`), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout, stderr := NewCapture(0), NewCapture(0)
+	conf := &RunConfig{Gop: &GopEnv{}, Stdout: stdout, Stderr: stderr}
+	if err = RunFiles([]string{file}, []string{"input.go", "-x"}, conf); err != nil {
+		t.Fatal("RunFiles:", err, stderr)
+	}
+	if stdout.String() != "input.go,-x" {
+		t.Fatalf("RunFiles: stdout=%q stderr=%q", stdout, stderr)
+	}
+}
+
+func TestRunCache(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "main.go")
+	err := os.WriteFile(file, []byte(`package main
+
+import "os"
+
+func main() {
+	os.Stdout.WriteString("hello")
+}
+`), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mutex sync.Mutex
+	var builds int
+	run := func(cmd *exec.Cmd) error {
+		if len(cmd.Args) > 1 && cmd.Args[1] == "build" {
+			mutex.Lock()
+			builds++
+			mutex.Unlock()
+		}
+		return cmd.Run()
+	}
+	cacheDir := filepath.Join(dir, "cache")
+	var wg sync.WaitGroup
+	errs := make([]error, 4)
+	outs := make([]*Capture, 4)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			outs[i] = NewCapture(0)
+			conf := &RunConfig{Gop: &GopEnv{}, Stdout: outs[i], Run: run, CacheDir: cacheDir}
+			errs[i] = RunFiles([]string{file}, nil, conf)
+		}(i)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil || outs[i].String() != "hello" {
+			t.Fatal("RunFiles:", err, outs[i])
+		}
+	}
+	if builds != 1 {
+		t.Fatal("RunFiles: builds =", builds)
+	}
+	if _, ok := runCacheKey([]string{file}, &RunConfig{}); !ok {
+		t.Fatal("runCacheKey: not cacheable")
+	}
+	os.WriteFile(file, []byte("package main\n\nimport _ \"C\"\n\nfunc main() {}\n"), 0666)
+	if _, ok := runCacheKey([]string{file}, &RunConfig{}); ok {
+		t.Fatal("runCacheKey: cgo is cacheable")
+	}
+}
+
+func TestAppendGoFlags(t *testing.T) {
+	t.Setenv("GOFLAGS", "-mod=mod")
+	if err := AppendGoFlags("-trimpath"); err != nil {
+		t.Fatal("AppendGoFlags:", err)
+	}
+	if out, err := Command("env", "GOFLAGS").Output(); err != nil || strings.TrimSpace(string(out)) != "-mod=mod -trimpath" {
+		t.Fatalf("AppendGoFlags: %q %v", out, err)
+	}
+}
+
+func TestSelectGo(t *testing.T) {
+	t.Setenv("GOP_GOCMD", "")
+	t.Setenv("PATH", os.Getenv("PATH"))
+	t.Setenv("GOTOOLCHAIN", os.Getenv("GOTOOLCHAIN"))
+	path, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip(err)
+	}
+	ver, err := SelectGo(path)
+	if err != nil || !strings.HasPrefix(ver, "go") {
+		t.Fatal("SelectGo:", ver, err)
+	}
+	if Name() != path {
+		t.Fatal("SelectGo: GOP_GOCMD =", Name())
+	}
+	if _, err = SelectGo(filepath.Join(t.TempDir(), "go")); err == nil {
+		t.Fatal("SelectGo: no error")
+	}
+}
+
+func TestIsGoVersion(t *testing.T) {
+	for _, s := range []string{"go1.21.3", "1.21.3", "go1.22rc1"} {
+		if !isGoVersion(s) {
+			t.Fatal("isGoVersion:", s)
+		}
+	}
+	for _, s := range []string{"go", "/usr/bin/go", "go1.21/go", "2.0"} {
+		if isGoVersion(s) {
+			t.Fatal("isGoVersion:", s)
+		}
+	}
+}
+
+func TestRunOverlay(t *testing.T) {
+	dir, gen := t.TempDir(), t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "h.go"), []byte(`package main
+
+func hello() string { return "hello" }
+`), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	genFile := filepath.Join(gen, "main.go")
+	err = os.WriteFile(genFile, []byte(`package main
+
+import "os"
+
+func main() {
+	os.Stdout.WriteString(hello())
+}
+`), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout, stderr := NewCapture(0), NewCapture(0)
+	conf := &RunConfig{Gop: &GopEnv{}, Stdout: stdout, Stderr: stderr}
+	conf.Overlay = map[string]string{filepath.Join(dir, "gop_autogen.go"): genFile}
+	if err = RunDir(dir, nil, conf); err != nil {
+		t.Fatal("RunDir:", err, stderr)
+	}
+	if stdout.String() != "hello" {
+		t.Fatalf("RunDir: stdout=%q stderr=%q", stdout, stderr)
+	}
+}
+
+func TestRunWorkDir(t *testing.T) {
+	src, work := t.TempDir(), t.TempDir()
+	file := filepath.Join(src, "main.go")
+	err := os.WriteFile(file, []byte(`package main
+
+import "os"
+
+func main() {
+	data, err := os.ReadFile("data.txt")
+	if err != nil {
+		panic(err)
+	}
+	os.Stdout.Write(data)
+}
+`), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(filepath.Join(work, "data.txt"), []byte("work"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(filepath.Join(src, "data.txt"), []byte("src"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	run := func(t *testing.T, conf *RunConfig, runFn func(conf *RunConfig) error) {
+		stdout, stderr := NewCapture(0), NewCapture(0)
+		conf.Gop, conf.Stdout, conf.Stderr = &GopEnv{}, stdout, stderr
+		if err := runFn(conf); err != nil {
+			t.Fatal(err, stderr)
+		}
+		if stdout.String() != "work" {
+			t.Fatalf("stdout=%q stderr=%q", stdout, stderr)
+		}
+	}
+	t.Run("cwd", func(t *testing.T) {
+		old, err := os.Getwd()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = os.Chdir(work); err != nil {
+			t.Fatal(err)
+		}
+		defer os.Chdir(old)
+		run(t, &RunConfig{}, func(conf *RunConfig) error {
+			return RunDir(src, nil, conf)
+		})
+	})
+	t.Run("files", func(t *testing.T) {
+		run(t, &RunConfig{Dir: work}, func(conf *RunConfig) error {
+			return RunFiles([]string{file}, nil, conf)
+		})
+	})
+	t.Run("dir", func(t *testing.T) {
+		run(t, &RunConfig{Dir: work}, func(conf *RunConfig) error {
+			return RunDir(src, nil, conf)
+		})
+	})
+	t.Run("cache", func(t *testing.T) {
+		run(t, &RunConfig{Dir: work, CacheDir: filepath.Join(t.TempDir(), "cache")}, func(conf *RunConfig) error {
+			return RunFiles([]string{file}, nil, conf)
+		})
+	})
+}
+
+func TestCutExecFlag(t *testing.T) {
+	flags, launcher := cutExecFlag([]string{"-v", "-exec", `"/path/to/gop" sandbox`, "-race"})
+	if strings.Join(flags, " ") != "-v -race" || len(launcher) != 2 || launcher[0] != "/path/to/gop" || launcher[1] != "sandbox" {
+		t.Fatal("cutExecFlag:", flags, launcher)
+	}
+	if _, launcher = cutExecFlag([]string{"-exec=xprog"}); len(launcher) != 1 || launcher[0] != "xprog" {
+		t.Fatal("cutExecFlag:", launcher)
+	}
+}
+
+func TestAppendLdflags(t *testing.T) {
+	env := &GopEnv{Version: "v1.2.0", BuildDate: "2024-01-01", Root: "/gop"}
+	exargs, flags := appendLdflags([]string{"install"}, env, []string{"-v", "-ldflags=-X main.version=v1.0", "--ldflags=-s"})
+	if strings.Join(flags, " ") != "-v" || len(exargs) != 3 || exargs[1] != "-ldflags" {
+		t.Fatal("appendLdflags:", exargs, flags)
+	}
+	if !strings.HasPrefix(exargs[2], loadFlags(env)) || !strings.HasSuffix(exargs[2], " -X main.version=v1.0 -s") {
+		t.Fatal("appendLdflags:", exargs[2])
+	}
+}
+
+func TestParseTargets(t *testing.T) {
+	targets, err := ParseTargets("linux/amd64, windows/386")
+	if err != nil || len(targets) != 2 || targets[1] != (Target{"windows", "386"}) {
+		t.Fatal("ParseTargets:", targets, err)
+	}
+	for _, s := range []string{"linux", "/amd64", "linux/", "linux/amd64/v2", "linux/amd64,"} {
+		if _, err := ParseTargets(s); err == nil {
+			t.Fatal("ParseTargets: no error -", s)
+		}
+	}
+	if out := targets[1].Output(DefaultTargetOutput, "hello"); out != "hello_windows_386.exe" {
+		t.Fatal("Output:", out)
+	}
+	if out := targets[0].Output("bin/{os}-{arch}/{name}{ext}", "hello"); out != "bin/linux-amd64/hello" {
+		t.Fatal("Output:", out)
+	}
+}
+
+func TestTargetOutput(t *testing.T) {
+	dir := t.TempDir()
+	sep := string(filepath.Separator)
+	for _, c := range []struct{ output, want string }{
+		{"", DefaultTargetOutput},
+		{"out" + sep, filepath.Join("out", DefaultTargetOutput)},
+		{dir, filepath.Join(dir, DefaultTargetOutput)},
+		{"bin/{os}-{arch}/{name}", "bin/{os}-{arch}/{name}"},
+	} {
+		if ret, err := TargetOutput(c.output, 2); err != nil || ret != c.want {
+			t.Fatal("TargetOutput:", c.output, ret, err)
+		}
+	}
+	if ret, err := TargetOutput("hello", 1); err != nil || ret != "hello" {
+		t.Fatal("TargetOutput:", ret, err)
+	}
+	for _, output := range []string{"hello", "hello_{os}"} {
+		if _, err := TargetOutput(output, 2); err == nil {
+			t.Fatal("TargetOutput: no error -", output)
+		}
+	}
+}
+
+func TestBuildTargetsSameOutput(t *testing.T) {
+	targets := []Target{{"linux", "amd64"}, {"darwin", "amd64"}}
+	run := RunTargets(targets, "out/{name}_{arch}", "hello", 0)
+	err := run(exec.Command("go", "build"))
+	if err == nil || err.Error() != "targets linux/amd64 and darwin/amd64 have the same output out/hello_amd64" {
+		t.Fatal("RunTargets:", err)
+	}
+}
+
+func TestBuildTargets(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "main.go"), []byte(`package main
+
+func main() {
+}
+`), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	targets := []Target{{runtime.GOOS, runtime.GOARCH}, {"nonos", "amd64"}}
+	stderr := NewCapture(0)
+	conf := &BuildConfig{Gop: &GopEnv{}, Stderr: stderr}
+	conf.Run = RunTargets(targets, filepath.Join(dir, DefaultTargetOutput), "hello", 0)
+	err = BuildFiles([]string{filepath.Join(dir, "main.go")}, conf)
+	if err == nil || err.Error() != "build failed for targets: nonos/amd64" {
+		t.Fatal("BuildFiles:", err)
+	}
+	if !strings.HasPrefix(stderr.String(), "# nonos/amd64\n") {
+		t.Fatal("BuildFiles: stderr -", stderr)
+	}
+	if _, err = os.Stat(filepath.Join(dir, targets[0].Output(DefaultTargetOutput, "hello"))); err != nil {
+		t.Fatal("BuildFiles:", err)
+	}
+}
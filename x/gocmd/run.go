@@ -18,8 +18,14 @@ package gocmd
 
 import (
 	"os"
+	"os/exec"
 	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
 	"strings"
+
+	"github.com/goplus/gop/x/pathutil"
 )
 
 // -----------------------------------------------------------------------------
@@ -39,6 +45,22 @@ func RunDir(dir string, args []string, conf *RunConfig) (err error) {
 			}
 		}
 	}
+	if conf != nil && len(conf.Overlay) > 0 { // add files which exist in overlay only
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return err
+		}
+		n := len(files)
+		for file := range conf.Overlay {
+			if pathutil.Equal(filepath.Dir(file), abs) && filterRunFname(filepath.Base(file)) {
+				if _, e := os.Lstat(file); e != nil {
+					// go requires all files to be in the same directory literally
+					files = append(files, filepath.Join(dir, filepath.Base(file)))
+				}
+			}
+		}
+		sort.Strings(files[n:])
+	}
 	return RunFiles(files, args, conf)
 }
 
@@ -49,9 +71,116 @@ func filterRunFname(fname string) bool {
 
 // -----------------------------------------------------------------------------
 
+// RunFiles runs the program of Go source files with arguments args.
+//
+// `go run` treats leading arguments ending with .go as source files, so if
+// the first argument of the program looks like a Go file (eg. `gop run
+// main.gop -- input.go`) or conf.Dir is specified, the program is built into
+// a temporary directory and executed instead.
+//
+// If conf.CacheDir is specified, the executable is cached there and reused by
+// later (and concurrent) runs of the unchanged program.
 func RunFiles(files []string, args []string, conf *RunConfig) (err error) {
+	if conf != nil && conf.CacheDir != "" {
+		if key, ok := runCacheKey(files, conf); ok {
+			return runCached(key, files, args, conf)
+		}
+	}
+	if (len(args) > 0 && strings.HasSuffix(args[0], ".go")) || (conf != nil && conf.Dir != "") {
+		return buildAndRun(files, args, conf)
+	}
 	args = append(files, args...)
 	return doWithArgs("run", conf, args...)
 }
 
+func buildAndRun(files []string, args []string, conf *RunConfig) (err error) {
+	if conf == nil {
+		conf = new(Config)
+	}
+	dir, err := os.MkdirTemp("", "gop-run-")
+	if err != nil {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	prog := filepath.Join(dir, "prog")
+	if runtime.GOOS == "windows" {
+		prog += ".exe"
+	}
+	build := *conf
+	var launcher []string // specified by `go run -exec xprog`
+	build.Flags, launcher = cutExecFlag(conf.Flags)
+	if err = doWithArgs("build", &build, append([]string{"-o", prog}, files...)...); err != nil {
+		return
+	}
+	return runProg(conf, prog, launcher, args)
+}
+
+// runProg runs the executable prog with arguments args in conf.Dir, by
+// launcher if it isn't nil.
+func runProg(conf *RunConfig, prog string, launcher, args []string) error {
+	if conf.Dir != "" { // prog is relative to the current directory
+		abs, err := filepath.Abs(prog)
+		if err != nil {
+			return err
+		}
+		prog = abs
+	}
+	var cmd *exec.Cmd
+	if launcher != nil {
+		args = append(append(launcher[1:len(launcher):len(launcher)], prog), args...)
+		cmd = exec.Command(launcher[0], args...)
+	} else {
+		cmd = exec.Command(prog, args...)
+	}
+	cmd.Dir = conf.Dir
+	return runWith(conf, cmd)
+}
+
+// cutExecFlag removes the flag `-exec xprog` of `go run` from flags, and
+// returns the command line of xprog.
+func cutExecFlag(flags []string) (ret []string, launcher []string) {
+	ret = make([]string, 0, len(flags))
+	for i := 0; i < len(flags); i++ {
+		flag := flags[i]
+		switch {
+		case flag == "-exec" || flag == "--exec":
+			if i+1 < len(flags) {
+				i++
+				launcher = splitQuoted(flags[i])
+			}
+		case strings.HasPrefix(flag, "-exec=") || strings.HasPrefix(flag, "--exec="):
+			launcher = splitQuoted(flag[strings.IndexByte(flag, '=')+1:])
+		default:
+			ret = append(ret, flag)
+		}
+	}
+	return
+}
+
+// splitQuoted splits s into fields separated by spaces, where a field may
+// be quoted by strconv.Quote.
+func splitQuoted(s string) (fields []string) {
+	for {
+		s = strings.TrimLeft(s, " \t")
+		if s == "" {
+			return
+		}
+		if s[0] == '"' || s[0] == '\'' {
+			if quoted, err := strconv.QuotedPrefix(s); err == nil {
+				field, _ := strconv.Unquote(quoted)
+				fields = append(fields, field)
+				s = s[len(quoted):]
+				continue
+			}
+		}
+		n := strings.IndexAny(s, " \t")
+		if n < 0 {
+			n = len(s)
+		}
+		fields = append(fields, s[:n])
+		s = s[n:]
+	}
+}
+
 // -----------------------------------------------------------------------------
@@ -0,0 +1,298 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gocmd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/goplus/mod/gopmod"
+	"github.com/qiniu/x/errors"
+)
+
+// -----------------------------------------------------------------------------
+
+// CacheDir returns the cache directory of gop, that is $GOPCACHE if it is
+// set, or else $UserCacheDir/gop (or a directory in os.TempDir if there is no
+// user cache directory).
+func CacheDir() string {
+	if dir := os.Getenv("GOPCACHE"); dir != "" {
+		return dir
+	}
+	if cache, err := os.UserCacheDir(); err == nil {
+		return filepath.Join(cache, "gop")
+	}
+	return filepath.Join(os.TempDir(), "gop-cache")
+}
+
+// RunCacheDir returns the default directory to cache executables of programs
+// run by RunFiles, that is $GOPCACHE/run (see CacheDir).
+func RunCacheDir() string {
+	return filepath.Join(CacheDir(), "run")
+}
+
+const (
+	runCacheTrimAge      = 5 * 24 * time.Hour // age of unused entries to remove
+	runCacheTrimInterval = 24 * time.Hour
+)
+
+// runCached builds the program of Go source files into the cache entry key
+// only if it doesn't exist, and runs it. Concurrent runs of the same program
+// are serialized by locking the entry, so it is built only once.
+func runCached(key string, files []string, args []string, conf *RunConfig) (err error) {
+	dir := filepath.Join(conf.CacheDir, key)
+	f, err := lockEntry(dir)
+	if err != nil {
+		return
+	}
+	prog := filepath.Join(dir, "prog")
+	if runtime.GOOS == "windows" {
+		prog += ".exe"
+	}
+	build := *conf
+	var launcher []string
+	build.Flags, launcher = cutExecFlag(conf.Flags)
+	if _, e := os.Stat(prog); e != nil {
+		tmp := prog + ".tmp"
+		err = doWithArgs("build", &build, append([]string{"-o", tmp}, files...)...)
+		if err == nil {
+			err = os.Rename(tmp, prog)
+		}
+		if err == nil {
+			trimRunCache(conf.CacheDir)
+		}
+	}
+	now := time.Now()
+	os.Chtimes(dir, now, now)
+	unlockFile(f)
+	if err != nil {
+		return
+	}
+	return runProg(conf, prog, launcher, args)
+}
+
+// lockEntry locks the cache entry dir. It retries if the entry is removed by
+// trimRunCache while waiting for the lock.
+func lockEntry(dir string) (f *os.File, err error) {
+	lock := filepath.Join(dir, "lock")
+	for {
+		if err = os.MkdirAll(dir, 0755); err != nil {
+			return
+		}
+		if f, err = lockFile(lock); err != nil {
+			return
+		}
+		fi, e1 := f.Stat()
+		cur, e2 := os.Stat(lock)
+		if e1 == nil && e2 == nil && os.SameFile(fi, cur) {
+			return
+		}
+		unlockFile(f)
+	}
+}
+
+// trimRunCache removes cache entries unused for runCacheTrimAge, at most once
+// per runCacheTrimInterval.
+func trimRunCache(cacheDir string) {
+	mark := filepath.Join(cacheDir, "trim.txt")
+	if fi, err := os.Stat(mark); err == nil && time.Since(fi.ModTime()) < runCacheTrimInterval {
+		return
+	}
+	os.WriteFile(mark, nil, 0666)
+	fis, err := os.ReadDir(cacheDir)
+	if err != nil {
+		return
+	}
+	for _, fi := range fis {
+		if !fi.IsDir() {
+			continue
+		}
+		if info, err := fi.Info(); err == nil && time.Since(info.ModTime()) > runCacheTrimAge {
+			dir := filepath.Join(cacheDir, fi.Name())
+			if f, err := lockFile(filepath.Join(dir, "lock")); err == nil {
+				os.RemoveAll(dir)
+				unlockFile(f)
+			}
+		}
+	}
+}
+
+// lockFile opens file and locks it exclusively, waiting until other processes
+// release the lock.
+func lockFile(file string) (f *os.File, err error) {
+	f, err = os.OpenFile(file, os.O_RDWR|os.O_CREATE, 0666)
+	if err != nil {
+		return
+	}
+	if err = lock(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return
+}
+
+// unlockFile unlocks file locked by lockFile and closes it.
+func unlockFile(f *os.File) error {
+	unlock(f)
+	return f.Close()
+}
+
+// -----------------------------------------------------------------------------
+
+// runCacheKey returns the key of a cache entry for the program of Go source
+// files, which covers everything the executable depends on: contents of the
+// source files, go.mod and go.sum, build flags, environment variables of go
+// and the go command itself. It returns false if the program can't be cached
+// safely, eg. it imports packages of the main module, or replaces a module
+// with a local directory, or uses cgo or //go:embed.
+func runCacheKey(files []string, conf *RunConfig) (key string, ok bool) {
+	if len(files) == 0 || !canLock {
+		return
+	}
+	goCmd := conf.GoCmd
+	if goCmd == "" {
+		goCmd = Name()
+	}
+	goCmd, err := exec.LookPath(goCmd)
+	if err != nil {
+		return
+	}
+	fi, err := os.Stat(goCmd)
+	if err != nil {
+		return
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "gop run\ngo %s %d %v\n", goCmd, fi.Size(), fi.ModTime().UnixNano())
+	if gop := conf.Gop; gop != nil {
+		fmt.Fprintf(h, "gop %s %s %s\n", gop.Version, gop.BuildDate, gop.Root)
+	}
+	env := make([]string, 0, 16)
+	for _, kv := range os.Environ() {
+		if strings.HasPrefix(kv, "GO") || strings.HasPrefix(kv, "CGO_") {
+			env = append(env, kv)
+		}
+	}
+	sort.Strings(env)
+	env = append(env, conf.Env...)
+	fmt.Fprintf(h, "env %q\nflags %q\n", env, conf.Flags)
+
+	abs, err := filepath.Abs(files[0])
+	if err != nil {
+		return
+	}
+	mod, err := gopmod.Load(filepath.Dir(abs))
+	if err != nil {
+		if errors.Err(err) != syscall.ENOENT {
+			return
+		}
+		mod = nil
+	} else {
+		for _, r := range mod.Replace {
+			if r.New.Version == "" { // replaced by a local directory
+				return
+			}
+		}
+		if findUp(mod.Root(), "go.work") && os.Getenv("GOWORK") != "off" {
+			return
+		}
+		for _, file := range []string{mod.Modfile(), filepath.Join(mod.Root(), "go.sum")} {
+			b, err := os.ReadFile(file)
+			if err != nil && !os.IsNotExist(err) {
+				return
+			}
+			fmt.Fprintf(h, "file %s %d\n", file, len(b))
+			h.Write(b)
+		}
+	}
+
+	fset := token.NewFileSet()
+	for _, file := range files {
+		real := file
+		if abs, err := filepath.Abs(file); err == nil {
+			if v, ok := conf.Overlay[abs]; ok {
+				real = v
+			}
+		}
+		b, err := os.ReadFile(real)
+		if err != nil || bytes.Contains(b, []byte("//go:embed")) {
+			return "", false
+		}
+		f, err := parser.ParseFile(fset, file, b, parser.ImportsOnly)
+		if err != nil {
+			return "", false
+		}
+		for _, imp := range f.Imports {
+			path, err := strconv.Unquote(imp.Path.Value)
+			if err != nil || !cacheableImport(mod, path) {
+				return "", false
+			}
+		}
+		fmt.Fprintf(h, "file %s %d\n", file, len(b))
+		h.Write(b)
+	}
+	return hex.EncodeToString(h.Sum(nil)), true
+}
+
+// cacheableImport reports whether an executable importing pkgPath can be
+// cached, that is pkgPath is a standard package or belongs to a module
+// required by mod, whose contents are covered by go.sum.
+func cacheableImport(mod *gopmod.Module, pkgPath string) bool {
+	if pkgPath == "C" {
+		return false
+	}
+	if mod == nil {
+		elem := pkgPath
+		if pos := strings.IndexByte(elem, '/'); pos >= 0 {
+			elem = elem[:pos]
+		}
+		return !strings.Contains(elem, ".") && pkgPath[0] != '.'
+	}
+	switch mod.PkgType(pkgPath) {
+	case gopmod.PkgtStandard, gopmod.PkgtExtern:
+		return true
+	}
+	return false
+}
+
+// findUp reports whether file exists in dir or any of its parent directories.
+func findUp(dir, file string) bool {
+	for {
+		if _, err := os.Stat(filepath.Join(dir, file)); err == nil {
+			return true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return false
+		}
+		dir = parent
+	}
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,161 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gocmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// -----------------------------------------------------------------------------
+
+// DefaultTargetOutput is the default output naming template of BuildTargets.
+const DefaultTargetOutput = "{name}_{os}_{arch}{ext}"
+
+// Target represents a platform to build for.
+type Target struct {
+	GOOS, GOARCH string
+}
+
+// String returns the target in form of GOOS/GOARCH.
+func (t Target) String() string {
+	return t.GOOS + "/" + t.GOARCH
+}
+
+// Output expands the output naming template tmpl for target t. Placeholders
+// {name}, {os}, {arch} and {ext} are replaced with name, GOOS, GOARCH and
+// the executable suffix (".exe" on windows) respectively.
+func (t Target) Output(tmpl, name string) string {
+	ext := ""
+	if t.GOOS == "windows" {
+		ext = ".exe"
+	}
+	return strings.NewReplacer(
+		"{name}", name, "{os}", t.GOOS, "{arch}", t.GOARCH, "{ext}", ext).Replace(tmpl)
+}
+
+// ParseTargets parses a comma-separated list of GOOS/GOARCH pairs, eg.
+// "linux/amd64,darwin/arm64".
+func ParseTargets(s string) (targets []Target, err error) {
+	for _, v := range strings.Split(s, ",") {
+		v = strings.TrimSpace(v)
+		pos := strings.IndexByte(v, '/')
+		if pos <= 0 || pos == len(v)-1 || strings.IndexByte(v[pos+1:], '/') >= 0 {
+			return nil, fmt.Errorf("invalid target %q, should be GOOS/GOARCH", v)
+		}
+		targets = append(targets, Target{GOOS: v[:pos], GOARCH: v[pos+1:]})
+	}
+	return
+}
+
+// TargetOutput returns the output naming template of builds for ntargets
+// targets from the -o flag output. An empty output means DefaultTargetOutput,
+// and an output ending with a path separator or naming an existing directory
+// means DefaultTargetOutput in that directory. Outputs of multiple targets
+// must be distinguished by {os} and {arch}.
+func TargetOutput(output string, ntargets int) (string, error) {
+	if output == "" {
+		return DefaultTargetOutput, nil
+	}
+	if os.IsPathSeparator(output[len(output)-1]) {
+		return filepath.Join(output, DefaultTargetOutput), nil
+	}
+	if fi, err := os.Stat(output); err == nil && fi.IsDir() {
+		return filepath.Join(output, DefaultTargetOutput), nil
+	}
+	if ntargets > 1 && !(strings.Contains(output, "{os}") && strings.Contains(output, "{arch}")) {
+		return "", fmt.Errorf("output %q of multiple targets should contain {os} and {arch}", output)
+	}
+	return output, nil
+}
+
+// RunTargets returns a function for Config.Run, which turns one `go build`
+// command into builds for each of targets. Builds run in parallel, at most
+// `parallel` ones at the same time (runtime.NumCPU() if parallel <= 0).
+// Output file of each target is named by Target.Output(output, name), which
+// must be different for each target (see TargetOutput), and output of failed
+// builds is written to stderr of the command.
+func RunTargets(targets []Target, output, name string, parallel int) func(cmd *exec.Cmd) error {
+	if parallel <= 0 {
+		parallel = runtime.NumCPU()
+	}
+	return func(cmd *exec.Cmd) error {
+		outputs := make(map[string]Target, len(targets))
+		for _, t := range targets {
+			out := t.Output(output, name)
+			if t2, ok := outputs[out]; ok {
+				return fmt.Errorf("targets %v and %v have the same output %s", t2, t, out)
+			}
+			outputs[out] = t
+		}
+		env := cmd.Env
+		if env == nil {
+			env = os.Environ()
+		}
+		stderr := cmd.Stderr
+		if stderr == nil {
+			stderr = io.Discard
+		}
+		var (
+			wg     sync.WaitGroup
+			mutex  sync.Mutex
+			failed []string
+			sem    = make(chan struct{}, parallel)
+		)
+		for _, t := range targets {
+			args := make([]string, 0, len(cmd.Args)+2)
+			args = append(args, cmd.Args[1])
+			args = append(args, "-o", t.Output(output, name))
+			args = append(args, cmd.Args[2:]...)
+			c := exec.Command(cmd.Path, args...)
+			c.Dir = cmd.Dir
+			c.Env = append(env[:len(env):len(env)], "GOOS="+t.GOOS, "GOARCH="+t.GOARCH)
+			var out bytes.Buffer
+			c.Stdout, c.Stderr = &out, &out
+			wg.Add(1)
+			go func(t Target) {
+				defer wg.Done()
+				sem <- struct{}{}
+				err := c.Run()
+				<-sem
+				if err != nil {
+					mutex.Lock()
+					defer mutex.Unlock()
+					fmt.Fprintf(stderr, "# %v\n%s", t, out.Bytes())
+					failed = append(failed, t.String())
+				}
+			}(t)
+		}
+		wg.Wait()
+		if failed != nil {
+			sort.Strings(failed)
+			return errors.New("build failed for targets: " + strings.Join(failed, ", "))
+		}
+		return nil
+	}
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,94 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package gui
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// -----------------------------------------------------------------------------
+
+type textDriver struct {
+	in  io.Reader
+	out io.Writer
+}
+
+// NewTextDriver creates a driver which renders windows as text and reads
+// commands from in:
+//
+//	click <id>
+//	input <id> <text>
+//	quit
+func NewTextDriver(in io.Reader, out io.Writer) Driver {
+	return &textDriver{in: in, out: out}
+}
+
+func (p *textDriver) Run(app *App) error {
+	p.render(app)
+	scanner := bufio.NewScanner(p.in)
+	for scanner.Scan() {
+		cmd, args, _ := strings.Cut(strings.TrimSpace(scanner.Text()), " ")
+		var ok bool
+		switch cmd {
+		case "":
+			continue
+		case "quit":
+			return nil
+		case "click":
+			ok = dispatch(app, func(w *Window) bool { return w.Click(args) })
+		case "input":
+			id, text, _ := strings.Cut(args, " ")
+			ok = dispatch(app, func(w *Window) bool { return w.Change(id, text) })
+		}
+		if !ok {
+			fmt.Fprintf(p.out, "invalid command: %s\n", scanner.Text())
+			continue
+		}
+		app.Refresh()
+		p.render(app)
+	}
+	return scanner.Err()
+}
+
+func dispatch(app *App, fn func(w *Window) bool) bool {
+	for _, win := range app.windows {
+		if fn(win) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *textDriver) render(app *App) {
+	for _, win := range app.windows {
+		fmt.Fprintf(p.out, "== %s ==\n", win.title)
+		for _, w := range win.widgets {
+			switch w.Kind {
+			case KindLabel:
+				fmt.Fprintf(p.out, "%s\n", w.text)
+			case KindButton:
+				fmt.Fprintf(p.out, "[%s] (%s)\n", w.text, w.ID)
+			default:
+				fmt.Fprintf(p.out, "<%s> (%s)\n", w.text, w.ID)
+			}
+		}
+	}
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,330 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+// Package gui implements the runtime of the GUI scripting classfile, a simple
+// declarative GUI for educational users. Register it in gop.mod:
+//
+//	project .gui App github.com/goplus/gop/x/gui
+//	class .win Window
+//
+// Each *.win file is a window (its name should be capitalized). Its body declares the widgets of the window:
+//
+//	var (
+//		count int
+//	)
+//
+//	func onShow() {
+//		println "shown"
+//	}
+//
+//	title "Counter"
+//	label => "count: " + count.string
+//	button "Add", => {
+//		count++
+//	}
+//
+// Properties given as functions (like the label text above) are bound: they
+// are reevaluated after each event. Methods named onX are event handlers of
+// the window, bound by the compiler (see Gop_bind).
+package gui
+
+import (
+	"os"
+	"reflect"
+	"strconv"
+)
+
+const (
+	GopPackage     = true // to indicate this is a Go+ package
+	Gop_bind       = "Gop_Bind"
+	Gop_workfields = true
+)
+
+// -----------------------------------------------------------------------------
+
+// Kind represents the kind of a widget.
+type Kind int
+
+const (
+	KindLabel Kind = iota
+	KindButton
+	KindInput
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindLabel:
+		return "label"
+	case KindButton:
+		return "button"
+	}
+	return "input"
+}
+
+// Widget represents a widget of a window.
+type Widget struct {
+	Kind Kind
+	ID   string
+
+	text     string
+	bindText func() string
+	onClick  func()
+	onChange func(text string)
+}
+
+// Text returns the current text of the widget.
+func (p *Widget) Text() string {
+	return p.text
+}
+
+// SetText sets text of the widget and removes its binding (if any).
+func (p *Widget) SetText(text string) {
+	p.text, p.bindText = text, nil
+}
+
+func (p *Widget) refresh() {
+	if p.bindText != nil {
+		p.text = p.bindText()
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+// Window is the worker class of the GUI scripting classfile.
+type Window struct {
+	title   string
+	widgets []*Widget
+	onShow  func()
+	onClose func()
+}
+
+func (p *Window) win() *Window {
+	return p
+}
+
+// Title sets title of the window.
+func (p *Window) Title(title string) {
+	p.title = title
+}
+
+// GetTitle returns title of the window.
+func (p *Window) GetTitle() string {
+	return p.title
+}
+
+// Widgets returns all widgets of the window.
+func (p *Window) Widgets() []*Widget {
+	return p.widgets
+}
+
+// Widget returns the widget with the specified id.
+func (p *Window) Widget(id string) *Widget {
+	for _, w := range p.widgets {
+		if w.ID == id {
+			return w
+		}
+	}
+	return nil
+}
+
+func (p *Window) add(kind Kind, text string, bindText func() string) *Widget {
+	n := 1
+	for _, w := range p.widgets {
+		if w.Kind == kind {
+			n++
+		}
+	}
+	w := &Widget{Kind: kind, ID: kind.String() + strconv.Itoa(n), text: text, bindText: bindText}
+	w.refresh()
+	p.widgets = append(p.widgets, w)
+	return w
+}
+
+// Label__0 adds a label.
+func (p *Window) Label__0(text string) *Widget {
+	return p.add(KindLabel, text, nil)
+}
+
+// Label__1 adds a label whose text is bound to the result of fn.
+func (p *Window) Label__1(fn func() string) *Widget {
+	return p.add(KindLabel, "", fn)
+}
+
+// Button adds a button.
+func (p *Window) Button(text string, onClick func()) *Widget {
+	w := p.add(KindButton, text, nil)
+	w.onClick = onClick
+	return w
+}
+
+// Input adds a text input.
+func (p *Window) Input(onChange func(text string)) *Widget {
+	w := p.add(KindInput, "", nil)
+	w.onChange = onChange
+	return w
+}
+
+// OnShow sets the handler called when the window is shown.
+func (p *Window) OnShow(onShow func()) {
+	p.onShow = onShow
+}
+
+// OnClose sets the handler called when the window is closed.
+func (p *Window) OnClose(onClose func()) {
+	p.onClose = onClose
+}
+
+// Click simulates clicking the widget with the specified id.
+func (p *Window) Click(id string) bool {
+	if w := p.Widget(id); w != nil && w.onClick != nil {
+		w.onClick()
+		return true
+	}
+	return false
+}
+
+// Change simulates changing text of the input with the specified id.
+func (p *Window) Change(id, text string) bool {
+	if w := p.Widget(id); w != nil && w.Kind == KindInput {
+		w.text = text
+		if w.onChange != nil {
+			w.onChange(text)
+		}
+		return true
+	}
+	return false
+}
+
+// -----------------------------------------------------------------------------
+
+// Driver renders windows and delivers user events to them.
+type Driver interface {
+	Run(app *App) error
+}
+
+// App is the project class of the GUI scripting classfile.
+type App struct {
+	windows []*Window
+
+	// Driver is the GUI driver (default is a text driver on stdin/stdout).
+	Driver Driver
+}
+
+func (p *App) app() *App {
+	return p
+}
+
+// Windows returns all windows of the application.
+func (p *App) Windows() []*Window {
+	return p.windows
+}
+
+// Refresh reevaluates all bound properties. Drivers call it after each event.
+func (p *App) Refresh() {
+	for _, win := range p.windows {
+		for _, w := range win.widgets {
+			w.refresh()
+		}
+	}
+}
+
+// Show shows all windows: it calls their onShow handlers.
+func (p *App) Show() {
+	for _, win := range p.windows {
+		if win.onShow != nil {
+			win.onShow()
+		}
+	}
+	p.Refresh()
+}
+
+// Close closes all windows: it calls their onClose handlers.
+func (p *App) Close() {
+	for _, win := range p.windows {
+		if win.onClose != nil {
+			win.onClose()
+		}
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+type iApp interface {
+	MainEntry()
+	app() *App
+}
+
+type iWindow interface {
+	Main()
+	win() *Window
+}
+
+type iBinder interface {
+	Gop_Bind()
+}
+
+// Gopt_App_Main is the main entry of the GUI scripting classfile.
+func Gopt_App_Main(a iApp) {
+	if err := Gopt_App_Run(a); err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+}
+
+// Gopt_App_Run initializes windows of the application and runs it.
+func Gopt_App_Run(a iApp) error {
+	p := a.app()
+	if b, ok := a.(iBinder); ok {
+		b.Gop_Bind()
+	}
+	v := reflect.ValueOf(a).Elem()
+	t := v.Type()
+	for i, n := 0, v.NumField(); i < n; i++ {
+		if t.Field(i).Anonymous || !t.Field(i).IsExported() {
+			continue
+		}
+		fld := v.Field(i).Addr()
+		w, ok := fld.Interface().(iWindow)
+		if !ok {
+			continue
+		}
+		setAppField(fld.Elem(), a)
+		if b, ok := w.(iBinder); ok {
+			b.Gop_Bind()
+		}
+		w.Main()
+		p.windows = append(p.windows, w.win())
+	}
+	a.MainEntry()
+	driver := p.Driver
+	if driver == nil {
+		driver = NewTextDriver(os.Stdin, os.Stdout)
+	}
+	p.Show()
+	defer p.Close()
+	return driver.Run(p)
+}
+
+// setAppField sets the embedded pointer to the project class of a window.
+func setAppField(win reflect.Value, a iApp) {
+	av := reflect.ValueOf(a)
+	for i, n := 0, win.NumField(); i < n; i++ {
+		if fld := win.Field(i); fld.Type() == av.Type() && fld.CanSet() {
+			fld.Set(av)
+		}
+	}
+}
+
+// -----------------------------------------------------------------------------
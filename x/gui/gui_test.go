@@ -0,0 +1,88 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package gui
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type AppT struct {
+	App
+	Counter CounterT
+	shown   bool
+}
+
+func (p *AppT) MainEntry() {}
+
+type CounterT struct {
+	Window
+	*AppT
+	count int
+	typed string
+}
+
+func (p *CounterT) onShow() {
+	p.shown = true
+}
+
+func (p *CounterT) Gop_Bind() {
+	p.OnShow(p.onShow)
+}
+
+func (p *CounterT) Main() {
+	p.Title("Counter")
+	p.Label__1(func() string { return "count: " + strconv.Itoa(p.count) })
+	p.Button("Add", func() { p.count++ })
+	p.Input(func(text string) { p.typed = text })
+}
+
+func TestApp(t *testing.T) {
+	var out bytes.Buffer
+	in := strings.NewReader("click button1\nclick button1\ninput input1 hi\nclick unknown\nquit\n")
+	app := new(AppT)
+	app.Driver = NewTextDriver(in, &out)
+	if err := Gopt_App_Run(app); err != nil {
+		t.Fatal("Gopt_App_Run:", err)
+	}
+	win := app.Counter
+	if win.AppT != app || !app.shown || win.count != 2 || win.typed != "hi" {
+		t.Fatal("Gopt_App_Run:", win.AppT == app, app.shown, win.count, win.typed)
+	}
+	if w := win.Widget("label1"); w == nil || w.Text() != "count: 2" {
+		t.Fatal("label1:", w)
+	}
+	ret := out.String()
+	if !strings.HasPrefix(ret, "== Counter ==\ncount: 0\n[Add] (button1)\n<> (input1)\n") ||
+		!strings.Contains(ret, "invalid command: click unknown\n") ||
+		!strings.Contains(ret, "count: 2\n[Add] (button1)\n<hi> (input1)\n") {
+		t.Fatal("output:", ret)
+	}
+}
+
+func TestWidget(t *testing.T) {
+	var win Window
+	label := win.Label__0("hello")
+	label.SetText("world")
+	if label.Text() != "world" || label.ID != "label1" || win.Label__0("").ID != "label2" {
+		t.Fatal("Label:", label.Text(), label.ID)
+	}
+	if win.Click("label1") || win.Change("label1", "x") || win.Widget("button1") != nil {
+		t.Fatal("Click/Change: unexpected success")
+	}
+}
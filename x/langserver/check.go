@@ -0,0 +1,259 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package langserver
+
+import (
+	goast "go/ast"
+	goparser "go/parser"
+	"go/types"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/goplus/gop"
+	"github.com/goplus/gop/ast"
+	"github.com/goplus/gop/cl"
+	"github.com/goplus/gop/parser"
+	"github.com/goplus/gop/token"
+	"github.com/goplus/gop/x/gopenv"
+	"github.com/goplus/gop/x/pathutil"
+	"github.com/goplus/gop/x/typesutil"
+	"github.com/goplus/mod/gopmod"
+)
+
+// -----------------------------------------------------------------------------
+
+// A File is a type-checked Go+ source file.
+type File struct {
+	Path string
+	Src  []byte
+	Fset *token.FileSet
+	AST  *ast.File
+	Mod  *gopmod.Module
+
+	// Pkg and Info are type information of the package the file belongs to.
+	// They may be incomplete if the package has type errors.
+	Pkg  *types.Package
+	Info *typesutil.Info
+
+	// Diags are diagnostics of the Go+ compiler, which may have suggested
+	// fixes. See CodeActions.
+	Diags []*cl.Diagnostic
+}
+
+// TypeOf returns the type of expression e, or nil if it is unknown.
+func (p *File) TypeOf(e ast.Expr) types.Type {
+	return p.Info.TypeOf(e)
+}
+
+// Offset returns the byte offset of pos in the file.
+func (p *File) Offset(pos token.Pos) int {
+	return p.Fset.Position(pos).Offset
+}
+
+// Pos returns the position of the byte offset in the file.
+func (p *File) Pos(offset int) token.Pos {
+	return p.Fset.File(p.AST.Pos()).Pos(offset)
+}
+
+// CheckFile parses the Go+ source file path and type-checks it with other
+// source files of its package. If src != nil, it is used as content of the
+// file instead of the content on disk. Type errors are ignored.
+func CheckFile(file string, src []byte) (ret *File, err error) {
+	return checkFile(file, src, nil)
+}
+
+// checkFile is like CheckFile, but type errors are reported to onError.
+func checkFile(file string, src []byte, onError func(err error)) (ret *File, err error) {
+	ret, err = parseFile(file, src)
+	if err != nil {
+		return
+	}
+	fset, f, mod := ret.Fset, ret.AST, ret.Mod
+	dir, fname := filepath.Split(ret.Path)
+	conf := parser.Config{ClassKind: mod.ClassKind, Mode: parser.ParseComments}
+	goPkg := goPkgNameIn(dir)
+	pkgName := pkgNameOf(f, goPkg)
+	gopFiles := []*ast.File{f}
+	var goFiles []*goast.File
+	if entries, e := os.ReadDir(dir); e == nil {
+		for _, entry := range entries {
+			name := entry.Name()
+			if entry.IsDir() || name == fname || isTestFile(name) && !isTestFile(fname) {
+				continue
+			}
+			if isGoFile(name) {
+				if gof, e := goparser.ParseFile(fset, filepath.Join(dir, name), nil, goparser.ParseComments); e == nil && gof.Name.Name == pkgName {
+					goFiles = append(goFiles, gof)
+				}
+			} else if isGopFile(mod, name) {
+				if gopf, e := parser.ParseEntry(fset, filepath.Join(dir, name), nil, conf); e == nil && pkgNameOf(gopf, goPkg) == pkgName {
+					gopFiles = append(gopFiles, gopf)
+				}
+			}
+		}
+	}
+	ret.Pkg, ret.Info, ret.Diags = checkPkg(fset, mod, dir, pkgName, goFiles, gopFiles, onError)
+	return
+}
+
+// parseFile parses the Go+ source file without type-checking it, so Pkg and
+// Info of the returned File are nil. If src != nil, it is used as content of
+// the file instead of the content on disk.
+func parseFile(file string, src []byte) (ret *File, err error) {
+	if file, err = pathutil.Abs(file); err != nil {
+		return
+	}
+	if src == nil {
+		if src, err = os.ReadFile(file); err != nil {
+			return
+		}
+	}
+	mod, err := gop.LoadMod(filepath.Dir(file))
+	if err != nil {
+		return
+	}
+	fset := token.NewFileSet()
+	conf := parser.Config{ClassKind: mod.ClassKind, Mode: parser.ParseComments}
+	f, err := parser.ParseEntry(fset, file, src, conf)
+	if err != nil {
+		return
+	}
+	return &File{Path: file, Src: src, Fset: fset, AST: f, Mod: mod}, nil
+}
+
+// checkPkg type-checks files of the package pkgName in dir. Type errors are
+// reported to onError, or ignored if onError is nil. Warnings of the compiler
+// are reported as soft errors, and are also returned as diags with errors of
+// the compiler.
+func checkPkg(fset *token.FileSet, mod *gopmod.Module, dir, pkgName string, goFiles []*goast.File, gopFiles []*ast.File, onError func(err error)) (*types.Package, *typesutil.Info, []*cl.Diagnostic) {
+	pkg := types.NewPackage(pkgPathOf(mod, dir, pkgName), pkgName)
+	info := &typesutil.Info{
+		Types:      make(map[ast.Expr]types.TypeAndValue),
+		Defs:       make(map[*ast.Ident]types.Object),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Implicits:  make(map[ast.Node]types.Object),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+		Scopes:     make(map[ast.Node]*types.Scope),
+		Classes:    make(map[string]*cl.ClassInfo),
+	}
+	if onError == nil {
+		onError = func(err error) {}
+	}
+	conf := &types.Config{
+		Importer: gop.NewImporter(mod, gopenv.Get(), fset),
+		Error:    onError,
+	}
+	var diags []*cl.Diagnostic
+	opts := &typesutil.Config{
+		Types: pkg, Fset: fset, Mod: mod, Strict: true,
+		OnDiagnostic: func(d *cl.Diagnostic) {
+			diags = append(diags, d)
+		},
+	}
+	check := typesutil.NewChecker(conf, opts, nil, info)
+	check.Files(goFiles, gopFiles)
+	return pkg, info, diags
+}
+
+// goPkgNameIn returns the package name of Go files in dir (excluding external
+// tests), if they are all in one package other than main. Go+ files without
+// package clauses in dir are in this package instead of main, as
+// parser.ParseFSDir does.
+func goPkgNameIn(dir string) (ret string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	fset := token.NewFileSet()
+	for _, entry := range entries {
+		if name := entry.Name(); !entry.IsDir() && isGoFile(name) {
+			f, err := goparser.ParseFile(fset, filepath.Join(dir, name), nil, goparser.PackageClauseOnly)
+			if err != nil {
+				continue
+			}
+			n := strings.TrimSuffix(f.Name.Name, "_test")
+			if ret != "" && ret != n {
+				return ""
+			}
+			ret = n
+		}
+	}
+	if ret == "main" {
+		return ""
+	}
+	return
+}
+
+// pkgNameOf returns the package name of the Go+ file f, which is goPkg if f
+// has no package clause and goPkg isn't empty (see goPkgNameIn).
+func pkgNameOf(f *ast.File, goPkg string) string {
+	if f.NoPkgDecl && goPkg != "" {
+		f.Name.Name = goPkg
+	}
+	return f.Name.Name
+}
+
+// isGoFile reports whether name is a Go source file (excluding files
+// generated by Go+).
+func isGoFile(name string) bool {
+	return filepath.Ext(name) == ".go" && !strings.HasPrefix(name, "gop_autogen")
+}
+
+// isGopFile reports whether name is a Go+ source file (including classfiles).
+func isGopFile(mod *gopmod.Module, name string) bool {
+	switch ext := filepath.Ext(name); ext {
+	case ".gop", ".gox":
+		return true
+	default:
+		return mod.IsClass(ext)
+	}
+}
+
+func isTestFile(name string) bool {
+	return strings.HasSuffix(strings.TrimSuffix(name, filepath.Ext(name)), "_test")
+}
+
+func pkgPathOf(mod *gopmod.Module, dir, pkgName string) string {
+	if pkgName != "main" {
+		if pkgPath, ok := modPkgPath(mod, dir); ok {
+			if strings.HasSuffix(pkgName, "_test") {
+				pkgPath += "_test"
+			}
+			return pkgPath
+		}
+	}
+	return pkgName
+}
+
+func hasModfile(mod *gopmod.Module) bool {
+	f := mod.File
+	return f != nil && f.Syntax != nil
+}
+
+// modPkgPath returns the package path of dir in the module mod.
+func modPkgPath(mod *gopmod.Module, dir string) (string, bool) {
+	if hasModfile(mod) {
+		if rel, ok := pathutil.Rel(mod.Root(), dir); ok {
+			return path.Join(mod.Path(), filepath.ToSlash(rel)), true
+		}
+	}
+	return "", false
+}
+
+// -----------------------------------------------------------------------------
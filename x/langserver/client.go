@@ -25,8 +25,22 @@ import (
 // -----------------------------------------------------------------------------
 
 const (
-	methodGenGo   = "gengo"
-	methodChanged = "changed"
+	methodGenGo         = "gengo"
+	methodChanged       = "changed"
+	methodCodeAction    = "codeAction"
+	methodReferences    = "references"
+	methodRename        = "rename"
+	methodUnused        = "unused"
+	methodHover         = "hover"
+	methodInlayHint     = "inlayHint"
+	methodSignature     = "signatureHelp"
+	methodDiagnostic    = "diagnostic"
+	methodWorkspaceDiag = "workspaceDiagnostic"
+	methodDefinition    = "definition"
+	methodSymbols       = "symbols"
+	methodCompletion    = "completion"
+	methodOutline       = "outline"
+	methodFolding       = "foldingRange"
 )
 
 // -----------------------------------------------------------------------------
@@ -71,4 +85,112 @@ func (p Client) Changed(ctx context.Context, files ...string) (err error) {
 	return p.conn.Notify(ctx, methodChanged, files)
 }
 
+// CodeActions returns code actions available at range [start, end) of the
+// file (in byte offsets).
+func (p Client) CodeActions(ctx context.Context, file string, start, end int) (ret []*CodeAction, err error) {
+	params := &CodeActionParams{File: file, Start: start, End: end}
+	err = p.conn.Call(ctx, methodCodeAction, params).Await(ctx, &ret)
+	return
+}
+
+// References returns all occurrences of the symbol at the byte offset of the
+// file in its workspace.
+func (p Client) References(ctx context.Context, file string, offset int) (ret []*Ref, err error) {
+	params := &RefParams{File: file, Offset: offset}
+	err = p.conn.Call(ctx, methodReferences, params).Await(ctx, &ret)
+	return
+}
+
+// Rename returns edits of files to rename the symbol at the byte offset of the
+// file to newName.
+func (p Client) Rename(ctx context.Context, file string, offset int, newName string) (ret map[string][]*TextEdit, err error) {
+	params := &RenameParams{File: file, Offset: offset, NewName: newName}
+	err = p.conn.Call(ctx, methodRename, params).Await(ctx, &ret)
+	return
+}
+
+// Hover returns information of the symbol at the byte offset of the file,
+// or nil if there is no symbol at the offset.
+func (p Client) Hover(ctx context.Context, file string, offset int) (ret *Hover, err error) {
+	params := &HoverParams{File: file, Offset: offset}
+	err = p.conn.Call(ctx, methodHover, params).Await(ctx, &ret)
+	return
+}
+
+// InlayHints returns inlay hints in range [start, end) of the file (in byte
+// offsets), or of the whole file if end <= start.
+func (p Client) InlayHints(ctx context.Context, file string, start, end int) (ret []*InlayHint, err error) {
+	params := &InlayHintParams{File: file, Start: start, End: end}
+	err = p.conn.Call(ctx, methodInlayHint, params).Await(ctx, &ret)
+	return
+}
+
+// SignatureHelp returns signatures of the innermost call at the byte offset
+// of the file, or nil if the offset isn't in a call.
+func (p Client) SignatureHelp(ctx context.Context, file string, offset int) (ret *SignatureHelp, err error) {
+	params := &SignatureHelpParams{File: file, Offset: offset}
+	err = p.conn.Call(ctx, methodSignature, params).Await(ctx, &ret)
+	return
+}
+
+// Diagnostics returns diagnostics of the package of the file. If the result
+// is the same as the previous one identified by prevResultID, the report is
+// marked unchanged without items.
+func (p Client) Diagnostics(ctx context.Context, file, prevResultID string) (ret *DiagnosticReport, err error) {
+	params := &DiagnosticParams{File: file, PreviousResultID: prevResultID}
+	err = p.conn.Call(ctx, methodDiagnostic, params).Await(ctx, &ret)
+	return
+}
+
+// WorkspaceDiagnostics returns diagnostics of all packages in the workspace
+// of the file. prevResultIDs maps directories of packages to their previous
+// result ids (see Diagnostics).
+func (p Client) WorkspaceDiagnostics(ctx context.Context, file string, prevResultIDs map[string]string) (ret []*DiagnosticReport, err error) {
+	params := &WorkspaceDiagnosticParams{File: file, PreviousResultIDs: prevResultIDs}
+	err = p.conn.Call(ctx, methodWorkspaceDiag, params).Await(ctx, &ret)
+	return
+}
+
+// Unused returns declarations of unused symbols in the workspace of the file.
+// See Index.Unused.
+func (p Client) Unused(ctx context.Context, file string) (ret []*Ref, err error) {
+	err = p.conn.Call(ctx, methodUnused, file).Await(ctx, &ret)
+	return
+}
+
+// Definition returns the location where the symbol at the byte offset of the
+// file is declared, or nil if not found.
+func (p Client) Definition(ctx context.Context, file string, offset int) (ret *Location, err error) {
+	params := &DefinitionParams{File: file, Offset: offset}
+	err = p.conn.Call(ctx, methodDefinition, params).Await(ctx, &ret)
+	return
+}
+
+// Symbols returns top-level declarations of the file. See Symbols.
+func (p Client) Symbols(ctx context.Context, file string) (ret []*Symbol, err error) {
+	err = p.conn.Call(ctx, methodSymbols, file).Await(ctx, &ret)
+	return
+}
+
+// Outline returns a hierarchical outline of the file. See Outline.
+func (p Client) Outline(ctx context.Context, file string) (ret []*Symbol, err error) {
+	err = p.conn.Call(ctx, methodOutline, file).Await(ctx, &ret)
+	return
+}
+
+// FoldingRanges returns ranges of the file which can be folded. See
+// FoldingRanges.
+func (p Client) FoldingRanges(ctx context.Context, file string) (ret []*FoldingRange, err error) {
+	err = p.conn.Call(ctx, methodFolding, file).Await(ctx, &ret)
+	return
+}
+
+// Completions returns candidates to complete the identifier ending at the
+// byte offset of the file.
+func (p Client) Completions(ctx context.Context, file string, offset int) (ret []*Completion, err error) {
+	params := &CompletionParams{File: file, Offset: offset}
+	err = p.conn.Call(ctx, methodCompletion, params).Await(ctx, &ret)
+	return
+}
+
 // -----------------------------------------------------------------------------
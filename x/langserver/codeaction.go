@@ -0,0 +1,153 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package langserver
+
+import (
+	"bytes"
+
+	"github.com/goplus/gop/ast"
+	"github.com/goplus/gop/format"
+	"github.com/goplus/gop/token"
+	xformat "github.com/goplus/gop/x/format"
+)
+
+// -----------------------------------------------------------------------------
+
+// A TextEdit replaces text in range [Start, End) of a file (in byte offsets)
+// with NewText.
+type TextEdit struct {
+	Start   int    `json:"start"`
+	End     int    `json:"end"`
+	NewText string `json:"newText"`
+}
+
+// Kinds of code actions.
+const (
+	CodeActionQuickFix = "quickfix"         // fixes suggested by the compiler
+	CodeActionRewrite  = "refactor.rewrite" // rewrites code
+)
+
+// A CodeAction is a change to a file which can be applied by editors.
+type CodeAction struct {
+	Title string      `json:"title"`
+	Kind  string      `json:"kind"`
+	Edits []*TextEdit `json:"edits"`
+}
+
+// CodeActionParams represents parameters of the codeAction request.
+type CodeActionParams struct {
+	File  string `json:"file"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+}
+
+type rewriter struct {
+	title   string
+	rewrite func(file *ast.File, pos token.Pos, typeOf xformat.TypeOf) bool
+}
+
+var rewriters = []rewriter{
+	{"Convert comprehension to for loop", xformat.CompToLoop},
+	{"Convert for loop to comprehension", xformat.LoopToComp},
+}
+
+// CodeActions returns code actions available at the byte offset of the Go+
+// source file, ie. fixes of diagnostics at the offset suggested by the compiler
+// (eg. adding a missing return statement) and rewrites. If src != nil, it is
+// used as content of the file instead of the content on disk.
+func CodeActions(file string, src []byte, offset int) (ret []*CodeAction, err error) {
+	if ret, err = quickFixes(file, src, offset); err != nil {
+		return
+	}
+	for _, r := range rewriters {
+		f, e := CheckFile(file, src) // rewriters change the AST
+		if e != nil {
+			return nil, e
+		}
+		if src = f.Src; offset < 0 || offset > len(src) {
+			return
+		}
+		if !r.rewrite(f.AST, f.Pos(offset), f.TypeOf) {
+			continue
+		}
+		var buf bytes.Buffer
+		if err = format.Node(&buf, f.Fset, f.AST); err != nil {
+			return
+		}
+		newSrc, e := format.Source(buf.Bytes(), f.AST.IsClass, file)
+		if e != nil {
+			continue
+		}
+		ret = append(ret, &CodeAction{Title: r.title, Kind: CodeActionRewrite, Edits: diffEdits(src, newSrc)})
+	}
+	return
+}
+
+// quickFixes returns fixes of diagnostics at the byte offset of the file.
+func quickFixes(file string, src []byte, offset int) (ret []*CodeAction, err error) {
+	f, err := CheckFile(file, src)
+	if err != nil {
+		return
+	}
+	tf := f.Fset.File(f.AST.Pos())
+	for _, d := range f.Diags {
+		if f.Fset.File(d.Pos) != tf {
+			continue
+		}
+		start, end := f.Offset(d.Pos), f.Offset(d.Pos)
+		if d.End.IsValid() {
+			end = f.Offset(d.End)
+		}
+		if offset < start || offset > end {
+			continue
+		}
+	next:
+		for _, fix := range d.Fixes {
+			edits := make([]*TextEdit, 0, len(fix.Edits))
+			for _, e := range fix.Edits {
+				if f.Fset.File(e.Pos) != tf {
+					continue next // TODO: edits of other files
+				}
+				edits = append(edits, &TextEdit{Start: f.Offset(e.Pos), End: f.Offset(e.End), NewText: e.NewText})
+			}
+			ret = append(ret, &CodeAction{Title: fix.Message, Kind: CodeActionQuickFix, Edits: edits})
+		}
+	}
+	return
+}
+
+// diffEdits returns edits to change old into new, which replace the changed
+// part between their common prefix and common suffix.
+func diffEdits(old, new []byte) []*TextEdit {
+	i, n := 0, len(old)
+	if len(new) < n {
+		n = len(new)
+	}
+	for i < n && old[i] == new[i] {
+		i++
+	}
+	j := 0
+	for j < n-i && old[len(old)-1-j] == new[len(new)-1-j] {
+		j++
+	}
+	if i == len(old)-j && i == len(new)-j {
+		return nil
+	}
+	return []*TextEdit{{Start: i, End: len(old) - j, NewText: string(new[i : len(new)-j])}}
+}
+
+// -----------------------------------------------------------------------------
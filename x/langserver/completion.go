@@ -0,0 +1,263 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package langserver
+
+import (
+	"go/types"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/goplus/gop/ast"
+	"github.com/goplus/gop/token"
+	"github.com/goplus/gop/x/pathutil"
+)
+
+// -----------------------------------------------------------------------------
+
+// A Completion is a candidate to complete the identifier at a position.
+type Completion struct {
+	Label  string `json:"label"`
+	Kind   string `json:"kind"`             // see KindFunc, KindVar, etc.
+	Detail string `json:"detail,omitempty"` // eg. type of a variable
+}
+
+// CompletionParams represents parameters of the completion request.
+type CompletionParams = RefParams
+
+// Completions returns candidates to complete the identifier ending at the
+// byte offset of the Go+ source file. They are members of x if the identifier
+// is the selector of `x.sel`, or objects in scope otherwise, filtered by the
+// part of the identifier before the offset (case-insensitively). If src !=
+// nil, it is used as content of the file instead of the content on disk.
+//
+// The file must be parsed without errors, except that the selector after a
+// dot may be missing (eg. `fmt.` while typing).
+func Completions(file string, src []byte, offset int) (ret []*Completion, err error) {
+	if file, err = pathutil.Abs(file); err != nil {
+		return
+	}
+	if src == nil {
+		if src, err = os.ReadFile(file); err != nil {
+			return
+		}
+	}
+	if offset < 0 || offset > len(src) {
+		return
+	}
+	start := offset
+	for start > 0 {
+		r, n := utf8.DecodeLastRune(src[:start])
+		if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			break
+		}
+		start -= n
+	}
+	prefix := strings.ToLower(string(src[start:offset]))
+	if start == offset && start > 0 && src[start-1] == '.' { // a missing selector
+		patched := make([]byte, 0, len(src)+1)
+		patched = append(append(append(patched, src[:start]...), '_'), src[start:]...)
+		src = patched
+	}
+	f, err := CheckFile(file, src)
+	if err != nil {
+		return
+	}
+	p := &completer{f: f, prefix: prefix, seen: make(map[string]bool)}
+	p.qualifier = func(pkg *types.Package) string {
+		if pkg == f.Pkg {
+			return ""
+		}
+		return pkg.Name()
+	}
+	pos := f.Pos(start)
+	if sel := selectorAt(f.AST, pos); sel != nil {
+		p.members(sel.X)
+	} else {
+		p.scopes(pos)
+	}
+	return p.items, nil
+}
+
+type completer struct {
+	f         *File
+	prefix    string
+	seen      map[string]bool
+	items     []*Completion
+	qualifier types.Qualifier
+}
+
+func (p *completer) add(obj types.Object) {
+	name := obj.Name()
+	if name == "_" || p.seen[name] || !strings.HasPrefix(strings.ToLower(name), p.prefix) {
+		return
+	}
+	p.seen[name] = true
+	item := &Completion{Label: name}
+	switch v := obj.(type) {
+	case *types.Var:
+		item.Kind = KindVar
+		if v.IsField() {
+			item.Kind = KindField
+		}
+	case *types.Const:
+		item.Kind = KindConst
+	case *types.TypeName:
+		item.Kind = KindType
+		switch obj.Type().Underlying().(type) {
+		case *types.Struct:
+			item.Kind = KindStruct
+		case *types.Interface:
+			item.Kind = KindInterface
+		}
+	case *types.Func:
+		item.Kind = KindFunc
+		if v.Type().(*types.Signature).Recv() != nil {
+			item.Kind = KindMethod
+		}
+	case *types.PkgName:
+		item.Kind, item.Detail = KindPackage, v.Imported().Path()
+	case *types.Builtin:
+		item.Kind = KindFunc
+	default:
+		item.Kind = KindVar
+	}
+	if item.Detail == "" && obj.Type() != nil && obj.Type() != types.Typ[types.Invalid] {
+		switch obj.(type) {
+		case *types.Builtin, *types.TypeName:
+		default:
+			item.Detail = types.TypeString(obj.Type(), p.qualifier)
+		}
+	}
+	p.items = append(p.items, item)
+}
+
+// members adds exported members of the package x, or fields and methods of
+// the value x (unexported ones are added if they are in the same package).
+func (p *completer) members(x ast.Expr) {
+	if id, ok := x.(*ast.Ident); ok {
+		if pkgName, ok := p.f.Info.Uses[id].(*types.PkgName); ok {
+			scope := pkgName.Imported().Scope()
+			for _, name := range scope.Names() {
+				if obj := scope.Lookup(name); obj.Exported() {
+					p.add(obj)
+				}
+			}
+			return
+		}
+	}
+	t := p.f.TypeOf(x)
+	if t == nil {
+		return
+	}
+	visible := func(obj types.Object) bool {
+		return obj.Exported() || obj.Pkg() == p.f.Pkg
+	}
+	mset := t
+	if _, ok := t.Underlying().(*types.Interface); !ok {
+		if _, ok := t.(*types.Pointer); !ok {
+			mset = types.NewPointer(t) // x is assumed to be addressable
+		}
+	}
+	ms := types.NewMethodSet(mset)
+	for i, n := 0, ms.Len(); i < n; i++ {
+		if obj := ms.At(i).Obj(); visible(obj) {
+			p.add(obj)
+		}
+	}
+	// fields of embedded structs are added breadth first, so that the ones
+	// shadowed by shallower fields are skipped
+	queue, visited := []types.Type{t}, make(map[types.Type]bool)
+	for len(queue) > 0 {
+		t := queue[0]
+		queue = queue[1:]
+		if ptr, ok := t.(*types.Pointer); ok {
+			t = ptr.Elem()
+		}
+		st, ok := t.Underlying().(*types.Struct)
+		if !ok || visited[t] {
+			continue
+		}
+		visited[t] = true
+		for i, n := 0, st.NumFields(); i < n; i++ {
+			field := st.Field(i)
+			if visible(field) {
+				p.add(field)
+			}
+			if field.Embedded() {
+				queue = append(queue, field.Type())
+			}
+		}
+	}
+}
+
+// scopes adds objects in scope at pos. Local objects are added only if they
+// are declared before pos.
+func (p *completer) scopes(pos token.Pos) {
+	pkgScope := p.f.Pkg.Scope()
+	for s := scopeAt(p.f, pos); s != nil; s = s.Parent() {
+		local := s != pkgScope && s != types.Universe && s.Parent() != types.Universe
+		for _, name := range s.Names() {
+			obj := s.Lookup(name)
+			if local && obj.Pos().IsValid() && obj.Pos() >= pos {
+				continue
+			}
+			p.add(obj)
+		}
+	}
+}
+
+// scopeAt returns the innermost scope containing pos, or the package scope if
+// there is none.
+func scopeAt(f *File, pos token.Pos) (ret *types.Scope) {
+	var node ast.Node
+	for n, s := range f.Info.Scopes {
+		if _, ok := n.(*ast.File); ok || !(n.Pos() <= pos && pos <= n.End()) {
+			continue
+		}
+		if node == nil || n.Pos() >= node.Pos() && n.End() <= node.End() {
+			node, ret = n, s
+		}
+	}
+	if ret == nil {
+		ret = f.Pkg.Scope()
+		for n, s := range f.Info.Scopes {
+			if n == f.AST {
+				ret = s
+			}
+		}
+	}
+	return
+}
+
+// selectorAt returns the selector expression whose selector is at pos, or nil.
+func selectorAt(f *ast.File, pos token.Pos) (ret *ast.SelectorExpr) {
+	ast.Inspect(f, func(n ast.Node) bool {
+		if ret != nil || n == nil {
+			return false
+		}
+		if sel, ok := n.(*ast.SelectorExpr); ok && sel.Sel.Pos() == pos {
+			ret = sel
+			return false
+		}
+		return true
+	})
+	return
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,101 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package langserver
+
+import (
+	"bytes"
+	"os"
+
+	"github.com/goplus/gop/token"
+)
+
+// -----------------------------------------------------------------------------
+
+// A Location is a range of a source file.
+type Location struct {
+	File   string `json:"file"`
+	Offset int    `json:"offset"` // byte offset, starting at 0
+	End    int    `json:"end"`    // byte offset of the end of the range
+	Line   int    `json:"line"`   // line number, starting at 1
+	Column int    `json:"column"` // column number, starting at 1 (in bytes)
+}
+
+// DefinitionParams represents parameters of the definition request.
+type DefinitionParams = RefParams
+
+// DefinitionAt returns the location of the name where the symbol of the
+// identifier at the byte offset of the Go+ source file is declared, or nil if
+// there is no symbol at the offset or it isn't declared in a source file (eg.
+// a builtin). If src != nil, it is used as content of the file instead of the
+// content on disk.
+func DefinitionAt(file string, src []byte, offset int) (ret *Location, err error) {
+	f, err := CheckFile(file, src)
+	if err != nil || offset < 0 || offset > len(f.Src) {
+		return
+	}
+	id := identAt(f.AST, f.Pos(offset))
+	if id == nil {
+		return
+	}
+	obj := f.Info.Uses[id]
+	if obj == nil {
+		if obj = f.Info.Defs[id]; obj == nil {
+			return
+		}
+	}
+	if !obj.Pos().IsValid() {
+		return
+	}
+	pos := f.Fset.Position(obj.Pos())
+	data := f.Src
+	if pos.Filename != f.Path {
+		if data, err = os.ReadFile(pos.Filename); err != nil {
+			return nil, nil
+		}
+	}
+	return locate(data, pos, obj.Name()), nil
+}
+
+// locate returns the location of name declared at pos of the source file data.
+// Positions of objects imported from export data have lines only, so name is
+// searched in the line if it isn't at the offset of pos.
+func locate(data []byte, pos token.Position, name string) *Location {
+	ret := &Location{File: pos.Filename, Offset: pos.Offset, Line: pos.Line, Column: pos.Column}
+	if end := pos.Offset + len(name); pos.Offset < 0 || end > len(data) || string(data[pos.Offset:end]) != name {
+		start, line := 0, 1
+		for ; line < pos.Line; line++ {
+			i := bytes.IndexByte(data[start:], '\n')
+			if i < 0 {
+				break
+			}
+			start += i + 1
+		}
+		lineEnd := bytes.IndexByte(data[start:], '\n')
+		if lineEnd < 0 {
+			lineEnd = len(data) - start
+		}
+		col := bytes.Index(data[start:start+lineEnd], []byte(name))
+		if col < 0 {
+			col = 0
+		}
+		ret.Offset, ret.Column = start+col, col+1
+	}
+	ret.End = ret.Offset + len(name)
+	return ret
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,397 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package langserver
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	goast "go/ast"
+	goparser "go/parser"
+	"go/scanner"
+	"go/types"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goplus/gop"
+	"github.com/goplus/gop/ast"
+	"github.com/goplus/gop/parser"
+	"github.com/goplus/gop/token"
+	"github.com/goplus/gop/x/pathutil"
+	"github.com/goplus/mod/gopmod"
+)
+
+// -----------------------------------------------------------------------------
+
+// Severities of diagnostics.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// A Diagnostic is an error (or a warning) of a source file.
+type Diagnostic struct {
+	File     string `json:"file"`
+	Offset   int    `json:"offset"` // byte offset, starting at 0
+	Line     int    `json:"line"`   // line number, starting at 1
+	Column   int    `json:"column"` // column number, starting at 1 (in bytes)
+	Severity string `json:"severity"`
+	Msg      string `json:"msg"`
+}
+
+// A DiagnosticReport is diagnostics of a package, ie. source files in a
+// directory.
+type DiagnosticReport struct {
+	Dir string `json:"dir"`
+
+	// ResultID identifies the result. If it is the same as the previous
+	// result id given by the client, Unchanged is true and Items is omitted.
+	ResultID  string        `json:"resultId"`
+	Unchanged bool          `json:"unchanged,omitempty"`
+	Items     []*Diagnostic `json:"items,omitempty"`
+}
+
+// DiagnosticParams represents parameters of the diagnostic request, which
+// pulls diagnostics of the package of File.
+type DiagnosticParams struct {
+	File             string `json:"file"`
+	PreviousResultID string `json:"previousResultId,omitempty"`
+}
+
+// WorkspaceDiagnosticParams represents parameters of the workspaceDiagnostic
+// request, which pulls diagnostics of all packages in the workspace of File.
+type WorkspaceDiagnosticParams struct {
+	File              string            `json:"file"`
+	PreviousResultIDs map[string]string `json:"previousResultIds,omitempty"` // dir => result id
+}
+
+// DiagnosticsDelay is how long to wait after a package is changed before its
+// diagnostics are recomputed in background, so that rapid changes (eg.
+// saving while typing) are debounced.
+var DiagnosticsDelay = 300 * time.Millisecond
+
+// A Diagnoser caches diagnostics of packages. A change of a package
+// invalidates diagnostics of the package and packages depending on it, which
+// are recomputed when they are pulled, or in background after DiagnosticsDelay
+// (by the server of Client).
+type Diagnoser struct {
+	mutex   sync.Mutex
+	pkgs    map[string]*pkgDiagnostics // dir => diagnostics
+	pending map[string]time.Time       // dir => time of the last change
+}
+
+type pkgDiagnostics struct {
+	resultID string
+	items    []*Diagnostic
+	deps     []string // directories of local packages imported
+}
+
+// NewDiagnoser creates a new Diagnoser.
+func NewDiagnoser() *Diagnoser {
+	return &Diagnoser{
+		pkgs:    make(map[string]*pkgDiagnostics),
+		pending: make(map[string]time.Time),
+	}
+}
+
+// Changed invalidates diagnostics of packages in the changed directories and
+// packages depending on them (directly or indirectly).
+func (p *Diagnoser) Changed(dirs ...string) {
+	now := time.Now()
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for len(dirs) > 0 {
+		dir := dirs[len(dirs)-1]
+		dirs = dirs[:len(dirs)-1]
+		if _, ok := p.pending[dir]; ok {
+			p.pending[dir] = now
+			continue
+		}
+		p.pending[dir] = now
+		for d, pkg := range p.pkgs {
+			if _, ok := p.pending[d]; !ok && contains(pkg.deps, dir) {
+				dirs = append(dirs, d)
+			}
+		}
+	}
+}
+
+// flush recomputes diagnostics of packages changed before DiagnosticsDelay.
+func (p *Diagnoser) flush() {
+	var dirs []string
+	deadline := time.Now().Add(-DiagnosticsDelay)
+	p.mutex.Lock()
+	for dir, t := range p.pending {
+		if t.Before(deadline) {
+			dirs = append(dirs, dir)
+		}
+	}
+	p.mutex.Unlock()
+	for _, dir := range dirs {
+		p.update(dir)
+	}
+}
+
+// update recomputes diagnostics of the package in dir.
+func (p *Diagnoser) update(dir string) *pkgDiagnostics {
+	p.mutex.Lock()
+	t, ok := p.pending[dir]
+	p.mutex.Unlock()
+	pkg := diagnose(dir)
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if t2, ok2 := p.pending[dir]; ok2 == ok && t2.Equal(t) { // not changed again
+		delete(p.pending, dir)
+	}
+	p.pkgs[dir] = pkg
+	return pkg
+}
+
+// Diagnostics returns diagnostics of the package in dir. If the result is the
+// same as the previous one identified by prevResultID, the report is marked
+// unchanged without items.
+func (p *Diagnoser) Diagnostics(dir, prevResultID string) *DiagnosticReport {
+	p.mutex.Lock()
+	pkg, ok := p.pkgs[dir]
+	if _, pending := p.pending[dir]; pending {
+		ok = false
+	}
+	p.mutex.Unlock()
+	if !ok {
+		pkg = p.update(dir)
+	}
+	ret := &DiagnosticReport{Dir: dir, ResultID: pkg.resultID}
+	if pkg.resultID == prevResultID {
+		ret.Unchanged = true
+	} else {
+		ret.Items = pkg.items
+	}
+	return ret
+}
+
+// Diagnostics returns diagnostics of the package of the file. Results are
+// cached until the file or packages imported by the file are changed. See
+// Client.Changed.
+func (p *handler) Diagnostics(file, prevResultID string) (ret *DiagnosticReport, err error) {
+	if file, err = pathutil.Abs(file); err != nil {
+		return
+	}
+	for p.genDirty() { // packages are imported by their generated Go code
+	}
+	return p.diags.Diagnostics(filepath.Dir(file), prevResultID), nil
+}
+
+// Workspace returns diagnostics of all packages of Go+ files in the directory
+// tree of root. prevResultIDs maps directories of packages to their previous
+// result ids (see Diagnostics).
+func (p *Diagnoser) Workspace(root string, prevResultIDs map[string]string) (ret []*DiagnosticReport, err error) {
+	files, err := gopFilesIn(root)
+	if err != nil {
+		return
+	}
+	dirs := make(map[string]bool)
+	for file := range files {
+		dirs[filepath.Dir(file)] = true
+	}
+	for dir := range dirs {
+		ret = append(ret, p.Diagnostics(dir, prevResultIDs[dir]))
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Dir < ret[j].Dir
+	})
+	return
+}
+
+// WorkspaceDiagnostics returns diagnostics of all packages in the workspace
+// of the file (see WorkspaceOf).
+func (p *handler) WorkspaceDiagnostics(file string, prevResultIDs map[string]string) (ret []*DiagnosticReport, err error) {
+	root, err := WorkspaceOf(file)
+	if err != nil {
+		return
+	}
+	for p.genDirty() {
+	}
+	return p.diags.Workspace(root, prevResultIDs)
+}
+
+// -----------------------------------------------------------------------------
+
+// diagnose parses and type-checks packages in dir, and returns diagnostics
+// of them.
+func diagnose(dir string) *pkgDiagnostics {
+	ret := &pkgDiagnostics{}
+	var items []*Diagnostic
+	if mod, err := gop.LoadMod(dir); err == nil {
+		items, ret.deps = diagnoseDir(mod, dir)
+	} else {
+		items = []*Diagnostic{{File: dir, Line: 1, Column: 1, Severity: SeverityError, Msg: err.Error()}}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Offset < b.Offset
+	})
+	ret.items = items
+	b, _ := json.Marshal(items)
+	h := sha1.Sum(b)
+	ret.resultID = hex.EncodeToString(h[:8])
+	return ret
+}
+
+func diagnoseDir(mod *gopmod.Module, dir string) (items []*Diagnostic, deps []string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	fset := token.NewFileSet()
+	addErr := func(err error) {
+		items = appendDiagnostics(items, err, dir)
+	}
+	conf := parser.Config{ClassKind: mod.ClassKind, Mode: parser.ParseComments}
+	gopPkgs := make(map[string][]*ast.File)
+	goPkgs := make(map[string][]*goast.File)
+	imports := make(map[string]bool)
+	goPkg := goPkgNameIn(dir)
+	for _, entry := range entries {
+		name := entry.Name()
+		file := filepath.Join(dir, name)
+		if entry.IsDir() {
+			continue
+		}
+		if isGoFile(name) {
+			f, err := goparser.ParseFile(fset, file, nil, goparser.ParseComments)
+			if err != nil {
+				addErr(err)
+				continue
+			}
+			goPkgs[f.Name.Name] = append(goPkgs[f.Name.Name], f)
+			for _, imp := range f.Imports {
+				imports[imp.Path.Value] = true
+			}
+		} else if isGopFile(mod, name) {
+			f, err := parser.ParseEntry(fset, file, nil, conf)
+			if err != nil {
+				addErr(err)
+				continue
+			}
+			pkgName := pkgNameOf(f, goPkg)
+			gopPkgs[pkgName] = append(gopPkgs[pkgName], f)
+			for _, imp := range f.Imports {
+				imports[imp.Path.Value] = true
+			}
+		}
+	}
+	for pkgName, files := range gopPkgs {
+		checkPkg(fset, mod, dir, pkgName, goPkgs[pkgName], files, addErr)
+	}
+	for imp := range imports {
+		if pkgPath, err := strconv.Unquote(imp); err == nil {
+			if dep, ok := modPkgDir(mod, pkgPath); ok {
+				deps = append(deps, dep)
+			}
+		}
+	}
+	sort.Strings(deps)
+	return
+}
+
+// appendDiagnostics appends diagnostics of err to items. Errors without
+// positions are reported at the beginning of file.
+func appendDiagnostics(items []*Diagnostic, err error, file string) []*Diagnostic {
+	add := func(pos token.Position, severity, msg string) {
+		items = append(items, &Diagnostic{
+			File: pos.Filename, Offset: pos.Offset, Line: pos.Line, Column: pos.Column, Severity: severity, Msg: msg,
+		})
+	}
+	switch e := err.(type) {
+	case scanner.ErrorList:
+		for _, v := range e {
+			add(v.Pos, SeverityError, v.Msg)
+		}
+	case types.Error:
+		severity := SeverityError
+		if e.Soft {
+			severity = SeverityWarning
+		}
+		add(e.Fset.Position(e.Pos), severity, e.Msg)
+	default:
+		add(token.Position{Filename: file}, SeverityError, err.Error())
+	}
+	return items
+}
+
+// DiagnoseFile returns diagnostics of the Go+ source file, which is
+// type-checked with other source files of its package. If src != nil, it is
+// used as content of the file instead of the content on disk, so that unsaved
+// changes of an editor can be diagnosed. Unlike Diagnostics, results are not
+// cached, and diagnostics of other files of the package are dropped.
+func DiagnoseFile(file string, src []byte) (ret []*Diagnostic, err error) {
+	if file, err = pathutil.Abs(file); err != nil {
+		return
+	}
+	var items []*Diagnostic
+	_, err = checkFile(file, src, func(e error) {
+		items = appendDiagnostics(items, e, file)
+	})
+	if err != nil {
+		if _, ok := err.(scanner.ErrorList); !ok {
+			return
+		}
+		items, err = appendDiagnostics(nil, err, file), nil
+	}
+	for _, item := range items {
+		if item.File == file {
+			ret = append(ret, item)
+		}
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		return ret[i].Offset < ret[j].Offset
+	})
+	return
+}
+
+// modPkgDir returns the directory of the package pkgPath in the module mod.
+func modPkgDir(mod *gopmod.Module, pkgPath string) (string, bool) {
+	if hasModfile(mod) {
+		modPath := mod.Path()
+		if pkgPath == modPath {
+			return mod.Root(), true
+		}
+		if strings.HasPrefix(pkgPath, modPath+"/") {
+			return filepath.Join(mod.Root(), filepath.FromSlash(pkgPath[len(modPath)+1:])), true
+		}
+	}
+	return "", false
+}
+
+func contains(dirs []string, dir string) bool {
+	for _, d := range dirs {
+		if d == dir {
+			return true
+		}
+	}
+	return false
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,121 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package langserver
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeModule writes files (named by slash-separated paths) of module
+// example.com/m into a new temporary directory, and returns it.
+func writeModule(t *testing.T, files map[string]string) string {
+	t.Helper()
+	if out, err := exec.Command("go", "env", "GOCACHE").Output(); err == nil {
+		t.Setenv("GOCACHE", strings.TrimSpace(string(out)))
+	}
+	root := t.TempDir()
+	files["go.mod"] = "module example.com/m\n\ngo 1.18\n"
+	for name, src := range files {
+		writeFile(t, filepath.Join(root, filepath.FromSlash(name)), src)
+	}
+	return root
+}
+
+func writeFile(t *testing.T, file, src string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func msgsOf(items []*Diagnostic) string {
+	msgs := make([]string, len(items))
+	for i, item := range items {
+		msgs[i] = filepath.Base(item.File) + ": " + item.Msg
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func TestDiagnosticsUnchanged(t *testing.T) {
+	root := writeModule(t, map[string]string{
+		"main.gop": "println y\n",
+	})
+	p := NewDiagnoser()
+	ret := p.Diagnostics(root, "")
+	if ret.Unchanged || ret.ResultID == "" || len(ret.Items) != 1 {
+		t.Fatalf("diagnostics: %+v\n%s", ret, msgsOf(ret.Items))
+	}
+	prev := ret.ResultID
+	if ret = p.Diagnostics(root, prev); !ret.Unchanged || ret.ResultID != prev || ret.Items != nil {
+		t.Fatalf("diagnostics: %+v", ret)
+	}
+
+	// the error is moved by a change, but recomputed diagnostics are
+	// unchanged if they are the same
+	writeFile(t, filepath.Join(root, "main.gop"), "\nprintln y\n")
+	p.Changed(root)
+	if ret = p.Diagnostics(root, prev); ret.Unchanged || ret.ResultID == prev {
+		t.Fatalf("diagnostics: %+v", ret)
+	}
+	prev = ret.ResultID
+	writeFile(t, filepath.Join(root, "other.gop"), "// a comment\n")
+	p.Changed(root)
+	if ret = p.Diagnostics(root, prev); !ret.Unchanged || ret.ResultID != prev {
+		t.Fatalf("diagnostics: %+v", ret)
+	}
+}
+
+func TestDiagnosticsInvalidate(t *testing.T) {
+	root := writeModule(t, map[string]string{
+		"a/main.gop": "import \"example.com/m/b\"\n\nprintln b.Hello()\n",
+		"b/b.go":     "package b\n\nfunc Hello() string {\n\treturn \"hello\"\n}\n",
+	})
+	dirA, dirB := filepath.Join(root, "a"), filepath.Join(root, "b")
+	p := NewDiagnoser()
+	ret := p.Diagnostics(dirA, "")
+	if len(ret.Items) != 0 {
+		t.Fatal("diagnostics:", msgsOf(ret.Items))
+	}
+	prev := ret.ResultID
+	if pkg := p.pkgs[dirA]; len(pkg.deps) != 1 || pkg.deps[0] != dirB {
+		t.Fatal("deps:", pkg.deps)
+	}
+
+	// a change of b invalidates diagnostics of a, which imports b
+	writeFile(t, filepath.Join(dirB, "b.go"), "package b\n\nfunc Hi() string {\n\treturn \"hi\"\n}\n")
+	if ret = p.Diagnostics(dirA, prev); !ret.Unchanged {
+		t.Fatalf("diagnostics: %+v", ret)
+	}
+	p.Changed(dirB)
+	if _, ok := p.pending[dirA]; !ok {
+		t.Fatal("changed: a isn't invalidated")
+	}
+	ret = p.Diagnostics(dirA, prev)
+	if ret.Unchanged || !strings.Contains(msgsOf(ret.Items), "Hello") {
+		t.Fatalf("diagnostics: %+v\n%s", ret, msgsOf(ret.Items))
+	}
+	if _, ok := p.pending[dirA]; ok {
+		t.Fatal("diagnostics: a is still pending")
+	}
+}
@@ -0,0 +1,112 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package langserver
+
+import (
+	"sort"
+
+	"github.com/goplus/gop/ast"
+	"github.com/goplus/gop/token"
+)
+
+// -----------------------------------------------------------------------------
+
+// Kinds of folding ranges.
+const (
+	FoldComment = "comment"
+	FoldImports = "imports"
+	FoldRegion  = "region"
+)
+
+// A FoldingRange is a range of a Go+ source file which editors can fold. For
+// a range in brackets (eg. a block), Start is the offset after the opening
+// bracket and End is the offset of the closing one.
+type FoldingRange struct {
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+	Kind  string `json:"kind,omitempty"` // FoldComment, FoldImports, FoldRegion or ""
+}
+
+// FoldingRanges returns ranges of the Go+ source file which span multiple
+// lines and can be folded: blocks, composite literals, fields of structs,
+// methods of interfaces, parenthesized declarations, arguments of calls,
+// case clauses, comments and regions (see Outline). The file is parsed but
+// not type-checked. If src != nil, it is used as content of the file instead
+// of the content on disk.
+func FoldingRanges(file string, src []byte) (ret []*FoldingRange, err error) {
+	f, err := parseFile(file, src)
+	if err != nil {
+		return
+	}
+	add := func(start, end token.Pos, kind string) {
+		if !start.IsValid() || !end.IsValid() {
+			return
+		}
+		if f.Fset.Position(start).Line < f.Fset.Position(end).Line {
+			ret = append(ret, &FoldingRange{Start: f.Offset(start), End: f.Offset(end), Kind: kind})
+		}
+	}
+	ast.Inspect(f.AST, func(node ast.Node) bool {
+		switch v := node.(type) {
+		case *ast.BlockStmt:
+			add(v.Lbrace+1, v.Rbrace, "")
+		case *ast.CompositeLit:
+			add(v.Lbrace+1, v.Rbrace, "")
+		case *ast.SliceLit:
+			add(v.Lbrack+1, v.Rbrack, "")
+		case *ast.ComprehensionExpr:
+			add(v.Lpos+1, v.Rpos, "")
+		case *ast.CallExpr:
+			add(v.Lparen+1, v.Rparen, "")
+		case *ast.FieldList:
+			if v.Opening.IsValid() {
+				add(v.Opening+1, v.Closing, "")
+			}
+		case *ast.GenDecl:
+			if v.Lparen.IsValid() {
+				kind := ""
+				if v.Tok == token.IMPORT {
+					kind = FoldImports
+				}
+				add(v.Lparen+1, v.Rparen, kind)
+			}
+		case *ast.CaseClause:
+			add(v.Colon+1, v.End(), "")
+		case *ast.CommClause:
+			add(v.Colon+1, v.End(), "")
+		}
+		return true
+	})
+	for _, cg := range f.AST.Comments {
+		add(cg.Pos(), cg.End(), FoldComment)
+	}
+	p := &symbolizer{f: f}
+	var addRegions func(regions []*Symbol)
+	addRegions = func(regions []*Symbol) {
+		for _, r := range regions {
+			ret = append(ret, &FoldingRange{Start: r.Start, End: r.End, Kind: FoldRegion})
+			addRegions(r.Children)
+		}
+	}
+	addRegions(p.regionSyms())
+	sort.SliceStable(ret, func(i, j int) bool {
+		return ret[i].Start < ret[j].Start
+	})
+	return
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,338 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package langserver
+
+import (
+	"fmt"
+	goast "go/ast"
+	goparser "go/parser"
+	gotoken "go/token"
+	"go/types"
+	"path/filepath"
+
+	"github.com/goplus/gop/ast"
+	"github.com/goplus/gop/cl"
+	"github.com/goplus/gop/parser"
+	"github.com/goplus/gop/token"
+	"github.com/goplus/gop/x/typesutil"
+)
+
+// -----------------------------------------------------------------------------
+
+// A Hover describes the symbol at a position of a file.
+type Hover struct {
+	Start     int    `json:"start"` // range of the identifier (in byte offsets)
+	End       int    `json:"end"`
+	Signature string `json:"signature"`
+	Doc       string `json:"doc,omitempty"`
+
+	// Origin explains where the symbol comes from if it isn't declared by
+	// the code as it is written, eg. members of a class injected by its
+	// classfile framework.
+	Origin string `json:"origin,omitempty"`
+}
+
+// HoverParams represents parameters of the hover request.
+type HoverParams = RefParams
+
+// HoverAt returns information of the identifier at the byte offset of the Go+
+// source file, or nil if there is no symbol at the offset. If src != nil, it
+// is used as content of the file instead of the content on disk.
+func HoverAt(file string, src []byte, offset int) (ret *Hover, err error) {
+	f, err := CheckFile(file, src)
+	if err != nil || offset < 0 || offset > len(f.Src) {
+		return
+	}
+	id := identAt(f.AST, f.Pos(offset))
+	if id == nil {
+		return
+	}
+	obj := f.Info.Defs[id]
+	if obj == nil {
+		if obj = f.Info.Uses[id]; obj == nil {
+			return
+		}
+	}
+	qualifier := func(pkg *types.Package) string {
+		if pkg == f.Pkg {
+			return ""
+		}
+		return pkg.Name()
+	}
+	return &Hover{
+		Start:     f.Offset(id.Pos()),
+		End:       f.Offset(id.End()),
+		Signature: types.ObjectString(obj, qualifier),
+		Doc:       docOf(f, obj),
+		Origin:    originOf(f.Fset, f.Info, f.Path, obj),
+	}, nil
+}
+
+// identAt returns the identifier at pos of file f, or nil if there is none.
+// Identifiers which are not in source (eg. generated by classfiles) are
+// ignored.
+func identAt(f *ast.File, pos token.Pos) (ret *ast.Ident) {
+	ast.Inspect(f, func(n ast.Node) bool {
+		switch v := n.(type) {
+		case nil:
+			return false
+		case *ast.File: // its range may be wrong, eg. if it's a classfile
+			return true
+		case *ast.FuncDecl:
+			if !v.Name.Pos().IsValid() { // eg. event binding method of a class
+				return false
+			}
+		case *ast.Ident:
+			if v.Pos() <= pos && pos < v.End() && v != shadowName(f, v) {
+				ret = v
+			}
+			return false
+		}
+		return n.Pos() <= pos && pos <= n.End()
+	})
+	return
+}
+
+// shadowName returns id if it's the name of a shadow function of file f (eg.
+// main func of a script), or nil.
+func shadowName(f *ast.File, id *ast.Ident) *ast.Ident {
+	if f.ShadowEntry != nil && f.ShadowEntry.Name == id {
+		return id
+	}
+	for _, decl := range f.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Shadow && fn.Name == id {
+			return id
+		}
+	}
+	return nil
+}
+
+// originOf explains where obj comes from if it's a member of a class injected
+// by its classfile framework, or returns "".
+func originOf(fset *token.FileSet, info *typesutil.Info, file string, obj types.Object) string {
+	var proj *cl.ClassInfo
+	for _, c := range info.Classes {
+		if c.Proj {
+			proj = c
+		}
+	}
+	class := info.Classes[file]
+	switch o := obj.(type) {
+	case *types.Var:
+		if !o.IsField() {
+			if class != nil && o.Name() == "this" {
+				return fmt.Sprintf("the instance of class %s", class.Name)
+			}
+			break
+		}
+		if proj != nil {
+			if o.Embedded() && o.Name() == proj.Name {
+				return fmt.Sprintf("project class %s, embedded in work classes of the project", proj.Name)
+			}
+			for _, w := range proj.Workers {
+				if o.Name() == w && typeName(o.Type()) == w {
+					return fmt.Sprintf("work class %s, injected as a field of project class %s", w, proj.Name)
+				}
+			}
+		}
+	case *types.TypeName:
+		for _, c := range info.Classes {
+			if o.Name() == c.Name && isClassOf(o.Type(), c) {
+				return fmt.Sprintf("class of classfile %s", filepath.Base(c.File))
+			}
+		}
+	case *types.Func:
+		for _, c := range info.Classes {
+			for _, h := range c.Handlers {
+				if info.Defs[h.Name] == obj {
+					return fmt.Sprintf("event handler of class %s, bound by calling %s.%s in %s", c.Name, qualifiedName(c.Base), h.Binder, c.Bind)
+				}
+			}
+		}
+	}
+	if class != nil && class.Base != nil {
+		if o, _, _ := types.LookupFieldOrMethod(class.Base.Type(), true, obj.Pkg(), obj.Name()); o == obj {
+			return fmt.Sprintf("inherited from %s, the base class of class %s", qualifiedName(class.Base), class.Name)
+		}
+	}
+	if c := info.Classes[fset.Position(obj.Pos()).Filename]; c != nil && isMember(obj) {
+		return fmt.Sprintf("member of class %s, declared in classfile %s", c.Name, filepath.Base(c.File))
+	}
+	return ""
+}
+
+// isClassOf reports whether t is the class of classfile c, which embeds the
+// base class as its first field.
+func isClassOf(t types.Type, c *cl.ClassInfo) bool {
+	st, ok := t.Underlying().(*types.Struct)
+	if !ok || c.Base == nil {
+		return ok
+	}
+	if st.NumFields() == 0 || !st.Field(0).Embedded() {
+		return false
+	}
+	base := st.Field(0).Type()
+	if p, ok := base.(*types.Pointer); ok {
+		base = p.Elem()
+	}
+	return base == c.Base.Type()
+}
+
+func isMember(obj types.Object) bool {
+	switch o := obj.(type) {
+	case *types.Var:
+		return o.IsField()
+	case *types.Func:
+		return o.Type().(*types.Signature).Recv() != nil
+	}
+	return false
+}
+
+func qualifiedName(obj types.Object) string {
+	if obj.Pkg() == nil {
+		return obj.Name()
+	}
+	return obj.Pkg().Name() + "." + obj.Name()
+}
+
+// -----------------------------------------------------------------------------
+
+// docOf returns the doc comment of obj, or "" if it isn't available.
+func docOf(f *File, obj types.Object) string {
+	pos := f.Fset.Position(obj.Pos())
+	if !pos.IsValid() {
+		return ""
+	}
+	if pos.Filename == f.Path {
+		return gopDocAt(f.AST, f.Fset, pos.Line, obj.Name())
+	}
+	if filepath.Ext(pos.Filename) == ".go" {
+		fset := gotoken.NewFileSet()
+		if gof, err := goparser.ParseFile(fset, pos.Filename, nil, goparser.ParseComments); err == nil {
+			return goDocAt(gof, fset, pos.Line, obj.Name())
+		}
+		return ""
+	}
+	fset := token.NewFileSet()
+	conf := parser.Config{ClassKind: f.Mod.ClassKind, Mode: parser.ParseComments}
+	if gopf, err := parser.ParseEntry(fset, pos.Filename, nil, conf); err == nil {
+		return gopDocAt(gopf, fset, pos.Line, obj.Name())
+	}
+	return ""
+}
+
+// goDocAt returns the doc comment of the Go declaration of name at the line.
+// Columns aren't used because they aren't available in export data.
+func goDocAt(f *goast.File, fset *gotoken.FileSet, line int, name string) (doc string) {
+	found := false
+	at := func(id *goast.Ident) bool {
+		return id.Name == name && fset.Position(id.Pos()).Line == line
+	}
+	goast.Inspect(f, func(n goast.Node) bool {
+		switch v := n.(type) {
+		case *goast.FuncDecl:
+			if at(v.Name) {
+				doc, found = v.Doc.Text(), true
+			}
+		case *goast.GenDecl:
+			for _, spec := range v.Specs {
+				switch s := spec.(type) {
+				case *goast.TypeSpec:
+					if at(s.Name) {
+						doc, found = specDoc(v.Doc, s.Doc, s.Comment, len(v.Specs)), true
+					}
+				case *goast.ValueSpec:
+					for _, name := range s.Names {
+						if at(name) {
+							doc, found = specDoc(v.Doc, s.Doc, s.Comment, len(v.Specs)), true
+						}
+					}
+				}
+			}
+		case *goast.Field:
+			for _, name := range v.Names {
+				if at(name) {
+					doc, found = specDoc(nil, v.Doc, v.Comment, 0), true
+				}
+			}
+		}
+		return !found
+	})
+	return
+}
+
+// gopDocAt returns the doc comment of the Go+ declaration of name at the
+// line.
+func gopDocAt(f *ast.File, fset *token.FileSet, line int, name string) (doc string) {
+	found := false
+	at := func(id *ast.Ident) bool {
+		return id.Name == name && fset.Position(id.Pos()).Line == line
+	}
+	ast.Inspect(f, func(n ast.Node) bool {
+		switch v := n.(type) {
+		case *ast.FuncDecl:
+			if at(v.Name) {
+				doc, found = v.Doc.Text(), true
+			}
+		case *ast.GenDecl:
+			for _, spec := range v.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					if at(s.Name) {
+						doc, found = specDoc(v.Doc, s.Doc, s.Comment, len(v.Specs)), true
+					}
+				case *ast.ValueSpec:
+					for _, name := range s.Names {
+						if at(name) {
+							doc, found = specDoc(v.Doc, s.Doc, s.Comment, len(v.Specs)), true
+						}
+					}
+				}
+			}
+		case *ast.Field:
+			for _, name := range v.Names {
+				if at(name) {
+					doc, found = specDoc(nil, v.Doc, v.Comment, 0), true
+				}
+			}
+		}
+		return !found
+	})
+	return
+}
+
+type commentGroup interface {
+	Text() string
+}
+
+// specDoc returns the doc comment of a spec (or a field), which falls back
+// to the line comment, or the doc comment of its declaration if it's the
+// only spec of the declaration.
+func specDoc(declDoc, doc, comment commentGroup, nspec int) string {
+	if text := doc.Text(); text != "" {
+		return text
+	}
+	if text := comment.Text(); text != "" {
+		return text
+	}
+	if nspec == 1 {
+		return declDoc.Text()
+	}
+	return ""
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,532 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package langserver
+
+import (
+	"crypto/sha1"
+	"encoding/gob"
+	"encoding/hex"
+	goast "go/ast"
+	goparser "go/parser"
+	"go/types"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goplus/gop"
+	"github.com/goplus/gop/ast"
+	"github.com/goplus/gop/parser"
+	"github.com/goplus/gop/token"
+	"github.com/goplus/gop/x/pathutil"
+	"github.com/goplus/mod/gopmod"
+)
+
+// -----------------------------------------------------------------------------
+
+// A Ref is an occurrence of a symbol in a Go+ source file.
+//
+// A symbol is identified by its package path and name for package-level
+// objects (eg. `fmt.Println`), by its package path, type name and name for
+// methods and fields (eg. `main.T.Name`), or by the file and offset where it
+// is declared for local objects (eg. `/path/to/foo.gop#123`).
+type Ref struct {
+	Sym    string `json:"sym"`
+	File   string `json:"file"`
+	Offset int    `json:"offset"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	Def    bool   `json:"def,omitempty"` // the declaration of the symbol
+}
+
+type fileIndex struct {
+	ModTime time.Time
+	Size    int64
+	Pkg     string   // package name
+	Refs    []*Ref   // identifiers in the file
+	Globals []string // package-level symbols declared in the file
+}
+
+// indexVersion is the version of the index file format.
+const indexVersion = 1
+
+type indexFile struct {
+	Version int
+	Root    string
+	Files   map[string]*fileIndex
+}
+
+// An Index is an index of identifier usages of all Go+ source files in a
+// workspace, which is persisted in the user cache directory and updated
+// incrementally, so that tools like find-references, rename and dead code
+// detection don't need to parse and type-check the whole workspace. Go
+// source files are type-checked with Go+ files, but they are not indexed.
+type Index struct {
+	root  string
+	mutex sync.Mutex
+	files map[string]*fileIndex
+	syms  map[string][]*Ref // symbol => refs
+}
+
+// LoadIndex loads the index of the workspace root from the user cache
+// directory, and updates it. It creates a new index if there is no index
+// cached yet.
+func LoadIndex(root string) (p *Index, err error) {
+	if root, err = pathutil.Abs(root); err != nil {
+		return
+	}
+	p = &Index{root: root, files: make(map[string]*fileIndex)}
+	if f, e := os.Open(p.cacheFile()); e == nil {
+		var data indexFile
+		if gob.NewDecoder(f).Decode(&data) == nil && data.Version == indexVersion && data.Root == root {
+			p.files = data.Files
+		}
+		f.Close()
+	}
+	p.rebuildSyms()
+	_, err = p.Update()
+	return
+}
+
+// Root returns the root directory of the workspace.
+func (p *Index) Root() string {
+	return p.root
+}
+
+func (p *Index) cacheFile() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	h := sha1.Sum([]byte(p.root))
+	return filepath.Join(dir, "gop-index", hex.EncodeToString(h[:8])+".gob")
+}
+
+// Save saves the index into the user cache directory.
+func (p *Index) Save() error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	file := p.cacheFile()
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(file), "index")
+	if err != nil {
+		return err
+	}
+	err = gob.NewEncoder(f).Encode(&indexFile{Version: indexVersion, Root: p.root, Files: p.files})
+	f.Close()
+	if err == nil {
+		err = os.Rename(f.Name(), file)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// Update re-indexes packages of the workspace which are changed since they
+// were indexed, saves the index if it is changed, and reports whether it is
+// changed.
+func (p *Index) Update() (changed bool, err error) {
+	stats, err := gopFilesIn(p.root) // Go+ files in the workspace
+	if err != nil {
+		return
+	}
+	dirs := make(map[string]bool) // directories to re-index
+	p.mutex.Lock()
+	for file, fi := range stats {
+		if old, ok := p.files[file]; !ok || old.Size != fi.Size() || !old.ModTime.Equal(fi.ModTime()) {
+			dirs[filepath.Dir(file)] = true
+		}
+	}
+	for file := range p.files {
+		if _, ok := stats[file]; !ok {
+			dirs[filepath.Dir(file)] = true
+		}
+	}
+	p.mutex.Unlock()
+	if len(dirs) == 0 {
+		return
+	}
+	p.updateDirs(dirs)
+	return true, p.Save()
+}
+
+// gopFilesIn returns Go+ source files in the directory tree of root.
+// Directories ignored by the go command (eg. testdata) are skipped.
+func gopFilesIn(root string) (files map[string]fs.FileInfo, err error) {
+	files = make(map[string]fs.FileInfo)
+	mods := make(map[string]*gopmod.Module)
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if path != root && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "testdata" || name == "vendor") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		dir := filepath.Dir(path)
+		mod, ok := mods[dir]
+		if !ok {
+			mod, _ = gop.LoadMod(dir)
+			mods[dir] = mod
+		}
+		if mod == nil || !isGopFile(mod, name) {
+			return nil
+		}
+		if fi, e := d.Info(); e == nil {
+			files[path] = fi
+		}
+		return nil
+	})
+	return
+}
+
+// UpdateFiles re-indexes packages of the files, and saves the index. It's
+// called when the files are changed (eg. saved by an editor).
+func (p *Index) UpdateFiles(files ...string) error {
+	dirs := make(map[string]bool)
+	for _, file := range files {
+		if file, err := pathutil.Abs(file); err == nil && p.contains(file) {
+			dirs[filepath.Dir(file)] = true
+		}
+	}
+	if len(dirs) == 0 {
+		return nil
+	}
+	p.updateDirs(dirs)
+	return p.Save()
+}
+
+func (p *Index) contains(file string) bool {
+	_, ok := pathutil.Rel(p.root, file)
+	return ok
+}
+
+func (p *Index) updateDirs(dirs map[string]bool) {
+	var wg sync.WaitGroup
+	ch := make(chan string)
+	for i, n := 0, runtime.NumCPU(); i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for dir := range ch {
+				files := indexDir(dir)
+				p.mutex.Lock()
+				for file := range p.files {
+					if filepath.Dir(file) == dir {
+						delete(p.files, file)
+					}
+				}
+				for file, fi := range files {
+					p.files[file] = fi
+				}
+				p.mutex.Unlock()
+			}
+		}()
+	}
+	for dir := range dirs {
+		ch <- dir
+	}
+	close(ch)
+	wg.Wait()
+	p.rebuildSyms()
+}
+
+func (p *Index) rebuildSyms() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	syms := make(map[string][]*Ref)
+	for _, fi := range p.files {
+		for _, ref := range fi.Refs {
+			syms[ref.Sym] = append(syms[ref.Sym], ref)
+		}
+	}
+	for _, refs := range syms {
+		sortRefs(refs)
+	}
+	p.syms = syms
+}
+
+func sortRefs(refs []*Ref) {
+	sort.Slice(refs, func(i, j int) bool {
+		if refs[i].File != refs[j].File {
+			return refs[i].File < refs[j].File
+		}
+		return refs[i].Offset < refs[j].Offset
+	})
+}
+
+// -----------------------------------------------------------------------------
+
+// RefParams represents parameters of the references request.
+type RefParams struct {
+	File   string `json:"file"`
+	Offset int    `json:"offset"`
+}
+
+// RenameParams represents parameters of the rename request.
+type RenameParams struct {
+	File    string `json:"file"`
+	Offset  int    `json:"offset"`
+	NewName string `json:"newName"`
+}
+
+// SymbolAt returns the symbol of the identifier at the byte offset of file,
+// or "" if not found.
+func (p *Index) SymbolAt(file string, offset int) string {
+	if file, err := pathutil.Abs(file); err == nil {
+		p.mutex.Lock()
+		defer p.mutex.Unlock()
+		if fi, ok := p.files[file]; ok {
+			for _, ref := range fi.Refs {
+				if offset >= ref.Offset && offset < ref.Offset+len(symName(ref.Sym)) {
+					return ref.Sym
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// References returns all occurrences of the symbol, including its declaration.
+func (p *Index) References(sym string) []*Ref {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return append([]*Ref(nil), p.syms[sym]...)
+}
+
+// Unused returns declarations of package-level symbols which are never used
+// in the workspace. Exported symbols of non-main packages, methods, fields,
+// and the main and init functions are not reported, because they may be
+// used implicitly or out of the workspace.
+func (p *Index) Unused() (ret []*Ref) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for _, fi := range p.files {
+		for _, sym := range fi.Globals {
+			name := symName(sym)
+			if name == "main" || name == "init" || (token.IsExported(name) && fi.Pkg != "main") {
+				continue
+			}
+			refs := p.syms[sym]
+			used := false
+			for _, ref := range refs {
+				used = used || !ref.Def
+			}
+			if !used {
+				for _, ref := range refs {
+					if ref.Def {
+						ret = append(ret, ref)
+					}
+				}
+			}
+		}
+	}
+	sortRefs(ret)
+	return
+}
+
+// symName returns the name of the symbol.
+func symName(sym string) string {
+	if pos := strings.LastIndexByte(sym, '#'); pos >= 0 { // local object
+		return sym[strings.LastIndexByte(sym, '@')+1 : pos]
+	}
+	return sym[strings.LastIndexByte(sym, '.')+1:]
+}
+
+// -----------------------------------------------------------------------------
+
+// indexDir indexes Go+ source files in dir.
+func indexDir(dir string) map[string]*fileIndex {
+	ret := make(map[string]*fileIndex)
+	mod, err := gop.LoadMod(dir)
+	if err != nil {
+		return ret
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ret
+	}
+	fset := token.NewFileSet()
+	conf := parser.Config{ClassKind: mod.ClassKind, Mode: parser.ParseComments}
+	gopPkgs := make(map[string][]*ast.File)
+	goPkgs := make(map[string][]*goast.File)
+	goPkg := goPkgNameIn(dir)
+	for _, entry := range entries {
+		name := entry.Name()
+		file := filepath.Join(dir, name)
+		if entry.IsDir() {
+			continue
+		}
+		if isGoFile(name) {
+			if f, e := goparser.ParseFile(fset, file, nil, goparser.ParseComments); e == nil {
+				goPkgs[f.Name.Name] = append(goPkgs[f.Name.Name], f)
+			}
+		} else if isGopFile(mod, name) {
+			fi, e := entry.Info()
+			if e != nil {
+				continue
+			}
+			ret[file] = &fileIndex{ModTime: fi.ModTime(), Size: fi.Size()}
+			if f, e := parser.ParseEntry(fset, file, nil, conf); e == nil {
+				pkgName := pkgNameOf(f, goPkg)
+				ret[file].Pkg = pkgName
+				gopPkgs[pkgName] = append(gopPkgs[pkgName], f)
+			}
+		}
+	}
+	for pkgName, files := range gopPkgs {
+		pkg, info, _ := checkPkg(fset, mod, dir, pkgName, goPkgs[pkgName], files, nil)
+		idx := &indexer{fset: fset, pkg: pkg, pkgPath: pkg.Path(), fields: make(map[*types.Var]string), shadows: make(map[*ast.Ident]bool)}
+		for _, f := range files {
+			if f.ShadowEntry != nil {
+				idx.shadows[f.ShadowEntry.Name] = true
+			}
+			for _, decl := range f.Decls {
+				if fn, ok := decl.(*ast.FuncDecl); ok && fn.Shadow { // eg. main func of a script
+					idx.shadows[fn.Name] = true
+				}
+			}
+		}
+		if pkg.Path() == pkg.Name() { // not in a module, or a main package
+			if pkgPath, ok := modPkgPath(mod, dir); ok {
+				idx.pkgPath = pkgPath
+			} else {
+				idx.pkgPath = filepath.ToSlash(dir)
+			}
+		}
+		for id, obj := range info.Defs {
+			idx.add(ret, id, obj, true)
+		}
+		for id, obj := range info.Uses {
+			idx.add(ret, id, obj, false)
+		}
+	}
+	return ret
+}
+
+type indexer struct {
+	fset    *token.FileSet
+	pkg     *types.Package
+	pkgPath string                // package path of pkg in symbols
+	fields  map[*types.Var]string // field => its type name
+	pkgs    map[*types.Package]bool
+	shadows map[*ast.Ident]bool // identifiers not in source
+}
+
+func (p *indexer) add(ret map[string]*fileIndex, id *ast.Ident, obj types.Object, def bool) {
+	if obj == nil || id.Name == "_" || !id.Pos().IsValid() || p.shadows[id] {
+		return
+	}
+	pos := p.fset.Position(id.Pos())
+	fi, ok := ret[pos.Filename]
+	if !ok {
+		return
+	}
+	sym := p.symbolOf(obj)
+	if sym == "" {
+		return
+	}
+	if def && obj.Parent() == p.pkg.Scope() {
+		fi.Globals = append(fi.Globals, sym)
+	}
+	fi.Refs = append(fi.Refs, &Ref{
+		Sym: sym, File: pos.Filename, Offset: pos.Offset, Line: pos.Line, Column: pos.Column, Def: def,
+	})
+}
+
+func (p *indexer) symbolOf(obj types.Object) string {
+	pkg := obj.Pkg()
+	if pkg == nil {
+		return ""
+	}
+	pkgPath := pkg.Path()
+	if pkg == p.pkg {
+		pkgPath = p.pkgPath
+	}
+	switch v := obj.(type) {
+	case *types.PkgName, *types.Label:
+		return ""
+	case *types.Func:
+		if recv := v.Type().(*types.Signature).Recv(); recv != nil {
+			if name := typeName(recv.Type()); name != "" {
+				return pkgPath + "." + name + "." + v.Name()
+			}
+			return ""
+		}
+	case *types.Var:
+		if v.IsField() {
+			if name, ok := p.fieldOwner(v); ok {
+				return pkgPath + "." + name + "." + v.Name()
+			}
+		}
+	}
+	if obj.Parent() == pkg.Scope() {
+		return pkgPath + "." + obj.Name()
+	}
+	if pkg != p.pkg || !obj.Pos().IsValid() {
+		return ""
+	}
+	pos := p.fset.Position(obj.Pos())
+	return pos.Filename + "@" + obj.Name() + "#" + strconv.Itoa(pos.Offset)
+}
+
+// fieldOwner returns the name of the named struct type the field belongs to.
+func (p *indexer) fieldOwner(field *types.Var) (name string, ok bool) {
+	pkg := field.Pkg()
+	if p.pkgs == nil {
+		p.pkgs = make(map[*types.Package]bool)
+	}
+	if !p.pkgs[pkg] {
+		p.pkgs[pkg] = true
+		scope := pkg.Scope()
+		for _, n := range scope.Names() {
+			if tn, ok := scope.Lookup(n).(*types.TypeName); ok {
+				if t, ok := tn.Type().Underlying().(*types.Struct); ok {
+					for i, nf := 0, t.NumFields(); i < nf; i++ {
+						p.fields[t.Field(i)] = n
+					}
+				}
+			}
+		}
+	}
+	name, ok = p.fields[field]
+	return
+}
+
+// typeName returns the name of the named type t (or *t).
+func typeName(t types.Type) string {
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	if named, ok := t.(*types.Named); ok {
+		return named.Obj().Name()
+	}
+	return ""
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,226 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package langserver_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/goplus/gop/x/langserver"
+)
+
+var goCache string
+
+func init() {
+	if os.Getenv("GOPROOT") == "" {
+		dir, _ := os.Getwd()
+		os.Setenv("GOPROOT", filepath.Clean(filepath.Join(dir, "./../..")))
+	}
+	if out, err := exec.Command("go", "env", "GOCACHE").Output(); err == nil {
+		goCache = strings.TrimSpace(string(out))
+	}
+}
+
+// writeFiles writes files into a new temporary directory, and returns it.
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// loadIndex loads the index of files, which is cached in a temporary directory.
+func loadIndex(t *testing.T, files map[string]string) (*langserver.Index, string) {
+	t.Helper()
+	t.Setenv("GOCACHE", goCache) // keep the build cache of the go command
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	dir := writeFiles(t, files)
+	idx, err := langserver.LoadIndex(dir)
+	if err != nil {
+		t.Fatal("LoadIndex:", err)
+	}
+	return idx, dir
+}
+
+// offsetOf returns the byte offset of the n-th (0-based) occurrence of s in
+// file.
+func offsetOf(t *testing.T, file, s string, n int) int {
+	t.Helper()
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	off := -1
+	for i := 0; i <= n; i++ {
+		pos := strings.Index(string(data[off+1:]), s)
+		if pos < 0 {
+			t.Fatalf("%s: %q not found", file, s)
+		}
+		off += pos + 1
+	}
+	return off
+}
+
+// refsOf returns refs as "file:line:col" (with "def" for the declaration).
+func refsOf(refs []*langserver.Ref) string {
+	ret := make([]string, len(refs))
+	for i, ref := range refs {
+		ret[i] = filepath.Base(ref.File) + ":" + strconv.Itoa(ref.Line) + ":" + strconv.Itoa(ref.Column)
+		if ref.Def {
+			ret[i] += " def"
+		}
+	}
+	sort.Strings(ret)
+	return strings.Join(ret, ", ")
+}
+
+var refsFiles = map[string]string{
+	"hello.gop": `func hello(name string) string {
+	return "hello " + name
+}
+
+func greet() {
+}
+
+var unusedVar = 1
+
+func unusedFn() {}
+`,
+	"main.gop": `func f() {
+	msg := "x"
+	println hello(msg)
+}
+
+f()
+greet()
+`,
+	"other.gop": `func g() string {
+	return hello("y")
+}
+
+var _ = g
+`,
+}
+
+func TestReferences(t *testing.T) {
+	idx, dir := loadIndex(t, refsFiles)
+	hello := filepath.Join(dir, "hello.gop")
+	sym := idx.SymbolAt(hello, offsetOf(t, hello, "hello", 0))
+	if !strings.HasSuffix(sym, ".hello") {
+		t.Fatal("SymbolAt:", sym)
+	}
+	if refs := refsOf(idx.References(sym)); refs != "hello.gop:1:6 def, main.gop:3:10, other.gop:2:9" {
+		t.Fatal("References:", refs)
+	}
+	main := filepath.Join(dir, "main.gop")
+	if sym2 := idx.SymbolAt(main, offsetOf(t, main, "hello", 0)+2); sym2 != sym {
+		t.Fatal("SymbolAt:", sym2)
+	}
+}
+
+func TestRename(t *testing.T) {
+	idx, dir := loadIndex(t, refsFiles)
+	hello := filepath.Join(dir, "hello.gop")
+	main := filepath.Join(dir, "main.gop")
+	helloSym := idx.SymbolAt(hello, offsetOf(t, hello, "hello", 0))
+	msgSym := idx.SymbolAt(main, offsetOf(t, main, "msg", 0))
+
+	edits, err := idx.Rename(helloSym, "sayHello")
+	if err != nil {
+		t.Fatal("Rename:", err)
+	}
+	if len(edits) != 3 || len(edits[main]) != 1 || edits[main][0].NewText != "sayHello" ||
+		edits[main][0].Start != offsetOf(t, main, "hello", 0) || edits[main][0].End != edits[main][0].Start+5 {
+		t.Fatal("Rename:", edits)
+	}
+
+	cases := []struct {
+		sym, newName, err string
+	}{
+		{helloSym, "1x", "invalid identifier: 1x"},
+		{helloSym, "greet", "renaming hello to greet conflicts with greet declared at"},
+		{helloSym, "msg", "renaming hello to msg makes references captured by msg declared at"},
+		{helloSym, "f", "renaming hello to f conflicts with f declared at"},
+		{msgSym, "hello", "renaming msg to hello shadows hello declared at"},
+		{msgSym, "println", ""},
+	}
+	for _, c := range cases {
+		_, err := idx.Rename(c.sym, c.newName)
+		if c.err == "" {
+			if err != nil {
+				t.Fatal("Rename:", c.newName, err)
+			}
+		} else if err == nil || !strings.HasPrefix(err.Error(), c.err) {
+			t.Fatal("Rename:", c.newName, err)
+		}
+	}
+}
+
+func TestRenameClassMethod(t *testing.T) {
+	idx, dir := loadIndex(t, map[string]string{
+		"Rect.gox": `var (
+	W, H int
+)
+
+func Area() int {
+	return W * H
+}
+
+func Double() int {
+	return Area() * 2
+}
+`,
+		"main.gop": `r := &Rect{W: 2, H: 3}
+println r.Area(), r.Double()
+`,
+	})
+	rect := filepath.Join(dir, "Rect.gox")
+	main := filepath.Join(dir, "main.gop")
+	sym := idx.SymbolAt(main, offsetOf(t, main, "Area", 0))
+	if !strings.HasSuffix(sym, ".Rect.Area") {
+		t.Fatal("SymbolAt:", sym)
+	}
+	edits, err := idx.Rename(sym, "Size")
+	if err != nil {
+		t.Fatal("Rename:", err)
+	}
+	if len(edits[rect]) != 2 || len(edits[main]) != 1 {
+		t.Fatal("Rename:", edits)
+	}
+	if _, err = idx.Rename(sym, "Double"); err == nil || !strings.Contains(err.Error(), "conflicts with Double") {
+		t.Fatal("Rename:", err)
+	}
+	if _, err = idx.Rename(sym, "W"); err == nil || !strings.Contains(err.Error(), "conflicts with W") {
+		t.Fatal("Rename:", err)
+	}
+}
+
+func TestUnused(t *testing.T) {
+	idx, _ := loadIndex(t, refsFiles)
+	if refs := refsOf(idx.Unused()); refs != "hello.gop:10:6 def, hello.gop:8:5 def" {
+		t.Fatal("Unused:", refs)
+	}
+}
@@ -0,0 +1,224 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package langserver
+
+import (
+	"go/types"
+	"sort"
+	"strings"
+
+	"github.com/goplus/gop/ast"
+	"github.com/goplus/gop/token"
+)
+
+// -----------------------------------------------------------------------------
+
+// Kinds of inlay hints.
+const (
+	InlayHintType       = "type"       // inferred type of a variable
+	InlayHintParameter  = "parameter"  // parameter name of an argument
+	InlayHintConversion = "conversion" // implicit conversion of a value
+)
+
+// An InlayHint is a label which editors display inline at a position of a
+// file, but isn't a part of the code.
+type InlayHint struct {
+	Offset int    `json:"offset"` // position of the hint (in byte offsets)
+	Label  string `json:"label"`
+	Kind   string `json:"kind"`
+}
+
+// InlayHintParams represents parameters of the inlayHint request.
+type InlayHintParams struct {
+	File  string `json:"file"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+}
+
+// InlayHints returns inlay hints in range [start, end) of the Go+ source file
+// (in byte offsets), or of the whole file if end <= start. If src != nil, it
+// is used as content of the file instead of the content on disk.
+//
+// Hints are inferred types of variables declared without types (eg. `x := 1`,
+// `for k, v in m` and parameters of lambdas), parameter names of arguments at
+// call sites, and implicit conversions of arguments and assigned values.
+func InlayHints(file string, src []byte, start, end int) (ret []*InlayHint, err error) {
+	f, err := CheckFile(file, src)
+	if err != nil {
+		return
+	}
+	p := &hinter{f: f, start: start, end: end}
+	if end <= start {
+		p.end = len(f.Src) + 1
+	}
+	p.qualifier = func(pkg *types.Package) string {
+		if pkg == f.Pkg {
+			return ""
+		}
+		return pkg.Name()
+	}
+	ast.Inspect(f.AST, p.visit)
+	sort.SliceStable(p.hints, func(i, j int) bool {
+		return p.hints[i].Offset < p.hints[j].Offset
+	})
+	return p.hints, nil
+}
+
+type hinter struct {
+	f          *File
+	start, end int
+	qualifier  types.Qualifier
+	hints      []*InlayHint
+}
+
+func (p *hinter) visit(n ast.Node) bool {
+	switch v := n.(type) {
+	case nil:
+		return false
+	case *ast.FuncDecl:
+		if !v.Name.Pos().IsValid() { // eg. event binding method of a class
+			return false
+		}
+	case *ast.AssignStmt:
+		if v.Tok == token.DEFINE {
+			p.types(exprIdents(v.Lhs)...)
+		} else if v.Tok == token.ASSIGN && len(v.Lhs) == len(v.Rhs) {
+			for i, rhs := range v.Rhs {
+				p.conversion(rhs, p.f.TypeOf(v.Lhs[i]))
+			}
+		}
+	case *ast.RangeStmt:
+		if v.Tok == token.DEFINE {
+			p.types(exprIdents([]ast.Expr{v.Key, v.Value})...)
+		}
+	case *ast.ForPhrase: // also of ForPhraseStmt
+		p.types(v.Key, v.Value)
+	case *ast.LambdaExpr:
+		p.types(v.Lhs...)
+	case *ast.LambdaExpr2:
+		p.types(v.Lhs...)
+	case *ast.ValueSpec:
+		if v.Type == nil {
+			p.types(v.Names...)
+		}
+	case *ast.CallExpr:
+		p.call(v)
+	}
+	return true
+}
+
+// types adds hints of types of variables defined by idents.
+func (p *hinter) types(idents ...*ast.Ident) {
+	for _, id := range idents {
+		if id == nil || id.Name == "_" {
+			continue
+		}
+		if obj, ok := p.f.Info.Defs[id].(*types.Var); ok { // nil if redeclared
+			p.add(id.End(), " "+types.TypeString(obj.Type(), p.qualifier), InlayHintType)
+		}
+	}
+}
+
+// call adds hints of parameter names and implicit conversions of arguments
+// of a call.
+func (p *hinter) call(call *ast.CallExpr) {
+	sig, ok := p.f.TypeOf(call.Fun).(*types.Signature)
+	if !ok {
+		return
+	}
+	params := sig.Params()
+	n := params.Len()
+	if sig.Variadic() {
+		if len(call.Args) < n-1 {
+			return
+		}
+	} else if len(call.Args) != n {
+		return
+	}
+	for i, arg := range call.Args {
+		var param *types.Var
+		var typ types.Type
+		label := ""
+		switch {
+		case i < n-1 || i == n-1 && !sig.Variadic():
+			param = params.At(i)
+			label, typ = param.Name(), param.Type()
+		case call.Ellipsis.IsValid():
+			param = params.At(n - 1)
+			label, typ = param.Name(), param.Type()
+		default:
+			param = params.At(n - 1)
+			if i == n-1 {
+				label = param.Name() + "..."
+			}
+			typ = param.Type().(*types.Slice).Elem()
+		}
+		if label != "" && label != "_" && label != "..." && !isIdentNamed(arg, param.Name()) {
+			p.add(arg.Pos(), label+":", InlayHintParameter)
+		}
+		p.conversion(arg, typ)
+	}
+}
+
+// conversion adds hints if the value of expression e is converted to type
+// typ implicitly (eg. from []int to a named slice type). Conversions of
+// untyped constants and to interfaces are ignored as they are obvious, and
+// so are values not assignable to typ (type errors).
+func (p *hinter) conversion(e ast.Expr, typ types.Type) {
+	if typ == nil {
+		return
+	}
+	tv, ok := p.f.Info.Types[e]
+	if !ok || tv.Type == nil || tv.IsNil() || types.Identical(tv.Type, typ) || types.IsInterface(typ) ||
+		!types.AssignableTo(tv.Type, typ) {
+		return
+	}
+	if t, ok := tv.Type.(*types.Basic); ok && t.Info()&types.IsUntyped != 0 {
+		return
+	}
+	conv := types.TypeString(typ, p.qualifier)
+	if strings.HasPrefix(conv, "*") || strings.HasPrefix(conv, "<-") || strings.HasPrefix(conv, "func") {
+		conv = "(" + conv + ")"
+	}
+	p.add(e.Pos(), conv+"(", InlayHintConversion)
+	p.add(e.End(), ")", InlayHintConversion)
+}
+
+func (p *hinter) add(pos token.Pos, label, kind string) {
+	if !pos.IsValid() {
+		return
+	}
+	if off := p.f.Offset(pos); off >= p.start && off < p.end {
+		p.hints = append(p.hints, &InlayHint{Offset: off, Label: label, Kind: kind})
+	}
+}
+
+// exprIdents returns identifiers of exprs (nil for other expressions).
+func exprIdents(exprs []ast.Expr) []*ast.Ident {
+	ret := make([]*ast.Ident, len(exprs))
+	for i, e := range exprs {
+		ret[i], _ = e.(*ast.Ident)
+	}
+	return ret
+}
+
+func isIdentNamed(e ast.Expr, name string) bool {
+	id, ok := e.(*ast.Ident)
+	return ok && id.Name == name
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,112 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package langserver_test
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/goplus/gop/x/langserver"
+)
+
+// hintsOf returns hints as "line:col label" separated by ", ".
+func hintsOf(src string, hints []*langserver.InlayHint, kind string) string {
+	var ret []string
+	for _, h := range hints {
+		if h.Kind != kind {
+			continue
+		}
+		line := strings.Count(src[:h.Offset], "\n") + 1
+		col := h.Offset - strings.LastIndex(src[:h.Offset], "\n")
+		ret = append(ret, strconv.Itoa(line)+":"+strconv.Itoa(col)+" "+strings.TrimSpace(h.Label))
+	}
+	return strings.Join(ret, ", ")
+}
+
+func inlayHints(t *testing.T, src string, start, end int) []*langserver.InlayHint {
+	t.Helper()
+	t.Setenv("GOCACHE", goCache)
+	dir := writeFiles(t, map[string]string{"main.gop": src})
+	hints, err := langserver.InlayHints(filepath.Join(dir, "main.gop"), nil, start, end)
+	if err != nil {
+		t.Fatal("InlayHints:", err)
+	}
+	return hints
+}
+
+const inlaySrc = `func add(a, b int) int {
+	return a + b
+}
+
+a := 1
+x := add(a, 2)
+for k, v <- [10, 20] {
+	println k, v
+}
+ys := [y * 2 for y <- [1, 2]]
+println x, ys
+`
+
+func TestInlayHints(t *testing.T) {
+	hints := inlayHints(t, inlaySrc, 0, 0)
+	// types of a, x, k, v (range), ys and y (comprehension)
+	if ret := hintsOf(inlaySrc, hints, langserver.InlayHintType); ret != "5:2 int, 6:2 int, 7:6 int, 7:9 int, 10:3 []int, 10:19 int" {
+		t.Fatal("type hints:", ret)
+	}
+	// no hint for a, which is named like the parameter
+	if ret := hintsOf(inlaySrc, hints, langserver.InlayHintParameter); ret != "6:13 b:, 8:10 a...:, 11:9 a...:" {
+		t.Fatal("parameter hints:", ret)
+	}
+}
+
+func TestInlayHintsRange(t *testing.T) {
+	start := strings.Index(inlaySrc, "x := ")
+	end := strings.Index(inlaySrc, "for ")
+	hints := inlayHints(t, inlaySrc, start, end)
+	if len(hints) != 2 {
+		t.Fatal("InlayHints:", len(hints))
+	}
+	if ret := hintsOf(inlaySrc, hints, langserver.InlayHintType); ret != "6:2 int" {
+		t.Fatal("type hints:", ret)
+	}
+	if ret := hintsOf(inlaySrc, hints, langserver.InlayHintParameter); ret != "6:13 b:" {
+		t.Fatal("parameter hints:", ret)
+	}
+}
+
+func TestInlayHintsSuppressed(t *testing.T) {
+	const src = `func area(w, h int) int {
+	return w * h
+}
+
+var n int = 1
+w, h := 2, 3
+println area(w, h)
+println area(h, 1)
+`
+	hints := inlayHints(t, src, 0, 0)
+	// no type hint for n (declared with type), no parameter hints for
+	// arguments named like the parameters
+	if ret := hintsOf(src, hints, langserver.InlayHintType); ret != "6:2 int, 6:5 int" {
+		t.Fatal("type hints:", ret)
+	}
+	if ret := hintsOf(src, hints, langserver.InlayHintParameter); ret != "7:9 a...:, 8:9 a...:, 8:14 w:, 8:17 h:" {
+		t.Fatal("parameter hints:", ret)
+	}
+}
@@ -0,0 +1,190 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package langserver
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/goplus/gop/ast"
+	"github.com/goplus/gop/token"
+)
+
+// -----------------------------------------------------------------------------
+
+// Outline returns a hierarchical outline of the Go+ source file for editors:
+//   - top-level declarations, as Symbols returns.
+//   - for a classfile, the class which contains its fields, methods and event
+//     handlers (methods like onStart).
+//   - regions marked by `// region name` and `// endregion` comments, which
+//     contain declarations in them.
+//
+// The file is parsed but not type-checked. If src != nil, it is used as
+// content of the file instead of the content on disk.
+func Outline(file string, src []byte) (ret []*Symbol, err error) {
+	f, err := parseFile(file, src)
+	if err != nil {
+		return
+	}
+	p := &symbolizer{f: f}
+	if f.AST.IsClass {
+		p.fields = classFields(f.AST)
+	}
+	ret = p.symbols()
+	if f.AST.IsClass {
+		ret = []*Symbol{p.classSym(ret)}
+	}
+	return nest(ret, p.regionSyms()), nil
+}
+
+// classFields returns the declaration of fields of a class, which is the
+// first var declaration before any func declaration of the classfile.
+func classFields(f *ast.File) *ast.GenDecl {
+	for _, decl := range f.Decls {
+		d, ok := decl.(*ast.GenDecl)
+		if !ok {
+			break
+		}
+		if d.Tok == token.VAR {
+			return d
+		}
+	}
+	return nil
+}
+
+func (p *symbolizer) classSym(members []*Symbol) *Symbol {
+	name := filepath.Base(p.f.Path)
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	for _, sym := range members {
+		if sym.Kind == KindFunc {
+			sym.Kind = KindMethod
+			if isEventHandler(sym.Name) {
+				sym.Kind = KindHandler
+			}
+		}
+	}
+	return &Symbol{
+		Name: name, Kind: KindClass, Detail: "classfile",
+		Start: 0, End: len(p.f.Src), Children: members,
+	}
+}
+
+// isEventHandler reports whether name looks like an event handler, eg.
+// onStart. It's the same as the compiler checks.
+func isEventHandler(name string) bool {
+	return len(name) > 2 && strings.HasPrefix(name, "on") && name[2] >= 'A' && name[2] <= 'Z'
+}
+
+// regionSyms returns regions marked by `// region name` and `// endregion`
+// comments (`#region` and `#endregion` are also allowed), with nested regions
+// as their children. Unterminated regions are ignored.
+func (p *symbolizer) regionSyms() (ret []*Symbol) {
+	var stack []*Symbol
+	for _, cg := range p.f.AST.Comments {
+		for _, c := range cg.List {
+			name, begin, ok := regionMark(c.Text)
+			if !ok {
+				continue
+			}
+			if begin {
+				off := p.f.Offset(c.Pos())
+				stack = append(stack, &Symbol{Name: name, Kind: KindRegion, Start: off, NameOffset: off})
+				continue
+			}
+			n := len(stack)
+			if n == 0 {
+				continue
+			}
+			sym := stack[n-1]
+			sym.End = p.f.Offset(c.End())
+			if stack = stack[:n-1]; n > 1 {
+				parent := stack[n-2]
+				parent.Children = append(parent.Children, sym)
+			} else {
+				ret = append(ret, sym)
+			}
+		}
+	}
+	return
+}
+
+// regionMark checks if a comment marks the beginning or the end of a region.
+func regionMark(text string) (name string, begin, ok bool) {
+	if !strings.HasPrefix(text, "//") {
+		return
+	}
+	text = strings.TrimPrefix(strings.TrimSpace(text[2:]), "#")
+	if text == "endregion" || strings.HasPrefix(text, "endregion ") {
+		return "", false, true
+	}
+	if text == "region" || strings.HasPrefix(text, "region ") {
+		if name = strings.TrimSpace(text[6:]); name == "" {
+			name = "region"
+		}
+		return name, true, true
+	}
+	return
+}
+
+// nest moves symbols into the innermost regions containing them. The
+// returned symbols (and their children) are sorted by their positions.
+func nest(syms, regions []*Symbol) []*Symbol {
+	if len(regions) == 0 {
+		return syms
+	}
+	var ret []*Symbol
+	for _, sym := range syms {
+		if sym.Kind == KindClass { // a class covers the whole file
+			sym.Children = nest(sym.Children, regions)
+			return []*Symbol{sym}
+		}
+		if r := innermost(regions, sym); r != nil {
+			r.Children = append(r.Children, sym)
+		} else {
+			ret = append(ret, sym)
+		}
+	}
+	ret = append(ret, regions...)
+	sortSyms(ret)
+	return ret
+}
+
+func innermost(regions []*Symbol, sym *Symbol) *Symbol {
+	for _, r := range regions {
+		if r.Kind == KindRegion && r.Start <= sym.Start && sym.End <= r.End {
+			if inner := innermost(r.Children, sym); inner != nil {
+				return inner
+			}
+			return r
+		}
+	}
+	return nil
+}
+
+func sortSyms(syms []*Symbol) {
+	sort.SliceStable(syms, func(i, j int) bool {
+		return syms[i].Start < syms[j].Start
+	})
+	for _, sym := range syms {
+		if sym.Kind == KindRegion {
+			sortSyms(sym.Children)
+		}
+	}
+}
+
+// -----------------------------------------------------------------------------
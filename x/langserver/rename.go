@@ -0,0 +1,175 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package langserver
+
+import (
+	"fmt"
+	"go/types"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/goplus/gop/ast"
+	"github.com/goplus/gop/token"
+)
+
+// -----------------------------------------------------------------------------
+
+// Rename returns edits of files to rename the symbol to newName. It fails if
+// newName isn't a valid identifier, or if renaming changes the meaning of the
+// code:
+//   - newName is declared in the same scope (or is a field or method of the
+//     same type) already.
+//   - a declaration of newName in an inner scope would capture references of
+//     the symbol.
+//   - the renamed symbol would shadow uses of another newName.
+func (p *Index) Rename(sym, newName string) (map[string][]*TextEdit, error) {
+	if !token.IsIdentifier(newName) || newName == "_" {
+		return nil, fmt.Errorf("invalid identifier: %s", newName)
+	}
+	name := symName(sym)
+	refs := p.References(sym)
+	if !strings.Contains(sym, "#") { // package-level object, method or field
+		for _, ref := range p.References(sym[:len(sym)-len(name)] + newName) {
+			if ref.Def {
+				return nil, fmt.Errorf("renaming %s to %s conflicts with %s declared at %s:%d:%d",
+					name, newName, newName, ref.File, ref.Line, ref.Column)
+			}
+		}
+	}
+	if err := checkRename(refs, name, newName); err != nil {
+		return nil, err
+	}
+	ret := make(map[string][]*TextEdit)
+	for _, ref := range refs {
+		ret[ref.File] = append(ret[ref.File], &TextEdit{Start: ref.Offset, End: ref.Offset + len(name), NewText: newName})
+	}
+	return ret, nil
+}
+
+// checkRename type-checks packages of refs to check whether renaming them
+// changes the meaning of the code.
+func checkRename(refs []*Ref, name, newName string) error {
+	dirs := make(map[string][]*Ref)
+	for _, ref := range refs {
+		dir := filepath.Dir(ref.File)
+		dirs[dir] = append(dirs[dir], ref)
+	}
+	names := make([]string, 0, len(dirs))
+	for dir := range dirs {
+		names = append(names, dir)
+	}
+	sort.Strings(names)
+	for _, dir := range names {
+		f, err := CheckFile(dirs[dir][0].File, nil)
+		if err != nil { // files with syntax errors: there is nothing to check
+			continue
+		}
+		if err = checkRenameIn(f, dirs[dir], name, newName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkRenameIn(f *File, refs []*Ref, name, newName string) error {
+	type refKey struct {
+		file   string
+		offset int
+	}
+	keys := make(map[refKey]bool, len(refs))
+	for _, ref := range refs {
+		keys[refKey{ref.File, ref.Offset}] = true
+	}
+	objs := make(map[types.Object][]token.Pos) // renamed objects => their refs
+	addRefs := func(m map[*ast.Ident]types.Object) {
+		for id, obj := range m {
+			if pos := f.Fset.Position(id.Pos()); obj != nil && keys[refKey{pos.Filename, pos.Offset}] {
+				objs[obj] = append(objs[obj], id.Pos())
+			}
+		}
+	}
+	addRefs(f.Info.Defs)
+	addRefs(f.Info.Uses)
+	conflict := func(what string, obj types.Object) error {
+		return fmt.Errorf("renaming %s to %s %s %s declared at %v", name, newName, what, newName, f.Fset.Position(obj.Pos()))
+	}
+	for obj, poses := range objs {
+		scope := obj.Parent()
+		if obj.Pkg() != f.Pkg || scope == nil { // imported objects are referenced by selectors
+			continue
+		}
+		if o := scope.Lookup(newName); o != nil && o != obj {
+			return conflict("conflicts with", o)
+		}
+		if scope == f.Pkg.Scope() { // imports in file scopes conflict with package-level names
+			for n, s := range f.Info.Scopes {
+				if _, ok := n.(*ast.File); ok {
+					if o := s.Lookup(newName); o != nil {
+						return conflict("conflicts with", o)
+					}
+				}
+			}
+		}
+		for _, pos := range poses {
+			s := scopeOf(f.Pkg, pos)
+			if _, o := s.LookupParent(newName, pos); o != nil && o != obj && o.Parent() != scope && isInScope(o.Parent(), scope) {
+				return conflict("makes references captured by", o)
+			}
+		}
+		for id, o := range f.Info.Uses {
+			if id.Name != newName || o == obj || o.Parent() == nil || !isInScope(scope, o.Parent()) || o.Parent() == scope {
+				continue
+			}
+			if s := scopeOf(f.Pkg, id.Pos()); isInScope(s, scope) && (scope == f.Pkg.Scope() || id.Pos() > obj.Pos()) {
+				return conflict("shadows", o)
+			}
+		}
+	}
+	return nil
+}
+
+// isInScope reports whether s is outer or one of its inner scopes.
+func isInScope(s, outer *types.Scope) bool {
+	for ; s != nil; s = s.Parent() {
+		if s == outer {
+			return true
+		}
+	}
+	return false
+}
+
+// scopeOf returns the innermost scope containing pos in any file of pkg: a
+// local scope, the file scope, or the package scope. Local scopes of Go+
+// functions are children of the package scope.
+func scopeOf(pkg *types.Package, pos token.Pos) (ret *types.Scope) {
+	ret = pkg.Scope()
+	var walk func(s *types.Scope)
+	walk = func(s *types.Scope) {
+		for i, n := 0, s.NumChildren(); i < n; i++ {
+			c := s.Child(i)
+			if c.Pos().IsValid() && c.Contains(pos) && (ret == pkg.Scope() || c.End()-c.Pos() <= ret.End()-ret.Pos()) {
+				ret = c
+			}
+			walk(c)
+		}
+	}
+	walk(pkg.Scope())
+	return
+}
+
+// -----------------------------------------------------------------------------
@@ -26,6 +26,7 @@ import (
 	"github.com/goplus/gop"
 	"github.com/goplus/gop/x/gopprojs"
 	"github.com/goplus/gop/x/jsonrpc2"
+	"github.com/goplus/gop/x/pathutil"
 )
 
 // -----------------------------------------------------------------------------
@@ -68,12 +69,19 @@ type handler struct {
 	mutex sync.Mutex
 	dirty map[string]none
 
+	idxMutex sync.Mutex
+	indexes  map[string]*Index // workspace root => index
+
+	diags *Diagnoser
+
 	server *Server
 }
 
 func newHandle() *handler {
 	return &handler{
-		dirty: make(map[string]none),
+		dirty:   make(map[string]none),
+		indexes: make(map[string]*Index),
+		diags:   NewDiagnoser(),
 	}
 }
 
@@ -88,22 +96,84 @@ func (p *handler) runLoop() {
 		duration = time.Second / 100
 	)
 	for {
-		var dir string
-		p.mutex.Lock()
-		for dir = range p.dirty {
-			delete(p.dirty, dir)
-			break
-		}
-		p.mutex.Unlock()
-		if dir == "" {
+		if !p.genDirty() {
+			p.diags.flush()
 			time.Sleep(duration)
-			continue
 		}
-		gop.GenGoEx(dir, nil, true, gop.GenFlagPrompt)
+	}
+}
+
+// genDirty generates Go code of a changed directory, and reports whether
+// there is one.
+func (p *handler) genDirty() bool {
+	var dir string
+	p.mutex.Lock()
+	for dir = range p.dirty {
+		delete(p.dirty, dir)
+		break
+	}
+	p.mutex.Unlock()
+	if dir == "" {
+		return false
+	}
+	gop.GenGoEx(dir, nil, true, gop.GenFlagPrompt)
+	p.updateIndexes(dir)
+	return true
+}
+
+// indexOf returns the index of the workspace the file belongs to (see
+// WorkspaceOf).
+func (p *handler) indexOf(file string) (idx *Index, err error) {
+	root, err := WorkspaceOf(file)
+	if err != nil {
+		return
+	}
+	p.idxMutex.Lock()
+	defer p.idxMutex.Unlock()
+	if idx = p.indexes[root]; idx == nil {
+		if idx, err = LoadIndex(root); err != nil {
+			return
+		}
+		p.indexes[root] = idx
+	}
+	return
+}
+
+// WorkspaceOf returns the workspace the file belongs to, which is the module
+// root of the file, or the directory of the file if it isn't in a module.
+func WorkspaceOf(file string) (root string, err error) {
+	file, err = pathutil.Abs(file)
+	if err != nil {
+		return
+	}
+	root = filepath.Dir(file)
+	if mod, e := gop.LoadMod(root); e == nil && hasModfile(mod) {
+		root = mod.Root()
+	}
+	return
+}
+
+// updateIndexes re-indexes the changed directory in indexes loaded.
+func (p *handler) updateIndexes(dir string) {
+	p.idxMutex.Lock()
+	defer p.idxMutex.Unlock()
+	for _, idx := range p.indexes {
+		if idx.contains(dir) {
+			idx.updateDirs(map[string]bool{dir: true})
+			idx.Save()
+		}
 	}
 }
 
 func (p *handler) Changed(files []string) {
+	dirs := make([]string, 0, len(files))
+	for _, file := range files {
+		if file, err := pathutil.Abs(file); err == nil {
+			dirs = append(dirs, filepath.Dir(file))
+		}
+	}
+	p.diags.Changed(dirs...)
+
 	p.mutex.Lock()
 	defer p.mutex.Unlock()
 
@@ -129,6 +199,116 @@ func (p *handler) Handle(ctx context.Context, req *jsonrpc2.Request) (result int
 			return
 		}
 		err = GenGo(pattern...)
+	case methodCodeAction:
+		var params CodeActionParams
+		err = json.Unmarshal(req.Params, &params)
+		if err != nil {
+			return
+		}
+		result, err = CodeActions(params.File, nil, params.Start)
+	case methodReferences:
+		var params RefParams
+		err = json.Unmarshal(req.Params, &params)
+		if err != nil {
+			return
+		}
+		var idx *Index
+		if idx, err = p.indexOf(params.File); err == nil {
+			result = idx.References(idx.SymbolAt(params.File, params.Offset))
+		}
+	case methodRename:
+		var params RenameParams
+		err = json.Unmarshal(req.Params, &params)
+		if err != nil {
+			return
+		}
+		var idx *Index
+		if idx, err = p.indexOf(params.File); err == nil {
+			result, err = idx.Rename(idx.SymbolAt(params.File, params.Offset), params.NewName)
+		}
+	case methodHover:
+		var params HoverParams
+		err = json.Unmarshal(req.Params, &params)
+		if err != nil {
+			return
+		}
+		result, err = HoverAt(params.File, nil, params.Offset)
+	case methodInlayHint:
+		var params InlayHintParams
+		err = json.Unmarshal(req.Params, &params)
+		if err != nil {
+			return
+		}
+		result, err = InlayHints(params.File, nil, params.Start, params.End)
+	case methodSignature:
+		var params SignatureHelpParams
+		err = json.Unmarshal(req.Params, &params)
+		if err != nil {
+			return
+		}
+		result, err = SignatureHelpAt(params.File, nil, params.Offset)
+	case methodDiagnostic:
+		var params DiagnosticParams
+		err = json.Unmarshal(req.Params, &params)
+		if err != nil {
+			return
+		}
+		result, err = p.Diagnostics(params.File, params.PreviousResultID)
+	case methodWorkspaceDiag:
+		var params WorkspaceDiagnosticParams
+		err = json.Unmarshal(req.Params, &params)
+		if err != nil {
+			return
+		}
+		result, err = p.WorkspaceDiagnostics(params.File, params.PreviousResultIDs)
+	case methodUnused:
+		var file string
+		err = json.Unmarshal(req.Params, &file)
+		if err != nil {
+			return
+		}
+		var idx *Index
+		if idx, err = p.indexOf(file); err == nil {
+			result = idx.Unused()
+		}
+	case methodDefinition:
+		var params DefinitionParams
+		err = json.Unmarshal(req.Params, &params)
+		if err != nil {
+			return
+		}
+		result, err = DefinitionAt(params.File, nil, params.Offset)
+	case methodSymbols:
+		var file string
+		err = json.Unmarshal(req.Params, &file)
+		if err != nil {
+			return
+		}
+		result, err = Symbols(file, nil)
+	case methodOutline:
+		var file string
+		err = json.Unmarshal(req.Params, &file)
+		if err != nil {
+			return
+		}
+		result, err = Outline(file, nil)
+	case methodFolding:
+		var file string
+		err = json.Unmarshal(req.Params, &file)
+		if err != nil {
+			return
+		}
+		result, err = FoldingRanges(file, nil)
+	case methodCompletion:
+		var params CompletionParams
+		err = json.Unmarshal(req.Params, &params)
+		if err != nil {
+			return
+		}
+		result, err = Completions(params.File, nil, params.Offset)
+	}
+	if err != nil {
+		result = nil // eg. a nil map of edits, which isn't a nil interface
 	}
 	return
 }
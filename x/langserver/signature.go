@@ -0,0 +1,308 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package langserver
+
+import (
+	"bytes"
+	goast "go/ast"
+	"go/build"
+	goparser "go/parser"
+	"go/printer"
+	gotoken "go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/goplus/gop/ast"
+	"github.com/goplus/gop/token"
+	"github.com/goplus/gox"
+)
+
+// -----------------------------------------------------------------------------
+
+// A SignatureInfo is a signature of a function.
+type SignatureInfo struct {
+	Label  string   `json:"label"`  // eg. "repeat(s string, count int) string"
+	Params []string `json:"params"` // labels of parameters, eg. "count int"
+	Doc    string   `json:"doc,omitempty"`
+}
+
+// A SignatureHelp describes the call at a position of a file. Overloaded
+// functions have multiple signatures.
+type SignatureHelp struct {
+	Signatures      []*SignatureInfo `json:"signatures"`
+	ActiveSignature int              `json:"activeSignature"`
+	ActiveParam     int              `json:"activeParameter"`
+}
+
+// SignatureHelpParams represents parameters of the signatureHelp request.
+type SignatureHelpParams = RefParams
+
+// fixups are inserted at the position of signature help if a file can't be
+// parsed, as it's likely to be an incomplete call being typed.
+var fixups = []string{")", "_"}
+
+// SignatureHelpAt returns signatures of the innermost call at the byte offset
+// of the Go+ source file, or nil if the offset isn't in a call. If src != nil,
+// it is used as content of the file instead of the content on disk.
+func SignatureHelpAt(file string, src []byte, offset int) (ret *SignatureHelp, err error) {
+	f, err := CheckFile(file, src)
+	if err != nil {
+		if src == nil {
+			if src, err = os.ReadFile(file); err != nil {
+				return
+			}
+		}
+		if offset < 0 || offset > len(src) {
+			return nil, nil
+		}
+		for _, fixup := range fixups {
+			fixed := make([]byte, 0, len(src)+len(fixup))
+			fixed = append(append(append(fixed, src[:offset]...), fixup...), src[offset:]...)
+			if f, err = CheckFile(file, fixed); err == nil {
+				break
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+	if offset < 0 || offset > len(f.Src) {
+		return
+	}
+	pos := f.Pos(offset)
+	call := callAt(f.AST, pos)
+	if call == nil {
+		return
+	}
+	var id *ast.Ident
+	recvArg := false // the receiver is passed as the first argument
+	switch fn := call.Fun.(type) {
+	case *ast.Ident:
+		id = fn
+	case *ast.SelectorExpr:
+		id = fn.Sel
+		if x, ok := fn.X.(*ast.Ident); !ok || !isPkgName(f.Info.Uses[x]) {
+			recvArg = true // eg. "hello".repeat(2) => strings.Repeat("hello", 2)
+		}
+	default:
+		return
+	}
+	obj := f.Info.Uses[id]
+	if obj == nil {
+		return
+	}
+	ret = &SignatureHelp{}
+	for i, arg := range call.Args {
+		if end := f.Offset(arg.End()); end < offset && bytes.IndexByte(f.Src[end:offset], ',') >= 0 {
+			ret.ActiveParam = i + 1
+		}
+	}
+	switch o := obj.(type) {
+	case *types.Builtin, *gox.TemplateFunc:
+		sig := builtinSignature(obj.Name())
+		if sig == nil {
+			return nil, nil
+		}
+		ret.Signatures = []*SignatureInfo{sig}
+	case *types.Func:
+		qualifier := func(pkg *types.Package) string {
+			if pkg == f.Pkg {
+				return ""
+			}
+			return pkg.Name()
+		}
+		fns, resolved := overloads(o)
+		for i, fn := range fns {
+			if fn == obj {
+				ret.ActiveSignature = i
+			}
+			sig := fn.Type().(*types.Signature)
+			info := signatureOf(id.Name, sig, recvArg && sig.Recv() == nil || strings.HasPrefix(fn.Name(), "Gopt_"), qualifier)
+			info.Doc = docOf(f, fn)
+			ret.Signatures = append(ret.Signatures, info)
+		}
+		if !resolved { // the first overload having enough parameters
+			for i, sig := range ret.Signatures {
+				if len(sig.Params) > ret.ActiveParam || isVariadic(sig) {
+					ret.ActiveSignature = i
+					break
+				}
+			}
+		}
+	default:
+		return nil, nil
+	}
+	if active := ret.Signatures[ret.ActiveSignature]; ret.ActiveParam >= len(active.Params) && isVariadic(active) {
+		ret.ActiveParam = len(active.Params) - 1
+	}
+	return
+}
+
+func isVariadic(sig *SignatureInfo) bool {
+	n := len(sig.Params)
+	return n > 0 && strings.Contains(sig.Params[n-1], "...")
+}
+
+// callAt returns the innermost call whose arguments contain pos.
+func callAt(f *ast.File, pos token.Pos) (ret *ast.CallExpr) {
+	ast.Inspect(f, func(n ast.Node) bool {
+		switch v := n.(type) {
+		case nil:
+			return false
+		case *ast.File: // its range may be wrong, eg. if it's a classfile
+			return true
+		case *ast.FuncDecl:
+			if !v.Name.Pos().IsValid() { // eg. event binding method of a class
+				return false
+			}
+		case *ast.CallExpr:
+			if v.Lparen.IsValid() {
+				if v.Lparen < pos && (pos <= v.Rparen || !v.Rparen.IsValid()) {
+					ret = v
+				}
+			} else if v.Fun.End() < pos && pos <= v.End() { // command style, eg. println x
+				ret = v
+			}
+		}
+		return n.Pos() <= pos && pos <= n.End()
+	})
+	return
+}
+
+func isPkgName(obj types.Object) bool {
+	_, ok := obj.(*types.PkgName)
+	return ok
+}
+
+// overloads returns all overloads of the Go+ overloaded function fn, or fn
+// itself if it isn't overloaded. fn is either an overload (named like Foo__0,
+// Foo__1, etc) resolved by types of arguments, or the overloaded function
+// itself if it isn't resolved (eg. arguments are being typed).
+func overloads(fn *types.Func) (ret []types.Object, resolved bool) {
+	sig := fn.Type().(*types.Signature)
+	if fns, ok := gox.CheckOverloadFunc(sig); ok {
+		return fns, false
+	}
+	if fns, ok := gox.CheckOverloadMethod(sig); ok {
+		return fns, false
+	}
+	name := fn.Name()
+	pos := strings.Index(name, "__")
+	if pos <= 0 || fn.Pkg() == nil {
+		return []types.Object{fn}, true
+	}
+	lookup := fn.Pkg().Scope().Lookup
+	if recv := sig.Recv(); recv != nil {
+		lookup = func(name string) types.Object {
+			o, _, _ := types.LookupFieldOrMethod(recv.Type(), true, fn.Pkg(), name)
+			return o
+		}
+	}
+	for _, c := range overloadIndexes {
+		o := lookup(name[:pos+2] + string(c))
+		if o == nil {
+			break
+		}
+		ret = append(ret, o)
+	}
+	if ret == nil {
+		ret = []types.Object{fn}
+	}
+	return ret, true
+}
+
+const overloadIndexes = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+// signatureOf returns the signature of function name. If recvArg is true,
+// the first parameter is the receiver and is omitted.
+func signatureOf(name string, sig *types.Signature, recvArg bool, qualifier types.Qualifier) *SignatureInfo {
+	params := sig.Params()
+	ret := &SignatureInfo{}
+	for i := 0; i < params.Len(); i++ {
+		if i == 0 && recvArg {
+			continue
+		}
+		param := params.At(i)
+		typ := types.TypeString(param.Type(), qualifier)
+		if i == params.Len()-1 && sig.Variadic() {
+			typ = "..." + strings.TrimPrefix(typ, "[]")
+		}
+		if param.Name() != "" {
+			typ = param.Name() + " " + typ
+		}
+		ret.Params = append(ret.Params, typ)
+	}
+	ret.Label = name + "(" + strings.Join(ret.Params, ", ") + ")"
+	if results := sig.Results(); results.Len() > 0 {
+		s := types.TypeString(results, qualifier)
+		if results.Len() == 1 && results.At(0).Name() == "" {
+			s = s[1 : len(s)-1]
+		}
+		ret.Label += " " + s
+	}
+	return ret
+}
+
+// builtinSignature returns the signature of the Go builtin function name
+// documented in $GOROOT/src/builtin/builtin.go.
+func builtinSignature(name string) *SignatureInfo {
+	fset := gotoken.NewFileSet()
+	file := filepath.Join(build.Default.GOROOT, "src", "builtin", "builtin.go")
+	f, err := goparser.ParseFile(fset, file, nil, goparser.ParseComments)
+	if err != nil {
+		return nil
+	}
+	for _, decl := range f.Decls {
+		fn, ok := decl.(*goast.FuncDecl)
+		if !ok || fn.Name.Name != name {
+			continue
+		}
+		ret := &SignatureInfo{Doc: fn.Doc.Text()}
+		nodeString := func(n goast.Node) string {
+			var b bytes.Buffer
+			printer.Fprint(&b, fset, n)
+			return b.String()
+		}
+		for _, field := range fn.Type.Params.List {
+			typ := nodeString(field.Type)
+			if len(field.Names) == 0 {
+				ret.Params = append(ret.Params, typ)
+			}
+			for _, name := range field.Names {
+				ret.Params = append(ret.Params, name.Name+" "+typ)
+			}
+		}
+		ret.Label = name + "(" + strings.Join(ret.Params, ", ") + ")"
+		if fn.Type.Results != nil {
+			results := make([]string, 0, len(fn.Type.Results.List))
+			for _, field := range fn.Type.Results.List {
+				results = append(results, nodeString(field.Type))
+			}
+			if len(results) == 1 {
+				ret.Label += " " + results[0]
+			} else {
+				ret.Label += " (" + strings.Join(results, ", ") + ")"
+			}
+		}
+		return ret
+	}
+	return nil
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,135 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package langserver_test
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/goplus/gop/x/langserver"
+)
+
+// signatureAt returns signature help at the position of "|" in src, which
+// is the content of main.gop. Other files of the package are in files.
+func signatureAt(t *testing.T, src string, files map[string]string) *langserver.SignatureHelp {
+	t.Helper()
+	t.Setenv("GOCACHE", goCache)
+	offset := strings.Index(src, "|")
+	all := map[string]string{"main.gop": src[:offset] + src[offset+1:]}
+	for name, data := range files {
+		all[name] = data
+	}
+	dir := writeFiles(t, all)
+	ret, err := langserver.SignatureHelpAt(filepath.Join(dir, "main.gop"), nil, offset)
+	if err != nil {
+		t.Fatal("SignatureHelpAt:", err)
+	}
+	return ret
+}
+
+// labelOf returns the label of the active signature and its active parameter.
+func labelOf(ret *langserver.SignatureHelp) string {
+	if ret == nil {
+		return "<nil>"
+	}
+	sig := ret.Signatures[ret.ActiveSignature]
+	if ret.ActiveParam >= len(sig.Params) {
+		return sig.Label + " @" + strconv.Itoa(ret.ActiveParam)
+	}
+	return sig.Label + " @" + sig.Params[ret.ActiveParam]
+}
+
+func TestSignatureNested(t *testing.T) {
+	const defs = `func pair(a string, b int) string {
+	return a
+}
+
+func twice(x int) int {
+	return x * 2
+}
+
+`
+	for _, c := range []struct{ src, want string }{
+		{`println pair("x", twice(|1))`, "twice(x int) int @x int"},
+		{`println pair("x", twice(1|)`, "twice(x int) int @x int"},
+		{`println pair("x", |twice(1))`, "pair(a string, b int) string @b int"},
+		{`println pair(|"x", twice(1))`, "pair(a string, b int) string @a string"},
+	} {
+		if ret := labelOf(signatureAt(t, defs+c.src+"\n", nil)); ret != c.want {
+			t.Errorf("%s: got %s, want %s", c.src, ret, c.want)
+		}
+	}
+}
+
+func TestSignatureVariadic(t *testing.T) {
+	const defs = `func join(sep string, parts ...string) string {
+	return sep
+}
+
+`
+	for _, c := range []struct{ src, want string }{
+		{`println join(|",", "a", "b")`, "join(sep string, parts ...string) string @sep string"},
+		{`println join(",", |"a", "b")`, "join(sep string, parts ...string) string @parts ...string"},
+		{`println join(",", "a", "b", |"c")`, "join(sep string, parts ...string) string @parts ...string"},
+	} {
+		if ret := labelOf(signatureAt(t, defs+c.src+"\n", nil)); ret != c.want {
+			t.Errorf("%s: got %s, want %s", c.src, ret, c.want)
+		}
+	}
+}
+
+func TestSignatureOverload(t *testing.T) {
+	// overloads are declared in Go, and add is resolved to add__1 by types
+	// of arguments
+	files := map[string]string{"add.go": `package main
+
+func add__0(a, b int) int {
+	return a + b
+}
+
+func add__1(a, b string) string {
+	return a + b
+}
+`}
+	ret := signatureAt(t, `println add("x", |"y")`+"\n", files)
+	if ret == nil || len(ret.Signatures) != 2 {
+		t.Fatal("SignatureHelpAt:", ret)
+	}
+	if got := labelOf(ret); got != "add(a string, b string) string @b string" {
+		t.Fatal("SignatureHelpAt:", got)
+	}
+	if got := labelOf(signatureAt(t, `println add(|1, 2)`+"\n", files)); got != "add(a int, b int) int @a int" {
+		t.Fatal("SignatureHelpAt:", got)
+	}
+}
+
+func TestSignatureTrailingComma(t *testing.T) {
+	const defs = `func pair(a string, b int) string {
+	return a
+}
+
+`
+	// the call is incomplete, so it's fixed up to be checked
+	if got := labelOf(signatureAt(t, defs+`println pair("x", |`+"\n", nil)); got != "pair(a string, b int) string @b int" {
+		t.Fatal("SignatureHelpAt:", got)
+	}
+	if got := labelOf(signatureAt(t, defs+`println pair("x",| )`+"\n", nil)); got != "pair(a string, b int) string @b int" {
+		t.Fatal("SignatureHelpAt:", got)
+	}
+}
@@ -0,0 +1,177 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package langserver
+
+import (
+	"github.com/goplus/gop/ast"
+	"github.com/goplus/gop/token"
+)
+
+// -----------------------------------------------------------------------------
+
+// Kinds of symbols and completions.
+const (
+	KindFunc      = "func"
+	KindMethod    = "method"
+	KindType      = "type"
+	KindStruct    = "struct"
+	KindInterface = "interface"
+	KindField     = "field"
+	KindVar       = "var"
+	KindConst     = "const"
+	KindPackage   = "package"
+	KindClass     = "class"   // class of a classfile
+	KindHandler   = "handler" // event handler of a class, eg. onStart
+	KindRegion    = "region"  // region marked by comments, see Outline
+)
+
+// A Symbol is a declaration of a Go+ source file, which editors display in
+// outlines of the file.
+type Symbol struct {
+	Name   string `json:"name"`
+	Kind   string `json:"kind"`
+	Detail string `json:"detail,omitempty"` // eg. the receiver of a method
+
+	// Start and End are the range of the declaration, and NameOffset is the
+	// position of its name (in byte offsets).
+	Start      int `json:"start"`
+	End        int `json:"end"`
+	NameOffset int `json:"nameOffset"`
+
+	Children []*Symbol `json:"children,omitempty"` // eg. fields of a struct
+}
+
+// Symbols returns top-level declarations of the Go+ source file, with fields
+// of structs and methods of interfaces as their children. Declarations which
+// are not in source (eg. main func of a script) are omitted. The file is
+// parsed but not type-checked. If src != nil, it is used as content of the
+// file instead of the content on disk.
+func Symbols(file string, src []byte) (ret []*Symbol, err error) {
+	f, err := parseFile(file, src)
+	if err != nil {
+		return
+	}
+	p := &symbolizer{f: f}
+	return p.symbols(), nil
+}
+
+type symbolizer struct {
+	f      *File
+	fields *ast.GenDecl // fields of the class, if it's a classfile
+}
+
+func (p *symbolizer) symbols() (ret []*Symbol) {
+	f := p.f
+	for _, decl := range f.AST.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Shadow || shadowName(f.AST, d.Name) != nil || !d.Name.Pos().IsValid() {
+				continue
+			}
+			ret = append(ret, p.funcSym(d, d.Recv, d.Name))
+		case *ast.OverloadFuncDecl:
+			ret = append(ret, p.funcSym(d, d.Recv, d.Name))
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				var node ast.Node = spec
+				if !d.Lparen.IsValid() { // a single spec, eg. `type T int`
+					node = d
+				}
+				ret = append(ret, p.specSyms(node, spec, d)...)
+			}
+		}
+	}
+	return
+}
+
+func (p *symbolizer) newSym(node ast.Node, name *ast.Ident, kind string) *Symbol {
+	return &Symbol{
+		Name: name.Name, Kind: kind,
+		Start: p.f.Offset(node.Pos()), End: p.f.Offset(node.End()), NameOffset: p.f.Offset(name.Pos()),
+	}
+}
+
+func (p *symbolizer) funcSym(node ast.Node, recv *ast.FieldList, name *ast.Ident) *Symbol {
+	if recv == nil || len(recv.List) == 0 {
+		return p.newSym(node, name, KindFunc)
+	}
+	ret := p.newSym(node, name, KindMethod)
+	ret.Detail = "(" + recvTypeName(recv.List[0].Type) + ")"
+	return ret
+}
+
+func (p *symbolizer) specSyms(node ast.Node, spec ast.Spec, d *ast.GenDecl) (ret []*Symbol) {
+	switch s := spec.(type) {
+	case *ast.TypeSpec:
+		switch t := s.Type.(type) {
+		case *ast.StructType:
+			sym := p.newSym(node, s.Name, KindStruct)
+			sym.Children = p.fieldSyms(t.Fields, KindField)
+			ret = append(ret, sym)
+		case *ast.InterfaceType:
+			sym := p.newSym(node, s.Name, KindInterface)
+			sym.Children = p.fieldSyms(t.Methods, KindMethod)
+			ret = append(ret, sym)
+		default:
+			ret = append(ret, p.newSym(node, s.Name, KindType))
+		}
+	case *ast.ValueSpec:
+		kind := KindVar
+		if d.Tok == token.CONST {
+			kind = KindConst
+		} else if d == p.fields {
+			kind = KindField
+		}
+		for _, name := range s.Names {
+			if name.Name != "_" {
+				ret = append(ret, p.newSym(node, name, kind))
+			}
+		}
+	}
+	return
+}
+
+// fieldSyms returns symbols of named fields of a struct, or methods of an
+// interface. Embedded fields are omitted.
+func (p *symbolizer) fieldSyms(fields *ast.FieldList, kind string) (ret []*Symbol) {
+	if fields == nil {
+		return
+	}
+	for _, field := range fields.List {
+		for _, name := range field.Names {
+			ret = append(ret, p.newSym(field, name, kind))
+		}
+	}
+	return
+}
+
+// recvTypeName returns the name of the receiver type t, eg. `*T`.
+func recvTypeName(t ast.Expr) string {
+	switch v := t.(type) {
+	case *ast.StarExpr:
+		return "*" + recvTypeName(v.X)
+	case *ast.Ident:
+		return v.Name
+	case *ast.IndexExpr: // generic type, eg. `T[E]`
+		return recvTypeName(v.X)
+	case *ast.IndexListExpr:
+		return recvTypeName(v.X)
+	}
+	return ""
+}
+
+// -----------------------------------------------------------------------------
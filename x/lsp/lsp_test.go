@@ -0,0 +1,574 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package lsp_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goplus/gop/x/jsonrpc2"
+	"github.com/goplus/gop/x/jsonrpc2/stdio"
+	"github.com/goplus/gop/x/lsp"
+)
+
+var goCache string
+
+func init() {
+	if os.Getenv("GOPROOT") == "" {
+		dir, _ := os.Getwd()
+		os.Setenv("GOPROOT", filepath.Clean(filepath.Join(dir, "./../..")))
+	}
+	if out, err := exec.Command("go", "env", "GOCACHE").Output(); err == nil {
+		goCache = strings.TrimSpace(string(out))
+	}
+}
+
+const testSrc = `import "strings"
+
+type T struct {
+	Name string
+}
+
+func (t *T) Hello() string {
+	return "héllo " + strings.ToUpper(t.Name)
+}
+
+var t = &T{Name: "x"}
+println t.Hello()
+x := undefined
+`
+
+type client struct {
+	conn  *jsonrpc2.Connection
+	diags chan *lsp.PublishDiagnosticsParams
+}
+
+func (p *client) Handle(ctx context.Context, req *jsonrpc2.Request) (interface{}, error) {
+	if req.Method == "textDocument/publishDiagnostics" {
+		var params lsp.PublishDiagnosticsParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+		p.diags <- &params
+	}
+	return nil, nil
+}
+
+func (p *client) call(t *testing.T, method string, params, result interface{}) {
+	t.Helper()
+	ctx := context.Background()
+	if err := p.conn.Call(ctx, method, params).Await(ctx, result); err != nil {
+		t.Fatal(method, err)
+	}
+}
+
+func (p *client) notify(t *testing.T, method string, params interface{}) {
+	t.Helper()
+	if err := p.conn.Notify(context.Background(), method, params); err != nil {
+		t.Fatal(method, err)
+	}
+}
+
+func (p *client) waitDiags(t *testing.T) *lsp.PublishDiagnosticsParams {
+	t.Helper()
+	select {
+	case ret := <-p.diags:
+		return ret
+	case <-time.After(time.Minute):
+		t.Fatal("no diagnostics published")
+	}
+	return nil
+}
+
+// newClient starts a server and returns a client connected to it.
+func newClient(t *testing.T) *client {
+	ctx := context.Background()
+	listener := stdio.Listener(true)
+	server := lsp.NewServer(ctx, listener, nil)
+	t.Cleanup(func() { server.Shutdown() }) // it closes the listener
+
+	c := &client{diags: make(chan *lsp.PublishDiagnosticsParams, 8)}
+	conn, err := jsonrpc2.Dial(ctx, listener.Dialer(), jsonrpc2.BinderFunc(
+		func(ctx context.Context, conn *jsonrpc2.Connection) jsonrpc2.ConnectionOptions {
+			return jsonrpc2.ConnectionOptions{Handler: c}
+		}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	c.conn = conn
+	return c
+}
+
+func TestServer(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "main.gop")
+	if err := os.WriteFile(file, []byte("println 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c := newClient(t)
+
+	var init lsp.InitializeResult
+	c.call(t, "initialize", map[string]interface{}{}, &init)
+	if !init.Capabilities.HoverProvider || init.Capabilities.CompletionProvider == nil {
+		t.Fatal("initialize:", init)
+	}
+	c.notify(t, "initialized", map[string]interface{}{})
+
+	// the document is unsaved, so it is diagnosed instead of the file on disk
+	uri := lsp.URIOf(file)
+	doc := lsp.TextDocumentIdentifier{URI: uri}
+	c.notify(t, "textDocument/didOpen", &lsp.DidOpenTextDocumentParams{
+		TextDocument: lsp.TextDocumentItem{URI: uri, LanguageID: "gop", Version: 1, Text: testSrc},
+	})
+	diags := c.waitDiags(t)
+	if len(diags.Diagnostics) != 1 || diags.Version != 1 {
+		t.Fatal("diagnostics:", diags)
+	}
+	if d := diags.Diagnostics[0]; d.Range != (lsp.Range{Start: lsp.Position{12, 5}, End: lsp.Position{12, 14}}) ||
+		!strings.Contains(d.Message, "undefined") {
+		t.Fatal("diagnostic:", d)
+	}
+
+	var loc lsp.Location
+	c.call(t, "textDocument/definition", &lsp.TextDocumentPositionParams{
+		TextDocument: doc, Position: lsp.Position{Line: 11, Character: 11}, // Hello
+	}, &loc)
+	if loc.URI != uri || loc.Range != (lsp.Range{Start: lsp.Position{6, 12}, End: lsp.Position{6, 17}}) {
+		t.Fatal("definition:", loc)
+	}
+
+	var hover lsp.Hover
+	c.call(t, "textDocument/hover", &lsp.TextDocumentPositionParams{
+		TextDocument: doc, Position: lsp.Position{Line: 7, Character: 38}, // Name, after a non-ASCII char
+	}, &hover)
+	if !strings.Contains(hover.Contents.Value, "field Name string") ||
+		*hover.Range != (lsp.Range{Start: lsp.Position{7, 37}, End: lsp.Position{7, 41}}) {
+		t.Fatal("hover:", hover.Contents.Value, hover.Range)
+	}
+
+	var syms []lsp.DocumentSymbol
+	c.call(t, "textDocument/documentSymbol", &lsp.DocumentSymbolParams{TextDocument: doc}, &syms)
+	var names []string
+	for _, sym := range syms {
+		names = append(names, sym.Name)
+		for _, child := range sym.Children {
+			names = append(names, sym.Name+"."+child.Name)
+		}
+	}
+	if ret := strings.Join(names, " "); ret != "T T.Name Hello t" {
+		t.Fatal("documentSymbol:", ret)
+	}
+
+	// complete members of t after typing `t.`
+	c.notify(t, "textDocument/didChange", &lsp.DidChangeTextDocumentParams{
+		TextDocument: lsp.VersionedTextDocumentIdentifier{URI: uri, Version: 2},
+		ContentChanges: []lsp.TextDocumentContentChangeEvent{{
+			Range: &lsp.Range{Start: lsp.Position{12, 0}, End: lsp.Position{12, 14}}, Text: "t.",
+		}},
+	})
+	var list lsp.CompletionList
+	c.call(t, "textDocument/completion", &lsp.TextDocumentPositionParams{
+		TextDocument: doc, Position: lsp.Position{Line: 12, Character: 2},
+	}, &list)
+	var labels []string
+	for _, item := range list.Items {
+		labels = append(labels, item.Label)
+	}
+	if ret := strings.Join(labels, " "); ret != "Hello Name" {
+		t.Fatal("completion:", ret)
+	}
+
+	// complete members of a package, filtered by the partial name
+	c.notify(t, "textDocument/didChange", &lsp.DidChangeTextDocumentParams{
+		TextDocument: lsp.VersionedTextDocumentIdentifier{URI: uri, Version: 3},
+		ContentChanges: []lsp.TextDocumentContentChangeEvent{{
+			Range: &lsp.Range{Start: lsp.Position{12, 0}, End: lsp.Position{12, 2}}, Text: "strings.toU",
+		}},
+	})
+	c.call(t, "textDocument/completion", &lsp.TextDocumentPositionParams{
+		TextDocument: doc, Position: lsp.Position{Line: 12, Character: 11},
+	}, &list)
+	labels = labels[:0]
+	for _, item := range list.Items {
+		labels = append(labels, item.Label)
+	}
+	if ret := strings.Join(labels, " "); ret != "ToUpper ToUpperSpecial" {
+		t.Fatal("completion:", ret)
+	}
+
+	c.notify(t, "textDocument/didClose", &lsp.DidCloseTextDocumentParams{TextDocument: doc})
+	for diags = c.waitDiags(t); diags.Version != 0; diags = c.waitDiags(t) { // skip ones of earlier changes
+	}
+	if len(diags.Diagnostics) != 0 {
+		t.Fatal("diagnostics after close:", diags)
+	}
+}
+
+const testClassSrc = `var (
+	name string
+)
+
+// region Events
+func onStart() {
+	println name
+}
+
+func onMsg(msg string) {
+}
+// endregion
+
+func Hello(s string) string {
+	return s + name
+}
+`
+
+func symbolNames(syms []lsp.DocumentSymbol) string {
+	var names []string
+	for _, sym := range syms {
+		name := sym.Name + ":" + strconv.Itoa(sym.Kind)
+		if sym.Children != nil {
+			name += "{" + symbolNames(sym.Children) + "}"
+		}
+		names = append(names, name)
+	}
+	return strings.Join(names, " ")
+}
+
+func TestOutline(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "Kai.gox")
+	if err := os.WriteFile(file, []byte(testClassSrc), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c := newClient(t)
+
+	var init lsp.InitializeResult
+	c.call(t, "initialize", map[string]interface{}{}, &init)
+	if !init.Capabilities.FoldingRangeProvider {
+		t.Fatal("initialize:", init)
+	}
+
+	doc := lsp.TextDocumentIdentifier{URI: lsp.URIOf(file)}
+	var syms []lsp.DocumentSymbol
+	c.call(t, "textDocument/documentSymbol", &lsp.DocumentSymbolParams{TextDocument: doc}, &syms)
+	if ret := symbolNames(syms); ret != "Kai:5{name:8 Events:3{onStart:24 onMsg:24} Hello:6}" {
+		t.Fatal("documentSymbol:", ret)
+	}
+
+	var ranges []lsp.FoldingRange
+	c.call(t, "textDocument/foldingRange", &lsp.FoldingRangeParams{TextDocument: doc}, &ranges)
+	expected := []lsp.FoldingRange{{0, 1, ""}, {4, 11, "region"}, {5, 6, ""}, {13, 14, ""}}
+	if !reflect.DeepEqual(ranges, expected) {
+		t.Fatal("foldingRange:", ranges)
+	}
+}
+
+func TestCodeAction(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "main.gop")
+	if err := os.WriteFile(file, []byte("println 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c := newClient(t)
+
+	var init lsp.InitializeResult
+	c.call(t, "initialize", map[string]interface{}{}, &init)
+	if !init.Capabilities.CodeActionProvider {
+		t.Fatal("initialize:", init)
+	}
+
+	uri := lsp.URIOf(file)
+	c.notify(t, "textDocument/didOpen", &lsp.DidOpenTextDocumentParams{
+		TextDocument: lsp.TextDocumentItem{URI: uri, LanguageID: "gop", Version: 1, Text: `func f(s string) int {
+	println s
+}
+`},
+	})
+	diags := c.waitDiags(t)
+	if len(diags.Diagnostics) != 1 || diags.Diagnostics[0].Severity != lsp.SeverityWarning ||
+		diags.Diagnostics[0].Message != "missing return" {
+		t.Fatal("diagnostics:", diags)
+	}
+
+	var actions []lsp.CodeAction
+	c.call(t, "textDocument/codeAction", &lsp.CodeActionParams{
+		TextDocument: lsp.TextDocumentIdentifier{URI: uri},
+		Range:        lsp.Range{Start: lsp.Position{2, 0}, End: lsp.Position{2, 0}},
+		Context:      lsp.CodeActionContext{Only: []string{lsp.CodeActionQuickFix}},
+	}, &actions)
+	expected := []lsp.CodeAction{{
+		Title: "add return statement", Kind: lsp.CodeActionQuickFix,
+		Edit: &lsp.WorkspaceEdit{Changes: map[string][]lsp.TextEdit{
+			uri: {{Range: lsp.Range{Start: lsp.Position{2, 0}, End: lsp.Position{2, 0}}, NewText: "\treturn 0\n"}},
+		}},
+	}}
+	if !reflect.DeepEqual(actions, expected) {
+		t.Fatal("codeAction:", actions)
+	}
+}
+
+func TestPosition(t *testing.T) {
+	text := []byte("héllo\n😀x\n")
+	cases := []struct {
+		offset int
+		pos    lsp.Position
+	}{
+		{0, lsp.Position{0, 0}},
+		{3, lsp.Position{0, 2}},
+		{7, lsp.Position{1, 0}},
+		{11, lsp.Position{1, 2}},
+		{12, lsp.Position{1, 3}},
+		{13, lsp.Position{2, 0}},
+	}
+	for _, c := range cases {
+		if pos := lsp.PositionOf(text, c.offset); pos != c.pos {
+			t.Fatal("PositionOf:", c.offset, pos)
+		}
+		if offset := lsp.OffsetOf(text, c.pos); offset != c.offset {
+			t.Fatal("OffsetOf:", c.pos, offset)
+		}
+	}
+	if offset := lsp.OffsetOf(text, lsp.Position{0, 100}); offset != 6 {
+		t.Fatal("OffsetOf beyond the line:", offset)
+	}
+}
+
+func TestURI(t *testing.T) {
+	uri := lsp.URIOf("/a b/c.gop")
+	if uri != "file:///a%20b/c.gop" {
+		t.Fatal("URIOf:", uri)
+	}
+	if path, err := lsp.PathOf(uri); err != nil || path != filepath.FromSlash("/a b/c.gop") {
+		t.Fatal("PathOf:", path, err)
+	}
+	if _, err := lsp.PathOf("untitled:Untitled-1"); err == nil {
+		t.Fatal("PathOf: no error")
+	}
+}
+
+// writeFiles writes files into a new temporary directory, and returns it. The
+// index of references is cached in another temporary directory.
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	t.Setenv("GOCACHE", goCache) // keep the build cache of the go command
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	dir := t.TempDir()
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func initialize(t *testing.T, c *client, params interface{}) lsp.ServerCapabilities {
+	t.Helper()
+	var init lsp.InitializeResult
+	c.call(t, "initialize", params, &init)
+	c.notify(t, "initialized", map[string]interface{}{})
+	return init.Capabilities
+}
+
+var refsFiles = map[string]string{
+	"hello.gop": `func hello(name string) string {
+	return "hello " + name
+}
+
+func greet() {
+}
+`,
+	"main.gop": `println hello("x")
+println hello("y")
+`,
+}
+
+func TestReferences(t *testing.T) {
+	dir := writeFiles(t, refsFiles)
+	c := newClient(t)
+	if caps := initialize(t, c, map[string]interface{}{}); !caps.ReferencesProvider || !caps.RenameProvider {
+		t.Fatal("initialize:", caps)
+	}
+
+	hello, main := lsp.URIOf(filepath.Join(dir, "hello.gop")), lsp.URIOf(filepath.Join(dir, "main.gop"))
+	pos := lsp.TextDocumentPositionParams{
+		TextDocument: lsp.TextDocumentIdentifier{URI: main}, Position: lsp.Position{Line: 1, Character: 10}, // hello
+	}
+	var locs []lsp.Location
+	c.call(t, "textDocument/references", &lsp.ReferenceParams{
+		TextDocumentPositionParams: pos, Context: lsp.ReferenceContext{IncludeDeclaration: true},
+	}, &locs)
+	expected := []lsp.Location{
+		{URI: hello, Range: lsp.Range{Start: lsp.Position{0, 5}, End: lsp.Position{0, 10}}},
+		{URI: main, Range: lsp.Range{Start: lsp.Position{0, 8}, End: lsp.Position{0, 13}}},
+		{URI: main, Range: lsp.Range{Start: lsp.Position{1, 8}, End: lsp.Position{1, 13}}},
+	}
+	if !reflect.DeepEqual(locs, expected) {
+		t.Fatal("references:", locs)
+	}
+	c.call(t, "textDocument/references", &lsp.ReferenceParams{TextDocumentPositionParams: pos}, &locs)
+	if !reflect.DeepEqual(locs, expected[1:]) {
+		t.Fatal("references without the declaration:", locs)
+	}
+}
+
+func TestRename(t *testing.T) {
+	dir := writeFiles(t, refsFiles)
+	c := newClient(t)
+	initialize(t, c, map[string]interface{}{})
+
+	hello, main := lsp.URIOf(filepath.Join(dir, "hello.gop")), lsp.URIOf(filepath.Join(dir, "main.gop"))
+	pos := lsp.TextDocumentPositionParams{
+		TextDocument: lsp.TextDocumentIdentifier{URI: hello}, Position: lsp.Position{Line: 0, Character: 6}, // hello
+	}
+	var edit lsp.WorkspaceEdit
+	c.call(t, "textDocument/rename", &lsp.RenameParams{TextDocumentPositionParams: pos, NewName: "sayHello"}, &edit)
+	expected := lsp.WorkspaceEdit{Changes: map[string][]lsp.TextEdit{
+		hello: {{Range: lsp.Range{Start: lsp.Position{0, 5}, End: lsp.Position{0, 10}}, NewText: "sayHello"}},
+		main: {
+			{Range: lsp.Range{Start: lsp.Position{0, 8}, End: lsp.Position{0, 13}}, NewText: "sayHello"},
+			{Range: lsp.Range{Start: lsp.Position{1, 8}, End: lsp.Position{1, 13}}, NewText: "sayHello"},
+		},
+	}}
+	if !reflect.DeepEqual(edit, expected) {
+		t.Fatal("rename:", edit)
+	}
+
+	// a conflict is reported as an error
+	ctx := context.Background()
+	err := c.conn.Call(ctx, "textDocument/rename", &lsp.RenameParams{
+		TextDocumentPositionParams: pos, NewName: "greet",
+	}).Await(ctx, &edit)
+	if err == nil || !strings.Contains(err.Error(), "conflicts with") {
+		t.Fatal("rename to greet:", err)
+	}
+}
+
+const testCallSrc = `func add(a, b int) int {
+	return a + b
+}
+
+a := 1
+x := add(a, 2)
+println x
+`
+
+func TestSignatureHelp(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"main.gop": testCallSrc})
+	c := newClient(t)
+	caps := initialize(t, c, map[string]interface{}{})
+	if caps.SignatureHelpProvider == nil || !reflect.DeepEqual(caps.SignatureHelpProvider.TriggerCharacters, []string{"(", ","}) {
+		t.Fatal("initialize:", caps.SignatureHelpProvider)
+	}
+
+	var help lsp.SignatureHelp
+	c.call(t, "textDocument/signatureHelp", &lsp.TextDocumentPositionParams{
+		TextDocument: lsp.TextDocumentIdentifier{URI: lsp.URIOf(filepath.Join(dir, "main.gop"))},
+		Position:     lsp.Position{Line: 5, Character: 12}, // after `add(a,`
+	}, &help)
+	expected := lsp.SignatureHelp{
+		Signatures: []lsp.SignatureInformation{{
+			Label: "add(a int, b int) int", Parameters: []lsp.ParameterInformation{{Label: "a int"}, {Label: "b int"}},
+		}},
+		ActiveParameter: 1,
+	}
+	if !reflect.DeepEqual(help, expected) {
+		t.Fatal("signatureHelp:", help)
+	}
+}
+
+func TestInlayHint(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"main.gop": testCallSrc})
+	c := newClient(t)
+	if caps := initialize(t, c, map[string]interface{}{}); !caps.InlayHintProvider {
+		t.Fatal("initialize:", caps)
+	}
+
+	var hints []lsp.InlayHint
+	c.call(t, "textDocument/inlayHint", &lsp.InlayHintParams{
+		TextDocument: lsp.TextDocumentIdentifier{URI: lsp.URIOf(filepath.Join(dir, "main.gop"))},
+		Range:        lsp.Range{Start: lsp.Position{Line: 4}, End: lsp.Position{Line: 6}},
+	}, &hints)
+	expected := []lsp.InlayHint{
+		{Position: lsp.Position{4, 1}, Label: " int", Kind: lsp.InlayHintType},
+		{Position: lsp.Position{5, 1}, Label: " int", Kind: lsp.InlayHintType},
+		{Position: lsp.Position{5, 12}, Label: "b:", Kind: lsp.InlayHintParameter, PaddingRight: true},
+	}
+	if !reflect.DeepEqual(hints, expected) {
+		t.Fatal("inlayHint:", hints)
+	}
+}
+
+func TestPullDiagnostics(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"hello.gop": "func hello() string {\n\treturn \"hello\"\n}\n",
+		"main.gop":  "println hello(1)\n",
+	})
+	c := newClient(t)
+	caps := initialize(t, c, map[string]interface{}{
+		"rootUri":      lsp.URIOf(dir),
+		"capabilities": map[string]interface{}{"textDocument": map[string]interface{}{"diagnostic": map[string]interface{}{}}},
+	})
+	if p := caps.DiagnosticProvider; p == nil || !p.InterFileDependencies || !p.WorkspaceDiagnostics {
+		t.Fatal("initialize:", p)
+	}
+
+	hello, main := lsp.URIOf(filepath.Join(dir, "hello.gop")), lsp.URIOf(filepath.Join(dir, "main.gop"))
+	var report lsp.DocumentDiagnosticReport
+	c.call(t, "textDocument/diagnostic", &lsp.DocumentDiagnosticParams{
+		TextDocument: lsp.TextDocumentIdentifier{URI: main},
+	}, &report)
+	if report.Kind != lsp.ReportFull || report.ResultID == "" || len(report.Items) != 1 ||
+		report.Items[0].Range.Start != (lsp.Position{0, 8}) {
+		t.Fatal("diagnostic:", report)
+	}
+	resultID := report.ResultID
+	c.call(t, "textDocument/diagnostic", &lsp.DocumentDiagnosticParams{
+		TextDocument: lsp.TextDocumentIdentifier{URI: main}, PreviousResultID: resultID,
+	}, &report)
+	if report.Kind != lsp.ReportUnchanged || report.ResultID != resultID || report.Items != nil {
+		t.Fatal("diagnostic unchanged:", report)
+	}
+
+	var ws lsp.WorkspaceDiagnosticReport
+	c.call(t, "workspace/diagnostic", &lsp.WorkspaceDiagnosticParams{
+		PreviousResultIDs: []lsp.PreviousResultID{{URI: main, Value: resultID}},
+	}, &ws)
+	if len(ws.Items) != 1 || ws.Items[0].URI != main || ws.Items[0].Kind != lsp.ReportUnchanged {
+		t.Fatal("workspace/diagnostic:", ws)
+	}
+
+	// saving hello.gop invalidates diagnostics of main.gop, which are fixed
+	err := os.WriteFile(filepath.Join(dir, "hello.gop"), []byte("func hello(n int) string {\n\treturn \"hello\"\n}\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.notify(t, "textDocument/didSave", &lsp.DidSaveTextDocumentParams{TextDocument: lsp.TextDocumentIdentifier{URI: hello}})
+	c.call(t, "workspace/diagnostic", &lsp.WorkspaceDiagnosticParams{
+		PreviousResultIDs: []lsp.PreviousResultID{{URI: main, Value: resultID}},
+	}, &ws)
+	if len(ws.Items) != 1 || ws.Items[0].URI != main || ws.Items[0].Kind != lsp.ReportFull ||
+		ws.Items[0].ResultID == resultID || len(ws.Items[0].Items) != 0 || ws.Items[0].Version != nil {
+		t.Fatal("workspace/diagnostic after save:", ws)
+	}
+}
@@ -0,0 +1,448 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package lsp
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// -----------------------------------------------------------------------------
+
+// A Position is a position of a text document, where Character is in UTF-16
+// code units. Both are zero-based.
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// A Range is a range of a text document.
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// A Location is a range of a text document.
+type Location struct {
+	URI   string `json:"uri"`
+	Range Range  `json:"range"`
+}
+
+type TextDocumentIdentifier struct {
+	URI string `json:"uri"`
+}
+
+type VersionedTextDocumentIdentifier struct {
+	URI     string `json:"uri"`
+	Version int    `json:"version"`
+}
+
+type TextDocumentItem struct {
+	URI        string `json:"uri"`
+	LanguageID string `json:"languageId"`
+	Version    int    `json:"version"`
+	Text       string `json:"text"`
+}
+
+// A TextDocumentContentChangeEvent replaces Range of a text document with
+// Text, or the whole document if Range is nil.
+type TextDocumentContentChangeEvent struct {
+	Range *Range `json:"range,omitempty"`
+	Text  string `json:"text"`
+}
+
+type DidOpenTextDocumentParams struct {
+	TextDocument TextDocumentItem `json:"textDocument"`
+}
+
+type DidChangeTextDocumentParams struct {
+	TextDocument   VersionedTextDocumentIdentifier  `json:"textDocument"`
+	ContentChanges []TextDocumentContentChangeEvent `json:"contentChanges"`
+}
+
+type DidSaveTextDocumentParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+type DidCloseTextDocumentParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+// TextDocumentPositionParams represents parameters of requests at a position
+// of a text document, eg. textDocument/hover.
+type TextDocumentPositionParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Position     Position               `json:"position"`
+}
+
+type ReferenceContext struct {
+	IncludeDeclaration bool `json:"includeDeclaration"`
+}
+
+type ReferenceParams struct {
+	TextDocumentPositionParams
+	Context ReferenceContext `json:"context"`
+}
+
+type RenameParams struct {
+	TextDocumentPositionParams
+	NewName string `json:"newName"`
+}
+
+type ParameterInformation struct {
+	Label string `json:"label"`
+}
+
+type SignatureInformation struct {
+	Label         string                 `json:"label"`
+	Documentation string                 `json:"documentation,omitempty"`
+	Parameters    []ParameterInformation `json:"parameters"`
+}
+
+type SignatureHelp struct {
+	Signatures      []SignatureInformation `json:"signatures"`
+	ActiveSignature int                    `json:"activeSignature"`
+	ActiveParameter int                    `json:"activeParameter"`
+}
+
+type InlayHintParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Range        Range                  `json:"range"`
+}
+
+// Kinds of inlay hints.
+const (
+	InlayHintType      = 1
+	InlayHintParameter = 2
+)
+
+type InlayHint struct {
+	Position     Position `json:"position"`
+	Label        string   `json:"label"`
+	Kind         int      `json:"kind,omitempty"` // none for implicit conversions
+	PaddingLeft  bool     `json:"paddingLeft,omitempty"`
+	PaddingRight bool     `json:"paddingRight,omitempty"`
+}
+
+type DocumentSymbolParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+type FoldingRangeParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+// Kinds of folding ranges.
+const (
+	FoldingComment = "comment"
+	FoldingImports = "imports"
+	FoldingRegion  = "region"
+)
+
+type FoldingRange struct {
+	StartLine int    `json:"startLine"`
+	EndLine   int    `json:"endLine"`
+	Kind      string `json:"kind,omitempty"`
+}
+
+type CodeActionContext struct {
+	Diagnostics []Diagnostic `json:"diagnostics"`
+	Only        []string     `json:"only,omitempty"` // kinds of code actions requested
+}
+
+type CodeActionParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Range        Range                  `json:"range"`
+	Context      CodeActionContext      `json:"context"`
+}
+
+type TextEdit struct {
+	Range   Range  `json:"range"`
+	NewText string `json:"newText"`
+}
+
+type WorkspaceEdit struct {
+	Changes map[string][]TextEdit `json:"changes"` // uri => edits
+}
+
+// Kinds of code actions.
+const (
+	CodeActionQuickFix = "quickfix"
+	CodeActionRewrite  = "refactor.rewrite"
+)
+
+type CodeAction struct {
+	Title string         `json:"title"`
+	Kind  string         `json:"kind,omitempty"`
+	Edit  *WorkspaceEdit `json:"edit,omitempty"`
+}
+
+// Severities of diagnostics.
+const (
+	SeverityError   = 1
+	SeverityWarning = 2
+)
+
+type Diagnostic struct {
+	Range    Range  `json:"range"`
+	Severity int    `json:"severity"`
+	Source   string `json:"source,omitempty"`
+	Message  string `json:"message"`
+}
+
+type DocumentDiagnosticParams struct {
+	TextDocument     TextDocumentIdentifier `json:"textDocument"`
+	PreviousResultID string                 `json:"previousResultId,omitempty"`
+}
+
+// Kinds of diagnostic reports.
+const (
+	ReportFull      = "full"
+	ReportUnchanged = "unchanged"
+)
+
+// A DocumentDiagnosticReport is diagnostics of a document, which is a report
+// of Kind ReportUnchanged without Items if they are the same as the ones
+// identified by the previous result id.
+type DocumentDiagnosticReport struct {
+	Kind     string       `json:"kind"`
+	ResultID string       `json:"resultId,omitempty"`
+	Items    []Diagnostic `json:"items"`
+}
+
+type PreviousResultID struct {
+	URI   string `json:"uri"`
+	Value string `json:"value"`
+}
+
+type WorkspaceDiagnosticParams struct {
+	PreviousResultIDs []PreviousResultID `json:"previousResultIds"`
+}
+
+type WorkspaceDocumentDiagnosticReport struct {
+	URI     string `json:"uri"`
+	Version *int   `json:"version"` // of the open document, or null
+	DocumentDiagnosticReport
+}
+
+type WorkspaceDiagnosticReport struct {
+	Items []WorkspaceDocumentDiagnosticReport `json:"items"`
+}
+
+type PublishDiagnosticsParams struct {
+	URI         string       `json:"uri"`
+	Version     int          `json:"version,omitempty"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+type MarkupContent struct {
+	Kind  string `json:"kind"` // plaintext or markdown
+	Value string `json:"value"`
+}
+
+type Hover struct {
+	Contents MarkupContent `json:"contents"`
+	Range    *Range        `json:"range,omitempty"`
+}
+
+// Kinds of symbols.
+const (
+	SymbolNamespace = 3
+	SymbolClass     = 5
+	SymbolMethod    = 6
+	SymbolField     = 8
+	SymbolInterface = 11
+	SymbolFunction  = 12
+	SymbolVariable  = 13
+	SymbolConstant  = 14
+	SymbolStruct    = 23
+	SymbolEvent     = 24
+)
+
+type DocumentSymbol struct {
+	Name           string           `json:"name"`
+	Detail         string           `json:"detail,omitempty"`
+	Kind           int              `json:"kind"`
+	Range          Range            `json:"range"`
+	SelectionRange Range            `json:"selectionRange"`
+	Children       []DocumentSymbol `json:"children,omitempty"`
+}
+
+// Kinds of completion items.
+const (
+	CompletionMethod    = 2
+	CompletionFunction  = 3
+	CompletionField     = 5
+	CompletionVariable  = 6
+	CompletionClass     = 7
+	CompletionInterface = 8
+	CompletionModule    = 9
+	CompletionConstant  = 21
+	CompletionStruct    = 22
+)
+
+type CompletionItem struct {
+	Label  string `json:"label"`
+	Kind   int    `json:"kind"`
+	Detail string `json:"detail,omitempty"`
+}
+
+type CompletionList struct {
+	IsIncomplete bool             `json:"isIncomplete"`
+	Items        []CompletionItem `json:"items"`
+}
+
+// Kinds of text document synchronization.
+const (
+	SyncFull        = 1
+	SyncIncremental = 2
+)
+
+type CompletionOptions struct {
+	TriggerCharacters []string `json:"triggerCharacters,omitempty"`
+}
+
+type SignatureHelpOptions struct {
+	TriggerCharacters []string `json:"triggerCharacters,omitempty"`
+}
+
+type DiagnosticOptions struct {
+	InterFileDependencies bool `json:"interFileDependencies"`
+	WorkspaceDiagnostics  bool `json:"workspaceDiagnostics"`
+}
+
+type ServerCapabilities struct {
+	TextDocumentSync       int                   `json:"textDocumentSync"`
+	HoverProvider          bool                  `json:"hoverProvider"`
+	DefinitionProvider     bool                  `json:"definitionProvider"`
+	ReferencesProvider     bool                  `json:"referencesProvider"`
+	RenameProvider         bool                  `json:"renameProvider"`
+	DocumentSymbolProvider bool                  `json:"documentSymbolProvider"`
+	CompletionProvider     *CompletionOptions    `json:"completionProvider,omitempty"`
+	SignatureHelpProvider  *SignatureHelpOptions `json:"signatureHelpProvider,omitempty"`
+	InlayHintProvider      bool                  `json:"inlayHintProvider"`
+	FoldingRangeProvider   bool                  `json:"foldingRangeProvider"`
+	CodeActionProvider     bool                  `json:"codeActionProvider"`
+	DiagnosticProvider     *DiagnosticOptions    `json:"diagnosticProvider,omitempty"`
+}
+
+type ServerInfo struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+type WorkspaceFolder struct {
+	URI  string `json:"uri"`
+	Name string `json:"name"`
+}
+
+// ClientCapabilities are capabilities of the client, of which only ones
+// changing behaviors of the server are declared.
+type ClientCapabilities struct {
+	TextDocument struct {
+		Diagnostic *struct{} `json:"diagnostic,omitempty"` // pull diagnostics are supported
+	} `json:"textDocument"`
+}
+
+type InitializeParams struct {
+	RootURI          string             `json:"rootUri,omitempty"`
+	WorkspaceFolders []WorkspaceFolder  `json:"workspaceFolders,omitempty"`
+	Capabilities     ClientCapabilities `json:"capabilities"`
+}
+
+type InitializeResult struct {
+	Capabilities ServerCapabilities `json:"capabilities"`
+	ServerInfo   *ServerInfo        `json:"serverInfo,omitempty"`
+}
+
+// -----------------------------------------------------------------------------
+
+// OffsetOf returns the byte offset of pos in text. Positions beyond the end of
+// a line (or of text) are clamped.
+func OffsetOf(text []byte, pos Position) int {
+	offset := 0
+	for line := 0; line < pos.Line; line++ {
+		i := bytes.IndexByte(text[offset:], '\n')
+		if i < 0 {
+			return len(text)
+		}
+		offset += i + 1
+	}
+	for n := 0; n < pos.Character && offset < len(text) && text[offset] != '\n'; {
+		r, size := utf8.DecodeRune(text[offset:])
+		if r >= 0x10000 {
+			n += 2 // a surrogate pair
+		} else {
+			n++
+		}
+		offset += size
+	}
+	return offset
+}
+
+// PositionOf returns the position of the byte offset in text.
+func PositionOf(text []byte, offset int) (ret Position) {
+	if offset > len(text) {
+		offset = len(text)
+	}
+	start := 0
+	for i := 0; i < offset; i++ {
+		if text[i] == '\n' {
+			ret.Line++
+			start = i + 1
+		}
+	}
+	for _, r := range string(text[start:offset]) {
+		if r >= 0x10000 {
+			ret.Character += 2
+		} else {
+			ret.Character++
+		}
+	}
+	return
+}
+
+// PathOf returns the file path of a file:// URI.
+func PathOf(uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "file" {
+		return "", fmt.Errorf("unsupported URI %q: not a file", uri)
+	}
+	path := u.Path
+	if len(path) >= 3 && path[0] == '/' && path[2] == ':' { // eg. /C:/foo on Windows
+		path = path[1:]
+	}
+	return filepath.FromSlash(path), nil
+}
+
+// URIOf returns the file:// URI of an absolute file path.
+func URIOf(path string) string {
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
+
+// -----------------------------------------------------------------------------
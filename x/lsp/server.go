@@ -0,0 +1,939 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package lsp implements a server of the Language Server Protocol for Go+,
+// which editors (eg. VS Code and Neovim) talk to over stdio. It serves
+// diagnostics (both pushed and pulled), go-to-definition, references, rename,
+// hover, signature help, inlay hints, document symbols, completion, folding
+// ranges and code actions (eg. quick fixes suggested by the compiler) of .gop
+// files by translating the standard protocol to the langserver package, which
+// works on byte offsets of files.
+package lsp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/goplus/gop/env"
+	"github.com/goplus/gop/x/jsonrpc2"
+	"github.com/goplus/gop/x/langserver"
+)
+
+// -----------------------------------------------------------------------------
+
+// Listener is implemented by protocols to accept new inbound connections.
+type Listener = jsonrpc2.Listener
+
+// Server is a running server that is accepting incoming connections.
+type Server = jsonrpc2.Server
+
+// Config holds the options for new connections.
+type Config struct {
+	// Framer allows control over the message framing and encoding.
+	// If nil, HeaderFramer will be used, as the protocol requires.
+	Framer jsonrpc2.Framer
+}
+
+// DiagnosticsDelay is how long to wait after a document is changed before its
+// diagnostics are recomputed and published, so that rapid changes while
+// typing are debounced.
+var DiagnosticsDelay = langserver.DiagnosticsDelay
+
+// NewServer creates a new LSP server and returns it. Each connection is an
+// editor session, which has its own open documents.
+func NewServer(ctx context.Context, listener Listener, conf *Config) *Server {
+	return jsonrpc2.NewServer(ctx, listener, jsonrpc2.BinderFunc(
+		func(ctx context.Context, c *jsonrpc2.Connection) (ret jsonrpc2.ConnectionOptions) {
+			if conf != nil {
+				ret.Framer = conf.Framer
+			}
+			ret.Handler = newHandler(c)
+			return
+		}))
+}
+
+// -----------------------------------------------------------------------------
+
+const (
+	methodInitialize     = "initialize"
+	methodInitialized    = "initialized"
+	methodShutdown       = "shutdown"
+	methodExit           = "exit"
+	methodDidOpen        = "textDocument/didOpen"
+	methodDidChange      = "textDocument/didChange"
+	methodDidSave        = "textDocument/didSave"
+	methodDidClose       = "textDocument/didClose"
+	methodHover          = "textDocument/hover"
+	methodDefinition     = "textDocument/definition"
+	methodReferences     = "textDocument/references"
+	methodRename         = "textDocument/rename"
+	methodSignatureHelp  = "textDocument/signatureHelp"
+	methodInlayHint      = "textDocument/inlayHint"
+	methodDocumentSymbol = "textDocument/documentSymbol"
+	methodCompletion     = "textDocument/completion"
+	methodFoldingRange   = "textDocument/foldingRange"
+	methodCodeAction     = "textDocument/codeAction"
+	methodDiagnostic     = "textDocument/diagnostic"
+	methodWorkspaceDiag  = "workspace/diagnostic"
+	methodPublishDiags   = "textDocument/publishDiagnostics"
+)
+
+// A document is a text document opened by the editor, whose content may be
+// different from the file on disk.
+type document struct {
+	path    string
+	version int
+	text    []byte
+	dirty   bool        // text isn't saved
+	timer   *time.Timer // of publishing diagnostics
+}
+
+type handler struct {
+	conn *jsonrpc2.Connection
+
+	mutex sync.Mutex
+	docs  map[string]*document // uri => document
+	roots []string             // workspace folders
+	pull  bool                 // diagnostics are pulled by the client instead of published
+
+	idxMutex sync.Mutex
+	indexes  map[string]*langserver.Index // workspace root => index
+
+	diags *langserver.Diagnoser
+}
+
+func newHandler(conn *jsonrpc2.Connection) *handler {
+	return &handler{
+		conn:    conn,
+		docs:    make(map[string]*document),
+		indexes: make(map[string]*langserver.Index),
+		diags:   langserver.NewDiagnoser(),
+	}
+}
+
+func (p *handler) Handle(ctx context.Context, req *jsonrpc2.Request) (result interface{}, err error) {
+	switch req.Method {
+	case methodInitialize:
+		var params InitializeParams
+		if err = json.Unmarshal(req.Params, &params); err != nil {
+			return
+		}
+		p.initialize(&params)
+		result = &InitializeResult{
+			Capabilities: ServerCapabilities{
+				TextDocumentSync:       SyncIncremental,
+				HoverProvider:          true,
+				DefinitionProvider:     true,
+				ReferencesProvider:     true,
+				RenameProvider:         true,
+				DocumentSymbolProvider: true,
+				CompletionProvider:     &CompletionOptions{TriggerCharacters: []string{"."}},
+				SignatureHelpProvider:  &SignatureHelpOptions{TriggerCharacters: []string{"(", ","}},
+				InlayHintProvider:      true,
+				FoldingRangeProvider:   true,
+				CodeActionProvider:     true,
+				DiagnosticProvider:     &DiagnosticOptions{InterFileDependencies: true, WorkspaceDiagnostics: true},
+			},
+			ServerInfo: &ServerInfo{Name: "gop lsp", Version: env.Version()},
+		}
+	case methodInitialized:
+	case methodDidSave:
+		var params DidSaveTextDocumentParams
+		if err = json.Unmarshal(req.Params, &params); err != nil {
+			return
+		}
+		p.didSave(params.TextDocument.URI)
+	case methodShutdown:
+		result = none{}
+	case methodExit:
+		go p.conn.Close()
+	case methodDidOpen:
+		var params DidOpenTextDocumentParams
+		if err = json.Unmarshal(req.Params, &params); err != nil {
+			return
+		}
+		err = p.didOpen(&params)
+	case methodDidChange:
+		var params DidChangeTextDocumentParams
+		if err = json.Unmarshal(req.Params, &params); err != nil {
+			return
+		}
+		p.didChange(&params)
+	case methodDidClose:
+		var params DidCloseTextDocumentParams
+		if err = json.Unmarshal(req.Params, &params); err != nil {
+			return
+		}
+		p.didClose(params.TextDocument.URI)
+	case methodHover:
+		var params TextDocumentPositionParams
+		if err = json.Unmarshal(req.Params, &params); err != nil {
+			return
+		}
+		result, err = p.hover(&params)
+	case methodDefinition:
+		var params TextDocumentPositionParams
+		if err = json.Unmarshal(req.Params, &params); err != nil {
+			return
+		}
+		result, err = p.definition(&params)
+	case methodReferences:
+		var params ReferenceParams
+		if err = json.Unmarshal(req.Params, &params); err != nil {
+			return
+		}
+		result, err = p.references(&params)
+	case methodRename:
+		var params RenameParams
+		if err = json.Unmarshal(req.Params, &params); err != nil {
+			return
+		}
+		result, err = p.rename(&params)
+	case methodSignatureHelp:
+		var params TextDocumentPositionParams
+		if err = json.Unmarshal(req.Params, &params); err != nil {
+			return
+		}
+		result, err = p.signatureHelp(&params)
+	case methodInlayHint:
+		var params InlayHintParams
+		if err = json.Unmarshal(req.Params, &params); err != nil {
+			return
+		}
+		result, err = p.inlayHints(&params)
+	case methodDocumentSymbol:
+		var params DocumentSymbolParams
+		if err = json.Unmarshal(req.Params, &params); err != nil {
+			return
+		}
+		result, err = p.documentSymbols(params.TextDocument.URI)
+	case methodCompletion:
+		var params TextDocumentPositionParams
+		if err = json.Unmarshal(req.Params, &params); err != nil {
+			return
+		}
+		result, err = p.completion(&params)
+	case methodFoldingRange:
+		var params FoldingRangeParams
+		if err = json.Unmarshal(req.Params, &params); err != nil {
+			return
+		}
+		result, err = p.foldingRanges(params.TextDocument.URI)
+	case methodCodeAction:
+		var params CodeActionParams
+		if err = json.Unmarshal(req.Params, &params); err != nil {
+			return
+		}
+		result, err = p.codeActions(&params)
+	case methodDiagnostic:
+		var params DocumentDiagnosticParams
+		if err = json.Unmarshal(req.Params, &params); err != nil {
+			return
+		}
+		result, err = p.diagnostic(&params)
+	case methodWorkspaceDiag:
+		var params WorkspaceDiagnosticParams
+		if err = json.Unmarshal(req.Params, &params); err != nil {
+			return
+		}
+		result, err = p.workspaceDiagnostic(&params)
+	default:
+		err = jsonrpc2.ErrNotHandled
+	}
+	if err != nil {
+		result = nil // eg. a nil *WorkspaceEdit, which isn't a nil interface
+	}
+	return
+}
+
+type none = struct{}
+
+// -----------------------------------------------------------------------------
+
+func (p *handler) initialize(params *InitializeParams) {
+	var roots []string
+	for _, folder := range params.WorkspaceFolders {
+		if root, err := PathOf(folder.URI); err == nil {
+			roots = append(roots, root)
+		}
+	}
+	if len(roots) == 0 && params.RootURI != "" {
+		if root, err := PathOf(params.RootURI); err == nil {
+			roots = append(roots, root)
+		}
+	}
+	p.mutex.Lock()
+	p.roots = roots
+	p.pull = params.Capabilities.TextDocument.Diagnostic != nil
+	p.mutex.Unlock()
+}
+
+func (p *handler) didOpen(params *DidOpenTextDocumentParams) error {
+	item := params.TextDocument
+	path, err := PathOf(item.URI)
+	if err != nil {
+		return err
+	}
+	doc := &document{path: path, version: item.Version, text: []byte(item.Text)}
+	if data, err := os.ReadFile(path); err != nil || !bytes.Equal(data, doc.text) {
+		doc.dirty = true
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if old, ok := p.docs[item.URI]; ok && old.timer != nil {
+		old.timer.Stop()
+	}
+	p.docs[item.URI] = doc
+	p.schedule(item.URI, doc, 0)
+	return nil
+}
+
+func (p *handler) didChange(params *DidChangeTextDocumentParams) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	doc, ok := p.docs[params.TextDocument.URI]
+	if !ok {
+		return
+	}
+	for _, change := range params.ContentChanges {
+		if change.Range == nil {
+			doc.text = []byte(change.Text)
+			continue
+		}
+		start, end := OffsetOf(doc.text, change.Range.Start), OffsetOf(doc.text, change.Range.End)
+		if end < start {
+			end = start
+		}
+		text := make([]byte, 0, len(doc.text)-(end-start)+len(change.Text))
+		text = append(text, doc.text[:start]...)
+		text = append(text, change.Text...)
+		doc.text = append(text, doc.text[end:]...)
+	}
+	doc.version = params.TextDocument.Version
+	doc.dirty = true
+	p.schedule(params.TextDocument.URI, doc, DiagnosticsDelay)
+}
+
+// didSave invalidates cached diagnostics and indexes of the saved document,
+// and diagnostics of other open documents, which may depend on it.
+func (p *handler) didSave(uri string) {
+	path, err := PathOf(uri)
+	if err != nil {
+		return
+	}
+	p.diags.Changed(filepath.Dir(path))
+	p.idxMutex.Lock()
+	for _, idx := range p.indexes {
+		idx.UpdateFiles(path)
+	}
+	p.idxMutex.Unlock()
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if doc, ok := p.docs[uri]; ok {
+		doc.dirty = false
+	}
+	for uri, doc := range p.docs {
+		p.schedule(uri, doc, DiagnosticsDelay)
+	}
+}
+
+func (p *handler) didClose(uri string) {
+	p.mutex.Lock()
+	if doc, ok := p.docs[uri]; ok {
+		if doc.timer != nil {
+			doc.timer.Stop()
+		}
+		delete(p.docs, uri)
+	}
+	pull := p.pull
+	p.mutex.Unlock()
+	if pull {
+		return
+	}
+	p.conn.Notify(context.Background(), methodPublishDiags, &PublishDiagnosticsParams{
+		URI: uri, Diagnostics: []Diagnostic{},
+	})
+}
+
+// schedule publishes diagnostics of the document after delay, unless they
+// are pulled by the client. It must be called with p.mutex held.
+func (p *handler) schedule(uri string, doc *document, delay time.Duration) {
+	if doc.timer != nil {
+		doc.timer.Stop()
+	}
+	if p.pull {
+		return
+	}
+	doc.timer = time.AfterFunc(delay, func() {
+		p.publishDiagnostics(uri, doc)
+	})
+}
+
+func (p *handler) publishDiagnostics(uri string, doc *document) {
+	p.mutex.Lock()
+	version, text := doc.version, doc.text
+	p.mutex.Unlock()
+	items, err := langserver.DiagnoseFile(doc.path, text)
+	if err != nil {
+		items = []*langserver.Diagnostic{{Severity: langserver.SeverityError, Msg: err.Error()}}
+	}
+	params := &PublishDiagnosticsParams{URI: uri, Version: version, Diagnostics: diagnosticsOf(text, items)}
+	p.mutex.Lock()
+	stale := p.docs[uri] != doc || doc.version != version
+	p.mutex.Unlock()
+	if !stale {
+		p.conn.Notify(context.Background(), methodPublishDiags, params)
+	}
+}
+
+// diagnosticsOf converts diagnostics of langserver to the protocol.
+func diagnosticsOf(text []byte, items []*langserver.Diagnostic) []Diagnostic {
+	ret := make([]Diagnostic, 0, len(items))
+	for _, item := range items {
+		severity := SeverityError
+		if item.Severity == langserver.SeverityWarning {
+			severity = SeverityWarning
+		}
+		ret = append(ret, Diagnostic{
+			Range:    rangeOf(text, item.Offset),
+			Severity: severity,
+			Source:   "gop",
+			Message:  item.Msg,
+		})
+	}
+	return ret
+}
+
+// rangeOf returns the range of the identifier (or an empty range if there is
+// none) at the byte offset in text.
+func rangeOf(text []byte, offset int) Range {
+	if offset > len(text) {
+		offset = len(text)
+	}
+	return Range{Start: PositionOf(text, offset), End: PositionOf(text, offset+identLen(text[offset:]))}
+}
+
+// identLen returns the length of the identifier at the beginning of text, so
+// that a diagnostic of an identifier covers the whole name.
+func identLen(text []byte) int {
+	n := 0
+	for _, r := range string(text) {
+		if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			break
+		}
+		n += utf8.RuneLen(r)
+	}
+	return n
+}
+
+// -----------------------------------------------------------------------------
+
+// textOf returns the file path and content of the document uri, which is read
+// from disk if it isn't open.
+func (p *handler) textOf(uri string) (path string, text []byte, err error) {
+	p.mutex.Lock()
+	doc, ok := p.docs[uri]
+	if ok {
+		path, text = doc.path, doc.text
+	}
+	p.mutex.Unlock()
+	if !ok {
+		if path, err = PathOf(uri); err == nil {
+			text, err = os.ReadFile(path)
+		}
+	}
+	return
+}
+
+// textsOf returns a function which returns contents of files like textOf, and
+// caches them. A file which can't be read is empty.
+func (p *handler) textsOf() func(file string) []byte {
+	texts := make(map[string][]byte)
+	return func(file string) []byte {
+		text, ok := texts[file]
+		if !ok {
+			_, text, _ = p.textOf(URIOf(file))
+			texts[file] = text
+		}
+		return text
+	}
+}
+
+func (p *handler) hover(params *TextDocumentPositionParams) (ret *Hover, err error) {
+	path, text, err := p.textOf(params.TextDocument.URI)
+	if err != nil {
+		return
+	}
+	h, err := langserver.HoverAt(path, text, OffsetOf(text, params.Position))
+	if err != nil || h == nil {
+		return nil, nil // no hover is not an error
+	}
+	var b strings.Builder
+	b.WriteString("```gop\n" + h.Signature + "\n```")
+	if h.Doc != "" {
+		b.WriteString("\n\n" + h.Doc)
+	}
+	if h.Origin != "" {
+		b.WriteString("\n\n" + h.Origin)
+	}
+	return &Hover{
+		Contents: MarkupContent{Kind: "markdown", Value: b.String()},
+		Range:    &Range{Start: PositionOf(text, h.Start), End: PositionOf(text, h.End)},
+	}, nil
+}
+
+func (p *handler) definition(params *TextDocumentPositionParams) (ret *Location, err error) {
+	path, text, err := p.textOf(params.TextDocument.URI)
+	if err != nil {
+		return
+	}
+	loc, err := langserver.DefinitionAt(path, text, OffsetOf(text, params.Position))
+	if err != nil || loc == nil {
+		return nil, nil
+	}
+	uri := URIOf(loc.File)
+	if loc.File != path {
+		if _, text, err = p.textOf(uri); err != nil {
+			return nil, nil
+		}
+	}
+	return &Location{
+		URI:   uri,
+		Range: Range{Start: PositionOf(text, loc.Offset), End: PositionOf(text, loc.End)},
+	}, nil
+}
+
+// indexOf returns the index of the workspace the file belongs to (see
+// langserver.WorkspaceOf).
+func (p *handler) indexOf(file string) (idx *langserver.Index, err error) {
+	root, err := langserver.WorkspaceOf(file)
+	if err != nil {
+		return
+	}
+	p.idxMutex.Lock()
+	defer p.idxMutex.Unlock()
+	if idx = p.indexes[root]; idx == nil {
+		if idx, err = langserver.LoadIndex(root); err != nil {
+			return
+		}
+		p.indexes[root] = idx
+	}
+	return
+}
+
+// symbolAt returns the index of the document uri and the symbol at pos, or ""
+// if there is no symbol.
+func (p *handler) symbolAt(uri string, pos Position) (idx *langserver.Index, sym string, err error) {
+	path, text, err := p.textOf(uri)
+	if err != nil {
+		return
+	}
+	if idx, err = p.indexOf(path); err != nil {
+		return
+	}
+	return idx, idx.SymbolAt(path, OffsetOf(text, pos)), nil
+}
+
+func (p *handler) references(params *ReferenceParams) (ret []Location, err error) {
+	idx, sym, err := p.symbolAt(params.TextDocument.URI, params.Position)
+	if err != nil {
+		return
+	}
+	ret = []Location{}
+	if sym == "" {
+		return
+	}
+	textOf := p.textsOf()
+	for _, ref := range idx.References(sym) {
+		if ref.Def && !params.Context.IncludeDeclaration {
+			continue
+		}
+		ret = append(ret, Location{URI: URIOf(ref.File), Range: rangeOf(textOf(ref.File), ref.Offset)})
+	}
+	return
+}
+
+func (p *handler) rename(params *RenameParams) (ret *WorkspaceEdit, err error) {
+	idx, sym, err := p.symbolAt(params.TextDocument.URI, params.Position)
+	if err != nil {
+		return
+	}
+	if sym == "" {
+		return nil, errors.New("no symbol to rename at the position")
+	}
+	edits, err := idx.Rename(sym, params.NewName)
+	if err != nil { // eg. conflicts, which are shown to the user
+		return
+	}
+	textOf := p.textsOf()
+	ret = &WorkspaceEdit{Changes: make(map[string][]TextEdit, len(edits))}
+	for file, es := range edits {
+		text := textOf(file)
+		changes := make([]TextEdit, 0, len(es))
+		for _, e := range es {
+			changes = append(changes, TextEdit{
+				Range:   Range{Start: PositionOf(text, e.Start), End: PositionOf(text, e.End)},
+				NewText: e.NewText,
+			})
+		}
+		ret.Changes[URIOf(file)] = changes
+	}
+	return
+}
+
+var symbolKinds = map[string]int{
+	langserver.KindFunc:      SymbolFunction,
+	langserver.KindMethod:    SymbolMethod,
+	langserver.KindType:      SymbolClass,
+	langserver.KindStruct:    SymbolStruct,
+	langserver.KindInterface: SymbolInterface,
+	langserver.KindField:     SymbolField,
+	langserver.KindVar:       SymbolVariable,
+	langserver.KindConst:     SymbolConstant,
+	langserver.KindClass:     SymbolClass,
+	langserver.KindHandler:   SymbolEvent,
+	langserver.KindRegion:    SymbolNamespace,
+}
+
+func (p *handler) documentSymbols(uri string) (ret []DocumentSymbol, err error) {
+	path, text, err := p.textOf(uri)
+	if err != nil {
+		return
+	}
+	syms, err := langserver.Outline(path, text)
+	if err != nil {
+		return []DocumentSymbol{}, nil // eg. syntax errors, which are diagnosed
+	}
+	return documentSymbolsOf(text, syms), nil
+}
+
+func documentSymbolsOf(text []byte, syms []*langserver.Symbol) []DocumentSymbol {
+	ret := make([]DocumentSymbol, 0, len(syms))
+	for _, sym := range syms {
+		ret = append(ret, DocumentSymbol{
+			Name:   sym.Name,
+			Detail: sym.Detail,
+			Kind:   symbolKinds[sym.Kind],
+			Range:  Range{Start: PositionOf(text, sym.Start), End: PositionOf(text, sym.End)},
+			SelectionRange: Range{
+				Start: PositionOf(text, sym.NameOffset), End: PositionOf(text, sym.NameOffset+len(sym.Name)),
+			},
+			Children: documentSymbolsOf(text, sym.Children),
+		})
+	}
+	return ret
+}
+
+var completionKinds = map[string]int{
+	langserver.KindFunc:      CompletionFunction,
+	langserver.KindMethod:    CompletionMethod,
+	langserver.KindType:      CompletionClass,
+	langserver.KindStruct:    CompletionStruct,
+	langserver.KindInterface: CompletionInterface,
+	langserver.KindField:     CompletionField,
+	langserver.KindVar:       CompletionVariable,
+	langserver.KindConst:     CompletionConstant,
+	langserver.KindPackage:   CompletionModule,
+}
+
+func (p *handler) completion(params *TextDocumentPositionParams) (ret *CompletionList, err error) {
+	path, text, err := p.textOf(params.TextDocument.URI)
+	if err != nil {
+		return
+	}
+	ret = &CompletionList{Items: []CompletionItem{}}
+	items, err := langserver.Completions(path, text, OffsetOf(text, params.Position))
+	if err != nil {
+		return ret, nil
+	}
+	for _, item := range items {
+		ret.Items = append(ret.Items, CompletionItem{
+			Label: item.Label, Kind: completionKinds[item.Kind], Detail: item.Detail,
+		})
+	}
+	return
+}
+
+func (p *handler) signatureHelp(params *TextDocumentPositionParams) (ret *SignatureHelp, err error) {
+	path, text, err := p.textOf(params.TextDocument.URI)
+	if err != nil {
+		return
+	}
+	help, err := langserver.SignatureHelpAt(path, text, OffsetOf(text, params.Position))
+	if err != nil || help == nil {
+		return nil, nil // not in a call
+	}
+	ret = &SignatureHelp{ActiveSignature: help.ActiveSignature, ActiveParameter: help.ActiveParam}
+	for _, sig := range help.Signatures {
+		info := SignatureInformation{Label: sig.Label, Documentation: sig.Doc, Parameters: []ParameterInformation{}}
+		for _, param := range sig.Params {
+			info.Parameters = append(info.Parameters, ParameterInformation{Label: param})
+		}
+		ret.Signatures = append(ret.Signatures, info)
+	}
+	return
+}
+
+var inlayHintKinds = map[string]int{
+	langserver.InlayHintType:      InlayHintType,
+	langserver.InlayHintParameter: InlayHintParameter,
+}
+
+func (p *handler) inlayHints(params *InlayHintParams) (ret []InlayHint, err error) {
+	path, text, err := p.textOf(params.TextDocument.URI)
+	if err != nil {
+		return
+	}
+	ret = []InlayHint{}
+	start, end := OffsetOf(text, params.Range.Start), OffsetOf(text, params.Range.End)
+	if end <= start {
+		return
+	}
+	hints, err := langserver.InlayHints(path, text, start, end)
+	if err != nil {
+		return ret, nil // eg. syntax errors, which are diagnosed
+	}
+	for _, h := range hints {
+		ret = append(ret, InlayHint{
+			Position:     PositionOf(text, h.Offset),
+			Label:        h.Label,
+			Kind:         inlayHintKinds[h.Kind],
+			PaddingRight: h.Kind == langserver.InlayHintParameter,
+		})
+	}
+	return ret, nil
+}
+
+// -----------------------------------------------------------------------------
+
+func (p *handler) foldingRanges(uri string) (ret []FoldingRange, err error) {
+	path, text, err := p.textOf(uri)
+	if err != nil {
+		return
+	}
+	ret = []FoldingRange{}
+	items, err := langserver.FoldingRanges(path, text)
+	if err != nil {
+		return ret, nil // eg. syntax errors, which are diagnosed
+	}
+	for _, item := range items {
+		start, end := PositionOf(text, item.Start), PositionOf(text, item.End)
+		if item.Kind != langserver.FoldComment && item.Kind != langserver.FoldRegion &&
+			isLineStart(text, item.End) { // keep the closing bracket visible
+			end.Line--
+		}
+		if end.Line > start.Line {
+			ret = append(ret, FoldingRange{StartLine: start.Line, EndLine: end.Line, Kind: item.Kind})
+		}
+	}
+	return ret, nil
+}
+
+// isLineStart reports whether only spaces precede the offset in its line.
+func isLineStart(text []byte, offset int) bool {
+	for i := offset - 1; i >= 0; i-- {
+		switch text[i] {
+		case '\n':
+			return true
+		case ' ', '\t', '\r':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// -----------------------------------------------------------------------------
+
+func (p *handler) codeActions(params *CodeActionParams) (ret []CodeAction, err error) {
+	uri := params.TextDocument.URI
+	path, text, err := p.textOf(uri)
+	if err != nil {
+		return
+	}
+	ret = []CodeAction{}
+	actions, err := langserver.CodeActions(path, text, OffsetOf(text, params.Range.Start))
+	if err != nil {
+		return ret, nil // eg. syntax errors, which are diagnosed
+	}
+	for _, action := range actions {
+		if !kindMatches(action.Kind, params.Context.Only) {
+			continue
+		}
+		edits := make([]TextEdit, 0, len(action.Edits))
+		for _, e := range action.Edits {
+			edits = append(edits, TextEdit{
+				Range:   Range{Start: PositionOf(text, e.Start), End: PositionOf(text, e.End)},
+				NewText: e.NewText,
+			})
+		}
+		ret = append(ret, CodeAction{
+			Title: action.Title, Kind: action.Kind,
+			Edit: &WorkspaceEdit{Changes: map[string][]TextEdit{uri: edits}},
+		})
+	}
+	return ret, nil
+}
+
+// kindMatches reports whether the code action kind is one of (or a sub kind
+// of one of) kinds requested. All kinds match if none is requested.
+func kindMatches(kind string, only []string) bool {
+	if len(only) == 0 {
+		return true
+	}
+	for _, k := range only {
+		if kind == k || strings.HasPrefix(kind, k+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// -----------------------------------------------------------------------------
+
+// diagnostic returns diagnostics of the document pulled by the client. The
+// diagnostics of the package of a saved document are cached until it (or a
+// package imported by it) is saved, while unsaved changes are diagnosed
+// without caching.
+func (p *handler) diagnostic(params *DocumentDiagnosticParams) (ret *DocumentDiagnosticReport, err error) {
+	uri := params.TextDocument.URI
+	p.mutex.Lock()
+	doc, ok := p.docs[uri]
+	if ok && doc.dirty {
+		path, text := doc.path, doc.text
+		p.mutex.Unlock()
+		items, err := langserver.DiagnoseFile(path, text)
+		if err != nil {
+			items = []*langserver.Diagnostic{{Severity: langserver.SeverityError, Msg: err.Error()}}
+		}
+		return &DocumentDiagnosticReport{Kind: ReportFull, Items: diagnosticsOf(text, items)}, nil
+	}
+	p.mutex.Unlock()
+	path, text, err := p.textOf(uri)
+	if err != nil {
+		return
+	}
+	report := p.diags.Diagnostics(filepath.Dir(path), params.PreviousResultID)
+	if report.Unchanged {
+		return &DocumentDiagnosticReport{Kind: ReportUnchanged, ResultID: report.ResultID}, nil
+	}
+	var items []*langserver.Diagnostic
+	for _, item := range report.Items {
+		if item.File == path {
+			items = append(items, item)
+		}
+	}
+	return &DocumentDiagnosticReport{Kind: ReportFull, ResultID: report.ResultID, Items: diagnosticsOf(text, items)}, nil
+}
+
+// workspaceDiagnostic returns diagnostics of all documents in workspace
+// folders (or workspaces of open documents if there is no folder). Results
+// are cached per package like diagnostic, so a document is reported unchanged
+// if its package is.
+func (p *handler) workspaceDiagnostic(params *WorkspaceDiagnosticParams) (ret *WorkspaceDiagnosticReport, err error) {
+	prevURIs := make(map[string][]string) // dir => uris of documents reported previously
+	prevIDs := make(map[string]string)    // dir => result id of the package
+	for _, prev := range params.PreviousResultIDs {
+		path, err := PathOf(prev.URI)
+		if err != nil {
+			continue
+		}
+		dir := filepath.Dir(path)
+		if id, ok := prevIDs[dir]; ok && id != prev.Value { // eg. one of unsaved changes
+			prevIDs[dir] = ""
+		} else {
+			prevIDs[dir] = prev.Value
+		}
+		prevURIs[dir] = append(prevURIs[dir], prev.URI)
+	}
+
+	p.mutex.Lock()
+	roots := p.roots
+	versions := make(map[string]int, len(p.docs))
+	for uri, doc := range p.docs {
+		versions[uri] = doc.version
+		if len(p.roots) == 0 {
+			if root, err := langserver.WorkspaceOf(doc.path); err == nil && !contains(roots, root) {
+				roots = append(roots, root)
+			}
+		}
+	}
+	p.mutex.Unlock()
+
+	ret = &WorkspaceDiagnosticReport{Items: []WorkspaceDocumentDiagnosticReport{}}
+	add := func(uri string, report DocumentDiagnosticReport) {
+		item := WorkspaceDocumentDiagnosticReport{URI: uri, DocumentDiagnosticReport: report}
+		if version, ok := versions[uri]; ok {
+			item.Version = &version
+		}
+		ret.Items = append(ret.Items, item)
+	}
+	textOf := p.textsOf()
+	for _, root := range roots {
+		reports, err := p.diags.Workspace(root, prevIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, report := range reports {
+			if report.Unchanged {
+				for _, uri := range prevURIs[report.Dir] {
+					add(uri, DocumentDiagnosticReport{Kind: ReportUnchanged, ResultID: report.ResultID})
+				}
+				continue
+			}
+			files := make(map[string][]*langserver.Diagnostic)
+			for _, uri := range prevURIs[report.Dir] { // clear diagnostics fixed
+				if path, err := PathOf(uri); err == nil {
+					files[path] = nil
+				}
+			}
+			for _, item := range report.Items {
+				files[item.File] = append(files[item.File], item)
+			}
+			for file, items := range files {
+				add(URIOf(file), DocumentDiagnosticReport{
+					Kind: ReportFull, ResultID: report.ResultID, Items: diagnosticsOf(textOf(file), items),
+				})
+			}
+		}
+	}
+	sort.Slice(ret.Items, func(i, j int) bool {
+		return ret.Items[i].URI < ret.Items[j].URI
+	})
+	return
+}
+
+func contains(dirs []string, dir string) bool {
+	for _, d := range dirs {
+		if d == dir {
+			return true
+		}
+	}
+	return false
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,348 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+// Package notebook implements an engine executing Go+ cells which share a
+// persistent session, like cells of a notebook or inputs of a REPL.
+//
+// Declarations (imports, types, consts, vars and funcs) of a cell are
+// retained by the session, and a later declaration with the same name
+// replaces the former one. Variables defined by statements of a cell are
+// visible to later cells, which may redefine them.
+//
+// The session is compiled and run as a whole for each cell: statements of
+// previous cells are replayed and only output of the new cell is returned.
+// So side effects of previous cells (except their output) happen again.
+package notebook
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/goplus/gop"
+	"github.com/goplus/gop/ast"
+	"github.com/goplus/gop/parser"
+	"github.com/goplus/gop/token"
+	"github.com/goplus/gop/x/gocmd"
+)
+
+// -----------------------------------------------------------------------------
+
+// Cell represents an executed cell of a session.
+type Cell struct {
+	Source string
+	Output string // stdout of the cell
+}
+
+type decl struct {
+	names []string // nil for imports
+	src   string
+}
+
+type cellCode struct {
+	decls []*decl
+	stmts string
+}
+
+// Config represents options of a session.
+type Config struct {
+	// Dir is the working directory where the session is compiled and run.
+	// It must be in a Go module if cells use packages other than std.
+	// A temporary directory is used if it is empty.
+	Dir string
+
+	// Gop represents options of compiling cells.
+	Gop *gop.Config
+
+	// Run represents options of running cells.
+	Run *gocmd.RunConfig
+}
+
+// Session represents an execution session of cells.
+type Session struct {
+	dir   string
+	temp  bool
+	conf  Config
+	cells []*Cell
+	codes []*cellCode
+}
+
+// NewSession creates a new session.
+func NewSession(conf *Config) (*Session, error) {
+	if conf == nil {
+		conf = new(Config)
+	}
+	dir, temp := conf.Dir, false
+	if dir == "" {
+		tmp, err := os.MkdirTemp("", "gop-notebook-")
+		if err != nil {
+			return nil, err
+		}
+		dir, temp = tmp, true
+	} else if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &Session{dir: dir, temp: temp, conf: *conf}, nil
+}
+
+// Close releases resources of the session.
+func (p *Session) Close() error {
+	if p.temp {
+		return os.RemoveAll(p.dir)
+	}
+	return nil
+}
+
+// Cells returns all executed cells of the session.
+func (p *Session) Cells() []*Cell {
+	return p.cells
+}
+
+// Snapshot represents a saved state of a session.
+type Snapshot int
+
+// Snapshot saves the current state of the session.
+func (p *Session) Snapshot() Snapshot {
+	return Snapshot(len(p.cells))
+}
+
+// Rollback restores the session to a saved state.
+func (p *Session) Rollback(snap Snapshot) {
+	if n := int(snap); n >= 0 && n < len(p.cells) {
+		p.cells, p.codes = p.cells[:n], p.codes[:n]
+	}
+}
+
+// Reset clears the session.
+func (p *Session) Reset() {
+	p.Rollback(0)
+}
+
+// ErrRun is the error returned by Exec if the cell failed to run.
+var ErrRun = errors.New("cell failed to run")
+
+// Exec executes a cell. The cell is added to the session only if it
+// is executed successfully. Output of the cell is returned, even if the
+// cell failed to run (in this case, the error wraps ErrRun).
+func (p *Session) Exec(src string) (*Cell, error) {
+	code, err := parseCell(src)
+	if err != nil {
+		return nil, err
+	}
+	codes := append(p.codes[:len(p.codes):len(p.codes)], code)
+	file := filepath.Join(p.dir, "main.gop")
+	if err = os.WriteFile(file, genSource(codes), 0644); err != nil {
+		return nil, err
+	}
+	var stdout, stderr bytes.Buffer
+	run := new(gocmd.RunConfig)
+	if p.conf.Run != nil {
+		*run = *p.conf.Run
+	}
+	run.Run = func(cmd *exec.Cmd) error {
+		cmd.Dir, cmd.Stdout, cmd.Stderr = p.dir, &stdout, &stderr
+		return cmd.Run()
+	}
+	gopConf := p.conf.Gop
+	if gopConf == nil {
+		gopConf = &gop.Config{}
+	}
+	autogen := filepath.Join(p.dir, "gop_autogen.go")
+	err = gop.RunFiles(autogen, []string{file}, nil, gopConf, run)
+	out := stdout.String()
+	if idx := strings.LastIndex(out, cellMarker); idx >= 0 {
+		out = out[idx+len(cellMarker):]
+	}
+	cell := &Cell{Source: src, Output: out}
+	if err != nil {
+		if _, ok := err.(*exec.ExitError); ok {
+			err = &RunError{Stderr: stderr.String(), Err: err}
+		}
+		return cell, err
+	}
+	p.cells, p.codes = append(p.cells, cell), codes
+	return cell, nil
+}
+
+// RunError is the error returned by Exec if the cell failed to run.
+type RunError struct {
+	Stderr string
+	Err    error
+}
+
+func (e *RunError) Error() string {
+	return strings.TrimSpace(e.Stderr)
+}
+
+func (e *RunError) Is(target error) bool {
+	return target == ErrRun
+}
+
+func (e *RunError) Unwrap() error {
+	return e.Err
+}
+
+// -----------------------------------------------------------------------------
+
+const cellMarker = "\x00gop:cell\x00"
+
+func parseCell(src string) (code *cellCode, err error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "cell.gop", src, parser.ParseComments)
+	if err != nil {
+		return
+	}
+	code = new(cellCode)
+	base := fset.File(f.Pos()).Base()
+	text := func(from, to token.Pos) string {
+		return src[int(from)-base : int(to)-base]
+	}
+	for _, d := range f.Decls {
+		switch d := d.(type) {
+		case *ast.GenDecl:
+			var names []string
+			for _, spec := range d.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					names = append(names, spec.Name.Name)
+				case *ast.ValueSpec:
+					for _, name := range spec.Names {
+						names = append(names, name.Name)
+					}
+				}
+			}
+			code.decls = append(code.decls, &decl{names, text(d.Pos(), d.End())})
+		case *ast.FuncDecl:
+			if d == f.ShadowEntry {
+				if list := d.Body.List; len(list) > 0 {
+					code.stmts = text(list[0].Pos(), list[len(list)-1].End()) + "\n" + useDefined(list)
+				}
+				continue
+			}
+			name := d.Name.Name
+			if d.Recv != nil && len(d.Recv.List) == 1 {
+				name = recvTypeName(d.Recv.List[0].Type) + "." + name
+			}
+			code.decls = append(code.decls, &decl{[]string{name}, text(d.Pos(), d.End())})
+		}
+	}
+	return
+}
+
+func recvTypeName(typ ast.Expr) string {
+	if t, ok := typ.(*ast.StarExpr); ok {
+		typ = t.X
+	}
+	if t, ok := typ.(*ast.Ident); ok {
+		return t.Name
+	}
+	return ""
+}
+
+// useDefined generates `_ = name` for variables defined by stmts, so that
+// they are used even if no later cell uses them.
+func useDefined(stmts []ast.Stmt) string {
+	var b strings.Builder
+	use := func(name *ast.Ident) {
+		if name.Name != "_" {
+			b.WriteString("_ = " + name.Name + "\n")
+		}
+	}
+	for _, stmt := range stmts {
+		switch stmt := stmt.(type) {
+		case *ast.AssignStmt:
+			if stmt.Tok == token.DEFINE {
+				for _, lhs := range stmt.Lhs {
+					if name, ok := lhs.(*ast.Ident); ok {
+						use(name)
+					}
+				}
+			}
+		case *ast.DeclStmt:
+			if d, ok := stmt.Decl.(*ast.GenDecl); ok && d.Tok == token.VAR {
+				for _, spec := range d.Specs {
+					for _, name := range spec.(*ast.ValueSpec).Names {
+						use(name)
+					}
+				}
+			}
+		}
+	}
+	return b.String()
+}
+
+// genSource generates source of the whole session. Statements of each cell
+// are in a block nested in the block of the previous cell, so a cell can
+// redefine variables of previous cells.
+func genSource(codes []*cellCode) []byte {
+	var decls []*decl
+	for _, code := range codes {
+		for _, d := range code.decls {
+			if d.names != nil {
+				decls = removeDecls(decls, d.names)
+			}
+			decls = append(decls, d)
+		}
+	}
+	var b bytes.Buffer
+	imports := make(map[string]bool)
+	for _, d := range decls { // imports first
+		if d.names == nil && strings.HasPrefix(d.src, "import") && !imports[d.src] {
+			imports[d.src] = true
+			b.WriteString(d.src + "\n")
+		}
+	}
+	for _, d := range decls {
+		if !(d.names == nil && strings.HasPrefix(d.src, "import")) {
+			b.WriteString(d.src + "\n\n")
+		}
+	}
+	last := len(codes) - 1
+	for i, code := range codes {
+		if i == last {
+			b.WriteString("print " + strconv.Quote(cellMarker) + "\n")
+		}
+		b.WriteString("{\n" + code.stmts + "\n")
+	}
+	b.WriteString(strings.Repeat("}\n", len(codes)))
+	return b.Bytes()
+}
+
+func removeDecls(decls []*decl, names []string) []*decl {
+	ret := decls[:0]
+	for _, d := range decls {
+		if !hasAny(d.names, names) {
+			ret = append(ret, d)
+		}
+	}
+	return ret
+}
+
+func hasAny(a, b []string) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,81 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package notebook
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func init() {
+	if os.Getenv("GOPROOT") == "" {
+		dir, _ := os.Getwd()
+		os.Setenv("GOPROOT", filepath.Clean(filepath.Join(dir, "./../..")))
+	}
+}
+
+func TestSession(t *testing.T) {
+	s, err := NewSession(nil)
+	if err != nil {
+		t.Fatal("NewSession:", err)
+	}
+	defer s.Close()
+
+	exec := func(src, output string) {
+		t.Helper()
+		cell, err := s.Exec(src)
+		if err != nil {
+			t.Fatal("Exec:", src, err)
+		}
+		if cell.Output != output {
+			t.Fatalf("Exec %q: output %q, expected %q", src, cell.Output, output)
+		}
+	}
+	exec("x := 1\nprintln \"x:\", x", "x: 1\n")
+	exec(`import "strings"
+
+func upper(s string) string {
+	return strings.ToUpper(s)
+}
+
+println upper("hi"), x`, "HI 1\n")
+	snap := s.Snapshot()
+	exec("x := \"redefined\"\nprintln x", "redefined\n")
+	exec("func upper(s string) string {\n\treturn s + \"!\"\n}\nprintln upper(x)", "redefined!\n")
+	if n := len(s.Cells()); n != 4 {
+		t.Fatal("Cells:", n)
+	}
+
+	s.Rollback(snap)
+	exec("println x, upper(\"a\")", "1 A\n")
+
+	if _, err := s.Exec("println y"); err == nil {
+		t.Fatal("Exec: no error")
+	}
+	cell, err := s.Exec("println \"before panic\"\npanic \"oops\"")
+	if !errors.Is(err, ErrRun) || cell.Output != "before panic\n" {
+		t.Fatal("Exec panic:", err, cell.Output)
+	}
+	if n := len(s.Cells()); n != 3 {
+		t.Fatal("Cells after errors:", n)
+	}
+	s.Reset()
+	if _, err := s.Exec("println x"); err == nil {
+		t.Fatal("Exec after Reset: no error")
+	}
+}
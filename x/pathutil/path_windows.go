@@ -0,0 +1,36 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pathutil
+
+import (
+	"strings"
+)
+
+const longAbs = true // see Long
+
+// canonical makes the drive letter of a cleaned path upper case.
+func canonical(path string) string {
+	if len(path) >= 2 && path[1] == ':' && 'a' <= path[0] && path[0] <= 'z' {
+		return string(path[0]-'a'+'A') + path[1:]
+	}
+	return path
+}
+
+// equal compares paths case-insensitively, as file systems on Windows do.
+func equal(a, b string) bool {
+	return strings.EqualFold(a, b)
+}
@@ -0,0 +1,100 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package pathutil implements operations on file paths which are correct on
+// Windows too, where paths may be in extended-length form (\\?\C:\...) to
+// exceed MAX_PATH, may be UNC paths (\\host\share\...), and are compared
+// case-insensitively.
+package pathutil
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// -----------------------------------------------------------------------------
+
+// Abs returns the absolute and canonical form of path: it's cleaned, and on
+// Windows, the extended-length prefix is removed (\\?\UNC\host\share becomes
+// \\host\share) and the drive letter is upper case. Note the os package adds
+// the prefix back for long absolute paths, so they can be used as they are.
+func Abs(path string) (string, error) {
+	path, err := filepath.Abs(trimLongPrefix(path))
+	if err != nil {
+		return "", err
+	}
+	return canonical(path), nil
+}
+
+// Equal reports whether paths a and b are the same, case-insensitively on
+// Windows. Both of them should be absolute or relative to the same directory.
+func Equal(a, b string) bool {
+	return equal(canonical(filepath.Clean(trimLongPrefix(a))), canonical(filepath.Clean(trimLongPrefix(b))))
+}
+
+// Rel returns path relative to dir, and reports whether path is dir itself or
+// in dir. Unlike filepath.Rel, it never returns a path going up (..), and it
+// works for paths in extended-length form.
+func Rel(dir, path string) (rel string, ok bool) {
+	dir = canonical(filepath.Clean(trimLongPrefix(dir)))
+	path = canonical(filepath.Clean(trimLongPrefix(path)))
+	if equal(dir, path) {
+		return ".", true
+	}
+	prefix := dir
+	if !strings.HasSuffix(prefix, string(os.PathSeparator)) {
+		prefix += string(os.PathSeparator)
+	}
+	if len(path) > len(prefix) && equal(path[:len(prefix)], prefix) {
+		return path[len(prefix):], true
+	}
+	return "", false
+}
+
+// Long returns path in a form which can exceed MAX_PATH on Windows, where the
+// os package extends absolute paths only, so a relative path is made absolute.
+// On other systems, path is returned as it is.
+func Long(path string) string {
+	if longAbs && !filepath.IsAbs(path) {
+		if abs, err := filepath.Abs(path); err == nil {
+			return abs
+		}
+	}
+	return path
+}
+
+// Join joins dir and a slash-separated relative path, eg. a path relative to
+// the root directory of a watcher.
+func Join(dir, slashPath string) string {
+	return filepath.Join(dir, filepath.FromSlash(slashPath))
+}
+
+// -----------------------------------------------------------------------------
+
+// trimLongPrefix removes the extended-length prefix of a Windows path. It's
+// harmless for paths of other systems, which never start with `\\?\`.
+func trimLongPrefix(path string) string {
+	if len(path) >= 4 && path[:4] == `\\?\` {
+		if len(path) >= 8 && strings.EqualFold(path[4:8], `UNC\`) {
+			return `\\` + path[8:]
+		}
+		return path[4:]
+	}
+	return path
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,71 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pathutil
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestTrimLongPrefix(t *testing.T) {
+	cases := [][2]string{
+		{`\\?\C:\a\b`, `C:\a\b`},
+		{`\\?\UNC\host\share\a`, `\\host\share\a`},
+		{`\\?\unc\host\share`, `\\host\share`},
+		{`\\host\share\a`, `\\host\share\a`},
+		{"/a/b", "/a/b"},
+	}
+	for _, c := range cases {
+		if ret := trimLongPrefix(c[0]); ret != c[1] {
+			t.Fatalf("trimLongPrefix(%q) = %q, want %q", c[0], ret, c[1])
+		}
+	}
+}
+
+func TestRel(t *testing.T) {
+	dir := filepath.FromSlash("/home/gop")
+	cases := []struct {
+		path string
+		rel  string
+		ok   bool
+	}{
+		{"/home/gop", ".", true},
+		{"/home/gop/a/b.gop", "a/b.gop", true},
+		{"/home/gop/../gop/a", "a", true},
+		{"/home/gopx/a", "", false},
+		{"/home", "", false},
+		{"/home/..gop", "", false},
+	}
+	for _, c := range cases {
+		rel, ok := Rel(dir, filepath.FromSlash(c.path))
+		if rel != filepath.FromSlash(c.rel) || ok != c.ok {
+			t.Fatalf("Rel(%q, %q) = %q, %v", dir, c.path, rel, ok)
+		}
+	}
+	if rel, ok := Rel(filepath.FromSlash("/"), filepath.FromSlash("/a")); !ok || rel != "a" {
+		t.Fatal("Rel:", rel, ok)
+	}
+}
+
+func TestEqualJoin(t *testing.T) {
+	if !Equal(filepath.FromSlash("/a/b/../c"), filepath.FromSlash("/a/c/")) {
+		t.Fatal("Equal: false")
+	}
+	if Join(filepath.FromSlash("/a"), "b/c.gop") != filepath.FromSlash("/a/b/c.gop") {
+		t.Fatal("Join:", Join(filepath.FromSlash("/a"), "b/c.gop"))
+	}
+}
@@ -0,0 +1,47 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pathutil
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWindowsPaths(t *testing.T) {
+	if !Equal(`c:\Gop\A.gop`, `\\?\C:\gop\a.gop`) {
+		t.Fatal("Equal: drive letter or case")
+	}
+	if !Equal(`\\?\UNC\host\share\dir`, `\\HOST\share\dir\`) {
+		t.Fatal("Equal: UNC")
+	}
+	if rel, ok := Rel(`C:\Proj`, `c:\proj\Sub\a.gop`); !ok || rel != `Sub\a.gop` {
+		t.Fatal("Rel:", rel, ok)
+	}
+	if rel, ok := Rel(`\\host\share\proj`, `\\?\UNC\host\share\proj\a.gop`); !ok || rel != "a.gop" {
+		t.Fatal("Rel UNC:", rel, ok)
+	}
+	if _, ok := Rel(`C:\proj`, `D:\proj\a.gop`); ok {
+		t.Fatal("Rel: different volumes")
+	}
+	long := `C:\` + strings.Repeat(`long-directory-name\`, 20) + "a.gop"
+	if abs, err := Abs(`\\?\` + long); err != nil || abs != long {
+		t.Fatal("Abs:", abs, err)
+	}
+	if abs, err := Abs(`c:\a\..\b`); err != nil || abs != `C:\b` {
+		t.Fatal("Abs:", abs, err)
+	}
+}
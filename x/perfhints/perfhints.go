@@ -0,0 +1,280 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package perfhints turns escape analysis and inlining diagnostics of the Go
+// compiler (`go build -gcflags=-m`) on the generated Go code into performance
+// hints on Go+ source files.
+//
+// Generated Go code has //line directives, so the compiler already reports
+// Go+ files and lines, but columns are ones in the generated code. Parse
+// corrects them by looking for the subject of a diagnostic (eg. the function
+// of "inlining call to newPoint") in the Go+ source line.
+package perfhints
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/goplus/gop/token"
+)
+
+// -----------------------------------------------------------------------------
+
+// Hint is a performance hint on source code.
+type Hint struct {
+	Pos token.Position // Column is 0 if unknown
+	Msg string
+}
+
+// BuildFlags returns build flags args of the go command with -m added to
+// -gcflags, so that the compiler reports escape analysis and inlining
+// decisions.
+func BuildFlags(args []string) []string {
+	ret := make([]string, len(args), len(args)+1)
+	copy(ret, args)
+	for i, arg := range ret {
+		if v, ok := cutFlag(arg, "gcflags"); ok {
+			ret[i] = "-gcflags=" + strings.TrimSpace(v+" -m")
+			return ret
+		}
+	}
+	return append(ret, "-gcflags=-m")
+}
+
+func cutFlag(arg, name string) (string, bool) {
+	arg = strings.TrimPrefix(arg, "-")
+	if strings.HasPrefix(arg, "-") { // --gcflags
+		arg = arg[1:]
+	}
+	if strings.HasPrefix(arg, name+"=") {
+		return arg[len(name)+1:], true
+	}
+	return "", false
+}
+
+// -----------------------------------------------------------------------------
+
+var rePos = regexp.MustCompile(`^(.+?):(\d+):(\d+): (.*)$`)
+
+// kinds of diagnostics of escape analysis and inlining, with the subject of
+// each one.
+var (
+	reInline = regexp.MustCompile(`^(?:can inline|inlining call to) (\S+)`)
+	reHeap   = regexp.MustCompile(`^(?:moved to heap|leaking param(?: content)?|leaking closure reference): (\S+)`)
+	reEscape = regexp.MustCompile(`^(.+?) (?:escapes to heap|does not escape)$`)
+)
+
+// Parse parses output of `go build -gcflags=-m`. It returns performance hints
+// sorted by position, and the rest of output (eg. compile errors). Hints of
+// values which don't escape aren't returned as they need no action, nor hints
+// on generated Go files (gop_autogen*.go) which aren't mapped to Go+ source.
+// Relative file names in output are looked up in dirs to find source lines.
+func Parse(output []byte, dirs ...string) (hints []*Hint, rest []byte) {
+	var others [][]byte
+	seen := make(map[Hint]bool)
+	src := &sources{dirs: dirs, lines: make(map[string][]string)}
+	for _, line := range bytes.Split(output, []byte{'\n'}) {
+		m := rePos.FindSubmatch(line)
+		if m == nil {
+			if len(line) > 0 {
+				others = append(others, line)
+			}
+			continue
+		}
+		file, msg := string(m[1]), string(m[4])
+		subject, ok := subjectOf(msg)
+		if !ok {
+			others = append(others, line)
+			continue
+		}
+		if strings.HasSuffix(msg, "does not escape") || strings.HasPrefix(filepath.Base(file), "gop_autogen") {
+			continue
+		}
+		ln, _ := strconv.Atoi(string(m[2]))
+		col, _ := strconv.Atoi(string(m[3]))
+		h := Hint{Pos: token.Position{Filename: file, Line: ln}, Msg: msg}
+		h.Pos.Column = columnOf(src.line(file, ln), subject, col)
+		if h.Pos.Column == 0 && isExported(subject) { // Go+ calls Println as println
+			h.Pos.Column = columnOf(src.line(file, ln), strings.ToLower(subject[:1])+subject[1:], col)
+		}
+		if !seen[h] {
+			seen[h] = true
+			hints = append(hints, &h)
+		}
+	}
+	sort.SliceStable(hints, func(i, j int) bool {
+		a, b := hints[i].Pos, hints[j].Pos
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	if onlyHeaders(others) { // eg. "# example.com/foo" of packages with hints only
+		return hints, nil
+	}
+	for _, line := range others {
+		rest = append(rest, line...)
+		rest = append(rest, '\n')
+	}
+	return
+}
+
+func onlyHeaders(lines [][]byte) bool {
+	for _, line := range lines {
+		if !bytes.HasPrefix(line, []byte("# ")) {
+			return false
+		}
+	}
+	return true
+}
+
+// subjectOf returns what msg is about as it appears in source code (or ""
+// if unknown), and reports whether msg is a diagnostic of escape analysis or
+// inlining.
+func subjectOf(msg string) (string, bool) {
+	if m := reInline.FindStringSubmatch(msg); m != nil {
+		name := lastName(m[1])
+		if strings.HasPrefix(name, "func") && isDigits(name[4:]) { // closure, eg. main.func1
+			return "func", true
+		}
+		return name, true
+	}
+	if m := reHeap.FindStringSubmatch(msg); m != nil {
+		return m[1], true
+	}
+	if m := reEscape.FindStringSubmatch(msg); m != nil {
+		subject := m[1]
+		if i := strings.IndexByte(subject, '('); i > 0 { // call, eg. sum([]int{...})
+			return lastName(subject[:i]), true
+		}
+		if i := strings.Index(subject, "{...}"); i > 0 { // &Point{...}
+			return subject[:i+1], true
+		}
+		if subject == "func literal" {
+			return "func", true
+		}
+		if strings.HasPrefix(subject, "~") || strings.HasPrefix(subject, "...") { // temporaries
+			return "", true
+		}
+		return subject, true
+	}
+	return "", false
+}
+
+// lastName returns the last part of a qualified name, eg. Add of (*T).Add.
+func lastName(name string) string {
+	if i := strings.LastIndexByte(name, '.'); i >= 0 {
+		return name[i+1:]
+	}
+	return name
+}
+
+func isExported(name string) bool {
+	return name != "" && name[0] >= 'A' && name[0] <= 'Z'
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// columnOf returns the column of subject in line, the nearest one to col if
+// there are many, or 0 if not found.
+func columnOf(line, subject string, col int) int {
+	if subject == "" || line == "" {
+		return 0
+	}
+	ret, off := 0, 0
+	for {
+		i := strings.Index(line[off:], subject)
+		if i < 0 {
+			return ret
+		}
+		pos := off + i
+		off = pos + 1
+		if !isWordAt(line, pos, pos+len(subject)) {
+			continue
+		}
+		if c := pos + 1; ret == 0 || abs(c-col) < abs(ret-col) {
+			ret = c
+		}
+	}
+}
+
+// isWordAt reports whether line[pos:end] isn't a part of a longer identifier.
+func isWordAt(line string, pos, end int) bool {
+	return (pos == 0 || !isIdent(line[pos-1]) || !isIdent(line[pos])) &&
+		(end == len(line) || !isIdent(line[end]) || !isIdent(line[end-1]))
+}
+
+func isIdent(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// sources reads source lines of files.
+type sources struct {
+	dirs  []string
+	lines map[string][]string
+}
+
+func (p *sources) line(file string, n int) string {
+	lines, ok := p.lines[file]
+	if !ok {
+		lines = p.read(file)
+		p.lines[file] = lines
+	}
+	if n <= 0 || n > len(lines) {
+		return ""
+	}
+	return lines[n-1]
+}
+
+func (p *sources) read(file string) []string {
+	var names []string
+	if !filepath.IsAbs(file) {
+		for _, dir := range p.dirs {
+			names = append(names, filepath.Join(dir, file))
+		}
+	}
+	names = append(names, file)
+	for _, name := range names {
+		if b, err := os.ReadFile(name); err == nil {
+			return strings.Split(string(b), "\n")
+		}
+	}
+	return nil
+}
+
+// -----------------------------------------------------------------------------
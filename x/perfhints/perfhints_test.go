@@ -0,0 +1,100 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package perfhints_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/goplus/gop/x/perfhints"
+)
+
+func TestBuildFlags(t *testing.T) {
+	args := []string{"-o", "out", "-v=true"}
+	if ret := perfhints.BuildFlags(args); !reflect.DeepEqual(ret, []string{"-o", "out", "-v=true", "-gcflags=-m"}) {
+		t.Fatal("BuildFlags:", ret)
+	}
+	if ret := perfhints.BuildFlags([]string{"-gcflags=-N -l"}); !reflect.DeepEqual(ret, []string{"-gcflags=-N -l -m"}) {
+		t.Fatal("BuildFlags:", ret)
+	}
+	if args[2] != "-v=true" || len(args) != 3 {
+		t.Fatal("BuildFlags: args changed")
+	}
+}
+
+const src = `func newPoint(x, y int) *Point {
+	return &Point{x, y}
+}
+
+f := func(x int) int { return x * 2 }
+println newPoint(1, 2), sum([1, 2, 3]), f(3)
+`
+
+func TestParse(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "main.gop"), []byte(src), 0666); err != nil {
+		t.Fatal(err)
+	}
+	output := `# example.com/foo
+main.gop:1:6: can inline newPoint
+main.gop:5:7: can inline main.func1
+main.gop:6:22: inlining call to newPoint
+main.gop:6:13: inlining call to fmt.Println
+main.gop:2:9: &Point{...} escapes to heap
+main.gop:1:15: x does not escape
+main.gop:6:13: ... argument does not escape
+main.gop:6:33: sum([]int{...}) escapes to heap
+main.gop:6:22: &Point{...} escapes to heap
+gop_autogen.go:12:6: can inline Gop_Enum
+main.gop:6:22: inlining call to newPoint
+`
+	hints, rest := perfhints.Parse([]byte(output), dir)
+	if rest != nil {
+		t.Fatalf("Parse: rest = %q", rest)
+	}
+	var b strings.Builder
+	for _, h := range hints {
+		b.WriteString(h.Pos.String() + ": " + h.Msg + "\n")
+	}
+	if got := b.String(); got != `main.gop:1:6: can inline newPoint
+main.gop:2:9: &Point{...} escapes to heap
+main.gop:5:6: can inline main.func1
+main.gop:6: &Point{...} escapes to heap
+main.gop:6:1: inlining call to fmt.Println
+main.gop:6:9: inlining call to newPoint
+main.gop:6:25: sum([]int{...}) escapes to heap
+` {
+		t.Fatal("Parse:", got)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	output := `# example.com/foo
+main.gop:3:5: can inline foo
+main.gop:7:2: undefined: bar
+`
+	hints, rest := perfhints.Parse([]byte(output))
+	if len(hints) != 1 || hints[0].Pos.Column != 0 {
+		t.Fatal("Parse:", hints)
+	}
+	if string(rest) != "# example.com/foo\nmain.gop:7:2: undefined: bar\n" {
+		t.Fatalf("Parse: rest = %q", rest)
+	}
+}
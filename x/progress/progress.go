@@ -0,0 +1,158 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package progress reports progress of long operations of the gop command,
+// like `gop build ./...`, as machine-readable events in the NDJSON format
+// (one JSON object per line), so that IDEs and CI tools can show progress of
+// big builds.
+//
+// A build emits events of these phases in order:
+//   - "compile": a package was compiled into Go code;
+//   - "build", "install" or "test": the go command started (percent 0) or
+//     finished (percent 100);
+//   - "done": the operation finished, with an error or not.
+package progress
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/qiniu/x/errors"
+)
+
+// -----------------------------------------------------------------------------
+
+// An Event is a progress event.
+type Event struct {
+	Time    time.Time `json:"time"`
+	Phase   string    `json:"phase"`
+	Package string    `json:"package,omitempty"` // directory of the package
+	Percent int       `json:"percent"`           // progress of the phase, 0 to 100
+	Error   string    `json:"error,omitempty"`   // only in the "done" event
+}
+
+var (
+	mutex sync.Mutex
+	enc   *json.Encoder
+)
+
+// SetOutput makes events written to w. Reporting is disabled if w is nil.
+func SetOutput(w io.Writer) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	if w == nil {
+		enc = nil
+	} else {
+		enc = json.NewEncoder(w)
+	}
+}
+
+// Open makes events written to the file descriptor fd, eg. "3" for the one
+// opened by `3>progress.json` of the shell.
+func Open(fd string) error {
+	n, err := strconv.Atoi(fd)
+	if err != nil || n < 0 {
+		return fmt.Errorf("invalid file descriptor: %q", fd)
+	}
+	f := os.NewFile(uintptr(n), "json-progress")
+	if f == nil {
+		return fmt.Errorf("invalid file descriptor: %d", n)
+	}
+	if _, err = f.Stat(); err != nil {
+		return fmt.Errorf("invalid file descriptor %d: %v", n, err)
+	}
+	SetOutput(f)
+	return nil
+}
+
+// Enabled reports whether events are reported.
+func Enabled() bool {
+	mutex.Lock()
+	defer mutex.Unlock()
+	return enc != nil
+}
+
+// Report reports the event ev, if reporting is enabled.
+func Report(ev *Event) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	if enc == nil {
+		return
+	}
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+	enc.Encode(ev) // progress never breaks the command
+}
+
+// Hook returns a function to report "compile" events, which can be used as
+// gop.Config.OnProgress. It returns nil if reporting is disabled.
+func Hook() func(dir string, done, total int) {
+	if !Enabled() {
+		return nil
+	}
+	return func(dir string, done, total int) {
+		if abs, err := filepath.Abs(dir); err == nil {
+			dir = abs
+		}
+		Report(&Event{Phase: "compile", Package: dir, Percent: percent(done, total)})
+	}
+}
+
+// WrapRun returns a function to run go commands, which can be used as
+// gocmd.Config.Run, reporting the start and the end of phase. run is the
+// original one, and nil means (*exec.Cmd).Run. It returns run if reporting
+// is disabled.
+func WrapRun(phase string, run func(cmd *exec.Cmd) error) func(cmd *exec.Cmd) error {
+	if !Enabled() {
+		return run
+	}
+	if run == nil {
+		run = (*exec.Cmd).Run
+	}
+	return func(cmd *exec.Cmd) error {
+		Report(&Event{Phase: phase})
+		err := run(cmd)
+		Report(&Event{Phase: phase, Percent: 100})
+		return err
+	}
+}
+
+// Done reports the "done" event with err of the operation. The error message
+// is a summary of err, without stack of errors.Frame.
+func Done(err error) {
+	ev := &Event{Phase: "done", Percent: 100}
+	if err != nil {
+		ev.Error = errors.Summary(err)
+	}
+	Report(ev)
+}
+
+func percent(done, total int) int {
+	if total <= 0 || done >= total {
+		return 100
+	}
+	return done * 100 / total
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,101 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package progress
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func decodeEvents(t *testing.T, data []byte) (ret []Event) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for dec.More() {
+		var ev Event
+		if err := dec.Decode(&ev); err != nil {
+			t.Fatal("decode:", err)
+		}
+		if ev.Time.IsZero() {
+			t.Fatal("event without time:", ev)
+		}
+		ret = append(ret, ev)
+	}
+	return
+}
+
+func TestDisabled(t *testing.T) {
+	SetOutput(nil)
+	if Enabled() || Hook() != nil {
+		t.Fatal("reporting is enabled")
+	}
+	run := func(cmd *exec.Cmd) error { return nil }
+	if WrapRun("build", nil) != nil || WrapRun("build", run) == nil {
+		t.Fatal("WrapRun: run is wrapped")
+	}
+	Done(nil) // no output, no panic
+}
+
+func TestEvents(t *testing.T) {
+	var buf bytes.Buffer
+	SetOutput(&buf)
+	defer SetOutput(nil)
+
+	hook := Hook()
+	hook("a", 1, 3)
+	hook("b", 3, 3)
+	errBuild := errors.New("build failed")
+	run := WrapRun("build", func(cmd *exec.Cmd) error { return errBuild })
+	if err := run(nil); err != errBuild {
+		t.Fatal("WrapRun:", err)
+	}
+	Done(errBuild)
+
+	evs := decodeEvents(t, buf.Bytes())
+	abs, _ := filepath.Abs("a")
+	expected := []Event{
+		{Phase: "compile", Package: abs, Percent: 33},
+		{Phase: "compile", Package: filepath.Join(filepath.Dir(abs), "b"), Percent: 100},
+		{Phase: "build", Percent: 0},
+		{Phase: "build", Percent: 100},
+		{Phase: "done", Percent: 100, Error: "build failed"},
+	}
+	if len(evs) != len(expected) {
+		t.Fatalf("events: %v", evs)
+	}
+	for i, ev := range evs {
+		ev.Time = expected[i].Time
+		if ev != expected[i] {
+			t.Fatalf("event #%d: %v, expected %v", i, ev, expected[i])
+		}
+	}
+}
+
+func TestOpen(t *testing.T) {
+	defer SetOutput(nil)
+	if err := Open("x"); err == nil {
+		t.Fatal("Open x: no error")
+	}
+	if err := Open("12345"); err == nil {
+		t.Fatal("Open 12345: no error")
+	}
+	if err := Open("2"); err != nil || !Enabled() {
+		t.Fatal("Open 2:", err)
+	}
+}
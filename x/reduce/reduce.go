@@ -0,0 +1,85 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package reduce implements test-case reduction by delta debugging: it
+// shrinks a source file while preserving a failure of interest, eg. a panic
+// of the compiler.
+package reduce
+
+import (
+	"bytes"
+)
+
+// -----------------------------------------------------------------------------
+
+// Lines reduces src to a small subset of its lines for which interesting
+// still returns true, by the ddmin algorithm of delta debugging. It assumes
+// interesting(src) is true. The result is 1-minimal for lines: removing any
+// single line of it makes it uninteresting.
+//
+// interesting may be called many times (O(n²) in the worst case for n lines,
+// but usually much less). It can return false to stop reducing, eg. when its
+// time budget is used up, and Lines returns the smallest interesting source
+// found so far.
+func Lines(src []byte, interesting func(src []byte) bool) []byte {
+	lines := bytes.SplitAfter(src, []byte("\n"))
+	if n := len(lines); n > 0 && len(lines[n-1]) == 0 {
+		lines = lines[:n-1]
+	}
+	lines = ddmin(lines, func(lines [][]byte) bool {
+		return interesting(bytes.Join(lines, nil))
+	})
+	return bytes.Join(lines, nil)
+}
+
+// ddmin reduces items by removing chunks of them, with granularity n growing
+// from 2 to len(items), as long as test holds.
+func ddmin(items [][]byte, test func(items [][]byte) bool) [][]byte {
+	n := 2
+	for len(items) >= 2 {
+		size := (len(items) + n - 1) / n
+		reduced := false
+		for start := 0; start < len(items); start += size {
+			end := start + size
+			if end > len(items) {
+				end = len(items)
+			}
+			rest := make([][]byte, 0, len(items)-(end-start))
+			rest = append(append(rest, items[:start]...), items[end:]...)
+			if test(rest) {
+				items, reduced = rest, true
+				if n > 2 {
+					n--
+				}
+				break
+			}
+		}
+		if !reduced {
+			if n >= len(items) {
+				break
+			}
+			if n *= 2; n > len(items) {
+				n = len(items)
+			}
+		}
+	}
+	if len(items) == 1 && test(nil) {
+		return nil
+	}
+	return items
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,75 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package reduce
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestLines(t *testing.T) {
+	src := `import "fmt"
+
+func f() {
+	a := 1
+	b := 2
+	crash(a)
+	fmt.println b
+}
+
+f()
+`
+	var tests int
+	ret := Lines([]byte(src), func(src []byte) bool {
+		tests++
+		return bytes.Contains(src, []byte("crash(")) && bytes.Contains(src, []byte("func f"))
+	})
+	if s := string(ret); s != "func f() {\n\tcrash(a)\n" {
+		t.Fatalf("Lines: %q", s)
+	}
+	if tests > 40 {
+		t.Fatal("Lines: too many tests:", tests)
+	}
+}
+
+func TestLinesBudget(t *testing.T) {
+	src := strings.Repeat("x\n", 100) + "crash\n"
+	var tests int
+	ret := Lines([]byte(src), func(src []byte) bool {
+		if tests++; tests > 3 { // budget is used up
+			return false
+		}
+		return bytes.Contains(src, []byte("crash"))
+	})
+	if !bytes.Contains(ret, []byte("crash")) || len(ret) >= len(src) {
+		t.Fatalf("Lines: %q", ret)
+	}
+}
+
+func TestLinesEdge(t *testing.T) {
+	always := func(src []byte) bool { return true }
+	if ret := Lines(nil, always); len(ret) != 0 {
+		t.Fatal("Lines nil:", ret)
+	}
+	if ret := Lines([]byte("a\nb"), always); len(ret) != 0 {
+		t.Fatalf("Lines always: %q", ret)
+	}
+	if ret := Lines([]byte("a\nb"), func(src []byte) bool { return len(src) > 0 }); string(ret) != "b" && string(ret) != "a\n" {
+		t.Fatalf("Lines: %q", ret)
+	}
+}
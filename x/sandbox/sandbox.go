@@ -0,0 +1,141 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package sandbox implements executing untrusted programs under restrictions:
+// no network, read-only file system paths, and CPU/memory/time limits. It
+// uses OS primitives where available (namespaces and rlimits on Linux, rlimits
+// on macOS, FreeBSD, NetBSD and DragonFly), and reports an error if a
+// restriction isn't supported on the current OS.
+//
+// A sandboxed program is started by a launcher, that is a program calling
+// Main at the beginning of its main function (eg. the gop command):
+//
+//	launcher program [arguments...]
+//
+// with the environment variable returned by Config.Environ.
+package sandbox
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// -----------------------------------------------------------------------------
+
+// Config represents restrictions of a sandbox.
+type Config struct {
+	NoNetwork bool          `json:"nonet,omitempty"`   // disable network access
+	ReadOnly  []string      `json:"ro,omitempty"`      // read-only file system paths
+	CPUTime   time.Duration `json:"cpu,omitempty"`     // limit of CPU time
+	Memory    uint64        `json:"mem,omitempty"`     // limit of memory (address space) in bytes
+	Timeout   time.Duration `json:"timeout,omitempty"` // limit of wall clock time
+}
+
+var (
+	// ErrUnsupported is returned if a restriction isn't supported on the current OS.
+	ErrUnsupported = errors.New("sandbox: restriction not supported on this OS")
+)
+
+const (
+	envConfig = "GOP_SANDBOX"
+	envStage  = "GOP_SANDBOX_STAGE"
+)
+
+// ExitTimeout is the exit code of the launcher if the program is killed
+// because of the time limit.
+const ExitTimeout = 124
+
+// Environ returns the environment variable (in form of "key=value") to start
+// a launcher with restrictions of this sandbox.
+func (p *Config) Environ() string {
+	b, err := json.Marshal(p)
+	if err != nil {
+		panic(err)
+	}
+	return envConfig + "=" + string(b)
+}
+
+// Main runs the launcher if the current process is started as one, and never
+// returns in this case. Otherwise it returns immediately.
+func Main() {
+	data, ok := os.LookupEnv(envConfig)
+	if !ok {
+		return
+	}
+	if len(os.Args) < 2 {
+		fatal(errors.New("sandbox: no program to run"))
+	}
+	conf := new(Config)
+	if err := json.Unmarshal([]byte(data), conf); err != nil {
+		fatal(fmt.Errorf("sandbox: invalid config: %v", err))
+	}
+	if os.Getenv(envStage) == "" {
+		os.Exit(launch(conf))
+	}
+	os.Unsetenv(envConfig)
+	os.Unsetenv(envStage)
+	if err := restrict(conf); err != nil {
+		fatal(err)
+	}
+	fatal(execProgram(os.Args[1], os.Args[1:]))
+}
+
+// launch starts this launcher again in a new process (with namespaces on
+// Linux) to apply restrictions, and waits for it with the time limit.
+func launch(conf *Config) int {
+	attr, err := sysProcAttr(conf)
+	if err != nil {
+		fatal(err)
+	}
+	self, err := os.Executable()
+	if err != nil {
+		fatal(err)
+	}
+	cmd := exec.Command(self, os.Args[1:]...)
+	cmd.Env = append(os.Environ(), envStage+"=1")
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.SysProcAttr = attr
+	if err = cmd.Start(); err != nil {
+		fatal(err)
+	}
+	if conf.Timeout > 0 {
+		timer := time.AfterFunc(conf.Timeout, func() {
+			fmt.Fprintln(os.Stderr, "sandbox: time limit exceeded")
+			cmd.Process.Kill()
+			os.Exit(ExitTimeout)
+		})
+		defer timer.Stop()
+	}
+	cmd.Wait()
+	state := cmd.ProcessState
+	if ws, ok := state.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+		fmt.Fprintln(os.Stderr, "sandbox:", state)
+		return 128 + int(ws.Signal())
+	}
+	return state.ExitCode()
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,35 @@
+//go:build darwin || dragonfly || freebsd || netbsd
+// +build darwin dragonfly freebsd netbsd
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sandbox
+
+import (
+	"syscall"
+)
+
+func sysProcAttr(conf *Config) (*syscall.SysProcAttr, error) {
+	if conf.NoNetwork || conf.ReadOnly != nil {
+		return nil, ErrUnsupported
+	}
+	return nil, nil
+}
+
+func restrict(conf *Config) error {
+	return setrlimits(conf)
+}
@@ -0,0 +1,103 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sandbox
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+func sysProcAttr(conf *Config) (*syscall.SysProcAttr, error) {
+	if !useNamespaces(conf) {
+		return nil, nil
+	}
+	// The launcher runs as root in a new user namespace to get capabilities to
+	// mount. It drops all capabilities before executing the program.
+	flags := uintptr(syscall.CLONE_NEWUSER)
+	if conf.NoNetwork {
+		flags |= syscall.CLONE_NEWNET
+	}
+	if conf.ReadOnly != nil {
+		flags |= syscall.CLONE_NEWNS
+	}
+	return &syscall.SysProcAttr{
+		Cloneflags:  flags,
+		UidMappings: []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getuid(), Size: 1}},
+		GidMappings: []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getgid(), Size: 1}},
+	}, nil
+}
+
+func useNamespaces(conf *Config) bool {
+	return conf.NoNetwork || conf.ReadOnly != nil
+}
+
+func restrict(conf *Config) (err error) {
+	if conf.ReadOnly != nil {
+		if err = unix.Mount("", "/", "", unix.MS_REC|unix.MS_PRIVATE, ""); err != nil {
+			return fmt.Errorf("sandbox: make mounts private: %v", err)
+		}
+		for _, dir := range conf.ReadOnly {
+			if err = readOnly(dir); err != nil {
+				return fmt.Errorf("sandbox: read-only %s: %v", dir, err)
+			}
+		}
+	}
+	if err = setrlimits(conf); err != nil {
+		return
+	}
+	if useNamespaces(conf) {
+		if err = dropCapabilities(); err != nil {
+			return fmt.Errorf("sandbox: drop capabilities: %v", err)
+		}
+	}
+	return unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0)
+}
+
+// dropCapabilities drops all capabilities from the bounding set, so that the
+// program (running as root in the user namespace) gets no capability.
+func dropCapabilities() error {
+	for c := 0; ; c++ {
+		if err := unix.Prctl(unix.PR_CAPBSET_DROP, uintptr(c), 0, 0, 0); err != nil {
+			if err == unix.EINVAL { // no more capabilities
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+func readOnly(dir string) (err error) {
+	if err = unix.Mount(dir, dir, "", unix.MS_BIND|unix.MS_REC, ""); err != nil {
+		return
+	}
+	attr := &unix.MountAttr{Attr_set: unix.MOUNT_ATTR_RDONLY}
+	err = unix.MountSetattr(-1, dir, unix.AT_RECURSIVE, attr)
+	if err != unix.ENOSYS {
+		return
+	}
+	// fallback for kernels before 5.12 (submounts are left as they are)
+	var st unix.Statfs_t
+	if err = unix.Statfs(dir, &st); err != nil {
+		return
+	}
+	flags := uintptr(unix.MS_BIND | unix.MS_REMOUNT | unix.MS_RDONLY)
+	flags |= uintptr(st.Flags) & (unix.MS_NOSUID | unix.MS_NODEV | unix.MS_NOEXEC | unix.MS_NOATIME | unix.MS_RELATIME)
+	return unix.Mount("", dir, "", flags, "")
+}
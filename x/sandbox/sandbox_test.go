@@ -0,0 +1,86 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sandbox_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goplus/gop/x/sandbox"
+)
+
+func TestMain(m *testing.M) {
+	sandbox.Main() // the test binary serves as the launcher
+	os.Exit(m.Run())
+}
+
+func run(conf *sandbox.Config, script string) (string, int) {
+	cmd := exec.Command(os.Args[0], "/bin/sh", "-c", script)
+	cmd.Env = append(os.Environ(), conf.Environ())
+	out, _ := cmd.CombinedOutput()
+	return string(out), cmd.ProcessState.ExitCode()
+}
+
+func TestTimeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no /bin/sh")
+	}
+	out, code := run(&sandbox.Config{Timeout: 100 * time.Millisecond}, "echo hello; exec sleep 5")
+	if code != sandbox.ExitTimeout || !strings.Contains(out, "hello\nsandbox: time limit exceeded") {
+		t.Fatal("TestTimeout:", code, out)
+	}
+	out, code = run(&sandbox.Config{}, "echo hello; exit 3")
+	if code != 3 || out != "hello\n" {
+		t.Fatal("TestTimeout:", code, out)
+	}
+}
+
+func TestRestrictions(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("namespaces are only supported on Linux")
+	}
+	if exec.Command("unshare", "-Ur", "true").Run() != nil {
+		t.Skip("user namespaces are not available")
+	}
+	dir := t.TempDir()
+	conf := &sandbox.Config{NoNetwork: true, ReadOnly: []string{dir}}
+	out, code := run(conf, "cat /proc/net/dev")
+	if code != 0 || strings.Contains(out, "eth") || !strings.Contains(out, "lo:") {
+		t.Fatal("NoNetwork:", code, out)
+	}
+	file := filepath.Join(dir, "foo.txt")
+	out, code = run(conf, "echo hi > "+file)
+	if code == 0 {
+		t.Fatal("ReadOnly: write succeeded?", out)
+	}
+	if _, err := os.Stat(file); err == nil {
+		t.Fatal("ReadOnly: file created?")
+	}
+	out, code = run(conf, "grep CapEff /proc/self/status")
+	if code != 0 || out != "CapEff:\t0000000000000000\n" {
+		t.Fatal("capabilities not dropped:", code, out)
+	}
+	out, code = run(&sandbox.Config{CPUTime: time.Second}, "ulimit -t")
+	if code != 0 || out != "1\n" {
+		t.Fatal("CPUTime:", code, out)
+	}
+}
@@ -0,0 +1,52 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd
+// +build linux darwin dragonfly freebsd netbsd
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sandbox
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+func setrlimits(conf *Config) error {
+	if conf.CPUTime > 0 {
+		secs := uint64((conf.CPUTime + 999999999) / 1000000000)
+		if err := unix.Setrlimit(unix.RLIMIT_CPU, newRlimit(secs)); err != nil {
+			return fmt.Errorf("sandbox: limit CPU time: %v", err)
+		}
+	}
+	if mem := conf.Memory; mem > 0 {
+		if err := unix.Setrlimit(unix.RLIMIT_AS, newRlimit(mem)); err != nil {
+			return fmt.Errorf("sandbox: limit memory: %v", err)
+		}
+	}
+	return nil
+}
+
+func execProgram(name string, args []string) error {
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return err
+	}
+	return syscall.Exec(path, args, os.Environ())
+}
@@ -0,0 +1,254 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package service implements the runtime of the web service classfile.
+// Register it in gop.mod:
+//
+//	project .gsvc App github.com/goplus/gop/x/service
+//
+// Then routes can be declared in a main.gsvc file like this:
+//
+//	timeout 5s
+//
+//	get "/users/:id", ctx => {
+//		user, err := queryUser(ctx, ctx.param("id"))
+//		if err != nil {
+//			ctx.error 404, err
+//			return
+//		}
+//		ctx.json user
+//	}
+//
+// The ctx of a handler is the context.Context of the request: it is canceled
+// when the client goes away or the timeout of the service expires, and it can
+// be passed as is to any function which accepts a context. “gop vet” reports
+// handlers which drop it (eg. by calling context.Background).
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	GopPackage = true // to indicate this is a Go+ package
+)
+
+// -----------------------------------------------------------------------------
+
+// Context is the context of a request. It is also the context.Context of the
+// request.
+type Context struct {
+	context.Context
+
+	ResponseWriter http.ResponseWriter
+	Request        *http.Request
+
+	params map[string]string
+	status int
+}
+
+// Param returns the value of the path parameter name (declared by :name or
+// *name in the pattern of the route), or the query parameter name if there
+// is no such path parameter.
+func (p *Context) Param(name string) string {
+	if v, ok := p.params[name]; ok {
+		return v
+	}
+	return p.Request.URL.Query().Get(name)
+}
+
+// Status sets the status code of the response (default is 200).
+func (p *Context) Status(code int) {
+	p.status = code
+}
+
+// Text writes text as the response body.
+func (p *Context) Text(text string) {
+	p.write("text/plain; charset=utf-8", []byte(text))
+}
+
+// Json writes v in JSON as the response body.
+func (p *Context) Json(v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		p.Error(http.StatusInternalServerError, err)
+		return
+	}
+	p.write("application/json", b)
+}
+
+// Error writes err as the response with the status code.
+func (p *Context) Error(code int, err error) {
+	http.Error(p.ResponseWriter, err.Error(), code)
+}
+
+func (p *Context) write(contentType string, body []byte) {
+	w := p.ResponseWriter
+	w.Header().Set("Content-Type", contentType)
+	if p.status != 0 {
+		w.WriteHeader(p.status)
+	}
+	w.Write(body)
+}
+
+// -----------------------------------------------------------------------------
+
+type route struct {
+	method string
+	segs   []string
+	fn     func(ctx *Context)
+}
+
+func (r *route) match(segs []string) (params map[string]string, ok bool) {
+	for i, seg := range r.segs {
+		switch {
+		case strings.HasPrefix(seg, "*"):
+			return setParam(params, seg[1:], strings.Join(segs[i:], "/")), true
+		case i >= len(segs):
+			return nil, false
+		case strings.HasPrefix(seg, ":"):
+			params = setParam(params, seg[1:], segs[i])
+		case seg != segs[i]:
+			return nil, false
+		}
+	}
+	return params, len(segs) == len(r.segs)
+}
+
+func setParam(params map[string]string, name, val string) map[string]string {
+	if params == nil {
+		params = make(map[string]string)
+	}
+	params[name] = val
+	return params
+}
+
+func splitPath(path string) []string {
+	return strings.Split(strings.Trim(path, "/"), "/")
+}
+
+// App is the project class of the web service classfile.
+type App struct {
+	routes  []*route
+	timeout time.Duration
+}
+
+func (p *App) app() *App {
+	return p
+}
+
+// Handle declares a route of method for requests matching pattern. A segment
+// :name of pattern matches any segment, and a last segment *name matches the
+// rest of the path. Their values are returned by ctx.param(name).
+func (p *App) Handle(method, pattern string, fn func(ctx *Context)) {
+	p.routes = append(p.routes, &route{method: method, segs: splitPath(pattern), fn: fn})
+}
+
+// Get declares a route of GET requests.
+func (p *App) Get(pattern string, fn func(ctx *Context)) {
+	p.Handle(http.MethodGet, pattern, fn)
+}
+
+// Post declares a route of POST requests.
+func (p *App) Post(pattern string, fn func(ctx *Context)) {
+	p.Handle(http.MethodPost, pattern, fn)
+}
+
+// Put declares a route of PUT requests.
+func (p *App) Put(pattern string, fn func(ctx *Context)) {
+	p.Handle(http.MethodPut, pattern, fn)
+}
+
+// Delete declares a route of DELETE requests.
+func (p *App) Delete(pattern string, fn func(ctx *Context)) {
+	p.Handle(http.MethodDelete, pattern, fn)
+}
+
+// Timeout sets the time limit of handling a request. The context of a
+// request is canceled when it expires.
+func (p *App) Timeout(d time.Duration) {
+	p.timeout = d
+}
+
+// ServeHTTP handles a request by the first route matching it.
+func (p *App) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	segs := splitPath(req.URL.Path)
+	allowed := false
+	for _, r := range p.routes {
+		params, ok := r.match(segs)
+		if !ok {
+			continue
+		}
+		if r.method != req.Method {
+			allowed = true
+			continue
+		}
+		ctx := req.Context()
+		if p.timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, p.timeout)
+			defer cancel()
+		}
+		r.fn(&Context{Context: ctx, ResponseWriter: w, Request: req, params: params})
+		return
+	}
+	if allowed {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	http.NotFound(w, req)
+}
+
+// Run listens on the TCP network address addr (default is :8080) and serves
+// requests.
+func (p *App) Run(addr ...string) error {
+	a := ":8080"
+	if len(addr) > 0 {
+		a = addr[0]
+	}
+	return http.ListenAndServe(a, p)
+}
+
+// -----------------------------------------------------------------------------
+
+type iApp interface {
+	MainEntry()
+	app() *App
+}
+
+// Gopt_App_Main is the main entry of the web service classfile.
+func Gopt_App_Main(a iApp) {
+	p := a.app()
+	a.MainEntry()
+
+	flags := flag.NewFlagSet(filepath.Base(os.Args[0]), flag.ExitOnError)
+	addr := flags.String("addr", ":8080", "TCP network address to listen on")
+	flags.Parse(os.Args[1:])
+	if err := p.Run(*addr); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func serve(app *App, method, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+	return w
+}
+
+func TestRoutes(t *testing.T) {
+	app := new(App)
+	app.Get("/users/:id", func(ctx *Context) {
+		ctx.Text("user " + ctx.Param("id") + " " + ctx.Param("q"))
+	})
+	app.Post("/users", func(ctx *Context) {
+		ctx.Status(http.StatusCreated)
+		ctx.Json(map[string]int{"id": 1})
+	})
+	app.Get("/files/*path", func(ctx *Context) {
+		ctx.Text(ctx.Param("path"))
+	})
+	if w := serve(app, "GET", "/users/7?q=x"); w.Body.String() != "user 7 x" {
+		t.Fatal("GET /users/7:", w.Code, w.Body)
+	}
+	if w := serve(app, "POST", "/users"); w.Code != http.StatusCreated || w.Body.String() != `{"id":1}` {
+		t.Fatal("POST /users:", w.Code, w.Body)
+	}
+	if w := serve(app, "GET", "/files/a/b.txt"); w.Body.String() != "a/b.txt" {
+		t.Fatal("GET /files/a/b.txt:", w.Code, w.Body)
+	}
+	if w := serve(app, "DELETE", "/users/7"); w.Code != http.StatusMethodNotAllowed {
+		t.Fatal("DELETE /users/7:", w.Code)
+	}
+	if w := serve(app, "GET", "/users/7/x"); w.Code != http.StatusNotFound {
+		t.Fatal("GET /users/7/x:", w.Code)
+	}
+}
+
+func TestContext(t *testing.T) {
+	app := new(App)
+	app.Timeout(time.Millisecond)
+	app.Get("/slow", func(ctx *Context) {
+		var c context.Context = ctx
+		<-c.Done()
+		ctx.Error(http.StatusGatewayTimeout, c.Err())
+	})
+	w := serve(app, "GET", "/slow")
+	if w.Code != http.StatusGatewayTimeout || w.Body.String() != context.DeadlineExceeded.Error()+"\n" {
+		t.Fatal("GET /slow:", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,70 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package shell implements the runtime of the shell script classfile, whose
+// scripts have interactive helpers of builtin/tty in scope. Register it in
+// gop.mod:
+//
+//	project .gsh App github.com/goplus/gop/x/shell
+//
+// Then a main.gsh script can prompt the user like this:
+//
+//	name := ask("name? ")
+//	if confirm("Continue? [y/N] ") {
+//		println name, password("password: ")
+//	}
+package shell
+
+import (
+	"github.com/goplus/gop/builtin/tty"
+)
+
+const (
+	GopPackage = true // to indicate this is a Go+ package
+)
+
+// -----------------------------------------------------------------------------
+
+// App is the project class of the shell script classfile.
+type App struct {
+}
+
+// Ask prints prompt and returns the answer (see tty.Ask).
+func (p *App) Ask(prompt string) string {
+	return tty.Ask(prompt)
+}
+
+// Confirm prints prompt and reads a yes/no answer (see tty.Confirm).
+func (p *App) Confirm(prompt string) bool {
+	return tty.Confirm(prompt)
+}
+
+// Password prints prompt and reads a line without echoing it (see
+// tty.Password).
+func (p *App) Password(prompt string) string {
+	return tty.Password(prompt)
+}
+
+type iApp interface {
+	MainEntry()
+}
+
+// Gopt_App_Main is the main entry of the shell script classfile.
+func Gopt_App_Main(a iApp) {
+	a.MainEntry()
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,64 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package shell_test
+
+import (
+	"testing"
+
+	"github.com/goplus/gop/clstest"
+	"github.com/goplus/mod/modfile"
+)
+
+var shellProj = &modfile.Project{
+	Ext: ".gsh", Class: "App",
+	PkgPaths: []string{"github.com/goplus/gop/x/shell"},
+}
+
+func TestPrompts(t *testing.T) {
+	clstest.Expect(t, shellProj, clstest.Files{"main.gsh": `
+name := ask("name? ")
+if confirm("Continue? [y/N] ") {
+	println name, password("password: ")
+}
+`}, `package main
+
+import (
+	"fmt"
+	"github.com/goplus/gop/x/shell"
+)
+
+type App struct {
+	shell.App
+}
+
+func (this *App) MainEntry() {
+	name := this.Ask("name? ")
+	if this.Confirm("Continue? [y/N] ") {
+		fmt.Println(name, this.Password("password: "))
+	}
+}
+func main() {
+	shell.Gopt_App_Main(new(App))
+}
+`)
+}
+
+func TestNotInScope(t *testing.T) {
+	clstest.ExpectError(t, nil, clstest.Files{"main.gop": `
+println ask("name? ")
+`}, `main.gop:2:9: undefined: ask`)
+}
@@ -0,0 +1,200 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package stats records local usage statistics of the gop command: compile
+// counts, durations and error codes. It's opt-in (see EnvStats) and the
+// statistics are only saved to a local file, never uploaded.
+package stats
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/goplus/gop/cl"
+	"github.com/goplus/gop/scanner"
+	"github.com/goplus/gop/x/explain"
+	"github.com/goplus/gox"
+	xerrors "github.com/qiniu/x/errors"
+)
+
+// -----------------------------------------------------------------------------
+
+// EnvStats is the environment variable to enable usage statistics: "on"
+// means to save them to the default file (see File), other non-empty values
+// except "off" specify path of the file.
+const EnvStats = "GOP_STATS"
+
+// Record represents a compile.
+type Record struct {
+	Time     time.Time     `json:"time"`
+	Cmd      string        `json:"cmd"`            // gop command, eg. "build"
+	Dir      string        `json:"dir"`            // package directory (or file)
+	Duration time.Duration `json:"dur"`            // time of compiling
+	Errors   []string      `json:"errs,omitempty"` // error codes
+}
+
+// File returns the statistics file and if statistics are enabled.
+func File() (file string, ok bool) {
+	switch v := os.Getenv(EnvStats); v {
+	case "", "off":
+		return
+	case "on":
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return
+		}
+		return filepath.Join(dir, "gop", "stats.jsonl"), true
+	default:
+		return v, true
+	}
+}
+
+// Add appends a record to the statistics file.
+func Add(file string, rec *Record) (err error) {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return
+	}
+	if err = os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return
+	}
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return
+	}
+	_, err = f.Write(append(b, '\n'))
+	if e := f.Close(); err == nil {
+		err = e
+	}
+	return
+}
+
+// Load loads records from the statistics file. Broken lines are skipped.
+func Load(file string) (recs []*Record, err error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		rec := new(Record)
+		if json.Unmarshal(scanner.Bytes(), rec) == nil {
+			recs = append(recs, rec)
+		}
+	}
+	return recs, scanner.Err()
+}
+
+// Hook returns a function to record compiles of the gop command cmd, which
+// can be used as gop.Config.OnCompiled. It returns nil if statistics are
+// disabled.
+func Hook(cmd string) func(dir string, dur time.Duration, err error) {
+	file, ok := File()
+	if !ok {
+		return nil
+	}
+	return func(dir string, dur time.Duration, err error) {
+		if abs, e := filepath.Abs(dir); e == nil {
+			dir = abs
+		}
+		rec := &Record{Time: time.Now(), Cmd: cmd, Dir: dir, Duration: dur, Errors: ErrorCodes(err)}
+		Add(file, rec) // statistics never break the command
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+// ErrorCodes returns codes of errors in err.
+func ErrorCodes(err error) (codes []string) {
+	if err == nil {
+		return
+	}
+	var list xerrors.List
+	if errors.As(err, &list) {
+		for _, e := range list {
+			codes = append(codes, ErrorCodes(e)...)
+		}
+		return
+	}
+	var serrs scanner.ErrorList
+	if errors.As(err, &serrs) {
+		for range serrs {
+			codes = append(codes, "syntax")
+		}
+		return
+	}
+	return append(codes, errorCode(err))
+}
+
+func errorCode(err error) string {
+	if _, ok := err.(*gox.ImportError); ok {
+		return "import"
+	}
+	if code := explain.Code(err); code != "" {
+		return code
+	}
+	switch err.(type) {
+	case *gox.MatchError:
+		return "type-mismatch"
+	case *cl.Diagnostic, *gox.CodeError:
+		return "compile"
+	}
+	return "other"
+}
+
+// -----------------------------------------------------------------------------
+
+// Summary summarizes records.
+type Summary struct {
+	Count  int
+	Failed int
+	Total  time.Duration
+	P50    time.Duration
+	P95    time.Duration
+	Max    time.Duration
+	Cmds   map[string]int // gop command => count
+	Errors map[string]int // error code => count
+}
+
+// Summarize summarizes records.
+func Summarize(recs []*Record) *Summary {
+	ret := &Summary{Count: len(recs), Cmds: make(map[string]int), Errors: make(map[string]int)}
+	durs := make([]time.Duration, len(recs))
+	for i, rec := range recs {
+		durs[i] = rec.Duration
+		ret.Total += rec.Duration
+		ret.Cmds[rec.Cmd]++
+		if rec.Errors != nil {
+			ret.Failed++
+		}
+		for _, code := range rec.Errors {
+			ret.Errors[code]++
+		}
+	}
+	if n := len(durs); n > 0 {
+		sort.Slice(durs, func(i, j int) bool { return durs[i] < durs[j] })
+		ret.P50, ret.P95, ret.Max = durs[(n-1)*50/100], durs[(n-1)*95/100], durs[n-1]
+	}
+	return ret
+}
+
+// -----------------------------------------------------------------------------
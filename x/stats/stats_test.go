@@ -0,0 +1,75 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package stats_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/goplus/gop/scanner"
+	"github.com/goplus/gop/token"
+	"github.com/goplus/gop/x/stats"
+	"github.com/goplus/gox"
+	xerrors "github.com/qiniu/x/errors"
+)
+
+func TestFile(t *testing.T) {
+	t.Setenv(stats.EnvStats, "off")
+	if _, ok := stats.File(); ok {
+		t.Fatal("stats enabled?")
+	}
+	if stats.Hook("build") != nil {
+		t.Fatal("Hook: not nil")
+	}
+	t.Setenv(stats.EnvStats, "/foo/stats.jsonl")
+	if file, ok := stats.File(); !ok || file != "/foo/stats.jsonl" {
+		t.Fatal("File:", file, ok)
+	}
+}
+
+func TestHook(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "gop", "stats.jsonl")
+	t.Setenv(stats.EnvStats, file)
+	hook := stats.Hook("build")
+	hook("/foo", time.Second, nil)
+	hook("/foo", 3*time.Second, xerrors.List{
+		scanner.ErrorList{{Msg: "expected ')'"}},
+		&gox.CodeError{Fset: token.NewFileSet(), Pos: token.NoPos, Msg: "undefined: b"},
+		errors.New("unknown"),
+	})
+	hook("/bar", 2*time.Second, &gox.ImportError{Err: os.ErrNotExist})
+	recs, err := stats.Load(file)
+	if err != nil || len(recs) != 3 {
+		t.Fatal("Load:", recs, err)
+	}
+	if recs[0].Dir != "/foo" || recs[1].Duration != 3*time.Second || recs[2].Cmd != "build" {
+		t.Fatal("Load:", recs[0], recs[1], recs[2])
+	}
+	sum := stats.Summarize(recs)
+	if sum.Count != 3 || sum.Failed != 2 || sum.Total != 6*time.Second ||
+		sum.P50 != 2*time.Second || sum.Max != 3*time.Second {
+		t.Fatal("Summarize:", sum)
+	}
+	errs := map[string]int{"syntax": 1, "undefined": 1, "other": 1, "import": 1}
+	if !reflect.DeepEqual(sum.Errors, errs) || sum.Cmds["build"] != 3 {
+		t.Fatal("Summarize:", sum.Errors, sum.Cmds)
+	}
+}
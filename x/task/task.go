@@ -0,0 +1,347 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+// Package task implements the runtime of the task runner classfile, a typed
+// replacement of Makefiles. Register it in gop.mod:
+//
+//	project .gopt App github.com/goplus/gop/x/task
+//
+// Then tasks can be declared in a main.gopt file like this:
+//
+//	task "vet", => {
+//		...
+//	}
+//
+//	task "build", ["vet"], => {
+//		...
+//	}
+//	inputs "build", "*.gop", "go.mod"
+//
+// Running the program executes the specified tasks (or the "default" task)
+// after their dependencies. Independent dependencies run in parallel, and a
+// task that declares its inputs is skipped if none of them changed since its
+// last successful run.
+package task
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	GopPackage = true // to indicate this is a Go+ package
+)
+
+// HashFile is the file where hashes of task inputs are stored.
+const HashFile = ".gopt.hash"
+
+var (
+	ErrCycle = errors.New("dependency cycle")
+)
+
+// -----------------------------------------------------------------------------
+
+type taskDef struct {
+	name   string
+	deps   []string
+	inputs []string
+	fn     func()
+}
+
+// App is the project class of the task runner classfile.
+type App struct {
+	tasks map[string]*taskDef
+	names []string // in declaration order
+
+	// Stdout is where the runner writes its logs (default is os.Stdout).
+	Stdout io.Writer
+}
+
+func (p *App) app() *App {
+	return p
+}
+
+func (p *App) define(name string) *taskDef {
+	if p.tasks == nil {
+		p.tasks = make(map[string]*taskDef)
+	}
+	t, ok := p.tasks[name]
+	if !ok {
+		t = &taskDef{name: name}
+		p.tasks[name] = t
+		p.names = append(p.names, name)
+	}
+	return t
+}
+
+// Task__0 declares a task without dependencies.
+func (p *App) Task__0(name string, fn func()) {
+	p.define(name).fn = fn
+}
+
+// Task__1 declares a task which runs after its dependencies.
+func (p *App) Task__1(name string, deps []string, fn func()) {
+	t := p.define(name)
+	t.deps, t.fn = deps, fn
+}
+
+// Inputs declares input files (glob patterns) of a task. The task is skipped
+// if none of its inputs changed since its last successful run.
+func (p *App) Inputs(name string, patterns ...string) {
+	t := p.define(name)
+	t.inputs = append(t.inputs, patterns...)
+}
+
+// Tasks returns names of all declared tasks in declaration order.
+func (p *App) Tasks() []string {
+	return p.names
+}
+
+// -----------------------------------------------------------------------------
+
+// Config represents options of running tasks.
+type Config struct {
+	Parallel int  // max number of tasks running at the same time (default is GOMAXPROCS)
+	Force    bool // run tasks even if their inputs are unchanged
+}
+
+// Run runs the specified tasks and their dependencies. If no task is
+// specified, the "default" task is run.
+func (p *App) Run(conf *Config, names ...string) (err error) {
+	if conf == nil {
+		conf = new(Config)
+	}
+	if len(names) == 0 {
+		names = []string{"default"}
+	}
+	for _, name := range names {
+		if err = p.check(name, nil); err != nil {
+			return
+		}
+	}
+	n := conf.Parallel
+	if n <= 0 {
+		n = runtime.GOMAXPROCS(0)
+	}
+	r := &runner{
+		app: p, conf: conf, sem: make(chan struct{}, n),
+		states: make(map[string]*taskState), hashes: loadHashes(),
+	}
+	for _, name := range names {
+		if err = r.run(name); err != nil {
+			break
+		}
+	}
+	if r.hashChanged {
+		saveHashes(r.hashes)
+	}
+	return
+}
+
+func (p *App) check(name string, stack []string) error {
+	for i, v := range stack {
+		if v == name {
+			return fmt.Errorf("%w: %s", ErrCycle, strings.Join(append(stack[i:], name), " -> "))
+		}
+	}
+	t, ok := p.tasks[name]
+	if !ok {
+		return fmt.Errorf("task %s not found", name)
+	}
+	stack = append(stack, name)
+	for _, dep := range t.deps {
+		if err := p.check(dep, stack); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *App) logf(format string, args ...interface{}) {
+	w := p.Stdout
+	if w == nil {
+		w = os.Stdout
+	}
+	fmt.Fprintf(w, format, args...)
+}
+
+type taskState struct {
+	once sync.Once
+	err  error
+}
+
+type runner struct {
+	app    *App
+	conf   *Config
+	sem    chan struct{}
+	mutex  sync.Mutex
+	states map[string]*taskState
+	hashes map[string]string
+
+	hashChanged bool
+}
+
+func (r *runner) state(name string) *taskState {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	st, ok := r.states[name]
+	if !ok {
+		st = new(taskState)
+		r.states[name] = st
+	}
+	return st
+}
+
+func (r *runner) run(name string) error {
+	st := r.state(name)
+	st.once.Do(func() {
+		t := r.app.tasks[name]
+		errs := make([]error, len(t.deps))
+		var wg sync.WaitGroup
+		for i, dep := range t.deps {
+			wg.Add(1)
+			go func(i int, dep string) {
+				defer wg.Done()
+				errs[i] = r.run(dep)
+			}(i, dep)
+		}
+		wg.Wait()
+		for _, err := range errs {
+			if err != nil {
+				st.err = err
+				return
+			}
+		}
+		var hash string
+		if t.inputs != nil {
+			hash = hashInputs(t.inputs)
+			if !r.conf.Force && r.getHash(name) == hash {
+				r.app.logf("==> %s (up to date)\n", name)
+				return
+			}
+		}
+		r.sem <- struct{}{}
+		st.err = r.exec(t)
+		<-r.sem
+		if st.err == nil && hash != "" {
+			r.setHash(name, hash)
+		}
+	})
+	return st.err
+}
+
+func (r *runner) exec(t *taskDef) (err error) {
+	r.app.logf("==> %s\n", t.name)
+	if t.fn == nil {
+		return
+	}
+	defer func() {
+		if e := recover(); e != nil {
+			err = fmt.Errorf("task %s failed: %v", t.name, e)
+		}
+	}()
+	t.fn()
+	return
+}
+
+func (r *runner) getHash(name string) string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.hashes[name]
+}
+
+func (r *runner) setHash(name, hash string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.hashes[name], r.hashChanged = hash, true
+}
+
+// -----------------------------------------------------------------------------
+
+func hashInputs(patterns []string) string {
+	var files []string
+	for _, pattern := range patterns {
+		matches, _ := filepath.Glob(pattern)
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+	h := sha256.New()
+	for _, file := range files {
+		fmt.Fprintf(h, "%s\x00", file)
+		if f, err := os.Open(file); err == nil {
+			io.Copy(h, f)
+			f.Close()
+		}
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func loadHashes() map[string]string {
+	hashes := make(map[string]string)
+	if b, err := os.ReadFile(HashFile); err == nil {
+		json.Unmarshal(b, &hashes)
+	}
+	return hashes
+}
+
+func saveHashes(hashes map[string]string) {
+	if b, err := json.MarshalIndent(hashes, "", "  "); err == nil {
+		os.WriteFile(HashFile, b, 0644)
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+type iApp interface {
+	MainEntry()
+	app() *App
+}
+
+// Gopt_App_Main is the main entry of the task runner classfile.
+func Gopt_App_Main(a iApp) {
+	p := a.app()
+	a.MainEntry()
+
+	flags := flag.NewFlagSet(filepath.Base(os.Args[0]), flag.ExitOnError)
+	parallel := flags.Int("j", 0, "max number of tasks running at the same time")
+	force := flags.Bool("f", false, "run tasks even if their inputs are unchanged")
+	list := flags.Bool("l", false, "list all tasks")
+	flags.Parse(os.Args[1:])
+	names := flags.Args()
+	if *list || (len(names) == 0 && p.tasks["default"] == nil) {
+		for _, name := range p.names {
+			p.logf("%s\n", name)
+		}
+		return
+	}
+	if err := p.Run(&Config{Parallel: *parallel, Force: *force}, names...); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,99 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package task
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	var mutex sync.Mutex
+	var order []string
+	record := func(name string) func() {
+		return func() {
+			mutex.Lock()
+			order = append(order, name)
+			mutex.Unlock()
+		}
+	}
+	var log bytes.Buffer
+	app := &App{Stdout: &log}
+	app.Task__0("vet", record("vet"))
+	app.Task__0("gen", record("gen"))
+	app.Task__1("build", []string{"vet", "gen"}, record("build"))
+	app.Task__1("default", []string{"build", "vet"}, record("default"))
+	if names := app.Tasks(); strings.Join(names, ",") != "vet,gen,build,default" {
+		t.Fatal("Tasks:", names)
+	}
+	if err := app.Run(&Config{Parallel: 2}); err != nil {
+		t.Fatal("Run:", err)
+	}
+	if len(order) != 4 || order[2] != "build" || order[3] != "default" {
+		t.Fatal("Run order:", order)
+	}
+}
+
+func TestRunError(t *testing.T) {
+	app := &App{Stdout: new(bytes.Buffer)}
+	app.Task__1("a", []string{"b"}, nil)
+	app.Task__1("b", []string{"a"}, nil)
+	if err := app.Run(nil, "a"); !errors.Is(err, ErrCycle) || !strings.Contains(err.Error(), "a -> b -> a") {
+		t.Fatal("Run cycle:", err)
+	}
+	if err := app.Run(nil, "c"); err == nil || err.Error() != "task c not found" {
+		t.Fatal("Run not found:", err)
+	}
+	ran := false
+	app.Task__0("fail", func() { panic("oops") })
+	app.Task__1("next", []string{"fail"}, func() { ran = true })
+	if err := app.Run(nil, "next"); err == nil || err.Error() != "task fail failed: oops" || ran {
+		t.Fatal("Run fail:", err, ran)
+	}
+}
+
+func TestInputs(t *testing.T) {
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	os.Chdir(t.TempDir())
+	os.WriteFile("a.txt", []byte("hello"), 0644)
+
+	n := 0
+	app := &App{Stdout: new(bytes.Buffer)}
+	app.Task__0("gen", func() { n++ })
+	app.Inputs("gen", "*.txt")
+	for i := 0; i < 2; i++ {
+		if err := app.Run(nil, "gen"); err != nil {
+			t.Fatal("Run:", err)
+		}
+	}
+	if n != 1 {
+		t.Fatal("Run: not skipped", n)
+	}
+	os.WriteFile("a.txt", []byte("world"), 0644)
+	app.Run(nil, "gen")
+	if n != 2 {
+		t.Fatal("Run: changed input not detected", n)
+	}
+	app.Run(&Config{Force: true}, "gen")
+	if n != 3 {
+		t.Fatal("Run: force", n)
+	}
+}
@@ -79,6 +79,14 @@ type Config struct {
 
 	// Mod represents a Go+ module (optional).
 	Mod *gopmod.Module
+
+	// Strict = true means to report warnings of the Go+ compiler as (soft)
+	// errors. Warnings are ignored otherwise (see cl.Config.Strict).
+	Strict bool
+
+	// OnDiagnostic is called for each diagnostic of the Go+ compiler, which
+	// may have suggested fixes (optional).
+	OnDiagnostic func(d *cl.Diagnostic)
 }
 
 // A Checker maintains the state of the type checker.
@@ -141,6 +149,14 @@ func (p *Checker) Files(goFiles []*goast.File, gopFiles []*ast.File) (err error)
 	if mod == nil {
 		mod = gopmod.Default
 	}
+	var onWarning func(err error)
+	if opts.OnDiagnostic != nil {
+		onWarning = func(err error) {
+			for _, d := range cl.Diagnostics(err) {
+				opts.OnDiagnostic(d)
+			}
+		}
+	}
 	_, err = cl.NewPackage(pkgTypes.Path(), pkg, &cl.Config{
 		Types:          pkgTypes,
 		Fset:           fset,
@@ -152,8 +168,13 @@ func (p *Checker) Files(goFiles []*goast.File, gopFiles []*ast.File) (err error)
 		NoFileLine:     true,
 		NoAutoGenMain:  true,
 		NoSkipConstant: true,
+		Strict:         opts.Strict,
+		OnWarning:      onWarning,
 	})
 	if err != nil {
+		if onWarning != nil {
+			onWarning(err)
+		}
 		if onErr := conf.Error; onErr != nil {
 			if list, ok := err.(errors.List); ok {
 				for _, e := range list {
@@ -228,6 +249,13 @@ func DeleteObjects(scope *types.Scope, files []*goast.File) objMapT {
 
 func convErr(fset *token.FileSet, e error) (ret types.Error, ok bool) {
 	switch v := e.(type) {
+	case *cl.Diagnostic:
+		end := v.End
+		if end == token.NoPos {
+			end = v.Pos
+		}
+		ret.Pos, ret.Msg, ret.Soft = v.Pos, v.Msg, v.Severity == cl.SeverityWarning
+		typesutil.SetErrorGo116(&ret, 0, v.Pos, end)
 	case *gox.CodeError:
 		ret.Pos, ret.Msg = v.Pos, v.Msg
 		typesutil.SetErrorGo116(&ret, 0, v.Pos, v.Pos)
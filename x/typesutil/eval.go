@@ -17,9 +17,13 @@
 package typesutil
 
 import (
+	"fmt"
+	"go/constant"
+	gotoken "go/token"
 	"go/types"
 
 	"github.com/goplus/gop/ast"
+	"github.com/goplus/gop/parser"
 	"github.com/goplus/gop/token"
 )
 
@@ -44,3 +48,353 @@ import (
 func CheckExpr(fset *token.FileSet, pkg *types.Package, pos token.Pos, expr ast.Expr, info *Info) (err error) {
 	panic("todo")
 }
+
+// -----------------------------------------------------------------------------
+
+// Eval returns the type and, if constant, the value for the constant
+// expression expr, evaluated as if it had appeared at position pos of
+// package pkg.
+//
+// If pkg == nil, the Universe scope is used and the provided position pos
+// is ignored. If pkg != nil, and pos is invalid, the package scope is used.
+// Otherwise, the innermost scope enclosing pos is used.
+//
+// Unlike go/types.Eval, Eval doesn't type check expr: it only supports
+// constant expressions (literals, constants, operators, len of constant
+// strings and conversions to basic types). Big number literals like 1r
+// are evaluated as untyped integer constants of arbitrary precision.
+func Eval(fset *token.FileSet, pkg *types.Package, pos token.Pos, expr string) (tv types.TypeAndValue, err error) {
+	scope := types.Universe
+	if pkg != nil {
+		scope = pkg.Scope()
+		if pos.IsValid() {
+			if s := scope.Innermost(pos); s != nil {
+				scope = s
+			}
+		}
+	}
+	node, err := parser.ParseExprFrom(fset, "eval", expr, 0)
+	if err != nil {
+		return
+	}
+	return EvalConst(fset, scope, node)
+}
+
+// EvalConst returns the type and value of the constant expression expr.
+// Identifiers in expr are resolved in scope (or its parents) and must denote
+// constants, packages or basic types. If scope is nil, the Universe scope is
+// used.
+func EvalConst(fset *token.FileSet, scope *types.Scope, expr ast.Expr) (tv types.TypeAndValue, err error) {
+	if scope == nil {
+		scope = types.Universe
+	}
+	defer func() {
+		if e := recover(); e != nil {
+			if ee, ok := e.(*evalError); ok {
+				err = ee
+				return
+			}
+			panic(e)
+		}
+	}()
+	ev := &evaluator{fset: fset, scope: scope}
+	return ev.eval(expr), nil
+}
+
+type evalError struct {
+	Fset *token.FileSet
+	Pos  token.Pos
+	Msg  string
+}
+
+func (p *evalError) Error() string {
+	if p.Fset != nil && p.Pos.IsValid() {
+		return fmt.Sprintf("%v: %s", p.Fset.Position(p.Pos), p.Msg)
+	}
+	return p.Msg
+}
+
+type evaluator struct {
+	fset  *token.FileSet
+	scope *types.Scope
+}
+
+func (p *evaluator) errorf(pos token.Pos, format string, args ...interface{}) {
+	panic(&evalError{Fset: p.fset, Pos: pos, Msg: fmt.Sprintf(format, args...)})
+}
+
+func (p *evaluator) eval(expr ast.Expr) types.TypeAndValue {
+	switch v := expr.(type) {
+	case *ast.BasicLit:
+		return p.basicLit(v)
+	case *ast.Ident:
+		return p.constObj(v, p.lookup(v))
+	case *ast.SelectorExpr:
+		return p.constObj(v, p.lookupQualified(v))
+	case *ast.ParenExpr:
+		return p.eval(v.X)
+	case *ast.UnaryExpr:
+		return p.unaryExpr(v)
+	case *ast.BinaryExpr:
+		return p.binaryExpr(v)
+	case *ast.CallExpr:
+		return p.callExpr(v)
+	}
+	p.errorf(expr.Pos(), "%s is not constant", ExprString(expr))
+	return types.TypeAndValue{}
+}
+
+func (p *evaluator) basicLit(v *ast.BasicLit) types.TypeAndValue {
+	var kind types.BasicKind
+	var val constant.Value
+	switch v.Kind {
+	case token.INT:
+		kind = types.UntypedInt
+	case token.FLOAT:
+		kind = types.UntypedFloat
+	case token.IMAG:
+		kind = types.UntypedComplex
+	case token.CHAR:
+		kind = types.UntypedRune
+	case token.STRING:
+		kind = types.UntypedString
+	case token.RAT:
+		kind = types.UntypedInt
+		val = constant.MakeFromLiteral(v.Value[:len(v.Value)-1], gotoken.INT, 0)
+	default:
+		p.errorf(v.Pos(), "%s is not constant", v.Value)
+	}
+	if val == nil {
+		val = constant.MakeFromLiteral(v.Value, gotoken.Token(v.Kind), 0)
+	}
+	if val.Kind() == constant.Unknown {
+		p.errorf(v.Pos(), "invalid literal %s", v.Value)
+	}
+	return types.TypeAndValue{Type: types.Typ[kind], Value: val}
+}
+
+func (p *evaluator) lookup(id *ast.Ident) types.Object {
+	_, obj := p.scope.LookupParent(id.Name, token.NoPos)
+	if obj == nil {
+		p.errorf(id.Pos(), "undefined: %s", id.Name)
+	}
+	return obj
+}
+
+func (p *evaluator) lookupQualified(v *ast.SelectorExpr) types.Object {
+	if x, ok := v.X.(*ast.Ident); ok {
+		if pkgName, ok := p.lookup(x).(*types.PkgName); ok {
+			if obj := pkgName.Imported().Scope().Lookup(v.Sel.Name); obj != nil && obj.Exported() {
+				return obj
+			}
+			p.errorf(v.Sel.Pos(), "undefined: %s", ExprString(v))
+		}
+	}
+	p.errorf(v.Pos(), "%s is not constant", ExprString(v))
+	return nil
+}
+
+func (p *evaluator) constObj(expr ast.Expr, obj types.Object) types.TypeAndValue {
+	if c, ok := obj.(*types.Const); ok {
+		return types.TypeAndValue{Type: c.Type(), Value: c.Val()}
+	}
+	p.errorf(expr.Pos(), "%s is not constant", ExprString(expr))
+	return types.TypeAndValue{}
+}
+
+func (p *evaluator) unaryExpr(v *ast.UnaryExpr) types.TypeAndValue {
+	x := p.eval(v.X)
+	switch v.Op {
+	case token.ADD, token.SUB:
+		if !isNumeric(x.Type) {
+			p.errorf(v.Pos(), "invalid operation: operator %v not defined on %s", v.Op, ExprString(v.X))
+		}
+	case token.XOR:
+		if !isInteger(x.Type) {
+			p.errorf(v.Pos(), "invalid operation: operator %v not defined on %s", v.Op, ExprString(v.X))
+		}
+	case token.NOT:
+		if !isBoolean(x.Type) {
+			p.errorf(v.Pos(), "invalid operation: operator %v not defined on %s", v.Op, ExprString(v.X))
+		}
+	default:
+		p.errorf(v.Pos(), "%s is not constant", ExprString(v))
+	}
+	var prec uint
+	if t, ok := x.Type.Underlying().(*types.Basic); ok && v.Op == token.XOR && isUnsigned(t) {
+		prec = uint(8 * types.SizesFor("gc", "amd64").Sizeof(t))
+	}
+	x.Value = constant.UnaryOp(gotoken.Token(v.Op), x.Value, prec)
+	return p.representable(v, x)
+}
+
+func (p *evaluator) binaryExpr(v *ast.BinaryExpr) types.TypeAndValue {
+	x, y := p.eval(v.X), p.eval(v.Y)
+	op := gotoken.Token(v.Op)
+	switch v.Op {
+	case token.SHL, token.SHR:
+		if !isInteger(x.Type) && !(isUntyped(x.Type) && constant.ToInt(x.Value).Kind() == constant.Int) {
+			p.errorf(v.Pos(), "invalid operation: shifted operand %s must be integer", ExprString(v.X))
+		}
+		s, ok := constant.Uint64Val(constant.ToInt(y.Value))
+		if !ok || constant.ToInt(y.Value).Kind() != constant.Int {
+			p.errorf(v.Y.Pos(), "invalid shift count %s", ExprString(v.Y))
+		}
+		if isUntyped(x.Type) {
+			x.Type = types.Typ[types.UntypedInt]
+		}
+		x.Value = constant.Shift(constant.ToInt(x.Value), op, uint(s))
+		return p.representable(v, x)
+	case token.EQL, token.NEQ, token.LSS, token.LEQ, token.GTR, token.GEQ:
+		p.matchTypes(v, x.Type, y.Type)
+		return types.TypeAndValue{
+			Type: types.Typ[types.UntypedBool], Value: constant.MakeBool(constant.Compare(x.Value, op, y.Value)),
+		}
+	case token.LAND, token.LOR:
+		if !isBoolean(x.Type) || !isBoolean(y.Type) {
+			p.errorf(v.Pos(), "invalid operation: operator %v not defined on %s", v.Op, ExprString(v.X))
+		}
+	case token.ADD, token.SUB, token.MUL, token.QUO, token.REM, token.AND, token.OR, token.XOR, token.AND_NOT:
+	default:
+		p.errorf(v.Pos(), "%s is not constant", ExprString(v))
+	}
+	typ := p.matchTypes(v, x.Type, y.Type)
+	if v.Op == token.QUO || v.Op == token.REM {
+		if y.Value.Kind() != constant.Bool && constant.Sign(y.Value) == 0 {
+			p.errorf(v.Y.Pos(), "invalid operation: division by zero")
+		}
+		if v.Op == token.QUO && isInteger(typ) {
+			op = gotoken.QUO_ASSIGN // force integer division
+		}
+	}
+	if v.Op == token.ADD && isString(typ) {
+		return types.TypeAndValue{
+			Type: typ, Value: constant.MakeString(constant.StringVal(x.Value) + constant.StringVal(y.Value)),
+		}
+	}
+	ret := types.TypeAndValue{Type: typ, Value: constant.BinaryOp(x.Value, op, y.Value)}
+	return p.representable(v, ret)
+}
+
+func (p *evaluator) callExpr(v *ast.CallExpr) types.TypeAndValue {
+	if len(v.Args) == 1 {
+		if id, ok := v.Fun.(*ast.Ident); ok {
+			switch obj := p.lookup(id).(type) {
+			case *types.Builtin:
+				if id.Name == "len" {
+					x := p.eval(v.Args[0])
+					if isString(x.Type) {
+						n := len(constant.StringVal(x.Value))
+						return types.TypeAndValue{Type: types.Typ[types.Int], Value: constant.MakeInt64(int64(n))}
+					}
+				}
+			case *types.TypeName:
+				return p.convert(v, obj.Type(), p.eval(v.Args[0]))
+			}
+		}
+	}
+	p.errorf(v.Pos(), "%s is not constant", ExprString(v))
+	return types.TypeAndValue{}
+}
+
+func (p *evaluator) convert(v *ast.CallExpr, typ types.Type, x types.TypeAndValue) types.TypeAndValue {
+	t, ok := typ.Underlying().(*types.Basic)
+	if !ok {
+		p.errorf(v.Pos(), "cannot convert %s to type %v", ExprString(v.Args[0]), typ)
+	}
+	val := x.Value
+	switch {
+	case isInteger(t):
+		val = constant.ToInt(val)
+	case isFloat(t):
+		val = constant.ToFloat(val)
+	case isComplex(t):
+		val = constant.ToComplex(val)
+	case isString(t):
+		if isInteger(x.Type) {
+			r, ok := constant.Int64Val(constant.ToInt(val))
+			if !ok {
+				r = 0xfffd
+			}
+			val = constant.MakeString(string(rune(r)))
+		}
+	}
+	if val.Kind() == constant.Unknown {
+		p.errorf(v.Pos(), "cannot convert %s to type %v", ExprString(v.Args[0]), typ)
+	}
+	return p.representable(v, types.TypeAndValue{Type: typ, Value: val})
+}
+
+// matchTypes returns the result type of a binary operation on x and y.
+func (p *evaluator) matchTypes(v *ast.BinaryExpr, x, y types.Type) types.Type {
+	switch ux, uy := isUntyped(x), isUntyped(y); {
+	case ux && uy:
+		if isNumeric(x) && isNumeric(y) {
+			if x.(*types.Basic).Kind() < y.(*types.Basic).Kind() {
+				return y
+			}
+			return x
+		}
+	case ux:
+		return y
+	case uy:
+		return x
+	}
+	if !types.Identical(x, y) {
+		p.errorf(v.Pos(), "invalid operation: %s (mismatched types %v and %v)", ExprString(v), x, y)
+	}
+	return x
+}
+
+func (p *evaluator) representable(expr ast.Expr, x types.TypeAndValue) types.TypeAndValue {
+	if t, ok := x.Type.Underlying().(*types.Basic); ok && t.Info()&types.IsUntyped == 0 && isNumeric(t) {
+		sizes := types.SizesFor("gc", "amd64")
+		var ok bool
+		switch {
+		case isInteger(t):
+			x.Value = constant.ToInt(x.Value)
+			ok = x.Value.Kind() == constant.Int && fitsInt(x.Value, isUnsigned(t), 8*sizes.Sizeof(t))
+		case isFloat(t):
+			x.Value = constant.ToFloat(x.Value)
+			ok = x.Value.Kind() == constant.Float || x.Value.Kind() == constant.Int
+		case isComplex(t):
+			x.Value = constant.ToComplex(x.Value)
+			ok = x.Value.Kind() == constant.Complex
+		}
+		if !ok {
+			p.errorf(expr.Pos(), "cannot use %s (untyped constant %v) as %v value (overflows)", ExprString(expr), x.Value, x.Type)
+		}
+	}
+	return x
+}
+
+func fitsInt(val constant.Value, unsigned bool, bits int64) bool {
+	if unsigned {
+		if constant.Sign(val) < 0 {
+			return false
+		}
+		return constant.BitLen(val) <= int(bits)
+	}
+	if constant.Sign(val) < 0 {
+		val = constant.UnaryOp(gotoken.XOR, val, 0) // -x-1
+	}
+	return constant.BitLen(val) < int(bits)
+}
+
+func basicInfo(t types.Type) types.BasicInfo {
+	if t, ok := t.Underlying().(*types.Basic); ok {
+		return t.Info()
+	}
+	return 0
+}
+
+func isUntyped(t types.Type) bool  { return basicInfo(t)&types.IsUntyped != 0 }
+func isNumeric(t types.Type) bool  { return basicInfo(t)&types.IsNumeric != 0 }
+func isInteger(t types.Type) bool  { return basicInfo(t)&types.IsInteger != 0 }
+func isUnsigned(t types.Type) bool { return basicInfo(t)&types.IsUnsigned != 0 }
+func isFloat(t types.Type) bool    { return basicInfo(t)&types.IsFloat != 0 }
+func isComplex(t types.Type) bool  { return basicInfo(t)&types.IsComplex != 0 }
+func isString(t types.Type) bool   { return basicInfo(t)&types.IsString != 0 }
+func isBoolean(t types.Type) bool  { return basicInfo(t)&types.IsBoolean != 0 }
+
+// -----------------------------------------------------------------------------
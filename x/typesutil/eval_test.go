@@ -0,0 +1,92 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package typesutil_test
+
+import (
+	"go/constant"
+	"go/types"
+	"strings"
+	"testing"
+
+	"github.com/goplus/gop/token"
+	"github.com/goplus/gop/x/typesutil"
+)
+
+func TestEval(t *testing.T) {
+	pkg := types.NewPackage("foo", "foo")
+	scope := pkg.Scope()
+	scope.Insert(types.NewConst(token.NoPos, pkg, "N", types.Typ[types.Int], constant.MakeInt64(10)))
+	scope.Insert(types.NewConst(token.NoPos, pkg, "Name", types.Typ[types.UntypedString], constant.MakeString("gop")))
+
+	cases := []struct {
+		expr, typ, val string
+	}{
+		{`1 + 2*3`, "untyped int", "7"},
+		{`7 / 2`, "untyped int", "3"},
+		{`7 / 2.0`, "untyped float", "3.5"},
+		{`'a' + 1`, "untyped rune", "98"},
+		{`N * 2`, "int", "20"},
+		{`1 << 100`, "untyped int", "1267650600228229401496703205376"},
+		{`100000000000000000000000r * 10`, "untyped int", "1000000000000000000000000"},
+		{`Name + "+"`, "untyped string", `"gop+"`},
+		{`len(Name)`, "int", "3"},
+		{`N > 5 && true`, "untyped bool", "true"},
+		{`int8(-128)`, "int8", "-128"},
+		{`^uint8(1)`, "uint8", "254"},
+		{`float64(N) / 4`, "float64", "2.5"},
+		{`(1 + 2i) * 1i`, "untyped complex", "(-2 + 1i)"},
+	}
+	for _, c := range cases {
+		tv, err := typesutil.Eval(token.NewFileSet(), pkg, token.NoPos, c.expr)
+		if err != nil {
+			t.Fatal("Eval:", c.expr, err)
+		}
+		if typ := tv.Type.String(); typ != c.typ {
+			t.Fatal("Eval:", c.expr, "type:", typ, "expected:", c.typ)
+		}
+		if val := tv.Value.String(); val != c.val {
+			t.Fatal("Eval:", c.expr, "value:", val, "expected:", c.val)
+		}
+	}
+}
+
+func TestEvalError(t *testing.T) {
+	pkg := types.NewPackage("foo", "foo")
+	scope := pkg.Scope()
+	scope.Insert(types.NewVar(token.NoPos, pkg, "v", types.Typ[types.Int]))
+
+	cases := []struct {
+		expr, err string
+	}{
+		{`v + 1`, "v is not constant"},
+		{`x`, "undefined: x"},
+		{`1 / 0`, "division by zero"},
+		{`int8(128)`, "overflows"},
+		{`int8(1) + int16(2)`, "mismatched types int8 and int16"},
+		{`-"a"`, "operator - not defined"},
+		{`1 +`, "expected operand"},
+	}
+	for _, c := range cases {
+		_, err := typesutil.Eval(token.NewFileSet(), pkg, token.NoPos, c.expr)
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Fatal("Eval:", c.expr, err, "expected:", c.err)
+		}
+	}
+	if _, err := typesutil.Eval(token.NewFileSet(), nil, token.NoPos, `len("hello") + 1`); err != nil {
+		t.Fatal("Eval Universe:", err)
+	}
+}
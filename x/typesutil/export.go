@@ -0,0 +1,221 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package typesutil
+
+import (
+	"go/types"
+	"sort"
+
+	"github.com/goplus/gop/token"
+)
+
+// -----------------------------------------------------------------------------
+
+// TypeInfoVersion is the version of the exported type information format.
+// It is increased only when an incompatible change of the format happens.
+const TypeInfoVersion = 1
+
+// ObjectInfo is the machine-readable form of a types.Object.
+type ObjectInfo struct {
+	Kind string `json:"kind"` // var, const, type, func, pkgname, label, builtin or nil
+	Name string `json:"name"`
+	Pkg  string `json:"pkg,omitempty"`
+	Type string `json:"type,omitempty"`
+	Pos  string `json:"pos,omitempty"`
+}
+
+// IdentInfo is the type information of an identifier.
+type IdentInfo struct {
+	Pos  string      `json:"pos"`
+	Name string      `json:"name"`
+	Def  bool        `json:"def,omitempty"` // defines (not uses) an object
+	Obj  *ObjectInfo `json:"obj,omitempty"`
+}
+
+// ExprInfo is the type (and value for a constant) of an expression.
+type ExprInfo struct {
+	Pos   string `json:"pos"`
+	Expr  string `json:"expr"`
+	Type  string `json:"type"`
+	Value string `json:"value,omitempty"`
+}
+
+// SelectionInfo is the machine-readable form of a types.Selection.
+type SelectionInfo struct {
+	Pos      string      `json:"pos"`
+	Expr     string      `json:"expr"`
+	Kind     string      `json:"kind"` // field, method or methodexpr
+	Recv     string      `json:"recv"`
+	Type     string      `json:"type"`
+	Index    []int       `json:"index"`
+	Indirect bool        `json:"indirect,omitempty"`
+	Obj      *ObjectInfo `json:"obj"`
+}
+
+// PackageInfo is the machine-readable type information of a Go+ package.
+// All lists are sorted by source position.
+type PackageInfo struct {
+	Version    int              `json:"version"`
+	Path       string           `json:"path"`
+	Name       string           `json:"name"`
+	Idents     []*IdentInfo     `json:"idents"`
+	Exprs      []*ExprInfo      `json:"exprs"`
+	Selections []*SelectionInfo `json:"selections"`
+}
+
+// Export converts type information of pkg recorded in info into its stable
+// machine-readable form, which can be marshaled as JSON.
+func (info *Info) Export(fset *token.FileSet, pkg *types.Package) *PackageInfo {
+	ret := &PackageInfo{Version: TypeInfoVersion, Path: pkg.Path(), Name: pkg.Name()}
+	e := &exporter{fset: fset}
+	var idents []posKey
+	for id, obj := range info.Defs {
+		ret.Idents = append(ret.Idents, &IdentInfo{
+			Pos: e.pos(id.Pos()), Name: id.Name, Def: true, Obj: e.object(obj),
+		})
+		idents = append(idents, posKey{id.Pos(), id.End()})
+	}
+	for id, obj := range info.Uses {
+		ret.Idents = append(ret.Idents, &IdentInfo{
+			Pos: e.pos(id.Pos()), Name: id.Name, Obj: e.object(obj),
+		})
+		idents = append(idents, posKey{id.Pos(), id.End()})
+	}
+	sortByPos(ret.Idents, idents)
+
+	var exprs []posKey
+	for expr, tv := range info.Types {
+		if tv.Type == nil {
+			continue
+		}
+		ei := &ExprInfo{Pos: e.pos(expr.Pos()), Expr: ExprString(expr), Type: e.typ(tv.Type)}
+		if tv.Value != nil {
+			ei.Value = tv.Value.ExactString()
+		}
+		ret.Exprs = append(ret.Exprs, ei)
+		exprs = append(exprs, posKey{expr.Pos(), expr.End()})
+	}
+	sortByPos(ret.Exprs, exprs)
+
+	var sels []posKey
+	for expr, sel := range info.Selections {
+		ret.Selections = append(ret.Selections, &SelectionInfo{
+			Pos:      e.pos(expr.Sel.Pos()),
+			Expr:     ExprString(expr),
+			Kind:     selKind(sel.Kind()),
+			Recv:     e.typ(sel.Recv()),
+			Type:     e.typ(sel.Type()),
+			Index:    sel.Index(),
+			Indirect: sel.Indirect(),
+			Obj:      e.object(sel.Obj()),
+		})
+		sels = append(sels, posKey{expr.Sel.Pos(), expr.End()})
+	}
+	sortByPos(ret.Selections, sels)
+	return ret
+}
+
+type exporter struct {
+	fset *token.FileSet
+}
+
+func (p *exporter) pos(pos token.Pos) string {
+	if pos == token.NoPos {
+		return ""
+	}
+	return p.fset.Position(pos).String()
+}
+
+func (p *exporter) typ(t types.Type) string {
+	if t == nil {
+		return ""
+	}
+	return types.TypeString(t, nil)
+}
+
+func (p *exporter) object(obj types.Object) *ObjectInfo {
+	if obj == nil {
+		return nil
+	}
+	ret := &ObjectInfo{Kind: objKind(obj), Name: obj.Name(), Type: p.typ(obj.Type()), Pos: p.pos(obj.Pos())}
+	if pkg := obj.Pkg(); pkg != nil {
+		ret.Pkg = pkg.Path()
+	}
+	return ret
+}
+
+func objKind(obj types.Object) string {
+	switch obj.(type) {
+	case *types.Var:
+		return "var"
+	case *types.Const:
+		return "const"
+	case *types.TypeName:
+		return "type"
+	case *types.Func:
+		return "func"
+	case *types.PkgName:
+		return "pkgname"
+	case *types.Label:
+		return "label"
+	case *types.Builtin:
+		return "builtin"
+	case *types.Nil:
+		return "nil"
+	}
+	return "unknown"
+}
+
+func selKind(kind types.SelectionKind) string {
+	switch kind {
+	case types.FieldVal:
+		return "field"
+	case types.MethodVal:
+		return "method"
+	}
+	return "methodexpr"
+}
+
+type posKey struct {
+	pos, end token.Pos
+}
+
+// sortByPos sorts items by their source ranges to make the output
+// deterministic. Outer expressions go before inner ones.
+func sortByPos[T any](items []T, keys []posKey) {
+	sort.Sort(&posSorter[T]{items, keys})
+}
+
+type posSorter[T any] struct {
+	items []T
+	pos   []posKey
+}
+
+func (p *posSorter[T]) Len() int { return len(p.items) }
+func (p *posSorter[T]) Less(i, j int) bool {
+	a, b := p.pos[i], p.pos[j]
+	if a.pos != b.pos {
+		return a.pos < b.pos
+	}
+	return a.end > b.end
+}
+func (p *posSorter[T]) Swap(i, j int) {
+	p.items[i], p.items[j] = p.items[j], p.items[i]
+	p.pos[i], p.pos[j] = p.pos[j], p.pos[i]
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,68 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package typesutil_test
+
+import (
+	"encoding/json"
+	"go/types"
+	"testing"
+
+	"github.com/goplus/gop/token"
+	"github.com/goplus/gop/x/typesutil"
+)
+
+func TestExport(t *testing.T) {
+	fset := token.NewFileSet()
+	info, _, err := checkFiles(fset, "main.gop", `
+type Point struct {
+	x int
+}
+const N = 100
+pt := &Point{}
+println pt.x + N
+`, "", "", "", "")
+	if err != nil {
+		t.Fatal("checkFiles:", err)
+	}
+	ret := info.Export(fset, types.NewPackage("main", "main"))
+	if ret.Version != typesutil.TypeInfoVersion || ret.Path != "main" || ret.Name != "main" {
+		t.Fatal("Export:", ret.Version, ret.Path, ret.Name)
+	}
+	if id := ret.Idents[0]; id.Name != "Point" || !id.Def || id.Obj.Kind != "type" || id.Pos != "main.gop:2:6" {
+		t.Fatal("Export idents[0]:", *id)
+	}
+	var hasConst, hasField bool
+	for _, e := range ret.Exprs {
+		switch e.Expr {
+		case "N":
+			hasConst = e.Value == "100"
+		case "pt.x":
+			hasField = e.Type == "int"
+		}
+	}
+	if !hasConst || !hasField {
+		t.Fatal("Export exprs:", hasConst, hasField)
+	}
+	b1, err := json.Marshal(ret)
+	if err != nil {
+		t.Fatal("json.Marshal:", err)
+	}
+	b2, _ := json.Marshal(info.Export(fset, types.NewPackage("main", "main")))
+	if string(b1) != string(b2) {
+		t.Fatal("Export: output isn't deterministic")
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"go/types"
 
 	"github.com/goplus/gop/ast"
+	"github.com/goplus/gop/cl"
 	"github.com/qiniu/x/log"
 )
 
@@ -128,6 +129,10 @@ type Info struct {
 	// in source order. Variables without an initialization expression do not
 	// appear in this list.
 	// InitOrder []*Initializer
+
+	// Classes maps classfiles to their classes, which describe members
+	// injected into the classes by classfile frameworks (Go+ only).
+	Classes map[string]*cl.ClassInfo
 }
 
 // ObjectOf returns the object denoted by the specified id,
@@ -297,4 +302,14 @@ func (info gopRecorder) Scope(n ast.Node, scope *types.Scope) {
 	}
 }
 
+// Class maps a classfile to its class.
+func (info gopRecorder) Class(class *cl.ClassInfo) {
+	if debugVerbose {
+		log.Println("==> Class:", class.Name)
+	}
+	if info.Classes != nil {
+		info.Classes[class.File] = class
+	}
+}
+
 // -----------------------------------------------------------------------------
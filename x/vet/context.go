@@ -0,0 +1,160 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package vet
+
+import (
+	"go/types"
+
+	"github.com/goplus/gop/ast"
+)
+
+// -----------------------------------------------------------------------------
+
+// Context reports calls dropping the context of the enclosing function (or
+// lambda), that is a parameter implementing context.Context like the ctx of
+// handlers of the web service classfile: calls of context.Background or
+// context.TODO, and calls of functions or methods without context whose
+// variant FooContext or FooWithContext accepts one (eg. http.NewRequest).
+var Context = &Analyzer{
+	Name: "context",
+	Doc:  "report calls dropping the context of the enclosing function",
+	Run:  runContext,
+}
+
+func runContext(pass *Pass) {
+	for _, f := range pass.Files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			if ctx, body := contextOf(pass, n); ctx != nil && body != nil {
+				checkContextCalls(pass, ctx, body)
+			}
+			return true
+		})
+	}
+}
+
+// contextOf returns the context parameter and the body of n if n is a
+// function with such a parameter.
+func contextOf(pass *Pass, n ast.Node) (ctx *ast.Ident, body ast.Node) {
+	var params []*ast.Ident
+	switch v := n.(type) {
+	case *ast.FuncDecl:
+		params, body = fieldNames(v.Type.Params), v.Body
+	case *ast.FuncLit:
+		params, body = fieldNames(v.Type.Params), v.Body
+	case *ast.LambdaExpr:
+		params, body = v.Lhs, n
+	case *ast.LambdaExpr2:
+		params, body = v.Lhs, v.Body
+	}
+	for _, param := range params {
+		if obj := pass.Info.Defs[param]; obj != nil && isContext(obj.Type()) {
+			return param, body
+		}
+	}
+	return nil, nil
+}
+
+func fieldNames(fields *ast.FieldList) (names []*ast.Ident) {
+	if fields != nil {
+		for _, field := range fields.List {
+			names = append(names, field.Names...)
+		}
+	}
+	return
+}
+
+func checkContextCalls(pass *Pass, ctx *ast.Ident, body ast.Node) {
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch v := n.(type) {
+		case *ast.FuncLit, *ast.LambdaExpr, *ast.LambdaExpr2:
+			if inner, _ := contextOf(pass, n); inner != nil && n != body { // checked with its own context
+				return false
+			}
+		case *ast.CallExpr:
+			checkContextCall(pass, ctx, v)
+		}
+		return true
+	})
+}
+
+func checkContextCall(pass *Pass, ctx *ast.Ident, call *ast.CallExpr) {
+	var fn *types.Func
+	var recv types.Type
+	switch v := call.Fun.(type) {
+	case *ast.Ident:
+		fn, _ = pass.Info.Uses[v].(*types.Func)
+	case *ast.SelectorExpr:
+		if sel, ok := pass.Info.Selections[v]; ok {
+			if sel.Kind() != types.MethodVal {
+				return
+			}
+			fn, recv = sel.Obj().(*types.Func), sel.Recv()
+		} else {
+			fn, _ = pass.Info.Uses[v.Sel].(*types.Func)
+		}
+	}
+	if fn == nil || fn.Pkg() == nil {
+		return
+	}
+	if fn.Pkg().Path() == "context" && (fn.Name() == "Background" || fn.Name() == "TODO") {
+		pass.ReportRangef(call, "context.%s drops context %s, pass %s instead", fn.Name(), ctx.Name, ctx.Name)
+		return
+	}
+	sig := fn.Type().(*types.Signature)
+	if hasContextParam(sig) {
+		return
+	}
+	if recv == nil && sig.Recv() != nil {
+		recv = sig.Recv().Type()
+	}
+	for _, suffix := range [...]string{"Context", "WithContext"} {
+		name := fn.Name() + suffix
+		var variant types.Object
+		if recv != nil {
+			variant, _, _ = types.LookupFieldOrMethod(recv, true, fn.Pkg(), name)
+		} else {
+			variant = fn.Pkg().Scope().Lookup(name)
+		}
+		if variant, ok := variant.(*types.Func); ok && hasContextParam(variant.Type().(*types.Signature)) {
+			pass.ReportRangef(call, "%s drops context %s, use %s instead", calleeName(call.Fun), ctx.Name, name)
+			return
+		}
+	}
+}
+
+func hasContextParam(sig *types.Signature) bool {
+	params := sig.Params()
+	for i, n := 0, params.Len(); i < n; i++ {
+		if isContext(params.At(i).Type()) {
+			return true
+		}
+	}
+	return false
+}
+
+// isContext checks if t implements context.Context.
+func isContext(t types.Type) bool {
+	for _, name := range [...]string{"Deadline", "Done", "Err", "Value"} {
+		obj, _, _ := types.LookupFieldOrMethod(t, false, nil, name)
+		if _, ok := obj.(*types.Func); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// -----------------------------------------------------------------------------
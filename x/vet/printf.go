@@ -0,0 +1,156 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package vet
+
+import (
+	"go/constant"
+	"go/types"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/goplus/gop/ast"
+	"github.com/goplus/gop/internal/printf"
+)
+
+// -----------------------------------------------------------------------------
+
+// Printf checks calls of printf-style functions (the printf, sprintf,
+// errorf and fprintf builtins, and their fmt and log counterparts) whose
+// format is a constant: the verbs of the format must be known. The number and
+// types of arguments are checked by the Go+ compiler, whose "printf" warnings
+// are reported by this check. It also reports print-style calls (println etc.)
+// whose first argument looks like a format.
+var Printf = &Analyzer{
+	Name: "printf",
+	Doc:  "check consistency of printf-style format strings and arguments",
+	Run:  runPrintf,
+}
+
+// printFuncs maps print-style functions to the index of their first argument
+// to print.
+var printFuncs = map[string]int{
+	"fmt.Print":    0,
+	"fmt.Println":  0,
+	"fmt.Sprint":   0,
+	"fmt.Sprintln": 0,
+	"fmt.Fprint":   1,
+	"fmt.Fprintln": 1,
+	"log.Print":    0,
+	"log.Println":  0,
+	"log.Fatal":    0,
+	"log.Fatalln":  0,
+	"log.Panic":    0,
+	"log.Panicln":  0,
+}
+
+func runPrintf(pass *Pass) {
+	for _, d := range pass.Diagnostics {
+		if d.Code == "printf" {
+			pass.report(d.Pos, d.End, d.Msg)
+		}
+	}
+	for _, f := range pass.Files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			if call, ok := n.(*ast.CallExpr); ok {
+				checkPrintfCall(pass, call)
+			}
+			return true
+		})
+	}
+}
+
+func checkPrintfCall(pass *Pass, call *ast.CallExpr) {
+	var id *ast.Ident
+	switch fn := call.Fun.(type) {
+	case *ast.Ident:
+		id = fn
+	case *ast.SelectorExpr:
+		id = fn.Sel
+	default:
+		return
+	}
+	fn, ok := pass.Info.Uses[id].(*types.Func)
+	if !ok || fn.Pkg() == nil || fn.Type().(*types.Signature).Recv() != nil {
+		return
+	}
+	fullName := fn.Pkg().Path() + "." + fn.Name()
+	name := calleeName(call.Fun)
+	if idx, ok := printf.Funcs[fullName]; ok {
+		format, ok := constString(pass, call.Args, idx)
+		if !ok {
+			return
+		}
+		checkFormat(pass, call, name, format, fn.Name() == "Errorf")
+	} else if idx, ok := printFuncs[fullName]; ok {
+		if s, ok := constString(pass, call.Args, idx); ok {
+			if _, verb, _, ok := printf.NextDirective(s); ok && verb != "%%" {
+				pass.ReportRangef(call.Args[idx], "%s call has possible formatting directive %s", name, verb)
+			}
+		}
+	}
+}
+
+func checkFormat(pass *Pass, call *ast.CallExpr, name, format string, wrap bool) {
+	for s := format; ; {
+		rest, verb, _, ok := printf.NextDirective(s)
+		if !ok {
+			return
+		}
+		s = rest
+		if verb == "%%" {
+			continue
+		}
+		if !printf.Complete(verb) {
+			pass.ReportRangef(call, "%s format %s is missing verb at end of string", name, verb)
+			return
+		}
+		c, _ := utf8.DecodeLastRuneInString(verb)
+		if !strings.ContainsRune(printf.Verbs, c) {
+			pass.ReportRangef(call, "%s format %s has unknown verb %c", name, verb, c)
+			return
+		}
+		if c == 'w' && !wrap {
+			pass.ReportRangef(call, "%s does not support error-wrapping directive %%w", name)
+			return
+		}
+	}
+}
+
+func constString(pass *Pass, args []ast.Expr, idx int) (string, bool) {
+	if idx >= len(args) {
+		return "", false
+	}
+	if tv, ok := pass.Info.Types[args[idx]]; ok && tv.Value != nil && tv.Value.Kind() == constant.String {
+		return constant.StringVal(tv.Value), true
+	}
+	return "", false
+}
+
+func calleeName(fn ast.Expr) string {
+	switch v := fn.(type) {
+	case *ast.SelectorExpr:
+		if x, ok := v.X.(*ast.Ident); ok {
+			return x.Name + "." + v.Sel.Name
+		}
+		return v.Sel.Name
+	case *ast.Ident:
+		return v.Name
+	}
+	return ""
+}
+
+// -----------------------------------------------------------------------------
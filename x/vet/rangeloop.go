@@ -0,0 +1,147 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package vet
+
+import (
+	"go/types"
+
+	"github.com/goplus/gop/ast"
+	"github.com/goplus/gop/token"
+)
+
+// -----------------------------------------------------------------------------
+
+// RangeLoop reports suspicious uses of variables of `for range` and
+// `for x <- container` loops:
+//   - a loop variable referenced by a function literal in a go or defer
+//     statement, which may see a later value of the variable;
+//   - assignment to a field of a struct loop value, which only modifies a
+//     copy of the element.
+var RangeLoop = &Analyzer{
+	Name: "rangeloop",
+	Doc:  "report suspicious uses of range loop variables",
+	Run:  runRangeLoop,
+}
+
+func runRangeLoop(pass *Pass) {
+	for _, f := range pass.Files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			var key, val *ast.Ident
+			var body *ast.BlockStmt
+			switch v := n.(type) {
+			case *ast.RangeStmt:
+				if v.Tok != token.DEFINE {
+					return true
+				}
+				key, _ = v.Key.(*ast.Ident)
+				val, _ = v.Value.(*ast.Ident)
+				body = v.Body
+			case *ast.ForPhraseStmt:
+				key, val, body = v.Key, v.Value, v.Body
+			default:
+				return true
+			}
+			vars := make(map[types.Object]bool)
+			for _, id := range []*ast.Ident{key, val} {
+				if id != nil && id.Name != "_" {
+					if obj := pass.Info.Defs[id]; obj != nil {
+						vars[obj] = true
+					}
+				}
+			}
+			if len(vars) != 0 {
+				checkLoopClosures(pass, body, vars)
+			}
+			if val != nil {
+				if obj := pass.Info.Defs[val]; obj != nil {
+					checkLoopValueAssign(pass, body, obj)
+				}
+			}
+			return true
+		})
+	}
+}
+
+// checkLoopClosures reports references to loop variables vars from function
+// literals called by go or defer statements in body.
+func checkLoopClosures(pass *Pass, body *ast.BlockStmt, vars map[types.Object]bool) {
+	ast.Inspect(body, func(n ast.Node) bool {
+		var call *ast.CallExpr
+		switch v := n.(type) {
+		case *ast.GoStmt:
+			call = v.Call
+		case *ast.DeferStmt:
+			call = v.Call
+		case *ast.FuncLit:
+			return false // not called by go or defer
+		default:
+			return true
+		}
+		if lit, ok := call.Fun.(*ast.FuncLit); ok {
+			ast.Inspect(lit.Body, func(n ast.Node) bool {
+				if id, ok := n.(*ast.Ident); ok {
+					if obj := pass.Info.Uses[id]; obj != nil && vars[obj] {
+						pass.ReportRangef(id, "loop variable %s captured by func literal", id.Name)
+					}
+				}
+				return true
+			})
+		}
+		return false
+	})
+}
+
+// checkLoopValueAssign reports assignments to fields of the loop value val
+// of struct type in body.
+func checkLoopValueAssign(pass *Pass, body *ast.BlockStmt, val types.Object) {
+	if _, ok := val.Type().Underlying().(*types.Struct); !ok {
+		return
+	}
+	check := func(lhs ast.Expr) {
+		for {
+			sel, ok := lhs.(*ast.SelectorExpr)
+			if !ok {
+				return
+			}
+			if _, ok := pass.Info.TypeOf(sel.X).(*types.Pointer); ok {
+				return // modifies the element via a pointer
+			}
+			if id, ok := sel.X.(*ast.Ident); ok {
+				if pass.Info.Uses[id] == val {
+					pass.ReportRangef(lhs, "assignment to %s.%s modifies a copy of the range element", id.Name, sel.Sel.Name)
+				}
+				return
+			}
+			lhs = sel.X
+		}
+	}
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch v := n.(type) {
+		case *ast.AssignStmt:
+			if v.Tok != token.DEFINE {
+				for _, lhs := range v.Lhs {
+					check(lhs)
+				}
+			}
+		case *ast.IncDecStmt:
+			check(v.X)
+		}
+		return true
+	})
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,77 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package vet
+
+import (
+	"go/types"
+)
+
+// -----------------------------------------------------------------------------
+
+// Shadow reports declarations that shadow builtin names of Go+, such as
+// `len`, `string` or `println`.
+var Shadow = &Analyzer{
+	Name: "shadow",
+	Doc:  "report declarations shadowing builtin names",
+	Run:  runShadow,
+}
+
+// gopBuiltins are builtin names that Go+ adds to the Go universe.
+var gopBuiltins = []string{
+	"print", "println", "printf", "errorf",
+	"fprint", "fprintln", "fprintf",
+	"sprint", "sprintln", "sprintf",
+	"open", "create", "lines", "blines", "newRange",
+	"assert", "todo", "unreachable", "logInfo", "logWarn", "logError",
+	"retry", "backoff", "pmap", "pfor", "fanIn",
+	"bigint", "bigrat", "bigfloat", "int128", "uint128",
+}
+
+func isBuiltinName(name string) bool {
+	if types.Universe.Lookup(name) != nil {
+		return true
+	}
+	for _, v := range gopBuiltins {
+		if v == name {
+			return true
+		}
+	}
+	return false
+}
+
+func runShadow(pass *Pass) {
+	for id, obj := range pass.Info.Defs {
+		if obj == nil || !isBuiltinName(id.Name) {
+			continue
+		}
+		switch v := obj.(type) {
+		case *types.Var:
+			if v.IsField() {
+				continue
+			}
+		case *types.Func:
+			if v.Type().(*types.Signature).Recv() != nil {
+				continue
+			}
+		case *types.PkgName, *types.Label:
+			continue
+		}
+		pass.ReportRangef(id, "declaration of %q shadows builtin", id.Name)
+	}
+}
+
+// -----------------------------------------------------------------------------
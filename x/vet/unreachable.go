@@ -0,0 +1,94 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package vet
+
+import (
+	"go/types"
+
+	"github.com/goplus/gop/ast"
+)
+
+// -----------------------------------------------------------------------------
+
+// Unreachable reports statements following a return, a branch statement
+// (break, continue, goto or fallthrough) or a call to panic in the same
+// statement list. Labeled statements are not reported since they may be
+// targets of goto.
+var Unreachable = &Analyzer{
+	Name: "unreachable",
+	Doc:  "report unreachable code",
+	Run:  runUnreachable,
+}
+
+func runUnreachable(pass *Pass) {
+	for _, f := range pass.Files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			switch v := n.(type) {
+			case *ast.BlockStmt:
+				checkUnreachable(pass, v.List)
+			case *ast.CaseClause:
+				checkUnreachable(pass, v.Body)
+			case *ast.CommClause:
+				checkUnreachable(pass, v.Body)
+			}
+			return true
+		})
+	}
+}
+
+func checkUnreachable(pass *Pass, list []ast.Stmt) {
+	terminated := false
+	for _, stmt := range list {
+		switch stmt.(type) {
+		case *ast.EmptyStmt:
+			continue
+		case *ast.LabeledStmt:
+			terminated = false
+		}
+		if terminated {
+			pass.ReportRangef(stmt, "unreachable code")
+			return
+		}
+		terminated = isTerminating(pass, stmt)
+	}
+}
+
+func isTerminating(pass *Pass, stmt ast.Stmt) bool {
+	switch v := stmt.(type) {
+	case *ast.ReturnStmt, *ast.BranchStmt:
+		return true
+	case *ast.BlockStmt:
+		return len(v.List) > 0 && isTerminating(pass, v.List[len(v.List)-1])
+	case *ast.ExprStmt:
+		if call, ok := v.X.(*ast.CallExpr); ok {
+			if id, ok := call.Fun.(*ast.Ident); ok && id.Name == "panic" {
+				return isBuiltinPanic(pass.Info.Uses[id])
+			}
+		}
+	}
+	return false
+}
+
+func isBuiltinPanic(obj types.Object) bool {
+	if obj == nil {
+		return false
+	}
+	pkg := obj.Pkg()
+	return pkg == nil || pkg.Path() == ""
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,104 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package vet
+
+import (
+	"go/types"
+	"strconv"
+
+	"github.com/goplus/gop/ast"
+	"github.com/goplus/gop/token"
+)
+
+// -----------------------------------------------------------------------------
+
+// Unused reports unused imports and unused local variables.
+var Unused = &Analyzer{
+	Name: "unused",
+	Doc:  "report unused imports and local variables",
+	Run:  runUnused,
+}
+
+func runUnused(pass *Pass) {
+	used := make(map[types.Object]bool)
+	for _, obj := range pass.Info.Uses {
+		used[obj] = true
+	}
+	for _, f := range pass.Files {
+		for _, spec := range f.Imports {
+			if spec.Name != nil && (spec.Name.Name == "_" || spec.Name.Name == ".") {
+				continue
+			}
+			obj := pass.Info.Implicits[spec]
+			if spec.Name != nil {
+				obj = pass.Info.Defs[spec.Name]
+			}
+			if obj != nil && !used[obj] {
+				path, _ := strconv.Unquote(spec.Path.Value)
+				pass.ReportRangef(spec, "%q imported and not used", path)
+			}
+		}
+		for _, decl := range f.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && fn.Body != nil {
+				checkUnusedVars(pass, fn.Body, used)
+			}
+		}
+	}
+}
+
+// checkUnusedVars reports local variables declared in body but never used.
+func checkUnusedVars(pass *Pass, body *ast.BlockStmt, used map[types.Object]bool) {
+	check := func(id *ast.Ident) {
+		if id == nil || id.Name == "_" {
+			return
+		}
+		if obj, ok := pass.Info.Defs[id].(*types.Var); ok && !used[obj] {
+			pass.ReportRangef(id, "declared and not used: %s", id.Name)
+		}
+	}
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch v := n.(type) {
+		case *ast.AssignStmt:
+			if v.Tok == token.DEFINE {
+				for _, lhs := range v.Lhs {
+					if id, ok := lhs.(*ast.Ident); ok {
+						check(id)
+					}
+				}
+			}
+		case *ast.ValueSpec:
+			for _, id := range v.Names {
+				check(id)
+			}
+		case *ast.RangeStmt:
+			if v.Tok == token.DEFINE {
+				if id, ok := v.Key.(*ast.Ident); ok {
+					check(id)
+				}
+				if id, ok := v.Value.(*ast.Ident); ok {
+					check(id)
+				}
+			}
+		case *ast.ForPhrase:
+			check(v.Key)
+			check(v.Value)
+		}
+		return true
+	})
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,179 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package vet implements static checks of Go+ source code, as run by the
+// “gop vet” command.
+//
+// Checks run on the Go+ AST and the type information of package checked by
+// x/typesutil, not on the generated Go code, so positions of reports are the
+// positions in Go+ source files. A `//nolint` or `//nolint:<check>` comment
+// at the line of a report or the line before suppresses it.
+package vet
+
+import (
+	"fmt"
+	"go/types"
+	"sort"
+
+	"github.com/goplus/gop/ast"
+	"github.com/goplus/gop/cl"
+	"github.com/goplus/gop/token"
+	"github.com/goplus/gop/x/typesutil"
+)
+
+// -----------------------------------------------------------------------------
+
+// An Analyzer describes a check of Go+ source code.
+type Analyzer struct {
+	Name string // name of the check, used by flags and nolint comments
+	Doc  string // short description of the check
+	Run  func(pass *Pass)
+}
+
+// Analyzers are all checks run by “gop vet”.
+var Analyzers = []*Analyzer{
+	Unused,
+	Shadow,
+	RangeLoop,
+	Unreachable,
+	Printf,
+	Context,
+}
+
+// A Pass provides information of a type-checked package to the Run function
+// of an Analyzer.
+type Pass struct {
+	Fset  *token.FileSet
+	Files []*ast.File
+	Pkg   *types.Package
+	Info  *typesutil.Info
+
+	// Diagnostics are the warnings of the Go+ compiler for the package, that
+	// an Analyzer may report instead of checking the same thing again.
+	Diagnostics []*cl.Diagnostic
+
+	analyzer *Analyzer
+	diags    []Diagnostic
+}
+
+// Reportf reports a problem at pos.
+func (p *Pass) Reportf(pos token.Pos, format string, args ...interface{}) {
+	p.report(pos, token.NoPos, fmt.Sprintf(format, args...))
+}
+
+// A Range is a range of source code, eg. an ast.Node.
+type Range interface {
+	Pos() token.Pos
+	End() token.Pos
+}
+
+// ReportRangef reports a problem of the range rng, which is underlined when
+// the problem is printed, as diagnostics of the compiler are.
+func (p *Pass) ReportRangef(rng Range, format string, args ...interface{}) {
+	p.report(rng.Pos(), rng.End(), fmt.Sprintf(format, args...))
+}
+
+func (p *Pass) report(pos, end token.Pos, msg string) {
+	d := Diagnostic{Pos: p.Fset.Position(pos), Category: p.analyzer.Name, Message: msg}
+	if end.IsValid() {
+		d.End = p.Fset.Position(end)
+	}
+	p.diags = append(p.diags, d)
+}
+
+// A Diagnostic is a problem reported by an Analyzer.
+type Diagnostic struct {
+	Pos      token.Position
+	End      token.Position // end of the problem range (optional)
+	Category string         // name of the Analyzer
+	Message  string
+}
+
+func (p Diagnostic) String() string {
+	return fmt.Sprintf("%v: %s", p.Pos, p.Message)
+}
+
+// NewInfo returns a typesutil.Info with all maps needed by Analyzers.
+func NewInfo() *typesutil.Info {
+	return &typesutil.Info{
+		Types:      make(map[ast.Expr]types.TypeAndValue),
+		Defs:       make(map[*ast.Ident]types.Object),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Implicits:  make(map[ast.Node]types.Object),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+		Scopes:     make(map[ast.Node]*types.Scope),
+	}
+}
+
+// Run runs analyzers on files of the package pkg, whose type information is
+// info (see NewInfo) and whose compiler warnings are diags (see
+// typesutil.Config.OnDiagnostic), and returns the reported problems sorted by
+// position.
+func Run(fset *token.FileSet, files []*ast.File, pkg *types.Package, info *typesutil.Info, diags []*cl.Diagnostic, analyzers []*Analyzer) []Diagnostic {
+	nolints := make(map[nolintKey][]string)
+	for _, f := range files {
+		for _, cg := range f.Comments {
+			for _, c := range cg.List {
+				if names, ok := ast.Nolint(c.Text); ok {
+					pos := fset.Position(c.Pos())
+					nolints[nolintKey{pos.Filename, pos.Line}] = names
+					nolints[nolintKey{pos.Filename, pos.Line + 1}] = names
+				}
+			}
+		}
+	}
+	var ret []Diagnostic
+	for _, a := range analyzers {
+		pass := &Pass{Fset: fset, Files: files, Pkg: pkg, Info: info, Diagnostics: diags, analyzer: a}
+		a.Run(pass)
+		for _, d := range pass.diags {
+			if names, ok := nolints[nolintKey{d.Pos.Filename, d.Pos.Line}]; ok && suppressed(names, a.Name) {
+				continue
+			}
+			ret = append(ret, d)
+		}
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		pi, pj := ret[i].Pos, ret[j].Pos
+		if pi.Filename != pj.Filename {
+			return pi.Filename < pj.Filename
+		}
+		if pi.Line != pj.Line {
+			return pi.Line < pj.Line
+		}
+		return pi.Column < pj.Column
+	})
+	return ret
+}
+
+type nolintKey struct {
+	file string
+	line int
+}
+
+func suppressed(names []string, name string) bool {
+	if names == nil {
+		return true
+	}
+	for _, v := range names {
+		if v == name {
+			return true
+		}
+	}
+	return false
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,299 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package vet_test
+
+import (
+	"fmt"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/goplus/gop"
+	"github.com/goplus/gop/ast"
+	"github.com/goplus/gop/cl"
+	"github.com/goplus/gop/parser"
+	"github.com/goplus/gop/token"
+	"github.com/goplus/gop/x/gopenv"
+	"github.com/goplus/gop/x/typesutil"
+	"github.com/goplus/gop/x/vet"
+	"github.com/goplus/mod/gopmod"
+)
+
+func init() {
+	if os.Getenv("GOPROOT") == "" {
+		dir, _ := os.Getwd()
+		os.Setenv("GOPROOT", filepath.Clean(filepath.Join(dir, "./../..")))
+	}
+}
+
+func testVet(t *testing.T, src string, expected string, analyzers ...*vet.Analyzer) {
+	t.Helper()
+	var b strings.Builder
+	for _, d := range runVet(t, src, analyzers...) {
+		b.WriteString(d.String())
+		b.WriteByte('\n')
+	}
+	if ret := b.String(); ret != expected {
+		t.Fatalf("vet:\n%s\nexpected:\n%s", ret, expected)
+	}
+}
+
+func runVet(t *testing.T, src string, analyzers ...*vet.Analyzer) []vet.Diagnostic {
+	t.Helper()
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "main.gop", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal("parser.ParseFile:", err)
+	}
+	conf := &types.Config{
+		Importer: gop.NewImporter(nil, gopenv.Get(), fset),
+		Error: func(err error) {
+			t.Fatal("typecheck:", err)
+		},
+	}
+	pkg := types.NewPackage("main", "main")
+	info := vet.NewInfo()
+	files := []*ast.File{f}
+	var diags []*cl.Diagnostic
+	opts := &typesutil.Config{Types: pkg, Fset: fset, Mod: gopmod.Default, OnDiagnostic: func(d *cl.Diagnostic) {
+		diags = append(diags, d)
+	}}
+	err = typesutil.NewChecker(conf, opts, nil, info).Files(nil, files)
+	if err != nil {
+		t.Fatal("typesutil.Check:", err)
+	}
+	return vet.Run(fset, files, pkg, info, diags, analyzers)
+}
+
+func TestReportRange(t *testing.T) {
+	diags := runVet(t, `import "fmt"
+
+var len = 3
+fmt.Printf "%d\n", "x"
+`, vet.Shadow, vet.Printf)
+	var b strings.Builder
+	for _, d := range diags {
+		fmt.Fprintf(&b, "%d:%d-%d:%d\n", d.Pos.Line, d.Pos.Column, d.End.Line, d.End.Column)
+	}
+	if ret := b.String(); ret != "3:5-3:8\n4:20-4:23\n" {
+		t.Fatal("ReportRangef:", ret)
+	}
+}
+
+func TestUnused(t *testing.T) {
+	testVet(t, `import (
+	"fmt"
+	"os"
+	str "strings"
+)
+
+var unusedGlobal = 1
+
+func f(a []int) (n int) {
+	x := 1
+	var y, z = 2, 3
+	for i, v := range a {
+		n += v
+	}
+	for k, v <- a {
+		n += k
+	}
+	_ = z
+	return
+}
+
+fmt.println f(nil)
+`, `main.gop:3:2: "os" imported and not used
+main.gop:4:2: "strings" imported and not used
+main.gop:10:2: declared and not used: x
+main.gop:11:6: declared and not used: y
+main.gop:12:6: declared and not used: i
+main.gop:15:9: declared and not used: v
+`, vet.Unused)
+}
+
+func TestShadow(t *testing.T) {
+	testVet(t, `type T struct {
+	len int
+}
+
+func (T) string() string {
+	return ""
+}
+
+func println(s string) {
+}
+
+var len = 3
+
+func f() {
+	string := "x"
+	println string
+}
+`, `main.gop:9:6: declaration of "println" shadows builtin
+main.gop:12:5: declaration of "len" shadows builtin
+main.gop:15:2: declaration of "string" shadows builtin
+`, vet.Shadow)
+}
+
+func TestRangeLoop(t *testing.T) {
+	testVet(t, `type T struct {
+	n int
+	p *T
+}
+
+func f(a []T, m map[string]int) {
+	for i, v := range a {
+		defer func() { echo i, v.n }()
+		func() { echo i }()
+		v.n = 1
+		v.p.n = 2
+		v.n++
+	}
+	for k <- m {
+		go func() { echo k }()
+	}
+	for i := range a {
+		a[i].n = 1
+	}
+}
+
+func echo(args ...any) {
+}
+`, `main.gop:8:23: loop variable i captured by func literal
+main.gop:8:26: loop variable v captured by func literal
+main.gop:10:3: assignment to v.n modifies a copy of the range element
+main.gop:12:3: assignment to v.n modifies a copy of the range element
+main.gop:15:20: loop variable k captured by func literal
+`, vet.RangeLoop)
+}
+
+func TestUnreachable(t *testing.T) {
+	testVet(t, `func f(n int) int {
+	for {
+		if n > 0 {
+			break
+			n--
+		}
+		n++
+	}
+	switch n {
+	case 1:
+		panic("one")
+		n = 2
+	case 2:
+		goto L
+		n = 3
+	L:
+		n = 4
+	}
+	{
+		return n
+	}
+	return 0
+}
+`, `main.gop:5:4: unreachable code
+main.gop:12:3: unreachable code
+main.gop:15:3: unreachable code
+main.gop:22:2: unreachable code
+`, vet.Unreachable)
+}
+
+func TestPrintf(t *testing.T) {
+	testVet(t, `import (
+	"fmt"
+	"os"
+)
+
+printf "%d %s\n", 1
+fmt.printf "%d\n", 1, 2
+fprintf os.Stderr, "%v %v\n", 1, 2
+_ = errorf("%w", nil)
+_ = sprintf("%w", nil)
+printf "%[2]d %[1]d\n", 1, 2
+printf "%*d%%\n", 5, 1
+printf "%z\n", 1
+printf "100%"
+args := []any{1}
+printf("%d %d\n", args...)
+println "%d", 1
+println "100%%"
+fmt.Sprintf("%.2f", 1.0)
+printf "%d\n", "x"
+`, `main.gop:6:1: printf format %s reads arg #2, but call has 1 arg
+main.gop:7:1: fmt.printf call needs 1 arg but has 2 args
+main.gop:10:5: sprintf does not support error-wrapping directive %w
+main.gop:13:1: printf format %z has unknown verb z
+main.gop:14:1: printf format % is missing verb at end of string
+main.gop:17:9: println call has possible formatting directive %d
+main.gop:20:16: printf format %d has arg "x" of wrong type untyped string
+`, vet.Printf)
+}
+
+func TestContext(t *testing.T) {
+	testVet(t, `import (
+	"context"
+	"net/http"
+
+	"github.com/goplus/gop/x/service"
+)
+
+type DB struct{}
+
+func (DB) Query(q string)                             {}
+func (DB) QueryContext(ctx context.Context, q string) {}
+
+func get(ctx context.Context, db DB) {
+	db.Query "x"
+	db.QueryContext ctx, "x"
+	req, _ := http.newRequest("GET", "/", nil)
+	go func() {
+		_ = context.Background()
+	}()
+	_ = req
+}
+
+func plain(db DB) {
+	db.Query "x"
+	_ = context.TODO()
+}
+
+var app service.App
+app.get "/", ctx => {
+	req, _ := http.newRequestWithContext(ctx, "GET", "/", nil)
+	_ = context.TODO()
+	_ = req
+}
+`, `main.gop:14:2: db.Query drops context ctx, use QueryContext instead
+main.gop:16:12: http.newRequest drops context ctx, use NewRequestWithContext instead
+main.gop:18:7: context.Background drops context ctx, pass ctx instead
+main.gop:31:6: context.TODO drops context ctx, pass ctx instead
+`, vet.Context)
+}
+
+func TestNolint(t *testing.T) {
+	testVet(t, `func f() {
+	x := 1 //nolint:unused
+	//nolint
+	y := 2
+	z := 3 //nolint:shadow
+}
+`, `main.gop:5:2: declared and not used: z
+`, vet.Analyzers...)
+}